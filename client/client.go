@@ -70,6 +70,7 @@ import (
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/opencontainers/runtime-spec/specs-go/features"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"golang.org/x/sync/semaphore"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
@@ -145,6 +146,10 @@ func New(address string, opts ...Opt) (*Client, error) {
 		}
 		gopts = append(gopts, copts.extraDialOpts...)
 
+		// Propagate the span context and baggage of client side spans to the
+		// daemon so both ends show up in the same trace.
+		gopts = append(gopts, grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+
 		gopts = append(gopts, grpc.WithDefaultCallOptions(
 			grpc.MaxCallRecvMsgSize(defaults.DefaultMaxRecvMsgSize),
 			grpc.MaxCallSendMsgSize(defaults.DefaultMaxSendMsgSize)))
@@ -317,6 +322,15 @@ func (c *Client) NewContainer(ctx context.Context, id string, opts ...NewContain
 		tracing.Attribute("container.runtime.name", container.Runtime.Name),
 		tracing.Attribute("container.snapshotter.name", container.Snapshotter),
 	)
+	// Carry the container identity as baggage so daemon side spans caused by
+	// this call share the same attributes.
+	if ns, err := namespaces.NamespaceRequired(ctx); err == nil {
+		ctx = tracing.WithBaggage(ctx, tracing.Attribute("containerd.namespace", ns))
+	}
+	ctx = tracing.WithBaggage(ctx,
+		tracing.Attribute("container.id", container.ID),
+		tracing.Attribute("container.image.ref", container.Image),
+	)
 	r, err := c.ContainerService().Create(ctx, container)
 	if err != nil {
 		return nil, err
@@ -414,6 +428,14 @@ type RemoteContext struct {
 	// ChildLabelMap sets the labels used to reference child objects in the content
 	// store. By default, all GC reference labels will be set for all fetched content.
 	ChildLabelMap func(ocispec.Descriptor) []string
+
+	// ConvertSchema1 converts pulled legacy Docker schema 1 manifests to
+	// OCI manifests on ingestion instead of failing. The resolver must
+	// allow schema 1 resolution as well, see
+	// docker.ResolverOptions.AllowSchema1. Schema 1 is deprecated and the
+	// conversion only exists for registries which cannot serve newer
+	// manifests.
+	ConvertSchema1 bool
 }
 
 func defaultRemoteContext() *RemoteContext {
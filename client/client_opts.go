@@ -157,6 +157,18 @@ func WithPullUnpack(_ *Client, c *RemoteContext) error {
 	return nil
 }
 
+// WithSchema1Conversion is used to convert pulled legacy Docker schema 1
+// manifests to OCI manifests on ingestion instead of failing. The
+// resolver must allow schema 1 resolution as well, see
+// docker.ResolverOptions.AllowSchema1.
+//
+// Schema 1 is deprecated; use this only while migrating images away from
+// registries which cannot serve newer manifests.
+func WithSchema1Conversion(_ *Client, c *RemoteContext) error {
+	c.ConvertSchema1 = true
+	return nil
+}
+
 // WithUnpackOpts is used to add unpack options to the unpacker.
 func WithUnpackOpts(opts []UnpackOpt) RemoteOpt {
 	return func(_ *Client, c *RemoteContext) error {
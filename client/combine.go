@@ -0,0 +1,166 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/images"
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+type combineOpts struct {
+	mediaType   string
+	annotations map[string]string
+}
+
+// CombineOpt allows the caller to configure how images are combined
+type CombineOpt func(*combineOpts) error
+
+// WithCombineMediaType sets the media type of the combined index. The
+// default is the OCI image index media type.
+func WithCombineMediaType(mediaType string) CombineOpt {
+	return func(c *combineOpts) error {
+		if !images.IsIndexType(mediaType) {
+			return fmt.Errorf("unsupported index media type %q: %w", mediaType, errdefs.ErrInvalidArgument)
+		}
+		c.mediaType = mediaType
+		return nil
+	}
+}
+
+// WithCombineAnnotations sets annotations on the combined index
+func WithCombineAnnotations(annotations map[string]string) CombineOpt {
+	return func(c *combineOpts) error {
+		c.annotations = annotations
+		return nil
+	}
+}
+
+// CombineImages composes a new manifest list from the manifests of the
+// given locally stored images, writes it to the content store and tags it
+// with newRef. Sources which are themselves manifest lists contribute all
+// of their platform manifests. The per-platform descriptors are filled in
+// from the image configs so that the result resolves like a registry-built
+// multi-arch image.
+func (c *Client) CombineImages(ctx context.Context, newRef string, refs []string, opts ...CombineOpt) (images.Image, error) {
+	var copts combineOpts
+	for _, o := range opts {
+		if err := o(&copts); err != nil {
+			return images.Image{}, err
+		}
+	}
+	if newRef == "" {
+		return images.Image{}, fmt.Errorf("new image reference must not be empty: %w", errdefs.ErrInvalidArgument)
+	}
+	if len(refs) == 0 {
+		return images.Image{}, fmt.Errorf("no source images to combine: %w", errdefs.ErrInvalidArgument)
+	}
+
+	ctx, done, err := c.WithLease(ctx)
+	if err != nil {
+		return images.Image{}, err
+	}
+	defer done(ctx)
+
+	var (
+		cs        = c.ContentStore()
+		is        = c.ImageService()
+		manifests []ocispec.Descriptor
+		seen      = make(map[digest.Digest]struct{})
+	)
+	for _, ref := range refs {
+		img, err := is.Get(ctx, ref)
+		if err != nil {
+			return images.Image{}, fmt.Errorf("failed to get source image %q: %w", ref, err)
+		}
+
+		var descs []ocispec.Descriptor
+		switch target := img.Target; {
+		case images.IsManifestType(target.MediaType):
+			descs = append(descs, target)
+		case images.IsIndexType(target.MediaType):
+			idx, err := decodeIndex(ctx, cs, target)
+			if err != nil {
+				return images.Image{}, fmt.Errorf("failed to decode index of %q: %w", ref, err)
+			}
+			descs = append(descs, idx.Manifests...)
+		default:
+			return images.Image{}, fmt.Errorf("cannot combine %q with media type %q: %w", ref, target.MediaType, errdefs.ErrInvalidArgument)
+		}
+
+		for _, desc := range descs {
+			if _, ok := seen[desc.Digest]; ok {
+				continue
+			}
+			seen[desc.Digest] = struct{}{}
+			if desc.Platform == nil && images.IsManifestType(desc.MediaType) {
+				manifest, err := images.Manifest(ctx, cs, desc, nil)
+				if err != nil {
+					return images.Image{}, fmt.Errorf("failed to read manifest of %q: %w", ref, err)
+				}
+				platform, err := images.ConfigPlatform(ctx, cs, manifest.Config)
+				if err != nil {
+					return images.Image{}, fmt.Errorf("failed to read platform of %q: %w", ref, err)
+				}
+				desc.Platform = &platform
+			}
+			manifests = append(manifests, desc)
+		}
+	}
+
+	mediaType := copts.mediaType
+	if mediaType == "" {
+		mediaType = ocispec.MediaTypeImageIndex
+	}
+	index := ocispec.Index{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2,
+		},
+		MediaType:   mediaType,
+		Manifests:   manifests,
+		Annotations: copts.annotations,
+	}
+
+	// Label the manifests as children of the index so they are retained
+	// by the garbage collector.
+	labels := map[string]string{}
+	for i, m := range index.Manifests {
+		labels[fmt.Sprintf("containerd.io/gc.ref.content.%d", i)] = m.Digest.String()
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return images.Image{}, err
+	}
+	ref := fmt.Sprintf("combine-index-%s", digest.SHA256.FromBytes(data))
+	desc, err := writeContent(ctx, cs, mediaType, ref, bytes.NewReader(data), content.WithLabels(labels))
+	if err != nil {
+		return images.Image{}, err
+	}
+
+	return c.createNewImage(ctx, images.Image{
+		Name:   newRef,
+		Target: desc,
+	})
+}
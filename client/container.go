@@ -23,6 +23,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/containerd/containerd/api/services/tasks/v1"
 	"github.com/containerd/containerd/api/types"
@@ -39,7 +40,9 @@ import (
 	"github.com/basuotian/containerd/core/containers"
 	"github.com/basuotian/containerd/core/images"
 	"github.com/basuotian/containerd/pkg/cio"
+	"github.com/basuotian/containerd/pkg/namespaces"
 	"github.com/basuotian/containerd/pkg/oci"
+	"github.com/basuotian/containerd/pkg/startlatency"
 	"github.com/basuotian/containerd/pkg/tracing"
 )
 
@@ -245,9 +248,13 @@ func (c *container) NewTask(ctx context.Context, ioCreate cio.Creator, opts ...N
 		Stdout:      cfg.Stdout,
 		Stderr:      cfg.Stderr,
 	}
+	mountsStart := time.Now()
 	if err := c.handleMounts(ctx, request); err != nil {
 		return nil, err
 	}
+	if ns, ok := namespaces.Namespace(ctx); ok {
+		startlatency.Record(ns, c.id, startlatency.StageSnapshotMounts, time.Since(mountsStart))
+	}
 
 	r, err := c.get(ctx)
 	if err != nil {
@@ -293,6 +300,15 @@ func (c *container) NewTask(ctx context.Context, ioCreate cio.Creator, opts ...N
 		tracing.Attribute("task.request.options", request.Options.String()),
 		tracing.Attribute("task.runtime.name", info.runtime),
 	)
+	// Carry the task identity as baggage so daemon side spans caused by this
+	// call share the same attributes.
+	if ns, err := namespaces.NamespaceRequired(ctx); err == nil {
+		ctx = tracing.WithBaggage(ctx, tracing.Attribute("containerd.namespace", ns))
+	}
+	ctx = tracing.WithBaggage(ctx,
+		tracing.Attribute("container.id", c.id),
+		tracing.Attribute("container.image.ref", r.Image),
+	)
 	response, err := c.client.TaskService().Create(ctx, request)
 	if err != nil {
 		return nil, errgrpc.ToNative(err)
@@ -0,0 +1,154 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+
+	"github.com/basuotian/containerd/core/containers"
+	"github.com/basuotian/containerd/core/mount"
+)
+
+// WithNewSnapshotFromContainer allocates the new container's snapshot from
+// the current rootfs of the source container, captured through a committed
+// intermediate snapshot so the two containers end up with independent
+// copies. This enables fast "fork" workflows for testing and debugging a
+// running container's state.
+//
+// When pause is true and the source container has a running task, the task
+// is paused while the rootfs is captured and resumed afterwards, so the
+// copy cannot observe files mid write. Without pausing the capture is a
+// best effort consistent view.
+func WithNewSnapshotFromContainer(id string, source Container, pause bool) NewContainerOpts {
+	return func(ctx context.Context, client *Client, c *containers.Container) error {
+		info, err := source.Info(ctx)
+		if err != nil {
+			return err
+		}
+		if info.SnapshotKey == "" {
+			return fmt.Errorf("container %s has no snapshot to fork: %w", info.ID, errdefs.ErrInvalidArgument)
+		}
+		c.Snapshotter, err = client.resolveSnapshotterName(ctx, info.Snapshotter)
+		if err != nil {
+			return err
+		}
+		s, err := client.getSnapshotter(ctx, c.Snapshotter)
+		if err != nil {
+			return err
+		}
+
+		if pause {
+			task, err := source.Task(ctx, nil)
+			if err != nil && !errdefs.IsNotFound(err) {
+				return err
+			}
+			if task != nil {
+				status, err := task.Status(ctx)
+				if err != nil {
+					return err
+				}
+				if status.Status == Running {
+					if err := task.Pause(ctx); err != nil {
+						return fmt.Errorf("failed to pause task for fork: %w", err)
+					}
+					defer func() {
+						if err := task.Resume(ctx); err != nil {
+							log.G(ctx).WithError(err).WithField("id", info.ID).Warn("failed to resume task after fork")
+						}
+					}()
+				}
+			}
+		}
+
+		stat, err := s.Stat(ctx, info.SnapshotKey)
+		if err != nil {
+			return err
+		}
+
+		// Diff the source's active snapshot against its parent. The active
+		// snapshot cannot be committed without taking it away from the
+		// source, so its changes are captured as a layer instead.
+		unique, err := uniqueSuffix()
+		if err != nil {
+			return err
+		}
+		var lower []mount.Mount
+		if stat.Parent != "" {
+			viewKey := fmt.Sprintf("fork-view-%s-%s", id, unique)
+			lower, err = s.View(ctx, viewKey, stat.Parent)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := s.Remove(ctx, viewKey); err != nil {
+					log.G(ctx).WithError(err).Warn("failed to remove fork parent view")
+				}
+			}()
+		}
+		upper, err := s.Mounts(ctx, info.SnapshotKey)
+		if err != nil {
+			return err
+		}
+		desc, err := client.DiffService().Compare(ctx, lower, upper)
+		if err != nil {
+			return fmt.Errorf("failed to diff snapshot %s: %w", info.SnapshotKey, err)
+		}
+
+		// Apply the layer on a fresh snapshot of the same parent and commit
+		// it; the committed intermediate becomes the parent of the new
+		// container's snapshot and keeps the captured state alive.
+		var (
+			interKey  = fmt.Sprintf("fork-%s-%s", id, unique)
+			interName = fmt.Sprintf("fork/%s/%s", id, unique)
+		)
+		mounts, err := s.Prepare(ctx, interKey, stat.Parent)
+		if err != nil {
+			return err
+		}
+		if _, err := client.DiffService().Apply(ctx, desc, mounts); err != nil {
+			s.Remove(ctx, interKey)
+			return fmt.Errorf("failed to apply fork diff: %w", err)
+		}
+		if err := s.Commit(ctx, interName, interKey); err != nil {
+			s.Remove(ctx, interKey)
+			return err
+		}
+
+		if _, err := s.Prepare(ctx, id, interName); err != nil {
+			return err
+		}
+		c.SnapshotKey = id
+		c.Image = info.Image
+		return nil
+	}
+}
+
+// uniqueSuffix distinguishes the intermediate snapshots of forks of the
+// same container.
+func uniqueSuffix() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -241,6 +241,10 @@ func withNewSnapshot(id string, i Image, readonly bool, opts ...snapshots.Opt) N
 			return err
 		}
 
+		// Snapshotter labels defined on the image apply to the container
+		// snapshot as well; explicit opts take precedence.
+		opts = append(snapshotterLabelOpts(i.Labels()), opts...)
+
 		parent := identity.ChainID(diffIDs).String()
 		c.Snapshotter, err = client.resolveSnapshotterName(ctx, c.Snapshotter)
 		if err != nil {
@@ -23,10 +23,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"syscall"
 
 	"github.com/basuotian/containerd/core/containers"
 	"github.com/basuotian/containerd/core/mount"
+	"github.com/basuotian/containerd/core/snapshots"
 	"github.com/basuotian/containerd/internal/userns"
 
 	"github.com/containerd/errdefs"
@@ -35,7 +37,9 @@ import (
 )
 
 // WithRemappedSnapshot creates a new snapshot and remaps the uid/gid for the
-// filesystem to be used by a container with user namespaces
+// filesystem to be used by a container with user namespaces. Snapshotters
+// supporting idmapped mounts remap at mount time; otherwise the ownership
+// of a copy of the filesystem is changed with a recursive chown.
 func WithRemappedSnapshot(id string, i Image, uid, gid uint32) NewContainerOpts {
 	uidmaps := []specs.LinuxIDMapping{{ContainerID: 0, HostID: uid, Size: 65536}}
 	gidmaps := []specs.LinuxIDMapping{{ContainerID: 0, HostID: gid, Size: 65536}}
@@ -84,6 +88,27 @@ func withRemappedSnapshotBase(id string, i Image, uidmaps, gidmaps []specs.Linux
 		if err != nil {
 			return err
 		}
+
+		// Prefer the label-based idmapped mount path when the snapshotter
+		// supports it; remapping then happens at mount time and no chowned
+		// copy of the image is made. Capability probing can fail against
+		// older daemons, in which case the chown fallback below is used.
+		if capabs, err := client.GetSnapshotterCapabilities(ctx, c.Snapshotter); err == nil &&
+			slices.Contains(capabs, snapshots.CapabilityRemapIDs) {
+			labels := WithUserNSRemapperLabels(uidmaps, gidmaps)
+			if readonly {
+				_, err = snapshotter.View(ctx, id, rsn.Parent, labels)
+			} else {
+				_, err = snapshotter.Prepare(ctx, id, rsn.Parent, labels)
+			}
+			if err != nil {
+				return err
+			}
+			c.SnapshotKey = id
+			c.Image = i.Name()
+			return nil
+		}
+
 		if _, err := snapshotter.Stat(ctx, usernsID); err == nil {
 			if _, err := snapshotter.Prepare(ctx, id, usernsID); err == nil {
 				c.SnapshotKey = id
@@ -18,7 +18,16 @@ package client
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
 	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/basuotian/containerd/core/images/archive"
 )
@@ -29,3 +38,163 @@ import (
 func (c *Client) Export(ctx context.Context, w io.Writer, opts ...archive.ExportOpt) error {
 	return archive.Export(ctx, c.ContentStore(), w, opts...)
 }
+
+// ExportSplit exports images as Export does, splitting the stream into
+// numbered tar segments of at most segmentSize bytes under prefix and
+// writing a SHA256SUMS manifest of the segments next to them. It returns
+// the segments which were written.
+func (c *Client) ExportSplit(ctx context.Context, prefix string, segmentSize int64, opts ...archive.ExportOpt) ([]ExportSegment, error) {
+	sw, err := NewSplitWriter(prefix, segmentSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Export(ctx, sw, opts...); err != nil {
+		sw.Abort()
+		return nil, err
+	}
+	if err := sw.Close(); err != nil {
+		return nil, err
+	}
+	return sw.Segments(), nil
+}
+
+// ExportSegment describes one segment written by a SplitWriter.
+type ExportSegment struct {
+	// Path of the segment file.
+	Path string
+	// Size of the segment in bytes.
+	Size int64
+	// Digest of the segment contents.
+	Digest digest.Digest
+}
+
+// SplitWriter splits a stream into numbered segment files of a maximum
+// size, digesting each segment as it is written. Filled segments are
+// synced and closed by parallel writers while the stream continues into
+// the next segment. Close finishes the last segment and writes a
+// SHA256SUMS manifest of all segments into the directory of the prefix.
+type SplitWriter struct {
+	prefix string
+	max    int64
+
+	index    int
+	name     string
+	file     *os.File
+	hash     hash.Hash
+	written  int64
+	segments []ExportSegment
+
+	eg errgroup.Group
+}
+
+// NewSplitWriter returns a SplitWriter creating segment files named
+// "<prefix>.000", "<prefix>.001", ... of at most segmentSize bytes.
+func NewSplitWriter(prefix string, segmentSize int64) (*SplitWriter, error) {
+	if segmentSize <= 0 {
+		return nil, fmt.Errorf("segment size must be positive: %w", errdefs.ErrInvalidArgument)
+	}
+	return &SplitWriter{
+		prefix: prefix,
+		max:    segmentSize,
+	}, nil
+}
+
+func (sw *SplitWriter) Write(p []byte) (int, error) {
+	var total int
+	for len(p) > 0 {
+		if sw.file == nil {
+			name := fmt.Sprintf("%s.%03d", sw.prefix, sw.index)
+			f, err := os.Create(name)
+			if err != nil {
+				return total, err
+			}
+			sw.index++
+			sw.name = name
+			sw.file = f
+			sw.hash = sha256.New()
+			sw.written = 0
+		}
+
+		n := int64(len(p))
+		if room := sw.max - sw.written; n > room {
+			n = room
+		}
+		wn, err := sw.file.Write(p[:n])
+		sw.hash.Write(p[:wn])
+		sw.written += int64(wn)
+		total += wn
+		if err != nil {
+			return total, err
+		}
+		p = p[wn:]
+
+		if sw.written == sw.max {
+			sw.rotate()
+		}
+	}
+	return total, nil
+}
+
+// rotate finishes the current segment and hands the file off to a
+// background writer for sync and close.
+func (sw *SplitWriter) rotate() {
+	f := sw.file
+	sw.file = nil
+	sw.segments = append(sw.segments, ExportSegment{
+		Path:   sw.name,
+		Size:   sw.written,
+		Digest: digest.NewDigest(digest.SHA256, sw.hash),
+	})
+	sw.eg.Go(func() error {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// Close finishes the last segment, waits for the parallel segment
+// writers and writes the SHA256SUMS manifest.
+func (sw *SplitWriter) Close() error {
+	if sw.file != nil {
+		sw.rotate()
+	}
+	if err := sw.eg.Wait(); err != nil {
+		return err
+	}
+
+	manifest := filepath.Join(filepath.Dir(sw.prefix), "SHA256SUMS")
+	f, err := os.Create(manifest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for _, segment := range sw.segments {
+		if _, err := fmt.Fprintf(f, "%s  %s\n", segment.Digest.Encoded(), filepath.Base(segment.Path)); err != nil {
+			return err
+		}
+	}
+	return f.Sync()
+}
+
+// Abort closes and removes all segments written so far without writing
+// a manifest.
+func (sw *SplitWriter) Abort() {
+	if sw.file != nil {
+		f := sw.file
+		sw.file = nil
+		sw.eg.Go(f.Close)
+		sw.segments = append(sw.segments, ExportSegment{Path: sw.name})
+	}
+	_ = sw.eg.Wait()
+	for _, segment := range sw.segments {
+		os.Remove(segment.Path)
+	}
+	sw.segments = nil
+}
+
+// Segments returns the segments written, valid after Close.
+func (sw *SplitWriter) Segments() []ExportSegment {
+	return sw.segments
+}
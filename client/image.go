@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/basuotian/containerd/core/containers"
 	"github.com/basuotian/containerd/core/content"
 	"github.com/basuotian/containerd/core/diff"
 	"github.com/basuotian/containerd/core/images"
@@ -30,12 +31,14 @@ import (
 	"github.com/basuotian/containerd/core/snapshots"
 	"github.com/basuotian/containerd/internal/kmutex"
 	"github.com/basuotian/containerd/pkg/labels"
+	"github.com/basuotian/containerd/pkg/oci"
 	"github.com/basuotian/containerd/pkg/rootfs"
 	"github.com/containerd/errdefs"
 	"github.com/containerd/platforms"
 	"github.com/opencontainers/go-digest"
 	"github.com/opencontainers/image-spec/identity"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
 )
 
 // Image describes an image used by containers
@@ -66,6 +69,9 @@ type Image interface {
 	Platform() platforms.MatchComparer
 	// Spec returns the OCI image spec for a given image.
 	Spec(ctx context.Context) (ocispec.Image, error)
+	// EffectiveProcess computes the process configuration a container
+	// created from the image would get, without creating a container.
+	EffectiveProcess(ctx context.Context, args, env []string) (specs.Process, error)
 }
 
 type usageOptions struct {
@@ -245,6 +251,27 @@ func (i *image) Spec(ctx context.Context) (ocispec.Image, error) {
 	return ociImage, nil
 }
 
+// EffectiveProcess is a dry run of oci.WithImageConfigArgs over a default
+// spec: args, when not empty, replace the image CMD and env entries
+// override the image environment the same way container creation does.
+// Resolving a non-numeric image user requires the image rootfs, so a
+// user configured by name returns an error here.
+func (i *image) EffectiveProcess(ctx context.Context, args, env []string) (specs.Process, error) {
+	opts := []oci.SpecOpts{oci.WithImageConfigArgs(i, args)}
+	if len(env) > 0 {
+		opts = append(opts, oci.WithEnv(env))
+	}
+	var c containers.Container
+	s, err := oci.GenerateSpec(ctx, i.client, &c, opts...)
+	if err != nil {
+		return specs.Process{}, fmt.Errorf("compute effective process for image %s: %w", i.Name(), err)
+	}
+	if s.Process == nil {
+		return specs.Process{}, fmt.Errorf("no process configuration for image %s: %w", i.Name(), errdefs.ErrNotFound)
+	}
+	return *s.Process, nil
+}
+
 // UnpackConfig provides configuration for the unpack of an image
 type UnpackConfig struct {
 	// ApplyOpts for applying a diff to a snapshotter
@@ -300,6 +327,9 @@ func (i *image) Unpack(ctx context.Context, snapshotterName string, opts ...Unpa
 			return err
 		}
 	}
+	// Labels defined on the image under SnapshotterLabelPrefix are applied
+	// to every snapshot in the unpacked chain.
+	config.SnapshotOpts = append(snapshotterLabelOpts(i.i.Labels), config.SnapshotOpts...)
 
 	manifest, err := i.getManifest(ctx, i.platform)
 	if err != nil {
@@ -23,6 +23,7 @@ import (
 	"github.com/basuotian/containerd/core/images"
 	"github.com/basuotian/containerd/core/images/archive"
 	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
 	"github.com/containerd/platforms"
 	digest "github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -194,6 +195,16 @@ func (c *Client) Import(ctx context.Context, reader io.Reader, opts ...ImportOpt
 			return nil, err
 		}
 
+		if prov, ok, err := archive.ProvenanceFromAnnotations(idx.Annotations); err != nil {
+			log.G(ctx).WithError(err).Warn("archive carries malformed provenance annotations")
+		} else if ok {
+			log.G(ctx).WithFields(log.Fields{
+				"containerd-version": prov.ContainerdVersion,
+				"exported-at":        prov.ExportedAt,
+				"lease":              prov.Lease,
+			}).Info("image archive provenance")
+		}
+
 		for _, m := range idx.Manifests {
 			name := imageName(m.Annotations, iopts.imageRefT)
 			if name != "" {
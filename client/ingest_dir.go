@@ -0,0 +1,202 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/containerd/errdefs"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+type ingestDirOpts struct {
+	compress bool
+	labels   map[string]string
+}
+
+// IngestDirOpt configures how a directory is ingested into the content
+// store.
+type IngestDirOpt func(*ingestDirOpts) error
+
+// WithIngestDirCompression gzips the layer, producing a compressed layer
+// media type. The gzip stream carries no timestamp, so the blob stays
+// deterministic.
+func WithIngestDirCompression() IngestDirOpt {
+	return func(o *ingestDirOpts) error {
+		o.compress = true
+		return nil
+	}
+}
+
+// WithIngestDirLabels sets content store labels on the ingested blob, for
+// example a garbage collection root label to pin it.
+func WithIngestDirLabels(labels map[string]string) IngestDirOpt {
+	return func(o *ingestDirOpts) error {
+		o.labels = labels
+		return nil
+	}
+}
+
+// IngestDir writes the contents of a local directory to the content store
+// as a tar layer blob and returns its descriptor. The archive is
+// deterministic: entries are ordered lexically, timestamps are set to the
+// epoch and ownership is dropped, so ingesting the same tree always
+// produces the same digest. The descriptor can be appended to an image
+// with the mutate API. The blob has no references of its own; hold a
+// lease or pass a garbage collection label to keep it.
+func (c *Client) IngestDir(ctx context.Context, root string, opts ...IngestDirOpt) (ocispec.Descriptor, error) {
+	var copts ingestDirOpts
+	for _, o := range opts {
+		if err := o(&copts); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if !info.IsDir() {
+		return ocispec.Descriptor{}, fmt.Errorf("%q is not a directory: %w", root, errdefs.ErrInvalidArgument)
+	}
+
+	cs := c.ContentStore()
+	ref := fmt.Sprintf("ingest-dir-%d", time.Now().UnixNano())
+	writer, err := cs.Writer(ctx, content.WithRef(ref))
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer writer.Close()
+
+	var (
+		cw                  = &countingWriter{w: writer}
+		dst       io.Writer = cw
+		mediaType           = ocispec.MediaTypeImageLayer
+		gz        *gzip.Writer
+	)
+	if copts.compress {
+		mediaType = ocispec.MediaTypeImageLayerGzip
+		gz = gzip.NewWriter(cw)
+		dst = gz
+	}
+	tw := tar.NewWriter(dst)
+	if err := writeDeterministicTar(tw, root); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := tw.Close(); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+
+	var commitOpts []content.Opt
+	if len(copts.labels) > 0 {
+		commitOpts = append(commitOpts, content.WithLabels(copts.labels))
+	}
+	if err := writer.Commit(ctx, cw.n, "", commitOpts...); err != nil {
+		if !errdefs.IsAlreadyExists(err) {
+			return ocispec.Descriptor{}, err
+		}
+	}
+	return ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    writer.Digest(),
+		Size:      cw.n,
+	}, nil
+}
+
+// writeDeterministicTar archives root into tw with reproducible metadata:
+// lexical entry order (as guaranteed by filepath.WalkDir), epoch
+// timestamps, uid/gid zero and no user or group names.
+func writeDeterministicTar(tw *tar.Writer, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			// Sockets and other special files do not belong in a layer.
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.ModTime = time.Unix(0, 0)
+		hdr.AccessTime = time.Time{}
+		hdr.ChangeTime = time.Time{}
+		hdr.Uid, hdr.Gid = 0, 0
+		hdr.Uname, hdr.Gname = "", ""
+		hdr.Format = tar.FormatPAX
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
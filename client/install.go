@@ -24,7 +24,11 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
 
 	"github.com/basuotian/containerd/core/content"
 	"github.com/basuotian/containerd/core/images"
@@ -39,6 +43,9 @@ func (c *Client) Install(ctx context.Context, image Image, opts ...InstallOpts)
 	for _, o := range opts {
 		o(&config)
 	}
+	if config.Upgrade {
+		config.Replace = true
+	}
 	path, err := c.getInstallPath(ctx, config)
 	if err != nil {
 		return err
@@ -52,6 +59,32 @@ func (c *Client) Install(ctx context.Context, image Image, opts ...InstallOpts)
 		return err
 	}
 
+	// load the manifests of already installed packages so files owned by
+	// another package are never clobbered and an upgrade knows what the
+	// previous version installed
+	packages, err := c.installedPackages(ctx)
+	if err != nil {
+		return err
+	}
+	var (
+		prev   *installedPackage
+		owners = map[string]string{}
+	)
+	for i, p := range packages {
+		if p.Path != path {
+			continue
+		}
+		if p.Ref == image.Name() {
+			prev = &packages[i]
+			continue
+		}
+		for _, f := range p.Files {
+			owners[f.Name] = p.Ref
+		}
+	}
+
+	installed := map[string]struct{}{}
+
 	var binDir, libDir string
 	if runtime.GOOS == "windows" {
 		binDir = "Files\\bin"
@@ -83,9 +116,17 @@ func (c *Client) Install(ctx context.Context, image Image, opts ...InstallOpts)
 			if runtime.GOOS == "windows" {
 				hdr.Name = strings.Replace(hdr.Name, "Files", "", 1)
 			}
-			if result && !config.Replace {
-				if _, err := os.Lstat(filepath.Join(path, hdr.Name)); err == nil {
-					return false, fmt.Errorf("cannot replace %s in %s", hdr.Name, path)
+			if result {
+				if owner, ok := owners[hdr.Name]; ok {
+					return false, fmt.Errorf("cannot replace %s in %s: owned by package %s", hdr.Name, path, owner)
+				}
+				if !config.Replace {
+					if _, err := os.Lstat(filepath.Join(path, hdr.Name)); err == nil {
+						return false, fmt.Errorf("cannot replace %s in %s", hdr.Name, path)
+					}
+				}
+				if hdr.Typeflag != tar.TypeDir {
+					installed[hdr.Name] = struct{}{}
 				}
 			}
 			return result, nil
@@ -105,6 +146,51 @@ func (c *Client) Install(ctx context.Context, image Image, opts ...InstallOpts)
 		r.Close()
 		ra.Close()
 	}
+
+	// record what was installed so the package can be verified, upgraded
+	// and removed again
+	pkg := InstallManifest{
+		Ref:         image.Name(),
+		ImageDigest: image.Target().Digest,
+		InstalledAt: time.Now().UTC(),
+		Path:        path,
+		Files:       make([]InstallFile, 0, len(installed)),
+	}
+	names := make([]string, 0, len(installed))
+	for name := range installed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := InstallFile{Name: name}
+		full := filepath.Join(path, name)
+		if fi, err := os.Lstat(full); err == nil && fi.Mode().IsRegular() {
+			if f.Digest, err = fileDigest(full); err != nil {
+				return err
+			}
+		}
+		pkg.Files = append(pkg.Files, f)
+	}
+	if err := c.writeInstallManifest(ctx, pkg); err != nil {
+		return err
+	}
+
+	if prev != nil {
+		// on upgrade, drop files the new version no longer ships
+		if config.Upgrade {
+			for _, f := range prev.Files {
+				if _, ok := installed[f.Name]; ok {
+					continue
+				}
+				if err := removeInstalledFile(ctx, path, f); err != nil {
+					return err
+				}
+			}
+		}
+		if err := c.ContentStore().Delete(ctx, prev.blob); err != nil && !errdefs.IsNotFound(err) {
+			return fmt.Errorf("failed to remove previous install manifest: %w", err)
+		}
+	}
 	return nil
 }
 
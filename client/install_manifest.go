@@ -0,0 +1,211 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/core/content"
+)
+
+const (
+	// installManifestMediaType is the media type of the package manifest
+	// blob recorded in the content store for every install
+	installManifestMediaType = "application/vnd.containerd.install.manifest.v1+json"
+
+	// installRefLabel marks a content blob as the install manifest of the
+	// named package so it can be found again
+	installRefLabel = "containerd.io/install.ref"
+
+	// installGCRootLabel pins the manifest blob so the garbage collector
+	// does not remove it while the package is installed
+	installGCRootLabel = "containerd.io/gc.root"
+)
+
+// InstallManifest records what an installed package placed into the opt
+// directory, so the package can be verified, upgraded and removed again.
+type InstallManifest struct {
+	// Ref is the image the package was installed from.
+	Ref string `json:"ref"`
+
+	// ImageDigest is the digest of the source image at install time.
+	ImageDigest digest.Digest `json:"imageDigest"`
+
+	// InstalledAt is when the package was installed.
+	InstalledAt time.Time `json:"installedAt"`
+
+	// Path is the directory the package was installed to.
+	Path string `json:"path"`
+
+	// Files are the entries the package installed, relative to Path.
+	Files []InstallFile `json:"files"`
+}
+
+// InstallFile is one entry installed by a package.
+type InstallFile struct {
+	// Name is the path of the entry relative to the install directory.
+	Name string `json:"name"`
+
+	// Digest is the digest of the file content at install time. It is
+	// empty for entries which are not regular files, such as symlinks.
+	Digest digest.Digest `json:"digest,omitempty"`
+}
+
+// installedPackage pairs a manifest with the content blob it is stored in.
+type installedPackage struct {
+	InstallManifest
+	blob digest.Digest
+}
+
+// installedPackages returns the manifests of all installed packages.
+func (c *Client) installedPackages(ctx context.Context) ([]installedPackage, error) {
+	var (
+		cs  = c.ContentStore()
+		out []installedPackage
+	)
+	if err := cs.Walk(ctx, func(info content.Info) error {
+		ra, err := cs.ReaderAt(ctx, ocispec.Descriptor{Digest: info.Digest, Size: info.Size})
+		if err != nil {
+			return err
+		}
+		defer ra.Close()
+		data, err := io.ReadAll(content.NewReader(ra))
+		if err != nil {
+			return err
+		}
+		var m InstallManifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("failed to parse install manifest %v: %w", info.Digest, err)
+		}
+		out = append(out, installedPackage{
+			InstallManifest: m,
+			blob:            info.Digest,
+		})
+		return nil
+	}, fmt.Sprintf(`labels.%q`, installRefLabel)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// writeInstallManifest stores a package manifest in the content store,
+// pinned against garbage collection.
+func (c *Client) writeInstallManifest(ctx context.Context, m InstallManifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	dgst := digest.FromBytes(data)
+	return content.WriteBlob(ctx, c.ContentStore(), "install-manifest-"+dgst.String(), bytes.NewReader(data),
+		ocispec.Descriptor{
+			MediaType: installManifestMediaType,
+			Digest:    dgst,
+			Size:      int64(len(data)),
+		},
+		content.WithLabels(map[string]string{
+			installRefLabel:    m.Ref,
+			installGCRootLabel: m.InstalledAt.Format(time.RFC3339),
+		}))
+}
+
+// Uninstall removes a package previously installed with Install. Every file
+// is checked against the digest recorded at install time and left in place
+// with a warning when it was modified since; the package manifest is removed
+// in any case.
+func (c *Client) Uninstall(ctx context.Context, ref string, opts ...InstallOpts) error {
+	var config InstallConfig
+	for _, o := range opts {
+		o(&config)
+	}
+	packages, err := c.installedPackages(ctx)
+	if err != nil {
+		return err
+	}
+	var found bool
+	for _, p := range packages {
+		if p.Ref != ref {
+			continue
+		}
+		if config.Path != "" && p.Path != config.Path {
+			continue
+		}
+		found = true
+		for _, f := range p.Files {
+			if err := removeInstalledFile(ctx, p.Path, f); err != nil {
+				return err
+			}
+		}
+		if err := c.ContentStore().Delete(ctx, p.blob); err != nil && !errdefs.IsNotFound(err) {
+			return fmt.Errorf("failed to remove install manifest: %w", err)
+		}
+	}
+	if !found {
+		return fmt.Errorf("no install manifest for %q: %w", ref, errdefs.ErrNotFound)
+	}
+	return nil
+}
+
+// removeInstalledFile removes one installed entry after verifying it still
+// matches the digest recorded at install time. Modified or missing entries
+// are left alone with a warning.
+func removeInstalledFile(ctx context.Context, root string, f InstallFile) error {
+	full := filepath.Join(root, f.Name)
+	fi, err := os.Lstat(full)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if f.Digest != "" {
+		if !fi.Mode().IsRegular() {
+			log.G(ctx).Warnf("%s is no longer a regular file, leaving it in place", full)
+			return nil
+		}
+		dgst, err := fileDigest(full)
+		if err != nil {
+			return err
+		}
+		if dgst != f.Digest {
+			log.G(ctx).Warnf("%s was modified since install, leaving it in place", full)
+			return nil
+		}
+	}
+	return os.Remove(full)
+}
+
+// fileDigest returns the digest of the file content at path.
+func fileDigest(path string) (digest.Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return digest.FromReader(f)
+}
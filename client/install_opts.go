@@ -25,6 +25,9 @@ type InstallConfig struct {
 	Libs bool
 	// Replace will overwrite existing binaries or libs in the opt directory
 	Replace bool
+	// Upgrade replaces the files of a previous install of the same package
+	// and removes files the new version no longer ships
+	Upgrade bool
 	// Path to install libs and binaries to
 	Path string
 }
@@ -39,6 +42,11 @@ func WithInstallReplace(c *InstallConfig) {
 	c.Replace = true
 }
 
+// WithInstallUpgrade upgrades a previous install of the same package
+func WithInstallUpgrade(c *InstallConfig) {
+	c.Upgrade = true
+}
+
 // WithInstallPath sets the optional install path
 func WithInstallPath(path string) InstallOpts {
 	return func(c *InstallConfig) {
@@ -0,0 +1,30 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"github.com/basuotian/containerd/pkg/cio"
+)
+
+// WithRemoteConsole makes cio.NewCreator carry the task console over the
+// streaming service of the daemon instead of fifos on a shared
+// filesystem, so clients connected over the network, for example through
+// a tcp address, get fully interactive IO. The daemon bridges the
+// streams to local fifos before handing them to the shim.
+func (c *Client) WithRemoteConsole() cio.Opt {
+	return cio.WithStreamCreator(c.streamCreator())
+}
@@ -0,0 +1,378 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+	ver "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/core/containers"
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/images"
+	api "github.com/basuotian/containerd/core/sandbox"
+	"github.com/basuotian/containerd/pkg/oci"
+	ptypes "github.com/basuotian/containerd/pkg/protobuf/types"
+)
+
+const (
+	// checkpointPodSandboxLabel is the pod index annotation recording the
+	// checkpointed sandbox ID
+	checkpointPodSandboxLabel = "io.containerd.checkpoint.pod.sandbox"
+	// checkpointPodContainerLabel is the per-manifest annotation recording
+	// which container a checkpoint in the pod index belongs to
+	checkpointPodContainerLabel = "io.containerd.checkpoint.pod.container"
+)
+
+// podSandboxCheckpoint is the sandbox metadata blob stored in a pod
+// checkpoint so the sandbox can be recreated on restore.
+type podSandboxCheckpoint struct {
+	ID             string            `json:"id"`
+	Sandboxer      string            `json:"sandboxer,omitempty"`
+	Runtime        string            `json:"runtime"`
+	RuntimeOptions *anyPayload       `json:"runtimeOptions,omitempty"`
+	Spec           *anyPayload       `json:"spec,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+}
+
+// anyPayload is a JSON-friendly carrier for a typeurl.Any.
+type anyPayload struct {
+	TypeURL string `json:"typeUrl"`
+	Value   []byte `json:"value,omitempty"`
+}
+
+func toAnyPayload(a typeurl.Any) *anyPayload {
+	if a == nil {
+		return nil
+	}
+	return &anyPayload{
+		TypeURL: a.GetTypeUrl(),
+		Value:   a.GetValue(),
+	}
+}
+
+func (p *anyPayload) any() typeurl.Any {
+	if p == nil {
+		return nil
+	}
+	return &ptypes.Any{
+		TypeUrl: p.TypeURL,
+		Value:   p.Value,
+	}
+}
+
+// CheckpointPod checkpoints a sandbox together with every container that
+// belongs to it into a single OCI index referenced by ref. All running tasks
+// are paused before the first container is checkpointed so the pod is
+// captured in one consistent cut, and containers are processed in a stable
+// order (sorted by ID). Each container is checkpointed in the regular
+// container checkpoint format, so opts accepts the same CheckpointOpts as
+// Container.Checkpoint and the recorded checkpoints restore individually as
+// well as together.
+func (c *Client) CheckpointPod(ctx context.Context, sandboxID, ref string, opts ...CheckpointOpts) (Image, error) {
+	sb, err := c.SandboxStore().Get(ctx, sandboxID)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := c.Containers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var members []Container
+	for _, ctr := range all {
+		info, err := ctr.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if info.SandboxID == sandboxID {
+			members = append(members, ctr)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID() < members[j].ID()
+	})
+
+	ctx, done, err := c.WithLease(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done(ctx)
+
+	// pause every running task up front so no container sees the pod in a
+	// state newer than another container's checkpoint
+	var paused []Task
+	defer func() {
+		for _, t := range paused {
+			if err := t.Resume(ctx); err != nil {
+				log.G(ctx).WithError(err).Warnf("failed to resume task %q after pod checkpoint", t.ID())
+			}
+		}
+	}()
+	for _, ctr := range members {
+		task, err := ctr.Task(ctx, nil)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		st, err := task.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if st.Status == Running {
+			if err := task.Pause(ctx); err != nil {
+				return nil, fmt.Errorf("failed to pause task %q: %w", ctr.ID(), err)
+			}
+			paused = append(paused, task)
+		}
+	}
+
+	index := &ocispec.Index{
+		Versioned: ver.Versioned{
+			SchemaVersion: 2,
+		},
+		Annotations: map[string]string{
+			checkpointPodSandboxLabel: sandboxID,
+		},
+	}
+
+	meta := podSandboxCheckpoint{
+		ID:             sb.ID,
+		Sandboxer:      sb.Sandboxer,
+		Runtime:        sb.Runtime.Name,
+		RuntimeOptions: toAnyPayload(sb.Runtime.Options),
+		Spec:           toAnyPayload(sb.Spec),
+		Labels:         sb.Labels,
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := writeContent(ctx, c.ContentStore(), images.MediaTypeContainerd1PodCheckpointSandbox, ref+"-sandbox", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	index.Manifests = append(index.Manifests, desc)
+
+	for _, ctr := range members {
+		tmpRef := fmt.Sprintf("%s-%s", ref, ctr.ID())
+		cp, err := ctr.Checkpoint(ctx, tmpRef, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to checkpoint container %q: %w", ctr.ID(), err)
+		}
+		target := cp.Target()
+		if target.Annotations == nil {
+			target.Annotations = make(map[string]string)
+		}
+		target.Annotations[checkpointPodContainerLabel] = ctr.ID()
+		index.Manifests = append(index.Manifests, target)
+		// the temporary image record was only needed while taking the
+		// container checkpoint, the content is held by the lease and will
+		// be referenced by the pod index
+		if err := c.ImageService().Delete(ctx, tmpRef); err != nil && !errdefs.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	podDesc, err := writeIndex(ctx, index, c, sandboxID+"-pod-index")
+	if err != nil {
+		return nil, err
+	}
+	img, err := c.ImageService().Create(ctx, images.Image{
+		Name:   ref,
+		Target: podDesc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewImage(c, img), nil
+}
+
+// RestorePod recreates a sandbox and the containers recorded in a pod
+// checkpoint taken by CheckpointPod. The sandbox is created and started
+// first so restored containers can rejoin its shared namespaces: namespace
+// paths in the restored container specs which pointed into the checkpointed
+// sandbox process are rewritten to the new one. The given RestoreOpts are
+// applied to every container, matching Client.Restore. On error every
+// resource created so far is removed again.
+func (c *Client) RestorePod(ctx context.Context, sandboxID string, checkpoint Image, opts ...RestoreOpts) (_ Sandbox, _ []Container, retErr error) {
+	index, err := decodeIndex(ctx, c.ContentStore(), checkpoint.Target())
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := GetIndexByMediaType(index, images.MediaTypeContainerd1PodCheckpointSandbox)
+	if err != nil {
+		if err == ErrMediaTypeNotFound {
+			return nil, nil, fmt.Errorf("%q is not a pod checkpoint: %w", checkpoint.Name(), errdefs.ErrInvalidArgument)
+		}
+		return nil, nil, err
+	}
+	data, err := content.ReadBlob(ctx, c.ContentStore(), *m)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read pod checkpoint sandbox metadata: %w", err)
+	}
+	var meta podSandboxCheckpoint
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, nil, err
+	}
+
+	options := meta.RuntimeOptions.any()
+	if options == nil {
+		if options, err = typeurl.MarshalAny(&ptypes.Empty{}); err != nil {
+			return nil, nil, err
+		}
+	}
+	sb, err := c.NewSandbox(ctx, sandboxID, func(ctx context.Context, client *Client, s *api.Sandbox) error {
+		s.Sandboxer = meta.Sandboxer
+		s.Runtime = api.RuntimeOpts{
+			Name:    meta.Runtime,
+			Options: options,
+		}
+		s.Spec = meta.Spec.any()
+		s.Labels = meta.Labels
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create sandbox: %w", err)
+	}
+
+	var restored []Container
+	defer func() {
+		if retErr == nil {
+			return
+		}
+		for _, ctr := range restored {
+			if err := ctr.Delete(ctx, WithSnapshotCleanup); err != nil {
+				log.G(ctx).WithError(err).Warnf("failed to clean up restored container %q", ctr.ID())
+			}
+		}
+		if err := sb.Stop(ctx); err != nil {
+			log.G(ctx).WithError(err).Warnf("failed to stop sandbox %q", sandboxID)
+		}
+		if err := sb.Shutdown(ctx); err != nil {
+			log.G(ctx).WithError(err).Warnf("failed to clean up sandbox %q", sandboxID)
+		}
+	}()
+
+	if err := sb.Start(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to start sandbox: %w", err)
+	}
+
+	// the namespace paths of the restored containers have to point at the
+	// new sandbox process
+	var pid uint32
+	if status, err := c.SandboxController(meta.Sandboxer).Status(ctx, sandboxID, false); err != nil {
+		log.G(ctx).WithError(err).Warnf("cannot determine pid of sandbox %q, namespace paths are restored unchanged", sandboxID)
+	} else {
+		pid = status.Pid
+	}
+
+	for _, m := range index.Manifests {
+		id, ok := m.Annotations[checkpointPodContainerLabel]
+		if !ok {
+			continue
+		}
+		child := NewImage(c, images.Image{
+			Name:   fmt.Sprintf("%s@%s", checkpoint.Name(), id),
+			Target: m,
+		})
+		// the sandbox opt must run after the caller's opts so it sees the
+		// spec placed by WithRestoreSpec
+		copts := append(append([]RestoreOpts{}, opts...), withPodSandbox(sandboxID, pid))
+		ctr, err := c.Restore(ctx, id, child, copts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to restore container %q: %w", id, err)
+		}
+		restored = append(restored, ctr)
+	}
+
+	return sb, restored, nil
+}
+
+// PodContainerCheckpoints returns the per-container checkpoints recorded in
+// a pod checkpoint, keyed by container ID. The returned images share the pod
+// checkpoint content and use the regular container checkpoint format, so
+// they can be passed to WithTaskCheckpoint to restore the tasks as well.
+func (c *Client) PodContainerCheckpoints(ctx context.Context, checkpoint Image) (map[string]Image, error) {
+	index, err := decodeIndex(ctx, c.ContentStore(), checkpoint.Target())
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]Image)
+	for _, m := range index.Manifests {
+		if id, ok := m.Annotations[checkpointPodContainerLabel]; ok {
+			out[id] = NewImage(c, images.Image{
+				Name:   fmt.Sprintf("%s@%s", checkpoint.Name(), id),
+				Target: m,
+			})
+		}
+	}
+	return out, nil
+}
+
+// withPodSandbox attaches a restored container to the new sandbox and
+// rewrites namespace paths which pointed into the old sandbox process.
+func withPodSandbox(sandboxID string, pid uint32) RestoreOpts {
+	return func(ctx context.Context, id string, client *Client, checkpoint Image, index *ocispec.Index) NewContainerOpts {
+		return func(ctx context.Context, client *Client, c *containers.Container) error {
+			c.SandboxID = sandboxID
+			if c.Spec == nil || pid == 0 {
+				return nil
+			}
+			var spec oci.Spec
+			if err := json.Unmarshal(c.Spec.GetValue(), &spec); err != nil {
+				return fmt.Errorf("failed to parse restored spec: %w", err)
+			}
+			if spec.Linux == nil {
+				return nil
+			}
+			changed := false
+			for i, ns := range spec.Linux.Namespaces {
+				// namespaces shared with the sandbox are joined via
+				// /proc/<sandbox pid>/ns/<type>
+				if !strings.HasPrefix(ns.Path, "/proc/") || !strings.Contains(ns.Path, "/ns/") {
+					continue
+				}
+				spec.Linux.Namespaces[i].Path = fmt.Sprintf("/proc/%d/ns/%s", pid, ns.Type)
+				changed = true
+			}
+			if !changed {
+				return nil
+			}
+			data, err := json.Marshal(spec)
+			if err != nil {
+				return err
+			}
+			c.Spec = &ptypes.Any{
+				TypeUrl: c.Spec.GetTypeUrl(),
+				Value:   data,
+			}
+			return nil
+		}
+	}
+}
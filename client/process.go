@@ -71,6 +71,7 @@ type ExitStatus struct {
 	code     uint32
 	exitedAt time.Time
 	err      error
+	usage    *ResourceUsageSummary
 }
 
 // Result returns the exit code and time of the exit status.
@@ -102,6 +103,13 @@ func (s ExitStatus) Error() error {
 	return s.err
 }
 
+// Usage returns the final resource usage summary sampled before deletion.
+// It is only set when a task was deleted with WithResourceUsageSummary and
+// is nil otherwise.
+func (s ExitStatus) Usage() *ResourceUsageSummary {
+	return s.usage
+}
+
 type process struct {
 	id   string
 	task *task
@@ -0,0 +1,188 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/images"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/platforms"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// PrunePlatforms removes the platforms of a multi-arch image that keep does
+// not match. The image's index is rewritten to reference only the kept
+// manifests and content blobs reachable solely from the removed platforms
+// are deleted; blobs shared with the kept platforms or with any other image
+// are left alone. Manifest entries without a platform, such as attestation
+// manifests, are always kept. The updated image and the descriptors of the
+// deleted blobs are returned. Pruning an index whose platforms all match
+// keep is a no-op; pruning away every platform is rejected.
+func (c *Client) PrunePlatforms(ctx context.Context, ref string, keep platforms.MatchComparer) (images.Image, []ocispec.Descriptor, error) {
+	if keep == nil {
+		return images.Image{}, nil, fmt.Errorf("no platforms to keep: %w", errdefs.ErrInvalidArgument)
+	}
+
+	var (
+		is = c.ImageService()
+		cs = c.ContentStore()
+	)
+	img, err := is.Get(ctx, ref)
+	if err != nil {
+		return images.Image{}, nil, err
+	}
+	if !images.IsIndexType(img.Target.MediaType) {
+		return images.Image{}, nil, fmt.Errorf("image %q with media type %q is not a multi-platform image: %w", ref, img.Target.MediaType, errdefs.ErrInvalidArgument)
+	}
+
+	index, err := decodeIndex(ctx, cs, img.Target)
+	if err != nil {
+		return images.Image{}, nil, fmt.Errorf("failed to decode index of %q: %w", ref, err)
+	}
+
+	var kept, removed []ocispec.Descriptor
+	for _, m := range index.Manifests {
+		if m.Platform == nil || keep.Match(*m.Platform) {
+			kept = append(kept, m)
+		} else {
+			removed = append(removed, m)
+		}
+	}
+	if len(removed) == 0 {
+		return img, nil, nil
+	}
+	if len(kept) == 0 {
+		return images.Image{}, nil, fmt.Errorf("pruning would remove every platform of %q: %w", ref, errdefs.ErrInvalidArgument)
+	}
+
+	ctx, done, err := c.WithLease(ctx)
+	if err != nil {
+		return images.Image{}, nil, err
+	}
+	defer done(ctx)
+
+	// Rewrite the index with only the kept manifests, preserving the
+	// index-level media type and annotations.
+	oldTarget := img.Target
+	index.Manifests = kept
+	mediaType := index.MediaType
+	if mediaType == "" {
+		mediaType = oldTarget.MediaType
+	}
+
+	// Label the manifests as children of the index so they are retained
+	// by the garbage collector.
+	labels := map[string]string{}
+	for i, m := range kept {
+		labels[fmt.Sprintf("containerd.io/gc.ref.content.%d", i)] = m.Digest.String()
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return images.Image{}, nil, err
+	}
+	cref := fmt.Sprintf("prune-index-%s", digest.SHA256.FromBytes(data))
+	desc, err := writeContent(ctx, cs, mediaType, cref, bytes.NewReader(data), content.WithLabels(labels))
+	if err != nil {
+		return images.Image{}, nil, err
+	}
+	desc.Annotations = oldTarget.Annotations
+
+	img.Target = desc
+	img, err = is.Update(ctx, img, "target")
+	if err != nil {
+		return images.Image{}, nil, err
+	}
+
+	// Everything reachable from the kept manifests or from another image
+	// must survive; anything else under the removed manifests or the old
+	// index is safe to delete.
+	referenced := map[digest.Digest]ocispec.Descriptor{desc.Digest: desc}
+	for _, m := range kept {
+		if err := collectBlobs(ctx, cs, m, referenced); err != nil {
+			return img, nil, err
+		}
+	}
+	others, err := is.List(ctx)
+	if err != nil {
+		return img, nil, err
+	}
+	for _, other := range others {
+		if other.Name == img.Name {
+			continue
+		}
+		referenced[other.Target.Digest] = other.Target
+		if err := collectBlobs(ctx, cs, other.Target, referenced); err != nil {
+			return img, nil, err
+		}
+	}
+
+	candidates := map[digest.Digest]ocispec.Descriptor{oldTarget.Digest: oldTarget}
+	for _, m := range removed {
+		if err := collectBlobs(ctx, cs, m, candidates); err != nil {
+			return img, nil, err
+		}
+	}
+
+	var deleted []ocispec.Descriptor
+	for dgst, d := range candidates {
+		if _, ok := referenced[dgst]; ok {
+			continue
+		}
+		if err := cs.Delete(ctx, dgst); err != nil {
+			if errdefs.IsNotFound(err) {
+				continue
+			}
+			return img, deleted, fmt.Errorf("failed to delete blob %s: %w", dgst, err)
+		}
+		deleted = append(deleted, d)
+	}
+	sort.Slice(deleted, func(i, j int) bool {
+		return deleted[i].Digest < deleted[j].Digest
+	})
+
+	return img, deleted, nil
+}
+
+// collectBlobs records desc and every blob reachable from it. Children
+// whose content is missing locally, for example platforms of a sparsely
+// pulled image, are skipped.
+func collectBlobs(ctx context.Context, provider content.Provider, desc ocispec.Descriptor, out map[digest.Digest]ocispec.Descriptor) error {
+	if _, ok := out[desc.Digest]; ok {
+		return nil
+	}
+	out[desc.Digest] = desc
+	children, err := images.Children(ctx, provider, desc)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	for _, child := range children {
+		if err := collectBlobs(ctx, provider, child, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
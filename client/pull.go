@@ -27,8 +27,10 @@ import (
 	"github.com/basuotian/containerd/core/images"
 	"github.com/basuotian/containerd/core/remotes"
 	"github.com/basuotian/containerd/core/remotes/docker"
+	"github.com/basuotian/containerd/core/remotes/docker/schema1"
 	"github.com/basuotian/containerd/core/transfer"
 	"github.com/basuotian/containerd/core/unpack"
+	"github.com/basuotian/containerd/pkg/namespaces"
 	"github.com/basuotian/containerd/pkg/tracing"
 	"github.com/containerd/errdefs"
 	"github.com/containerd/platforms"
@@ -44,6 +46,13 @@ func (c *Client) Pull(ctx context.Context, ref string, opts ...RemoteOpt) (_ Ima
 	ctx, span := tracing.StartSpan(ctx, tracing.Name(pullSpanPrefix, "Pull"))
 	defer span.End()
 
+	// Carry the image reference as baggage so daemon side spans caused by
+	// this pull share the same attributes.
+	if ns, err := namespaces.NamespaceRequired(ctx); err == nil {
+		ctx = tracing.WithBaggage(ctx, tracing.Attribute("containerd.namespace", ns))
+	}
+	ctx = tracing.WithBaggage(ctx, tracing.Attribute("image.ref", ref))
+
 	pullCtx := defaultRemoteContext()
 
 	for _, o := range opts {
@@ -113,7 +122,7 @@ func (c *Client) Pull(ctx context.Context, ref string, opts ...RemoteOpt) (_ Ima
 			Platform:       platformMatcher,
 			SnapshotterKey: snapshotterName,
 			Snapshotter:    c.SnapshotService(snapshotterName),
-			SnapshotOpts:   append(pullCtx.SnapshotterOpts, uconfig.SnapshotOpts...),
+			SnapshotOpts:   append(append(snapshotterLabelOpts(pullCtx.Labels), pullCtx.SnapshotterOpts...), uconfig.SnapshotOpts...),
 			Applier:        c.DiffService(),
 			ApplyOpts:      uconfig.ApplyOpts,
 		}
@@ -201,9 +210,37 @@ func (c *Client) fetch(ctx context.Context, rCtx *RemoteContext, ref string, lim
 		limiter       *semaphore.Weighted
 	)
 	if desc.MediaType == images.MediaTypeDockerSchema1Manifest {
-		return images.Image{}, fmt.Errorf("%w: media type %q is no longer supported since containerd v2.1, please rebuild the image as %q or %q",
-			errdefs.ErrNotImplemented,
-			images.MediaTypeDockerSchema1Manifest, images.MediaTypeDockerSchema2Manifest, ocispec.MediaTypeImageManifest)
+		if !rCtx.ConvertSchema1 {
+			return images.Image{}, fmt.Errorf("%w: media type %q is no longer supported since containerd v2.1, please rebuild the image as %q or %q",
+				errdefs.ErrNotImplemented,
+				images.MediaTypeDockerSchema1Manifest, images.MediaTypeDockerSchema2Manifest, ocispec.MediaTypeImageManifest)
+		}
+		// The converter fetches the schema 1 manifest and its layers
+		// itself and synthesizes the OCI manifest once dispatch is done.
+		schema1Converter := schema1.NewConverter(store, fetcher)
+		handler = images.Handlers(append(rCtx.BaseHandlers, images.HandlerFunc(schema1Converter.Handle))...)
+		isConvertible = true
+		converterFunc = func(ctx context.Context, _ ocispec.Descriptor) (ocispec.Descriptor, error) {
+			return schema1Converter.Convert(ctx)
+		}
+
+		if rCtx.HandlerWrapper != nil {
+			handler = rCtx.HandlerWrapper(handler)
+		}
+
+		if err := images.Dispatch(ctx, handler, limiter, desc); err != nil {
+			return images.Image{}, err
+		}
+
+		if desc, err = converterFunc(ctx, desc); err != nil {
+			return images.Image{}, err
+		}
+
+		return images.Image{
+			Name:   name,
+			Target: desc,
+			Labels: rCtx.Labels,
+		}, nil
 	}
 	// Get all the children for a descriptor
 	childrenHandler := images.ChildrenHandler(store)
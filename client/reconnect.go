@@ -0,0 +1,162 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultReconnectAttempts = 5
+	defaultReconnectBackoff  = 100 * time.Millisecond
+	maxReconnectBackoff      = 2 * time.Second
+)
+
+// reconnector retries idempotent requests which failed because the
+// connection to the daemon was lost, for example across a containerd
+// restart. The underlying grpc channel re-establishes the connection
+// itself, the interceptor waits for it and replays the request.
+type reconnector struct {
+	attempts int
+	backoff  time.Duration
+	notify   func(method string, attempt int, err error)
+}
+
+// ReconnectOpt allows callers to set options on the reconnect behavior
+type ReconnectOpt func(*reconnector)
+
+// WithReconnectAttempts sets how often a failed idempotent request is
+// replayed before its error is returned to the caller
+func WithReconnectAttempts(attempts int) ReconnectOpt {
+	return func(r *reconnector) {
+		r.attempts = attempts
+	}
+}
+
+// WithReconnectBackoff sets the delay before the first replay of a
+// failed request. The delay doubles with every further attempt.
+func WithReconnectBackoff(backoff time.Duration) ReconnectOpt {
+	return func(r *reconnector) {
+		r.backoff = backoff
+	}
+}
+
+// WithReconnectNotify registers a callback invoked before every replay
+// with the failing method, the upcoming attempt and the error which
+// triggered it, so callers can observe daemon restarts
+func WithReconnectNotify(notify func(method string, attempt int, err error)) ReconnectOpt {
+	return func(r *reconnector) {
+		r.notify = notify
+	}
+}
+
+// WithReconnect makes the client transparently retry idempotent requests
+// which failed because the daemon was unavailable, such as during a
+// containerd restart. Requests which may have side effects are never
+// replayed and fail as before. Event subscriptions are re-established by
+// retrying the stream creation, messages sent while disconnected are lost.
+func WithReconnect(opts ...ReconnectOpt) Opt {
+	return func(c *clientOpts) error {
+		r := &reconnector{
+			attempts: defaultReconnectAttempts,
+			backoff:  defaultReconnectBackoff,
+		}
+		for _, o := range opts {
+			o(r)
+		}
+		c.extraDialOpts = append(c.extraDialOpts,
+			grpc.WithChainUnaryInterceptor(r.unary),
+			grpc.WithChainStreamInterceptor(r.stream),
+		)
+		return nil
+	}
+}
+
+func (r *reconnector) unary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if err == nil || !retriable(err) || !idempotent(method) {
+		return err
+	}
+	for attempt := 1; attempt <= r.attempts; attempt++ {
+		if err = r.wait(ctx, cc, method, attempt, err); err != nil {
+			return err
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil || !retriable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (r *reconnector) stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err == nil || !retriable(err) || !idempotent(method) {
+		return s, err
+	}
+	for attempt := 1; attempt <= r.attempts; attempt++ {
+		if err = r.wait(ctx, cc, method, attempt, err); err != nil {
+			return nil, err
+		}
+		s, err = streamer(ctx, desc, cc, method, opts...)
+		if err == nil || !retriable(err) {
+			return s, err
+		}
+	}
+	return nil, err
+}
+
+// wait notifies the caller, kicks the channel out of its connection
+// backoff and sleeps before the next attempt
+func (r *reconnector) wait(ctx context.Context, cc *grpc.ClientConn, method string, attempt int, cause error) error {
+	if r.notify != nil {
+		r.notify(method, attempt, cause)
+	}
+	cc.Connect()
+	backoff := min(r.backoff<<(attempt-1), maxReconnectBackoff)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(backoff):
+		return nil
+	}
+}
+
+// retriable reports whether the error indicates the daemon could not be
+// reached, rather than the request being handled and rejected
+func retriable(err error) bool {
+	return status.Code(err) == codes.Unavailable
+}
+
+// idempotent reports whether the method only reads state or waits on it,
+// so replaying it after a connection loss cannot repeat a side effect.
+// Wait and Subscribe re-subscribe to the state they observe.
+func idempotent(method string) bool {
+	name := method[strings.LastIndex(method, "/")+1:]
+	switch name {
+	case "Wait", "Status", "Version", "Check", "Info", "Usage", "Stat",
+		"Plugins", "Server", "Fields", "Metrics", "Pids", "Subscribe":
+		return true
+	}
+	return strings.HasPrefix(name, "Get") || strings.HasPrefix(name, "List")
+}
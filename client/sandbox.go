@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
 	"github.com/containerd/typeurl/v2"
 
 	"github.com/basuotian/containerd/core/containers"
@@ -83,6 +84,7 @@ func (s *sandboxClient) Start(ctx context.Context) error {
 		return err
 	}
 
+	s.setStateLabel(ctx, api.StateRunning)
 	return nil
 }
 
@@ -110,7 +112,22 @@ func (s *sandboxClient) Wait(ctx context.Context) (<-chan ExitStatus, error) {
 }
 
 func (s *sandboxClient) Stop(ctx context.Context) error {
-	return s.client.SandboxController(s.metadata.Sandboxer).Stop(ctx, s.ID())
+	if err := s.client.SandboxController(s.metadata.Sandboxer).Stop(ctx, s.ID()); err != nil {
+		return err
+	}
+
+	s.setStateLabel(ctx, api.StateStopped)
+	return nil
+}
+
+// setStateLabel records the last observed lifecycle state in the sandbox
+// store so sandboxes can be listed by state. The controller remains the
+// source of truth, so failures here are only logged.
+func (s *sandboxClient) setStateLabel(ctx context.Context, state string) {
+	s.metadata.AddLabel(api.StateLabel, state)
+	if _, err := s.client.SandboxStore().Update(ctx, s.metadata, "labels."+api.StateLabel); err != nil {
+		log.G(ctx).WithError(err).Warnf("failed to record state %q of sandbox %s", state, s.ID())
+	}
 }
 
 func (s *sandboxClient) Shutdown(ctx context.Context) error {
@@ -143,6 +160,10 @@ func (c *Client) NewSandbox(ctx context.Context, sandboxID string, opts ...NewSa
 		}
 	}
 
+	if _, ok := newSandbox.Labels[api.StateLabel]; !ok {
+		newSandbox.AddLabel(api.StateLabel, api.StateCreated)
+	}
+
 	metadata, err := c.SandboxStore().Create(ctx, newSandbox)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,126 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/basuotian/containerd/core/snapshots"
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/image-spec/identity"
+)
+
+// SnapshotterLabelPrefix is the image label prefix for labels that are
+// propagated to snapshots created from the image. An image label
+//
+//	containerd.io/snapshot-label/team=infra
+//
+// puts a "team=infra" label on every snapshot prepared while unpacking the
+// image and on container snapshots created from it, which can be used for
+// provenance, ownership or GC tiering without touching each snapshot by
+// hand.
+const SnapshotterLabelPrefix = "containerd.io/snapshot-label/"
+
+// WithSnapshotterLabels records labels on the pulled image that are applied
+// to every snapshot created from it, see [SnapshotterLabelPrefix].
+func WithSnapshotterLabels(labels map[string]string) RemoteOpt {
+	return func(_ *Client, rc *RemoteContext) error {
+		if rc.Labels == nil {
+			rc.Labels = make(map[string]string)
+		}
+		for k, v := range labels {
+			rc.Labels[SnapshotterLabelPrefix+k] = v
+		}
+		return nil
+	}
+}
+
+// SnapshotterLabels returns the snapshot labels defined by the given image
+// labels, with the [SnapshotterLabelPrefix] stripped.
+func SnapshotterLabels(imageLabels map[string]string) map[string]string {
+	var labels map[string]string
+	for k, v := range imageLabels {
+		if !strings.HasPrefix(k, SnapshotterLabelPrefix) {
+			continue
+		}
+		key := strings.TrimPrefix(k, SnapshotterLabelPrefix)
+		if key == "" {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[key] = v
+	}
+	return labels
+}
+
+// snapshotterLabelOpts returns snapshot opts applying the image's
+// snapshotter labels, or nil when the image defines none.
+func snapshotterLabelOpts(imageLabels map[string]string) []snapshots.Opt {
+	labels := SnapshotterLabels(imageLabels)
+	if len(labels) == 0 {
+		return nil
+	}
+	return []snapshots.Opt{snapshots.WithLabels(labels)}
+}
+
+// SyncSnapshotterLabels reapplies the image's snapshotter labels to the
+// committed snapshots of the image's rootfs chain in the given snapshotter.
+// Call it after relabeling an image to propagate the change to snapshots
+// that already exist; snapshots unpacked later pick the labels up on their
+// own. Missing chain snapshots are skipped.
+func (c *Client) SyncSnapshotterLabels(ctx context.Context, image Image, snapshotterName string) error {
+	labels := SnapshotterLabels(image.Labels())
+	if len(labels) == 0 {
+		return nil
+	}
+
+	snapshotterName, err := c.resolveSnapshotterName(ctx, snapshotterName)
+	if err != nil {
+		return err
+	}
+	sn, err := c.getSnapshotter(ctx, snapshotterName)
+	if err != nil {
+		return err
+	}
+
+	diffIDs, err := image.RootFS(ctx)
+	if err != nil {
+		return err
+	}
+
+	fieldpaths := make([]string, 0, len(labels))
+	for k := range labels {
+		fieldpaths = append(fieldpaths, "labels."+k)
+	}
+	for _, chainID := range identity.ChainIDs(diffIDs) {
+		info := snapshots.Info{
+			Name:   chainID.String(),
+			Labels: labels,
+		}
+		if _, err := sn.Update(ctx, info, fieldpaths...); err != nil {
+			if errdefs.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to update labels of snapshot %q: %w", chainID, err)
+		}
+	}
+	return nil
+}
@@ -30,11 +30,6 @@ import (
 	"github.com/opencontainers/runtime-spec/specs-go"
 )
 
-const (
-	capaRemapIDs     = "remap-ids"
-	capaOnlyRemapIDs = "only-remap-ids"
-)
-
 // WithRemapperLabels creates the labels used by any supporting snapshotter
 // to shift the filesystem ownership (user namespace mapping) automatically; currently
 // supported by the fuse-overlayfs and overlay snapshotters
@@ -66,7 +61,7 @@ func resolveSnapshotOptions(ctx context.Context, client *Client, snapshotterName
 	}
 
 	for _, capab := range capabs {
-		if capab == capaRemapIDs {
+		if capab == snapshots.CapabilityRemapIDs {
 			// Snapshotter supports ID remapping, we don't need to do anything.
 			return parent, nil
 		}
@@ -95,7 +90,7 @@ func resolveSnapshotOptions(ctx context.Context, client *Client, snapshotterName
 
 	capaOnlyRemap := false
 	for _, capa := range capabs {
-		if capa == capaOnlyRemapIDs {
+		if capa == snapshots.CapabilityOnlyRemapIDs {
 			capaOnlyRemap = true
 		}
 	}
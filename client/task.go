@@ -23,6 +23,7 @@ import (
 	"io"
 	goruntime "runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -42,7 +43,9 @@ import (
 	"github.com/basuotian/containerd/core/images"
 	"github.com/basuotian/containerd/core/mount"
 	"github.com/basuotian/containerd/pkg/cio"
+	"github.com/basuotian/containerd/pkg/criu"
 	"github.com/basuotian/containerd/pkg/oci"
+	"github.com/basuotian/containerd/pkg/processtree"
 	"github.com/basuotian/containerd/pkg/protobuf"
 	google_protobuf "github.com/basuotian/containerd/pkg/protobuf/types"
 	"github.com/basuotian/containerd/pkg/rootfs"
@@ -193,6 +196,11 @@ type Task interface {
 	Exec(context.Context, string, *specs.Process, cio.Creator) (Process, error)
 	// Pids returns a list of system specific process ids inside the task
 	Pids(context.Context) ([]ProcessInfo, error)
+	// ProcessTree returns the processes inside the task arranged by their
+	// parent relationship, when the runtime attaches process details to
+	// the Pids response. Processes without details become roots with only
+	// their pid set.
+	ProcessTree(context.Context) ([]*processtree.Node, error)
 	// Checkpoint serializes the runtime and memory information of a task into an
 	// OCI Index that can be pushed and pulled from a remote resource.
 	//
@@ -223,6 +231,33 @@ type task struct {
 	io  cio.IO
 	id  string
 	pid uint32
+
+	mu sync.Mutex
+	// deleteUsage is the final resource usage summary sampled by
+	// WithResourceUsageSummary, attached to the ExitStatus on Delete.
+	deleteUsage *ResourceUsageSummary
+}
+
+// WithResourceUsageSummary samples the task's resource usage right before
+// the task is deleted, so the ExitStatus returned by Delete carries a final
+// accounting of peak memory, total CPU time and block IO bytes.
+func WithResourceUsageSummary(ctx context.Context, p Process) error {
+	t, ok := p.(*task)
+	if !ok {
+		return fmt.Errorf("resource usage summary is only supported for tasks: %w", errdefs.ErrNotImplemented)
+	}
+	metric, err := t.Metrics(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to sample final task metrics: %w", err)
+	}
+	usage, err := usageFromMetric(metric)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.deleteUsage = usage
+	t.mu.Unlock()
+	return nil
 }
 
 // Spec returns the current OCI specification for the task
@@ -413,7 +448,11 @@ func (t *task) Delete(ctx context.Context, opts ...ProcessDeleteOpts) (*ExitStat
 	if t.io != nil {
 		t.io.Close()
 	}
-	return &ExitStatus{code: r.ExitStatus, exitedAt: protobuf.FromTimestamp(r.ExitedAt)}, nil
+	t.mu.Lock()
+	usage := t.deleteUsage
+	t.deleteUsage = nil
+	t.mu.Unlock()
+	return &ExitStatus{code: r.ExitStatus, exitedAt: protobuf.FromTimestamp(r.ExitedAt), usage: usage}, nil
 }
 
 func (t *task) Exec(ctx context.Context, id string, spec *specs.Process, ioCreate cio.Creator) (_ Process, retErr error) {
@@ -479,6 +518,26 @@ func (t *task) Pids(ctx context.Context) ([]ProcessInfo, error) {
 	return processList, nil
 }
 
+func (t *task) ProcessTree(ctx context.Context) ([]*processtree.Node, error) {
+	processList, err := t.Pids(ctx)
+	if err != nil {
+		return nil, err
+	}
+	procs := make([]processtree.Process, 0, len(processList))
+	for _, p := range processList {
+		proc := processtree.Process{Pid: p.Pid}
+		if p.Info != nil {
+			if v, err := typeurl.UnmarshalAny(p.Info); err == nil {
+				if d, ok := v.(*processtree.Process); ok {
+					proc = *d
+				}
+			}
+		}
+		procs = append(procs, proc)
+	}
+	return processtree.Tree(procs), nil
+}
+
 func (t *task) CloseIO(ctx context.Context, opts ...IOCloserOpts) error {
 	ctx, span := tracing.StartSpan(ctx, "task.CloseIO",
 		tracing.WithAttribute("task.id", t.ID()),
@@ -556,7 +615,13 @@ func (t *task) Checkpoint(ctx context.Context, opts ...CheckpointTaskOpts) (Imag
 		return nil, err
 	}
 
-	if status.Status != Paused {
+	// a CRIU pre-dump copies memory pages only and briefly freezes the task
+	// itself, so skip pausing to let the task keep running
+	preDump := false
+	if copts, ok := i.Options.(*criu.CheckpointOptions); ok {
+		preDump = copts.PreDump
+	}
+	if status.Status != Paused && !preDump {
 		// make sure we pause it and resume after all other filesystem operations are completed
 		if err := t.Pause(ctx); err != nil {
 			return nil, err
@@ -776,8 +841,15 @@ func isCheckpointPathExist(runtime string, v interface{}) bool {
 
 	switch runtime {
 	case plugins.RuntimeRuncV2:
-		if opts, ok := v.(*options.CheckpointOptions); ok && opts.ImagePath != "" {
-			return true
+		switch opts := v.(type) {
+		case *options.CheckpointOptions:
+			if opts.ImagePath != "" {
+				return true
+			}
+		case *criu.CheckpointOptions:
+			if opts.ImagePath != "" {
+				return true
+			}
 		}
 	}
 
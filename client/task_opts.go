@@ -24,6 +24,7 @@ import (
 
 	"github.com/basuotian/containerd/core/images"
 	"github.com/basuotian/containerd/core/mount"
+	"github.com/basuotian/containerd/pkg/criu"
 	"github.com/containerd/containerd/api/types"
 	"github.com/containerd/containerd/api/types/runc/options"
 	"github.com/containerd/errdefs"
@@ -113,6 +114,62 @@ func WithCheckpointImagePath(path string) CheckpointTaskOpts {
 	}
 }
 
+// WithCheckpointPreDump makes the checkpoint an incremental CRIU pre-dump:
+// only memory pages are dumped and the task keeps running afterwards. The
+// resulting image directory can serve as the parent of a later (pre-)dump,
+// allowing a final checkpoint with minimal downtime. Requires the checkpoint
+// image path to be set.
+func WithCheckpointPreDump(r *CheckpointTaskInfo) error {
+	opts, err := criuCheckpointOptions(r)
+	if err != nil {
+		return err
+	}
+	opts.PreDump = true
+	return nil
+}
+
+// WithCheckpointParentPath sets the image directory of the previous dump in
+// an incremental checkpoint chain, relative to the checkpoint image path.
+func WithCheckpointParentPath(path string) CheckpointTaskOpts {
+	return func(r *CheckpointTaskInfo) error {
+		opts, err := criuCheckpointOptions(r)
+		if err != nil {
+			return err
+		}
+		opts.ParentPath = path
+		return nil
+	}
+}
+
+// criuCheckpointOptions returns the extended checkpoint options for r,
+// converting any protobuf options already set in place.
+func criuCheckpointOptions(r *CheckpointTaskInfo) (*criu.CheckpointOptions, error) {
+	switch opts := r.Options.(type) {
+	case nil:
+		copts := &criu.CheckpointOptions{}
+		r.Options = copts
+		return copts, nil
+	case *criu.CheckpointOptions:
+		return opts, nil
+	case *options.CheckpointOptions:
+		copts := &criu.CheckpointOptions{
+			Exit:                opts.Exit,
+			OpenTCP:             opts.OpenTcp,
+			ExternalUnixSockets: opts.ExternalUnixSockets,
+			Terminal:            opts.Terminal,
+			FileLocks:           opts.FileLocks,
+			EmptyNamespaces:     opts.EmptyNamespaces,
+			CgroupsMode:         opts.CgroupsMode,
+			ImagePath:           opts.ImagePath,
+			WorkPath:            opts.WorkPath,
+		}
+		r.Options = copts
+		return copts, nil
+	default:
+		return nil, errors.New("invalid runtime v2 checkpoint options format")
+	}
+}
+
 // WithRestoreImagePath sets image path for create option
 func WithRestoreImagePath(path string) NewTaskOpts {
 	return func(ctx context.Context, c *Client, ti *TaskInfo) error {
@@ -20,6 +20,12 @@ package client
 
 import (
 	"context"
+	"errors"
+	"fmt"
+
+	"github.com/containerd/errdefs"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
 )
 
 // WithNoNewKeyring causes tasks not to be created with a new keyring for secret storage.
@@ -78,3 +84,78 @@ func WithGIDOwner(gid uint32) NewTaskOpts {
 		return nil
 	}
 }
+
+// WithDeviceReadBps limits bytes per second read from the block device
+// backing path for task updates. The path is resolved to its major:minor
+// numbers when the option is applied.
+func WithDeviceReadBps(path string, rate uint64) UpdateTaskOpts {
+	return withDeviceThrottle(path, rate, func(blkio *specs.LinuxBlockIO, td specs.LinuxThrottleDevice) {
+		blkio.ThrottleReadBpsDevice = append(blkio.ThrottleReadBpsDevice, td)
+	})
+}
+
+// WithDeviceWriteBps limits bytes per second written to the block device
+// backing path for task updates.
+func WithDeviceWriteBps(path string, rate uint64) UpdateTaskOpts {
+	return withDeviceThrottle(path, rate, func(blkio *specs.LinuxBlockIO, td specs.LinuxThrottleDevice) {
+		blkio.ThrottleWriteBpsDevice = append(blkio.ThrottleWriteBpsDevice, td)
+	})
+}
+
+// WithDeviceReadIOPS limits read operations per second from the block
+// device backing path for task updates.
+func WithDeviceReadIOPS(path string, rate uint64) UpdateTaskOpts {
+	return withDeviceThrottle(path, rate, func(blkio *specs.LinuxBlockIO, td specs.LinuxThrottleDevice) {
+		blkio.ThrottleReadIOPSDevice = append(blkio.ThrottleReadIOPSDevice, td)
+	})
+}
+
+// WithDeviceWriteIOPS limits write operations per second to the block
+// device backing path for task updates.
+func WithDeviceWriteIOPS(path string, rate uint64) UpdateTaskOpts {
+	return withDeviceThrottle(path, rate, func(blkio *specs.LinuxBlockIO, td specs.LinuxThrottleDevice) {
+		blkio.ThrottleWriteIOPSDevice = append(blkio.ThrottleWriteIOPSDevice, td)
+	})
+}
+
+func withDeviceThrottle(path string, rate uint64, add func(*specs.LinuxBlockIO, specs.LinuxThrottleDevice)) UpdateTaskOpts {
+	return func(_ context.Context, _ *Client, r *UpdateTaskInfo) error {
+		major, minor, err := deviceNumbers(path)
+		if err != nil {
+			return err
+		}
+
+		var resources *specs.LinuxResources
+		if r.Resources == nil {
+			resources = &specs.LinuxResources{}
+			r.Resources = resources
+		} else {
+			var ok bool
+			if resources, ok = r.Resources.(*specs.LinuxResources); !ok {
+				return errors.New("device throttles require *specs.LinuxResources")
+			}
+		}
+		if resources.BlockIO == nil {
+			resources.BlockIO = &specs.LinuxBlockIO{}
+		}
+
+		td := specs.LinuxThrottleDevice{Rate: rate}
+		td.Major = major
+		td.Minor = minor
+		add(resources.BlockIO, td)
+		return nil
+	}
+}
+
+// deviceNumbers resolves a block device path to its major:minor numbers.
+func deviceNumbers(path string) (major, minor int64, err error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0, 0, fmt.Errorf("failed to stat device %q: %w", path, err)
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFBLK {
+		return 0, 0, fmt.Errorf("%q is not a block device: %w", path, errdefs.ErrInvalidArgument)
+	}
+	dev := uint64(st.Rdev) //nolint:unconvert // Rdev is 32-bit on some unix platforms
+	return int64(unix.Major(dev)), int64(unix.Minor(dev)), nil
+}
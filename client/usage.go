@@ -0,0 +1,133 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"time"
+
+	wstats "github.com/Microsoft/hcsshim/cmd/containerd-shim-runhcs-v1/stats"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/typeurl/v2"
+
+	v1 "github.com/containerd/cgroups/v3/cgroup1/stats"
+	v2 "github.com/containerd/cgroups/v3/cgroup2/stats"
+	"github.com/containerd/containerd/api/types"
+)
+
+// ResourceUsageSummary is a final accounting of a task's resource
+// consumption, sampled from the runtime metrics right before the task is
+// deleted. Fields which the runtime does not report are left zero.
+type ResourceUsageSummary struct {
+	// MemoryBytes is the memory usage at sampling time.
+	MemoryBytes uint64 `json:"memoryBytes,omitempty"`
+
+	// PeakMemoryBytes is the peak memory usage over the task's lifetime,
+	// when the runtime tracks it.
+	PeakMemoryBytes uint64 `json:"peakMemoryBytes,omitempty"`
+
+	// CPUTotal is the total CPU time consumed by the task.
+	CPUTotal time.Duration `json:"cpuTotal,omitempty"`
+
+	// IOReadBytes is the total bytes read from block devices.
+	IOReadBytes uint64 `json:"ioReadBytes,omitempty"`
+
+	// IOWriteBytes is the total bytes written to block devices.
+	IOWriteBytes uint64 `json:"ioWriteBytes,omitempty"`
+}
+
+// usageFromMetric converts a runtime metric sample into a summary,
+// understanding the same metric payloads as task metrics: cgroup v1 and v2
+// stats and Windows statistics.
+func usageFromMetric(metric *types.Metric) (*ResourceUsageSummary, error) {
+	data, err := typeurl.UnmarshalAny(metric.Data)
+	if err != nil {
+		return nil, err
+	}
+	switch v := data.(type) {
+	case *v1.Metrics:
+		return usageFromCgroup1(v), nil
+	case *v2.Metrics:
+		return usageFromCgroup2(v), nil
+	case *wstats.Statistics:
+		if linux := v.GetLinux(); linux != nil {
+			return usageFromCgroup1(linux), nil
+		}
+		if windows := v.GetWindows(); windows != nil {
+			return usageFromWindows(windows), nil
+		}
+		return nil, fmt.Errorf("statistics contain no container stats: %w", errdefs.ErrNotFound)
+	default:
+		return nil, fmt.Errorf("cannot summarize metric type %T: %w", data, errdefs.ErrNotImplemented)
+	}
+}
+
+func usageFromCgroup1(m *v1.Metrics) *ResourceUsageSummary {
+	var s ResourceUsageSummary
+	if m.Memory != nil && m.Memory.Usage != nil {
+		s.MemoryBytes = m.Memory.Usage.Usage
+		s.PeakMemoryBytes = m.Memory.Usage.Max
+	}
+	if m.CPU != nil && m.CPU.Usage != nil {
+		s.CPUTotal = time.Duration(m.CPU.Usage.Total)
+	}
+	if m.Blkio != nil {
+		for _, e := range m.Blkio.IoServiceBytesRecursive {
+			switch e.Op {
+			case "Read":
+				s.IOReadBytes += e.Value
+			case "Write":
+				s.IOWriteBytes += e.Value
+			}
+		}
+	}
+	return &s
+}
+
+func usageFromCgroup2(m *v2.Metrics) *ResourceUsageSummary {
+	var s ResourceUsageSummary
+	if m.Memory != nil {
+		s.MemoryBytes = m.Memory.Usage
+		s.PeakMemoryBytes = m.Memory.MaxUsage
+	}
+	if m.CPU != nil {
+		s.CPUTotal = time.Duration(m.CPU.UsageUsec) * time.Microsecond
+	}
+	if m.Io != nil {
+		for _, e := range m.Io.Usage {
+			s.IOReadBytes += e.Rbytes
+			s.IOWriteBytes += e.Wbytes
+		}
+	}
+	return &s
+}
+
+func usageFromWindows(m *wstats.WindowsContainerStatistics) *ResourceUsageSummary {
+	var s ResourceUsageSummary
+	if mem := m.GetMemory(); mem != nil {
+		s.MemoryBytes = mem.MemoryUsagePrivateWorkingSetBytes
+		s.PeakMemoryBytes = mem.MemoryUsageCommitPeakBytes
+	}
+	if cpu := m.GetProcessor(); cpu != nil {
+		s.CPUTotal = time.Duration(cpu.TotalRuntimeNS)
+	}
+	if storage := m.GetStorage(); storage != nil {
+		s.IOReadBytes = storage.ReadSizeBytes
+		s.IOWriteBytes = storage.WriteSizeBytes
+	}
+	return &s
+}
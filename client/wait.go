@@ -0,0 +1,107 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/containerd/errdefs"
+)
+
+// TaskResult is the structured outcome of waiting for a single task.
+type TaskResult struct {
+	// ID is the ID of the waited task.
+	ID string
+	// ExitCode is the task's exit code. Only valid when Err is nil.
+	ExitCode uint32
+	// ExitedAt is the time the task exited. Only valid when Err is nil.
+	ExitedAt time.Time
+	// Err is set when the wait itself failed, e.g. the context expired
+	// before the task exited. The task may still be running.
+	Err error
+}
+
+// WaitAll waits until every given task has exited and returns a result per
+// task, in the same order as the tasks. A context deadline or cancellation
+// is recorded as the Err of the tasks which had not exited yet.
+func WaitAll(ctx context.Context, tasks ...Task) []TaskResult {
+	var (
+		results = make([]TaskResult, len(tasks))
+		wg      sync.WaitGroup
+	)
+	for i, t := range tasks {
+		results[i].ID = t.ID()
+		ch, err := t.Wait(ctx)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(i int, ch <-chan ExitStatus) {
+			defer wg.Done()
+			select {
+			case es := <-ch:
+				results[i].ExitCode, results[i].ExitedAt, results[i].Err = es.Result()
+			case <-ctx.Done():
+				results[i].Err = ctx.Err()
+			}
+		}(i, ch)
+	}
+	wg.Wait()
+	return results
+}
+
+// WaitAny waits until the first of the given tasks exits and returns its
+// result. A wait which fails to be set up also counts as an outcome and is
+// returned with its Err set. The error return is reserved for the wait as a
+// whole failing: no tasks were given or the context ended first.
+func WaitAny(ctx context.Context, tasks ...Task) (TaskResult, error) {
+	if len(tasks) == 0 {
+		return TaskResult{}, fmt.Errorf("no tasks to wait for: %w", errdefs.ErrInvalidArgument)
+	}
+
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	first := make(chan TaskResult, len(tasks))
+	for _, t := range tasks {
+		ch, err := t.Wait(waitCtx)
+		if err != nil {
+			first <- TaskResult{ID: t.ID(), Err: err}
+			continue
+		}
+		go func(id string, ch <-chan ExitStatus) {
+			select {
+			case es := <-ch:
+				var result = TaskResult{ID: id}
+				result.ExitCode, result.ExitedAt, result.Err = es.Result()
+				first <- result
+			case <-waitCtx.Done():
+			}
+		}(t.ID(), ch)
+	}
+
+	select {
+	case result := <-first:
+		return result, nil
+	case <-ctx.Done():
+		return TaskResult{}, ctx.Err()
+	}
+}
@@ -427,15 +427,30 @@ func (p *Init) checkpoint(ctx context.Context, r *CheckpointConfig) error {
 	if !r.Exit {
 		actions = append(actions, runc.LeaveRunning)
 	}
+	if r.PreDump {
+		actions = append(actions, runc.PreDump)
+	}
 	// keep criu work directory if criu work dir is set
 	work := r.WorkDir
 	if work == "" {
 		work = filepath.Join(p.WorkDir, "criu-work")
 		defer os.RemoveAll(work)
 	}
+	// Quiesce IO before the dump so no fifo write is in flight while CRIU
+	// freezes and dumps the container; anything the container writes
+	// between here and the freeze stays in the runtime pipes and ends up
+	// in the checkpoint image.
+	if p.io != nil {
+		if err := p.io.Quiesce(ctx); err != nil {
+			log.G(ctx).WithError(err).Warn("failed to quiesce io before checkpoint")
+		} else {
+			defer p.io.Resume()
+		}
+	}
 	if err := p.runtime.Checkpoint(ctx, p.id, &runc.CheckpointOpts{
 		WorkDir:                  work,
 		ImagePath:                r.Path,
+		ParentPath:               r.ParentPath,
 		AllowOpenTCP:             r.AllowOpenTCP,
 		AllowExternalUnixSockets: r.AllowExternalUnixSockets,
 		AllowTerminal:            r.AllowTerminal,
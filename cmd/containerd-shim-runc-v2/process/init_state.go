@@ -246,6 +246,13 @@ func (s *runningState) Pause(ctx context.Context) error {
 		return s.p.runtimeError(err, "OCI runtime pause failed")
 	}
 
+	// The frozen container cannot produce more output; drain what it
+	// wrote before the freeze so consumers see it while the task is
+	// paused.
+	if err := s.p.io.Drain(ctx); err != nil {
+		log.G(ctx).WithError(err).Warn("failed to drain io after pause")
+	}
+
 	return s.transition("paused")
 }
 
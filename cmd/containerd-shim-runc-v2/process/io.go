@@ -56,6 +56,9 @@ type processIO struct {
 	uri   *url.URL
 	copy  bool
 	stdio stdio.Stdio
+	// gate coordinates the copier goroutines with pause and checkpoint,
+	// see Drain and Quiesce. Only set when copy is true.
+	gate *ioGate
 }
 
 func (p *processIO) Close() error {
@@ -74,7 +77,7 @@ func (p *processIO) Copy(ctx context.Context, wg *sync.WaitGroup) error {
 		return nil
 	}
 	var cwg sync.WaitGroup
-	if err := copyPipes(ctx, p.IO(), p.stdio.Stdin, p.stdio.Stdout, p.stdio.Stderr, wg, &cwg); err != nil {
+	if err := copyPipes(ctx, p.IO(), p.stdio.Stdin, p.stdio.Stdout, p.stdio.Stderr, wg, &cwg, p.gate); err != nil {
 		return fmt.Errorf("unable to copy pipes: %w", err)
 	}
 	cwg.Wait()
@@ -84,6 +87,7 @@ func (p *processIO) Copy(ctx context.Context, wg *sync.WaitGroup) error {
 func createIO(ctx context.Context, id string, ioUID, ioGID int, stdio stdio.Stdio) (*processIO, error) {
 	pio := &processIO{
 		stdio: stdio,
+		gate:  newIOGate(),
 	}
 	if stdio.IsNull() {
 		i, err := runc.NewNullIO()
@@ -131,7 +135,7 @@ func createIO(ctx context.Context, id string, ioUID, ioGID int, stdio stdio.Stdi
 	return pio, nil
 }
 
-func copyPipes(ctx context.Context, rio runc.IO, stdin, stdout, stderr string, wg, cwg *sync.WaitGroup) error {
+func copyPipes(ctx context.Context, rio runc.IO, stdin, stdout, stderr string, wg, cwg *sync.WaitGroup, gate *ioGate) error {
 	var sameFile *countingWriteCloser
 	for _, i := range []struct {
 		name string
@@ -146,7 +150,7 @@ func copyPipes(ctx context.Context, rio runc.IO, stdin, stdout, stderr string, w
 					cwg.Done()
 					p := bufPool.Get().(*[]byte)
 					defer bufPool.Put(p)
-					if _, err := io.CopyBuffer(wc, rio.Stdout(), *p); err != nil {
+					if _, err := io.CopyBuffer(wc, wrapGatedReader(rio.Stdout(), gate), *p); err != nil {
 						log.G(ctx).Warn("error copying stdout")
 					}
 					wg.Done()
@@ -165,7 +169,7 @@ func copyPipes(ctx context.Context, rio runc.IO, stdin, stdout, stderr string, w
 					cwg.Done()
 					p := bufPool.Get().(*[]byte)
 					defer bufPool.Put(p)
-					if _, err := io.CopyBuffer(wc, rio.Stderr(), *p); err != nil {
+					if _, err := io.CopyBuffer(wc, wrapGatedReader(rio.Stderr(), gate), *p); err != nil {
 						log.G(ctx).Warn("error copying stderr")
 					}
 					wg.Done()
@@ -195,7 +199,7 @@ func copyPipes(ctx context.Context, rio runc.IO, stdin, stdout, stderr string, w
 		} else {
 			if sameFile != nil {
 				sameFile.bumpCount(1)
-				i.dest(sameFile, nil)
+				i.dest(wrapGated(sameFile, gate), nil)
 				continue
 			}
 			if fw, err = os.OpenFile(i.name, syscall.O_WRONLY|syscall.O_APPEND, 0); err != nil {
@@ -205,7 +209,7 @@ func copyPipes(ctx context.Context, rio runc.IO, stdin, stdout, stderr string, w
 				sameFile = newCountingWriteCloser(fw, 1)
 			}
 		}
-		i.dest(fw, fr)
+		i.dest(wrapGated(fw, gate), fr)
 	}
 	if stdin == "" {
 		return nil
@@ -0,0 +1,253 @@
+//go:build !windows
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// drainPollInterval is how often Drain re-checks the runtime pipes for
+// output the copiers have not picked up yet.
+const drainPollInterval = 10 * time.Millisecond
+
+// drainTimeout bounds how long Drain waits for the pipes to empty; a
+// consumer that stopped reading the fifos must not wedge pause or
+// checkpoint forever.
+const drainTimeout = 2 * time.Second
+
+// ioGate coordinates the copier goroutines with pause and checkpoint
+// operations. Copiers wrap their fifo writers in a gatedWriter; pausing
+// the gate waits for in-flight writes to finish and blocks new ones until
+// the gate is resumed.
+type ioGate struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	paused  bool
+	writing int
+	// buffered counts bytes a copier has read from the runtime pipe but
+	// not yet written to its fifo, so Drain does not miss output sitting
+	// in a copier's buffer between a read and the matching write.
+	buffered int
+}
+
+func newIOGate() *ioGate {
+	g := &ioGate{}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+// beginWrite blocks while the gate is paused, then marks a write in flight.
+func (g *ioGate) beginWrite() {
+	g.mu.Lock()
+	for g.paused {
+		g.cond.Wait()
+	}
+	g.writing++
+	g.mu.Unlock()
+}
+
+func (g *ioGate) endWrite() {
+	g.mu.Lock()
+	g.writing--
+	g.cond.Broadcast()
+	g.mu.Unlock()
+}
+
+// pause blocks new writes and waits until in-flight writes have finished.
+// A copier blocked inside a fifo write - the consumer stopped reading - can
+// hold the gate open indefinitely, so the wait is bounded by ctx: when it
+// expires the pause is abandoned, the gate reopened and an error returned
+// rather than wedging the caller.
+func (g *ioGate) pause(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.paused = true
+	if g.writing == 0 {
+		return nil
+	}
+	stop := context.AfterFunc(ctx, func() {
+		g.mu.Lock()
+		g.cond.Broadcast()
+		g.mu.Unlock()
+	})
+	defer stop()
+	for g.writing > 0 {
+		if err := ctx.Err(); err != nil {
+			g.paused = false
+			g.cond.Broadcast()
+			return fmt.Errorf("waiting for in-flight fifo write: %w", err)
+		}
+		g.cond.Wait()
+	}
+	return nil
+}
+
+// resume lets blocked copiers continue.
+func (g *ioGate) resume() {
+	g.mu.Lock()
+	g.paused = false
+	g.cond.Broadcast()
+	g.mu.Unlock()
+}
+
+func (g *ioGate) addBuffered(n int) {
+	g.mu.Lock()
+	g.buffered += n
+	g.mu.Unlock()
+}
+
+func (g *ioGate) bufferedBytes() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buffered
+}
+
+// gatedWriter holds the processIO gate around each write so that a paused
+// gate never interrupts a write mid-buffer.
+type gatedWriter struct {
+	io.WriteCloser
+	gate *ioGate
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	g.gate.beginWrite()
+	defer g.gate.endWrite()
+	n, err := g.WriteCloser.Write(p)
+	g.gate.addBuffered(-n)
+	return n, err
+}
+
+// gatedReader records how much a copier has read so the gate knows about
+// output that is buffered in the copier rather than the pipe.
+type gatedReader struct {
+	io.Reader
+	gate *ioGate
+}
+
+func (g *gatedReader) Read(p []byte) (int, error) {
+	n, err := g.Reader.Read(p)
+	g.gate.addBuffered(n)
+	return n, err
+}
+
+// wrapGatedReader wraps a runtime pipe in a gatedReader when a gate is set.
+func wrapGatedReader(r io.Reader, gate *ioGate) io.Reader {
+	if gate == nil {
+		return r
+	}
+	return &gatedReader{r, gate}
+}
+
+// wrapGated wraps a fifo writer in a gatedWriter when a gate is set.
+func wrapGated(wc io.WriteCloser, gate *ioGate) io.WriteCloser {
+	if gate == nil {
+		return wc
+	}
+	return &gatedWriter{wc, gate}
+}
+
+// pendingBytes returns the number of bytes buffered in the pipe behind the
+// reader, or 0 with ok false when the reader does not expose a descriptor.
+func pendingBytes(r io.Reader) (int, bool) {
+	fder, ok := r.(interface{ Fd() uintptr })
+	if !ok {
+		return 0, false
+	}
+	n, err := unix.IoctlGetInt(int(fder.Fd()), fionread)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// Drain waits until the copiers have consumed all output buffered in the
+// runtime's stdout and stderr pipes and any in-flight fifo write has
+// finished. Call it after the container is paused, when no new output can
+// be produced, to make sure everything written before the freeze reaches
+// the consumers.
+func (p *processIO) Drain(ctx context.Context) error {
+	if p == nil || !p.copy || p.io == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, drainTimeout)
+	defer cancel()
+
+	for {
+		pending := 0
+		for _, r := range []io.Reader{p.io.Stdout(), p.io.Stderr()} {
+			if r == nil {
+				continue
+			}
+			if n, ok := pendingBytes(r); ok {
+				pending += n
+			}
+		}
+		if pending == 0 {
+			// Let any in-flight fifo write finish, then make sure no
+			// output is still buffered in a copier.
+			if err := p.gate.pause(ctx); err != nil {
+				return fmt.Errorf("draining io: %w", err)
+			}
+			drained := p.gate.bufferedBytes() == 0
+			p.gate.resume()
+			if drained {
+				return nil
+			}
+			pending = p.gate.bufferedBytes()
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("draining io: %d bytes still buffered: %w", pending, ctx.Err())
+		case <-time.After(drainPollInterval):
+		}
+	}
+}
+
+// Quiesce drains buffered output and then holds the copiers' fifo writes
+// until Resume, so a CRIU dump does not race with the shim writing to the
+// stdio fifos. Output produced between Quiesce and the freeze stays in the
+// runtime pipes and is captured in the checkpoint image.
+func (p *processIO) Quiesce(ctx context.Context) error {
+	if p == nil || !p.copy {
+		return nil
+	}
+	if err := p.Drain(ctx); err != nil {
+		return err
+	}
+	pctx, cancel := context.WithTimeout(ctx, drainTimeout)
+	defer cancel()
+	if err := p.gate.pause(pctx); err != nil {
+		return fmt.Errorf("quiescing io: %w", err)
+	}
+	return nil
+}
+
+// Resume releases copiers blocked by Quiesce.
+func (p *processIO) Resume() {
+	if p == nil || !p.copy {
+		return
+	}
+	p.gate.resume()
+}
@@ -0,0 +1,191 @@
+//go:build linux
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRuncIO exposes one end of an os pipe as the runtime's stdout, the way
+// runc's pipe IO does.
+type fakeRuncIO struct {
+	stdout *os.File
+}
+
+func (f *fakeRuncIO) Stdin() io.WriteCloser  { return nil }
+func (f *fakeRuncIO) Stdout() io.ReadCloser  { return f.stdout }
+func (f *fakeRuncIO) Stderr() io.ReadCloser  { return nil }
+func (f *fakeRuncIO) Set(cmd *exec.Cmd)      {}
+func (f *fakeRuncIO) Close() error           { return f.stdout.Close() }
+func (f *fakeRuncIO) CloseAfterStart() error { return nil }
+
+// lockedBuffer is a goroutine-safe WriteCloser destination.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Close() error { return nil }
+
+func (b *lockedBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+func TestQuiesceDrainsHeavyOutput(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	pio := &processIO{
+		io:   &fakeRuncIO{stdout: r},
+		copy: true,
+		gate: newIOGate(),
+	}
+
+	dst := &lockedBuffer{}
+	copierDone := make(chan struct{})
+	go func() {
+		defer close(copierDone)
+		io.Copy(wrapGated(dst, pio.gate), wrapGatedReader(r, pio.gate))
+	}()
+
+	// Produce heavy output, more than the pipe buffer holds.
+	const total = 1 << 20
+	chunk := bytes.Repeat([]byte("x"), 4096)
+	for written := 0; written < total; written += len(chunk) {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The container is "paused" now: no new output. Quiesce must wait for
+	// the copier to flush everything buffered in the pipe.
+	if err := pio.Quiesce(context.Background()); err != nil {
+		t.Fatalf("quiesce failed: %v", err)
+	}
+	if got := dst.Len(); got != total {
+		t.Fatalf("expected %d bytes flushed before quiesce returned, got %d", total, got)
+	}
+
+	// While quiesced, copier writes are held.
+	if _, err := w.Write(chunk); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := dst.Len(); got != total {
+		t.Fatalf("expected writes to be gated during quiesce, %d extra bytes copied", got-total)
+	}
+
+	// Resume releases the copier.
+	pio.Resume()
+	deadline := time.Now().Add(2 * time.Second)
+	for dst.Len() != total+len(chunk) {
+		if time.Now().After(deadline) {
+			t.Fatalf("copier did not resume, got %d of %d bytes", dst.Len(), total+len(chunk))
+		}
+		time.Sleep(drainPollInterval)
+	}
+
+	w.Close()
+	<-copierDone
+}
+
+func TestDrainTimesOutWhenConsumerStuck(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	pio := &processIO{
+		io:   &fakeRuncIO{stdout: r},
+		copy: true,
+		gate: newIOGate(),
+	}
+
+	// No copier is running, so the pipe never drains.
+	if _, err := w.Write([]byte("stuck")); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := pio.Drain(context.Background()); err == nil {
+		t.Fatal("expected drain to fail with no consumer")
+	}
+	if time.Since(start) > drainTimeout+time.Second {
+		t.Fatal("drain did not respect its timeout")
+	}
+}
+
+func TestPauseTimesOutWithStalledWrite(t *testing.T) {
+	g := newIOGate()
+	// Simulate a copier stuck inside a fifo write because the consumer
+	// stopped reading.
+	g.beginWrite()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := g.pause(ctx); err == nil {
+		t.Fatal("expected pause to fail with a write in flight")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("pause did not respect the context deadline")
+	}
+
+	// The abandoned pause must reopen the gate so new writes do not block.
+	done := make(chan struct{})
+	go func() {
+		g.beginWrite()
+		g.endWrite()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("gate left closed after abandoned pause")
+	}
+	g.endWrite()
+}
+
+func TestDrainNilIO(t *testing.T) {
+	var pio *processIO
+	if err := pio.Drain(context.Background()); err != nil {
+		t.Fatalf("nil processIO drain: %v", err)
+	}
+	pio.Resume()
+}
@@ -39,7 +39,9 @@ import (
 
 	"github.com/basuotian/containerd/cmd/containerd-shim-runc-v2/process"
 	"github.com/basuotian/containerd/core/mount"
+	"github.com/basuotian/containerd/pkg/criu"
 	"github.com/basuotian/containerd/pkg/namespaces"
+	"github.com/basuotian/containerd/pkg/sdnotify"
 	"github.com/basuotian/containerd/pkg/stdio"
 )
 
@@ -134,6 +136,13 @@ func NewContainer(ctx context.Context, platform stdio.Platform, r *task.CreateTa
 	if err != nil {
 		return nil, errgrpc.ToGRPC(err)
 	}
+	// The keyring annotations may override the NoNewKeyring option, e.g.
+	// to place the container on a keyring shared across its pod.
+	noNewKeyring, err := setupKeyring(r.Bundle, p.NoNewKeyring)
+	if err != nil {
+		return nil, errgrpc.ToGRPC(err)
+	}
+	p.NoNewKeyring = noNewKeyring
 	if err := p.Create(ctx, config); err != nil {
 		return nil, errgrpc.ToGRPC(err)
 	}
@@ -144,8 +153,19 @@ func NewContainer(ctx context.Context, platform stdio.Platform, r *task.CreateTa
 		processes:       make(map[string]process.Process),
 		reservedProcess: make(map[string]struct{}),
 	}
+	if s, err := readSpec(r.Bundle); err == nil {
+		container.annotations = make(map[string]string, len(s.Annotations))
+		for k, v := range s.Annotations {
+			container.annotations[k] = v
+		}
+	}
 	pid := p.Pid()
 	if pid > 0 {
+		// The workload has not been started yet, so a cookie assigned
+		// here is inherited by every process of the container.
+		if err := applySchedCore(r.Bundle, pid); err != nil {
+			return nil, errgrpc.ToGRPC(err)
+		}
 		if cg, err := loadProcessCgroup(ctx, pid); err == nil {
 			container.cgroup = cg
 		}
@@ -233,12 +253,18 @@ type Container struct {
 	ID string
 	// Bundle path
 	Bundle string
+	// SdNotify receives sd_notify readiness messages from the workload
+	// when the spec opts into them, nil otherwise
+	SdNotify *sdnotify.Listener
 
 	// cgroup is either cgroups.Cgroup or *cgroupsv2.Manager
 	cgroup          interface{}
 	process         process.Process
 	processes       map[string]process.Process
 	reservedProcess map[string]struct{}
+	// annotations are the runtime-visible annotations of the container,
+	// seeded from the bundle spec and updated through task Update
+	annotations map[string]string
 }
 
 // All processes in the container
@@ -351,6 +377,13 @@ func (c *Container) Start(ctx context.Context, r *task.StartRequest) (process.Pr
 	if err := p.Start(ctx); err != nil {
 		return p, err
 	}
+	if r.ExecID != "" && p.Pid() > 0 {
+		// Execs are forked from the shim rather than inside the
+		// container and have to be placed on its cookie explicitly.
+		if err := inheritSchedCore(c, p.Pid()); err != nil {
+			return p, err
+		}
+	}
 	if c.Cgroup() == nil && p.Pid() > 0 {
 		if cg, err := loadProcessCgroup(ctx, p.Pid()); err == nil {
 			c.cgroup = cg
@@ -444,26 +477,79 @@ func (c *Container) Checkpoint(ctx context.Context, r *task.CheckpointTaskReques
 		return err
 	}
 
-	var opts options.CheckpointOptions
+	config := process.CheckpointConfig{
+		Path: r.Path,
+	}
 	if r.Options != nil {
-		if err := typeurl.UnmarshalTo(r.Options, &opts); err != nil {
+		v, err := typeurl.UnmarshalAny(r.Options)
+		if err != nil {
 			return err
 		}
+		switch opts := v.(type) {
+		case *options.CheckpointOptions:
+			config.Exit = opts.Exit
+			config.AllowOpenTCP = opts.OpenTcp
+			config.AllowExternalUnixSockets = opts.ExternalUnixSockets
+			config.AllowTerminal = opts.Terminal
+			config.FileLocks = opts.FileLocks
+			config.EmptyNamespaces = opts.EmptyNamespaces
+			config.WorkDir = opts.WorkPath
+		case *criu.CheckpointOptions:
+			config.Exit = opts.Exit
+			config.AllowOpenTCP = opts.OpenTCP
+			config.AllowExternalUnixSockets = opts.ExternalUnixSockets
+			config.AllowTerminal = opts.Terminal
+			config.FileLocks = opts.FileLocks
+			config.EmptyNamespaces = opts.EmptyNamespaces
+			config.WorkDir = opts.WorkPath
+			config.PreDump = opts.PreDump
+			config.ParentPath = opts.ParentPath
+		default:
+			return fmt.Errorf("unsupported checkpoint options type %T", v)
+		}
+	}
+	return p.(*process.Init).Checkpoint(ctx, &config)
+}
+
+// Annotations returns a copy of the runtime annotations of the container.
+func (c *Container) Annotations() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	annotations := make(map[string]string, len(c.annotations))
+	for k, v := range c.annotations {
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// UpdateAnnotations merges the given annotations into the runtime
+// annotations of the container. An empty value clears the annotation.
+func (c *Container) UpdateAnnotations(annotations map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.annotations == nil {
+		c.annotations = make(map[string]string, len(annotations))
+	}
+	for k, v := range annotations {
+		if v == "" {
+			delete(c.annotations, k)
+			continue
+		}
+		c.annotations[k] = v
 	}
-	return p.(*process.Init).Checkpoint(ctx, &process.CheckpointConfig{
-		Path:                     r.Path,
-		Exit:                     opts.Exit,
-		AllowOpenTCP:             opts.OpenTcp,
-		AllowExternalUnixSockets: opts.ExternalUnixSockets,
-		AllowTerminal:            opts.Terminal,
-		FileLocks:                opts.FileLocks,
-		EmptyNamespaces:          opts.EmptyNamespaces,
-		WorkDir:                  opts.WorkPath,
-	})
 }
 
 // Update the resource information of a running container
 func (c *Container) Update(ctx context.Context, r *task.UpdateTaskRequest) error {
+	if len(r.Annotations) > 0 {
+		c.UpdateAnnotations(r.Annotations)
+		log.G(ctx).WithField("id", c.ID).WithField("annotations", r.Annotations).
+			Info("updated runtime annotations")
+	}
+	// An annotation-only update has nothing to hand to the OCI runtime.
+	if r.Resources == nil || r.Resources.GetValue() == nil {
+		return nil
+	}
 	p, err := c.Process("")
 	if err != nil {
 		return err
@@ -0,0 +1,201 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package runc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	runcC "github.com/containerd/go-runc"
+	"github.com/containerd/log"
+	"golang.org/x/sys/unix"
+
+	"github.com/basuotian/containerd/pkg/coredump"
+)
+
+const (
+	// CoreDumpAnnotation enables capturing core dumps for the container
+	// when set to "true".
+	CoreDumpAnnotation = "io.containerd.runc.v2.coredump"
+	// CoreDumpDirAnnotation overrides the directory captured core dumps
+	// are stored in. Defaults to the "cores" directory in the bundle.
+	CoreDumpDirAnnotation = "io.containerd.runc.v2.coredump-dir"
+	// CoreDumpMaxSizeAnnotation caps the number of bytes captured per
+	// core dump; larger dumps are truncated at the cap.
+	CoreDumpMaxSizeAnnotation = "io.containerd.runc.v2.coredump-max-size"
+
+	// defaultCoreDumpMaxSize is the capture cap used when the annotation
+	// does not specify one.
+	defaultCoreDumpMaxSize = int64(64 << 20)
+
+	// coreDumpWindow is how far before the exit timestamp a core file may
+	// have been written and still be attributed to the exit.
+	coreDumpWindow = 30 * time.Second
+
+	exitSignalOffset = 128
+)
+
+// coreSignal reports whether the exit status describes a termination by a
+// signal whose default action produces a core dump.
+func coreSignal(status int) (uint32, bool) {
+	if status <= exitSignalOffset {
+		return 0, false
+	}
+	sig := unix.Signal(status - exitSignalOffset)
+	switch sig {
+	case unix.SIGQUIT, unix.SIGILL, unix.SIGTRAP, unix.SIGABRT, unix.SIGBUS,
+		unix.SIGFPE, unix.SIGSEGV, unix.SIGXCPU, unix.SIGXFSZ, unix.SIGSYS:
+		return uint32(sig), true
+	}
+	return 0, false
+}
+
+// CaptureCoreDump captures the core dump a crashed container process left
+// behind and returns the event describing the capture. It returns nil when
+// capturing is not enabled for the container, the exit was not caused by a
+// core producing signal or no core file could be found.
+//
+// Cores are looked for in the working directory of the container process,
+// which is where the kernel writes them with the default core pattern.
+// Patterns handing cores to a collector on the host cannot be captured
+// from here.
+func CaptureCoreDump(ctx context.Context, bundle, containerID, processID string, e runcC.Exit) (*coredump.TaskCoreDump, error) {
+	sig, ok := coreSignal(e.Status)
+	if !ok {
+		return nil, nil
+	}
+
+	spec, err := readSpec(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config.json: %w", err)
+	}
+	if spec.Annotations[CoreDumpAnnotation] != "true" {
+		return nil, nil
+	}
+
+	maxSize := defaultCoreDumpMaxSize
+	if v, ok := spec.Annotations[CoreDumpMaxSizeAnnotation]; ok {
+		maxSize, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid annotation %q=%q: %w", CoreDumpMaxSizeAnnotation, v, err)
+		}
+	}
+
+	rootfs := spec.Root.Path
+	if !filepath.IsAbs(rootfs) {
+		rootfs = filepath.Join(bundle, rootfs)
+	}
+	cwd := "/"
+	if spec.Process != nil && spec.Process.Cwd != "" {
+		cwd = spec.Process.Cwd
+	}
+	src := findCoreFile(filepath.Join(rootfs, cwd), e.Timestamp)
+	if src == "" {
+		return nil, nil
+	}
+
+	dir := spec.Annotations[CoreDumpDirAnnotation]
+	if dir == "" {
+		dir = filepath.Join(bundle, "cores")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%s-%d-%d.core", processID, e.Pid, e.Timestamp.Unix())
+	dst := filepath.Join(dir, name)
+	size, truncated, err := copyCapped(dst, src, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	dump := &coredump.TaskCoreDump{
+		ContainerID: containerID,
+		ID:          processID,
+		Pid:         uint32(e.Pid),
+		Signal:      sig,
+		Path:        dst,
+		Size:        size,
+		Truncated:   truncated,
+		ExitedAt:    e.Timestamp,
+	}
+	if err := coredump.Append(dir, dump); err != nil {
+		log.G(ctx).WithError(err).Warn("failed to record core dump in index")
+	}
+	return dump, nil
+}
+
+// findCoreFile returns the newest core file in dir written within the
+// capture window before the exit, or empty when there is none.
+func findCoreFile(dir string, exitedAt time.Time) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var (
+		newest      string
+		newestMtime time.Time
+		cutoff      = exitedAt.Add(-coreDumpWindow)
+	)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), "core") {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil || fi.ModTime().Before(cutoff) {
+			continue
+		}
+		if fi.ModTime().After(newestMtime) {
+			newest = filepath.Join(dir, entry.Name())
+			newestMtime = fi.ModTime()
+		}
+	}
+	return newest
+}
+
+// copyCapped copies src to dst, truncating at limit bytes.
+func copyCapped(dst, src string, limit int64) (int64, bool, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, false, err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return 0, false, err
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, io.LimitReader(in, limit))
+	if err != nil {
+		return 0, false, err
+	}
+	truncated := false
+	if n == limit {
+		// Only report truncation when there was actually more to read.
+		if fi, err := in.Stat(); err == nil && fi.Size() > limit {
+			truncated = true
+		}
+	}
+	return n, truncated, nil
+}
@@ -0,0 +1,141 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package runc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/errdefs"
+	"golang.org/x/sys/unix"
+
+	"github.com/basuotian/containerd/pkg/oci"
+)
+
+// sandboxIDAnnotation is written into the spec by CRI and names the pod
+// the container belongs to. It is the default name for the pod keyring.
+const sandboxIDAnnotation = "io.kubernetes.cri.sandbox-id"
+
+// setupKeyring applies the keyring annotations of the container in the
+// bundle and reports whether runc must be told not to replace the session
+// keyring. The shim serves a single pod, so a session keyring joined here
+// is inherited by every container it creates while the keyring is current.
+func setupKeyring(bundle string, noNewKeyring bool) (bool, error) {
+	spec, err := readSpec(bundle)
+	if err != nil {
+		return noNewKeyring, fmt.Errorf("failed to read config.json: %w", err)
+	}
+	if err := checkKeyringQuota(spec.Annotations); err != nil {
+		return noNewKeyring, err
+	}
+
+	links := spec.Annotations[oci.KeyringLinksAnnotation]
+	switch policy := spec.Annotations[oci.KeyringPolicyAnnotation]; policy {
+	case "":
+		return noNewKeyring, nil
+	case oci.KeyringPolicyHost:
+		return true, nil
+	case oci.KeyringPolicyPrivate:
+		if links == "" {
+			// runc creates the fresh session keyring itself.
+			return false, nil
+		}
+		// Links have to be applied before the container starts, so the
+		// shim creates the keyring and runc must leave it in place.
+		ring, err := unix.KeyctlJoinSessionKeyring("")
+		if err != nil {
+			return noNewKeyring, fmt.Errorf("failed to create session keyring: %w", err)
+		}
+		if err := linkKeyrings(ring, links); err != nil {
+			return noNewKeyring, err
+		}
+		return true, nil
+	case oci.KeyringPolicyPod:
+		name := spec.Annotations[oci.KeyringNameAnnotation]
+		if name == "" {
+			name = spec.Annotations[sandboxIDAnnotation]
+		}
+		if name == "" {
+			return noNewKeyring, fmt.Errorf("keyring policy %q needs a sandbox ID or the %s annotation: %w",
+				policy, oci.KeyringNameAnnotation, errdefs.ErrInvalidArgument)
+		}
+		ring, err := unix.KeyctlJoinSessionKeyring(name)
+		if err != nil {
+			return noNewKeyring, fmt.Errorf("failed to join session keyring %q: %w", name, err)
+		}
+		if err := linkKeyrings(ring, links); err != nil {
+			return noNewKeyring, err
+		}
+		return true, nil
+	default:
+		return noNewKeyring, fmt.Errorf("unknown keyring policy %q: %w", policy, errdefs.ErrInvalidArgument)
+	}
+}
+
+// linkKeyrings links each named keyring from the comma separated list into
+// ring. The names are looked up in the user keyring of the shim.
+func linkKeyrings(ring int, links string) error {
+	for _, name := range strings.Split(links, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, err := unix.KeyctlSearch(unix.KEY_SPEC_USER_KEYRING, "keyring", name, 0)
+		if err != nil {
+			return fmt.Errorf("keyring %q not found in the user keyring: %w", name, err)
+		}
+		if _, err := unix.KeyctlInt(unix.KEYCTL_LINK, id, ring, 0, 0); err != nil {
+			return fmt.Errorf("failed to link keyring %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// checkKeyringQuota verifies the kernel key quotas cover what the keyring
+// quota annotations request. The kernel enforces the quotas per UID, not
+// per container, so exceeding requests can only be rejected, not limited.
+func checkKeyringQuota(annotations map[string]string) error {
+	for annotation, sysctl := range map[string]string{
+		oci.KeyringMaxKeysAnnotation:  "maxkeys",
+		oci.KeyringMaxBytesAnnotation: "maxbytes",
+	} {
+		value, ok := annotations[annotation]
+		if !ok {
+			continue
+		}
+		want, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid annotation %q=%q: %w", annotation, value, err)
+		}
+		data, err := os.ReadFile(filepath.Join("/proc/sys/kernel/keys", sysctl))
+		if err != nil {
+			return fmt.Errorf("failed to read kernel key quota: %w", err)
+		}
+		limit, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse kernel key quota %s: %w", sysctl, err)
+		}
+		if want > limit {
+			return fmt.Errorf("requested keyring quota %s=%d exceeds the per UID kernel limit %d, raise the kernel.keys.%s sysctl: %w",
+				annotation, want, limit, sysctl, errdefs.ErrFailedPrecondition)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package runc
+
+import (
+	"fmt"
+	"os"
+	goruntime "runtime"
+	"sync"
+
+	"github.com/containerd/errdefs"
+
+	"github.com/basuotian/containerd/pkg/oci"
+	"github.com/basuotian/containerd/pkg/schedcore"
+)
+
+// podSchedCore tracks the init process holding the pod wide core
+// scheduling cookie. The shim serves a single pod, so one holder is
+// enough. If the holder exits before another container is created the
+// cookie cannot be extended anymore and creation fails; restarting the
+// container under a fresh cookie would silently drop the SMT isolation
+// between it and the rest of the pod.
+var podSchedCore struct {
+	sync.Mutex
+	pid int
+}
+
+// applySchedCore assigns a core scheduling cookie to the init process of
+// the container according to the sched-core annotation in the bundle
+// spec. It must run after runc create, when the init process exists but
+// the workload has not started, so every process forked inside the
+// container inherits the cookie.
+func applySchedCore(bundle string, pid int) error {
+	spec, err := readSpec(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to read config.json: %w", err)
+	}
+	switch scope := spec.Annotations[oci.SchedCoreAnnotation]; scope {
+	case "":
+		return nil
+	case oci.SchedCoreScopeContainer:
+		if err := schedcore.CreateFor(uint64(pid), schedcore.ThreadGroup); err != nil {
+			return fmt.Errorf("failed to create core scheduling cookie: %w", err)
+		}
+		return nil
+	case oci.SchedCoreScopePod:
+		podSchedCore.Lock()
+		defer podSchedCore.Unlock()
+		if podSchedCore.pid == 0 {
+			if err := schedcore.CreateFor(uint64(pid), schedcore.ThreadGroup); err != nil {
+				return fmt.Errorf("failed to create core scheduling cookie: %w", err)
+			}
+			podSchedCore.pid = pid
+			return nil
+		}
+		if err := copySchedCoreCookie(podSchedCore.pid, pid); err != nil {
+			return fmt.Errorf("failed to join the pod core scheduling cookie: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown core scheduling scope %q: %w", scope, errdefs.ErrInvalidArgument)
+	}
+}
+
+// inheritSchedCore copies the core scheduling cookie of the container
+// init process onto an exec'd process. Execs are forked from the shim,
+// not from the container, so they do not inherit the cookie on their own.
+func inheritSchedCore(c *Container, pid int) error {
+	spec, err := readSpec(c.Bundle)
+	if err != nil {
+		return fmt.Errorf("failed to read config.json: %w", err)
+	}
+	if spec.Annotations[oci.SchedCoreAnnotation] == "" {
+		return nil
+	}
+	if err := copySchedCoreCookie(c.Pid(), pid); err != nil {
+		return fmt.Errorf("failed to inherit core scheduling cookie: %w", err)
+	}
+	return nil
+}
+
+// copySchedCoreCookie assigns the cookie of process from to process to.
+// Cookies can only be pulled onto or pushed from the calling thread, so
+// the copy stages the cookie on a locked thread and restores the cookie
+// of the main thread afterwards, which is zero unless SCHED_CORE was set
+// when the shim started.
+func copySchedCoreCookie(from, to int) error {
+	goruntime.LockOSThread()
+	defer goruntime.UnlockOSThread()
+	if err := schedcore.ShareFrom(uint64(from), schedcore.Pid); err != nil {
+		return err
+	}
+	err := schedcore.ShareTo(uint64(to), schedcore.ThreadGroup)
+	if rerr := schedcore.ShareFrom(uint64(os.Getpid()), schedcore.Pid); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package runc
+
+import (
+	"path/filepath"
+	"slices"
+
+	"github.com/basuotian/containerd/pkg/sdnotify"
+)
+
+// SetupSdNotify creates the sd_notify listener for a container whose
+// spec opts into readiness notifications, identified by NOTIFY_SOCKET
+// pointing at the mounted notification socket. It returns nil when the
+// container did not opt in. The listener must be created before the
+// container, its socket directory is bind mounted by the runtime.
+func SetupSdNotify(bundle string) (*sdnotify.Listener, error) {
+	spec, err := readSpec(bundle)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Process == nil || !slices.Contains(spec.Process.Env, "NOTIFY_SOCKET="+sdnotify.ContainerSocket) {
+		return nil, nil
+	}
+	return sdnotify.Listen(filepath.Join(bundle, sdnotify.BundleDir))
+}
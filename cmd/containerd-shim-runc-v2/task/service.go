@@ -31,7 +31,6 @@ import (
 	cgroupsv2 "github.com/containerd/cgroups/v3/cgroup2"
 	eventstypes "github.com/containerd/containerd/api/events"
 	taskAPI "github.com/containerd/containerd/api/runtime/task/v3"
-	"github.com/containerd/containerd/api/types/runc/options"
 	"github.com/containerd/containerd/api/types/task"
 	"github.com/containerd/errdefs"
 	"github.com/containerd/errdefs/pkg/errgrpc"
@@ -47,8 +46,10 @@ import (
 	"github.com/basuotian/containerd/pkg/oom"
 	oomv1 "github.com/basuotian/containerd/pkg/oom/v1"
 	oomv2 "github.com/basuotian/containerd/pkg/oom/v2"
+	"github.com/basuotian/containerd/pkg/processtree"
 	"github.com/basuotian/containerd/pkg/protobuf"
 	ptypes "github.com/basuotian/containerd/pkg/protobuf/types"
+	"github.com/basuotian/containerd/pkg/sdnotify"
 	"github.com/basuotian/containerd/pkg/shim"
 	"github.com/basuotian/containerd/pkg/shutdown"
 	"github.com/basuotian/containerd/pkg/stdio"
@@ -230,10 +231,30 @@ func (s *service) Create(ctx context.Context, r *taskAPI.CreateTaskRequest) (_ *
 	s.lifecycleMu.Unlock()
 	defer cleanup()
 
+	// The notification socket has to exist before the container is
+	// created, the runtime bind mounts its directory.
+	notify, err := runc.SetupSdNotify(r.Bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up sd_notify socket: %w", err)
+	}
+
 	container, err := runc.NewContainer(ctx, s.platform, r)
 	if err != nil {
+		if notify != nil {
+			notify.Close()
+		}
 		return nil, err
 	}
+	container.SdNotify = notify
+	if notify != nil {
+		go func() {
+			select {
+			case <-notify.Ready():
+				s.send(&sdnotify.TaskReady{ContainerID: r.ID})
+			case <-notify.Done():
+			}
+		}()
+	}
 
 	s.containers[r.ID] = container
 
@@ -363,6 +384,9 @@ func (s *service) Delete(ctx context.Context, r *taskAPI.DeleteRequest) (*taskAP
 		s.mu.Lock()
 		delete(s.containers, r.ID)
 		s.mu.Unlock()
+		if container.SdNotify != nil {
+			container.SdNotify.Close()
+		}
 		s.send(&eventstypes.TaskDelete{
 			ContainerID: container.ID,
 			Pid:         uint32(p.Pid()),
@@ -514,19 +538,23 @@ func (s *service) Pids(ctx context.Context, r *taskAPI.PidsRequest) (*taskAPI.Pi
 		pInfo := task.ProcessInfo{
 			Pid: pid,
 		}
+		// Processes may exit between listing the cgroup and reading
+		// /proc, report the bare pid in that case.
+		d, err := processtree.Collect(pid)
+		if err != nil {
+			log.G(ctx).WithError(err).Debugf("failed to collect details of process %d", pid)
+		}
 		for _, p := range container.ExecdProcesses() {
 			if p.Pid() == int(pid) {
-				d := &options.ProcessDetails{
-					ExecID: p.ID(),
-				}
-				a, err := typeurl.MarshalAnyToProto(d)
-				if err != nil {
-					return nil, fmt.Errorf("failed to marshal process %d info: %w", pid, err)
-				}
-				pInfo.Info = a
+				d.ExecID = p.ID()
 				break
 			}
 		}
+		a, err := typeurl.MarshalAnyToProto(&d)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal process %d info: %w", pid, err)
+		}
+		pInfo.Info = a
 		processes = append(processes, &pInfo)
 	}
 	return &taskAPI.PidsResponse{
@@ -756,6 +784,11 @@ func (s *service) handleProcessExit(e runcC.Exit, c *runc.Container, p process.P
 		ExitStatus:  uint32(e.Status),
 		ExitedAt:    protobuf.ToTimestamp(p.ExitedAt()),
 	})
+	if dump, err := runc.CaptureCoreDump(s.context, c.Bundle, c.ID, p.ID(), e); err != nil {
+		log.G(s.context).WithError(err).WithField("id", p.ID()).Warn("failed to capture core dump")
+	} else if dump != nil {
+		s.send(dump)
+	}
 	if _, init := p.(*process.Init); !init {
 		s.lifecycleMu.Lock()
 		s.runningExecs[c]--
@@ -19,12 +19,14 @@ package builtins
 // register containerd builtins here
 import (
 	_ "github.com/basuotian/containerd/core/runtime/v2"
+	_ "github.com/basuotian/containerd/plugins/blobscanner"
 	_ "github.com/basuotian/containerd/plugins/content/local/plugin"
 	_ "github.com/basuotian/containerd/plugins/events"
 	_ "github.com/basuotian/containerd/plugins/gc"
 	_ "github.com/basuotian/containerd/plugins/imageverifier"
 	_ "github.com/basuotian/containerd/plugins/leases"
 	_ "github.com/basuotian/containerd/plugins/metadata"
+	_ "github.com/basuotian/containerd/plugins/mirror"
 	_ "github.com/basuotian/containerd/plugins/nri"
 	_ "github.com/basuotian/containerd/plugins/restart"
 	_ "github.com/basuotian/containerd/plugins/sandbox"
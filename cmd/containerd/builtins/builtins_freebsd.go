@@ -17,6 +17,8 @@
 package builtins
 
 import (
+	_ "github.com/basuotian/containerd/plugins/diff/decrypt/plugin"
 	_ "github.com/basuotian/containerd/plugins/diff/walking/plugin"
+	_ "github.com/basuotian/containerd/plugins/diff/zstdchunked/plugin"
 	_ "github.com/containerd/zfs/v2/plugin"
 )
@@ -19,8 +19,10 @@ package builtins
 import (
 	_ "github.com/basuotian/containerd/core/metrics/cgroups"
 	_ "github.com/basuotian/containerd/core/metrics/cgroups/v2"
+	_ "github.com/basuotian/containerd/plugins/diff/decrypt/plugin"
 	_ "github.com/basuotian/containerd/plugins/diff/erofs/plugin"
 	_ "github.com/basuotian/containerd/plugins/diff/walking/plugin"
+	_ "github.com/basuotian/containerd/plugins/diff/zstdchunked/plugin"
 	_ "github.com/basuotian/containerd/plugins/snapshots/blockfile/plugin"
 	_ "github.com/basuotian/containerd/plugins/snapshots/erofs/plugin"
 	_ "github.com/basuotian/containerd/plugins/snapshots/native/plugin"
@@ -19,7 +19,9 @@
 package builtins
 
 import (
+	_ "github.com/basuotian/containerd/plugins/diff/decrypt/plugin"
 	_ "github.com/basuotian/containerd/plugins/diff/walking/plugin"
+	_ "github.com/basuotian/containerd/plugins/diff/zstdchunked/plugin"
 	_ "github.com/basuotian/containerd/plugins/snapshots/blockfile/plugin"
 	_ "github.com/basuotian/containerd/plugins/snapshots/native/plugin"
 )
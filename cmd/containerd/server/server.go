@@ -58,6 +58,7 @@ import (
 	"github.com/basuotian/containerd/core/diff"
 	diffproxy "github.com/basuotian/containerd/core/diff/proxy"
 	sbproxy "github.com/basuotian/containerd/core/sandbox/proxy"
+	"github.com/basuotian/containerd/core/snapshots"
 	ssproxy "github.com/basuotian/containerd/core/snapshots/proxy"
 	"github.com/basuotian/containerd/defaults"
 	"github.com/basuotian/containerd/pkg/dialer"
@@ -487,6 +488,13 @@ func LoadPlugins(ctx context.Context, config *srvconfig.Config) ([]plugin.Regist
 			f = func(conn *grpc.ClientConn) interface{} {
 				return ssproxy.NewSnapshotter(ssapi.NewSnapshotsClient(conn), ssname)
 			}
+			// Proxy snapshotters default to no capabilities, anything
+			// else must be declared in the proxy plugin configuration.
+			for _, c := range pp.Capabilities {
+				if !snapshots.KnownCapability(c) {
+					log.G(ctx).WithFields(log.Fields{"plugin": name, "capability": c}).Warn("unknown snapshotter capability")
+				}
+			}
 
 		case string(plugins.ContentPlugin), "content":
 			t = plugins.ContentPlugin
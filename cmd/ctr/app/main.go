@@ -115,6 +115,14 @@ containerd CLI
 			Value:   namespaces.Default,
 			EnvVars: []string{namespaces.NamespaceEnvVar},
 		},
+		&cli.BoolFlag{
+			Name:  "airgap",
+			Usage: "Refuse remote fetches to hosts not listed with --airgap-allow",
+		},
+		&cli.StringSliceFlag{
+			Name:  "airgap-allow",
+			Usage: "Registry hosts still reachable with --airgap",
+		},
 	}
 	app.Commands = append([]*cli.Command{
 		plugins.Command,
@@ -130,6 +138,7 @@ containerd CLI
 		snapshots.Command,
 		tasks.Command,
 		install.Command,
+		install.UninstallCommand,
 		ociCmd.Command,
 		sandboxes.Command,
 		info.Command,
@@ -17,6 +17,7 @@
 package commands
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -126,7 +127,7 @@ var (
 			Name:  "env",
 			Usage: "Specify additional container environment variables (e.g. FOO=bar)",
 		},
-		&cli.StringFlag{
+		&cli.StringSliceFlag{
 			Name:  "env-file",
 			Usage: "Specify additional container environment variables in a file(e.g. FOO=bar, one per line)",
 		},
@@ -134,6 +135,14 @@ var (
 			Name:  "label",
 			Usage: "Specify additional labels (e.g. foo=bar)",
 		},
+		&cli.StringSliceFlag{
+			Name:  "label-file",
+			Usage: "Specify additional labels in a file(e.g. foo=bar, one per line)",
+		},
+		&cli.BoolFlag{
+			Name:  "env-expand",
+			Usage: "Expand ${VAR} references in --env-file and --label-file values from the client environment",
+		},
 		&cli.StringSliceFlag{
 			Name:  "annotation",
 			Usage: "Specify additional OCI annotations (e.g. foo=bar)",
@@ -255,6 +264,82 @@ func LabelArgs(labelStrings []string) map[string]string {
 	return labels
 }
 
+// ContainerLabels returns the labels from the --label and --label-file
+// flags, with --label entries overriding those read from files.
+func ContainerLabels(cliContext *cli.Context) (map[string]string, error) {
+	labels := map[string]string{}
+	expand := cliContext.Bool("env-expand")
+	for _, path := range cliContext.StringSlice("label-file") {
+		if err := parseKVFile(path, expand, func(key, value string, hasValue bool) {
+			if !hasValue {
+				value = "true"
+			}
+			labels[key] = value
+		}); err != nil {
+			return nil, err
+		}
+	}
+	for k, v := range LabelArgs(cliContext.StringSlice("label")) {
+		labels[k] = v
+	}
+	return labels, nil
+}
+
+// ParseEnvFile reads environment variables from a file using the docker
+// --env-file syntax: one KEY=VALUE per line, with blank lines and lines
+// starting with '#' ignored. A key without a value takes its value from
+// the client environment and is dropped when not set there. When expand
+// is true, ${VAR} references in values are expanded from the client
+// environment.
+func ParseEnvFile(path string, expand bool) ([]string, error) {
+	var vars []string
+	if err := parseKVFile(path, expand, func(key, value string, hasValue bool) {
+		if !hasValue {
+			var ok bool
+			if value, ok = os.LookupEnv(key); !ok {
+				return
+			}
+		}
+		vars = append(vars, key+"="+value)
+	}); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// parseKVFile parses a file of KEY=VALUE lines, calling visit for each
+// entry. Parse errors include the file name and line number.
+func parseKVFile(path string, expand bool, visit func(key, value string, hasValue bool)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for lineno := 1; sc.Scan(); lineno++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, hasValue := strings.Cut(line, "=")
+		if key == "" {
+			return fmt.Errorf("%s:%d: empty key in line %q", path, lineno, line)
+		}
+		if strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("%s:%d: key %q contains whitespace", path, lineno, key)
+		}
+		if expand && hasValue {
+			value = os.Expand(value, os.Getenv)
+		}
+		visit(key, value, hasValue)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	return nil
+}
+
 // AnnotationArgs returns a map of annotation key,value pairs.
 func AnnotationArgs(annoStrings []string) (map[string]string, error) {
 	annotations := make(map[string]string, len(annoStrings))
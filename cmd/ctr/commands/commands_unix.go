@@ -56,6 +56,12 @@ func init() {
 	}, &cli.StringSliceFlag{
 		Name:  "device",
 		Usage: "File path to a device to add to the container; or a path to a directory tree of devices to add to the container",
+	}, &cli.StringSliceFlag{
+		Name:  "device-cgroup-rule",
+		Usage: "Device cgroup allow rule in the format \"<type> <major>:<minor> <access>\", e.g. \"c 1:3 rwm\"; does not add a device node",
+	}, &cli.StringFlag{
+		Name:  "device-allowlist",
+		Usage: "Path to a device allowlist file with one cgroup rule or device path glob per line",
 	})
 }
 
@@ -31,6 +31,15 @@ func init() {
 		}, &cli.Uint64Flag{
 			Name:  "cpu-max",
 			Usage: "The number of processor cycles threads in a container can use per 10,000 cycles. Set to a percentage times 100. Between 1 and 10,000",
+		}, &cli.StringSliceFlag{
+			Name:  "cpu-group",
+			Usage: "Processor group affinity for the container, in GROUP:MASK format with MASK in hex (e.g. 0:ff). May be repeated",
+		}, &cli.Uint64Flag{
+			Name:  "storage-max-iops",
+			Usage: "Maximum IO operations per second for the container's system drive",
+		}, &cli.Uint64Flag{
+			Name:  "storage-max-bps",
+			Usage: "Maximum IO bandwidth in bytes per second for the container's system drive",
 		}, &cli.StringSliceFlag{
 			Name:  "device",
 			Usage: "Identifier of a device to add to the container  (e.g. class://5B45201D-F2F2-4F3B-85BB-30FF1F953599)",
@@ -17,15 +17,28 @@
 package containers
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	containerd "github.com/basuotian/containerd/client"
 	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/images"
+	"github.com/basuotian/containerd/pkg/cio"
+	"github.com/basuotian/containerd/pkg/namespaces"
 	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/urfave/cli/v2"
 )
 
+// verifySnapshotterLabel is the annotation the checkpoint index records
+// the snapshotter under, mirroring the label the client writes.
+const verifySnapshotterLabel = "io.containerd.checkpoint.snapshotter"
+
 var checkpointCommand = &cli.Command{
 	Name:      "checkpoint",
 	Usage:     "Checkpoint a container",
@@ -43,6 +56,10 @@ var checkpointCommand = &cli.Command{
 			Name:  "task",
 			Usage: "Checkpoint container task",
 		},
+		&cli.BoolFlag{
+			Name:  "verify",
+			Usage: "Verify the checkpoint by test restoring it into a scratch namespace, restoring the task as well requires --image and --task",
+		},
 	},
 	Action: func(cliContext *cli.Context) error {
 		id := cliContext.Args().First()
@@ -93,10 +110,159 @@ var checkpointCommand = &cli.Command{
 			}()
 		}
 
-		if _, err := container.Checkpoint(ctx, ref, opts...); err != nil {
+		checkpoint, err := container.Checkpoint(ctx, ref, opts...)
+		if err != nil {
 			return err
 		}
 
+		if cliContext.Bool("verify") {
+			// verify while the task is still paused so the checkpoint
+			// reflects the state being test restored
+			if err := verifyCheckpoint(ctx, client, checkpoint, cliContext.Bool("image"), cliContext.Bool("rw"), cliContext.Bool("task")); err != nil {
+				return fmt.Errorf("checkpoint verification failed: %w", err)
+			}
+			fmt.Fprintln(cliContext.App.Writer, "checkpoint verified")
+		}
+
 		return nil
 	},
 }
+
+// verifyCheckpoint test restores the checkpoint into a scratch namespace to
+// catch unrestorable checkpoints at save time rather than at restore time.
+// When the checkpoint includes the image and the task, the task is restored
+// and immediately killed again; no networking is set up for it. All
+// resources are removed from the scratch namespace afterwards.
+func verifyCheckpoint(ctx context.Context, client *containerd.Client, checkpoint containerd.Image, withImage, withRW, withTask bool) (retErr error) {
+	ns := fmt.Sprintf("ctr-verify-%d", time.Now().UnixNano())
+	if err := client.NamespaceService().Create(ctx, ns, nil); err != nil {
+		return fmt.Errorf("failed to create scratch namespace: %w", err)
+	}
+	defer func() {
+		// the content copied into the scratch namespace is only released
+		// once the garbage collector has run, so removing the namespace
+		// may not succeed until then
+		if err := client.NamespaceService().Delete(ctx, ns); err != nil {
+			log.L.WithError(err).Warnf("scratch namespace %q could not be removed yet", ns)
+		}
+	}()
+
+	vctx, done, err := client.WithLease(namespaces.WithNamespace(ctx, ns))
+	if err != nil {
+		return err
+	}
+	defer done(vctx)
+
+	if err := copyImageInto(ctx, vctx, client, checkpoint.Name(), checkpoint.Target()); err != nil {
+		return err
+	}
+	defer func() {
+		if err := client.ImageService().Delete(vctx, checkpoint.Name(), images.SynchronousDelete()); err != nil {
+			log.L.WithError(err).Warnf("failed to delete checkpoint from scratch namespace %q", ns)
+		}
+	}()
+	vCheckpoint, err := client.GetImage(vctx, checkpoint.Name())
+	if err != nil {
+		return err
+	}
+
+	opts := []containerd.RestoreOpts{
+		containerd.WithRestoreSpec,
+		containerd.WithRestoreRuntime,
+	}
+	if withImage {
+		// the base image needs to be visible and unpacked in the scratch
+		// namespace for the rootfs of the restored container
+		p, err := content.ReadBlob(ctx, client.ContentStore(), checkpoint.Target())
+		if err != nil {
+			return err
+		}
+		var index ocispec.Index
+		if err := json.Unmarshal(p, &index); err != nil {
+			return err
+		}
+		name := index.Annotations[ocispec.AnnotationRefName]
+		if name == "" {
+			return errors.New("image name not found in checkpoint index")
+		}
+		snapshotter := index.Annotations[verifySnapshotterLabel]
+		if snapshotter == "" {
+			return errors.New("snapshotter name not found in checkpoint index")
+		}
+		base, err := client.GetImage(ctx, name)
+		if err != nil {
+			return fmt.Errorf("base image %q needed for verification: %w", name, err)
+		}
+		if err := copyImageInto(ctx, vctx, client, name, base.Target()); err != nil {
+			return err
+		}
+		defer func() {
+			if err := client.ImageService().Delete(vctx, name, images.SynchronousDelete()); err != nil {
+				log.L.WithError(err).Warnf("failed to delete base image from scratch namespace %q", ns)
+			}
+		}()
+		vBase, err := client.GetImage(vctx, name)
+		if err != nil {
+			return err
+		}
+		if err := vBase.Unpack(vctx, snapshotter); err != nil {
+			return fmt.Errorf("failed to unpack base image: %w", err)
+		}
+		opts = append(opts, containerd.WithRestoreImage)
+		if withRW {
+			opts = append(opts, containerd.WithRestoreRW)
+		}
+	}
+
+	ctr, err := client.Restore(vctx, "verify", vCheckpoint, opts...)
+	if err != nil {
+		return fmt.Errorf("test restore failed: %w", err)
+	}
+	defer func() {
+		if err := ctr.Delete(vctx, containerd.WithSnapshotCleanup); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+
+	if withTask && withImage {
+		task, err := ctr.NewTask(vctx, cio.NullIO, containerd.WithTaskCheckpoint(vCheckpoint))
+		if err != nil {
+			return fmt.Errorf("test task restore failed: %w", err)
+		}
+		defer func() {
+			if _, err := task.Delete(vctx, containerd.WithProcessKill); err != nil && retErr == nil {
+				retErr = err
+			}
+		}()
+		// the runtime performs the actual restore on start
+		if err := task.Start(vctx); err != nil {
+			return fmt.Errorf("test task restore failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// copyImageInto makes an image from the namespace of srcCtx visible in the
+// namespace of dstCtx by copying its content across and recording the image.
+func copyImageInto(srcCtx, dstCtx context.Context, client *containerd.Client, name string, target ocispec.Descriptor) error {
+	cs := client.ContentStore()
+	h := images.Handlers(images.HandlerFunc(func(_ context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		ra, err := cs.ReaderAt(srcCtx, desc)
+		if err != nil {
+			return nil, err
+		}
+		defer ra.Close()
+		ref := fmt.Sprintf("verify-%s", desc.Digest)
+		if err := content.WriteBlob(dstCtx, cs, ref, content.NewReader(ra), desc); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}), images.ChildrenHandler(cs))
+	if err := images.Dispatch(srcCtx, h, nil, target); err != nil {
+		return err
+	}
+	if _, err := client.ImageService().Create(dstCtx, images.Image{Name: name, Target: target}); err != nil && !errdefs.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
@@ -47,6 +47,7 @@ var Command = &cli.Command{
 		setLabelsCommand,
 		checkpointCommand,
 		restoreCommand,
+		forkCommand,
 	},
 }
 
@@ -101,6 +102,7 @@ var listCommand = &cli.Command{
 			Aliases: []string{"q"},
 			Usage:   "Print only the container id",
 		},
+		commands.OutputFlag,
 	},
 	Action: func(cliContext *cli.Context) error {
 		var (
@@ -116,6 +118,28 @@ var listCommand = &cli.Command{
 		if err != nil {
 			return err
 		}
+		if commands.OutputSelected(cliContext) {
+			type containerItem struct {
+				ID      string            `json:"id"`
+				Image   string            `json:"image,omitempty"`
+				Runtime string            `json:"runtime"`
+				Labels  map[string]string `json:"labels,omitempty"`
+			}
+			items := make([]containerItem, 0, len(containers))
+			for _, c := range containers {
+				info, err := c.Info(ctx, containerd.WithoutRefreshedMetadata)
+				if err != nil {
+					return err
+				}
+				items = append(items, containerItem{
+					ID:      c.ID(),
+					Image:   info.Image,
+					Runtime: info.Runtime.Name,
+					Labels:  info.Labels,
+				})
+			}
+			return commands.OutputList(cliContext, items)
+		}
 		if quiet {
 			for _, c := range containers {
 				fmt.Printf("%s\n", c.ID())
@@ -211,7 +235,12 @@ var setLabelsCommand = &cli.Command{
 	Usage:       "Set and clear labels for a container",
 	ArgsUsage:   "[flags] CONTAINER [<key>=<value>, ...]",
 	Description: "set and clear labels for a container",
-	Flags:       []cli.Flag{},
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "runtime",
+			Usage: "Also forward the labels to the running task as runtime annotations",
+		},
+	},
 	Action: func(cliContext *cli.Context) error {
 		containerID, labels := commands.ObjectWithLabelArgs(cliContext)
 		if containerID == "" {
@@ -233,6 +262,16 @@ var setLabelsCommand = &cli.Command{
 			return err
 		}
 
+		if cliContext.Bool("runtime") {
+			task, err := container.Task(ctx, nil)
+			if err != nil {
+				return err
+			}
+			if err := task.Update(ctx, containerd.WithAnnotations(labels)); err != nil {
+				return err
+			}
+		}
+
 		var labelStrings []string
 		for k, v := range setlabels {
 			labelStrings = append(labelStrings, fmt.Sprintf("%s=%s", k, v))
@@ -253,6 +292,7 @@ var infoCommand = &cli.Command{
 			Name:  "spec",
 			Usage: "Only display the spec",
 		},
+		commands.OutputFlag,
 	},
 	Action: func(cliContext *cli.Context) error {
 		id := cliContext.Args().First()
@@ -272,30 +312,30 @@ var infoCommand = &cli.Command{
 		if err != nil {
 			return err
 		}
+		var display interface{} = info
 		if cliContext.Bool("spec") {
 			v, err := typeurl.UnmarshalAny(info.Spec)
 			if err != nil {
 				return err
 			}
-			commands.PrintAsJSON(v)
-			return nil
-		}
-
-		if info.Spec != nil && info.Spec.GetValue() != nil {
+			display = v
+		} else if info.Spec != nil && info.Spec.GetValue() != nil {
 			v, err := typeurl.UnmarshalAny(info.Spec)
 			if err != nil {
 				return err
 			}
-			commands.PrintAsJSON(struct {
+			display = struct {
 				containers.Container
 				Spec interface{} `json:"Spec,omitempty"`
 			}{
 				Container: info,
 				Spec:      v,
-			})
-			return nil
+			}
+		}
+		if commands.OutputSelected(cliContext) {
+			return commands.OutputInfo(cliContext, display)
 		}
-		commands.PrintAsJSON(info)
+		commands.PrintAsJSON(display)
 		return nil
 	},
 }
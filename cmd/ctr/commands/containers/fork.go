@@ -0,0 +1,80 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package containers
+
+import (
+	"context"
+	"errors"
+
+	containerd "github.com/basuotian/containerd/client"
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/containers"
+	"github.com/urfave/cli/v2"
+)
+
+var forkCommand = &cli.Command{
+	Name:      "fork",
+	Usage:     "Fork a container's current rootfs into a new container",
+	ArgsUsage: "SOURCE NEW_CONTAINER",
+	Description: `Create a new container whose rootfs is a copy of the source
+container's current rootfs, captured through a committed intermediate
+snapshot. The runtime, spec and labels of the source are carried over.
+A running source task is paused during the capture unless --no-pause is
+given.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "no-pause",
+			Usage: "Do not pause a running source task while its rootfs is captured",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		sourceID := cliContext.Args().First()
+		if sourceID == "" {
+			return errors.New("source container id must be provided")
+		}
+		id := cliContext.Args().Get(1)
+		if id == "" {
+			return errors.New("new container id must be provided")
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		source, err := client.LoadContainer(ctx, sourceID)
+		if err != nil {
+			return err
+		}
+		info, err := source.Info(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = client.NewContainer(ctx, id,
+			containerd.WithNewSnapshotFromContainer(id, source, !cliContext.Bool("no-pause")),
+			func(ctx context.Context, _ *containerd.Client, c *containers.Container) error {
+				c.Labels = info.Labels
+				c.Runtime = info.Runtime
+				c.Spec = info.Spec
+				c.Extensions = info.Extensions
+				return nil
+			},
+		)
+		return err
+	},
+}
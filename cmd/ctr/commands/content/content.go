@@ -23,6 +23,7 @@ import (
 	"os"
 	"os/exec"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -52,6 +53,7 @@ var (
 			fetchBlobCommand,
 			getCommand,
 			ingestCommand,
+			ingestDirCommand,
 			listCommand,
 			pushObjectCommand,
 			setLabelsCommand,
@@ -64,6 +66,12 @@ var (
 		Usage:       "Get the data for an object",
 		ArgsUsage:   "[<digest>, ...]",
 		Description: "display the image object",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "range",
+				Usage: "Read only the byte range \"offset:length\" of the blob",
+			},
+		},
 		Action: func(cliContext *cli.Context) error {
 			dgst, err := digest.Parse(cliContext.Args().First())
 			if err != nil {
@@ -81,9 +89,18 @@ var (
 			}
 			defer ra.Close()
 
+			var r io.Reader = content.NewReader(ra)
+			if blobRange := cliContext.String("range"); blobRange != "" {
+				offset, length, err := parseRange(blobRange, ra.Size())
+				if err != nil {
+					return err
+				}
+				r = io.NewSectionReader(ra, offset, length)
+			}
+
 			// use 1MB buffer like we do for ingesting
 			buf := make([]byte, 1<<20)
-			_, err = io.CopyBuffer(os.Stdout, content.NewReader(ra), buf)
+			_, err = io.CopyBuffer(os.Stdout, r, buf)
 			return err
 		},
 	}
@@ -640,3 +657,25 @@ func (oc onCloser) Close() error {
 
 	return err
 }
+
+// parseRange parses a "offset:length" byte range of a blob of the given
+// size. An empty length selects the remainder of the blob.
+func parseRange(s string, size int64) (offset, length int64, err error) {
+	off, ln, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("range must be \"offset:length\": %w", errdefs.ErrInvalidArgument)
+	}
+	if offset, err = strconv.ParseInt(off, 10, 64); err != nil || offset < 0 || offset > size {
+		return 0, 0, fmt.Errorf("invalid range offset %q: %w", off, errdefs.ErrInvalidArgument)
+	}
+	if ln == "" {
+		return offset, size - offset, nil
+	}
+	if length, err = strconv.ParseInt(ln, 10, 64); err != nil || length < 0 {
+		return 0, 0, fmt.Errorf("invalid range length %q: %w", ln, errdefs.ErrInvalidArgument)
+	}
+	if offset+length > size {
+		length = size - offset
+	}
+	return offset, length, nil
+}
@@ -0,0 +1,69 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package content
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	containerd "github.com/basuotian/containerd/client"
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/urfave/cli/v2"
+)
+
+var ingestDirCommand = &cli.Command{
+	Name:      "ingest-dir",
+	Usage:     "Ingest a local directory as a deterministic tar layer blob",
+	ArgsUsage: "[flags] <path>",
+	Description: `Archive a local directory into the content store as a tar layer with
+reproducible entry order and timestamps and print its descriptor. The
+blob is pinned with a garbage collection root label so it can be
+appended to an image, for example with 'ctr images append'.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "gzip",
+			Usage: "Compress the layer with gzip",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		path := cliContext.Args().First()
+		if path == "" {
+			return errors.New("must specify a directory to ingest")
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		opts := []containerd.IngestDirOpt{
+			containerd.WithIngestDirLabels(map[string]string{
+				"containerd.io/gc.root": time.Now().UTC().Format(time.RFC3339),
+			}),
+		}
+		if cliContext.Bool("gzip") {
+			opts = append(opts, containerd.WithIngestDirCompression())
+		}
+		desc, err := client.IngestDir(ctx, path, opts...)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %s %d\n", desc.MediaType, desc.Digest, desc.Size)
+		return nil
+	},
+}
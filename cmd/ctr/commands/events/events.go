@@ -17,8 +17,15 @@
 package events
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/basuotian/containerd/cmd/ctr/commands"
 	"github.com/basuotian/containerd/core/events"
@@ -32,17 +39,57 @@ import (
 
 // Command is the cli command for displaying containerd events
 var Command = &cli.Command{
-	Name:    "events",
-	Aliases: []string{"event"},
-	Usage:   "Display containerd events",
+	Name:      "events",
+	Aliases:   []string{"event"},
+	Usage:     "Display containerd events",
+	ArgsUsage: "[filter, ...]",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "topic",
+			Usage: "Only show events whose topic matches the glob (e.g. \"/tasks/*\"); may be repeated",
+		},
+		&cli.StringSliceFlag{
+			Name:  "event-namespace",
+			Usage: "Only show events whose namespace matches the glob; may be repeated",
+		},
+		&cli.StringFlag{
+			Name:  "output-file",
+			Usage: "Append matching events to the file as newline-delimited JSON instead of printing them",
+		},
+		&cli.Int64Flag{
+			Name:  "rotate-size",
+			Usage: "Rotate the output file once it exceeds this many bytes; 0 disables rotation",
+		},
+		&cli.IntFlag{
+			Name:  "rotate-keep",
+			Usage: "Number of rotated output files to keep",
+			Value: 3,
+		},
+		&cli.StringFlag{
+			Name:  "exec",
+			Usage: "Run the command via \"sh -c\" for every matching event with the event JSON on stdin",
+		},
+	},
 	Action: func(cliContext *cli.Context) error {
 		client, ctx, cancel, err := commands.NewClient(cliContext)
 		if err != nil {
 			return err
 		}
 		defer cancel()
+
+		var w *rotatingWriter
+		if output := cliContext.String("output-file"); output != "" {
+			w, err = newRotatingWriter(output, cliContext.Int64("rotate-size"), cliContext.Int("rotate-keep"))
+			if err != nil {
+				return err
+			}
+			defer w.Close()
+		}
+		handler := cliContext.String("exec")
+
+		filters := subscribeFilters(cliContext)
 		eventsClient := client.EventService()
-		eventsCh, errCh := eventsClient.Subscribe(ctx, cliContext.Args().Slice()...)
+		eventsCh, errCh := eventsClient.Subscribe(ctx, filters...)
 		for {
 			var e *events.Envelope
 			select {
@@ -50,20 +97,27 @@ var Command = &cli.Command{
 			case err = <-errCh:
 				return err
 			}
-			if e != nil {
-				var out []byte
-				if e.Event != nil {
-					v, err := typeurl.UnmarshalAny(e.Event)
-					if err != nil {
-						log.G(ctx).WithError(err).Warn("cannot unmarshal an event from Any")
-						continue
-					}
-					out, err = json.Marshal(v)
-					if err != nil {
-						log.G(ctx).WithError(err).Warn("cannot marshal Any into JSON")
-						continue
-					}
+			if e == nil {
+				continue
+			}
+			var out []byte
+			if e.Event != nil {
+				v, err := typeurl.UnmarshalAny(e.Event)
+				if err != nil {
+					log.G(ctx).WithError(err).Warn("cannot unmarshal an event from Any")
+					continue
+				}
+				out, err = json.Marshal(v)
+				if err != nil {
+					log.G(ctx).WithError(err).Warn("cannot marshal Any into JSON")
+					continue
+				}
+			}
+			if w != nil {
+				if err := w.writeEvent(e, out); err != nil {
+					return err
 				}
+			} else {
 				if _, err := fmt.Println(
 					e.Timestamp,
 					e.Namespace,
@@ -73,6 +127,162 @@ var Command = &cli.Command{
 					return err
 				}
 			}
+			if handler != "" {
+				runHandler(ctx, handler, e, out)
+			}
 		}
 	},
 }
+
+// subscribeFilters combines any raw filter arguments with filters generated
+// from the --topic and --event-namespace globs. Globs within a flag are
+// ORed, the two flags are ANDed.
+func subscribeFilters(cliContext *cli.Context) []string {
+	filters := cliContext.Args().Slice()
+
+	var (
+		topics     = cliContext.StringSlice("topic")
+		namespaces = cliContext.StringSlice("event-namespace")
+	)
+	if len(topics) == 0 && len(namespaces) == 0 {
+		return filters
+	}
+	if len(topics) == 0 {
+		topics = []string{"*"}
+	}
+	if len(namespaces) == 0 {
+		namespaces = []string{"*"}
+	}
+	for _, ns := range namespaces {
+		for _, topic := range topics {
+			filters = append(filters, fmt.Sprintf("namespace~=%s,topic~=%s", globRegexp(ns), globRegexp(topic)))
+		}
+	}
+	return filters
+}
+
+// globRegexp converts a glob into a quoted, anchored regular expression
+// suitable for the ~= filter operator.
+func globRegexp(glob string) string {
+	re := "^"
+	for _, r := range glob {
+		switch r {
+		case '*':
+			re += ".*"
+		case '?':
+			re += "."
+		default:
+			re += regexp.QuoteMeta(string(r))
+		}
+	}
+	return strconv.Quote(re + "$")
+}
+
+// eventRecord is the NDJSON representation of an event envelope.
+type eventRecord struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Namespace string          `json:"namespace"`
+	Topic     string          `json:"topic"`
+	Event     json.RawMessage `json:"event,omitempty"`
+}
+
+// runHandler feeds the event JSON to the handler command on stdin. Handler
+// failures are logged but do not stop the subscription.
+func runHandler(ctx context.Context, handler string, e *events.Envelope, event []byte) {
+	data, err := json.Marshal(eventRecord{
+		Timestamp: e.Timestamp,
+		Namespace: e.Namespace,
+		Topic:     e.Topic,
+		Event:     event,
+	})
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("cannot marshal event for handler")
+		return
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", handler)
+	cmd.Stdin = bytes.NewReader(append(data, '\n'))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		log.G(ctx).WithError(err).WithField("topic", e.Topic).Warn("event handler failed")
+	}
+}
+
+// rotatingWriter appends NDJSON event records to a file, rotating it to
+// numbered backups (file.1 .. file.N) when it grows past maxSize.
+type rotatingWriter struct {
+	path    string
+	maxSize int64
+	keep    int
+	f       *os.File
+	size    int64
+}
+
+func newRotatingWriter(path string, maxSize int64, keep int) (*rotatingWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &rotatingWriter{
+		path:    path,
+		maxSize: maxSize,
+		keep:    keep,
+		f:       f,
+		size:    st.Size(),
+	}, nil
+}
+
+func (w *rotatingWriter) writeEvent(e *events.Envelope, event []byte) error {
+	data, err := json.Marshal(eventRecord{
+		Timestamp: e.Timestamp,
+		Namespace: e.Namespace,
+		Topic:     e.Topic,
+		Event:     event,
+	})
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(data)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := w.f.Write(data)
+	w.size += int64(n)
+	return err
+}
+
+func (w *rotatingWriter) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	for i := w.keep; i > 0; i-- {
+		src := w.path
+		if i > 1 {
+			src = fmt.Sprintf("%s.%d", w.path, i-1)
+		}
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := os.Rename(src, fmt.Sprintf("%s.%d", w.path, i)); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w.f = f
+	w.size = 0
+	return nil
+}
+
+func (w *rotatingWriter) Close() error {
+	return w.f.Close()
+}
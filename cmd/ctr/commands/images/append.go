@@ -0,0 +1,125 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/images/mutate"
+	"github.com/containerd/platforms"
+	"github.com/urfave/cli/v2"
+)
+
+var appendCommand = &cli.Command{
+	Name:      "append",
+	Usage:     "Append layers to an image and patch its config, creating a new reference",
+	ArgsUsage: "[flags] <ref> [<layer.tar>, ...]",
+	Description: `Derive a new image from an existing one without an external builder.
+
+Layers are uncompressed tar files appended on top of the existing rootfs.
+Config fields can be patched with '--env', '--label' and '--entrypoint'.
+The result is created under the reference given with '--new-ref', or the
+source reference is updated in place when the flag is omitted.
+
+e.g., 'ctr images append example.com/foo:latest layer.tar --new-ref example.com/foo:patched'
+`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "new-ref",
+			Usage: "Reference for the mutated image, defaults to replacing the source reference",
+		},
+		&cli.StringSliceFlag{
+			Name:  "env",
+			Usage: "Set an environment variable in the image config (KEY=VALUE, KEY alone unsets)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "label",
+			Usage: "Set a label in the image config (KEY=VALUE)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "entrypoint",
+			Usage: "Replace the image config entrypoint, may be repeated for multiple arguments",
+		},
+		&cli.StringSliceFlag{
+			Name:  "platform",
+			Usage: "Mutate content for a specific platform",
+			Value: cli.NewStringSlice(),
+		},
+		&cli.BoolFlag{
+			Name:  "all-platforms",
+			Usage: "Mutate content for all platforms",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		srcRef := cliContext.Args().First()
+		if srcRef == "" {
+			return errors.New("image reference needs to be specified")
+		}
+		newRef := cliContext.String("new-ref")
+		if newRef == "" {
+			newRef = srcRef
+		}
+
+		var mutateOpts []mutate.Opt
+		for _, path := range cliContext.Args().Tail() {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			mutateOpts = append(mutateOpts, mutate.WithAppendLayer(f))
+		}
+
+		if env := cliContext.StringSlice("env"); len(env) > 0 {
+			mutateOpts = append(mutateOpts, mutate.WithEnv(env))
+		}
+		if labels := cliContext.StringSlice("label"); len(labels) > 0 {
+			mutateOpts = append(mutateOpts, mutate.WithLabels(commands.LabelArgs(labels)))
+		}
+		if cliContext.IsSet("entrypoint") {
+			mutateOpts = append(mutateOpts, mutate.WithEntrypoint(cliContext.StringSlice("entrypoint")))
+		}
+
+		if !cliContext.Bool("all-platforms") {
+			if pss := cliContext.StringSlice("platform"); len(pss) > 0 {
+				all, err := platforms.ParseAll(pss)
+				if err != nil {
+					return err
+				}
+				mutateOpts = append(mutateOpts, mutate.WithPlatform(platforms.Ordered(all...)))
+			} else {
+				mutateOpts = append(mutateOpts, mutate.WithPlatform(platforms.DefaultStrict()))
+			}
+		}
+
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		newImg, err := mutate.Mutate(ctx, client, newRef, srcRef, mutateOpts...)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cliContext.App.Writer, newImg.Target.Digest.String())
+		return nil
+	},
+}
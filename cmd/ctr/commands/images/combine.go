@@ -0,0 +1,69 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/basuotian/containerd/client"
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/urfave/cli/v2"
+)
+
+var combineCommand = &cli.Command{
+	Name:      "combine",
+	Usage:     "Combine locally stored images into a multi-arch manifest list",
+	ArgsUsage: "<new-ref> <ref> [<ref>, ...]",
+	Description: `Compose a new manifest list from the manifests of the given images and
+tag it with <new-ref>. Sources which are already manifest lists contribute
+all of their platform manifests.
+`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "media-type",
+			Usage: "Media type of the combined index, OCI image index by default",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		var (
+			newRef = cliContext.Args().First()
+			refs   = cliContext.Args().Tail()
+		)
+		if newRef == "" || len(refs) == 0 {
+			return errors.New("please provide a new image reference and at least one source image")
+		}
+
+		c, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		var opts []client.CombineOpt
+		if mt := cliContext.String("media-type"); mt != "" {
+			opts = append(opts, client.WithCombineMediaType(mt))
+		}
+
+		img, err := c.CombineImages(ctx, newRef, refs, opts...)
+		if err != nil {
+			return err
+		}
+		fmt.Println(img.Target.Digest.String())
+		return nil
+	},
+}
@@ -0,0 +1,77 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/images/converter"
+	"github.com/basuotian/containerd/core/images/encryption"
+	"github.com/urfave/cli/v2"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var decryptCommand = &cli.Command{
+	Name:      "decrypt",
+	Usage:     "Decrypt image layers",
+	ArgsUsage: "[flags] <source_ref> <target_ref>",
+	Description: `Decrypt the layers of an encrypted image with the given keys and store
+the result as a new image.
+
+e.g., 'ctr images decrypt --key mykey.pem example.com/foo:enc example.com/foo:latest'
+`,
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "key",
+			Usage: "Path to a decryption key file (private key, x509 certificate or GPG private key ring)",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		srcRef := cliContext.Args().Get(0)
+		targetRef := cliContext.Args().Get(1)
+		if srcRef == "" || targetRef == "" {
+			return errors.New("src and target image need to be specified")
+		}
+
+		keys := cliContext.StringSlice("key")
+		if len(keys) == 0 {
+			return errors.New("at least one key must be specified")
+		}
+		cc, err := encryption.DecryptConfigFromKeyFiles(keys...)
+		if err != nil {
+			return err
+		}
+
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		allLayers := func(ocispec.Descriptor) bool { return true }
+		newImg, err := converter.Convert(ctx, client, targetRef, srcRef,
+			converter.WithIndexConvertFunc(encryption.GetImageDecryptConverter(&cc, allLayers)))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cliContext.App.Writer, newImg.Target.Digest.String())
+		return nil
+	},
+}
@@ -0,0 +1,88 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/images/converter"
+	"github.com/basuotian/containerd/core/images/encryption"
+	"github.com/urfave/cli/v2"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var encryptCommand = &cli.Command{
+	Name:      "encrypt",
+	Usage:     "Encrypt image layers",
+	ArgsUsage: "[flags] <source_ref> <target_ref>",
+	Description: `Encrypt the layers of an image for the given recipients and store the
+result as a new image.
+
+e.g., 'ctr images encrypt --recipient jwe:mypubkey.pem example.com/foo:latest example.com/foo:enc'
+`,
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "recipient",
+			Usage: "Recipient of the image is the person who can decrypt it, in the format <protocol>:<file> with protocol jwe (public key) or pkcs7 (x509 certificate)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "key",
+			Usage: "Path to a decryption key file, needed for adding recipients to already encrypted layers",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		srcRef := cliContext.Args().Get(0)
+		targetRef := cliContext.Args().Get(1)
+		if srcRef == "" || targetRef == "" {
+			return errors.New("src and target image need to be specified")
+		}
+
+		recipients := cliContext.StringSlice("recipient")
+		if len(recipients) == 0 {
+			return errors.New("at least one recipient must be specified")
+		}
+		cc, err := encryption.EncryptConfigFromRecipients(recipients...)
+		if err != nil {
+			return err
+		}
+		if keys := cliContext.StringSlice("key"); len(keys) > 0 {
+			dcc, err := encryption.DecryptConfigFromKeyFiles(keys...)
+			if err != nil {
+				return err
+			}
+			cc.EncryptConfig.AttachDecryptConfig(dcc.DecryptConfig)
+		}
+
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		allLayers := func(ocispec.Descriptor) bool { return true }
+		newImg, err := converter.Convert(ctx, client, targetRef, srcRef,
+			converter.WithIndexConvertFunc(encryption.GetImageEncryptConverter(&cc, allLayers)))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cliContext.App.Writer, newImg.Target.Digest.String())
+		return nil
+	},
+}
@@ -21,15 +21,19 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/urfave/cli/v2"
 
+	containerd "github.com/basuotian/containerd/client"
 	"github.com/basuotian/containerd/cmd/ctr/commands"
 	"github.com/basuotian/containerd/core/images/archive"
 	"github.com/basuotian/containerd/core/transfer"
 	tarchive "github.com/basuotian/containerd/core/transfer/archive"
 	"github.com/basuotian/containerd/core/transfer/image"
+	"github.com/basuotian/containerd/core/transfer/layout"
 	"github.com/containerd/platforms"
+	"github.com/docker/go-units"
 )
 
 var exportCommand = &cli.Command{
@@ -65,8 +69,20 @@ When '--all-platforms' is given all images in a manifest list must be available.
 			Name:  "local",
 			Usage: "Run export locally rather than through transfer API",
 		},
+		&cli.StringFlag{
+			Name:  "split-size",
+			Usage: "Split the archive into segments of at most this size (e.g. 2GB) and write a SHA256SUMS manifest",
+		},
+		&cli.BoolFlag{
+			Name:  "oci-layout",
+			Usage: "Export to an OCI image layout directory instead of a tar archive",
+		},
+		&cli.BoolFlag{
+			Name:  "provenance",
+			Usage: "Embed provenance annotations (source registry, pull time, containerd version) into the archive index, requires --local",
+		},
 	},
-	Action: func(cliContext *cli.Context) error {
+	Action: func(cliContext *cli.Context) (retErr error) {
 		var (
 			out        = cliContext.Args().First()
 			images     = cliContext.Args().Tail()
@@ -82,18 +98,69 @@ When '--all-platforms' is given all images in a manifest list must be available.
 		}
 		defer cancel()
 
+		if cliContext.Bool("oci-layout") {
+			if cliContext.Bool("local") {
+				return errors.New("\"--oci-layout\" is not supported with \"--local\"")
+			}
+			if cliContext.String("split-size") != "" {
+				return errors.New("cannot use --split-size when exporting to an OCI layout directory")
+			}
+			dir, err := filepath.Abs(out)
+			if err != nil {
+				return err
+			}
+			pf, done := ProgressHandler(ctx, os.Stdout)
+			defer done()
+
+			storeOpts := make([]image.StoreOpt, len(images))
+			for i, img := range images {
+				storeOpts[i] = image.WithExtraReference(img)
+			}
+
+			return client.Transfer(ctx,
+				image.NewStore("", storeOpts...),
+				layout.NewImageLayout(dir),
+				transfer.WithProgress(pf),
+			)
+		}
+
 		var w io.WriteCloser
-		if out == "-" {
+		if splitSize := cliContext.String("split-size"); splitSize != "" {
+			if out == "-" {
+				return errors.New("cannot use --split-size when exporting to stdout")
+			}
+			size, err := units.RAMInBytes(splitSize)
+			if err != nil {
+				return fmt.Errorf("invalid split size %q: %w", splitSize, err)
+			}
+			sw, err := containerd.NewSplitWriter(out, size)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if retErr != nil {
+					sw.Abort()
+					return
+				}
+				retErr = sw.Close()
+			}()
+			w = sw
+		} else if out == "-" {
 			w = os.Stdout
+			defer w.Close()
 		} else {
 			w, err = os.Create(out)
 			if err != nil {
 				return err
 			}
+			defer w.Close()
 		}
-		defer w.Close()
 
 		if !cliContext.Bool("local") {
+			if cliContext.Bool("provenance") {
+				// The transfer API has no way to carry the option.
+				return errors.New("\"--provenance\" requires \"--local\"")
+			}
 			pf, done := ProgressHandler(ctx, os.Stdout)
 			defer done()
 
@@ -153,6 +220,10 @@ When '--all-platforms' is given all images in a manifest list must be available.
 			exportOpts = append(exportOpts, archive.WithSkipNonDistributableBlobs())
 		}
 
+		if cliContext.Bool("provenance") {
+			exportOpts = append(exportOpts, archive.WithProvenance())
+		}
+
 		is := client.ImageService()
 		for _, img := range images {
 			exportOpts = append(exportOpts, archive.WithImage(is, img))
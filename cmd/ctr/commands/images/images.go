@@ -39,17 +39,25 @@ var Command = &cli.Command{
 	Aliases: []string{"image", "i"},
 	Usage:   "Manage images",
 	Subcommands: cli.Commands{
+		appendCommand,
 		checkCommand,
+		combineCommand,
+		decryptCommand,
+		encryptCommand,
 		exportCommand,
 		importCommand,
 		inspectCommand,
+		inspectConfigCommand,
 		listCommand,
 		mountCommand,
 		unmountCommand,
 		pullCommand,
 		pushCommand,
 		pruneCommand,
+		prunePlatformsCommand,
+		referrersCommand,
 		removeCommand,
+		scanCommand,
 		tagCommand,
 		setLabelsCommand,
 		convertCommand,
@@ -69,6 +77,7 @@ var listCommand = &cli.Command{
 			Aliases: []string{"q"},
 			Usage:   "Print only the image refs",
 		},
+		commands.OutputFlag,
 	},
 	Action: func(cliContext *cli.Context) error {
 		var (
@@ -88,6 +97,46 @@ var listCommand = &cli.Command{
 		if err != nil {
 			return fmt.Errorf("failed to list images: %w", err)
 		}
+		if commands.OutputSelected(cliContext) {
+			type imageItem struct {
+				Ref       string            `json:"ref"`
+				MediaType string            `json:"mediaType"`
+				Digest    string            `json:"digest"`
+				Size      int64             `json:"size"`
+				Platforms []string          `json:"platforms,omitempty"`
+				Labels    map[string]string `json:"labels,omitempty"`
+			}
+			items := make([]imageItem, 0, len(imageList))
+			for _, image := range imageList {
+				size, err := image.Size(ctx, cs, platforms.Default())
+				if err != nil {
+					log.G(ctx).WithError(err).Errorf("failed calculating size for image %s", image.Name)
+				}
+				var ps []string
+				specs, err := images.Platforms(ctx, cs, image.Target)
+				if err != nil {
+					log.G(ctx).WithError(err).Errorf("failed resolving platform for image %s", image.Name)
+				} else {
+					psm := map[string]struct{}{}
+					for _, p := range specs {
+						psm[platforms.Format(p)] = struct{}{}
+					}
+					for p := range psm {
+						ps = append(ps, p)
+					}
+					sort.Stable(sort.StringSlice(ps))
+				}
+				items = append(items, imageItem{
+					Ref:       image.Name,
+					MediaType: image.Target.MediaType,
+					Digest:    image.Target.Digest.String(),
+					Size:      size,
+					Platforms: ps,
+					Labels:    image.Labels,
+				})
+			}
+			return commands.OutputList(cliContext, items)
+		}
 		if quiet {
 			for _, image := range imageList {
 				fmt.Println(image.Name)
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/urfave/cli/v2"
@@ -32,6 +33,7 @@ import (
 	"github.com/basuotian/containerd/core/transfer"
 	tarchive "github.com/basuotian/containerd/core/transfer/archive"
 	"github.com/basuotian/containerd/core/transfer/image"
+	"github.com/basuotian/containerd/core/transfer/layout"
 	"github.com/containerd/log"
 	"github.com/containerd/platforms"
 )
@@ -103,6 +105,10 @@ If foobar.tar contains an OCI ref named "latest" and anonymous ref "sha256:deadb
 			Name:  "sync-fs",
 			Usage: "Synchronize the underlying filesystem containing files when unpack images, false by default",
 		},
+		&cli.BoolFlag{
+			Name:  "oci-layout",
+			Usage: "Import from an OCI image layout directory instead of a tar archive",
+		},
 	}, append(commands.SnapshotterFlags, commands.LabelFlag)...),
 
 	Action: func(cliContext *cli.Context) error {
@@ -169,6 +175,20 @@ If foobar.tar contains an OCI ref named "latest" and anonymous ref "sha256:deadb
 
 			is := image.NewStore(cliContext.String("index-name"), opts...)
 
+			pf, done := ProgressHandler(ctx, os.Stdout)
+			defer done()
+
+			if cliContext.Bool("oci-layout") {
+				if cliContext.Bool("compress-blobs") {
+					return errors.New("cannot use --compress-blobs when importing an OCI layout directory")
+				}
+				dir, err := filepath.Abs(in)
+				if err != nil {
+					return err
+				}
+				return client.Transfer(ctx, layout.NewImageLayout(dir), is, transfer.WithProgress(pf))
+			}
+
 			var iopts []tarchive.ImportOpt
 
 			if cliContext.Bool("compress-blobs") {
@@ -187,9 +207,6 @@ If foobar.tar contains an OCI ref named "latest" and anonymous ref "sha256:deadb
 			}
 			iis := tarchive.NewImageImportStream(r, "", iopts...)
 
-			pf, done := ProgressHandler(ctx, os.Stdout)
-			defer done()
-
 			err := client.Transfer(ctx, iis, is, transfer.WithProgress(pf))
 			closeErr := r.Close()
 			if err != nil {
@@ -201,6 +218,10 @@ If foobar.tar contains an OCI ref named "latest" and anonymous ref "sha256:deadb
 
 		// Local logic
 
+		if cliContext.Bool("oci-layout") {
+			return errors.New("\"--oci-layout\" is not supported with \"--local\"")
+		}
+
 		prefix := cliContext.String("base-name")
 		if prefix == "" {
 			prefix = fmt.Sprintf("import-%s", time.Now().Format("2006-01-02"))
@@ -0,0 +1,70 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"errors"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+)
+
+var inspectConfigCommand = &cli.Command{
+	Name:      "inspect-config",
+	Usage:     "Show the effective process configuration of an image",
+	ArgsUsage: "[flags] <image>",
+	Description: `Show the process a container created from the image would start with.
+
+The entrypoint, cmd, environment and user of the image config are merged
+the same way container creation does, so the output can be used to debug
+why a container starts the wrong process. Use '--with-args' to preview
+the merge with arguments replacing the image CMD.
+`,
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "with-args",
+			Usage: "Arguments replacing the image CMD, as on container creation",
+		},
+		&cli.StringSliceFlag{
+			Name:  "env",
+			Usage: "Environment entries overriding the image defaults",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		ref := cliContext.Args().First()
+		if ref == "" {
+			return errors.New("please provide an image reference to inspect")
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		img, err := client.GetImage(ctx, ref)
+		if err != nil {
+			return err
+		}
+		process, err := img.EffectiveProcess(ctx, cliContext.StringSlice("with-args"), cliContext.StringSlice("env"))
+		if err != nil {
+			return err
+		}
+		commands.PrintAsJSON(process)
+		return nil
+	},
+}
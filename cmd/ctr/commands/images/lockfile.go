@@ -0,0 +1,102 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// imageLock maps image references to the digests they are pinned to. It is
+// persisted as a flat text file with one "<ref> <digest>" pair per line,
+// similar in spirit to a go.sum file, so that it diffs well under version
+// control.
+type imageLock map[string]digest.Digest
+
+// readImageLock parses the lock file at path. A missing file yields an empty
+// lock so that update mode can create it from scratch.
+func readImageLock(path string) (imageLock, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return imageLock{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	lock := imageLock{}
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		ref, dgst, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("malformed lock file %s: line %d: expected \"<ref> <digest>\"", path, line)
+		}
+		parsed, err := digest.Parse(strings.TrimSpace(dgst))
+		if err != nil {
+			return nil, fmt.Errorf("malformed lock file %s: line %d: %w", path, line, err)
+		}
+		lock[ref] = parsed
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// write atomically rewrites the lock file at path with the entries sorted by
+// reference.
+func (l imageLock) write(path string) error {
+	refs := make([]string, 0, len(l))
+	for ref := range l {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	var b strings.Builder
+	for _, ref := range refs {
+		fmt.Fprintf(&b, "%s %s\n", ref, l[ref])
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// verify checks that ref resolves to the digest pinned in the lock.
+func (l imageLock) verify(path, ref string, resolved digest.Digest) error {
+	pinned, ok := l[ref]
+	if !ok {
+		return fmt.Errorf("%q is not present in lock file %s, pull with --update-lockfile to pin it", ref, path)
+	}
+	if pinned != resolved {
+		return fmt.Errorf("refusing to pull %q: resolved digest %s does not match locked digest %s", ref, resolved, pinned)
+	}
+	return nil
+}
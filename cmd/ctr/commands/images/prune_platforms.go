@@ -0,0 +1,80 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/pkg/progress"
+	"github.com/containerd/platforms"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/urfave/cli/v2"
+)
+
+var prunePlatformsCommand = &cli.Command{
+	Name:      "prune-platforms",
+	Usage:     "Remove platforms of a multi-arch image that are not needed on this node",
+	ArgsUsage: "[flags] <ref> [<ref>, ...]",
+	Description: `Rewrite the index of a multi-arch image to the kept platforms and delete
+content blobs that only the removed platforms reference. Blobs shared with
+other images are left in place. This reclaims space on single-arch nodes
+that pulled an image with --all-platforms.`,
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "keep",
+			Usage: "Platform to keep (e.g. linux/amd64), may be repeated. Defaults to the host platform",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		if cliContext.NArg() == 0 {
+			return errors.New("please provide an image reference to prune")
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		matcher := platforms.Default()
+		if keep := cliContext.StringSlice("keep"); len(keep) > 0 {
+			var ps []ocispec.Platform
+			for _, s := range keep {
+				p, err := platforms.Parse(s)
+				if err != nil {
+					return fmt.Errorf("invalid platform %q: %w", s, err)
+				}
+				ps = append(ps, p)
+			}
+			matcher = platforms.Any(ps...)
+		}
+
+		for _, ref := range cliContext.Args().Slice() {
+			img, deleted, err := client.PrunePlatforms(ctx, ref, matcher)
+			if err != nil {
+				return err
+			}
+			var reclaimed int64
+			for _, d := range deleted {
+				reclaimed += d.Size
+			}
+			fmt.Printf("%s@%s: deleted %d blobs, reclaimed %s\n", img.Name, img.Target.Digest, len(deleted), progress.Bytes(reclaimed))
+		}
+		return nil
+	},
+}
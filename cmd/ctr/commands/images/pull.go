@@ -89,6 +89,14 @@ command. As part of this process, we do the following:
 			Name:  "sync-fs",
 			Usage: "Synchronize the underlying filesystem containing files when unpack images, false by default",
 		},
+		&cli.StringFlag{
+			Name:  "lockfile",
+			Usage: "Path to a digest lock file, refuse to pull a tag whose resolved digest does not match its locked digest",
+		},
+		&cli.BoolFlag{
+			Name:  "update-lockfile",
+			Usage: "Pin the resolved digest in the lock file instead of verifying against it, requires --lockfile",
+		},
 	),
 	Action: func(cliContext *cli.Context) error {
 		var (
@@ -104,6 +112,35 @@ command. As part of this process, we do the following:
 		}
 		defer cancel()
 
+		var (
+			lockfilePath   = cliContext.String("lockfile")
+			updateLockfile = cliContext.Bool("update-lockfile")
+			lock           imageLock
+		)
+		if updateLockfile && lockfilePath == "" {
+			return errors.New("\"--update-lockfile\" requires \"--lockfile\" flag")
+		}
+		if lockfilePath != "" {
+			if lock, err = readImageLock(lockfilePath); err != nil {
+				return err
+			}
+			// resolve the tag up front so that a mismatch is caught before
+			// any content is fetched
+			resolver, err := commands.GetResolver(ctx, cliContext)
+			if err != nil {
+				return err
+			}
+			_, desc, err := resolver.Resolve(ctx, ref)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %q for lock file check: %w", ref, err)
+			}
+			if updateLockfile {
+				lock[ref] = desc.Digest
+			} else if err := lock.verify(lockfilePath, ref, desc.Digest); err != nil {
+				return err
+			}
+		}
+
 		if !cliContext.Bool("local") {
 			unsupportedFlags := []string{"max-concurrent-downloads", "print-chainid",
 				"skip-verify", "tlscacert", "tlscert", "tlskey", // RegistryFlags
@@ -175,7 +212,13 @@ command. As part of this process, we do the following:
 			pf, done := ProgressHandler(ctx, os.Stdout)
 			defer done()
 
-			return client.Transfer(ctx, reg, is, transfer.WithProgress(pf))
+			if err := client.Transfer(ctx, reg, is, transfer.WithProgress(pf)); err != nil {
+				return err
+			}
+			if updateLockfile {
+				return lock.write(lockfilePath)
+			}
+			return nil
 		}
 
 		ctx, done, err := client.WithLease(ctx)
@@ -233,6 +276,9 @@ command. As part of this process, we do the following:
 			}
 		}
 		fmt.Printf("done: %s\t\n", time.Since(start))
+		if updateLockfile {
+			return lock.write(lockfilePath)
+		}
 		return nil
 	},
 }
@@ -0,0 +1,117 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/remotes"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/urfave/cli/v2"
+)
+
+var referrersCommand = &cli.Command{
+	Name:      "referrers",
+	Usage:     "List artifacts attached to an image (signatures, SBOMs, attestations, ...)",
+	ArgsUsage: "[flags] <ref>",
+	Flags: append(commands.RegistryFlags, []cli.Flag{
+		&cli.StringFlag{
+			Name:  "artifact-type",
+			Usage: "Only list referrers with this artifact type",
+		},
+		&cli.BoolFlag{
+			Name:  "no-fetch",
+			Usage: "Do not cache the artifact manifests in the content store",
+		},
+	}...),
+	Action: func(cliContext *cli.Context) error {
+		ref := cliContext.Args().First()
+		if ref == "" {
+			return errors.New("please provide an image reference to list referrers of")
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		resolver, err := commands.GetResolver(ctx, cliContext)
+		if err != nil {
+			return err
+		}
+		lister, ok := resolver.(remotes.ReferrersLister)
+		if !ok {
+			return fmt.Errorf("resolver does not support listing referrers: %w", errdefs.ErrNotImplemented)
+		}
+
+		name, desc, err := resolver.Resolve(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %q: %w", ref, err)
+		}
+
+		index, err := lister.Referrers(ctx, name, desc.Digest, cliContext.String("artifact-type"))
+		if err != nil {
+			return fmt.Errorf("failed to list referrers of %q: %w", ref, err)
+		}
+
+		if !cliContext.Bool("no-fetch") && len(index.Manifests) > 0 {
+			ctx, done, err := client.WithLease(ctx)
+			if err != nil {
+				return err
+			}
+			defer done(ctx)
+
+			fetcher, err := resolver.Fetcher(ctx, name)
+			if err != nil {
+				return err
+			}
+			cs := client.ContentStore()
+			for _, m := range index.Manifests {
+				if _, err := cs.Info(ctx, m.Digest); err == nil {
+					continue
+				}
+				rc, err := fetcher.Fetch(ctx, m)
+				if err != nil {
+					log.G(ctx).WithError(err).Warnf("failed to fetch referrer %s", m.Digest)
+					continue
+				}
+				err = content.WriteBlob(ctx, cs, remotes.MakeRefKey(ctx, m), rc, m)
+				rc.Close()
+				if err != nil {
+					log.G(ctx).WithError(err).Warnf("failed to store referrer %s", m.Digest)
+				}
+			}
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 1, 8, 4, ' ', 0)
+		fmt.Fprintln(w, "DIGEST\tARTIFACT TYPE\tSIZE")
+		for _, m := range index.Manifests {
+			artifactType := m.ArtifactType
+			if artifactType == "" {
+				artifactType = m.MediaType
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\n", m.Digest, artifactType, m.Size)
+		}
+		return w.Flush()
+	},
+}
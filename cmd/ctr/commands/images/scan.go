@@ -0,0 +1,128 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/images/archive"
+	"github.com/containerd/platforms"
+	"github.com/urfave/cli/v2"
+)
+
+var scanCommand = &cli.Command{
+	Name:      "scan",
+	Usage:     "Run an SBOM scanner hook over an image's layers",
+	ArgsUsage: "[flags] <ref>",
+	Description: `Exports the image as an OCI archive into a temporary file and invokes the
+configured scanner command with the archive path appended as its last
+argument. The scanner's standard output, typically an SBOM document, is
+written to --output.
+
+Example:
+	ctr images scan --scanner "syft scan" --output image.sbom docker.io/library/hello-world:latest
+`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "scanner",
+			Usage: "Scanner command to invoke on the exported OCI archive, split on whitespace",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "Path to write the scanner output to, \"-\" for stdout",
+			Value:   "-",
+		},
+		&cli.StringSliceFlag{
+			Name:  "platform",
+			Usage: "Export content from a specific platform",
+			Value: cli.NewStringSlice(),
+		},
+		&cli.BoolFlag{
+			Name:  "all-platforms",
+			Usage: "Export content from all platforms",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		ref := cliContext.Args().First()
+		if ref == "" {
+			return errors.New("please provide an image reference to scan")
+		}
+		scanner := strings.Fields(cliContext.String("scanner"))
+		if len(scanner) == 0 {
+			return errors.New("please provide a scanner command with --scanner")
+		}
+
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		exportOpts := []archive.ExportOpt{
+			archive.WithImage(client.ImageService(), ref),
+		}
+		if pss := cliContext.StringSlice("platform"); len(pss) > 0 {
+			all, err := platforms.ParseAll(pss)
+			if err != nil {
+				return err
+			}
+			exportOpts = append(exportOpts, archive.WithPlatform(platforms.Ordered(all...)))
+		} else {
+			exportOpts = append(exportOpts, archive.WithPlatform(platforms.DefaultStrict()))
+		}
+		if cliContext.Bool("all-platforms") {
+			exportOpts = append(exportOpts, archive.WithAllPlatforms())
+		}
+
+		tmp, err := os.CreateTemp("", "ctr-scan-")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+
+		if err := client.Export(ctx, tmp, exportOpts...); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to export %q for scanning: %w", ref, err)
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+
+		out := os.Stdout
+		if output := cliContext.String("output"); output != "-" {
+			if out, err = os.Create(output); err != nil {
+				return err
+			}
+			defer out.Close()
+		}
+
+		args := append(scanner[1:], tmp.Name())
+		cmd := exec.CommandContext(ctx, scanner[0], args...)
+		cmd.Stdout = out
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("scanner %v: %w", cmd.Args, err)
+		}
+		return nil
+	},
+}
@@ -17,22 +17,33 @@
 package images
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
 	"github.com/urfave/cli/v2"
 
 	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/images"
 	"github.com/basuotian/containerd/core/transfer/image"
-	"github.com/containerd/errdefs"
-	"github.com/distribution/reference"
 )
 
 var tagCommand = &cli.Command{
-	Name:        "tag",
-	Usage:       "Tag an image",
-	ArgsUsage:   "[flags] <source_ref> <target_ref> [<target_ref>, ...]",
-	Description: `Tag an image for use in containerd.`,
+	Name:      "tag",
+	Usage:     "Tag an image",
+	ArgsUsage: "[flags] <source_ref> <target_ref> [<target_ref>, ...]",
+	Description: `Tag an image for use in containerd.
+
+With --from-file, references are read from a mapping file with one
+"source_ref=target_ref" entry per line instead of the arguments, and all
+entries are applied transactionally: when any of them fails, tags already
+created by the run are rolled back.`,
 	Flags: []cli.Flag{
 		&cli.BoolFlag{
 			Name:  "force",
@@ -46,8 +57,25 @@ var tagCommand = &cli.Command{
 			Name:  "skip-reference-check",
 			Usage: "Skip the strict check for reference names",
 		},
+		&cli.StringFlag{
+			Name:  "from-file",
+			Usage: "Bulk tag from a mapping file with one source_ref=target_ref per line, all or nothing",
+		},
+		&cli.StringFlag{
+			Name:  "force-digest-check",
+			Usage: "Verify source image digests against the expected list in the given file, one source_ref=digest per line, before tagging anything",
+		},
 	},
 	Action: func(cliContext *cli.Context) error {
+		if cliContext.String("from-file") != "" {
+			if cliContext.NArg() > 0 {
+				return errors.New("--from-file cannot be combined with reference arguments")
+			}
+			return bulkTag(cliContext)
+		}
+		if cliContext.String("force-digest-check") != "" {
+			return errors.New("--force-digest-check requires --from-file")
+		}
 		var (
 			ref = cliContext.Args().First()
 		)
@@ -119,3 +147,145 @@ var tagCommand = &cli.Command{
 		return nil
 	},
 }
+
+// parseRefMapping reads a mapping file with one "key=value" entry per
+// line. Blank lines and lines starting with # are ignored.
+func parseRefMapping(path string) ([][2]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mappings [][2]string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("%s:%d: expected key=value, got %q", path, line, text)
+		}
+		mappings = append(mappings, [2]string{strings.TrimSpace(key), strings.TrimSpace(value)})
+	}
+	return mappings, scanner.Err()
+}
+
+// bulkTag applies the mappings of --from-file with all or nothing
+// semantics: every entry is validated before any tag is created, and a
+// failure while creating rolls back the tags created so far.
+func bulkTag(cliContext *cli.Context) error {
+	mappings, err := parseRefMapping(cliContext.String("from-file"))
+	if err != nil {
+		return err
+	}
+	if len(mappings) == 0 {
+		return errors.New("mapping file contains no entries")
+	}
+
+	var expected map[string]digest.Digest
+	if digestFile := cliContext.String("force-digest-check"); digestFile != "" {
+		list, err := parseRefMapping(digestFile)
+		if err != nil {
+			return err
+		}
+		expected = make(map[string]digest.Digest, len(list))
+		for _, entry := range list {
+			dgst, err := digest.Parse(entry[1])
+			if err != nil {
+				return fmt.Errorf("invalid digest for %q: %w", entry[0], err)
+			}
+			expected[entry[0]] = dgst
+		}
+	}
+
+	client, ctx, cancel, err := commands.NewClient(cliContext)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	ctx, done, err := client.WithLease(ctx)
+	if err != nil {
+		return err
+	}
+	defer done(ctx)
+
+	imageService := client.ImageService()
+
+	// Validate every entry before touching the image store.
+	creates := make([]images.Image, 0, len(mappings))
+	for _, m := range mappings {
+		sourceRef, targetRef := m[0], m[1]
+		if !cliContext.Bool("skip-reference-check") {
+			if _, err := reference.ParseAnyReference(targetRef); err != nil {
+				return fmt.Errorf("error parsing reference: %q is not a valid repository/tag %v", targetRef, err)
+			}
+		}
+		img, err := imageService.Get(ctx, sourceRef)
+		if err != nil {
+			return fmt.Errorf("source %q: %w", sourceRef, err)
+		}
+		if expected != nil {
+			want, ok := expected[sourceRef]
+			if !ok {
+				return fmt.Errorf("no expected digest for source %q", sourceRef)
+			}
+			if img.Target.Digest != want {
+				return fmt.Errorf("source %q has digest %s, expected %s", sourceRef, img.Target.Digest, want)
+			}
+		}
+		img.Name = targetRef
+		creates = append(creates, img)
+	}
+
+	var (
+		created  []string
+		replaced []images.Image
+	)
+	rollback := func() {
+		for _, img := range replaced {
+			if err := imageService.Delete(ctx, img.Name); err != nil && !errdefs.IsNotFound(err) {
+				log.L.WithError(err).Warnf("failed to remove tag %q while rolling back", img.Name)
+				continue
+			}
+			if _, err := imageService.Create(ctx, img); err != nil {
+				log.L.WithError(err).Warnf("failed to restore replaced tag %q", img.Name)
+			}
+		}
+		for _, name := range created {
+			if err := imageService.Delete(ctx, name); err != nil {
+				log.L.WithError(err).Warnf("failed to roll back tag %q", name)
+			}
+		}
+	}
+	for _, img := range creates {
+		if _, err := imageService.Create(ctx, img); err != nil {
+			if errdefs.IsAlreadyExists(err) && cliContext.Bool("force") {
+				previous, err := imageService.Get(ctx, img.Name)
+				if err == nil {
+					err = imageService.Delete(ctx, img.Name)
+				}
+				if err == nil {
+					_, err = imageService.Create(ctx, img)
+				}
+				if err != nil {
+					rollback()
+					return fmt.Errorf("bulk tag aborted at %q: %w", img.Name, err)
+				}
+				replaced = append(replaced, previous)
+			} else {
+				rollback()
+				return fmt.Errorf("bulk tag aborted at %q: %w", img.Name, err)
+			}
+		} else {
+			created = append(created, img.Name)
+		}
+	}
+	for _, img := range creates {
+		fmt.Println(img.Name)
+	}
+	return nil
+}
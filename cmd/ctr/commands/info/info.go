@@ -30,6 +30,9 @@ type Info struct {
 var Command = &cli.Command{
 	Name:  "info",
 	Usage: "Print the server info",
+	Subcommands: cli.Commands{
+		setMaintenanceCommand,
+	},
 	Action: func(cliContext *cli.Context) error {
 		client, ctx, cancel, err := commands.NewClient(cliContext)
 		if err != nil {
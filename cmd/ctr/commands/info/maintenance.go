@@ -0,0 +1,67 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package info
+
+import (
+	"fmt"
+
+	"github.com/containerd/typeurl/v2"
+	"github.com/urfave/cli/v2"
+
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/metadata"
+	"github.com/basuotian/containerd/plugins"
+)
+
+var setMaintenanceCommand = &cli.Command{
+	Name:      "set-maintenance",
+	Usage:     "Toggle the daemon read-only maintenance mode, in which metadata mutations are rejected while reads keep working",
+	ArgsUsage: "on|off",
+	Action: func(cliContext *cli.Context) error {
+		var readOnly bool
+		switch arg := cliContext.Args().First(); arg {
+		case "on":
+			readOnly = true
+		case "off":
+			readOnly = false
+		default:
+			return fmt.Errorf("expected \"on\" or \"off\", got %q", arg)
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		resp, err := client.IntrospectionService().PluginInfo(ctx, string(plugins.MetadataPlugin), "bolt", &metadata.MaintenanceRequest{
+			ReadOnly: &readOnly,
+		})
+		if err != nil {
+			return err
+		}
+		var info metadata.MaintenanceResponse
+		if err := typeurl.UnmarshalTo(resp.Extra, &info); err != nil {
+			return err
+		}
+		if info.ReadOnly {
+			fmt.Println("maintenance mode is on")
+		} else {
+			fmt.Println("maintenance mode is off")
+		}
+		return nil
+	},
+}
@@ -39,6 +39,11 @@ var Command = &cli.Command{
 			Aliases: []string{"r"},
 			Usage:   "Replace any binaries or libs in the opt directory",
 		},
+		&cli.BoolFlag{
+			Name:    "upgrade",
+			Aliases: []string{"u"},
+			Usage:   "Upgrade a previous install of the same package, removing files the new version no longer ships",
+		},
 		&cli.StringFlag{
 			Name:  "path",
 			Usage: "Set an optional install path other than the managed opt directory",
@@ -62,6 +67,9 @@ var Command = &cli.Command{
 		if cliContext.Bool("replace") {
 			opts = append(opts, containerd.WithInstallReplace)
 		}
+		if cliContext.Bool("upgrade") {
+			opts = append(opts, containerd.WithInstallUpgrade)
+		}
 		if path := cliContext.String("path"); path != "" {
 			opts = append(opts, containerd.WithInstallPath(path))
 		}
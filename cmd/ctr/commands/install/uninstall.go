@@ -0,0 +1,55 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package install
+
+import (
+	"errors"
+
+	containerd "github.com/basuotian/containerd/client"
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/urfave/cli/v2"
+)
+
+// UninstallCommand removes a package installed with install
+var UninstallCommand = &cli.Command{
+	Name:        "uninstall",
+	Usage:       "Remove an installed package",
+	ArgsUsage:   "<ref>",
+	Description: "remove the files a package installed, verifying their checksums against the install manifest",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "path",
+			Usage: "Only remove the install below an optional install path",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		ref := cliContext.Args().First()
+		if ref == "" {
+			return errors.New("ref must be provided")
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		var opts []containerd.InstallOpts
+		if path := cliContext.String("path"); path != "" {
+			opts = append(opts, containerd.WithInstallPath(path))
+		}
+		return client.Uninstall(ctx, ref, opts...)
+	},
+}
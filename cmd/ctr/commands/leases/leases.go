@@ -26,6 +26,7 @@ import (
 
 	"github.com/basuotian/containerd/cmd/ctr/commands"
 	"github.com/basuotian/containerd/core/leases"
+	"github.com/basuotian/containerd/pkg/progress"
 	"github.com/urfave/cli/v2"
 )
 
@@ -37,6 +38,7 @@ var Command = &cli.Command{
 		listCommand,
 		createCommand,
 		deleteCommand,
+		usageCommand,
 	},
 }
 
@@ -53,6 +55,7 @@ var listCommand = &cli.Command{
 			Aliases: []string{"q"},
 			Usage:   "Print only the blob digest",
 		},
+		commands.OutputFlag,
 	},
 	Action: func(cliContext *cli.Context) error {
 		var (
@@ -71,6 +74,22 @@ var listCommand = &cli.Command{
 		if err != nil {
 			return fmt.Errorf("failed to list leases: %w", err)
 		}
+		if commands.OutputSelected(cliContext) {
+			type leaseItem struct {
+				ID        string            `json:"id"`
+				CreatedAt time.Time         `json:"createdAt"`
+				Labels    map[string]string `json:"labels,omitempty"`
+			}
+			items := make([]leaseItem, 0, len(leaseList))
+			for _, l := range leaseList {
+				items = append(items, leaseItem{
+					ID:        l.ID,
+					CreatedAt: l.CreatedAt,
+					Labels:    l.Labels,
+				})
+			}
+			return commands.OutputList(cliContext, items)
+		}
 		if quiet {
 			for _, l := range leaseList {
 				fmt.Println(l.ID)
@@ -197,3 +216,47 @@ var deleteCommand = &cli.Command{
 		return nil
 	},
 }
+
+var usageCommand = &cli.Command{
+	Name:        "usage",
+	Usage:       "Display disk usage retained by a lease",
+	ArgsUsage:   "[flags] <lease id> ...",
+	Description: "display the content and snapshot disk usage retained by a lease",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "exclusive",
+			Usage: "Only count resources not referenced by any other lease, estimating what deleting the lease would free",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		var lids = cliContext.Args().Slice()
+		if len(lids) == 0 {
+			return cli.ShowSubcommandHelp(cliContext)
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		var (
+			ls        = client.LeasesService()
+			exclusive = cliContext.Bool("exclusive")
+		)
+		tw := tabwriter.NewWriter(os.Stdout, 1, 8, 1, ' ', 0)
+		fmt.Fprintln(tw, "ID\tCONTENT\tSNAPSHOTS\tINODES\t")
+		for _, lid := range lids {
+			u, err := leases.ResourceUsage(ctx, ls, client.ContentStore(), client.SnapshotService, leases.Lease{ID: lid}, exclusive)
+			if err != nil {
+				return fmt.Errorf("failed to compute usage for lease %q: %w", lid, err)
+			}
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t\n",
+				lid,
+				progress.Bytes(u.ContentBytes),
+				progress.Bytes(u.SnapshotBytes),
+				u.SnapshotInodes)
+		}
+
+		return tw.Flush()
+	},
+}
@@ -0,0 +1,247 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package namespaces
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	containerd "github.com/basuotian/containerd/client"
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/leases"
+	"github.com/basuotian/containerd/core/snapshots"
+	"github.com/basuotian/containerd/pkg/namespaces"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/urfave/cli/v2"
+)
+
+var cleanCommand = &cli.Command{
+	Name:      "clean",
+	Usage:     "Tear down all resources in a namespace so it can be removed",
+	ArgsUsage: "[flags] <name>",
+	Description: `Stop all tasks and remove containers, images, leases, snapshots and
+content in the given namespace through the regular APIs, then remove the
+namespace itself. Use --dry-run to list what would be removed. The
+cleanup only runs with --force and after the namespace name has been
+typed to confirm.
+`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "List the resources that would be removed without removing anything",
+		},
+		&cli.BoolFlag{
+			Name:    "force",
+			Aliases: []string{"f"},
+			Usage:   "Allow removal of all resources in the namespace",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		target := cliContext.Args().First()
+		if target == "" {
+			return errors.New("please specify a namespace")
+		}
+		dryRun := cliContext.Bool("dry-run")
+		if !dryRun && !cliContext.Bool("force") {
+			return errors.New("cleaning a namespace removes all of its resources, pass --force to proceed or --dry-run to list them")
+		}
+
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		// Verify the namespace exists before touching anything.
+		if _, err := client.NamespaceService().Labels(ctx, target); err != nil {
+			return fmt.Errorf("unable to read namespace %v: %w", target, err)
+		}
+		ctx = namespaces.WithNamespace(ctx, target)
+
+		if !dryRun {
+			fmt.Printf("This removes all resources in namespace %q. Type the namespace name to confirm: ", target)
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(line) != target {
+				return errors.New("confirmation does not match the namespace name, aborting")
+			}
+		}
+
+		if err := cleanContainers(ctx, client, dryRun); err != nil {
+			return err
+		}
+		if err := cleanImages(ctx, client, dryRun); err != nil {
+			return err
+		}
+		if err := cleanLeases(ctx, client, dryRun); err != nil {
+			return err
+		}
+		if err := cleanSnapshots(ctx, client, dryRun); err != nil {
+			return err
+		}
+		if err := cleanContent(ctx, client, dryRun); err != nil {
+			return err
+		}
+
+		if dryRun {
+			fmt.Printf("namespace %v would be removed\n", target)
+			return nil
+		}
+		if err := client.NamespaceService().Delete(ctx, target); err != nil {
+			return fmt.Errorf("unable to delete namespace %v: %w", target, err)
+		}
+		fmt.Println(target)
+		return nil
+	},
+}
+
+func cleanContainers(ctx context.Context, client *containerd.Client, dryRun bool) error {
+	containers, err := client.Containers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, container := range containers {
+		if dryRun {
+			fmt.Printf("container %v\n", container.ID())
+			continue
+		}
+		task, err := container.Task(ctx, nil)
+		if err == nil {
+			// Delete with process kill stops the task, including all of
+			// its execs, before removing it.
+			if _, err := task.Delete(ctx, containerd.WithProcessKill); err != nil && !errdefs.IsNotFound(err) {
+				return fmt.Errorf("unable to delete task of container %v: %w", container.ID(), err)
+			}
+		} else if !errdefs.IsNotFound(err) {
+			return fmt.Errorf("unable to get task of container %v: %w", container.ID(), err)
+		}
+		if err := container.Delete(ctx, containerd.WithSnapshotCleanup); err != nil && !errdefs.IsNotFound(err) {
+			return fmt.Errorf("unable to delete container %v: %w", container.ID(), err)
+		}
+		fmt.Printf("container %v\n", container.ID())
+	}
+	return nil
+}
+
+func cleanImages(ctx context.Context, client *containerd.Client, dryRun bool) error {
+	is := client.ImageService()
+	imgs, err := is.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, img := range imgs {
+		if !dryRun {
+			if err := is.Delete(ctx, img.Name); err != nil && !errdefs.IsNotFound(err) {
+				return fmt.Errorf("unable to delete image %v: %w", img.Name, err)
+			}
+		}
+		fmt.Printf("image %v\n", img.Name)
+	}
+	return nil
+}
+
+func cleanLeases(ctx context.Context, client *containerd.Client, dryRun bool) error {
+	ls := client.LeasesService()
+	ll, err := ls.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, l := range ll {
+		if !dryRun {
+			if err := ls.Delete(ctx, l, leases.SynchronousDelete); err != nil && !errdefs.IsNotFound(err) {
+				return fmt.Errorf("unable to delete lease %v: %w", l.ID, err)
+			}
+		}
+		fmt.Printf("lease %v\n", l.ID)
+	}
+	return nil
+}
+
+func cleanSnapshots(ctx context.Context, client *containerd.Client, dryRun bool) error {
+	resp, err := client.IntrospectionService().Plugins(ctx, "type==io.containerd.snapshotter.v1")
+	if err != nil {
+		return err
+	}
+	for _, p := range resp.Plugins {
+		if p.InitErr != nil {
+			continue
+		}
+		sn := client.SnapshotService(p.ID)
+		var keys []string
+		if err := sn.Walk(ctx, func(ctx context.Context, info snapshots.Info) error {
+			keys = append(keys, info.Name)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("unable to walk snapshotter %v: %w", p.ID, err)
+		}
+		if dryRun {
+			for _, key := range keys {
+				fmt.Printf("snapshot %v/%v\n", p.ID, key)
+			}
+			continue
+		}
+		// Snapshots can only be removed when they have no children, so
+		// keep iterating until no removal makes progress anymore.
+		for len(keys) > 0 {
+			var remaining []string
+			for _, key := range keys {
+				if err := sn.Remove(ctx, key); err != nil {
+					if errdefs.IsNotFound(err) {
+						continue
+					}
+					remaining = append(remaining, key)
+					continue
+				}
+				fmt.Printf("snapshot %v/%v\n", p.ID, key)
+			}
+			if len(remaining) == len(keys) {
+				log.G(ctx).Warnf("unable to remove %d snapshots from snapshotter %v", len(remaining), p.ID)
+				break
+			}
+			keys = remaining
+		}
+	}
+	return nil
+}
+
+func cleanContent(ctx context.Context, client *containerd.Client, dryRun bool) error {
+	cs := client.ContentStore()
+	var dgsts []content.Info
+	if err := cs.Walk(ctx, func(info content.Info) error {
+		dgsts = append(dgsts, info)
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, info := range dgsts {
+		if !dryRun {
+			if err := cs.Delete(ctx, info.Digest); err != nil && !errdefs.IsNotFound(err) {
+				return fmt.Errorf("unable to delete content %v: %w", info.Digest, err)
+			}
+		}
+		fmt.Printf("content %v\n", info.Digest)
+	}
+	return nil
+}
@@ -36,6 +36,7 @@ var Command = &cli.Command{
 	Aliases: []string{"namespace", "ns"},
 	Usage:   "Manage namespaces",
 	Subcommands: cli.Commands{
+		cleanCommand,
 		createCommand,
 		listCommand,
 		removeCommand,
@@ -101,6 +102,7 @@ var listCommand = &cli.Command{
 			Aliases: []string{"q"},
 			Usage:   "Print only the namespace name",
 		},
+		commands.OutputFlag,
 	},
 	Action: func(cliContext *cli.Context) error {
 		quiet := cliContext.Bool("quiet")
@@ -115,6 +117,25 @@ var listCommand = &cli.Command{
 			return err
 		}
 
+		if commands.OutputSelected(cliContext) {
+			type namespaceItem struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels,omitempty"`
+			}
+			items := make([]namespaceItem, 0, len(nss))
+			for _, ns := range nss {
+				labels, err := namespaces.Labels(ctx, ns)
+				if err != nil {
+					return err
+				}
+				items = append(items, namespaceItem{
+					Name:   ns,
+					Labels: labels,
+				})
+			}
+			return commands.OutputList(cliContext, items)
+		}
+
 		if quiet {
 			for _, ns := range nss {
 				fmt.Println(ns)
@@ -0,0 +1,263 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package pprof
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containerd/log"
+	"github.com/urfave/cli/v2"
+)
+
+var pprofCollectCommand = &cli.Command{
+	Name:  "collect",
+	Usage: "Periodically capture CPU, heap and goroutine profiles over a duration",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "Directory to write the captured profiles to",
+			Value:   "containerd-pprof",
+		},
+		&cli.DurationFlag{
+			Name:  "duration",
+			Usage: "Total time to collect profiles for, 0 collects until interrupted",
+			Value: 10 * time.Minute,
+		},
+		&cli.DurationFlag{
+			Name:  "interval",
+			Usage: "Time between two capture rounds",
+			Value: time.Minute,
+		},
+		&cli.DurationFlag{
+			Name:  "cpu-seconds",
+			Usage: "Duration of each CPU profile, must not exceed the interval",
+			Value: 30 * time.Second,
+		},
+		&cli.IntFlag{
+			Name:  "keep",
+			Usage: "Number of captures kept per profile kind, older ones are rotated out, 0 keeps all",
+			Value: 10,
+		},
+		&cli.BoolFlag{
+			Name:  "tar",
+			Usage: "Write the collected profiles as <output>.tar.gz and remove the directory",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		var (
+			dir        = cliContext.String("output")
+			duration   = cliContext.Duration("duration")
+			interval   = cliContext.Duration("interval")
+			cpuSeconds = cliContext.Duration("cpu-seconds")
+			keep       = cliContext.Int("keep")
+		)
+		if interval <= 0 {
+			return fmt.Errorf("interval must be positive, got %v", interval)
+		}
+		if cpuSeconds > interval {
+			return fmt.Errorf("cpu profile duration %v must not exceed the interval %v", cpuSeconds, interval)
+		}
+		client, err := getPProfClient(cliContext)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		deadline := time.Time{}
+		if duration > 0 {
+			deadline = time.Now().Add(duration)
+		}
+
+		for round := 1; ; round++ {
+			started := time.Now()
+			log.L.Infof("capturing profile round %d into %s", round, dir)
+			if err := captureProfiles(client, dir, cpuSeconds); err != nil {
+				log.L.WithError(err).Warn("profile capture failed")
+			}
+			if keep > 0 {
+				if err := rotateProfiles(dir, keep); err != nil {
+					log.L.WithError(err).Warn("profile rotation failed")
+				}
+			}
+			wait := interval - time.Since(started)
+			if wait < 0 {
+				wait = 0
+			}
+			if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+				break
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				log.L.Info("interrupted, stopping collection")
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		if cliContext.Bool("tar") {
+			tarball := dir + ".tar.gz"
+			if err := tarProfiles(dir, tarball); err != nil {
+				return fmt.Errorf("failed to write %s: %w", tarball, err)
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+			fmt.Println(tarball)
+		}
+		return nil
+	},
+}
+
+// profileKinds maps each profile file prefix to the debug endpoint it is
+// captured from. The CPU profile is handled separately because of its
+// seconds parameter.
+var profileKinds = map[string]string{
+	"heap":      "/debug/pprof/heap?debug=0",
+	"goroutine": "/debug/pprof/goroutine?debug=0",
+}
+
+func captureProfiles(client *http.Client, dir string, cpuSeconds time.Duration) error {
+	timestamp := time.Now().UTC().Format("20060102T150405Z")
+
+	kinds := map[string]string{
+		"cpu": fmt.Sprintf("/debug/pprof/profile?seconds=%v&debug=0", cpuSeconds.Seconds()),
+	}
+	for kind, uri := range profileKinds {
+		kinds[kind] = uri
+	}
+
+	var firstErr error
+	for kind, uri := range kinds {
+		if err := captureProfile(client, uri, filepath.Join(dir, fmt.Sprintf("%s-%s.pprof", kind, timestamp))); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to capture %s profile: %w", kind, err)
+			}
+		}
+	}
+	return firstErr
+}
+
+func captureProfile(client *http.Client, uri, path string) error {
+	output, err := httpGetRequest(client, uri)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, output); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// rotateProfiles removes the oldest captures of each profile kind so that
+// at most keep captures per kind remain. The timestamped names sort
+// chronologically.
+func rotateProfiles(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	byKind := map[string][]string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		kind, _, ok := strings.Cut(name, "-")
+		if !ok || !strings.HasSuffix(name, ".pprof") {
+			continue
+		}
+		byKind[kind] = append(byKind[kind], name)
+	}
+	for _, names := range byKind {
+		if len(names) <= keep {
+			continue
+		}
+		sort.Strings(names)
+		for _, name := range names[:len(names)-keep] {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func tarProfiles(dir, tarball string) error {
+	f, err := os.Create(tarball)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(filepath.Join(filepath.Base(dir), entry.Name()))
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		src, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
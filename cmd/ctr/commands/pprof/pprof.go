@@ -46,6 +46,7 @@ var Command = &cli.Command{
 	},
 	Subcommands: []*cli.Command{
 		pprofBlockCommand,
+		pprofCollectCommand,
 		pprofGoroutinesCommand,
 		pprofHeapCommand,
 		pprofProfileCommand,
@@ -108,7 +108,11 @@ func GetResolver(ctx context.Context, cliContext *cli.Context) (remotes.Resolver
 
 	options.Hosts = config.ConfigureHosts(ctx, hostOptions)
 
-	return docker.NewResolver(options), nil
+	resolver := docker.NewResolver(options)
+	if cliContext.Bool("airgap") {
+		resolver = remotes.AirgapResolver(resolver, cliContext.StringSlice("airgap-allow")...)
+	}
+	return resolver, nil
 }
 
 func resolverDefaultTLS(cliContext *cli.Context) (*tls.Config, error) {
@@ -55,7 +55,7 @@ func withMounts(cliContext *cli.Context) oci.SpecOpts {
 	}
 }
 
-// parseMountFlag parses a mount string in the form "type=foo,source=/path,destination=/target,options=rbind:rw"
+// parseMountFlag parses a mount string in the form "type=foo,source=/path,destination=/target,options=rbind:rw,propagation=rslave"
 func parseMountFlag(m string) (specs.Mount, error) {
 	mount := specs.Mount{}
 	r := csv.NewReader(strings.NewReader(m))
@@ -80,6 +80,11 @@ func parseMountFlag(m string) (specs.Mount, error) {
 			mount.Destination = val
 		case "options":
 			mount.Options = strings.Split(val, ":")
+		case "propagation":
+			if !oci.ValidPropagationMode(val) {
+				return mount, fmt.Errorf("invalid mount propagation mode %q", val)
+			}
+			mount.Options = append(mount.Options, val)
 		default:
 			return mount, fmt.Errorf("mount option %q not supported", key)
 		}
@@ -115,6 +120,10 @@ var Command = &cli.Command{
 			Name:  "fifo-dir",
 			Usage: "Directory used for storing IO FIFOs",
 		},
+		&cli.BoolFlag{
+			Name:  "remote-io",
+			Usage: "Forward IO over the streaming service instead of FIFOs, for daemons on another host",
+		},
 		&cli.StringFlag{
 			Name:  "cgroup",
 			Usage: "Cgroup path (To disable use of cgroup, set to \"\" explicitly)",
@@ -131,6 +140,10 @@ var Command = &cli.Command{
 			Name:  "sync-fs",
 			Usage: "Synchronize the underlying filesystem containing files when unpack images, false by default",
 		},
+		&cli.StringFlag{
+			Name:  "restart",
+			Usage: "Restart policy for the container (no|always|unless-stopped|on-failure[:max-retries]), requires the restart monitor plugin",
+		},
 	}, append(platformRunFlags,
 		append(commands.RuntimeFlags,
 			append(append(commands.SnapshotterFlags, []cli.Flag{commands.SnapshotterLabels}...),
@@ -202,6 +215,9 @@ var Command = &cli.Command{
 
 		opts := tasks.GetNewTaskOpts(cliContext)
 		ioOpts := []cio.Opt{cio.WithFIFODir(cliContext.String("fifo-dir"))}
+		if cliContext.Bool("remote-io") {
+			ioOpts = append(ioOpts, client.WithRemoteConsole())
+		}
 		task, err := tasks.NewTask(ctx, client, container, cliContext.String("checkpoint"), con, cliContext.Bool("null-io"), cliContext.String("log-uri"), ioOpts, opts...)
 		if err != nil {
 			return err
@@ -37,6 +37,7 @@ import (
 	"github.com/basuotian/containerd/contrib/seccomp"
 	"github.com/basuotian/containerd/core/containers"
 	"github.com/basuotian/containerd/core/diff"
+	"github.com/basuotian/containerd/core/runtime/restart"
 	"github.com/basuotian/containerd/core/snapshots"
 	cdispec "github.com/basuotian/containerd/pkg/cdi"
 	"github.com/basuotian/containerd/pkg/oci"
@@ -83,6 +84,18 @@ var platformRunFlags = []cli.Flag{
 		Name:  "cpuset-mems",
 		Usage: "Set the memory nodes the container will run in (e.g., 1-2,4)",
 	},
+	&cli.StringFlag{
+		Name:  "checkpoint-image-path",
+		Usage: "Path to criu image files to restore the task from, the final image directory of a pre-dump chain",
+	},
+	&cli.StringFlag{
+		Name:  "checkpoint-work-path",
+		Usage: "Path to criu work files and logs for the restore",
+	},
+	&cli.BoolFlag{
+		Name:  "sd-notify",
+		Usage: "Set NOTIFY_SOCKET so the workload can report sd_notify readiness, published as a task ready event",
+	},
 }
 
 // NewContainer creates a new container
@@ -107,8 +120,13 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 		cOpts = append(cOpts, containerd.WithSandbox(sandbox))
 	}
 
+	labelArgs, err := commands.ContainerLabels(cliContext)
+	if err != nil {
+		return nil, err
+	}
+
 	if config {
-		cOpts = append(cOpts, containerd.WithContainerLabels(commands.LabelArgs(cliContext.StringSlice("label"))))
+		cOpts = append(cOpts, containerd.WithContainerLabels(labelArgs))
 		opts = append(opts, oci.WithSpecFromFile(cliContext.String("config")))
 	} else {
 		var (
@@ -117,8 +135,12 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 			args = cliContext.Args().Slice()[2:]
 		)
 		opts = append(opts, oci.WithDefaultSpec(), oci.WithDefaultUnixDevices)
-		if ef := cliContext.String("env-file"); ef != "" {
-			opts = append(opts, oci.WithEnvFile(ef))
+		for _, ef := range cliContext.StringSlice("env-file") {
+			vars, err := commands.ParseEnvFile(ef, cliContext.Bool("env-expand"))
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, oci.WithEnv(vars))
 		}
 		opts = append(opts, oci.WithEnv(cliContext.StringSlice("env")))
 		opts = append(opts, withMounts(cliContext))
@@ -129,7 +151,7 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 				return nil, err
 			}
 			opts = append(opts, oci.WithRootFSPath(rootfs))
-			cOpts = append(cOpts, containerd.WithContainerLabels(commands.LabelArgs(cliContext.StringSlice("label"))))
+			cOpts = append(cOpts, containerd.WithContainerLabels(labelArgs))
 		} else {
 			snapshotter := cliContext.String("snapshotter")
 			var image containerd.Image
@@ -156,7 +178,7 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 					return nil, err
 				}
 			}
-			labels := buildLabels(commands.LabelArgs(cliContext.StringSlice("label")), image.Labels())
+			labels := buildLabels(labelArgs, image.Labels())
 			opts = append(opts, oci.WithImageConfig(image))
 			cOpts = append(cOpts,
 				containerd.WithImage(image),
@@ -208,6 +230,9 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 		if cliContext.Bool("tty") {
 			opts = append(opts, oci.WithTTY)
 		}
+		if cliContext.Bool("sd-notify") {
+			opts = append(opts, oci.WithSdNotify)
+		}
 
 		privileged := cliContext.Bool("privileged")
 		privilegedWithoutHostDevices := cliContext.Bool("privileged-without-host-devices")
@@ -358,20 +383,15 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 			opts = append(opts, withStaticCDIRegistry())
 		}
 		opts = append(opts, cdispec.WithCDIDevices(cdiDeviceIDs...))
+		if rules := cliContext.StringSlice("device-cgroup-rule"); len(rules) > 0 {
+			opts = append(opts, oci.WithDeviceCgroupRules(rules...))
+		}
+		if allowlist := cliContext.String("device-allowlist"); allowlist != "" {
+			opts = append(opts, oci.WithDeviceAllowlist(allowlist))
+		}
 
-		rootfsPropagation := cliContext.String("rootfs-propagation")
-		if rootfsPropagation != "" {
-			opts = append(opts, func(_ context.Context, _ oci.Client, _ *containers.Container, s *oci.Spec) error {
-				if s.Linux != nil {
-					s.Linux.RootfsPropagation = rootfsPropagation
-				} else {
-					s.Linux = &specs.Linux{
-						RootfsPropagation: rootfsPropagation,
-					}
-				}
-
-				return nil
-			})
+		if rootfsPropagation := cliContext.String("rootfs-propagation"); rootfsPropagation != "" {
+			opts = append(opts, oci.WithRootFSPropagation(rootfsPropagation))
 		}
 
 		if c := cliContext.String("blockio-config-file"); c != "" {
@@ -400,13 +420,17 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 		cOpts = append(cOpts, containerd.WithContainerExtension(commands.CtrCniMetadataExtension, cniMeta))
 	}
 
+	if policy := cliContext.String("restart"); policy != "" {
+		cOpts = append(cOpts, restart.WithRestartPolicy(policy))
+	}
+
 	runtimeOpts, err := commands.RuntimeOptions(cliContext)
 	if err != nil {
 		return nil, err
 	}
 	cOpts = append(cOpts, containerd.WithRuntime(cliContext.String("runtime"), runtimeOpts))
 
-	opts = append(opts, oci.WithAnnotations(commands.LabelArgs(cliContext.StringSlice("label"))))
+	opts = append(opts, oci.WithAnnotations(labelArgs))
 	var s specs.Spec
 	spec = containerd.WithSpec(&s, opts...)
 
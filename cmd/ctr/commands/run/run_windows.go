@@ -19,12 +19,15 @@ package run
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/Microsoft/hcsshim/cmd/containerd-shim-runhcs-v1/options"
 	containerd "github.com/basuotian/containerd/client"
 	"github.com/basuotian/containerd/cmd/ctr/commands"
 	"github.com/basuotian/containerd/core/diff"
+	"github.com/basuotian/containerd/core/runtime/restart"
 	"github.com/basuotian/containerd/core/snapshots"
 	"github.com/basuotian/containerd/pkg/netns"
 	"github.com/basuotian/containerd/pkg/oci"
@@ -56,10 +59,15 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 		cOpts = append(cOpts, containerd.WithSandbox(sandbox))
 	}
 
+	labelArgs, err := commands.ContainerLabels(cliContext)
+	if err != nil {
+		return nil, err
+	}
+
 	if config {
 		id = cliContext.Args().First()
 		opts = append(opts, oci.WithSpecFromFile(cliContext.String("config")))
-		cOpts = append(cOpts, containerd.WithContainerLabels(commands.LabelArgs(cliContext.StringSlice("label"))))
+		cOpts = append(cOpts, containerd.WithContainerLabels(labelArgs))
 	} else {
 		var (
 			ref  = cliContext.Args().First()
@@ -77,8 +85,12 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 			opts = append(opts, oci.WithWindowNetworksAllowUnqualifiedDNSQuery())
 			opts = append(opts, oci.WithWindowsIgnoreFlushesDuringBoot())
 		}
-		if ef := cliContext.String("env-file"); ef != "" {
-			opts = append(opts, oci.WithEnvFile(ef))
+		for _, ef := range cliContext.StringSlice("env-file") {
+			vars, err := commands.ParseEnvFile(ef, cliContext.Bool("env-expand"))
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, oci.WithEnv(vars))
 		}
 		opts = append(opts, oci.WithEnv(cliContext.StringSlice("env")))
 		opts = append(opts, withMounts(cliContext))
@@ -97,7 +109,7 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 			}
 		}
 		opts = append(opts, oci.WithImageConfig(image))
-		labels := buildLabels(commands.LabelArgs(cliContext.StringSlice("label")), image.Labels())
+		labels := buildLabels(labelArgs, image.Labels())
 		cOpts = append(cOpts,
 			containerd.WithImage(image),
 			containerd.WithImageConfigLabels(image),
@@ -156,6 +168,30 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 		if cmax != 0 {
 			opts = append(opts, oci.WithWindowsCPUMaximum(uint16(cmax)))
 		}
+		for _, group := range cliContext.StringSlice("cpu-group") {
+			groupStr, maskStr, ok := strings.Cut(group, ":")
+			if !ok {
+				return nil, errors.New("cpu groups must be in the format GROUP:MASK")
+			}
+			groupID, err := strconv.ParseUint(groupStr, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cpu group %q: %w", groupStr, err)
+			}
+			mask, err := strconv.ParseUint(maskStr, 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cpu group mask %q: %w", maskStr, err)
+			}
+			opts = append(opts, oci.WithWindowsCPUGroups(specs.WindowsCPUGroupAffinity{
+				Group: uint32(groupID),
+				Mask:  mask,
+			}))
+		}
+		if iops := cliContext.Uint64("storage-max-iops"); iops != 0 {
+			opts = append(opts, oci.WithWindowsStorageIops(iops))
+		}
+		if bps := cliContext.Uint64("storage-max-bps"); bps != 0 {
+			opts = append(opts, oci.WithWindowsStorageBps(bps))
+		}
 		for _, dev := range cliContext.StringSlice("device") {
 			idType, devID, ok := strings.Cut(dev, "://")
 			if !ok {
@@ -182,6 +218,10 @@ func NewContainer(ctx context.Context, client *containerd.Client, cliContext *cl
 	}
 	cOpts = append(cOpts, containerd.WithRuntime(runtime, runtimeOpts))
 
+	if policy := cliContext.String("restart"); policy != "" {
+		cOpts = append(cOpts, restart.WithRestartPolicy(policy))
+	}
+
 	var s specs.Spec
 	spec = containerd.WithSpec(&s, opts...)
 
@@ -0,0 +1,82 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sandboxes
+
+import (
+	"errors"
+	"fmt"
+
+	containerd "github.com/basuotian/containerd/client"
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/urfave/cli/v2"
+)
+
+var checkpointCommand = &cli.Command{
+	Name:      "checkpoint",
+	Usage:     "Checkpoint a sandbox together with all of its containers",
+	ArgsUsage: "SANDBOX REF",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "rw",
+			Usage: "Include the rw layers in the checkpoint",
+		},
+		&cli.BoolFlag{
+			Name:  "image",
+			Usage: "Include the images in the checkpoint",
+		},
+		&cli.BoolFlag{
+			Name:  "task",
+			Usage: "Checkpoint the container tasks",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		id := cliContext.Args().First()
+		if id == "" {
+			return errors.New("sandbox id must be provided")
+		}
+		ref := cliContext.Args().Get(1)
+		if ref == "" {
+			return errors.New("ref must be provided")
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		opts := []containerd.CheckpointOpts{
+			containerd.WithCheckpointRuntime,
+		}
+		if cliContext.Bool("image") {
+			opts = append(opts, containerd.WithCheckpointImage)
+		}
+		if cliContext.Bool("rw") {
+			opts = append(opts, containerd.WithCheckpointRW)
+		}
+		if cliContext.Bool("task") {
+			opts = append(opts, containerd.WithCheckpointTask)
+		}
+
+		checkpoint, err := client.CheckpointPod(ctx, id, ref, opts...)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(checkpoint.Name())
+		return nil
+	},
+}
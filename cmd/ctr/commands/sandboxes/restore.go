@@ -0,0 +1,109 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sandboxes
+
+import (
+	"errors"
+	"fmt"
+
+	containerd "github.com/basuotian/containerd/client"
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/pkg/cio"
+	"github.com/containerd/errdefs"
+	"github.com/urfave/cli/v2"
+)
+
+var restoreCommand = &cli.Command{
+	Name:      "restore",
+	Usage:     "Restore a sandbox and all of its containers from a pod checkpoint",
+	ArgsUsage: "SANDBOX REF",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "rw",
+			Usage: "Restore the rw layers from the checkpoint",
+		},
+		&cli.BoolFlag{
+			Name:  "live",
+			Usage: "Restore the runtime and memory data of the tasks from the checkpoint",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		id := cliContext.Args().First()
+		if id == "" {
+			return errors.New("sandbox id must be provided")
+		}
+		ref := cliContext.Args().Get(1)
+		if ref == "" {
+			return errors.New("ref must be provided")
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		checkpoint, err := client.GetImage(ctx, ref)
+		if err != nil {
+			if !errdefs.IsNotFound(err) {
+				return err
+			}
+			ck, err := client.Fetch(ctx, ref)
+			if err != nil {
+				return err
+			}
+			checkpoint = containerd.NewImage(client, ck)
+		}
+
+		opts := []containerd.RestoreOpts{
+			containerd.WithRestoreImage,
+			containerd.WithRestoreSpec,
+			containerd.WithRestoreRuntime,
+		}
+		if cliContext.Bool("rw") {
+			opts = append(opts, containerd.WithRestoreRW)
+		}
+
+		sandbox, ctrs, err := client.RestorePod(ctx, id, checkpoint, opts...)
+		if err != nil {
+			return err
+		}
+
+		var checkpoints map[string]containerd.Image
+		if cliContext.Bool("live") {
+			if checkpoints, err = client.PodContainerCheckpoints(ctx, checkpoint); err != nil {
+				return err
+			}
+		}
+		for _, ctr := range ctrs {
+			topts := []containerd.NewTaskOpts{}
+			if ck, ok := checkpoints[ctr.ID()]; ok {
+				topts = append(topts, containerd.WithTaskCheckpoint(ck))
+			}
+			task, err := ctr.NewTask(ctx, cio.NullIO, topts...)
+			if err != nil {
+				return fmt.Errorf("failed to create task for container %q: %w", ctr.ID(), err)
+			}
+			// the runtime performs the actual restore on start
+			if err := task.Start(ctx); err != nil {
+				return fmt.Errorf("failed to start container %q: %w", ctr.ID(), err)
+			}
+		}
+
+		fmt.Println(sandbox.ID())
+		return nil
+	},
+}
@@ -24,6 +24,7 @@ import (
 
 	containerd "github.com/basuotian/containerd/client"
 	"github.com/basuotian/containerd/cmd/ctr/commands"
+	sb "github.com/basuotian/containerd/core/sandbox"
 	"github.com/basuotian/containerd/defaults"
 	"github.com/basuotian/containerd/pkg/oci"
 	"github.com/containerd/log"
@@ -39,6 +40,8 @@ var Command = &cli.Command{
 		runCommand,
 		listCommand,
 		removeCommand,
+		checkpointCommand,
+		restoreCommand,
 	},
 }
 
@@ -103,8 +106,9 @@ var listCommand = &cli.Command{
 	Usage:   "List sandboxes",
 	Flags: []cli.Flag{
 		&cli.StringSliceFlag{
-			Name:  "filters",
-			Usage: "The list of filters to apply when querying sandboxes from the store",
+			Name:    "filters",
+			Aliases: []string{"filter"},
+			Usage:   "The list of filters to apply when querying sandboxes from the store, e.g. 'labels.\"key\"==\"value\"', 'runtime.name==runc', 'state==running'",
 		},
 	},
 	Action: func(cliContext *cli.Context) error {
@@ -128,12 +132,16 @@ var listCommand = &cli.Command{
 			return fmt.Errorf("failed to list sandboxes: %w", err)
 		}
 
-		if _, err := fmt.Fprintln(writer, "ID\tCREATED\tRUNTIME\t"); err != nil {
+		if _, err := fmt.Fprintln(writer, "ID\tCREATED\tRUNTIME\tSTATE\t"); err != nil {
 			return err
 		}
 
 		for _, sandbox := range list {
-			_, err := fmt.Fprintf(writer, "%s\t%s\t%s\t\n", sandbox.ID, sandbox.CreatedAt, sandbox.Runtime.Name)
+			state := sandbox.Labels[sb.StateLabel]
+			if state == "" {
+				state = "unknown"
+			}
+			_, err := fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t\n", sandbox.ID, sandbox.CreatedAt, sandbox.Runtime.Name, state)
 			if err != nil {
 				return err
 			}
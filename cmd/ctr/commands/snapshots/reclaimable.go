@@ -0,0 +1,68 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snapshots
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/containerd/typeurl/v2"
+	"github.com/urfave/cli/v2"
+
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/metadata"
+	"github.com/basuotian/containerd/pkg/progress"
+	"github.com/basuotian/containerd/plugins"
+)
+
+var reclaimableCommand = &cli.Command{
+	Name:      "reclaimable",
+	Usage:     "Report the space per snapshotter that a garbage collection would free, without deleting anything",
+	ArgsUsage: "[flags]",
+	Flags: []cli.Flag{
+		commands.OutputFlag,
+	},
+	Action: func(cliContext *cli.Context) error {
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		resp, err := client.IntrospectionService().PluginInfo(ctx, string(plugins.MetadataPlugin), "bolt", &metadata.ReclaimableSpaceRequest{})
+		if err != nil {
+			return err
+		}
+		var space metadata.ReclaimableSpaceResponse
+		if err := typeurl.UnmarshalTo(resp.Extra, &space); err != nil {
+			return fmt.Errorf("failed to unmarshal reclaimable space: %w", err)
+		}
+		if commands.OutputSelected(cliContext) {
+			return commands.OutputList(cliContext, space.Snapshotters)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 1, 8, 1, ' ', 0)
+		fmt.Fprintln(tw, "SNAPSHOTTER\tSNAPSHOTS\tSIZE\tINODES\t")
+		for _, r := range space.Snapshotters {
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t\n",
+				r.Snapshotter,
+				r.Snapshots,
+				progress.Bytes(r.Size),
+				r.Inodes)
+		}
+		return tw.Flush()
+	},
+}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/containerd/log"
 	digest "github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/identity"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/urfave/cli/v2"
 
@@ -39,6 +40,7 @@ import (
 	"github.com/basuotian/containerd/core/diff"
 	"github.com/basuotian/containerd/core/mount"
 	"github.com/basuotian/containerd/core/snapshots"
+	"github.com/basuotian/containerd/defaults"
 	"github.com/basuotian/containerd/pkg/progress"
 	"github.com/basuotian/containerd/pkg/rootfs"
 )
@@ -56,6 +58,7 @@ var Command = &cli.Command{
 		listCommand,
 		mountCommand,
 		prepareCommand,
+		reclaimableCommand,
 		removeCommand,
 		setLabelCommand,
 		treeCommand,
@@ -69,16 +72,38 @@ var listCommand = &cli.Command{
 	Name:    "list",
 	Aliases: []string{"ls"},
 	Usage:   "List snapshots",
+	Flags: []cli.Flag{
+		commands.OutputFlag,
+	},
 	Action: func(cliContext *cli.Context) error {
 		client, ctx, cancel, err := commands.NewClient(cliContext)
 		if err != nil {
 			return err
 		}
 		defer cancel()
-		var (
-			snapshotter = client.SnapshotService(cliContext.String("snapshotter"))
-			tw          = tabwriter.NewWriter(os.Stdout, 1, 8, 1, ' ', 0)
-		)
+		snapshotter := client.SnapshotService(cliContext.String("snapshotter"))
+		if commands.OutputSelected(cliContext) {
+			type snapshotItem struct {
+				Key    string            `json:"key"`
+				Parent string            `json:"parent,omitempty"`
+				Kind   string            `json:"kind"`
+				Labels map[string]string `json:"labels,omitempty"`
+			}
+			var items []snapshotItem
+			if err := snapshotter.Walk(ctx, func(ctx context.Context, info snapshots.Info) error {
+				items = append(items, snapshotItem{
+					Key:    info.Name,
+					Parent: info.Parent,
+					Kind:   info.Kind.String(),
+					Labels: info.Labels,
+				})
+				return nil
+			}); err != nil {
+				return err
+			}
+			return commands.OutputList(cliContext, items)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 1, 8, 1, ' ', 0)
 		fmt.Fprintln(tw, "KEY\tPARENT\tKIND\t")
 		if err := snapshotter.Walk(ctx, func(ctx context.Context, info snapshots.Info) error {
 			fmt.Fprintf(tw, "%v\t%v\t%v\t\n",
@@ -432,7 +457,7 @@ var commitCommand = &cli.Command{
 
 var treeCommand = &cli.Command{
 	Name:  "tree",
-	Usage: "Display tree view of snapshot branches",
+	Usage: "Display tree view of snapshot branches with image and container back references",
 	Action: func(cliContext *cli.Context) error {
 		client, ctx, cancel, err := commands.NewClient(cliContext)
 		if err != nil {
@@ -440,9 +465,13 @@ var treeCommand = &cli.Command{
 		}
 		defer cancel()
 		var (
-			snapshotter = client.SnapshotService(cliContext.String("snapshotter"))
-			tree        = newSnapshotTree()
+			snapshotterName = cliContext.String("snapshotter")
+			snapshotter     = client.SnapshotService(snapshotterName)
+			tree            = newSnapshotTree()
 		)
+		if snapshotterName == "" {
+			snapshotterName = defaults.DefaultSnapshotter
+		}
 
 		if err := snapshotter.Walk(ctx, func(ctx context.Context, info snapshots.Info) error {
 			// Get or create node and add node details
@@ -452,6 +481,35 @@ var treeCommand = &cli.Command{
 			return err
 		}
 
+		// Annotate nodes with the images and containers referencing them,
+		// to help tell why a snapshot is retained by the garbage collector.
+		images, err := client.ListImages(ctx)
+		if err != nil {
+			return err
+		}
+		for _, i := range images {
+			diffIDs, err := i.RootFS(ctx)
+			if err != nil {
+				log.G(ctx).WithError(err).Warnf("failed to resolve rootfs of image %q", i.Name())
+				continue
+			}
+			tree.annotate(identity.ChainID(diffIDs).String(), "image "+i.Name())
+		}
+		containers, err := client.Containers(ctx)
+		if err != nil {
+			return err
+		}
+		for _, c := range containers {
+			info, err := c.Info(ctx)
+			if err != nil {
+				return err
+			}
+			if info.Snapshotter != snapshotterName || info.SnapshotKey == "" {
+				continue
+			}
+			tree.annotate(info.SnapshotKey, "container "+c.ID())
+		}
+
 		printTree(tree)
 
 		return nil
@@ -462,6 +520,9 @@ var infoCommand = &cli.Command{
 	Name:      "info",
 	Usage:     "Get info about a snapshot",
 	ArgsUsage: "<key>",
+	Flags: []cli.Flag{
+		commands.OutputFlag,
+	},
 	Action: func(cliContext *cli.Context) error {
 		if cliContext.NArg() != 1 {
 			return cli.ShowSubcommandHelp(cliContext)
@@ -479,6 +540,9 @@ var infoCommand = &cli.Command{
 			return err
 		}
 
+		if commands.OutputSelected(cliContext) {
+			return commands.OutputInfo(cliContext, info)
+		}
 		commands.PrintAsJSON(info)
 
 		return nil
@@ -596,6 +660,7 @@ func newSnapshotTree() *snapshotTree {
 type snapshotTreeNode struct {
 	info     snapshots.Info
 	children []string
+	refs     []string
 }
 
 func (st *snapshotTree) add(info snapshots.Info) *snapshotTreeNode {
@@ -624,6 +689,15 @@ func (st *snapshotTree) get(name string) *snapshotTreeNode {
 	return st.index[name]
 }
 
+// annotate records a back reference on the named snapshot, ignoring
+// references to snapshots that do not exist, e.g. images that were never
+// unpacked with this snapshotter.
+func (st *snapshotTree) annotate(name, ref string) {
+	if node := st.index[name]; node != nil {
+		node.refs = append(node.refs, ref)
+	}
+}
+
 func printTree(st *snapshotTree) {
 	for _, node := range st.nodes {
 		// Print for root(parent-less) nodes only
@@ -641,7 +715,11 @@ func printNode(name string, tree *snapshotTree, level int) {
 		prefix += "\\_"
 	}
 
-	fmt.Printf(prefix+" %s\n", node.info.Name)
+	line := prefix + " " + node.info.Name
+	if len(node.refs) > 0 {
+		line += " (" + strings.Join(node.refs, ", ") + ")"
+	}
+	fmt.Println(line)
 	level++
 	for _, child := range node.children {
 		printNode(child, tree, level)
@@ -19,6 +19,7 @@ package tasks
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 
 	containerd "github.com/basuotian/containerd/client"
 	"github.com/basuotian/containerd/cmd/ctr/commands"
@@ -43,6 +44,10 @@ var checkpointCommand = &cli.Command{
 			Name:  "work-path",
 			Usage: "Path to criu work files and logs",
 		},
+		&cli.IntFlag{
+			Name:  "pre-dump",
+			Usage: "Number of criu pre-dump iterations to run before the final checkpoint, requires --image-path",
+		},
 	},
 	Action: func(cliContext *cli.Context) error {
 		id := cliContext.Args().First()
@@ -66,18 +71,49 @@ var checkpointCommand = &cli.Command{
 		if err != nil {
 			return err
 		}
+		var (
+			imagePath = cliContext.String("image-path")
+			preDumps  = cliContext.Int("pre-dump")
+			parent    string
+		)
+		if preDumps > 0 && imagePath == "" {
+			return errors.New("--pre-dump requires --image-path")
+		}
+		// run the pre-dump iterations first, each dump in the chain keeping
+		// a relative reference to its parent so that criu can restore from
+		// the final image directory alone
+		for i := 1; i <= preDumps; i++ {
+			opts := []containerd.CheckpointTaskOpts{
+				containerd.WithCheckpointImagePath(filepath.Join(imagePath, fmt.Sprintf(preDumpDirFormat, i))),
+				containerd.WithCheckpointPreDump,
+			}
+			if parent != "" {
+				opts = append(opts, containerd.WithCheckpointParentPath(filepath.Join("..", parent)))
+			}
+			if _, err := task.Checkpoint(ctx, opts...); err != nil {
+				return err
+			}
+			parent = fmt.Sprintf(preDumpDirFormat, i)
+		}
 		opts := []containerd.CheckpointTaskOpts{withCheckpointOpts(info.Runtime.Name, cliContext)}
+		if parent != "" {
+			opts = append(opts, containerd.WithCheckpointParentPath(parent))
+		}
 		checkpoint, err := task.Checkpoint(ctx, opts...)
 		if err != nil {
 			return err
 		}
-		if cliContext.String("image-path") == "" {
+		if imagePath == "" {
 			fmt.Println(checkpoint.Name())
 		}
 		return nil
 	},
 }
 
+// preDumpDirFormat names the image directory of a single pre-dump iteration
+// below the checkpoint image path
+const preDumpDirFormat = "pre-dump-%d"
+
 // withCheckpointOpts only suitable for runc runtime now
 func withCheckpointOpts(rt string, cliContext *cli.Context) containerd.CheckpointTaskOpts {
 	return func(r *containerd.CheckpointTaskInfo) error {
@@ -0,0 +1,77 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/basuotian/containerd/defaults"
+	"github.com/basuotian/containerd/pkg/coredump"
+)
+
+var coresCommand = &cli.Command{
+	Name:      "cores",
+	Usage:     "List core dumps captured for a task by the runc shim",
+	ArgsUsage: "CONTAINER",
+	Description: `List the core dumps the runc shim captured for crashed container processes.
+
+Capturing must have been enabled on the container with the
+"io.containerd.runc.v2.coredump" annotation. The index is read from the
+capture directory on the local host, so the command must run on the node
+the container runs on.
+`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "core-dir",
+			Usage: "Directory holding the captured core dumps, when set with the coredump-dir annotation",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		id := cliContext.Args().First()
+		if id == "" {
+			return errors.New("container id must be provided")
+		}
+		dir := cliContext.String("core-dir")
+		if dir == "" {
+			ns := cliContext.String("namespace")
+			dir = filepath.Join(defaults.DefaultStateDir, "io.containerd.runtime.v2.task", ns, id, "cores")
+		}
+		dumps, err := coredump.List(dir)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 1, 8, 4, ' ', 0)
+		fmt.Fprintln(w, "PROCESS\tPID\tSIGNAL\tSIZE\tEXITED AT\tPATH")
+		for _, d := range dumps {
+			size := fmt.Sprintf("%d", d.Size)
+			if d.Truncated {
+				size += " (truncated)"
+			}
+			if _, err := fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\n",
+				d.ID, d.Pid, d.Signal, size, d.ExitedAt.Local().Format("2006-01-02 15:04:05"), d.Path); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	},
+}
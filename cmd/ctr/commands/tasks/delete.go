@@ -18,10 +18,13 @@ package tasks
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	containerd "github.com/basuotian/containerd/client"
 	"github.com/basuotian/containerd/cmd/ctr/commands"
 	"github.com/basuotian/containerd/pkg/cio"
+	"github.com/basuotian/containerd/pkg/progress"
 	"github.com/containerd/log"
 	"github.com/urfave/cli/v2"
 )
@@ -41,11 +44,16 @@ var deleteCommand = &cli.Command{
 			Name:  "exec-id",
 			Usage: "Process ID to kill",
 		},
+		&cli.BoolFlag{
+			Name:  "summary",
+			Usage: "Print a final resource usage summary sampled before deletion",
+		},
 	},
 	Action: func(cliContext *cli.Context) error {
 		var (
-			execID = cliContext.String("exec-id")
-			force  = cliContext.Bool("force")
+			execID  = cliContext.String("exec-id")
+			force   = cliContext.Bool("force")
+			summary = cliContext.Bool("summary")
 		)
 		client, ctx, cancel, err := commands.NewClient(cliContext)
 		if err != nil {
@@ -56,6 +64,12 @@ var deleteCommand = &cli.Command{
 		if force {
 			opts = append(opts, containerd.WithProcessKill)
 		}
+		if summary {
+			if execID != "" {
+				return errors.New("usage summary is only supported for tasks, not execs")
+			}
+			opts = append(opts, containerd.WithResourceUsageSummary)
+		}
 		var exitErr error
 		if execID != "" {
 			task, err := loadTask(ctx, client, cliContext.Args().First())
@@ -91,6 +105,9 @@ var deleteCommand = &cli.Command{
 					log.G(ctx).WithError(err).Errorf("unable to delete %v", task.ID())
 					continue
 				}
+				if usage := status.Usage(); usage != nil {
+					printUsageSummary(task.ID(), status)
+				}
 				if ec := status.ExitCode(); ec != 0 {
 					log.G(ctx).Warnf("task %v exit with non-zero exit code %v", task.ID(), int(ec))
 				}
@@ -100,6 +117,17 @@ var deleteCommand = &cli.Command{
 	},
 }
 
+func printUsageSummary(id string, status *containerd.ExitStatus) {
+	usage := status.Usage()
+	fmt.Printf("%s\tcpu=%v\tmem=%s\tpeak_mem=%s\tio_read=%s\tio_write=%s\n",
+		id,
+		usage.CPUTotal,
+		progress.Bytes(int64(usage.MemoryBytes)),
+		progress.Bytes(int64(usage.PeakMemoryBytes)),
+		progress.Bytes(int64(usage.IOReadBytes)),
+		progress.Bytes(int64(usage.IOWriteBytes)))
+}
+
 func loadTask(ctx context.Context, client *containerd.Client, containerID string) (containerd.Task, error) {
 	container, err := client.LoadContainer(ctx, containerID)
 	if err != nil {
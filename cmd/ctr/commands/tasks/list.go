@@ -22,7 +22,11 @@ import (
 	"text/tabwriter"
 
 	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/runtime"
+	"github.com/basuotian/containerd/plugins"
+	"github.com/basuotian/containerd/plugins/services"
 	tasks "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/typeurl/v2"
 	"github.com/urfave/cli/v2"
 )
 
@@ -37,6 +41,12 @@ var listCommand = &cli.Command{
 			Aliases: []string{"q"},
 			Usage:   "Print only the task id",
 		},
+		&cli.BoolFlag{
+			Name:    "all-namespaces",
+			Aliases: []string{"a"},
+			Usage:   "List tasks across all namespaces (requires allow_cross_namespace_list in the tasks service config)",
+		},
+		commands.OutputFlag,
 	},
 	Action: func(cliContext *cli.Context) error {
 		quiet := cliContext.Bool("quiet")
@@ -45,11 +55,59 @@ var listCommand = &cli.Command{
 			return err
 		}
 		defer cancel()
+		if cliContext.Bool("all-namespaces") {
+			resp, err := client.IntrospectionService().PluginInfo(ctx, string(plugins.ServicePlugin), services.TasksService, &runtime.ListAllTasksRequest{})
+			if err != nil {
+				return err
+			}
+			var all runtime.ListAllTasksResponse
+			if err := typeurl.UnmarshalTo(resp.Extra, &all); err != nil {
+				return fmt.Errorf("failed to unmarshal task list: %w", err)
+			}
+			if commands.OutputSelected(cliContext) {
+				return commands.OutputList(cliContext, all.Tasks)
+			}
+			if quiet {
+				for _, task := range all.Tasks {
+					fmt.Printf("%s/%s\n", task.Namespace, task.ID)
+				}
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 4, 8, 4, ' ', 0)
+			fmt.Fprintln(w, "NAMESPACE\tTASK\tPID\tSTATUS\t")
+			for _, task := range all.Tasks {
+				if _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%s\n",
+					task.Namespace,
+					task.ID,
+					task.Pid,
+					task.Status,
+				); err != nil {
+					return err
+				}
+			}
+			return w.Flush()
+		}
 		s := client.TaskService()
 		response, err := s.List(ctx, &tasks.ListTasksRequest{})
 		if err != nil {
 			return err
 		}
+		if commands.OutputSelected(cliContext) {
+			type taskItem struct {
+				ID     string `json:"id"`
+				PID    uint32 `json:"pid"`
+				Status string `json:"status"`
+			}
+			items := make([]taskItem, 0, len(response.Tasks))
+			for _, task := range response.Tasks {
+				items = append(items, taskItem{
+					ID:     task.ID,
+					PID:    task.Pid,
+					Status: task.Status.String(),
+				})
+			}
+			return commands.OutputList(cliContext, items)
+		}
 		if quiet {
 			for _, task := range response.Tasks {
 				fmt.Println(task.ID)
@@ -23,6 +23,7 @@ import (
 	"text/tabwriter"
 
 	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/pkg/processtree"
 	"github.com/containerd/typeurl/v2"
 	"github.com/urfave/cli/v2"
 )
@@ -31,6 +32,12 @@ var psCommand = &cli.Command{
 	Name:      "ps",
 	Usage:     "List processes for container",
 	ArgsUsage: "CONTAINER",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "tree",
+			Usage: "Print the processes as a tree",
+		},
+	},
 	Action: func(cliContext *cli.Context) error {
 		id := cliContext.Args().First()
 		if id == "" {
@@ -49,6 +56,20 @@ var psCommand = &cli.Command{
 		if err != nil {
 			return err
 		}
+		if cliContext.Bool("tree") {
+			roots, err := task.ProcessTree(ctx)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 1, 8, 4, ' ', 0)
+			fmt.Fprintln(w, "PID\tEXEC ID\tCOMMAND")
+			for _, n := range roots {
+				if err := printTree(w, n, ""); err != nil {
+					return err
+				}
+			}
+			return w.Flush()
+		}
 		processes, err := task.Pids(ctx)
 		if err != nil {
 			return err
@@ -70,3 +91,23 @@ var psCommand = &cli.Command{
 		return w.Flush()
 	},
 }
+
+func printTree(w *tabwriter.Writer, n *processtree.Node, indent string) error {
+	command := n.Cmdline
+	switch {
+	case command == "" && n.Comm != "":
+		// Zombies and kernel threads only have a command name.
+		command = "[" + n.Comm + "]"
+	case command == "":
+		command = "-"
+	}
+	if _, err := fmt.Fprintf(w, "%d\t%s\t%s%s\n", n.Pid, n.ExecID, indent, command); err != nil {
+		return err
+	}
+	for _, child := range n.Children {
+		if err := printTree(w, child, indent+"  "); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -45,6 +45,10 @@ var startCommand = &cli.Command{
 			Name:  "fifo-dir",
 			Usage: "Directory used for storing IO FIFOs",
 		},
+		&cli.BoolFlag{
+			Name:  "remote-io",
+			Usage: "Forward IO over the streaming service instead of FIFOs, for daemons on another host",
+		},
 		&cli.StringFlag{
 			Name:  "pid-file",
 			Usage: "File path to write the task's pid",
@@ -83,6 +87,9 @@ var startCommand = &cli.Command{
 			opts   = GetNewTaskOpts(cliContext)
 			ioOpts = []cio.Opt{cio.WithFIFODir(cliContext.String("fifo-dir"))}
 		)
+		if cliContext.Bool("remote-io") {
+			ioOpts = append(ioOpts, client.WithRemoteConsole())
+		}
 		var con console.Console
 		if tty {
 			con = console.Current()
@@ -34,6 +34,7 @@ var Command = &cli.Command{
 	Subcommands: []*cli.Command{
 		attachCommand,
 		checkpointCommand,
+		coresCommand,
 		deleteCommand,
 		execCommand,
 		listCommand,
@@ -43,5 +44,7 @@ var Command = &cli.Command{
 		psCommand,
 		resumeCommand,
 		startCommand,
+		updateCommand,
+		waitCommand,
 	},
 }
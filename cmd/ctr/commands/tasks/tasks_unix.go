@@ -122,8 +122,15 @@ func NewTask(ctx context.Context, client *containerd.Client, container container
 
 // GetNewTaskOpts resolves containerd.NewTaskOpts from cli.Context
 func GetNewTaskOpts(cliContext *cli.Context) []containerd.NewTaskOpts {
+	var opts []containerd.NewTaskOpts
 	if cliContext.Bool("no-pivot") {
-		return []containerd.NewTaskOpts{containerd.WithNoPivotRoot}
+		opts = append(opts, containerd.WithNoPivotRoot)
 	}
-	return nil
+	if path := cliContext.String("checkpoint-image-path"); path != "" {
+		opts = append(opts, containerd.WithRestoreImagePath(path))
+	}
+	if path := cliContext.String("checkpoint-work-path"); path != "" {
+		opts = append(opts, containerd.WithRestoreWorkPath(path))
+	}
+	return opts
 }
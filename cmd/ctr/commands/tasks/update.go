@@ -0,0 +1,75 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package tasks
+
+import (
+	"errors"
+
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/urfave/cli/v2"
+)
+
+var updateCommand = &cli.Command{
+	Name:      "update",
+	Usage:     "Update resources of a running task",
+	ArgsUsage: "CONTAINER",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "device-read-bps",
+			Usage: "Limit read rate from a device (e.g. /dev/sda:10mb)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "device-write-bps",
+			Usage: "Limit write rate to a device (e.g. /dev/sda:10mb)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "device-read-iops",
+			Usage: "Limit read operations per second from a device (e.g. /dev/sda:1000)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "device-write-iops",
+			Usage: "Limit write operations per second to a device (e.g. /dev/sda:1000)",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		id := cliContext.Args().First()
+		if id == "" {
+			return errors.New("container id must be provided")
+		}
+		opts, err := updateTaskOpts(cliContext)
+		if err != nil {
+			return err
+		}
+		if len(opts) == 0 {
+			return errors.New("no updates specified")
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		container, err := client.LoadContainer(ctx, id)
+		if err != nil {
+			return err
+		}
+		task, err := container.Task(ctx, nil)
+		if err != nil {
+			return err
+		}
+		return task.Update(ctx, opts...)
+	},
+}
@@ -0,0 +1,74 @@
+//go:build !windows
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package tasks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	containerd "github.com/basuotian/containerd/client"
+	"github.com/docker/go-units"
+	"github.com/urfave/cli/v2"
+)
+
+func updateTaskOpts(cliContext *cli.Context) ([]containerd.UpdateTaskOpts, error) {
+	var opts []containerd.UpdateTaskOpts
+	for _, throttle := range []struct {
+		flag  string
+		bytes bool
+		opt   func(string, uint64) containerd.UpdateTaskOpts
+	}{
+		{"device-read-bps", true, containerd.WithDeviceReadBps},
+		{"device-write-bps", true, containerd.WithDeviceWriteBps},
+		{"device-read-iops", false, containerd.WithDeviceReadIOPS},
+		{"device-write-iops", false, containerd.WithDeviceWriteIOPS},
+	} {
+		for _, value := range cliContext.StringSlice(throttle.flag) {
+			device, rate, err := parseDeviceThrottle(value, throttle.bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", throttle.flag, value, err)
+			}
+			opts = append(opts, throttle.opt(device, rate))
+		}
+	}
+	return opts, nil
+}
+
+// parseDeviceThrottle splits a "path:rate" throttle value, parsing the
+// rate with size units (e.g. "10mb") when bytes is set and as a plain
+// count otherwise.
+func parseDeviceThrottle(value string, bytes bool) (string, uint64, error) {
+	device, rate, ok := strings.Cut(value, ":")
+	if !ok || device == "" || rate == "" {
+		return "", 0, fmt.Errorf("expected format is path:rate")
+	}
+	if bytes {
+		v, err := units.RAMInBytes(rate)
+		if err != nil || v < 0 {
+			return "", 0, fmt.Errorf("invalid rate %q", rate)
+		}
+		return device, uint64(v), nil
+	}
+	v, err := strconv.ParseUint(rate, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid rate %q", rate)
+	}
+	return device, v, nil
+}
@@ -0,0 +1,33 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package tasks
+
+import (
+	"errors"
+
+	containerd "github.com/basuotian/containerd/client"
+	"github.com/urfave/cli/v2"
+)
+
+func updateTaskOpts(cliContext *cli.Context) ([]containerd.UpdateTaskOpts, error) {
+	for _, flag := range []string{"device-read-bps", "device-write-bps", "device-read-iops", "device-write-iops"} {
+		if len(cliContext.StringSlice(flag)) > 0 {
+			return nil, errors.New("device throttling is not supported on Windows")
+		}
+	}
+	return nil, nil
+}
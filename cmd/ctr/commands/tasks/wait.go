@@ -0,0 +1,161 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	containerd "github.com/basuotian/containerd/client"
+	"github.com/basuotian/containerd/cmd/ctr/commands"
+	"github.com/basuotian/containerd/core/runtime"
+	"github.com/basuotian/containerd/pkg/sdnotify"
+	"github.com/containerd/typeurl/v2"
+	"github.com/urfave/cli/v2"
+)
+
+var waitCommand = &cli.Command{
+	Name:      "wait",
+	Usage:     "Wait for one or more tasks to exit",
+	ArgsUsage: "CONTAINER [CONTAINER, ...]",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "any",
+			Usage: "Return as soon as the first task exits",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "Give up waiting after this duration",
+		},
+		&cli.BoolFlag{
+			Name:  "ready",
+			Usage: "Wait for sd_notify readiness (READY=1) reported after this command starts, instead of task exit",
+		},
+	},
+	Action: func(cliContext *cli.Context) error {
+		ids := cliContext.Args().Slice()
+		if len(ids) == 0 {
+			return errors.New("container id must be provided")
+		}
+		client, ctx, cancel, err := commands.NewClient(cliContext)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		if d := cliContext.Duration("timeout"); d > 0 {
+			var tcancel context.CancelFunc
+			ctx, tcancel = context.WithTimeout(ctx, d)
+			defer tcancel()
+		}
+
+		if cliContext.Bool("ready") {
+			return waitReady(ctx, client, ids, cliContext.Bool("any"))
+		}
+
+		tasks := make([]containerd.Task, 0, len(ids))
+		for _, id := range ids {
+			container, err := client.LoadContainer(ctx, id)
+			if err != nil {
+				return err
+			}
+			task, err := container.Task(ctx, nil)
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+		}
+
+		var results []containerd.TaskResult
+		if cliContext.Bool("any") {
+			result, err := containerd.WaitAny(ctx, tasks...)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		} else {
+			results = containerd.WaitAll(ctx, tasks...)
+		}
+
+		var (
+			w      = tabwriter.NewWriter(os.Stdout, 1, 8, 4, ' ', 0)
+			failed bool
+		)
+		fmt.Fprintln(w, "TASK\tEXIT CODE\tEXITED AT\tERROR")
+		for _, result := range results {
+			if result.Err != nil {
+				failed = true
+				fmt.Fprintf(w, "%s\t-\t-\t%v\n", result.ID, result.Err)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t\n", result.ID, result.ExitCode, result.ExitedAt.Format(time.RFC3339))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if failed {
+			return errors.New("failed waiting for one or more tasks")
+		}
+		return nil
+	},
+}
+
+// waitReady blocks until the tasks publish their sd_notify readiness
+// event. Readiness reported before the subscription was established
+// cannot be observed.
+func waitReady(ctx context.Context, client *containerd.Client, ids []string, any bool) error {
+	eventsCh, errCh := client.Subscribe(ctx, fmt.Sprintf("topic==%q", runtime.TaskReadyEventTopic))
+
+	pending := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, err := client.LoadContainer(ctx, id); err != nil {
+			return err
+		}
+		pending[id] = struct{}{}
+	}
+
+	for len(pending) > 0 {
+		select {
+		case env := <-eventsCh:
+			ev, err := typeurl.UnmarshalAny(env.Event)
+			if err != nil {
+				continue
+			}
+			ready, ok := ev.(*sdnotify.TaskReady)
+			if !ok {
+				continue
+			}
+			if _, ok := pending[ready.ContainerID]; !ok {
+				continue
+			}
+			delete(pending, ready.ContainerID)
+			fmt.Printf("%s: ready\n", ready.ContainerID)
+			if any {
+				return nil
+			}
+		case err := <-errCh:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
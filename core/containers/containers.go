@@ -38,6 +38,12 @@ type Container struct {
 	// These are optional and fully mutable.
 	Labels map[string]string
 
+	// Annotations provide descriptive metadata for a container, kept
+	// separate from Labels and allowed to hold larger values.
+	//
+	// These are optional and fully mutable.
+	Annotations map[string]string
+
 	// Image specifies the image reference used for a container.
 	//
 	// This property is optional and mutable.
@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/basuotian/containerd/pkg/filters"
+	"github.com/basuotian/containerd/pkg/labels"
 )
 
 // AdaptInfo returns `filters.Adaptor` that handles `content.Info`.
@@ -34,6 +35,11 @@ func AdaptInfo(info Info) filters.Adaptor {
 			return info.Digest.String(), true
 		case "size":
 			// TODO: support size based filtering
+		case "accessedat":
+			// Surfaced as a label by stores which track blob accesses.
+			return checkMap([]string{labels.LabelBlobAccessedAt}, info.Labels)
+		case "accesscount":
+			return checkMap([]string{labels.LabelBlobAccessCount}, info.Labels)
 		case "labels":
 			return checkMap(fieldpath[1:], info.Labels)
 		}
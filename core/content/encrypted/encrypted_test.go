@@ -0,0 +1,181 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/plugins/content/local"
+)
+
+func newTestStore(t *testing.T) (content.Store, string) {
+	t.Helper()
+	root := t.TempDir()
+	keyPath := filepath.Join(root, "master.key")
+	key := make([]byte, keySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyPath, []byte(hex.EncodeToString(key)), 0600))
+	provider, err := NewFileKeyProvider(keyPath)
+	require.NoError(t, err)
+
+	blobRoot := filepath.Join(root, "content")
+	ls, err := local.NewFSLabelStore(filepath.Join(blobRoot, "labels"))
+	require.NoError(t, err)
+	base, err := local.NewLabeledStore(blobRoot, ls)
+	require.NoError(t, err)
+	return NewStore(base, provider), blobRoot
+}
+
+func writeBlob(ctx context.Context, t *testing.T, cs content.Store, ref string, data []byte) digest.Digest {
+	t.Helper()
+	dgst := digest.FromBytes(data)
+	w, err := cs.Writer(ctx, content.WithRef(ref))
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Commit(ctx, int64(len(data)), dgst))
+	require.NoError(t, w.Close())
+	return dgst
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	cs, blobRoot := newTestStore(t)
+
+	// Three chunks, the last one partial.
+	data := make([]byte, 2*plaintextChunkSize+123)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+	dgst := writeBlob(ctx, t, cs, "test-blob", data)
+
+	info, err := cs.Info(ctx, dgst)
+	require.NoError(t, err)
+	assert.Equal(t, dgst, info.Digest)
+	assert.Equal(t, int64(len(data)), info.Size)
+	assert.NotContains(t, info.Labels, LabelWrappedKey)
+
+	ra, err := cs.ReaderAt(ctx, ocispec.Descriptor{Digest: dgst})
+	require.NoError(t, err)
+	defer ra.Close()
+	assert.Equal(t, int64(len(data)), ra.Size())
+
+	got, err := io.ReadAll(io.NewSectionReader(ra, 0, ra.Size()))
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(data, got))
+
+	// Random access across chunk boundaries.
+	for _, off := range []int64{0, 1, plaintextChunkSize - 1, plaintextChunkSize, 2*plaintextChunkSize + 100} {
+		buf := make([]byte, 64)
+		n, err := ra.ReadAt(buf, off)
+		if err != nil {
+			require.ErrorIs(t, err, io.EOF)
+		}
+		assert.Equal(t, data[off:off+int64(n)], buf[:n], "offset %d", off)
+	}
+
+	// The plaintext must not appear on disk.
+	err = filepath.Walk(filepath.Join(blobRoot, "blobs"), func(path string, fi os.FileInfo, err error) error {
+		require.NoError(t, err)
+		if fi.IsDir() {
+			return nil
+		}
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.False(t, bytes.Contains(raw, data[:32]), "plaintext found in %s", path)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestEncryptedCommitVerifiesDigest(t *testing.T) {
+	ctx := context.Background()
+	cs, _ := newTestStore(t)
+
+	w, err := cs.Writer(ctx, content.WithRef("bad-digest"))
+	require.NoError(t, err)
+	defer w.Close()
+	_, err = w.Write([]byte("some data"))
+	require.NoError(t, err)
+	err = w.Commit(ctx, 0, digest.FromString("other data"))
+	require.Error(t, err)
+	assert.True(t, errdefs.IsFailedPrecondition(err))
+}
+
+func TestEncryptedAlreadyExists(t *testing.T) {
+	ctx := context.Background()
+	cs, _ := newTestStore(t)
+
+	data := []byte("duplicate blob")
+	dgst := writeBlob(ctx, t, cs, "first", data)
+
+	w, err := cs.Writer(ctx, content.WithRef("second"))
+	require.NoError(t, err)
+	defer w.Close()
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	err = w.Commit(ctx, 0, dgst)
+	require.Error(t, err)
+	assert.True(t, errdefs.IsAlreadyExists(err))
+}
+
+func TestEncryptedWalkAndDelete(t *testing.T) {
+	ctx := context.Background()
+	cs, _ := newTestStore(t)
+
+	dgst := writeBlob(ctx, t, cs, "walked", []byte("walk me"))
+
+	var seen []digest.Digest
+	require.NoError(t, cs.Walk(ctx, func(info content.Info) error {
+		seen = append(seen, info.Digest)
+		return nil
+	}))
+	assert.Equal(t, []digest.Digest{dgst}, seen)
+
+	require.NoError(t, cs.Delete(ctx, dgst))
+	_, err := cs.Info(ctx, dgst)
+	assert.True(t, errdefs.IsNotFound(err))
+}
+
+func TestFileKeyProviderRejectsForeignKeyID(t *testing.T) {
+	ctx := context.Background()
+	keyPath := filepath.Join(t.TempDir(), "master.key")
+	key := make([]byte, keySize)
+	require.NoError(t, os.WriteFile(keyPath, key, 0600))
+	provider, err := NewFileKeyProvider(keyPath)
+	require.NoError(t, err)
+
+	wrapped, _, err := provider.WrapKey(ctx, make([]byte, keySize))
+	require.NoError(t, err)
+	_, err = provider.UnwrapKey(ctx, wrapped, "sha256:other")
+	require.Error(t, err)
+	assert.True(t, errdefs.IsFailedPrecondition(err))
+}
@@ -0,0 +1,92 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+)
+
+// NewFileKeyProvider returns a KeyProvider using a master key read from
+// path. The file holds a 32 byte AES-256 key, raw or hex encoded, and
+// must not be readable by other users.
+func NewFileKeyProvider(path string) (KeyProvider, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat master key file: %w", err)
+	}
+	// File modes carry no ownership semantics on Windows.
+	if perm := fi.Mode().Perm(); runtime.GOOS != "windows" && perm&0o077 != 0 {
+		return nil, fmt.Errorf("master key file %s must only be accessible by its owner, got mode %04o", path, perm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+	key := bytes.TrimSpace(data)
+	if decoded, err := hex.DecodeString(string(key)); err == nil {
+		key = decoded
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("master key must be %d bytes, raw or hex encoded, got %d bytes", keySize, len(key))
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &fileKeyProvider{
+		aead: aead,
+		id:   digest.FromBytes(key).String(),
+	}, nil
+}
+
+type fileKeyProvider struct {
+	aead cipher.AEAD
+	id   string
+}
+
+func (p *fileKeyProvider) WrapKey(_ context.Context, key []byte) ([]byte, string, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+	return p.aead.Seal(nonce, nonce, key, nil), p.id, nil
+}
+
+func (p *fileKeyProvider) UnwrapKey(_ context.Context, wrapped []byte, keyID string) ([]byte, error) {
+	if keyID != p.id {
+		return nil, fmt.Errorf("data key was wrapped by master key %s, not the configured one: %w", keyID, errdefs.ErrFailedPrecondition)
+	}
+	if len(wrapped) < p.aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key too short: %w", errdefs.ErrInvalidArgument)
+	}
+	nonce, sealed := wrapped[:p.aead.NonceSize()], wrapped[p.aead.NonceSize():]
+	key, err := p.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,85 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package encrypted
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/basuotian/containerd/core/content"
+)
+
+// decryptingReaderAt decrypts the chunked ciphertext of a blob on the
+// fly. A read only touches the chunks covering the requested range, so
+// random access stays proportional to the amount read.
+type decryptingReaderAt struct {
+	ra   content.ReaderAt
+	aead cipher.AEAD
+	// size is the plaintext size of the blob.
+	size int64
+}
+
+func (r *decryptingReaderAt) Size() int64 {
+	return r.size
+}
+
+func (r *decryptingReaderAt) Close() error {
+	return r.ra.Close()
+}
+
+func (r *decryptingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("invalid offset %d", off)
+	}
+	n := 0
+	for n < len(p) && off < r.size {
+		chunk, err := r.readChunk(off / plaintextChunkSize)
+		if err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], chunk[off%plaintextChunkSize:])
+		n += copied
+		off += int64(copied)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// readChunk decrypts the chunk with the given index.
+func (r *decryptingReaderAt) readChunk(index int64) ([]byte, error) {
+	plaintextLen := r.size - index*plaintextChunkSize
+	if plaintextLen <= 0 {
+		return nil, io.EOF
+	}
+	if plaintextLen > plaintextChunkSize {
+		plaintextLen = plaintextChunkSize
+	}
+	overhead := int64(r.aead.Overhead())
+	sealed := make([]byte, plaintextLen+overhead)
+	if _, err := r.ra.ReadAt(sealed, index*(plaintextChunkSize+overhead)); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	chunk, err := r.aead.Open(nil, chunkNonce(r.aead, index), sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt chunk %d: %w", index, err)
+	}
+	return chunk, nil
+}
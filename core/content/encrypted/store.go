@@ -0,0 +1,302 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package encrypted
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/core/content"
+)
+
+// NewStore decorates base so blob data is encrypted at rest with data
+// keys wrapped by provider. Blobs written before encryption was enabled
+// remain readable in the clear. The underlying store must support labels.
+func NewStore(base content.Store, provider KeyProvider) content.Store {
+	return &store{
+		base:        base,
+		provider:    provider,
+		byPlaintext: map[digest.Digest]digest.Digest{},
+	}
+}
+
+type store struct {
+	base     content.Store
+	provider KeyProvider
+
+	// byPlaintext caches the ciphertext digest blobs are stored under in
+	// the base store, keyed by the plaintext digest consumers use.
+	mu          sync.Mutex
+	byPlaintext map[digest.Digest]digest.Digest
+}
+
+// resolve returns the base store info of the blob addressed by dgst and
+// whether the blob is encrypted.
+func (s *store) resolve(ctx context.Context, dgst digest.Digest) (content.Info, bool, error) {
+	// Blobs stored before encryption was enabled live under their
+	// plaintext digest.
+	if info, err := s.base.Info(ctx, dgst); err == nil {
+		return info, info.Labels[LabelPlaintextDigest] != "", nil
+	} else if !errdefs.IsNotFound(err) {
+		return content.Info{}, false, err
+	}
+
+	s.mu.Lock()
+	ct, ok := s.byPlaintext[dgst]
+	s.mu.Unlock()
+	if ok {
+		info, err := s.base.Info(ctx, ct)
+		if err == nil {
+			return info, true, nil
+		}
+		if !errdefs.IsNotFound(err) {
+			return content.Info{}, false, err
+		}
+		s.forget(dgst)
+	}
+
+	var found *content.Info
+	filter := fmt.Sprintf(`labels.%q==%q`, LabelPlaintextDigest, dgst.String())
+	if err := s.base.Walk(ctx, func(info content.Info) error {
+		found = &info
+		return nil
+	}, filter); err != nil && !errors.Is(err, os.ErrNotExist) {
+		// A store nothing was written to yet may not have a blob
+		// directory to walk.
+		return content.Info{}, false, err
+	}
+	if found == nil {
+		return content.Info{}, false, fmt.Errorf("content %v: %w", dgst, errdefs.ErrNotFound)
+	}
+	s.remember(dgst, found.Digest)
+	return *found, true, nil
+}
+
+func (s *store) remember(plaintext, ciphertext digest.Digest) {
+	s.mu.Lock()
+	s.byPlaintext[plaintext] = ciphertext
+	s.mu.Unlock()
+}
+
+func (s *store) forget(plaintext digest.Digest) {
+	s.mu.Lock()
+	delete(s.byPlaintext, plaintext)
+	s.mu.Unlock()
+}
+
+// plaintextView translates the base store info of an encrypted blob into
+// the view consumers get: addressed by the plaintext digest, sized as the
+// plaintext, without the encryption labels.
+func plaintextView(info content.Info) (content.Info, error) {
+	dgst, err := digest.Parse(info.Labels[LabelPlaintextDigest])
+	if err != nil {
+		return content.Info{}, fmt.Errorf("invalid %s label on %v: %w", LabelPlaintextDigest, info.Digest, err)
+	}
+	size, err := strconv.ParseInt(info.Labels[LabelPlaintextSize], 10, 64)
+	if err != nil {
+		return content.Info{}, fmt.Errorf("invalid %s label on %v: %w", LabelPlaintextSize, info.Digest, err)
+	}
+	view := info
+	view.Digest = dgst
+	view.Size = size
+	view.Labels = nil
+	for k, v := range info.Labels {
+		if strings.HasPrefix(k, labelPrefix) {
+			continue
+		}
+		if view.Labels == nil {
+			view.Labels = map[string]string{}
+		}
+		view.Labels[k] = v
+	}
+	return view, nil
+}
+
+func (s *store) Info(ctx context.Context, dgst digest.Digest) (content.Info, error) {
+	info, encrypted, err := s.resolve(ctx, dgst)
+	if err != nil {
+		return content.Info{}, err
+	}
+	if !encrypted {
+		return info, nil
+	}
+	return plaintextView(info)
+}
+
+func (s *store) Update(ctx context.Context, info content.Info, fieldpaths ...string) (content.Info, error) {
+	base, encrypted, err := s.resolve(ctx, info.Digest)
+	if err != nil {
+		return content.Info{}, err
+	}
+	if !encrypted {
+		return s.base.Update(ctx, info, fieldpaths...)
+	}
+	clone := info
+	clone.Digest = base.Digest
+	clone.Size = base.Size
+	if clone.Labels != nil {
+		// The encryption labels are not for consumers to change; carry
+		// them over so a full label replacement cannot drop them.
+		clone.Labels = make(map[string]string, len(info.Labels))
+		for k, v := range info.Labels {
+			if !strings.HasPrefix(k, labelPrefix) {
+				clone.Labels[k] = v
+			}
+		}
+		for k, v := range base.Labels {
+			if strings.HasPrefix(k, labelPrefix) {
+				clone.Labels[k] = v
+			}
+		}
+	}
+	updated, err := s.base.Update(ctx, clone, fieldpaths...)
+	if err != nil {
+		return content.Info{}, err
+	}
+	return plaintextView(updated)
+}
+
+func (s *store) Walk(ctx context.Context, fn content.WalkFunc, fs ...string) error {
+	return s.base.Walk(ctx, func(info content.Info) error {
+		if info.Labels[LabelPlaintextDigest] == "" {
+			return fn(info)
+		}
+		view, err := plaintextView(info)
+		if err != nil {
+			return err
+		}
+		s.remember(view.Digest, info.Digest)
+		return fn(view)
+	}, fs...)
+}
+
+func (s *store) Delete(ctx context.Context, dgst digest.Digest) error {
+	info, encrypted, err := s.resolve(ctx, dgst)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		defer s.forget(dgst)
+	}
+	return s.base.Delete(ctx, info.Digest)
+}
+
+func (s *store) ReaderAt(ctx context.Context, desc ocispec.Descriptor) (content.ReaderAt, error) {
+	info, encrypted, err := s.resolve(ctx, desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted {
+		return s.base.ReaderAt(ctx, desc)
+	}
+	if scheme := info.Labels[LabelScheme]; scheme != schemeAESGCMChunked {
+		return nil, fmt.Errorf("blob %v uses unknown encryption scheme %q: %w", desc.Digest, scheme, errdefs.ErrNotImplemented)
+	}
+	size, err := strconv.ParseInt(info.Labels[LabelPlaintextSize], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s label on %v: %w", LabelPlaintextSize, info.Digest, err)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(info.Labels[LabelWrappedKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s label on %v: %w", LabelWrappedKey, info.Digest, err)
+	}
+	key, err := s.provider.UnwrapKey(ctx, wrapped, info.Labels[LabelKeyID])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	ra, err := s.base.ReaderAt(ctx, ocispec.Descriptor{Digest: info.Digest, Size: info.Size})
+	if err != nil {
+		return nil, err
+	}
+	return &decryptingReaderAt{ra: ra, aead: aead, size: size}, nil
+}
+
+func (s *store) Status(ctx context.Context, ref string) (content.Status, error) {
+	return s.base.Status(ctx, ref)
+}
+
+func (s *store) ListStatuses(ctx context.Context, filters ...string) ([]content.Status, error) {
+	return s.base.ListStatuses(ctx, filters...)
+}
+
+func (s *store) Abort(ctx context.Context, ref string) error {
+	return s.base.Abort(ctx, ref)
+}
+
+func (s *store) Writer(ctx context.Context, opts ...content.WriterOpt) (content.Writer, error) {
+	var wOpts content.WriterOpts
+	for _, opt := range opts {
+		if err := opt(&wOpts); err != nil {
+			return nil, err
+		}
+	}
+	if wOpts.Desc.Digest != "" {
+		if _, _, err := s.resolve(ctx, wOpts.Desc.Digest); err == nil {
+			return nil, fmt.Errorf("content %v: %w", wOpts.Desc.Digest, errdefs.ErrAlreadyExists)
+		} else if !errdefs.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	bw, err := s.base.Writer(ctx, content.WithRef(wOpts.Ref))
+	if err != nil {
+		return nil, err
+	}
+	// A resumed ingest cannot be continued without the data key of the
+	// interrupted write; start the blob over.
+	if st, err := bw.Status(); err == nil && st.Offset > 0 {
+		if err := bw.Truncate(0); err != nil {
+			bw.Close()
+			return nil, err
+		}
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		bw.Close()
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		bw.Close()
+		return nil, err
+	}
+	return &writer{
+		store:    s,
+		base:     bw,
+		key:      key,
+		aead:     aead,
+		digester: digest.Canonical.Digester(),
+		expected: wOpts.Desc.Digest,
+		total:    wOpts.Desc.Size,
+	}, nil
+}
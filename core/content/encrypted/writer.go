@@ -0,0 +1,164 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package encrypted
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+
+	"github.com/basuotian/containerd/core/content"
+)
+
+// writer encrypts the written plaintext chunk-wise into the base writer
+// while digesting the plaintext, so Commit can verify the expected digest
+// the consumer knows against data the base store never sees.
+type writer struct {
+	store    *store
+	base     content.Writer
+	key      []byte
+	aead     cipher.AEAD
+	digester digest.Digester
+	expected digest.Digest
+	total    int64
+
+	buf       []byte
+	chunk     int64
+	offset    int64
+	committed bool
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.committed {
+		return 0, fmt.Errorf("cannot write to committed writer: %w", errdefs.ErrFailedPrecondition)
+	}
+	w.digester.Hash().Write(p)
+	w.offset += int64(len(p))
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= plaintextChunkSize {
+		if err := w.seal(w.buf[:plaintextChunkSize]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[plaintextChunkSize:]
+	}
+	return len(p), nil
+}
+
+// seal encrypts one chunk of plaintext into the base writer.
+func (w *writer) seal(chunk []byte) error {
+	sealed := w.aead.Seal(nil, chunkNonce(w.aead, w.chunk), chunk, nil)
+	if _, err := w.base.Write(sealed); err != nil {
+		return err
+	}
+	w.chunk++
+	return nil
+}
+
+func (w *writer) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	if w.committed {
+		return fmt.Errorf("cannot commit on committed writer: %w", errdefs.ErrFailedPrecondition)
+	}
+	if len(w.buf) > 0 {
+		if err := w.seal(w.buf); err != nil {
+			return err
+		}
+		w.buf = nil
+	}
+	if size > 0 && size != w.offset {
+		return fmt.Errorf("unexpected commit size %d, expected %d: %w", w.offset, size, errdefs.ErrFailedPrecondition)
+	}
+	dgst := w.digester.Digest()
+	if expected == "" {
+		expected = w.expected
+	}
+	if expected != "" && expected != dgst {
+		return fmt.Errorf("unexpected commit digest %s, expected %s: %w", dgst, expected, errdefs.ErrFailedPrecondition)
+	}
+
+	// Another write of the same blob may have finished first; its
+	// ciphertext differs, so the base store cannot deduplicate for us.
+	if _, _, err := w.store.resolve(ctx, dgst); err == nil {
+		return fmt.Errorf("content %v: %w", dgst, errdefs.ErrAlreadyExists)
+	} else if !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	var base content.Info
+	for _, opt := range opts {
+		if err := opt(&base); err != nil {
+			return err
+		}
+	}
+	wrapped, keyID, err := w.store.provider.WrapKey(ctx, w.key)
+	if err != nil {
+		return fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	labels := make(map[string]string, len(base.Labels)+5)
+	for k, v := range base.Labels {
+		labels[k] = v
+	}
+	labels[LabelScheme] = schemeAESGCMChunked
+	labels[LabelPlaintextDigest] = dgst.String()
+	labels[LabelPlaintextSize] = strconv.FormatInt(w.offset, 10)
+	labels[LabelWrappedKey] = base64.StdEncoding.EncodeToString(wrapped)
+	labels[LabelKeyID] = keyID
+
+	if err := w.base.Commit(ctx, 0, "", content.WithLabels(labels)); err != nil {
+		return err
+	}
+	w.store.remember(dgst, w.base.Digest())
+	w.committed = true
+	return nil
+}
+
+func (w *writer) Digest() digest.Digest {
+	return w.digester.Digest()
+}
+
+func (w *writer) Status() (content.Status, error) {
+	st, err := w.base.Status()
+	if err != nil {
+		return content.Status{}, err
+	}
+	st.Offset = w.offset
+	st.Total = w.total
+	st.Expected = w.expected
+	return st, nil
+}
+
+func (w *writer) Truncate(size int64) error {
+	if size != 0 {
+		return fmt.Errorf("truncate of encrypted ingest only supports size 0: %w", errdefs.ErrNotImplemented)
+	}
+	if err := w.base.Truncate(0); err != nil {
+		return err
+	}
+	w.buf = nil
+	w.chunk = 0
+	w.offset = 0
+	w.digester = digest.Canonical.Digester()
+	return nil
+}
+
+func (w *writer) Close() error {
+	return w.base.Close()
+}
@@ -0,0 +1,190 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package proxy
+
+import (
+	"io"
+	"sync"
+
+	"github.com/basuotian/containerd/core/content"
+)
+
+const (
+	// defaultChunkSize is the range size fetched per content Read call.
+	defaultChunkSize = 1 << 20
+	// defaultCachedChunks is the number of chunks kept in the LRU cache.
+	defaultCachedChunks = 8
+)
+
+// rangedReaderAt fetches a remote blob in fixed size chunks, keeping a
+// small LRU cache of recent chunks and reading one chunk ahead of the last
+// access. Consumers doing sparse random access over large artifacts, such
+// as lazily loaded models, avoid streaming the whole blob while repeated
+// reads within the same region stay local.
+type rangedReaderAt struct {
+	inner        content.ReaderAt
+	chunkSize    int64
+	cachedChunks int
+
+	mu       sync.Mutex
+	chunks   map[int64][]byte
+	order    []int64
+	inflight map[int64]chan struct{}
+}
+
+func newRangedReaderAt(inner content.ReaderAt, chunkSize int64, cachedChunks int) *rangedReaderAt {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	if cachedChunks <= 0 {
+		cachedChunks = defaultCachedChunks
+	}
+	return &rangedReaderAt{
+		inner:        inner,
+		chunkSize:    chunkSize,
+		cachedChunks: cachedChunks,
+		chunks:       make(map[int64][]byte),
+		inflight:     make(map[int64]chan struct{}),
+	}
+}
+
+func (ra *rangedReaderAt) Size() int64 {
+	return ra.inner.Size()
+}
+
+func (ra *rangedReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	size := ra.inner.Size()
+	if off < 0 || off >= size {
+		return 0, io.EOF
+	}
+	for len(p) > 0 && off < size {
+		idx := off / ra.chunkSize
+		c, err := ra.chunk(idx)
+		if err != nil {
+			return n, err
+		}
+		copied := copy(p, c[off-idx*ra.chunkSize:])
+		n += copied
+		p = p[copied:]
+		off += int64(copied)
+
+		// Read ahead of the chunk just served; sequential consumers
+		// overlap the next fetch with processing the current chunk.
+		ra.prefetch(idx + 1)
+	}
+	if len(p) > 0 {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (ra *rangedReaderAt) Close() error {
+	ra.mu.Lock()
+	ra.chunks = make(map[int64][]byte)
+	ra.order = nil
+	ra.mu.Unlock()
+	return ra.inner.Close()
+}
+
+// chunk returns the cached chunk with the given index, fetching it from the
+// remote store when it is neither cached nor already being prefetched.
+func (ra *rangedReaderAt) chunk(idx int64) ([]byte, error) {
+	for {
+		ra.mu.Lock()
+		if c, ok := ra.chunks[idx]; ok {
+			ra.touch(idx)
+			ra.mu.Unlock()
+			return c, nil
+		}
+		ch, ok := ra.inflight[idx]
+		if !ok {
+			ch = make(chan struct{})
+			ra.inflight[idx] = ch
+			ra.mu.Unlock()
+			return ra.fetch(idx, ch)
+		}
+		ra.mu.Unlock()
+
+		// Wait for the in-flight fetch; when it failed the chunk is not
+		// cached and the next round fetches it again, returning the error
+		// to this caller.
+		<-ch
+	}
+}
+
+// fetch reads the chunk from the remote store and publishes it in the
+// cache. The inflight entry is cleared even on error so waiters retry.
+func (ra *rangedReaderAt) fetch(idx int64, ch chan struct{}) ([]byte, error) {
+	defer func() {
+		ra.mu.Lock()
+		delete(ra.inflight, idx)
+		ra.mu.Unlock()
+		close(ch)
+	}()
+
+	off := idx * ra.chunkSize
+	length := min(ra.chunkSize, ra.inner.Size()-off)
+	c := make([]byte, length)
+	n, err := ra.inner.ReadAt(c, off)
+	if err != nil && !(err == io.EOF && int64(n) == length) {
+		return nil, err
+	}
+
+	ra.mu.Lock()
+	ra.chunks[idx] = c
+	ra.touch(idx)
+	for len(ra.order) > ra.cachedChunks {
+		delete(ra.chunks, ra.order[0])
+		ra.order = ra.order[1:]
+	}
+	ra.mu.Unlock()
+	return c, nil
+}
+
+// touch moves the chunk to the most recently used end of the LRU order.
+// Callers must hold mu.
+func (ra *rangedReaderAt) touch(idx int64) {
+	for i, o := range ra.order {
+		if o == idx {
+			ra.order = append(ra.order[:i], ra.order[i+1:]...)
+			break
+		}
+	}
+	ra.order = append(ra.order, idx)
+}
+
+// prefetch starts loading the chunk in the background unless it is cached,
+// already being fetched or past the end of the blob.
+func (ra *rangedReaderAt) prefetch(idx int64) {
+	if idx*ra.chunkSize >= ra.inner.Size() {
+		return
+	}
+	ra.mu.Lock()
+	if _, ok := ra.chunks[idx]; ok {
+		ra.mu.Unlock()
+		return
+	}
+	if _, ok := ra.inflight[idx]; ok {
+		ra.mu.Unlock()
+		return
+	}
+	ch := make(chan struct{})
+	ra.inflight[idx] = ch
+	ra.mu.Unlock()
+
+	go ra.fetch(idx, ch)
+}
@@ -0,0 +1,124 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+)
+
+// fakeReaderAt counts the reads issued against a blob held in memory.
+type fakeReaderAt struct {
+	data []byte
+
+	mu    sync.Mutex
+	reads []int64
+}
+
+func (f *fakeReaderAt) Size() int64 {
+	return int64(len(f.data))
+}
+
+func (f *fakeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	f.reads = append(f.reads, off)
+	f.mu.Unlock()
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *fakeReaderAt) Close() error {
+	return nil
+}
+
+func (f *fakeReaderAt) readCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.reads)
+}
+
+func TestRangedReaderAt(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	inner := &fakeReaderAt{data: data}
+	ra := newRangedReaderAt(inner, 100, 2)
+	defer ra.Close()
+
+	// Read spanning two chunks.
+	p := make([]byte, 150)
+	n, err := ra.ReadAt(p, 50)
+	if err != nil || n != 150 {
+		t.Fatalf("ReadAt = %d, %v", n, err)
+	}
+	if !bytes.Equal(p, data[50:200]) {
+		t.Error("unexpected data for spanning read")
+	}
+
+	// A repeated read within cached chunks must not hit the remote again.
+	before := inner.readCount()
+	if _, err := ra.ReadAt(p[:50], 100); err != nil {
+		t.Fatal(err)
+	}
+	if after := inner.readCount(); after != before {
+		t.Errorf("cached read issued %d remote reads", after-before)
+	}
+
+	// Read up to the end of the blob returns EOF with the partial data.
+	p = make([]byte, 100)
+	n, err = ra.ReadAt(p, 950)
+	if err != io.EOF {
+		t.Fatalf("expected EOF at end of blob, got %v", err)
+	}
+	if n != 50 || !bytes.Equal(p[:n], data[950:]) {
+		t.Errorf("unexpected tail read %d", n)
+	}
+
+	// Reads past the end fail with EOF.
+	if _, err := ra.ReadAt(p, 1000); err != io.EOF {
+		t.Errorf("expected EOF past end of blob, got %v", err)
+	}
+}
+
+func TestRangedReaderAtEviction(t *testing.T) {
+	inner := &fakeReaderAt{data: make([]byte, 400)}
+	ra := newRangedReaderAt(inner, 100, 1)
+	defer ra.Close()
+
+	p := make([]byte, 100)
+	for _, off := range []int64{0, 100, 200, 300} {
+		if _, err := ra.ReadAt(p, off); err != nil {
+			t.Fatalf("ReadAt(%d): %v", off, err)
+		}
+	}
+
+	ra.mu.Lock()
+	cached := len(ra.chunks)
+	ra.mu.Unlock()
+	if cached > 2 {
+		t.Errorf("expected at most 2 cached chunks (cache size 1 plus read-ahead), got %d", cached)
+	}
+}
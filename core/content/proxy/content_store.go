@@ -39,31 +39,51 @@ type proxyContentStore struct {
 	// client is the rpc content client
 	// NOTE: ttrpc is used because it is the smaller interface shared with grpc
 	client contentapi.TTRPCContentClient
+	// rangedChunkSize, when non-zero, makes ReaderAt fetch blobs in chunks
+	// of this size instead of streaming whole requests.
+	rangedChunkSize int64
+	// rangedCachedChunks is the number of chunks kept cached per reader.
+	rangedCachedChunks int
+}
+
+// StoreOpt configures a proxy content store.
+type StoreOpt func(*proxyContentStore)
+
+// WithRangedReads makes readers returned by ReaderAt fetch the blob in
+// fixed size chunks with one chunk of read-ahead and a small LRU cache,
+// instead of issuing one streaming request per read. This suits consumers
+// doing sparse random access over large artifacts. Zero values select a
+// 1 MiB chunk size and an 8 chunk cache.
+func WithRangedReads(chunkSize int64, cachedChunks int) StoreOpt {
+	return func(pcs *proxyContentStore) {
+		pcs.rangedChunkSize = chunkSize
+		if pcs.rangedChunkSize <= 0 {
+			pcs.rangedChunkSize = defaultChunkSize
+		}
+		pcs.rangedCachedChunks = cachedChunks
+	}
 }
 
 // NewContentStore returns a new content store which communicates over a GRPC
 // connection using the containerd content GRPC API.
-func NewContentStore(client any) content.Store {
+func NewContentStore(client any, opts ...StoreOpt) content.Store {
+	pcs := &proxyContentStore{}
 	switch c := client.(type) {
 	case contentapi.ContentClient:
-		return &proxyContentStore{
-			client: convertClient{c},
-		}
+		pcs.client = convertClient{c}
 	case grpc.ClientConnInterface:
-		return &proxyContentStore{
-			client: convertClient{contentapi.NewContentClient(c)},
-		}
+		pcs.client = convertClient{contentapi.NewContentClient(c)}
 	case contentapi.TTRPCContentClient:
-		return &proxyContentStore{
-			client: c,
-		}
+		pcs.client = c
 	case *ttrpc.Client:
-		return &proxyContentStore{
-			client: contentapi.NewTTRPCContentClient(c),
-		}
+		pcs.client = contentapi.NewTTRPCContentClient(c)
 	default:
 		panic(fmt.Errorf("unsupported content client %T: %w", client, errdefs.ErrNotImplemented))
 	}
+	for _, o := range opts {
+		o(pcs)
+	}
+	return pcs
 }
 
 func (pcs *proxyContentStore) Info(ctx context.Context, dgst digest.Digest) (content.Info, error) {
@@ -122,12 +142,16 @@ func (pcs *proxyContentStore) ReaderAt(ctx context.Context, desc ocispec.Descrip
 		return nil, err
 	}
 
-	return &remoteReaderAt{
+	rr := &remoteReaderAt{
 		ctx:    ctx,
 		digest: desc.Digest,
 		size:   i.Size,
 		client: pcs.client,
-	}, nil
+	}
+	if pcs.rangedChunkSize > 0 {
+		return newRangedReaderAt(rr, pcs.rangedChunkSize, pcs.rangedCachedChunks), nil
+	}
+	return rr, nil
 }
 
 func (pcs *proxyContentStore) Status(ctx context.Context, ref string) (content.Status, error) {
@@ -32,14 +32,34 @@ import (
 
 // NewFileSystemApplier returns an applier which simply mounts
 // and applies diff onto the mounted filesystem.
-func NewFileSystemApplier(cs content.Provider) diff.Applier {
-	return &fsApplier{
+func NewFileSystemApplier(cs content.Provider, opts ...ApplierOpt) diff.Applier {
+	s := &fsApplier{
 		store: cs,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ApplierOpt configures a filesystem applier.
+type ApplierOpt func(*fsApplier)
+
+// WithOverlayWhiteoutConversion makes the applier translate overlay style
+// whiteout markers in applied layers into deletions the target filesystem
+// can represent. It is meant for snapshotters which declare that their
+// backing filesystem cannot use the markers directly, for example because
+// it lacks trusted xattr support.
+func WithOverlayWhiteoutConversion() ApplierOpt {
+	return func(s *fsApplier) {
+		s.convertOverlayWhiteouts = true
+	}
 }
 
 type fsApplier struct {
 	store content.Provider
+
+	convertOverlayWhiteouts bool
 }
 
 var emptyDesc = ocispec.Descriptor{}
@@ -92,7 +112,10 @@ func (s *fsApplier) Apply(ctx context.Context, desc ocispec.Descriptor, mounts [
 		r: io.TeeReader(processor, digester.Hash()),
 	}
 
-	if err := apply(ctx, mounts, rc, config.SyncFs); err != nil {
+	if s.convertOverlayWhiteouts {
+		config.ConvertOverlayWhiteouts = true
+	}
+	if err := apply(ctx, mounts, rc, config); err != nil {
 		return emptyDesc, err
 	}
 
@@ -26,12 +26,14 @@ import (
 	"github.com/moby/sys/userns"
 	"golang.org/x/sys/unix"
 
+	"github.com/basuotian/containerd/core/diff"
 	"github.com/basuotian/containerd/core/mount"
 	"github.com/basuotian/containerd/pkg/archive"
 	"github.com/containerd/errdefs"
 )
 
-func apply(ctx context.Context, mounts []mount.Mount, r io.Reader, sync bool) (retErr error) {
+func apply(ctx context.Context, mounts []mount.Mount, r io.Reader, config diff.ApplyConfig) (retErr error) {
+	sync := config.SyncFs
 	switch {
 	case len(mounts) == 1 && mounts[0].Type == "overlay":
 		// OverlayConvertWhiteout (mknod c 0 0) doesn't work in userns.
@@ -39,6 +41,11 @@ func apply(ctx context.Context, mounts []mount.Mount, r io.Reader, sync bool) (r
 		if userns.RunningInUserNS() {
 			break
 		}
+		// The target cannot represent overlay markers, apply through the
+		// mounted filesystem so deletions become overlay whiteouts.
+		if config.ConvertOverlayWhiteouts {
+			break
+		}
 		path, parents, err := getOverlayPath(mounts[0].Options)
 		if err != nil {
 			if errdefs.IsInvalidArgument(err) {
@@ -67,7 +74,11 @@ func apply(ctx context.Context, mounts []mount.Mount, r io.Reader, sync bool) (r
 		}()
 	}
 	return mount.WithTempMount(ctx, mounts, func(root string) error {
-		_, err := archive.Apply(ctx, root, r)
+		var opts []archive.ApplyOpt
+		if config.ConvertOverlayWhiteouts {
+			opts = append(opts, archive.WithSourceOverlayWhiteouts())
+		}
+		_, err := archive.Apply(ctx, root, r, opts...)
 		return err
 	})
 }
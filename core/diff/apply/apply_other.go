@@ -23,16 +23,20 @@ import (
 	"io"
 	"os"
 
+	"github.com/basuotian/containerd/core/diff"
 	"github.com/basuotian/containerd/core/mount"
 	"github.com/basuotian/containerd/pkg/archive"
 )
 
-func apply(ctx context.Context, mounts []mount.Mount, r io.Reader, _sync bool) error {
+func apply(ctx context.Context, mounts []mount.Mount, r io.Reader, config diff.ApplyConfig) error {
 	// TODO: for windows, how to sync?
 
-	if !mount.HasBindMounts && len(mounts) == 1 && mounts[0].Type == "bind" {
-		opts := []archive.ApplyOpt{}
+	var opts []archive.ApplyOpt
+	if config.ConvertOverlayWhiteouts {
+		opts = append(opts, archive.WithSourceOverlayWhiteouts())
+	}
 
+	if !mount.HasBindMounts && len(mounts) == 1 && mounts[0].Type == "bind" {
 		if os.Getuid() != 0 {
 			opts = append(opts, archive.WithNoSameOwner())
 		}
@@ -45,7 +49,7 @@ func apply(ctx context.Context, mounts []mount.Mount, r io.Reader, _sync bool) e
 	}
 
 	return mount.WithTempMount(ctx, mounts, func(root string) error {
-		_, err := archive.Apply(ctx, root, r)
+		_, err := archive.Apply(ctx, root, r, opts...)
 		return err
 	})
 }
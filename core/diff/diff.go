@@ -69,6 +69,10 @@ type ApplyConfig struct {
 	ProcessorPayloads map[string]typeurl.Any
 	// SyncFs is to synchronize the underlying filesystem containing files
 	SyncFs bool
+	// ConvertOverlayWhiteouts requests translation of overlay style whiteout
+	// markers in the layer into deletions the target filesystem can
+	// represent. It is honored by in-process appliers only.
+	ConvertOverlayWhiteouts bool
 }
 
 // ApplyOpt is used to configure an Apply operation
@@ -135,6 +139,18 @@ func WithSyncFs(sync bool) ApplyOpt {
 	}
 }
 
+// WithOverlayWhiteoutConversion requests translation of overlay style
+// whiteout markers in the layer into deletions the target filesystem can
+// represent. It is used when the target snapshotter cannot represent the
+// markers, for example because its backing filesystem lacks trusted xattr
+// support.
+func WithOverlayWhiteoutConversion(convert bool) ApplyOpt {
+	return func(_ context.Context, _ ocispec.Descriptor, c *ApplyConfig) error {
+		c.ConvertOverlayWhiteouts = convert
+		return nil
+	}
+}
+
 // WithSourceDateEpoch specifies the timestamp used to provide control for reproducibility.
 // See also https://reproducible-builds.org/docs/source-date-epoch/ .
 //
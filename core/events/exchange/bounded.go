@@ -0,0 +1,184 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package exchange
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	goevents "github.com/docker/go-events"
+)
+
+// SlowSubscriberPolicy selects how the exchange treats a subscriber whose
+// buffer limit is reached.
+type SlowSubscriberPolicy string
+
+const (
+	// PolicyDropOldest drops the oldest buffered event to make room for
+	// the new one.
+	PolicyDropOldest SlowSubscriberPolicy = "drop-oldest"
+	// PolicyDisconnect ends the subscription.
+	PolicyDisconnect SlowSubscriberPolicy = "disconnect"
+	// PolicyBlockWithTimeout blocks the publisher up to the configured
+	// timeout waiting for room, then ends the subscription.
+	PolicyBlockWithTimeout SlowSubscriberPolicy = "block-with-timeout"
+)
+
+// Valid reports whether p is a known policy.
+func (p SlowSubscriberPolicy) Valid() bool {
+	switch p {
+	case PolicyDropOldest, PolicyDisconnect, PolicyBlockWithTimeout:
+		return true
+	}
+	return false
+}
+
+// boundedQueue accepts events from the broadcaster and delivers them to
+// dst from a buffer holding at most limit events, applying the configured
+// policy when the buffer is full. It replaces the unbounded
+// goevents.Queue for subscriptions with a buffer limit.
+type boundedQueue struct {
+	dst     goevents.Sink
+	limit   int
+	policy  SlowSubscriberPolicy
+	timeout time.Duration
+	// subscriber identifies the subscription in metrics.
+	subscriber string
+
+	mu           sync.Mutex
+	cond         *sync.Cond
+	events       *list.List
+	closed       bool
+	disconnected chan struct{}
+}
+
+func newBoundedQueue(dst goevents.Sink, limit int, policy SlowSubscriberPolicy, timeout time.Duration, subscriber string) *boundedQueue {
+	q := &boundedQueue{
+		dst:          dst,
+		limit:        limit,
+		policy:       policy,
+		timeout:      timeout,
+		subscriber:   subscriber,
+		events:       list.New(),
+		disconnected: make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	go q.run()
+	return q
+}
+
+// Disconnected is closed when the queue ended the subscription because
+// the subscriber was too slow.
+func (q *boundedQueue) Disconnected() <-chan struct{} {
+	return q.disconnected
+}
+
+// Write accepts an event into the queue. When the buffer is full the
+// configured policy decides whether the oldest event is dropped, the
+// subscription is ended, or the write blocks waiting for room.
+func (q *boundedQueue) Write(event goevents.Event) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return goevents.ErrSinkClosed
+	}
+
+	if q.events.Len() >= q.limit {
+		switch q.policy {
+		case PolicyDropOldest:
+			q.events.Remove(q.events.Front())
+			droppedCounter.WithValues(q.subscriber).Inc()
+		case PolicyBlockWithTimeout:
+			if !q.waitForRoom() {
+				q.disconnectLocked()
+				return goevents.ErrSinkClosed
+			}
+		default: // PolicyDisconnect
+			q.disconnectLocked()
+			return goevents.ErrSinkClosed
+		}
+	}
+
+	q.events.PushBack(event)
+	q.cond.Signal()
+	return nil
+}
+
+// waitForRoom blocks until the buffer has room or the timeout passes,
+// reporting whether room became available. Called with the lock held.
+func (q *boundedQueue) waitForRoom() bool {
+	deadline := time.Now().Add(q.timeout)
+	timer := time.AfterFunc(q.timeout, func() {
+		// Wake the waiter so it observes the deadline.
+		q.cond.Broadcast()
+	})
+	defer timer.Stop()
+	for q.events.Len() >= q.limit && !q.closed {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		q.cond.Wait()
+	}
+	return !q.closed
+}
+
+// disconnectLocked ends the subscription. Called with the lock held.
+func (q *boundedQueue) disconnectLocked() {
+	if !q.closed {
+		q.closed = true
+		close(q.disconnected)
+		disconnectedCounter.WithValues(q.subscriber).Inc()
+		q.cond.Broadcast()
+	}
+}
+
+func (q *boundedQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	q.cond.Broadcast()
+	return nil
+}
+
+// run delivers buffered events to the destination sink.
+func (q *boundedQueue) run() {
+	for {
+		q.mu.Lock()
+		for q.events.Len() == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		front := q.events.Front()
+		event := front.Value.(goevents.Event)
+		q.events.Remove(front)
+		q.cond.Broadcast()
+		q.mu.Unlock()
+
+		if err := q.dst.Write(event); err != nil {
+			// The destination channel is closed when the
+			// subscription ends; nothing left to deliver to.
+			return
+		}
+	}
+}
@@ -19,7 +19,10 @@ package exchange
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/basuotian/containerd/core/events"
@@ -35,13 +38,72 @@ import (
 // Exchange broadcasts events
 type Exchange struct {
 	broadcaster *goevents.Broadcaster
+
+	// bufferLimit bounds each subscriber's queue. Zero means unbounded.
+	bufferLimit  int
+	policy       SlowSubscriberPolicy
+	blockTimeout time.Duration
+	subscriberID atomic.Uint64
+
+	mu        sync.Mutex
+	published map[string]uint64
+}
+
+// ExchangeOpt allows the caller to set options on a new Exchange
+type ExchangeOpt func(*Exchange)
+
+// WithSubscriberBufferLimit bounds the number of events buffered for each
+// subscriber. When a subscriber falls behind by more than limit events, the
+// exchange applies the slow subscriber policy. A zero limit leaves
+// subscriber queues unbounded.
+func WithSubscriberBufferLimit(limit int) ExchangeOpt {
+	return func(e *Exchange) {
+		e.bufferLimit = limit
+	}
+}
+
+// WithSlowSubscriberPolicy sets how a subscriber which reaches its buffer
+// limit is handled.
+func WithSlowSubscriberPolicy(policy SlowSubscriberPolicy) ExchangeOpt {
+	return func(e *Exchange) {
+		e.policy = policy
+	}
+}
+
+// WithBlockTimeout sets how long publishing blocks waiting for a full
+// subscriber under PolicyBlockWithTimeout before disconnecting it.
+func WithBlockTimeout(timeout time.Duration) ExchangeOpt {
+	return func(e *Exchange) {
+		e.blockTimeout = timeout
+	}
 }
 
 // NewExchange returns a new event Exchange
-func NewExchange() *Exchange {
-	return &Exchange{
+func NewExchange(opts ...ExchangeOpt) *Exchange {
+	e := &Exchange{
 		broadcaster: goevents.NewBroadcaster(),
+		policy:      PolicyDropOldest,
+		published:   map[string]uint64{},
+	}
+	for _, opt := range opts {
+		opt(e)
 	}
+	return e
+}
+
+// Published returns the number of events accepted on the exchange for the
+// given namespace since the exchange was created.
+func (e *Exchange) Published(namespace string) uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.published[namespace]
+}
+
+func (e *Exchange) accountPublished(namespace string) {
+	e.mu.Lock()
+	e.published[namespace]++
+	e.mu.Unlock()
+	publishedCounter.WithValues(namespace).Inc()
 }
 
 var _ events.Publisher = &Exchange{}
@@ -71,7 +133,11 @@ func (e *Exchange) Forward(ctx context.Context, envelope *events.Envelope) (err
 		}
 	}()
 
-	return e.broadcaster.Write(envelope)
+	if err := e.broadcaster.Write(envelope); err != nil {
+		return err
+	}
+	e.accountPublished(envelope.Namespace)
+	return nil
 }
 
 // Publish packages and sends an event. The caller will be considered the
@@ -115,7 +181,11 @@ func (e *Exchange) Publish(ctx context.Context, topic string, event events.Event
 		}
 	}()
 
-	return e.broadcaster.Write(&envelope)
+	if err := e.broadcaster.Write(&envelope); err != nil {
+		return err
+	}
+	e.accountPublished(envelope.Namespace)
+	return nil
 }
 
 // Subscribe to events on the exchange. Events are sent through the returned
@@ -127,13 +197,24 @@ func (e *Exchange) Publish(ctx context.Context, topic string, event events.Event
 // the standard containerd filters package syntax.
 func (e *Exchange) Subscribe(ctx context.Context, fs ...string) (ch <-chan *events.Envelope, errs <-chan error) {
 	var (
-		evch                  = make(chan *events.Envelope)
-		errq                  = make(chan error, 1)
-		channel               = goevents.NewChannel(0)
-		queue                 = goevents.NewQueue(channel)
-		dst     goevents.Sink = queue
+		evch         = make(chan *events.Envelope)
+		errq         = make(chan error, 1)
+		channel      = goevents.NewChannel(0)
+		queue        goevents.Sink
+		disconnected <-chan struct{}
+		dst          goevents.Sink
 	)
 
+	if e.bufferLimit > 0 {
+		subscriber := strconv.FormatUint(e.subscriberID.Add(1), 10)
+		bounded := newBoundedQueue(channel, e.bufferLimit, e.policy, e.blockTimeout, subscriber)
+		disconnected = bounded.Disconnected()
+		queue = bounded
+	} else {
+		queue = goevents.NewQueue(channel)
+	}
+	dst = queue
+
 	closeAll := func() {
 		channel.Close()
 		queue.Close()
@@ -180,7 +261,13 @@ func (e *Exchange) Subscribe(ctx context.Context, fs ...string) (ch <-chan *even
 				case evch <- env:
 				case <-ctx.Done():
 					break loop
+				case <-disconnected:
+					err = fmt.Errorf("subscription too slow, disconnected by the exchange: %w", errdefs.ErrUnavailable)
+					break loop
 				}
+			case <-disconnected:
+				err = fmt.Errorf("subscription too slow, disconnected by the exchange: %w", errdefs.ErrUnavailable)
+				break loop
 			case <-ctx.Done():
 				break loop
 			}
@@ -19,6 +19,7 @@ package exchange
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -324,3 +325,93 @@ func TestExchangeValidateTopic(t *testing.T) {
 		})
 	}
 }
+
+func TestExchangeSlowSubscriber(t *testing.T) {
+	t.Run("drop-oldest", func(t *testing.T) {
+		ns := "slow-drop-oldest"
+		ctx := namespaces.WithNamespace(context.Background(), ns)
+		exchange := NewExchange(
+			WithSubscriberBufferLimit(2),
+			WithSlowSubscriberPolicy(PolicyDropOldest),
+		)
+
+		subctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		eventq, errq := exchange.Subscribe(subctx)
+
+		// Publish without reading so the subscriber's buffer overflows.
+		count := 10
+		for i := 0; i < count; i++ {
+			event := &eventstypes.ContainerCreate{ID: fmt.Sprint(i)}
+			if err := exchange.Publish(ctx, "/test", event); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if published := exchange.Published(ns); published != uint64(count) {
+			t.Fatalf("expected %d published events accounted, got %d", count, published)
+		}
+
+		// With a buffer limit of 2 only a handful of events survive and
+		// the newest event is never dropped.
+		var (
+			received int
+			last     string
+		)
+	recv:
+		for {
+			select {
+			case env := <-eventq:
+				received++
+				v, err := typeurl.UnmarshalAny(env.Event)
+				if err != nil {
+					t.Fatal(err)
+				}
+				last = v.(*eventstypes.ContainerCreate).ID
+				if last == fmt.Sprint(count-1) {
+					break recv
+				}
+			case <-time.After(5 * time.Second):
+				break recv
+			}
+		}
+		if received >= count {
+			t.Fatalf("expected events to be dropped, received all %d", received)
+		}
+		if last != fmt.Sprint(count-1) {
+			t.Fatalf("expected newest event %d to survive, last received %q", count-1, last)
+		}
+
+		cancel()
+		if err := <-errq; err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		ctx := namespaces.WithNamespace(context.Background(), "slow-disconnect")
+		exchange := NewExchange(
+			WithSubscriberBufferLimit(1),
+			WithSlowSubscriberPolicy(PolicyDisconnect),
+		)
+
+		subctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		_, errq := exchange.Subscribe(subctx)
+
+		for i := 0; i < 5; i++ {
+			event := &eventstypes.ContainerCreate{ID: fmt.Sprint(i)}
+			if err := exchange.Publish(ctx, "/test", event); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		select {
+		case err := <-errq:
+			if !errdefs.IsUnavailable(err) {
+				t.Fatalf("expected unavailable error for slow subscriber, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("subscriber was not disconnected")
+		}
+	})
+}
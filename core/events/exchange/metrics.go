@@ -0,0 +1,38 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package exchange
+
+import "github.com/docker/go-metrics"
+
+var (
+	// publishedCounter metrics for counter of events published per namespace.
+	publishedCounter metrics.LabeledCounter
+
+	// droppedCounter metrics for counter of events dropped per slow subscriber.
+	droppedCounter metrics.LabeledCounter
+
+	// disconnectedCounter metrics for counter of subscribers disconnected for being slow.
+	disconnectedCounter metrics.LabeledCounter
+)
+
+func init() {
+	ns := metrics.NewNamespace("containerd", "events", nil)
+	publishedCounter = ns.NewLabeledCounter("published", "counter of events published per namespace", "namespace")
+	droppedCounter = ns.NewLabeledCounter("dropped", "counter of events dropped per slow subscriber", "subscriber")
+	disconnectedCounter = ns.NewLabeledCounter("slow_disconnects", "counter of subscribers disconnected for being slow", "subscriber")
+	metrics.Register(ns)
+}
@@ -25,10 +25,13 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/basuotian/containerd/core/content"
 	"github.com/basuotian/containerd/core/images"
+	"github.com/basuotian/containerd/core/leases"
 	"github.com/basuotian/containerd/pkg/labels"
+	"github.com/basuotian/containerd/version"
 	"github.com/containerd/errdefs"
 	"github.com/containerd/log"
 	"github.com/containerd/platforms"
@@ -42,6 +45,8 @@ type exportOptions struct {
 	platform           platforms.MatchComparer
 	allPlatforms       bool
 	skipDockerManifest bool
+	provenance         bool
+	pullTimes          map[digest.Digest]time.Time
 	blobRecordOptions  blobRecordOptions
 }
 
@@ -77,6 +82,19 @@ func WithSkipDockerManifest() ExportOpt {
 	}
 }
 
+// WithProvenance embeds provenance annotations into the archive index,
+// recording the containerd version and lease of the exporting daemon, the
+// export time and the pull time of each exported image, so consumers of
+// archive-distributed images can trace where they originated. The original
+// registry source is recorded by the containerd.io/distribution.source
+// labels, which are always copied onto the manifest descriptors.
+func WithProvenance() ExportOpt {
+	return func(ctx context.Context, o *exportOptions) error {
+		o.provenance = true
+		return nil
+	}
+}
+
 // WithImage adds the provided images to the exported archive.
 func WithImage(is images.Store, name string) ExportOpt {
 	return func(ctx context.Context, o *exportOptions) error {
@@ -87,6 +105,7 @@ func WithImage(is images.Store, name string) ExportOpt {
 
 		img.Target.Annotations = addNameAnnotation(name, img.Target.Annotations)
 		o.manifests = append(o.manifests, img.Target)
+		o.recordPullTime(img)
 
 		return nil
 	}
@@ -98,12 +117,25 @@ func WithImages(imgs []images.Image) ExportOpt {
 		for _, img := range imgs {
 			img.Target.Annotations = addNameAnnotation(img.Name, img.Target.Annotations)
 			o.manifests = append(o.manifests, img.Target)
+			o.recordPullTime(img)
 		}
 
 		return nil
 	}
 }
 
+// recordPullTime remembers when the image was created in the image store,
+// normally its pull time, for the provenance annotations.
+func (o *exportOptions) recordPullTime(img images.Image) {
+	if img.CreatedAt.IsZero() {
+		return
+	}
+	if o.pullTimes == nil {
+		o.pullTimes = map[digest.Digest]time.Time{}
+	}
+	o.pullTimes[img.Target.Digest] = img.CreatedAt
+}
+
 // WithManifest adds a manifest to the exported archive.
 // When names are given they will be set on the manifest in the
 // exported archive, creating an index record for each name.
@@ -234,6 +266,29 @@ func Export(ctx context.Context, store content.InfoReaderProvider, writer io.Wri
 		manifests = append(manifests, d)
 	}
 
+	var indexAnnotations map[string]string
+	if eo.provenance {
+		indexAnnotations = map[string]string{
+			AnnotationProvenanceVersion:    version.Version,
+			AnnotationProvenanceExportedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		if lease, ok := leases.FromContext(ctx); ok {
+			indexAnnotations[AnnotationProvenanceLease] = lease
+		}
+		for i, desc := range manifests {
+			pulledAt, ok := eo.pullTimes[desc.Digest]
+			if !ok {
+				continue
+			}
+			annotations := make(map[string]string, len(desc.Annotations)+1)
+			for k, v := range desc.Annotations {
+				annotations[k] = v
+			}
+			annotations[AnnotationProvenancePulledAt] = pulledAt.UTC().Format(time.RFC3339)
+			manifests[i].Annotations = annotations
+		}
+	}
+
 	algorithms := map[string]struct{}{}
 	dManifests := map[digest.Digest]*exportManifest{}
 	resolvedIndex := map[digest.Digest]digest.Digest{}
@@ -327,7 +382,7 @@ func Export(ctx context.Context, store content.InfoReaderProvider, writer io.Wri
 		}
 	}
 
-	records = append(records, ociIndexRecord(manifests))
+	records = append(records, ociIndexRecord(manifests, indexAnnotations))
 
 	if !eo.skipDockerManifest && len(dManifests) > 0 {
 		tr, err := manifestsRecord(ctx, store, dManifests)
@@ -461,13 +516,14 @@ func ociLayoutFile(version string) tarRecord {
 
 }
 
-func ociIndexRecord(manifests []ocispec.Descriptor) tarRecord {
+func ociIndexRecord(manifests []ocispec.Descriptor, annotations map[string]string) tarRecord {
 	index := ocispec.Index{
 		Versioned: ocispecs.Versioned{
 			SchemaVersion: 2,
 		},
-		MediaType: ocispec.MediaTypeImageIndex,
-		Manifests: manifests,
+		MediaType:   ocispec.MediaTypeImageIndex,
+		Manifests:   manifests,
+		Annotations: annotations,
 	}
 
 	b, err := json.Marshal(index)
@@ -0,0 +1,76 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package archive
+
+import (
+	"fmt"
+	"time"
+)
+
+// Provenance annotations embedded into exported archives by WithProvenance.
+// The registry an image was pulled from is recorded separately, through the
+// containerd.io/distribution.source labels always copied onto the manifest
+// descriptors of the index.
+const (
+	// AnnotationProvenanceVersion is the containerd version of the
+	// exporting daemon, set on the archive index.
+	AnnotationProvenanceVersion = "io.containerd.export.provenance.containerd-version"
+	// AnnotationProvenanceExportedAt is the time the archive was
+	// exported, set on the archive index in RFC 3339 format.
+	AnnotationProvenanceExportedAt = "io.containerd.export.provenance.exported-at"
+	// AnnotationProvenanceLease is the lease the export ran under, if
+	// any, set on the archive index.
+	AnnotationProvenanceLease = "io.containerd.export.provenance.lease"
+	// AnnotationProvenancePulledAt is the time the image was created in
+	// the image store of the exporting daemon, normally the pull time,
+	// set on the manifest descriptors of the archive index in RFC 3339
+	// format.
+	AnnotationProvenancePulledAt = "io.containerd.export.provenance.pulled-at"
+)
+
+// Provenance describes where an exported archive came from, parsed from
+// the annotations embedded by WithProvenance.
+type Provenance struct {
+	// ContainerdVersion is the containerd version of the exporting daemon.
+	ContainerdVersion string
+	// ExportedAt is the time the archive was exported.
+	ExportedAt time.Time
+	// Lease is the lease the export ran under, empty when none was set.
+	Lease string
+}
+
+// ProvenanceFromAnnotations parses the provenance annotations of an
+// archive index. It reports false when the archive carries no provenance
+// and an error when the annotations are present but malformed.
+func ProvenanceFromAnnotations(annotations map[string]string) (Provenance, bool, error) {
+	p := Provenance{
+		ContainerdVersion: annotations[AnnotationProvenanceVersion],
+		Lease:             annotations[AnnotationProvenanceLease],
+	}
+	exportedAt, ok := annotations[AnnotationProvenanceExportedAt]
+	if !ok && p.ContainerdVersion == "" && p.Lease == "" {
+		return Provenance{}, false, nil
+	}
+	if exportedAt != "" {
+		t, err := time.Parse(time.RFC3339, exportedAt)
+		if err != nil {
+			return Provenance{}, true, fmt.Errorf("invalid %s annotation %q: %w", AnnotationProvenanceExportedAt, exportedAt, err)
+		}
+		p.ExportedAt = t
+	}
+	return p, true, nil
+}
@@ -0,0 +1,138 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package encryption
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	encconfig "github.com/containers/ocicrypt/config"
+	encutils "github.com/containers/ocicrypt/utils"
+)
+
+// DecryptConfigFromKeyFiles builds a CryptoConfig for decryption from a
+// list of key files. The type of each key is detected from its contents;
+// private keys (PEM, JWK), x509 certificates and GPG private key rings
+// are supported. Password protected private keys are not supported.
+func DecryptConfigFromKeyFiles(paths ...string) (encconfig.CryptoConfig, error) {
+	var (
+		ccs         []encconfig.CryptoConfig
+		privKeys    [][]byte
+		x509s       [][]byte
+		gpgPrivKeys [][]byte
+	)
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return encconfig.CryptoConfig{}, fmt.Errorf("failed to read decryption key %q: %w", p, err)
+		}
+		switch {
+		case encutils.IsCertificate(data):
+			x509s = append(x509s, data)
+		case encutils.IsGPGPrivateKeyRing(data):
+			gpgPrivKeys = append(gpgPrivKeys, data)
+		default:
+			ok, err := encutils.IsPrivateKey(data, nil)
+			if encutils.IsPasswordError(err) {
+				return encconfig.CryptoConfig{}, fmt.Errorf("password protected decryption key %q is not supported", p)
+			}
+			if !ok {
+				return encconfig.CryptoConfig{}, fmt.Errorf("unrecognized decryption key %q", p)
+			}
+			privKeys = append(privKeys, data)
+		}
+	}
+
+	if len(privKeys) > 0 {
+		cc, err := encconfig.DecryptWithPrivKeys(privKeys, make([][]byte, len(privKeys)))
+		if err != nil {
+			return encconfig.CryptoConfig{}, err
+		}
+		ccs = append(ccs, cc)
+	}
+	if len(x509s) > 0 {
+		cc, err := encconfig.DecryptWithX509s(x509s)
+		if err != nil {
+			return encconfig.CryptoConfig{}, err
+		}
+		ccs = append(ccs, cc)
+	}
+	if len(gpgPrivKeys) > 0 {
+		cc, err := encconfig.DecryptWithGpgPrivKeys(gpgPrivKeys, make([][]byte, len(gpgPrivKeys)))
+		if err != nil {
+			return encconfig.CryptoConfig{}, err
+		}
+		ccs = append(ccs, cc)
+	}
+	return encconfig.CombineCryptoConfigs(ccs), nil
+}
+
+// EncryptConfigFromRecipients builds a CryptoConfig for encryption from a
+// list of recipients of the form <protocol>:<file>, where protocol is
+// either "jwe", taking a public key file, or "pkcs7", taking an x509
+// certificate file.
+func EncryptConfigFromRecipients(recipients ...string) (encconfig.CryptoConfig, error) {
+	var (
+		ccs     []encconfig.CryptoConfig
+		pubKeys [][]byte
+		x509s   [][]byte
+	)
+	for _, r := range recipients {
+		protocol, path, ok := strings.Cut(r, ":")
+		if !ok {
+			return encconfig.CryptoConfig{}, fmt.Errorf("invalid recipient %q, must be of the form <protocol>:<file>", r)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return encconfig.CryptoConfig{}, fmt.Errorf("failed to read recipient key %q: %w", path, err)
+		}
+		switch protocol {
+		case "jwe":
+			if !encutils.IsPublicKey(data) && !encutils.IsCertificate(data) {
+				return encconfig.CryptoConfig{}, fmt.Errorf("recipient key %q is not a public key", path)
+			}
+			pubKeys = append(pubKeys, data)
+		case "pkcs7":
+			if !encutils.IsCertificate(data) {
+				return encconfig.CryptoConfig{}, fmt.Errorf("recipient key %q is not an x509 certificate", path)
+			}
+			x509s = append(x509s, data)
+		default:
+			return encconfig.CryptoConfig{}, fmt.Errorf("unsupported recipient protocol %q", protocol)
+		}
+	}
+
+	if len(pubKeys) > 0 {
+		cc, err := encconfig.EncryptWithJwe(pubKeys)
+		if err != nil {
+			return encconfig.CryptoConfig{}, err
+		}
+		ccs = append(ccs, cc)
+	}
+	if len(x509s) > 0 {
+		cc, err := encconfig.EncryptWithPkcs7(x509s)
+		if err != nil {
+			return encconfig.CryptoConfig{}, err
+		}
+		ccs = append(ccs, cc)
+	}
+	if len(ccs) == 0 {
+		return encconfig.CryptoConfig{}, fmt.Errorf("no recipients specified")
+	}
+	return encconfig.CombineCryptoConfigs(ccs), nil
+}
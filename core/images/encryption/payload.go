@@ -0,0 +1,48 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package encryption
+
+import (
+	"github.com/containerd/typeurl/v2"
+
+	encconfig "github.com/containers/ocicrypt/config"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// PayloadURI is the type URI the decryption payload is marshalled
+// under. It matches the URI established by the external ocicrypt
+// decoder so stream processor based setups keep working unchanged.
+const PayloadURI = "io.containerd.ocicrypt.v1.Payload"
+
+// PayloadToolIDs are the processor ids the decryption payload is
+// attached to in the diff ApplyConfig, one per encrypted media type.
+var PayloadToolIDs = []string{
+	"io.containerd.ocicrypt.decoder.v1.tar",
+	"io.containerd.ocicrypt.decoder.v1.tar.gzip",
+	"io.containerd.ocicrypt.decoder.v1.tar.zstd",
+}
+
+func init() {
+	typeurl.Register(&Payload{}, PayloadURI)
+}
+
+// Payload holds the data the layer decryption needs for decrypting
+// a layer, passed from the client through the diff ApplyConfig
+type Payload struct {
+	DecryptConfig encconfig.DecryptConfig
+	Descriptor    ocispec.Descriptor
+}
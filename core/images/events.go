@@ -0,0 +1,41 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"github.com/containerd/typeurl/v2"
+)
+
+// ImageTagMovedTopic is the topic of ImageTagMoved events.
+const ImageTagMovedTopic = "/images/tag-moved"
+
+func init() {
+	typeurl.Register(&ImageTagMoved{},
+		"github.com/basuotian/containerd/core/images", "ImageTagMoved")
+}
+
+// ImageTagMoved is published in addition to the regular update event when
+// an existing image name is re-pointed to a different target, allowing
+// consumers which cache by name to invalidate precisely.
+type ImageTagMoved struct {
+	// Name of the image which was re-pointed.
+	Name string `json:"name"`
+	// OldDigest is the target digest the name pointed to before the update.
+	OldDigest string `json:"oldDigest"`
+	// NewDigest is the target digest the name points to now.
+	NewDigest string `json:"newDigest"`
+}
@@ -51,6 +51,10 @@ const (
 	MediaTypeContainerd1CheckpointRuntimeName    = "application/vnd.containerd.container.checkpoint.runtime.name"
 	MediaTypeContainerd1CheckpointRuntimeOptions = "application/vnd.containerd.container.checkpoint.runtime.options+proto"
 
+	// MediaTypeContainerd1PodCheckpointSandbox is the sandbox metadata blob
+	// of a whole-pod checkpoint
+	MediaTypeContainerd1PodCheckpointSandbox = "application/vnd.containerd.pod.checkpoint.sandbox.v1+json"
+
 	// MediaTypeDockerSchema1Manifest is the legacy Docker schema1 manifest
 	MediaTypeDockerSchema1Manifest = "application/vnd.docker.distribution.manifest.v1+prettyjws"
 
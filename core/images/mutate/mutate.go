@@ -0,0 +1,417 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package mutate derives a new image from an existing one without an
+// external builder: layers can be appended, config fields patched and
+// the result stored under a new reference. All new blobs are written to
+// the content store and digests are recomputed bottom up.
+package mutate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/platforms"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/images"
+	"github.com/basuotian/containerd/core/images/converter"
+	"github.com/basuotian/containerd/core/leases"
+)
+
+type layerSource struct {
+	reader io.Reader
+	desc   *ocispec.Descriptor
+}
+
+type mutateOpts struct {
+	layers        []layerSource
+	env           []string
+	labels        map[string]string
+	entrypoint    []string
+	hasEntrypoint bool
+	platformMC    platforms.MatchComparer
+}
+
+// Opt is an option for Mutate()
+type Opt func(*mutateOpts) error
+
+// WithAppendLayer appends a layer read as an uncompressed tar stream.
+// The layer is stored uncompressed, so its diff ID equals its digest.
+func WithAppendLayer(r io.Reader) Opt {
+	return func(mopts *mutateOpts) error {
+		mopts.layers = append(mopts.layers, layerSource{reader: r})
+		return nil
+	}
+}
+
+// WithAppendLayerDescriptor appends a layer blob already present in the
+// content store, e.g. one produced by the diff service from a snapshot.
+// The descriptor media type is kept as given.
+func WithAppendLayerDescriptor(desc ocispec.Descriptor) Opt {
+	return func(mopts *mutateOpts) error {
+		mopts.layers = append(mopts.layers, layerSource{desc: &desc})
+		return nil
+	}
+}
+
+// WithEnv patches the config environment. Entries of the form KEY=VALUE
+// replace an existing variable of the same name or are appended; an
+// entry without "=" removes the variable.
+func WithEnv(env []string) Opt {
+	return func(mopts *mutateOpts) error {
+		mopts.env = append(mopts.env, env...)
+		return nil
+	}
+}
+
+// WithLabels merges the given labels into the config labels.
+func WithLabels(labels map[string]string) Opt {
+	return func(mopts *mutateOpts) error {
+		if mopts.labels == nil {
+			mopts.labels = make(map[string]string)
+		}
+		for k, v := range labels {
+			mopts.labels[k] = v
+		}
+		return nil
+	}
+}
+
+// WithEntrypoint replaces the config entrypoint.
+func WithEntrypoint(entrypoint []string) Opt {
+	return func(mopts *mutateOpts) error {
+		mopts.entrypoint = entrypoint
+		mopts.hasEntrypoint = true
+		return nil
+	}
+}
+
+// WithPlatform limits which manifests of an index are mutated.
+// Defaults to all platforms.
+func WithPlatform(p platforms.MatchComparer) Opt {
+	return func(mopts *mutateOpts) error {
+		mopts.platformMC = p
+		return nil
+	}
+}
+
+// Client is implemented by *containerd.Client .
+type Client interface {
+	WithLease(ctx context.Context, opts ...leases.Opt) (context.Context, func(context.Context) error, error)
+	ContentStore() content.Store
+	ImageService() images.Store
+}
+
+// Mutate applies the given mutations to srcRef and creates the result
+// as dstRef. When dstRef equals srcRef the existing record is updated.
+func Mutate(ctx context.Context, client Client, dstRef, srcRef string, opts ...Opt) (*images.Image, error) {
+	ctx, done, err := client.WithLease(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done(ctx)
+
+	cs := client.ContentStore()
+	is := client.ImageService()
+	srcImg, err := is.Get(ctx, srcRef)
+	if err != nil {
+		return nil, err
+	}
+
+	newDesc, err := Apply(ctx, cs, srcImg.Target, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	dstImg := srcImg
+	dstImg.Name = dstRef
+	dstImg.Target = *newDesc
+	var res images.Image
+	if dstRef != srcRef {
+		_ = is.Delete(ctx, dstRef)
+		res, err = is.Create(ctx, dstImg)
+	} else {
+		res, err = is.Update(ctx, dstImg)
+	}
+	return &res, err
+}
+
+// Apply mutates the image rooted at desc in the content store and
+// returns the new root descriptor. Callers are responsible for holding
+// a lease covering the new content.
+func Apply(ctx context.Context, cs content.Store, desc ocispec.Descriptor, opts ...Opt) (*ocispec.Descriptor, error) {
+	var mopts mutateOpts
+	for _, o := range opts {
+		if err := o(&mopts); err != nil {
+			return nil, err
+		}
+	}
+	if mopts.platformMC == nil {
+		mopts.platformMC = platforms.All
+	}
+	if len(mopts.layers) == 0 && len(mopts.env) == 0 && len(mopts.labels) == 0 && !mopts.hasEntrypoint {
+		return nil, fmt.Errorf("no mutations specified: %w", errdefs.ErrInvalidArgument)
+	}
+
+	m := &mutator{opts: mopts}
+	if err := m.materializeLayers(ctx, cs); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case images.IsManifestType(desc.MediaType):
+		return m.mutateManifest(ctx, cs, desc)
+	case images.IsIndexType(desc.MediaType):
+		return m.mutateIndex(ctx, cs, desc)
+	default:
+		return nil, fmt.Errorf("unexpected media type %q: %w", desc.MediaType, errdefs.ErrNotImplemented)
+	}
+}
+
+// appendedLayer is a layer blob already written to the content store,
+// shared between all mutated manifests of an index.
+type appendedLayer struct {
+	mediaType string
+	digest    digest.Digest
+	size      int64
+	diffID    digest.Digest
+}
+
+type mutator struct {
+	opts   mutateOpts
+	layers []appendedLayer
+}
+
+// materializeLayers writes reader based layers to the content store and
+// resolves diff IDs, so that the blobs are created once even when an
+// index contains several matching manifests.
+func (m *mutator) materializeLayers(ctx context.Context, cs content.Store) error {
+	for _, src := range m.opts.layers {
+		if src.desc != nil {
+			if _, err := cs.Info(ctx, src.desc.Digest); err != nil {
+				return fmt.Errorf("layer %s not in content store: %w", src.desc.Digest, err)
+			}
+			diffID, err := images.GetDiffID(ctx, cs, *src.desc)
+			if err != nil {
+				return fmt.Errorf("failed to resolve diff ID of layer %s: %w", src.desc.Digest, err)
+			}
+			m.layers = append(m.layers, appendedLayer{
+				mediaType: src.desc.MediaType,
+				digest:    src.desc.Digest,
+				size:      src.desc.Size,
+				diffID:    diffID,
+			})
+			continue
+		}
+		dgst, size, err := writeLayerBlob(ctx, cs, src.reader)
+		if err != nil {
+			return fmt.Errorf("failed to write layer blob: %w", err)
+		}
+		// The layer is stored as uncompressed tar, so the diff ID is
+		// the blob digest. The media type is chosen per manifest.
+		m.layers = append(m.layers, appendedLayer{
+			digest: dgst,
+			size:   size,
+			diffID: dgst,
+		})
+	}
+	return nil
+}
+
+func (m *mutator) mutateManifest(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (*ocispec.Descriptor, error) {
+	var manifest ocispec.Manifest
+	labels, err := readJSON(ctx, cs, &manifest, desc)
+	if err != nil {
+		return nil, err
+	}
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	var config ocispec.Image
+	if _, err := readJSON(ctx, cs, &config, manifest.Config); err != nil {
+		return nil, err
+	}
+
+	for _, l := range m.layers {
+		mt := l.mediaType
+		if mt == "" {
+			if images.IsDockerType(manifest.MediaType) {
+				mt = images.MediaTypeDockerSchema2Layer
+			} else {
+				mt = ocispec.MediaTypeImageLayer
+			}
+		}
+		labelKey := fmt.Sprintf("containerd.io/gc.ref.content.l.%d", len(manifest.Layers))
+		labels[labelKey] = l.digest.String()
+		manifest.Layers = append(manifest.Layers, ocispec.Descriptor{
+			MediaType: mt,
+			Digest:    l.digest,
+			Size:      l.size,
+		})
+		config.RootFS.DiffIDs = append(config.RootFS.DiffIDs, l.diffID)
+		now := time.Now().UTC()
+		config.History = append(config.History, ocispec.History{
+			Created:   &now,
+			CreatedBy: "containerd mutate",
+		})
+	}
+
+	patchConfig(&config, &m.opts)
+
+	newConfig, err := writeJSON(ctx, cs, &config, manifest.Config, nil)
+	if err != nil {
+		return nil, err
+	}
+	converter.ClearGCLabels(labels, manifest.Config.Digest)
+	labels["containerd.io/gc.ref.content.config"] = newConfig.Digest.String()
+	manifest.Config = *newConfig
+
+	return writeJSON(ctx, cs, &manifest, desc, labels)
+}
+
+func (m *mutator) mutateIndex(ctx context.Context, cs content.Store, desc ocispec.Descriptor) (*ocispec.Descriptor, error) {
+	var index ocispec.Index
+	labels, err := readJSON(ctx, cs, &index, desc)
+	if err != nil {
+		return nil, err
+	}
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	var matched int
+	for i, mani := range index.Manifests {
+		if mani.Platform != nil && !m.opts.platformMC.Match(*mani.Platform) {
+			continue
+		}
+		matched++
+		newMani, err := m.mutateManifest(ctx, cs, mani)
+		if err != nil {
+			return nil, err
+		}
+		converter.ClearGCLabels(labels, mani.Digest)
+		labels[fmt.Sprintf("containerd.io/gc.ref.content.m.%d", i)] = newMani.Digest.String()
+		index.Manifests[i] = *newMani
+	}
+	if matched == 0 {
+		return nil, fmt.Errorf("no manifest matched the requested platform: %w", errdefs.ErrNotFound)
+	}
+
+	return writeJSON(ctx, cs, &index, desc, labels)
+}
+
+// patchConfig applies the env, label and entrypoint mutations.
+func patchConfig(config *ocispec.Image, mopts *mutateOpts) {
+	for _, e := range mopts.env {
+		name, _, ok := strings.Cut(e, "=")
+		idx := -1
+		for i, existing := range config.Config.Env {
+			if k, _, _ := strings.Cut(existing, "="); k == name {
+				idx = i
+				break
+			}
+		}
+		switch {
+		case !ok:
+			// No value unsets the variable.
+			if idx >= 0 {
+				config.Config.Env = append(config.Config.Env[:idx], config.Config.Env[idx+1:]...)
+			}
+		case idx >= 0:
+			config.Config.Env[idx] = e
+		default:
+			config.Config.Env = append(config.Config.Env, e)
+		}
+	}
+	if len(mopts.labels) > 0 {
+		if config.Config.Labels == nil {
+			config.Config.Labels = make(map[string]string)
+		}
+		for k, v := range mopts.labels {
+			config.Config.Labels[k] = v
+		}
+	}
+	if mopts.hasEntrypoint {
+		config.Config.Entrypoint = mopts.entrypoint
+	}
+}
+
+func writeLayerBlob(ctx context.Context, cs content.Store, r io.Reader) (digest.Digest, int64, error) {
+	ref := fmt.Sprintf("mutate-layer-%d", time.Now().UnixNano())
+	w, err := content.OpenWriter(ctx, cs, content.WithRef(ref))
+	if err != nil {
+		return "", 0, err
+	}
+	defer w.Close()
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := w.Commit(ctx, n, ""); err != nil && !errdefs.IsAlreadyExists(err) {
+		return "", 0, err
+	}
+	return w.Digest(), n, nil
+}
+
+func readJSON(ctx context.Context, cs content.Store, x interface{}, desc ocispec.Descriptor) (map[string]string, error) {
+	info, err := cs.Info(ctx, desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	labels := info.Labels
+	b, err := content.ReadBlob(ctx, cs, desc)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, x); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+func writeJSON(ctx context.Context, cs content.Store, x interface{}, oldDesc ocispec.Descriptor, labels map[string]string) (*ocispec.Descriptor, error) {
+	b, err := json.Marshal(x)
+	if err != nil {
+		return nil, err
+	}
+	dgst := digest.SHA256.FromBytes(b)
+	ref := fmt.Sprintf("mutate-write-json-%s", dgst.String())
+	w, err := content.OpenWriter(ctx, cs, content.WithRef(ref))
+	if err != nil {
+		return nil, err
+	}
+	if err := content.Copy(ctx, w, bytes.NewReader(b), int64(len(b)), dgst, content.WithLabels(labels)); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	newDesc := oldDesc
+	newDesc.Size = int64(len(b))
+	newDesc.Digest = dgst
+	return &newDesc, nil
+}
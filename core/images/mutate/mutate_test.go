@@ -0,0 +1,149 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package mutate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log/logtest"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/images/imagetest"
+)
+
+func TestApplyManifest(t *testing.T) {
+	ctx := logtest.WithT(context.Background(), t)
+	cs := imagetest.NewContentStore(ctx, t)
+
+	config := ocispec.Image{
+		Platform: ocispec.Platform{
+			Architecture: "amd64",
+			OS:           "linux",
+		},
+		RootFS: ocispec.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{digest.FromString("base-diff")},
+		},
+		Config: ocispec.ImageConfig{
+			Env:        []string{"PATH=/bin", "FOO=bar"},
+			Entrypoint: []string{"/init"},
+		},
+	}
+	manifest := cs.Manifest(
+		cs.JSONObject(ocispec.MediaTypeImageConfig, config),
+		cs.RandomBlob(ocispec.MediaTypeImageLayer, 64),
+	)
+
+	layerData := []byte("pretend this is a tar stream")
+	newDesc, err := Apply(ctx, cs.Store, manifest.Descriptor,
+		WithAppendLayer(bytes.NewReader(layerData)),
+		WithEnv([]string{"FOO=baz", "NEW=1", "PATH"}),
+		WithLabels(map[string]string{"mutated": "true"}),
+		WithEntrypoint([]string{"/new-init"}),
+	)
+	require.NoError(t, err)
+	assert.NotEqual(t, manifest.Descriptor.Digest, newDesc.Digest)
+	assert.Equal(t, manifest.Descriptor.MediaType, newDesc.MediaType)
+
+	var newManifest ocispec.Manifest
+	readBlobJSON(ctx, t, cs.Store, *newDesc, &newManifest)
+	require.Len(t, newManifest.Layers, 2)
+	appended := newManifest.Layers[1]
+	assert.Equal(t, ocispec.MediaTypeImageLayer, appended.MediaType)
+	assert.Equal(t, digest.FromBytes(layerData), appended.Digest)
+	assert.Equal(t, int64(len(layerData)), appended.Size)
+
+	var newConfig ocispec.Image
+	readBlobJSON(ctx, t, cs.Store, newManifest.Config, &newConfig)
+	require.Len(t, newConfig.RootFS.DiffIDs, 2)
+	// Uncompressed layers have their blob digest as diff ID.
+	assert.Equal(t, appended.Digest, newConfig.RootFS.DiffIDs[1])
+	assert.Equal(t, []string{"FOO=baz", "NEW=1"}, newConfig.Config.Env)
+	assert.Equal(t, map[string]string{"mutated": "true"}, newConfig.Config.Labels)
+	assert.Equal(t, []string{"/new-init"}, newConfig.Config.Entrypoint)
+	require.Len(t, newConfig.History, 1)
+	assert.Equal(t, "containerd mutate", newConfig.History[0].CreatedBy)
+
+	// The new manifest blob must reference its children for GC.
+	info, err := cs.Store.Info(ctx, newDesc.Digest)
+	require.NoError(t, err)
+	assert.Equal(t, newManifest.Config.Digest.String(), info.Labels["containerd.io/gc.ref.content.config"])
+	assert.Equal(t, appended.Digest.String(), info.Labels["containerd.io/gc.ref.content.l.1"])
+}
+
+func TestApplyIndex(t *testing.T) {
+	ctx := logtest.WithT(context.Background(), t)
+	cs := imagetest.NewContentStore(ctx, t)
+
+	newManifest := func(arch string) imagetest.Content {
+		config := ocispec.Image{
+			Platform: ocispec.Platform{
+				Architecture: arch,
+				OS:           "linux",
+			},
+			RootFS: ocispec.RootFS{Type: "layers"},
+		}
+		m := cs.Manifest(
+			cs.JSONObject(ocispec.MediaTypeImageConfig, config),
+			cs.RandomBlob(ocispec.MediaTypeImageLayer, 64),
+		)
+		return imagetest.AddPlatform(m, ocispec.Platform{Architecture: arch, OS: "linux"})
+	}
+	index := cs.Index(newManifest("amd64"), newManifest("arm64"))
+
+	newDesc, err := Apply(ctx, cs.Store, index.Descriptor,
+		WithLabels(map[string]string{"mutated": "true"}),
+	)
+	require.NoError(t, err)
+
+	var newIndex ocispec.Index
+	readBlobJSON(ctx, t, cs.Store, *newDesc, &newIndex)
+	require.Len(t, newIndex.Manifests, 2)
+	for i, m := range newIndex.Manifests {
+		assert.NotEqual(t, index.Children[i].Descriptor.Digest, m.Digest)
+		require.NotNil(t, m.Platform)
+		assert.Equal(t, index.Children[i].Descriptor.Platform.Architecture, m.Platform.Architecture)
+	}
+}
+
+func TestApplyNoMutations(t *testing.T) {
+	ctx := logtest.WithT(context.Background(), t)
+	cs := imagetest.NewContentStore(ctx, t)
+
+	manifest := cs.Manifest(
+		cs.JSONObject(ocispec.MediaTypeImageConfig, ocispec.Image{}),
+		cs.RandomBlob(ocispec.MediaTypeImageLayer, 64),
+	)
+
+	_, err := Apply(ctx, cs.Store, manifest.Descriptor)
+	assert.True(t, errdefs.IsInvalidArgument(err))
+}
+
+func readBlobJSON(ctx context.Context, t *testing.T, cs content.Store, desc ocispec.Descriptor, x interface{}) {
+	t.Helper()
+	b, err := content.ReadBlob(ctx, cs, desc)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, x))
+}
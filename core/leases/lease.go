@@ -102,3 +102,20 @@ func WithExpiration(d time.Duration) Opt {
 		return nil
 	}
 }
+
+// ParentLabel is the label holding the ID of a lease's parent lease.
+const ParentLabel = "containerd.io/lease.parent"
+
+// WithParent makes the lease a child of the lease with the given ID. The
+// parent must exist when the child is created, and deleting the parent
+// cascades to all of its children.
+func WithParent(id string) Opt {
+	return func(l *Lease) error {
+		if l.Labels == nil {
+			l.Labels = map[string]string{}
+		}
+		l.Labels[ParentLabel] = id
+
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package leases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/snapshots"
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// Usage describes the disk space retained by a lease. Only content and
+// snapshot resources are accounted; image and ingest references carry no
+// directly attributable size.
+type Usage struct {
+	// ContentBytes is the total size of content blobs referenced by the
+	// lease.
+	ContentBytes int64
+	// SnapshotBytes is the total size reported by the snapshotters for
+	// the snapshots referenced by the lease.
+	SnapshotBytes int64
+	// SnapshotInodes is the total inode count reported by the
+	// snapshotters for the snapshots referenced by the lease.
+	SnapshotInodes int64
+}
+
+// ResourceUsage computes the disk usage retained by a lease from the
+// resources it references. Resources which no longer exist are skipped,
+// since the lease only prevents garbage collection and does not guarantee
+// the resource was ever created.
+//
+// When exclusive is set, resources which are also referenced by another
+// lease are excluded, leaving an estimate of what would become collectable
+// if this lease alone were released. References held outside of leases,
+// such as by committed images or containers, are not considered.
+func ResourceUsage(ctx context.Context, m Manager, provider content.InfoProvider, snapshotter func(string) snapshots.Snapshotter, lease Lease, exclusive bool) (Usage, error) {
+	var u Usage
+
+	rs, err := m.ListResources(ctx, lease)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	var shared map[Resource]struct{}
+	if exclusive {
+		shared = map[Resource]struct{}{}
+		all, err := m.List(ctx)
+		if err != nil {
+			return Usage{}, err
+		}
+		for _, other := range all {
+			if other.ID == lease.ID {
+				continue
+			}
+			ors, err := m.ListResources(ctx, other)
+			if err != nil {
+				if errdefs.IsNotFound(err) {
+					// The lease expired while iterating.
+					continue
+				}
+				return Usage{}, err
+			}
+			for _, r := range ors {
+				shared[r] = struct{}{}
+			}
+		}
+	}
+
+	for _, r := range rs {
+		if shared != nil {
+			if _, ok := shared[r]; ok {
+				continue
+			}
+		}
+		switch {
+		case r.Type == "content":
+			dgst, err := digest.Parse(r.ID)
+			if err != nil {
+				return Usage{}, fmt.Errorf("invalid content resource id %s: %w", r.ID, err)
+			}
+			info, err := provider.Info(ctx, dgst)
+			if err != nil {
+				if errdefs.IsNotFound(err) {
+					continue
+				}
+				return Usage{}, fmt.Errorf("failed to get info for content %s: %w", r.ID, err)
+			}
+			u.ContentBytes += info.Size
+		case strings.HasPrefix(r.Type, "snapshots/"):
+			sn := snapshotter(strings.TrimPrefix(r.Type, "snapshots/"))
+			if sn == nil {
+				continue
+			}
+			su, err := sn.Usage(ctx, r.ID)
+			if err != nil {
+				if errdefs.IsNotFound(err) {
+					continue
+				}
+				return Usage{}, fmt.Errorf("failed to get usage for snapshot %s: %w", r.ID, err)
+			}
+			u.SnapshotBytes += su.Size
+			u.SnapshotInodes += su.Inodes
+		}
+	}
+
+	return u, nil
+}
@@ -0,0 +1,155 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package leases
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/mount"
+	"github.com/basuotian/containerd/core/snapshots"
+	"github.com/containerd/errdefs"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	usageDigestA = digest.FromString("a")
+	usageDigestB = digest.FromString("b")
+)
+
+func TestResourceUsage(t *testing.T) {
+	var (
+		ctx = context.Background()
+		m   = &fakeUsageManager{
+			resources: map[string][]Resource{
+				"l1": {
+					{ID: usageDigestA.String(), Type: "content"},
+					{ID: usageDigestB.String(), Type: "content"},
+					{ID: digest.FromString("gone").String(), Type: "content"},
+					{ID: "sn-1", Type: "snapshots/overlayfs"},
+					{ID: "img-1", Type: "images"},
+				},
+				"l2": {
+					{ID: usageDigestB.String(), Type: "content"},
+				},
+			},
+		}
+		provider = fakeInfoProvider{
+			usageDigestA: 100,
+			usageDigestB: 20,
+		}
+		sn = func(name string) snapshots.Snapshotter {
+			if name != "overlayfs" {
+				return nil
+			}
+			return fakeUsageSnapshotter{"sn-1": {Size: 7, Inodes: 3}}
+		}
+	)
+
+	u, err := ResourceUsage(ctx, m, provider, sn, Lease{ID: "l1"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, Usage{ContentBytes: 120, SnapshotBytes: 7, SnapshotInodes: 3}, u)
+
+	// usageDigestB is also held by l2, so it does not count as exclusive.
+	u, err = ResourceUsage(ctx, m, provider, sn, Lease{ID: "l1"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, Usage{ContentBytes: 100, SnapshotBytes: 7, SnapshotInodes: 3}, u)
+
+	_, err = ResourceUsage(ctx, m, provider, sn, Lease{ID: "l3"}, false)
+	assert.True(t, errdefs.IsNotFound(err))
+}
+
+type fakeUsageManager struct {
+	Manager
+	resources map[string][]Resource
+}
+
+func (m *fakeUsageManager) List(ctx context.Context, filters ...string) ([]Lease, error) {
+	var ls []Lease
+	for id := range m.resources {
+		ls = append(ls, Lease{ID: id})
+	}
+	return ls, nil
+}
+
+func (m *fakeUsageManager) ListResources(ctx context.Context, lease Lease) ([]Resource, error) {
+	rs, ok := m.resources[lease.ID]
+	if !ok {
+		return nil, fmt.Errorf("lease %q: %w", lease.ID, errdefs.ErrNotFound)
+	}
+	return rs, nil
+}
+
+type fakeInfoProvider map[digest.Digest]int64
+
+func (p fakeInfoProvider) Info(ctx context.Context, dgst digest.Digest) (content.Info, error) {
+	size, ok := p[dgst]
+	if !ok {
+		return content.Info{}, fmt.Errorf("content %v: %w", dgst, errdefs.ErrNotFound)
+	}
+	return content.Info{Digest: dgst, Size: size}, nil
+}
+
+type fakeUsageSnapshotter map[string]snapshots.Usage
+
+func (s fakeUsageSnapshotter) Usage(ctx context.Context, key string) (snapshots.Usage, error) {
+	u, ok := s[key]
+	if !ok {
+		return snapshots.Usage{}, fmt.Errorf("snapshot %q: %w", key, errdefs.ErrNotFound)
+	}
+	return u, nil
+}
+
+func (s fakeUsageSnapshotter) Stat(ctx context.Context, key string) (snapshots.Info, error) {
+	return snapshots.Info{}, errdefs.ErrNotImplemented
+}
+
+func (s fakeUsageSnapshotter) Update(ctx context.Context, info snapshots.Info, fieldpaths ...string) (snapshots.Info, error) {
+	return snapshots.Info{}, errdefs.ErrNotImplemented
+}
+
+func (s fakeUsageSnapshotter) Mounts(ctx context.Context, key string) ([]mount.Mount, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (s fakeUsageSnapshotter) Prepare(ctx context.Context, key, parent string, opts ...snapshots.Opt) ([]mount.Mount, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (s fakeUsageSnapshotter) View(ctx context.Context, key, parent string, opts ...snapshots.Opt) ([]mount.Mount, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (s fakeUsageSnapshotter) Commit(ctx context.Context, name, key string, opts ...snapshots.Opt) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (s fakeUsageSnapshotter) Remove(ctx context.Context, key string) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (s fakeUsageSnapshotter) Walk(ctx context.Context, fn snapshots.WalkFunc, filters ...string) error {
+	return errdefs.ErrNotImplemented
+}
+
+func (s fakeUsageSnapshotter) Close() error {
+	return nil
+}
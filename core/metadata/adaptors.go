@@ -17,6 +17,7 @@
 package metadata
 
 import (
+	"encoding/json"
 	"strings"
 
 	"github.com/basuotian/containerd/core/containers"
@@ -85,6 +86,8 @@ func adaptContainer(o interface{}) filters.Adaptor {
 			return obj.Image, len(obj.Image) > 0
 		case "labels":
 			return checkMap(fieldpath[1:], obj.Labels)
+		case "annotations":
+			return checkMap(fieldpath[1:], obj.Annotations)
 		}
 
 		return "", false
@@ -161,12 +164,47 @@ func adaptSandbox(instance *sandbox.Sandbox) filters.Adaptor {
 			return instance.ID, true
 		case "labels":
 			return checkMap(fieldpath[1:], instance.Labels)
+		case "annotations":
+			return checkMap(fieldpath[1:], sandboxAnnotations(instance))
+		case "runtime":
+			if len(fieldpath) <= 1 {
+				return "", false
+			}
+
+			switch fieldpath[1] {
+			case "name":
+				return instance.Runtime.Name, len(instance.Runtime.Name) > 0
+			default:
+				return "", false
+			}
+		case "sandboxer":
+			return instance.Sandboxer, true
+		case "state":
+			state, ok := instance.Labels[sandbox.StateLabel]
+			return state, ok
 		default:
 			return "", false
 		}
 	})
 }
 
+// sandboxAnnotations extracts the annotations from the runtime spec carried
+// by the sandbox, which is where pod annotations end up for OCI specs.
+func sandboxAnnotations(instance *sandbox.Sandbox) map[string]string {
+	if instance.Spec == nil {
+		return nil
+	}
+
+	var spec struct {
+		Annotations map[string]string `json:"annotations,omitempty"`
+	}
+	if err := json.Unmarshal(instance.Spec.GetValue(), &spec); err != nil {
+		return nil
+	}
+
+	return spec.Annotations
+}
+
 func checkMap(fieldpath []string, m map[string]string) (string, bool) {
 	if len(m) == 0 {
 		return "", false
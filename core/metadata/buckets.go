@@ -174,6 +174,8 @@ var (
 	bucketKeyExpected    = []byte("expected")
 	bucketKeyRef         = []byte("ref")
 	bucketKeyExpireAt    = []byte("expireat")
+	bucketKeyAccessedAt  = []byte("accessedat")
+	bucketKeyAccessCount = []byte("accesscount")
 	bucketKeySandboxID   = []byte("sandboxid")
 	bucketKeySandboxer   = []byte("sandboxer")
 
@@ -201,7 +201,7 @@ func (s *containerStore) Update(ctx context.Context, container containers.Contai
 
 		if len(fieldpaths) == 0 {
 			// only allow updates to these field on full replace.
-			fieldpaths = []string{"labels", "spec", "extensions", "image", "snapshotkey"}
+			fieldpaths = []string{"labels", "annotations", "spec", "extensions", "image", "snapshotkey"}
 
 			// Fields that are immutable must cause an error when no field paths
 			// are provided. This allows these fields to become mutable in the
@@ -228,6 +228,15 @@ func (s *containerStore) Update(ctx context.Context, container containers.Contai
 				continue
 			}
 
+			if strings.HasPrefix(path, "annotations.") {
+				if updated.Annotations == nil {
+					updated.Annotations = map[string]string{}
+				}
+				key := strings.TrimPrefix(path, "annotations.")
+				updated.Annotations[key] = container.Annotations[key]
+				continue
+			}
+
 			if strings.HasPrefix(path, "extensions.") {
 				if updated.Extensions == nil {
 					updated.Extensions = map[string]typeurl.Any{}
@@ -240,6 +249,8 @@ func (s *containerStore) Update(ctx context.Context, container containers.Contai
 			switch path {
 			case "labels":
 				updated.Labels = container.Labels
+			case "annotations":
+				updated.Annotations = container.Annotations
 			case "spec":
 				updated.Spec = container.Spec
 			case "extensions":
@@ -306,6 +317,15 @@ func (s *containerStore) Delete(ctx context.Context, id string) error {
 	})
 }
 
+const (
+	// maxAnnotationSize is the largest allowed size of a single container
+	// annotation key and value pair
+	maxAnnotationSize = 16 * 1024
+	// maxAnnotationsTotalSize caps the accumulated size of all annotations
+	// on a single container
+	maxAnnotationsTotalSize = 256 * 1024
+)
+
 func validateContainer(container *containers.Container) error {
 	if err := identifiers.Validate(container.ID); err != nil {
 		return fmt.Errorf("container.ID: %w", err)
@@ -324,6 +344,20 @@ func validateContainer(container *containers.Container) error {
 		}
 	}
 
+	var annotationsSize int
+	for k, v := range container.Annotations {
+		if k == "" {
+			return fmt.Errorf("container.Annotations keys must not be zero-length: %w", errdefs.ErrInvalidArgument)
+		}
+		if len(k)+len(v) > maxAnnotationSize {
+			return fmt.Errorf("container.Annotations key and value length (%d bytes) greater than maximum size (%d bytes), key: %s: %w", len(k)+len(v), maxAnnotationSize, k, errdefs.ErrInvalidArgument)
+		}
+		annotationsSize += len(k) + len(v)
+	}
+	if annotationsSize > maxAnnotationsTotalSize {
+		return fmt.Errorf("container.Annotations total size (%d bytes) greater than maximum size (%d bytes): %w", annotationsSize, maxAnnotationsTotalSize, errdefs.ErrInvalidArgument)
+	}
+
 	if container.Runtime.Name == "" {
 		return fmt.Errorf("container.Runtime.Name must be set: %w", errdefs.ErrInvalidArgument)
 	}
@@ -346,6 +380,12 @@ func readContainer(container *containers.Container, bkt *bolt.Bucket) error {
 	}
 	container.Labels = labels
 
+	annotations, err := boltutil.ReadAnnotations(bkt)
+	if err != nil {
+		return err
+	}
+	container.Annotations = annotations
+
 	if err := boltutil.ReadTimestamps(bkt, &container.CreatedAt, &container.UpdatedAt); err != nil {
 		return err
 	}
@@ -441,5 +481,9 @@ func writeContainer(bkt *bolt.Bucket, container *containers.Container) error {
 		return err
 	}
 
+	if err := boltutil.WriteAnnotations(bkt, container.Annotations); err != nil {
+		return err
+	}
+
 	return boltutil.WriteLabels(bkt, container.Labels)
 }
@@ -361,6 +361,41 @@ func TestContainersCreateUpdateDelete(t *testing.T) {
 				Image: "test image",
 			},
 		},
+		{
+			name: "UpdateAnnotation",
+			original: containers.Container{
+				Annotations: map[string]string{
+					"foo": "one",
+					"bar": "two",
+				},
+				Spec:        encoded,
+				SnapshotKey: "test-snapshot-key",
+				Snapshotter: "snapshotter",
+				Runtime: containers.RuntimeInfo{
+					Name: "testruntime",
+				},
+				Image: "test image",
+			},
+			input: containers.Container{
+				Annotations: map[string]string{
+					"bar": "baz",
+				},
+			},
+			fieldpaths: []string{"annotations.bar"},
+			expected: containers.Container{
+				Annotations: map[string]string{
+					"foo": "one",
+					"bar": "baz",
+				},
+				Spec:        encoded,
+				SnapshotKey: "test-snapshot-key",
+				Snapshotter: "snapshotter",
+				Runtime: containers.RuntimeInfo{
+					Name: "testruntime",
+				},
+				Image: "test image",
+			},
+		},
 		{
 			name: "DeleteAllLabels",
 			original: containers.Container{
@@ -43,6 +43,7 @@ type contentStore struct {
 	content.Store
 	db     *DB
 	shared bool
+	access *accessTracker
 	l      sync.RWMutex
 }
 
@@ -59,13 +60,64 @@ type contentStore struct {
 // "isolated" mode, the client must prove they have the content by providing
 // the entire blob before the content can be added to another namespace.
 //
-// Since we have only two policies right now, it's simpler using bool to
-// represent it internally.
+// A namespace may override the store wide policy through the
+// labels.LabelSharingPolicy namespace label, which additionally accepts
+// "shared-pull-only" to limit sharing to content offered by shareable
+// namespaces.
 func newContentStore(db *DB, shared bool, cs content.Store) *contentStore {
 	return &contentStore{
 		Store:  cs,
 		db:     db,
 		shared: shared,
+		access: newAccessTracker(db),
+	}
+}
+
+// Sharing policy names enforced by the metadata content store. The store
+// wide policy is set in the bolt plugin configuration and a namespace may
+// override it through the labels.LabelSharingPolicy namespace label.
+const (
+	// SharingPolicyShared resolves writer opens with an expected digest
+	// against the backend store, sharing committed content across
+	// namespaces.
+	SharingPolicyShared = "shared"
+	// SharingPolicySharedPullOnly only resolves expected digests against
+	// content held by namespaces carrying the shareable label, so blobs
+	// belonging to other tenants cannot be discovered by probing the
+	// backend with digests.
+	SharingPolicySharedPullOnly = "shared-pull-only"
+	// SharingPolicyIsolated requires the full content to be provided on
+	// ingest, even when another namespace offers it as shareable, and
+	// keeps the namespace's own content out of cross namespace sharing.
+	SharingPolicyIsolated = "isolated"
+)
+
+// sharingPolicy returns the content sharing policy in effect for the
+// namespace. Without a namespace label the store wide policy applies,
+// where "isolated" retains its historic meaning of still accepting
+// content offered by shareable namespaces.
+func (cs *contentStore) sharingPolicy(tx *bolt.Tx, ns string) string {
+	if p := namespaceSharingPolicy(tx, ns); p != "" {
+		return p
+	}
+	if cs.shared {
+		return SharingPolicyShared
+	}
+	return SharingPolicySharedPullOnly
+}
+
+// namespaceSharingPolicy returns the policy label of the namespace, or an
+// empty string when unset or not a known policy name.
+func namespaceSharingPolicy(tx *bolt.Tx, ns string) string {
+	lbkt := getNamespaceLabelsBucket(tx, ns)
+	if lbkt == nil {
+		return ""
+	}
+	switch p := string(lbkt.Get([]byte(labels.LabelSharingPolicy))); p {
+	case SharingPolicyShared, SharingPolicySharedPullOnly, SharingPolicyIsolated:
+		return p
+	default:
+		return ""
 	}
 }
 
@@ -411,7 +463,9 @@ func (cs *contentStore) Writer(ctx context.Context, opts ...content.WriterOpt) (
 				return nil
 			}
 
-			if cs.shared || isSharedContent(tx, wOpts.Desc.Digest) {
+			policy := cs.sharingPolicy(tx, ns)
+			if policy == SharingPolicyShared ||
+				(policy == SharingPolicySharedPullOnly && isSharedContent(tx, wOpts.Desc.Digest)) {
 				if st, err := cs.Store.Info(ctx, wOpts.Desc.Digest); err == nil {
 					// Ensure the expected size is the same, it is likely
 					// an error if the size is mismatched but the caller
@@ -733,10 +787,19 @@ func (nw *namespacedWriter) Status() (st content.Status, err error) {
 }
 
 func (cs *contentStore) ReaderAt(ctx context.Context, desc ocispec.Descriptor) (content.ReaderAt, error) {
+	ns, err := namespaces.NamespaceRequired(ctx)
+	if err != nil {
+		return nil, err
+	}
 	if err := cs.checkAccess(ctx, desc.Digest); err != nil {
 		return nil, err
 	}
-	return cs.Store.ReaderAt(ctx, desc)
+	ra, err := cs.Store.ReaderAt(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	cs.access.record(ns, desc.Digest)
+	return ra, nil
 }
 
 func (cs *contentStore) checkAccess(ctx context.Context, dgst digest.Digest) error {
@@ -768,6 +831,10 @@ func isSharedContent(tx *bolt.Tx, dgst digest.Digest) bool {
 			continue
 		}
 		if sharedNS := lbkt.Get([]byte(labels.LabelSharedNamespace)); sharedNS != nil && string(sharedNS) == "true" && getBlobBucket(tx, ns, dgst) != nil {
+			if namespaceSharingPolicy(tx, ns) == SharingPolicyIsolated {
+				// The namespace opted out of sharing its content.
+				continue
+			}
 			return true
 		}
 	}
@@ -799,7 +866,7 @@ func readInfo(info *content.Info, bkt *bolt.Bucket) error {
 		info.Size, _ = binary.Varint(v)
 	}
 
-	return nil
+	return readAccessLabels(info, bkt)
 }
 
 func writeInfo(info *content.Info, bkt *bolt.Bucket) error {
@@ -807,7 +874,7 @@ func writeInfo(info *content.Info, bkt *bolt.Bucket) error {
 		return err
 	}
 
-	if err := boltutil.WriteLabels(bkt, info.Labels); err != nil {
+	if err := boltutil.WriteLabels(bkt, stripAccessLabels(info.Labels)); err != nil {
 		return fmt.Errorf("writing labels for info %v: %w", info.Digest, err)
 	}
 
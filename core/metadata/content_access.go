@@ -0,0 +1,189 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"encoding/binary"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/containerd/log"
+	digest "github.com/opencontainers/go-digest"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/pkg/labels"
+)
+
+const (
+	// accessFlushThreshold is the number of distinct blobs with pending
+	// access records above which the records are flushed to the database.
+	accessFlushThreshold = 64
+
+	// accessFlushInterval is the longest pending access records are held
+	// in memory before they are flushed to the database.
+	accessFlushInterval = time.Minute
+)
+
+// blobAccess accumulates in-memory access records for a single blob within
+// a namespace until they are flushed to the blob bucket.
+type blobAccess struct {
+	count      int64
+	accessedAt time.Time
+}
+
+// accessTracker batches last-access timestamps and access counts for blobs
+// so that reads do not pay for a write transaction each. Records are
+// flushed once enough blobs are pending or the flush interval has passed.
+type accessTracker struct {
+	db *DB
+
+	mu        sync.Mutex
+	pending   map[string]map[digest.Digest]*blobAccess
+	blobs     int
+	lastFlush time.Time
+}
+
+func newAccessTracker(db *DB) *accessTracker {
+	return &accessTracker{
+		db:        db,
+		pending:   map[string]map[digest.Digest]*blobAccess{},
+		lastFlush: time.Now(),
+	}
+}
+
+// record notes a read of the given blob and flushes the pending batch in
+// the background when it is due.
+func (t *accessTracker) record(ns string, dgst digest.Digest) {
+	now := time.Now().UTC()
+
+	t.mu.Lock()
+	nsAccess, ok := t.pending[ns]
+	if !ok {
+		nsAccess = map[digest.Digest]*blobAccess{}
+		t.pending[ns] = nsAccess
+	}
+	access, ok := nsAccess[dgst]
+	if !ok {
+		access = &blobAccess{}
+		nsAccess[dgst] = access
+		t.blobs++
+	}
+	access.count++
+	access.accessedAt = now
+
+	if t.blobs < accessFlushThreshold && now.Sub(t.lastFlush) < accessFlushInterval {
+		t.mu.Unlock()
+		return
+	}
+	batch := t.pending
+	t.pending = map[string]map[digest.Digest]*blobAccess{}
+	t.blobs = 0
+	t.lastFlush = now
+	t.mu.Unlock()
+
+	go t.flush(batch)
+}
+
+func (t *accessTracker) flush(batch map[string]map[digest.Digest]*blobAccess) {
+	ctx := context.Background()
+	if err := update(ctx, t.db, func(tx *bolt.Tx) error {
+		for ns, nsAccess := range batch {
+			for dgst, access := range nsAccess {
+				bkt := getBlobBucket(tx, ns, dgst)
+				if bkt == nil {
+					// The blob was removed after the read was recorded.
+					continue
+				}
+				if err := writeAccess(bkt, access); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		log.G(ctx).WithError(err).Error("failed to flush content access records")
+	}
+}
+
+// readAccessLabels surfaces the access records of a blob as labels on its
+// info. The labels are maintained by the store; writeInfo strips them so
+// they cannot be altered through updates.
+func readAccessLabels(info *content.Info, bkt *bolt.Bucket) error {
+	v := bkt.Get(bucketKeyAccessedAt)
+	if len(v) == 0 {
+		return nil
+	}
+	var accessedAt time.Time
+	if err := accessedAt.UnmarshalBinary(v); err != nil {
+		return err
+	}
+
+	if info.Labels == nil {
+		info.Labels = map[string]string{}
+	}
+	info.Labels[labels.LabelBlobAccessedAt] = accessedAt.UTC().Format(time.RFC3339)
+	if count := readAccessCount(bkt); count > 0 {
+		info.Labels[labels.LabelBlobAccessCount] = strconv.FormatInt(count, 10)
+	}
+	return nil
+}
+
+// stripAccessLabels returns infoLabels without the store-maintained access
+// labels.
+func stripAccessLabels(infoLabels map[string]string) map[string]string {
+	_, hasAccessedAt := infoLabels[labels.LabelBlobAccessedAt]
+	_, hasAccessCount := infoLabels[labels.LabelBlobAccessCount]
+	if !hasAccessedAt && !hasAccessCount {
+		return infoLabels
+	}
+	stripped := make(map[string]string, len(infoLabels))
+	for k, v := range infoLabels {
+		if k == labels.LabelBlobAccessedAt || k == labels.LabelBlobAccessCount {
+			continue
+		}
+		stripped[k] = v
+	}
+	return stripped
+}
+
+func readAccessCount(bkt *bolt.Bucket) int64 {
+	v := bkt.Get(bucketKeyAccessCount)
+	if len(v) == 0 {
+		return 0
+	}
+	count, _ := binary.Varint(v)
+	return count
+}
+
+func writeAccess(bkt *bolt.Bucket, access *blobAccess) error {
+	accessedAt, err := access.accessedAt.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err := bkt.Put(bucketKeyAccessedAt, accessedAt); err != nil {
+		return err
+	}
+
+	countEncoded, err := encodeInt(readAccessCount(bkt) + access.count)
+	if err != nil {
+		return err
+	}
+	return bkt.Put(bucketKeyAccessCount, countEncoded)
+}
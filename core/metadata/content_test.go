@@ -94,6 +94,79 @@ func TestContent(t *testing.T) {
 		}...))
 }
 
+func TestContentSharingPolicy(t *testing.T) {
+	ctx, db := testDB(t)
+
+	cs := db.ContentStore()
+
+	blob := []byte("namespace sharing policy blob")
+	dgst := digest.FromBytes(blob)
+	desc := ocispec.Descriptor{Size: int64(len(blob)), Digest: dgst}
+
+	sourceCtx := namespaces.WithNamespace(ctx, "source")
+	if err := content.WriteBlob(sourceCtx, cs, "source-ref", bytes.NewReader(blob), desc); err != nil {
+		t.Fatal(err)
+	}
+
+	setNSLabel := func(t *testing.T, ns, key, value string) {
+		t.Helper()
+		if err := update(ctx, db, func(tx *bolt.Tx) error {
+			return NewNamespaceStore(tx).SetLabel(ctx, ns, key, value)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// shortCircuits reports whether the namespace can commit the blob
+	// by its digest without providing the data.
+	shortCircuits := func(t *testing.T, ns, ref string) bool {
+		t.Helper()
+		nsCtx := namespaces.WithNamespace(ctx, ns)
+		w, err := cs.Writer(nsCtx, content.WithRef(ref), content.WithDescriptor(desc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.Commit(nsCtx, desc.Size, dgst)
+		w.Close()
+		if err != nil && !errdefs.IsFailedPrecondition(err) && !errdefs.IsNotFound(err) && !errdefs.IsInvalidArgument(err) {
+			// Only a commit rejected for missing data is expected.
+			t.Fatal(err)
+		}
+		return err == nil
+	}
+
+	// The store wide policy is shared, an unlabeled namespace may
+	// resolve the digest against the backend.
+	if !shortCircuits(t, "tenant-default", "ref-default") {
+		t.Fatal("default namespace should resolve against shared content")
+	}
+
+	setNSLabel(t, "tenant-isolated", labels.LabelSharingPolicy, SharingPolicyIsolated)
+	if shortCircuits(t, "tenant-isolated", "ref-isolated") {
+		t.Fatal("isolated namespace should not resolve against shared content")
+	}
+
+	// Without a shareable source namespace, shared-pull-only must not
+	// find the digest.
+	setNSLabel(t, "tenant-pull", labels.LabelSharingPolicy, SharingPolicySharedPullOnly)
+	if shortCircuits(t, "tenant-pull", "ref-pull-1") {
+		t.Fatal("shared-pull-only namespace should not resolve against unshared content")
+	}
+
+	setNSLabel(t, "source", labels.LabelSharedNamespace, "true")
+	if !shortCircuits(t, "tenant-pull", "ref-pull-2") {
+		t.Fatal("shared-pull-only namespace should resolve against shareable content")
+	}
+
+	// A source namespace opting out of sharing stops serving its
+	// content even when still labeled shareable.
+	setNSLabel(t, "source", labels.LabelSharingPolicy, SharingPolicyIsolated)
+	setNSLabel(t, "tenant-pull2", labels.LabelSharingPolicy, SharingPolicySharedPullOnly)
+	if shortCircuits(t, "tenant-pull2", "ref-pull-3") {
+		t.Fatal("isolated source namespace should not serve shared content")
+	}
+}
+
 func TestContentLeased(t *testing.T) {
 	ctx, db := testDB(t)
 
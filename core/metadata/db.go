@@ -90,12 +90,22 @@ type DB struct {
 	// sweep phases without preventing read transactions.
 	wlock sync.RWMutex
 
+	// readOnly indicates that the database is in read-only maintenance
+	// mode: writable transactions and garbage collection are rejected with
+	// ErrReadOnlyMode while reads keep working.
+	readOnly atomic.Bool
+
 	// dirty flag indicates that references have been removed which require
 	// a garbage collection to ensure the database is clean. This tracks
 	// the number of dirty operations. This should be updated and read
 	// atomically if outside of wlock.Lock.
 	dirty atomic.Uint32
 
+	// generation counts committed write transactions. It is used by
+	// incremental garbage collection to detect mutations between the
+	// mark and sweep phases.
+	generation atomic.Uint64
+
 	// dirtySS and dirtyCS flags keeps track of datastores which have had
 	// deletions since the last garbage collection. These datastores will
 	// be garbage collected during the next garbage collection. These
@@ -257,10 +267,14 @@ func (m *DB) View(fn func(*bolt.Tx) error) error {
 
 // Update runs a writable transaction on the metadata store.
 func (m *DB) Update(fn func(*bolt.Tx) error) error {
+	if m.readOnly.Load() {
+		return ErrReadOnlyMode
+	}
 	m.wlock.RLock()
 	defer m.wlock.RUnlock()
 	err := m.db.Update(fn)
 	if err == nil {
+		m.generation.Add(1)
 		dirty := m.dirty.Load() > 0
 		for _, fn := range m.mutationCallbacks {
 			fn(dirty)
@@ -359,6 +373,13 @@ type GCStats struct {
 	MetaD     time.Duration
 	ContentD  time.Duration
 	SnapshotD map[string]time.Duration
+
+	// CycleD holds the duration of each sweep transaction of an
+	// incremental collection.
+	CycleD []time.Duration
+
+	// Removed is the number of metadata nodes removed by the collection.
+	Removed int
 }
 
 // Elapsed returns the duration which elapsed during a collection
@@ -368,6 +389,9 @@ func (s GCStats) Elapsed() time.Duration {
 
 // GarbageCollect removes resources (snapshots, contents, ...) that are no longer used.
 func (m *DB) GarbageCollect(ctx context.Context) (gc.Stats, error) {
+	if m.readOnly.Load() {
+		return nil, ErrReadOnlyMode
+	}
 	m.wlock.Lock()
 	t1 := time.Now()
 	c := startGCContext(ctx, m.collectors)
@@ -379,6 +403,7 @@ func (m *DB) GarbageCollect(ctx context.Context) (gc.Stats, error) {
 		return nil, err
 	}
 
+	var stats GCStats
 	events := []namespacedEvent{}
 	if err := m.db.Update(func(tx *bolt.Tx) error {
 		ctx, cancel := context.WithCancel(ctx)
@@ -406,6 +431,9 @@ func (m *DB) GarbageCollect(ctx context.Context) (gc.Stats, error) {
 						event:     event,
 					})
 			}
+			if err == nil {
+				stats.Removed++
+			}
 			return err
 		}
 
@@ -420,8 +448,29 @@ func (m *DB) GarbageCollect(ctx context.Context) (gc.Stats, error) {
 		return nil, err
 	}
 
-	var stats GCStats
-	var wg sync.WaitGroup
+	// Reset dirty. Truly don't need to be atomically stored inside of the wlock
+	// but we're using the atomic wrappers that guarantee atomic access everywhere.
+	m.dirty.Store(0)
+
+	wg := m.scheduleCleanup(ctx, events, &stats)
+
+	stats.MetaD = time.Since(t1)
+	m.wlock.Unlock()
+
+	c.finish(ctx)
+
+	wg.Wait()
+
+	return stats, err
+}
+
+// scheduleCleanup publishes the queued events and schedules cleanup of
+// the underlying content store and snapshotters which had resources
+// removed, recording the cleanup durations in stats. It must be called
+// while holding wlock, the returned WaitGroup is done once the cleanup
+// and publishing complete.
+func (m *DB) scheduleCleanup(ctx context.Context, events []namespacedEvent, stats *GCStats) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
 
 	// Flush events asynchronously after commit
 	wg.Add(1)
@@ -430,10 +479,6 @@ func (m *DB) GarbageCollect(ctx context.Context) (gc.Stats, error) {
 		wg.Done()
 	}()
 
-	// Reset dirty. Truly don't need to be atomically stored inside of the wlock
-	// but we're using the atomic wrappers that guarantee atomic access everywhere.
-	m.dirty.Store(0)
-
 	if len(m.dirtySS) > 0 {
 		var sl sync.Mutex
 		stats.SnapshotD = map[string]time.Duration{}
@@ -466,63 +511,236 @@ func (m *DB) GarbageCollect(ctx context.Context) (gc.Stats, error) {
 		m.dirtyCS = false
 	}
 
-	stats.MetaD = time.Since(t1)
+	return wg
+}
+
+// incrementalMarkRetries bounds how often an incremental collection
+// re-marks after detecting a mutation during the sweep before falling
+// back to collecting in a single transaction.
+const incrementalMarkRetries = 3
+
+// GarbageCollectIncremental removes unused resources as GarbageCollect
+// does, but bounds the time writers are blocked to roughly pauseBudget
+// at a time. Marking runs in a read transaction and the sweep is split
+// across multiple short write transactions, yielding to writers between
+// them. If a writer mutates the database between the mark and the end
+// of the sweep the collection re-marks, falling back to GarbageCollect
+// after incrementalMarkRetries attempts.
+func (m *DB) GarbageCollectIncremental(ctx context.Context, pauseBudget time.Duration) (gc.Stats, error) {
+	if m.readOnly.Load() {
+		return nil, ErrReadOnlyMode
+	}
+	if pauseBudget <= 0 {
+		return m.GarbageCollect(ctx)
+	}
+	var partial GCStats
+	for attempt := 0; attempt < incrementalMarkRetries; attempt++ {
+		stats, retry, err := m.incrementalCollection(ctx, pauseBudget)
+		if err != nil {
+			return nil, err
+		}
+		if !retry {
+			return mergeGCStats(partial, stats), nil
+		}
+		// Sweep transactions of the abandoned attempt that committed
+		// really removed resources; account for them in the final stats.
+		partial = mergeGCStats(partial, stats)
+		log.G(ctx).WithField("attempt", attempt+1).Debug("metadata mutated during incremental garbage collection, re-marking")
+	}
+	stats, err := m.GarbageCollect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if full, ok := stats.(GCStats); ok {
+		return mergeGCStats(partial, full), nil
+	}
+	return stats, nil
+}
+
+// mergeGCStats folds the stats of an earlier, abandoned sweep attempt into
+// those of a later attempt. ContentD and SnapshotD are left to the later
+// attempt, abandoned attempts do not schedule cleanup.
+func mergeGCStats(earlier, later GCStats) GCStats {
+	later.MetaD += earlier.MetaD
+	later.CycleD = append(earlier.CycleD, later.CycleD...)
+	later.Removed += earlier.Removed
+	return later
+}
+
+// incrementalCollection performs a single mark and incremental sweep,
+// returning true when the sweep was abandoned because of a concurrent
+// mutation and must be retried.
+func (m *DB) incrementalCollection(ctx context.Context, pauseBudget time.Duration) (GCStats, bool, error) {
+	c := startGCContext(ctx, m.collectors)
+
+	generation := m.generation.Load()
+	sweepable, err := m.getSweepable(ctx, c)
+	if err != nil {
+		c.cancel(ctx)
+		return GCStats{}, false, err
+	}
+
+	var (
+		stats  GCStats
+		events []namespacedEvent
+		idx    int
+	)
+	for idx < len(sweepable) {
+		m.wlock.Lock()
+		if m.generation.Load() != generation {
+			// A writer committed since marking and may have added
+			// references to nodes in the sweep set.
+			m.wlock.Unlock()
+			c.cancel(ctx)
+			// Deletions made by sweep transactions that already committed
+			// are permanent; publish their events before re-marking so
+			// they are not lost with the abandoned attempt.
+			m.publishEvents(events)
+			return stats, true, nil
+		}
+		ct1 := time.Now()
+		deadline := ct1.Add(pauseBudget)
+		// Remember what earlier transactions committed so a failed or
+		// rolled back transaction does not report its own removals.
+		committedEvents := len(events)
+		committedRemoved := stats.Removed
+		err := m.db.Update(func(tx *bolt.Tx) error {
+			for ; idx < len(sweepable); idx++ {
+				n := sweepable[idx]
+				if n.Type == ResourceSnapshot {
+					if i := strings.IndexRune(n.Key, '/'); i > 0 {
+						m.dirtySS[n.Key[:i]] = struct{}{}
+					}
+					// queue event to publish after successful commit
+				} else if n.Type == ResourceContent || n.Type == ResourceIngest {
+					m.dirtyCS = true
+				}
+
+				event, err := c.remove(ctx, tx, n)
+				if event != nil && err == nil {
+					events = append(events,
+						namespacedEvent{
+							namespace: n.Namespace,
+							event:     event,
+						})
+				}
+				if err != nil {
+					return err
+				}
+				stats.Removed++
+
+				if time.Now().After(deadline) {
+					// Yield to writers and continue in the next
+					// transaction.
+					idx++
+					break
+				}
+			}
+			return nil
+		})
+		d := time.Since(ct1)
+		stats.MetaD += d
+		stats.CycleD = append(stats.CycleD, d)
+		if err != nil {
+			m.wlock.Unlock()
+			c.cancel(ctx)
+			// The failed transaction rolled back, but deletions of the
+			// transactions before it are permanent: publish their events.
+			m.publishEvents(events[:committedEvents])
+			stats.Removed = committedRemoved
+			return stats, false, err
+		}
+		m.wlock.Unlock()
+	}
+
+	m.wlock.Lock()
+	if m.generation.Load() == generation {
+		// No writer committed since marking, the database is fully
+		// collected. Otherwise keep the dirty count so the next
+		// collection picks up deletions made during the sweep.
+		m.dirty.Store(0)
+	}
+	wg := m.scheduleCleanup(ctx, events, &stats)
 	m.wlock.Unlock()
 
 	c.finish(ctx)
 
 	wg.Wait()
 
-	return stats, err
+	return stats, false, nil
 }
 
 // getMarked returns all resources that are used.
 func (m *DB) getMarked(ctx context.Context, c *gcContext) (map[gc.Node]struct{}, error) {
 	var marked map[gc.Node]struct{}
 	if err := m.db.View(func(tx *bolt.Tx) error {
-		ctx, cancel := context.WithCancel(ctx)
-		defer cancel()
+		var err error
+		marked, err = m.mark(ctx, c, tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return marked, nil
+}
 
-		var (
-			nodes []gc.Node
-			wg    sync.WaitGroup
-			roots = make(chan gc.Node)
-		)
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for n := range roots {
-				nodes = append(nodes, n)
-			}
-		}()
-		// Call roots
-		if err := c.scanRoots(ctx, tx, roots); err != nil { // From gc context
-			cancel()
-			return err
+// mark scans the roots and returns all resources reachable from them
+// within the transaction.
+func (m *DB) mark(ctx context.Context, c *gcContext, tx *bolt.Tx) (map[gc.Node]struct{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		nodes []gc.Node
+		wg    sync.WaitGroup
+		roots = make(chan gc.Node)
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := range roots {
+			nodes = append(nodes, n)
 		}
-		close(roots)
-		wg.Wait()
-
-		refs := func(n gc.Node) ([]gc.Node, error) {
-			var sn []gc.Node
-			if err := c.references(ctx, tx, n, func(nn gc.Node) { // From gc context
-				sn = append(sn, nn)
-			}); err != nil {
-				return nil, err
-			}
-			return sn, nil
+	}()
+	// Call roots
+	if err := c.scanRoots(ctx, tx, roots); err != nil { // From gc context
+		cancel()
+		return nil, err
+	}
+	close(roots)
+	wg.Wait()
+
+	refs := func(n gc.Node) ([]gc.Node, error) {
+		var sn []gc.Node
+		if err := c.references(ctx, tx, n, func(nn gc.Node) { // From gc context
+			sn = append(sn, nn)
+		}); err != nil {
+			return nil, err
 		}
+		return sn, nil
+	}
 
-		reachable, err := gc.Tricolor(nodes, refs)
+	return gc.Tricolor(nodes, refs)
+}
+
+// getSweepable marks in a read transaction, so that writers are not
+// blocked, and returns the resources which are not reachable.
+func (m *DB) getSweepable(ctx context.Context, c *gcContext) ([]gc.Node, error) {
+	var sweepable []gc.Node
+	if err := m.db.View(func(tx *bolt.Tx) error {
+		marked, err := m.mark(ctx, c, tx)
 		if err != nil {
 			return err
 		}
-		marked = reachable
-		return nil
+		return c.scanAll(ctx, tx, func(ctx context.Context, n gc.Node) error { // From gc context
+			if _, ok := marked[n]; !ok {
+				sweepable = append(sweepable, n)
+			}
+			return nil
+		})
 	}); err != nil {
 		return nil, err
 	}
-	return marked, nil
+	return sweepable, nil
 }
 
 func (m *DB) cleanupSnapshotter(ctx context.Context, name string) (time.Duration, error) {
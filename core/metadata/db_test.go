@@ -563,6 +563,168 @@ func TestMetadataCollector(t *testing.T) {
 	checkNodesEqual(t, actual, remaining)
 }
 
+func TestReclaimableSpace(t *testing.T) {
+	mdb, cs, sn, cleanup := newStores(t)
+	defer cleanup()
+
+	ctx := logtest.WithT(context.Background(), t)
+
+	objects := []object{
+		newSnapshot("1", "", false, false),
+		newSnapshot("2", "1", false, false),
+		newSnapshot("3", "", false, false),
+		container("1", "2"),
+	}
+	if err := mdb.Update(func(tx *bolt.Tx) error {
+		for _, obj := range objects {
+			if _, err := create(obj, tx, mdb, cs, sn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("creation failed: %+v", err)
+	}
+
+	resp, err := mdb.ReclaimableSpace(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Snapshotters) != 1 {
+		t.Fatalf("expected a single snapshotter entry, got %+v", resp.Snapshotters)
+	}
+	native := resp.Snapshotters[0]
+	if native.Snapshotter != "native" {
+		t.Fatalf("unexpected snapshotter name %q", native.Snapshotter)
+	}
+	if native.Snapshots != 1 {
+		t.Fatalf("expected 1 reclaimable snapshot, got %d", native.Snapshots)
+	}
+
+	// The dry run must not delete anything.
+	nsctx := namespaces.WithNamespace(ctx, "test")
+	for _, key := range []string{"1", "2", "3"} {
+		if _, err := mdb.Snapshotter("native").Stat(nsctx, key); err != nil {
+			t.Fatalf("snapshot %v missing after dry run: %v", key, err)
+		}
+	}
+}
+
+func TestMaintenanceReadOnly(t *testing.T) {
+	mdb, cs, sn, cleanup := newStores(t)
+	defer cleanup()
+
+	ctx := logtest.WithT(context.Background(), t)
+
+	if mdb.IsReadOnly() {
+		t.Fatal("expected maintenance mode to be off by default")
+	}
+	mdb.SetReadOnly(true)
+
+	if err := mdb.Update(func(tx *bolt.Tx) error {
+		_, err := create(newSnapshot("1", "", false, false), tx, mdb, cs, sn)
+		return err
+	}); !errors.Is(err, ErrReadOnlyMode) {
+		t.Fatalf("expected ErrReadOnlyMode from update, got %v", err)
+	}
+	if !errdefs.IsUnavailable(ErrReadOnlyMode) {
+		t.Fatal("expected ErrReadOnlyMode to map to unavailable")
+	}
+	if _, err := mdb.GarbageCollect(ctx); !errors.Is(err, ErrReadOnlyMode) {
+		t.Fatalf("expected ErrReadOnlyMode from garbage collection, got %v", err)
+	}
+	if err := mdb.View(func(tx *bolt.Tx) error { return nil }); err != nil {
+		t.Fatalf("expected reads to keep working, got %v", err)
+	}
+
+	mdb.SetReadOnly(false)
+	if err := mdb.Update(func(tx *bolt.Tx) error {
+		_, err := create(newSnapshot("1", "", false, false), tx, mdb, cs, sn)
+		return err
+	}); err != nil {
+		t.Fatalf("expected update to work again, got %v", err)
+	}
+}
+
+func TestMetadataCollectorIncremental(t *testing.T) {
+	mdb, cs, sn, cleanup := newStores(t)
+	defer cleanup()
+
+	var (
+		ctx = logtest.WithT(context.Background(), t)
+
+		objects = []object{
+			blob(bytesFor(1), true),
+			blob(bytesFor(2), false),
+			blob(bytesFor(3), true),
+			blob(bytesFor(4), false, "containerd.io/gc.root", time.Now().String()),
+			newSnapshot("1", "", false, false),
+			newSnapshot("2", "1", false, false),
+			newSnapshot("3", "2", false, false),
+			newSnapshot("4", "3", false, true),
+			container("1", "3"),
+			image("image-1", digestFor(2)),
+		}
+
+		remaining []gc.Node
+	)
+
+	if err := mdb.Update(func(tx *bolt.Tx) error {
+		for _, obj := range objects {
+			node, err := create(obj, tx, mdb, cs, sn)
+			if err != nil {
+				return err
+			}
+			if node != nil {
+				remaining = append(remaining, *node)
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Creation failed: %+v", err)
+	}
+
+	scan := func() []gc.Node {
+		var nodes []gc.Node
+		if err := mdb.View(func(tx *bolt.Tx) error {
+			cc := startGCContext(ctx, mdb.collectors)
+			return cc.scanAll(ctx, tx, func(ctx context.Context, node gc.Node) error {
+				nodes = append(nodes, node)
+				return nil
+			})
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return nodes
+	}
+
+	before := scan()
+
+	// A tiny pause budget forces a yield after every removal so the
+	// sweep is split across multiple transactions.
+	stats, err := mdb.GarbageCollectIncremental(ctx, time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcStats, ok := stats.(GCStats)
+	if !ok {
+		t.Fatalf("unexpected stats type %T", stats)
+	}
+
+	actual := scan()
+	checkNodesEqual(t, actual, remaining)
+
+	if removed := len(before) - len(actual); gcStats.Removed != removed {
+		t.Errorf("expected %d removed nodes, got %d", removed, gcStats.Removed)
+	}
+	if gcStats.Removed == 0 {
+		t.Error("expected nodes to be removed")
+	}
+	if len(gcStats.CycleD) != gcStats.Removed {
+		t.Errorf("expected %d sweep cycles, got %d", gcStats.Removed, len(gcStats.CycleD))
+	}
+}
+
 func BenchmarkGarbageCollect(b *testing.B) {
 	b.Run("10-Sets", benchmarkTrigger(10))
 	b.Run("100-Sets", benchmarkTrigger(100))
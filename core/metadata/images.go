@@ -21,6 +21,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"path"
 	"strings"
 	"time"
 
@@ -39,12 +40,39 @@ import (
 )
 
 type imageStore struct {
-	db *DB
+	db            *DB
+	immutableTags []string
+}
+
+// ImageStoreOpt is an option to configure the image store
+type ImageStoreOpt func(*imageStore)
+
+// WithImmutableTags marks image names matching any of the given patterns
+// as immutable. Updates re-pointing a matching name to a different target
+// fail with ErrAlreadyExists. Patterns use the path.Match syntax and are
+// matched against the full image name.
+func WithImmutableTags(patterns ...string) ImageStoreOpt {
+	return func(s *imageStore) {
+		s.immutableTags = append(s.immutableTags, patterns...)
+	}
 }
 
 // NewImageStore returns a store backed by a bolt DB
-func NewImageStore(db *DB) images.Store {
-	return &imageStore{db: db}
+func NewImageStore(db *DB, opts ...ImageStoreOpt) images.Store {
+	s := &imageStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *imageStore) isImmutable(name string) bool {
+	for _, pattern := range s.immutableTags {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *imageStore) Get(ctx context.Context, name string) (images.Image, error) {
@@ -187,7 +215,10 @@ func (s *imageStore) Update(ctx context.Context, image images.Image, fieldpaths
 		return images.Image{}, fmt.Errorf("image name is required for update: %w", errdefs.ErrInvalidArgument)
 	}
 
-	var updated images.Image
+	var (
+		updated   images.Image
+		oldDigest digest.Digest
+	)
 
 	if err := update(ctx, s.db, func(tx *bolt.Tx) error {
 		bkt, err := createImagesBucket(tx, namespace)
@@ -204,6 +235,7 @@ func (s *imageStore) Update(ctx context.Context, image images.Image, fieldpaths
 			return fmt.Errorf("image %q: %w", image.Name, err)
 		}
 		createdat := updated.CreatedAt
+		oldDigest = updated.Target.Digest
 		updated.Name = image.Name
 
 		if len(fieldpaths) > 0 {
@@ -250,6 +282,10 @@ func (s *imageStore) Update(ctx context.Context, image images.Image, fieldpaths
 			return err
 		}
 
+		if updated.Target.Digest != oldDigest && s.isImmutable(updated.Name) {
+			return fmt.Errorf("cannot re-point immutable tag %q: %w", updated.Name, errdefs.ErrAlreadyExists)
+		}
+
 		// Collectible label may be added, if so add to lease
 		if err := addImageLease(ctx, tx, updated.Name, updated.Labels); err != nil {
 			return err
@@ -267,6 +303,15 @@ func (s *imageStore) Update(ctx context.Context, image images.Image, fieldpaths
 	}
 
 	if publisher := s.db.Publisher(ctx); publisher != nil {
+		if updated.Target.Digest != oldDigest {
+			if err := publisher.Publish(ctx, images.ImageTagMovedTopic, &images.ImageTagMoved{
+				Name:      updated.Name,
+				OldDigest: oldDigest.String(),
+				NewDigest: updated.Target.Digest.String(),
+			}); err != nil {
+				return images.Image{}, err
+			}
+		}
 		if err := publisher.Publish(ctx, "/images/update", &eventstypes.ImageUpdate{
 			Name:   updated.Name,
 			Labels: updated.Labels,
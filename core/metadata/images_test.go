@@ -607,6 +607,44 @@ func TestImagesCreateUpdateDelete(t *testing.T) {
 	}
 }
 
+func TestImagesImmutableTags(t *testing.T) {
+	ctx, db := testEnv(t)
+	store := NewImageStore(NewDB(db, nil, nil), WithImmutableTags("registry.test/locked:*"))
+
+	base := imageBase()
+	base.Name = "registry.test/locked:v1"
+	base.Target.Digest = digest.FromString("a")
+	if _, err := store.Create(ctx, base); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-pointing the tag to a different target must fail.
+	moved := base
+	moved.Target.Digest = digest.FromString("b")
+	if _, err := store.Update(ctx, moved); !errdefs.IsAlreadyExists(err) {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+
+	// Updating labels without changing the target is still allowed.
+	relabeled := base
+	relabeled.Labels = map[string]string{"foo": "baz"}
+	if _, err := store.Update(ctx, relabeled, "labels"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Names not matching a pattern can be re-pointed.
+	other := imageBase()
+	other.Name = "registry.test/free:v1"
+	other.Target.Digest = digest.FromString("a")
+	if _, err := store.Create(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	other.Target.Digest = digest.FromString("b")
+	if _, err := store.Update(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func imageBase() images.Image {
 	return images.Image{
 		Labels: map[string]string{
@@ -70,6 +70,15 @@ func (lm *leaseManager) Create(ctx context.Context, opts ...leases.Opt) (leases.
 			return err
 		}
 
+		if parent, ok := l.Labels[leases.ParentLabel]; ok {
+			if parent == l.ID {
+				return fmt.Errorf("lease %q cannot be its own parent: %w", l.ID, errdefs.ErrInvalidArgument)
+			}
+			if topbkt.Bucket([]byte(parent)) == nil {
+				return fmt.Errorf("parent lease %q: %w", parent, errdefs.ErrNotFound)
+			}
+		}
+
 		txbkt, err := topbkt.CreateBucket([]byte(l.ID))
 		if err != nil {
 			if err == errbolt.ErrBucketExists {
@@ -120,12 +129,57 @@ func (lm *leaseManager) Delete(ctx context.Context, lease leases.Lease, _ ...lea
 			return err
 		}
 
+		// cascade the delete to child leases, level by level
+		parents := []string{lease.ID}
+		for len(parents) > 0 {
+			var children []string
+			for _, parent := range parents {
+				c, err := childLeases(topbkt, parent)
+				if err != nil {
+					return err
+				}
+				children = append(children, c...)
+			}
+			for _, child := range children {
+				if err := topbkt.DeleteBucket([]byte(child)); err != nil && err != errbolt.ErrBucketNotFound {
+					return err
+				}
+			}
+			parents = children
+		}
+
 		lm.db.dirty.Add(1)
 
 		return nil
 	})
 }
 
+// childLeases returns the IDs of all leases whose parent label points at the
+// given lease ID.
+func childLeases(topbkt *bolt.Bucket, parent string) ([]string, error) {
+	var children []string
+	if err := topbkt.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		lbkt := topbkt.Bucket(k)
+		if lbkt == nil {
+			return nil
+		}
+		labels, err := boltutil.ReadLabels(lbkt)
+		if err != nil {
+			return err
+		}
+		if labels[leases.ParentLabel] == parent {
+			children = append(children, string(k))
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
 // List lists all active leases
 func (lm *leaseManager) List(ctx context.Context, fs ...string) ([]leases.Lease, error) {
 	namespace, err := namespaces.NamespaceRequired(ctx)
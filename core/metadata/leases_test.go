@@ -106,6 +106,44 @@ func TestLeases(t *testing.T) {
 	}
 }
 
+func TestLeasesHierarchy(t *testing.T) {
+	ctx, db := testEnv(t)
+
+	lm := NewLeaseManager(NewDB(db, nil, nil))
+
+	if _, err := lm.Create(ctx, leases.WithID("orphan"), leases.WithParent("missing")); !errors.Is(err, errdefs.ErrNotFound) {
+		t.Fatalf("expected not found error for missing parent, got %v", err)
+	}
+	if _, err := lm.Create(ctx, leases.WithID("self"), leases.WithParent("self")); !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Fatalf("expected invalid argument error for self parent, got %v", err)
+	}
+
+	if _, err := lm.Create(ctx, leases.WithID("parent")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lm.Create(ctx, leases.WithID("child"), leases.WithParent("parent")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lm.Create(ctx, leases.WithID("grandchild"), leases.WithParent("child")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lm.Create(ctx, leases.WithID("unrelated")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lm.Delete(ctx, leases.Lease{ID: "parent"}); err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := lm.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID != "unrelated" {
+		t.Fatalf("expected only the unrelated lease to remain, got %v", listed)
+	}
+}
+
 func TestLeasesList(t *testing.T) {
 	ctx, db := testEnv(t)
 
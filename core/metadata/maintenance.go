@@ -0,0 +1,69 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package metadata
+
+import (
+	"fmt"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/typeurl/v2"
+)
+
+func init() {
+	typeurl.Register(&MaintenanceRequest{}, "containerd.metadata.MaintenanceRequest")
+	typeurl.Register(&MaintenanceResponse{}, "containerd.metadata.MaintenanceResponse")
+}
+
+// ErrReadOnlyMode is returned by mutating operations while the metadata
+// database is in read-only maintenance mode.
+var ErrReadOnlyMode = fmt.Errorf("metadata database is in read-only maintenance mode: %w", errdefs.ErrUnavailable)
+
+// MaintenanceRequest may be sent to the metadata plugin through the
+// introspection service to query or toggle read-only maintenance mode. While
+// maintenance mode is on, writable metadata transactions and garbage
+// collection fail with ErrReadOnlyMode; reads keep working so the database
+// can be backed up or inspected on a live node.
+type MaintenanceRequest struct {
+	// ReadOnly, when set, enables or disables maintenance mode. When nil
+	// the current state is only reported.
+	ReadOnly *bool `json:"readOnly,omitempty"`
+}
+
+// MaintenanceResponse reports the maintenance mode state after a
+// MaintenanceRequest was handled.
+type MaintenanceResponse struct {
+	ReadOnly bool `json:"readOnly"`
+}
+
+// SetReadOnly toggles read-only maintenance mode. The switch only affects
+// transactions started after it, writable transactions already running are
+// left to finish.
+func (m *DB) SetReadOnly(readOnly bool) {
+	m.readOnly.Store(readOnly)
+}
+
+// IsReadOnly reports whether the database is in read-only maintenance mode.
+func (m *DB) IsReadOnly() bool {
+	return m.readOnly.Load()
+}
+
+func (m *DB) maintenance(req *MaintenanceRequest) *MaintenanceResponse {
+	if req.ReadOnly != nil {
+		m.SetReadOnly(*req.ReadOnly)
+	}
+	return &MaintenanceResponse{ReadOnly: m.IsReadOnly()}
+}
@@ -0,0 +1,132 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+
+	"github.com/basuotian/containerd/pkg/namespaces"
+)
+
+func init() {
+	typeurl.Register(&ReclaimableSpaceRequest{}, "containerd.metadata.ReclaimableSpaceRequest")
+	typeurl.Register(&ReclaimableSpaceResponse{}, "containerd.metadata.ReclaimableSpaceResponse")
+}
+
+// ReclaimableSpaceRequest may be sent to the metadata plugin through the
+// introspection service to compute, without deleting anything, how much
+// snapshot space the next garbage collection would free.
+type ReclaimableSpaceRequest struct{}
+
+// SnapshotterReclaimable reports the snapshots of one snapshotter which are
+// unreachable from any image, container or lease, summed across namespaces.
+type SnapshotterReclaimable struct {
+	// Snapshotter is the snapshotter name.
+	Snapshotter string `json:"snapshotter"`
+
+	// Snapshots is the number of unreachable snapshots.
+	Snapshots int `json:"snapshots"`
+
+	// Size is the space in bytes the unreachable snapshots account for.
+	Size int64 `json:"size"`
+
+	// Inodes is the inodes the unreachable snapshots account for, when the
+	// snapshotter reports them.
+	Inodes int64 `json:"inodes,omitempty"`
+}
+
+// ReclaimableSpaceResponse is the answer to a ReclaimableSpaceRequest, with
+// one entry per registered snapshotter sorted by name.
+type ReclaimableSpaceResponse struct {
+	Snapshotters []SnapshotterReclaimable `json:"snapshotters"`
+}
+
+// ReclaimableSpace marks the metadata in a read transaction and reports, per
+// snapshotter, the usage of the snapshots a collection would sweep. Nothing
+// is deleted and writers are not blocked.
+func (m *DB) ReclaimableSpace(ctx context.Context) (*ReclaimableSpaceResponse, error) {
+	c := startGCContext(ctx, m.collectors)
+	sweepable, err := m.getSweepable(ctx, c)
+	if err != nil {
+		c.cancel(ctx)
+		return nil, err
+	}
+	c.finish(ctx)
+
+	byName := make(map[string]*SnapshotterReclaimable)
+	for name := range m.Snapshotters() {
+		byName[name] = &SnapshotterReclaimable{Snapshotter: name}
+	}
+	for _, n := range sweepable {
+		if n.Type != ResourceSnapshot {
+			continue
+		}
+		name, key, ok := strings.Cut(n.Key, "/")
+		if !ok {
+			continue
+		}
+		r := byName[name]
+		if r == nil {
+			r = &SnapshotterReclaimable{Snapshotter: name}
+			byName[name] = r
+		}
+		r.Snapshots++
+
+		sn := m.Snapshotter(name)
+		if sn == nil {
+			continue
+		}
+		usage, err := sn.Usage(namespaces.WithNamespace(ctx, n.Namespace), key)
+		if err != nil {
+			if !errdefs.IsNotFound(err) {
+				log.G(ctx).WithError(err).WithField("key", n.Key).Warn("failed to get snapshot usage")
+			}
+			continue
+		}
+		r.Size += usage.Size
+		r.Inodes += usage.Inodes
+	}
+
+	resp := &ReclaimableSpaceResponse{
+		Snapshotters: make([]SnapshotterReclaimable, 0, len(byName)),
+	}
+	for _, r := range byName {
+		resp.Snapshotters = append(resp.Snapshotters, *r)
+	}
+	sort.Slice(resp.Snapshotters, func(i, j int) bool {
+		return resp.Snapshotters[i].Snapshotter < resp.Snapshotters[j].Snapshotter
+	})
+	return resp, nil
+}
+
+// PluginInfo answers introspection requests against the metadata plugin.
+func (m *DB) PluginInfo(ctx context.Context, request interface{}) (interface{}, error) {
+	switch r := request.(type) {
+	case *ReclaimableSpaceRequest:
+		return m.ReclaimableSpace(ctx)
+	case *MaintenanceRequest:
+		return m.maintenance(r), nil
+	}
+	return nil, fmt.Errorf("unknown request type %T: %w", request, errdefs.ErrNotImplemented)
+}
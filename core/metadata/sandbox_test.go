@@ -231,6 +231,64 @@ func TestSandboxListWithFilter(t *testing.T) {
 	assertEqualInstances(t, out[0], in[0])
 }
 
+func TestSandboxListWithFieldFilters(t *testing.T) {
+	ctx, db := testDB(t)
+	store := NewSandboxStore(db)
+
+	in := []api.Sandbox{
+		{
+			ID:     "1",
+			Labels: map[string]string{api.StateLabel: api.StateRunning},
+			Spec: &types.Any{
+				TypeUrl: "types.containerd.io/opencontainers/runtime-spec/1/Spec",
+				Value:   []byte(`{"annotations":{"io.kubernetes.cri.sandbox-name":"pod1"}}`),
+			},
+			Runtime:   api.RuntimeOpts{Name: "io.containerd.runc.v2"},
+			Sandboxer: "podsandbox",
+		},
+		{
+			ID:        "2",
+			Labels:    map[string]string{api.StateLabel: api.StateStopped},
+			Spec:      &types.Any{TypeUrl: "2", Value: []byte{3, 2, 1}},
+			Runtime:   api.RuntimeOpts{Name: "io.containerd.kata.v2"},
+			Sandboxer: "shim",
+		},
+	}
+
+	for _, inst := range in {
+		_, err := store.Create(ctx, inst)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		filter   string
+		expected []string
+	}{
+		{filter: "runtime.name==io.containerd.runc.v2", expected: []string{"1"}},
+		{filter: "sandboxer==shim", expected: []string{"2"}},
+		{filter: "state==running", expected: []string{"1"}},
+		{filter: "state==stopped", expected: []string{"2"}},
+		{filter: `annotations."io.kubernetes.cri.sandbox-name"==pod1`, expected: []string{"1"}},
+		{filter: "state==created", expected: nil},
+	} {
+		out, err := store.List(ctx, test.filter)
+		if err != nil {
+			t.Fatalf("filter %q: %v", test.filter, err)
+		}
+
+		var ids []string
+		for _, inst := range out {
+			ids = append(ids, inst.ID)
+		}
+
+		if !cmp.Equal(test.expected, ids) {
+			t.Fatalf("filter %q: expected %v, got %v", test.filter, test.expected, ids)
+		}
+	}
+}
+
 func TestSandboxDelete(t *testing.T) {
 	ctx, db := testDB(t)
 
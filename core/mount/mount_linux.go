@@ -350,6 +350,19 @@ func hasDirectIO(opts []string) (bool, []string) {
 	return false, opts
 }
 
+// OverlayOptionsTooLarge reports whether the given overlay mount options
+// would exceed the one page limit of the mount argument buffer, even after
+// the lowerdirs have been compacted to be relative to their common parent
+// directory. Callers can use this to decide whether a lower directory
+// chain needs to be shortened before mounting it.
+func OverlayOptionsTooLarge(options []string) bool {
+	if optionsSize(options) < pagesize-512 {
+		return false
+	}
+	_, options = compactLowerdirOption(options)
+	return optionsSize(options) >= pagesize-512
+}
+
 // compactLowerdirOption updates overlay lowdir option and returns the common
 // dir among all the lowdirs.
 func compactLowerdirOption(opts []string) (string, []string) {
@@ -23,6 +23,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"syscall"
 	"testing"
 
@@ -111,6 +112,31 @@ func TestCompactLowerdirOption(t *testing.T) {
 	}
 }
 
+func TestOverlayOptionsTooLarge(t *testing.T) {
+	short := []string{"workdir=/w", "upperdir=/u", "lowerdir=/snapshots/1/fs:/snapshots/2/fs"}
+	if OverlayOptionsTooLarge(short) {
+		t.Fatalf("expected options (%v) to fit", short)
+	}
+
+	// long lowerdirs sharing a common parent compact well enough to fit
+	compactable := make([]string, 0, pagesize/16)
+	for i := 0; len("lowerdir="+strings.Join(compactable, ":")) < pagesize; i++ {
+		compactable = append(compactable, fmt.Sprintf("/snapshots/%d/fs", i))
+	}
+	if OverlayOptionsTooLarge([]string{"lowerdir=" + strings.Join(compactable, ":")}) {
+		t.Fatal("expected compactable lowerdirs to fit")
+	}
+
+	// long lowerdirs without a common parent do not
+	distinct := make([]string, 0, pagesize/16)
+	for i := 0; len("lowerdir="+strings.Join(distinct, ":")) < 2*pagesize; i++ {
+		distinct = append(distinct, fmt.Sprintf("/snapshots-%d/fs", i))
+	}
+	if !OverlayOptionsTooLarge([]string{"lowerdir=" + strings.Join(distinct, ":")}) {
+		t.Fatal("expected distinct lowerdirs not to fit")
+	}
+}
+
 func TestFUSEHelper(t *testing.T) {
 	testutil.RequiresRoot(t)
 	const fuseoverlayfsBinary = "fuse-overlayfs"
@@ -0,0 +1,112 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remotes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/pkg/reference"
+)
+
+// AirgapViolationError is returned when airgap mode blocks access to a
+// remote host which is not on the allowlist. It makes blocked fetches
+// fail explicitly instead of timing out against unreachable hosts.
+type AirgapViolationError struct {
+	// Host is the registry host access was blocked to.
+	Host string
+}
+
+func (e *AirgapViolationError) Error() string {
+	return fmt.Sprintf("airgap mode blocks access to host %q", e.Host)
+}
+
+func (e *AirgapViolationError) Unwrap() error {
+	return errdefs.ErrFailedPrecondition
+}
+
+// AirgapAllowed reports whether the host is on the allowlist. Entries
+// match the host exactly, including a port when the host carries one.
+func AirgapAllowed(host string, allowed []string) bool {
+	for _, a := range allowed {
+		if a == host {
+			return true
+		}
+	}
+	return false
+}
+
+// AirgapResolver wraps a resolver, refusing any reference whose host is
+// not on the allowlist with an AirgapViolationError. An empty allowlist
+// refuses all references.
+func AirgapResolver(base Resolver, allowed ...string) Resolver {
+	return &airgapResolver{base: base, allowed: allowed}
+}
+
+type airgapResolver struct {
+	base    Resolver
+	allowed []string
+}
+
+func (r *airgapResolver) check(ref string) error {
+	refspec, err := reference.Parse(ref)
+	if err != nil {
+		return err
+	}
+	if host := refspec.Hostname(); !AirgapAllowed(host, r.allowed) {
+		return &AirgapViolationError{Host: host}
+	}
+	return nil
+}
+
+func (r *airgapResolver) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	if err := r.check(ref); err != nil {
+		return "", ocispec.Descriptor{}, err
+	}
+	return r.base.Resolve(ctx, ref)
+}
+
+func (r *airgapResolver) Fetcher(ctx context.Context, ref string) (Fetcher, error) {
+	if err := r.check(ref); err != nil {
+		return nil, err
+	}
+	return r.base.Fetcher(ctx, ref)
+}
+
+func (r *airgapResolver) Pusher(ctx context.Context, ref string) (Pusher, error) {
+	if err := r.check(ref); err != nil {
+		return nil, err
+	}
+	return r.base.Pusher(ctx, ref)
+}
+
+// Referrers delegates to the wrapped resolver when it can list referrers,
+// subject to the same allowlist as the other operations.
+func (r *airgapResolver) Referrers(ctx context.Context, ref string, dgst digest.Digest, artifactType string) (*ocispec.Index, error) {
+	lister, ok := r.base.(ReferrersLister)
+	if !ok {
+		return nil, fmt.Errorf("resolver does not support listing referrers: %w", errdefs.ErrNotImplemented)
+	}
+	if err := r.check(ref); err != nil {
+		return nil, err
+	}
+	return lister.Referrers(ctx, ref, dgst, artifactType)
+}
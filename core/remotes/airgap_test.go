@@ -0,0 +1,74 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remotes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/containerd/errdefs"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+type allowAllResolver struct{}
+
+func (allowAllResolver) Resolve(ctx context.Context, ref string) (string, ocispec.Descriptor, error) {
+	return ref, ocispec.Descriptor{}, nil
+}
+
+func (allowAllResolver) Fetcher(ctx context.Context, ref string) (Fetcher, error) {
+	return nil, nil
+}
+
+func (allowAllResolver) Pusher(ctx context.Context, ref string) (Pusher, error) {
+	return nil, nil
+}
+
+func TestAirgapResolver(t *testing.T) {
+	ctx := context.Background()
+	resolver := AirgapResolver(allowAllResolver{}, "allowed.example.com")
+
+	if _, _, err := resolver.Resolve(ctx, "allowed.example.com/img:latest"); err != nil {
+		t.Fatalf("expected allowlisted host to resolve, got %v", err)
+	}
+
+	_, _, err := resolver.Resolve(ctx, "blocked.example.com/img:latest")
+	var violation *AirgapViolationError
+	if !errors.As(err, &violation) {
+		t.Fatalf("expected AirgapViolationError, got %v", err)
+	}
+	if violation.Host != "blocked.example.com" {
+		t.Fatalf("unexpected host %q", violation.Host)
+	}
+	if !errors.Is(err, errdefs.ErrFailedPrecondition) {
+		t.Fatalf("expected failed precondition cause, got %v", err)
+	}
+
+	if _, err := resolver.Fetcher(ctx, "blocked.example.com/img:latest"); !errors.As(err, &violation) {
+		t.Fatalf("expected AirgapViolationError from Fetcher, got %v", err)
+	}
+	if _, err := resolver.Pusher(ctx, "blocked.example.com/img:latest"); !errors.As(err, &violation) {
+		t.Fatalf("expected AirgapViolationError from Pusher, got %v", err)
+	}
+
+	// An empty allowlist refuses everything.
+	resolver = AirgapResolver(allowAllResolver{})
+	if _, _, err := resolver.Resolve(ctx, "allowed.example.com/img:latest"); !errors.As(err, &violation) {
+		t.Fatalf("expected AirgapViolationError, got %v", err)
+	}
+}
@@ -56,6 +56,10 @@ type hostConfig struct {
 
 	header http.Header
 
+	// srvRecord is a DNS SRV record used to discover the actual registry
+	// endpoints for this host at request time.
+	srvRecord string
+
 	// TODO: Add credential configuration (domain alias, username)
 }
 
@@ -226,6 +230,13 @@ func ConfigureHosts(ctx context.Context, options HostOptions) docker.RegistryHos
 				}
 			}
 
+			if host.srvRecord != "" {
+				c := *rhosts[i].Client
+				c.Transport = docker.NewSRVDiscoveryTransport(c.Transport, host.srvRecord)
+				rhosts[i].Client = &c
+				log.G(ctx).WithField("host", host.host).WithField("srv", host.srvRecord).Debug("using SRV discovery for registry host")
+			}
+
 			rhosts[i].Scheme = host.scheme
 			rhosts[i].Host = host.host
 			rhosts[i].Path = host.path
@@ -302,6 +313,17 @@ func HostDirFromRoot(root string) func(string) (string, error) {
 	}
 }
 
+// srvBaseName strips the leading underscore-prefixed service and protocol
+// labels from an SRV record name, leaving the domain requests are addressed
+// to before discovery rewrites them.
+func srvBaseName(record string) string {
+	labels := strings.Split(record, ".")
+	for len(labels) > 0 && strings.HasPrefix(labels[0], "_") {
+		labels = labels[1:]
+	}
+	return strings.Join(labels, ".")
+}
+
 // hostDirectory converts ":port" to "_port_" in directory names
 func hostDirectory(host string) string {
 	idx := strings.LastIndex(host, ":")
@@ -373,6 +395,14 @@ type hostFileConfig struct {
 	// a connect to complete.
 	DialTimeout string `toml:"dial_timeout"`
 
+	// SRV is the name of a DNS SRV record (e.g. "_oci._tcp.registry.internal")
+	// used to discover the registry endpoints for this host. Requests are
+	// balanced across the resolved targets by priority and weight, failing
+	// targets are put in a cooldown and the record is re-resolved
+	// periodically. A host name starting with "_" is treated as an SRV
+	// record directly.
+	SRV string `toml:"srv"`
+
 	// TODO: Credentials: helper? name? username? alternate domain? token?
 }
 
@@ -447,6 +477,20 @@ func parseHostConfig(server string, baseDir string, config hostFileConfig) (host
 		result.path = u.Path
 	}
 
+	if config.SRV != "" {
+		result.srvRecord = config.SRV
+	} else if strings.HasPrefix(result.host, "_") {
+		// A host like "_oci._tcp.registry.internal" is an SRV record
+		// itself, address requests to the base domain and discover the
+		// real endpoints through the record.
+		result.srvRecord = result.host
+		base := srvBaseName(result.host)
+		if base == "" {
+			return hostConfig{}, fmt.Errorf("invalid SRV host %q: no domain after service labels", result.host)
+		}
+		result.host = base
+	}
+
 	result.skipVerify = config.SkipVerify
 
 	if len(config.Capabilities) > 0 {
@@ -232,6 +232,54 @@ ca = "/etc/path/default"
 	}
 }
 
+func TestParseSRVHosts(t *testing.T) {
+	const testtoml = `
+server = "https://registry.internal"
+
+[host."_oci._tcp.registry.internal"]
+capabilities = ["pull", "resolve"]
+
+[host."https://mirror.registry.internal"]
+capabilities = ["pull"]
+srv = "_oci._tcp.mirror.registry.internal"
+`
+	expected := []hostConfig{
+		{
+			scheme:       "https",
+			host:         "registry.internal",
+			path:         "/v2",
+			capabilities: docker.HostCapabilityPull | docker.HostCapabilityResolve,
+			srvRecord:    "_oci._tcp.registry.internal",
+		},
+		{
+			scheme:       "https",
+			host:         "mirror.registry.internal",
+			path:         "/v2",
+			capabilities: docker.HostCapabilityPull,
+			srvRecord:    "_oci._tcp.mirror.registry.internal",
+		},
+		{
+			scheme:       "https",
+			host:         "registry.internal",
+			path:         "/v2",
+			capabilities: allCaps,
+		},
+	}
+	hosts, err := parseHostsFile("", []byte(testtoml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hosts) != len(expected) {
+		t.Fatalf("Unexpected number of hosts %d, expected %d", len(hosts), len(expected))
+	}
+	for i := range hosts {
+		if !compareHostConfig(hosts[i], expected[i]) {
+			t.Fatalf("Mismatch at host %d", i)
+		}
+	}
+}
+
 func TestLoadCertFiles(t *testing.T) {
 	dir := t.TempDir()
 
@@ -576,6 +624,10 @@ func compareHostConfig(j, k hostConfig) bool {
 		return false
 	}
 
+	if j.srvRecord != k.srvRecord {
+		return false
+	}
+
 	return true
 }
 
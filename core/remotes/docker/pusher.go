@@ -162,112 +162,24 @@ func (p dockerPusher) push(ctx context.Context, desc ocispec.Descriptor, ref str
 		req = p.request(host, http.MethodPut, putPath...)
 		req.header.Add("Content-Type", desc.MediaType)
 	} else {
-		// Start upload request
-		req = p.request(host, http.MethodPost, "blobs", "uploads/")
-
-		mountedFrom := ""
-		var resp *http.Response
-		if fromRepo := selectRepositoryMountCandidate(p.refspec, desc.Annotations); fromRepo != "" {
-			preq := requestWithMountFrom(req, desc.Digest.String(), fromRepo)
-			pctx := ContextWithAppendPullRepositoryScope(ctx, fromRepo)
-
-			// NOTE: the fromRepo might be private repo and
-			// auth service still can grant token without error.
-			// but the post request will fail because of 401.
-			//
-			// for the private repo, we should remove mount-from
-			// query and send the request again.
-			resp, err = preq.doWithRetries(pctx, true)
-			if err != nil {
-				if !errors.Is(err, ErrInvalidAuthorization) {
-					return nil, fmt.Errorf("pushing with mount from %s: %w", fromRepo, err)
-				}
-				log.G(ctx).Debugf("failed to push with mount from repository %s: %v", fromRepo, err)
-			}
-			if resp != nil {
-				switch resp.StatusCode {
-				case http.StatusUnauthorized:
-					log.G(ctx).Debugf("failed to mount from repository %s, not authorized", fromRepo)
-
-					resp.Body.Close()
-					resp = nil
-				case http.StatusCreated:
-					mountedFrom = path.Join(p.refspec.Hostname(), fromRepo)
-				}
-			}
-		}
-
-		if resp == nil {
-			resp, err = req.doWithRetries(ctx, true)
-			if err != nil {
-				if errors.Is(err, ErrInvalidAuthorization) {
-					return nil, fmt.Errorf("push access denied, repository does not exist or may require authorization: %w", err)
-				}
-				return nil, err
+		var mountedFrom string
+		req, mountedFrom, err = p.initiateUploadSession(ctx, host, desc, selectRepositoryMountCandidate(p.refspec, desc.Annotations))
+		if err != nil {
+			if errors.Is(err, errdefs.ErrAlreadyExists) {
+				p.tracker.SetStatus(ref, Status{
+					Committed: true,
+					PushStatus: PushStatus{
+						MountedFrom: mountedFrom,
+					},
+					Status: content.Status{
+						Ref:    ref,
+						Total:  desc.Size,
+						Offset: desc.Size,
+					},
+				})
 			}
-		}
-		defer resp.Body.Close()
-
-		switch resp.StatusCode {
-		case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
-		case http.StatusCreated:
-			p.tracker.SetStatus(ref, Status{
-				Committed: true,
-				PushStatus: PushStatus{
-					MountedFrom: mountedFrom,
-				},
-				Status: content.Status{
-					Ref:    ref,
-					Total:  desc.Size,
-					Offset: desc.Size,
-				},
-			})
-			return nil, fmt.Errorf("content %v on remote: %w", desc.Digest, errdefs.ErrAlreadyExists)
-		default:
-			err := remoteserrors.NewUnexpectedStatusErr(resp)
-			log.G(ctx).WithField("resp", resp).WithField("body", string(err.(remoteserrors.ErrUnexpectedStatus).Body)).Debug("unexpected response")
 			return nil, err
 		}
-
-		var (
-			location = resp.Header.Get("Location")
-			lurl     *url.URL
-			lhost    = host
-		)
-		// Support paths without host in location
-		if strings.HasPrefix(location, "/") {
-			lurl, err = url.Parse(lhost.Scheme + "://" + lhost.Host + location)
-			if err != nil {
-				return nil, fmt.Errorf("unable to parse location %v: %w", location, err)
-			}
-		} else {
-			if !strings.Contains(location, "://") {
-				location = lhost.Scheme + "://" + location
-			}
-			lurl, err = url.Parse(location)
-			if err != nil {
-				return nil, fmt.Errorf("unable to parse location %v: %w", location, err)
-			}
-
-			if lurl.Host != lhost.Host || lhost.Scheme != lurl.Scheme {
-				lhost.Scheme = lurl.Scheme
-				lhost.Host = lurl.Host
-
-				// Check if different than what was requested, accounting for fallback in the transport layer
-				requested := resp.Request.URL
-				if requested.Host != lhost.Host || requested.Scheme != lhost.Scheme {
-					// Strip authorizer if change to host or scheme
-					lhost.Authorizer = nil
-					log.G(ctx).WithField("host", lhost.Host).WithField("scheme", lhost.Scheme).Debug("upload changed destination, authorizer removed")
-				}
-			}
-		}
-		q := lurl.Query()
-		q.Add("digest", desc.Digest.String())
-
-		req = p.request(lhost, http.MethodPut)
-		req.header.Set("Content-Type", "application/octet-stream")
-		req.path = lurl.Path + "?" + q.Encode()
 	}
 	p.tracker.SetStatus(ref, Status{
 		Status: content.Status{
@@ -290,26 +202,160 @@ func (p dockerPusher) push(ctx context.Context, desc ocispec.Descriptor, ref str
 	req.size = desc.Size
 
 	go func() {
-		resp, err := req.doWithRetries(ctx, true)
-		if err != nil {
-			pushw.setError(err)
-			return
-		}
+		for attempt := 0; ; attempt++ {
+			resp, err := req.doWithRetries(ctx, true)
+			if err != nil {
+				pushw.setError(err)
+				return
+			}
 
-		switch resp.StatusCode {
-		case http.StatusOK, http.StatusCreated, http.StatusNoContent:
-		default:
-			err := remoteserrors.NewUnexpectedStatusErr(resp)
-			log.G(ctx).WithField("resp", resp).WithField("body", string(err.(remoteserrors.ErrUnexpectedStatus).Body)).Debug("unexpected response")
-			pushw.setError(err)
+			switch resp.StatusCode {
+			case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+			case http.StatusNotFound, http.StatusGone:
+				// Registries with short upload session TTLs expire the
+				// session before a slow blob upload completes and answer
+				// the final PUT with 404 or 410. Start a fresh session
+				// and replay the blob; replacing the request body pipe
+				// makes the writer surface content.ErrReset so the
+				// caller rewrites the content from the beginning.
+				if !isManifest && attempt < maxUploadSessionRetries {
+					resp.Body.Close()
+					nreq, _, serr := p.initiateUploadSession(ctx, host, desc, "")
+					if serr == nil {
+						log.G(ctx).WithField("digest", desc.Digest).
+							Infof("upload session expired, retrying with a new session (attempt %d)", attempt+1)
+						nreq.body = req.body
+						nreq.size = req.size
+						req = nreq
+						continue
+					}
+					log.G(ctx).WithError(serr).WithField("digest", desc.Digest).
+						Debug("failed to renew expired upload session")
+				}
+				fallthrough
+			default:
+				err := remoteserrors.NewUnexpectedStatusErr(resp)
+				log.G(ctx).WithField("resp", resp).WithField("body", string(err.(remoteserrors.ErrUnexpectedStatus).Body)).Debug("unexpected response")
+				pushw.setError(err)
+				return
+			}
+			pushw.setResponse(resp)
 			return
 		}
-		pushw.setResponse(resp)
 	}()
 
 	return pushw, nil
 }
 
+// maxUploadSessionRetries bounds how often an expired blob upload session
+// is renewed before the push is failed.
+const maxUploadSessionRetries = 3
+
+// initiateUploadSession starts a blob upload session for desc and returns
+// the PUT request prepared with the session location. When mountCandidate
+// is not empty a cross-repository mount from that repository is attempted
+// first; a registry completing the mount ends the upload and is reported
+// as errdefs.ErrAlreadyExists together with the repository mounted from.
+func (p dockerPusher) initiateUploadSession(ctx context.Context, host RegistryHost, desc ocispec.Descriptor, mountCandidate string) (_ *request, mountedFrom string, _ error) {
+	req := p.request(host, http.MethodPost, "blobs", "uploads/")
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	if mountCandidate != "" {
+		preq := requestWithMountFrom(req, desc.Digest.String(), mountCandidate)
+		pctx := ContextWithAppendPullRepositoryScope(ctx, mountCandidate)
+
+		// NOTE: the mount candidate might be private repo and
+		// auth service still can grant token without error.
+		// but the post request will fail because of 401.
+		//
+		// for the private repo, we should remove mount-from
+		// query and send the request again.
+		resp, err = preq.doWithRetries(pctx, true)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidAuthorization) {
+				return nil, "", fmt.Errorf("pushing with mount from %s: %w", mountCandidate, err)
+			}
+			log.G(ctx).Debugf("failed to push with mount from repository %s: %v", mountCandidate, err)
+		}
+		if resp != nil {
+			switch resp.StatusCode {
+			case http.StatusUnauthorized:
+				log.G(ctx).Debugf("failed to mount from repository %s, not authorized", mountCandidate)
+
+				resp.Body.Close()
+				resp = nil
+			case http.StatusCreated:
+				mountedFrom = path.Join(p.refspec.Hostname(), mountCandidate)
+			}
+		}
+	}
+
+	if resp == nil {
+		resp, err = req.doWithRetries(ctx, true)
+		if err != nil {
+			if errors.Is(err, ErrInvalidAuthorization) {
+				return nil, "", fmt.Errorf("push access denied, repository does not exist or may require authorization: %w", err)
+			}
+			return nil, "", err
+		}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+	case http.StatusCreated:
+		return nil, mountedFrom, fmt.Errorf("content %v on remote: %w", desc.Digest, errdefs.ErrAlreadyExists)
+	default:
+		err := remoteserrors.NewUnexpectedStatusErr(resp)
+		log.G(ctx).WithField("resp", resp).WithField("body", string(err.(remoteserrors.ErrUnexpectedStatus).Body)).Debug("unexpected response")
+		return nil, "", err
+	}
+
+	var (
+		location = resp.Header.Get("Location")
+		lurl     *url.URL
+		lhost    = host
+	)
+	// Support paths without host in location
+	if strings.HasPrefix(location, "/") {
+		lurl, err = url.Parse(lhost.Scheme + "://" + lhost.Host + location)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to parse location %v: %w", location, err)
+		}
+	} else {
+		if !strings.Contains(location, "://") {
+			location = lhost.Scheme + "://" + location
+		}
+		lurl, err = url.Parse(location)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to parse location %v: %w", location, err)
+		}
+
+		if lurl.Host != lhost.Host || lhost.Scheme != lurl.Scheme {
+			lhost.Scheme = lurl.Scheme
+			lhost.Host = lurl.Host
+
+			// Check if different than what was requested, accounting for fallback in the transport layer
+			requested := resp.Request.URL
+			if requested.Host != lhost.Host || requested.Scheme != lhost.Scheme {
+				// Strip authorizer if change to host or scheme
+				lhost.Authorizer = nil
+				log.G(ctx).WithField("host", lhost.Host).WithField("scheme", lhost.Scheme).Debug("upload changed destination, authorizer removed")
+			}
+		}
+	}
+	q := lurl.Query()
+	q.Add("digest", desc.Digest.String())
+
+	preq := p.request(lhost, http.MethodPut)
+	preq.header.Set("Content-Type", "application/octet-stream")
+	preq.path = lurl.Path + "?" + q.Encode()
+	return preq, mountedFrom, nil
+}
+
 func getManifestPath(object string, dgst digest.Digest) []string {
 	if i := strings.IndexByte(object, '@'); i >= 0 {
 		if object[i+1:] != dgst.String() {
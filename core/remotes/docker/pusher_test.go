@@ -177,6 +177,52 @@ func TestPusherErrReset(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// TestPusherSessionExpiryRetry tests that a blob upload whose session has
+// expired before the final PUT is renewed with a fresh session and the
+// caller is asked to reset and rewrite the content.
+func TestPusherSessionExpiryRetry(t *testing.T) {
+	p, reg, _, done := samplePusher(t)
+	defer done()
+
+	reg.uploadable = true
+	reg.putHandlerFunc = func() func(w http.ResponseWriter, r *http.Request) bool {
+		// answer the first PUT with 404 as registries do for expired
+		// upload sessions, then let the renewed session succeed
+		expired := true
+		return func(w http.ResponseWriter, r *http.Request) bool {
+			if expired {
+				expired = false
+				w.WriteHeader(http.StatusNotFound)
+				return true
+			}
+			return false
+		}
+	}()
+
+	ct := []byte("layer-content")
+
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayerGzip,
+		Digest:    digest.FromBytes(ct),
+		Size:      int64(len(ct)),
+	}
+
+	w, err := p.push(context.Background(), desc, remotes.MakeRefKey(context.Background(), desc), false)
+	assert.NoError(t, err)
+
+	// first attempt runs into the expired session and must be reset
+	_, err = w.Write(ct)
+	assert.NoError(t, err)
+	err = w.Commit(context.Background(), desc.Size, desc.Digest)
+	assert.Equal(t, content.ErrReset, err)
+
+	// the rewrite goes to the renewed session
+	_, err = w.Write(ct)
+	assert.NoError(t, err)
+	err = w.Commit(context.Background(), desc.Size, desc.Digest)
+	assert.NoError(t, err)
+}
+
 func TestPusherInvalidAuthorizationOnMount(t *testing.T) {
 	t.Parallel()
 	// Simulate trying to mount a private repo that we cannot access to
@@ -0,0 +1,150 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/basuotian/containerd/core/remotes"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// maxReferrersIndexSize bounds the size of a referrers index response.
+const maxReferrersIndexSize = 8 << 20 // 8MB
+
+var _ remotes.ReferrersLister = (*dockerResolver)(nil)
+
+// Referrers lists the artifacts attached to the manifest with the given
+// digest using the OCI referrers API, falling back to the referrers tag
+// scheme on registries which do not support the endpoint. A digest with no
+// referrers yields an empty index, not an error.
+func (r *dockerResolver) Referrers(ctx context.Context, ref string, dgst digest.Digest, artifactType string) (*ocispec.Index, error) {
+	if err := dgst.Validate(); err != nil {
+		return nil, err
+	}
+	base, err := r.resolveDockerBase(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := base.filterHosts(HostCapabilityPull)
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no pull hosts: %w", errdefs.ErrNotFound)
+	}
+
+	ctx, err = ContextWithRepositoryScope(ctx, base.refspec, false)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := [][]string{
+		{"referrers", dgst.String()},
+		// Fallback tag scheme: an image index pushed to the tag
+		// "<alg>-<hex>" in the same repository.
+		{"manifests", referrersFallbackTag(dgst)},
+	}
+
+	var firstErr error
+	for _, u := range paths {
+		for i, host := range hosts {
+			ctx := log.WithLogger(ctx, log.G(ctx).WithField("host", host.Host))
+
+			req := base.request(host, http.MethodGet, u...)
+			req.header.Set("Accept", ocispec.MediaTypeImageIndex)
+			if err := req.addNamespace(base.refspec.Hostname()); err != nil {
+				return nil, err
+			}
+			if u[0] == "referrers" && artifactType != "" {
+				sep := "?"
+				if strings.ContainsRune(req.path, '?') {
+					sep = "&"
+				}
+				req.path += sep + "artifactType=" + url.QueryEscape(artifactType)
+			}
+
+			log.G(ctx).Debug("listing referrers")
+			resp, err := req.doWithRetries(ctx, i == len(hosts)-1)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue // try another host
+			}
+
+			if resp.StatusCode > 299 {
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusNotFound && firstErr == nil {
+					firstErr = fmt.Errorf("listing referrers of %s returned unexpected status: %s", dgst, resp.Status)
+				}
+				continue
+			}
+
+			body, err := io.ReadAll(io.LimitReader(resp.Body, maxReferrersIndexSize))
+			resp.Body.Close()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+
+			var index ocispec.Index
+			if err := json.Unmarshal(body, &index); err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("invalid referrers index for %s: %w", dgst, err)
+				}
+				continue
+			}
+			// Registries which ignore the artifactType query and the
+			// fallback tag scheme both require client-side filtering.
+			if artifactType != "" {
+				filtered := index.Manifests[:0]
+				for _, m := range index.Manifests {
+					if m.ArtifactType == artifactType {
+						filtered = append(filtered, m)
+					}
+				}
+				index.Manifests = filtered
+			}
+			return &index, nil
+		}
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	// Neither the referrers API nor the fallback tag exist: the manifest
+	// simply has no referrers.
+	return &ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+	}, nil
+}
+
+// referrersFallbackTag returns the tag holding the referrers index for dgst
+// under the fallback tag scheme, e.g. "sha256-abc...".
+func referrersFallbackTag(dgst digest.Digest) string {
+	return strings.ReplaceAll(dgst.String(), ":", "-")
+}
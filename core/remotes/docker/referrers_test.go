@@ -0,0 +1,118 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/basuotian/containerd/core/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferrersFallbackTag(t *testing.T) {
+	dgst := digest.Digest("sha256:b9f5d10f93a6d4f9594c47566b4b6ba8bae4fcee1a1eb82f1cf30c0cfc1a73ed")
+	assert.Equal(t, "sha256-b9f5d10f93a6d4f9594c47566b4b6ba8bae4fcee1a1eb82f1cf30c0cfc1a73ed", referrersFallbackTag(dgst))
+}
+
+func TestReferrers(t *testing.T) {
+	subject := digest.FromString("subject manifest")
+	sbom := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: "application/spdx+json",
+		Digest:       digest.FromString("sbom"),
+		Size:         10,
+	}
+	signature := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: "application/vnd.dev.cosign.simplesigning.v1+json",
+		Digest:       digest.FromString("signature"),
+		Size:         20,
+	}
+	index := ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{sbom, signature},
+	}
+	indexJSON, err := json.Marshal(index)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name         string
+		referrersAPI bool
+	}{
+		{
+			name:         "ReferrersAPI",
+			referrersAPI: true,
+		},
+		{
+			name: "FallbackTag",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v2/test/repo/referrers/" + subject.String():
+					if !test.referrersAPI {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+				case "/v2/test/repo/manifests/" + referrersFallbackTag(subject):
+					if test.referrersAPI {
+						t.Errorf("fallback tag used although the referrers API is available")
+					}
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Content-Type", ocispec.MediaTypeImageIndex)
+				w.Write(indexJSON)
+			}))
+			defer srv.Close()
+
+			u, err := url.Parse(srv.URL)
+			require.NoError(t, err)
+			resolver := NewResolver(ResolverOptions{
+				Hosts: ConfigureDefaultRegistries(WithPlainHTTP(MatchAllHosts)),
+			})
+			lister, ok := resolver.(remotes.ReferrersLister)
+			require.True(t, ok, "docker resolver must implement ReferrersLister")
+
+			ref := fmt.Sprintf("%s/test/repo:latest", u.Host)
+			result, err := lister.Referrers(context.Background(), ref, subject, "")
+			require.NoError(t, err)
+			assert.Equal(t, index.Manifests, result.Manifests)
+
+			// Filtered by artifact type.
+			result, err = lister.Referrers(context.Background(), ref, subject, sbom.ArtifactType)
+			require.NoError(t, err)
+			assert.Equal(t, []ocispec.Descriptor{sbom}, result.Manifests)
+
+			// Unknown digest has no referrers.
+			result, err = lister.Referrers(context.Background(), ref, digest.FromString("unknown"), "")
+			require.NoError(t, err)
+			assert.Empty(t, result.Manifests)
+		})
+	}
+}
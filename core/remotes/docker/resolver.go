@@ -39,6 +39,7 @@ import (
 
 	"github.com/basuotian/containerd/core/images"
 	"github.com/basuotian/containerd/core/remotes"
+	"github.com/basuotian/containerd/core/remotes/docker/schema1"
 	remoteerrors "github.com/basuotian/containerd/core/remotes/errors"
 	"github.com/basuotian/containerd/core/transfer"
 	"github.com/basuotian/containerd/pkg/reference"
@@ -129,6 +130,12 @@ type ResolverOptions struct {
 	//
 	// Deprecated: use Hosts.
 	Client *http.Client
+
+	// AllowSchema1 allows resolving legacy Docker schema 1 manifests
+	// instead of failing, so a puller can convert them to OCI manifests
+	// on ingestion. Schema 1 is deprecated and the conversion only exists
+	// for registries which cannot serve newer manifests.
+	AllowSchema1 bool
 }
 
 // DefaultHost is the default host function.
@@ -144,6 +151,7 @@ type dockerResolver struct {
 	header        http.Header
 	resolveHeader http.Header
 	tracker       StatusTracker
+	allowSchema1  bool
 	config        transfer.ImageResolverOptions
 }
 
@@ -203,6 +211,7 @@ func NewResolver(options ResolverOptions) remotes.Resolver {
 		header:        options.Headers,
 		resolveHeader: resolveHeader,
 		tracker:       options.Tracker,
+		allowSchema1:  options.AllowSchema1,
 	}
 }
 
@@ -387,8 +396,18 @@ func (r *dockerResolver) Resolve(ctx context.Context, ref string) (string, ocisp
 					}
 
 					if contentType == images.MediaTypeDockerSchema1Manifest {
-						return fmt.Errorf("%w: media type %q is no longer supported since containerd v2.0, please rebuild the image as %q or %q",
-							errdefs.ErrNotImplemented, images.MediaTypeDockerSchema1Manifest, images.MediaTypeDockerSchema2Manifest, ocispec.MediaTypeImageManifest)
+						if !r.allowSchema1 {
+							return fmt.Errorf("%w: media type %q is no longer supported since containerd v2.0, please rebuild the image as %q or %q",
+								errdefs.ErrNotImplemented, images.MediaTypeDockerSchema1Manifest, images.MediaTypeDockerSchema2Manifest, ocispec.MediaTypeImageManifest)
+						}
+						// The digest of a schema 1 manifest is calculated
+						// over the payload with the signature stripped.
+						b, err := schema1.ReadStripSignature(&bodyReader)
+						if err != nil {
+							return err
+						}
+						dgst = digest.FromBytes(b)
+						return nil
 					}
 
 					dgst, err = digest.FromReader(&bodyReader)
@@ -0,0 +1,412 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package schema1 provides a converter from legacy Docker schema 1
+// manifests to OCI manifests, so images in registries which cannot be
+// rebuilt can still be pulled while their owners migrate. Schema 1 is
+// deprecated; every conversion logs a deprecation warning.
+package schema1
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/images"
+	"github.com/basuotian/containerd/core/remotes"
+	"github.com/basuotian/containerd/pkg/archive/compression"
+	"github.com/basuotian/containerd/pkg/deprecation"
+	"github.com/basuotian/containerd/pkg/labels"
+)
+
+// Converter converts schema 1 manifests to OCI manifests on ingestion.
+//
+// It implements images.HandlerFunc through Handle: dispatching it over a
+// schema 1 manifest descriptor fetches the manifest and its layers into
+// the content store, after which Convert synthesizes the OCI config and
+// manifest and returns the new manifest descriptor.
+type Converter struct {
+	contentStore content.Store
+	fetcher      remotes.Fetcher
+
+	mu       sync.Mutex
+	manifest *manifest
+	// layers maps layer blob sums to the ingested layer descriptor and
+	// its uncompressed diff ID.
+	layers map[digest.Digest]convertedLayer
+}
+
+type convertedLayer struct {
+	desc   ocispec.Descriptor
+	diffID digest.Digest
+}
+
+// NewConverter returns a Converter pulling through fetcher into store.
+func NewConverter(store content.Store, fetcher remotes.Fetcher) *Converter {
+	return &Converter{
+		contentStore: store,
+		fetcher:      fetcher,
+		layers:       map[digest.Digest]convertedLayer{},
+	}
+}
+
+// Handle fetches the schema 1 manifest and returns its layers as
+// children so the dispatcher downloads them through this handler too.
+func (c *Converter) Handle(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	switch desc.MediaType {
+	case images.MediaTypeDockerSchema1Manifest:
+		if err := c.fetchManifest(ctx, desc); err != nil {
+			return nil, err
+		}
+		m := c.pulledManifest()
+		descs := make([]ocispec.Descriptor, 0, len(m.FSLayers))
+		seen := map[digest.Digest]struct{}{}
+		for i, l := range m.FSLayers {
+			if m.History[i].throwaway() {
+				continue
+			}
+			if _, ok := seen[l.BlobSum]; ok {
+				continue
+			}
+			seen[l.BlobSum] = struct{}{}
+			descs = append(descs, ocispec.Descriptor{
+				MediaType: images.MediaTypeDockerSchema2LayerGzip,
+				Digest:    l.BlobSum,
+				Size:      -1,
+			})
+		}
+		return descs, nil
+	case images.MediaTypeDockerSchema2LayerGzip:
+		if c.pulledManifest() == nil {
+			return nil, fmt.Errorf("manifest must be fetched before layers: %w", errdefs.ErrFailedPrecondition)
+		}
+		return nil, c.fetchLayer(ctx, desc)
+	default:
+		return nil, fmt.Errorf("%v not support for schema 1 conversion: %w", desc.MediaType, errdefs.ErrNotImplemented)
+	}
+}
+
+// Convert writes the synthesized OCI config and manifest to the content
+// store and returns the manifest descriptor. It must run after Handle was
+// dispatched over the schema 1 manifest.
+func (c *Converter) Convert(ctx context.Context) (ocispec.Descriptor, error) {
+	m := c.pulledManifest()
+	if m == nil {
+		return ocispec.Descriptor{}, fmt.Errorf("manifest not fetched: %w", errdefs.ErrFailedPrecondition)
+	}
+	if msg, ok := deprecation.Message(deprecation.PullSchema1Image); ok {
+		log.G(ctx).WithField("name", m.Name).Warn(msg)
+	}
+
+	var img ocispec.Image
+	if err := json.Unmarshal([]byte(m.History[0].V1Compatibility), &img); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("failed to unmarshal image from manifest history: %w", err)
+	}
+	img.History = nil
+	img.RootFS = ocispec.RootFS{Type: "layers"}
+
+	// Schema 1 lists layers and history newest first, OCI oldest first.
+	var layerDescs []ocispec.Descriptor
+	for i := len(m.FSLayers) - 1; i >= 0; i-- {
+		var h v1History
+		if err := json.Unmarshal([]byte(m.History[i].V1Compatibility), &h); err != nil {
+			return ocispec.Descriptor{}, fmt.Errorf("failed to unmarshal history of layer %d: %w", i, err)
+		}
+		img.History = append(img.History, ocispec.History{
+			Author:     h.Author,
+			Comment:    h.Comment,
+			Created:    &h.Created,
+			CreatedBy:  strings.Join(h.ContainerConfig.Cmd, " "),
+			EmptyLayer: m.History[i].throwaway(),
+		})
+		if m.History[i].throwaway() {
+			continue
+		}
+		l, ok := c.layer(m.FSLayers[i].BlobSum)
+		if !ok {
+			return ocispec.Descriptor{}, fmt.Errorf("layer %v not fetched: %w", m.FSLayers[i].BlobSum, errdefs.ErrFailedPrecondition)
+		}
+		img.RootFS.DiffIDs = append(img.RootFS.DiffIDs, l.diffID)
+		layerDescs = append(layerDescs, l.desc)
+	}
+
+	configBytes, err := json.Marshal(img)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	configDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(configBytes),
+		Size:      int64(len(configBytes)),
+	}
+
+	ociManifest := ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+		Layers:    layerDescs,
+	}
+	manifestBytes, err := json.Marshal(ociManifest)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	manifestDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifestBytes),
+		Size:      int64(len(manifestBytes)),
+	}
+
+	// Reference the config and layers from the manifest so they are not
+	// collected, the same way the fetch handlers label children.
+	mlabels := map[string]string{
+		"containerd.io/gc.ref.content.config": configDesc.Digest.String(),
+	}
+	for i, l := range layerDescs {
+		mlabels["containerd.io/gc.ref.content.l."+strconv.Itoa(i)] = l.Digest.String()
+	}
+
+	ref := remotes.MakeRefKey(ctx, configDesc)
+	if err := content.WriteBlob(ctx, c.contentStore, ref, bytes.NewReader(configBytes), configDesc); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("failed to write config: %w", err)
+	}
+	ref = remotes.MakeRefKey(ctx, manifestDesc)
+	if err := content.WriteBlob(ctx, c.contentStore, ref, bytes.NewReader(manifestBytes), manifestDesc, content.WithLabels(mlabels)); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return manifestDesc, nil
+}
+
+func (c *Converter) fetchManifest(ctx context.Context, desc ocispec.Descriptor) error {
+	log.G(ctx).Debug("fetch schema 1 manifest")
+	rc, err := c.fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	b, err := ReadStripSignature(rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	var m manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	if len(m.FSLayers) == 0 || len(m.FSLayers) != len(m.History) {
+		return fmt.Errorf("invalid schema 1 manifest, history and layer mismatch: %w", errdefs.ErrInvalidArgument)
+	}
+	c.mu.Lock()
+	c.manifest = &m
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Converter) fetchLayer(ctx context.Context, desc ocispec.Descriptor) error {
+	log.G(ctx).Debug("fetch schema 1 layer")
+
+	// Reuse a layer already in the content store when its diff ID is
+	// known.
+	if info, err := c.contentStore.Info(ctx, desc.Digest); err == nil {
+		if diffID, err := digest.Parse(info.Labels[labels.LabelUncompressed]); err == nil {
+			c.setLayer(desc.Digest, convertedLayer{
+				desc:   ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayerGzip, Digest: desc.Digest, Size: info.Size},
+				diffID: diffID,
+			})
+			return nil
+		}
+	} else if !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	rc, err := c.fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	ref := remotes.MakeRefKey(ctx, desc)
+	cw, err := content.OpenWriter(ctx, c.contentStore, content.WithRef(ref))
+	if err != nil {
+		return err
+	}
+	defer cw.Close()
+	if err := cw.Truncate(0); err != nil {
+		return err
+	}
+
+	// The blob is stored as fetched while the diff ID is calculated from
+	// the decompressed stream.
+	tee := io.TeeReader(rc, cw)
+	dr, err := compression.DecompressStream(tee)
+	if err != nil {
+		return err
+	}
+	diffID, err := digest.FromReader(dr)
+	if err != nil {
+		dr.Close()
+		return err
+	}
+	dr.Close()
+	// Drain trailing bytes of the compressed stream.
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return err
+	}
+
+	mediaType := ocispec.MediaTypeImageLayerGzip
+	if dr.GetCompression() == compression.Uncompressed {
+		mediaType = ocispec.MediaTypeImageLayer
+	}
+
+	st, err := cw.Status()
+	if err != nil {
+		return err
+	}
+	size := st.Offset
+	err = cw.Commit(ctx, size, desc.Digest, content.WithLabels(map[string]string{
+		labels.LabelUncompressed: diffID.String(),
+	}))
+	if err != nil && !errdefs.IsAlreadyExists(err) {
+		return err
+	}
+
+	c.setLayer(desc.Digest, convertedLayer{
+		desc:   ocispec.Descriptor{MediaType: mediaType, Digest: desc.Digest, Size: size},
+		diffID: diffID,
+	})
+	return nil
+}
+
+func (c *Converter) pulledManifest() *manifest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.manifest
+}
+
+func (c *Converter) setLayer(blobSum digest.Digest, l convertedLayer) {
+	c.mu.Lock()
+	c.layers[blobSum] = l
+	c.mu.Unlock()
+}
+
+func (c *Converter) layer(blobSum digest.Digest) (convertedLayer, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.layers[blobSum]
+	return l, ok
+}
+
+// ReadStripSignature reads a schema 1 manifest and returns it without
+// the JWS signature block, which is the canonical payload the manifest
+// digest is calculated over.
+func ReadStripSignature(r io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, 4<<20)) // limit to 4MB
+	if err != nil {
+		return nil, err
+	}
+	var sig struct {
+		Signatures []struct {
+			Protected string `json:"protected"`
+		} `json:"signatures"`
+	}
+	if err := json.Unmarshal(b, &sig); err != nil {
+		return nil, err
+	}
+	if len(sig.Signatures) == 0 {
+		// Unsigned schema 1 manifests are used as is.
+		return b, nil
+	}
+	protected, err := joseBase64Decode(sig.Signatures[0].Protected)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature protected header: %w", err)
+	}
+	var header struct {
+		Length int    `json:"formatLength"`
+		Tail   string `json:"formatTail"`
+	}
+	if err := json.Unmarshal(protected, &header); err != nil {
+		return nil, fmt.Errorf("invalid signature protected header: %w", err)
+	}
+	tail, err := joseBase64Decode(header.Tail)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature format tail: %w", err)
+	}
+	if header.Length > len(b) {
+		return nil, fmt.Errorf("invalid signature format length %d: %w", header.Length, errdefs.ErrInvalidArgument)
+	}
+	return append(b[:header.Length], tail...), nil
+}
+
+// joseBase64Decode decodes JOSE base64url data, which drops the padding.
+func joseBase64Decode(s string) ([]byte, error) {
+	switch len(s) % 4 {
+	case 2:
+		s += "=="
+	case 3:
+		s += "="
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
+
+// manifest is the subset of the schema 1 manifest needed for conversion.
+type manifest struct {
+	Name     string         `json:"name"`
+	Tag      string         `json:"tag"`
+	FSLayers []fsLayer      `json:"fsLayers"`
+	History  []manifestHist `json:"history"`
+}
+
+type fsLayer struct {
+	BlobSum digest.Digest `json:"blobSum"`
+}
+
+type manifestHist struct {
+	V1Compatibility string `json:"v1Compatibility"`
+}
+
+// throwaway reports whether the layer of this history entry carries no
+// filesystem changes.
+func (h manifestHist) throwaway() bool {
+	var c v1History
+	if err := json.Unmarshal([]byte(h.V1Compatibility), &c); err != nil {
+		return false
+	}
+	return c.ThrowAway != nil && *c.ThrowAway
+}
+
+// v1History is the subset of the v1Compatibility payload needed for
+// conversion.
+type v1History struct {
+	Author          string    `json:"author,omitempty"`
+	Created         time.Time `json:"created"`
+	Comment         string    `json:"comment,omitempty"`
+	ThrowAway       *bool     `json:"throwaway,omitempty"`
+	ContainerConfig struct {
+		Cmd []string `json:"Cmd,omitempty"`
+	} `json:"container_config,omitempty"`
+}
@@ -0,0 +1,211 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/errdefs"
+)
+
+const (
+	// srvRefreshInterval is how long resolved SRV targets are cached. The
+	// Go resolver does not expose record TTLs, so a fixed refresh interval
+	// stands in for TTL expiry.
+	srvRefreshInterval = 30 * time.Second
+
+	// srvFailureCooldown is how long a target is avoided after a failed
+	// request, as long as healthy targets remain.
+	srvFailureCooldown = 30 * time.Second
+)
+
+// NewSRVDiscoveryTransport wraps a roundtripper so requests are balanced
+// across the targets of the given DNS SRV record instead of going to the
+// URL host. Targets are ordered by SRV priority with a weighted shuffle
+// within each priority, targets whose requests fail are put in a cooldown
+// and retried only when no healthy target is left, and the record is
+// re-resolved periodically so cluster changes are picked up.
+func NewSRVDiscoveryTransport(rt http.RoundTripper, record string) http.RoundTripper {
+	return &srvDiscovery{
+		rt:     rt,
+		record: record,
+		lookup: func(ctx context.Context, record string) ([]*net.SRV, error) {
+			_, addrs, err := net.DefaultResolver.LookupSRV(ctx, "", "", record)
+			return addrs, err
+		},
+		now:      time.Now,
+		failures: map[string]time.Time{},
+	}
+}
+
+type srvTarget struct {
+	addr     string
+	priority uint16
+	weight   uint16
+}
+
+type srvDiscovery struct {
+	rt     http.RoundTripper
+	record string
+	lookup func(context.Context, string) ([]*net.SRV, error)
+	now    func() time.Time
+
+	mu       sync.Mutex
+	targets  []srvTarget
+	failures map[string]time.Time
+	expires  time.Time
+}
+
+// RoundTrip sends the request to the first SRV target which accepts it,
+// marking targets whose requests fail at the transport level. Responses are
+// returned as-is, an HTTP error status is the caller's concern and not a
+// sign of an unhealthy target.
+func (s *srvDiscovery) RoundTrip(req *http.Request) (*http.Response, error) {
+	addrs, err := s.candidates(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for i, addr := range addrs {
+		if i > 0 && req.Body != nil && req.GetBody == nil {
+			// The body was consumed by the failed attempt and cannot
+			// be replayed against another target.
+			break
+		}
+		r := req.Clone(req.Context())
+		r.URL.Host = addr
+		if i > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		resp, err := s.rt.RoundTrip(r)
+		if err != nil {
+			s.markFailed(addr)
+			lastErr = err
+			continue
+		}
+		s.markHealthy(addr)
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+// candidates returns the target addresses to try in order, re-resolving the
+// record when the cache expired. Resolver errors keep the previous targets
+// so a flaky DNS server does not take down a working cluster.
+func (s *srvDiscovery) candidates(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.After(s.expires) || len(s.targets) == 0 {
+		recs, err := s.lookup(ctx, s.record)
+		if err != nil {
+			if len(s.targets) == 0 {
+				return nil, fmt.Errorf("failed to resolve SRV record %q: %w", s.record, err)
+			}
+		} else {
+			targets := make([]srvTarget, 0, len(recs))
+			for _, rec := range recs {
+				targets = append(targets, srvTarget{
+					addr:     net.JoinHostPort(strings.TrimSuffix(rec.Target, "."), strconv.Itoa(int(rec.Port))),
+					priority: rec.Priority,
+					weight:   rec.Weight,
+				})
+			}
+			s.targets = targets
+		}
+		s.expires = now.Add(srvRefreshInterval)
+	}
+	if len(s.targets) == 0 {
+		return nil, fmt.Errorf("SRV record %q has no targets: %w", s.record, errdefs.ErrNotFound)
+	}
+
+	var healthy, cooling []srvTarget
+	for _, t := range s.targets {
+		if failedAt, ok := s.failures[t.addr]; ok && now.Sub(failedAt) < srvFailureCooldown {
+			cooling = append(cooling, t)
+		} else {
+			healthy = append(healthy, t)
+		}
+	}
+
+	// Targets in cooldown are kept as a last resort so a fully failed
+	// cluster still gets a connection attempt rather than a local error.
+	return append(orderTargets(healthy), orderTargets(cooling)...), nil
+}
+
+func (s *srvDiscovery) markFailed(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[addr] = s.now()
+}
+
+func (s *srvDiscovery) markHealthy(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, addr)
+}
+
+// orderTargets sorts targets by SRV priority and applies a weighted random
+// shuffle within each priority group, per RFC 2782.
+func orderTargets(targets []srvTarget) []string {
+	sorted := make([]srvTarget, len(targets))
+	copy(sorted, targets)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].priority < sorted[j].priority
+	})
+
+	addrs := make([]string, 0, len(sorted))
+	for start := 0; start < len(sorted); {
+		end := start
+		for end < len(sorted) && sorted[end].priority == sorted[start].priority {
+			end++
+		}
+		group := sorted[start:end]
+		for len(group) > 0 {
+			// Every target gets weight+1 so zero-weight targets remain
+			// selectable.
+			total := 0
+			for _, t := range group {
+				total += int(t.weight) + 1
+			}
+			r := rand.Intn(total)
+			for i, t := range group {
+				r -= int(t.weight) + 1
+				if r < 0 {
+					addrs = append(addrs, t.addr)
+					group = append(group[:i], group[i+1:]...)
+					break
+				}
+			}
+		}
+		start = end
+	}
+	return addrs
+}
@@ -0,0 +1,227 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeSRVRoundTripper struct {
+	hosts  []string
+	failOn map[string]error
+}
+
+func (f *fakeSRVRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.hosts = append(f.hosts, req.URL.Host)
+	if err, ok := f.failOn[req.URL.Host]; ok {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+func newTestSRVDiscovery(rt http.RoundTripper, recs []*net.SRV) *srvDiscovery {
+	return &srvDiscovery{
+		rt:     rt,
+		record: "_oci._tcp.registry.test",
+		lookup: func(context.Context, string) ([]*net.SRV, error) {
+			return recs, nil
+		},
+		now:      time.Now,
+		failures: map[string]time.Time{},
+	}
+}
+
+func srvRequest(t *testing.T) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, "https://registry.test/v2/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestSRVDiscoveryRewritesHost(t *testing.T) {
+	rt := &fakeSRVRoundTripper{}
+	s := newTestSRVDiscovery(rt, []*net.SRV{
+		{Target: "reg1.registry.test.", Port: 5000},
+	})
+
+	resp, err := s.RoundTrip(srvRequest(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(rt.hosts) != 1 || rt.hosts[0] != "reg1.registry.test:5000" {
+		t.Fatalf("unexpected hosts %v", rt.hosts)
+	}
+}
+
+func TestSRVDiscoveryFailover(t *testing.T) {
+	rt := &fakeSRVRoundTripper{
+		failOn: map[string]error{
+			"reg1.registry.test:5000": errors.New("connection refused"),
+		},
+	}
+	// reg1 has the lower priority value so it is always tried first.
+	s := newTestSRVDiscovery(rt, []*net.SRV{
+		{Target: "reg1.registry.test.", Port: 5000, Priority: 10},
+		{Target: "reg2.registry.test.", Port: 5000, Priority: 20},
+	})
+
+	resp, err := s.RoundTrip(srvRequest(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(rt.hosts) != 2 || rt.hosts[1] != "reg2.registry.test:5000" {
+		t.Fatalf("expected failover to reg2, got %v", rt.hosts)
+	}
+
+	// The failed target is in cooldown, the next request must go straight
+	// to the healthy one.
+	rt.hosts = nil
+	resp, err = s.RoundTrip(srvRequest(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(rt.hosts) != 1 || rt.hosts[0] != "reg2.registry.test:5000" {
+		t.Fatalf("expected cooled down target to be skipped, got %v", rt.hosts)
+	}
+}
+
+func TestSRVDiscoveryAllFailed(t *testing.T) {
+	failure := errors.New("connection refused")
+	rt := &fakeSRVRoundTripper{
+		failOn: map[string]error{
+			"reg1.registry.test:5000": failure,
+			"reg2.registry.test:5000": failure,
+		},
+	}
+	s := newTestSRVDiscovery(rt, []*net.SRV{
+		{Target: "reg1.registry.test.", Port: 5000},
+		{Target: "reg2.registry.test.", Port: 5000},
+	})
+
+	if _, err := s.RoundTrip(srvRequest(t)); !errors.Is(err, failure) {
+		t.Fatalf("expected transport failure, got %v", err)
+	}
+
+	// With every target in cooldown they are still attempted as a last
+	// resort instead of failing locally.
+	rt.hosts = nil
+	if _, err := s.RoundTrip(srvRequest(t)); !errors.Is(err, failure) {
+		t.Fatalf("expected transport failure, got %v", err)
+	}
+	if len(rt.hosts) == 0 {
+		t.Fatal("expected cooled down targets to be retried as a last resort")
+	}
+}
+
+func TestSRVDiscoveryReresolves(t *testing.T) {
+	rt := &fakeSRVRoundTripper{}
+	recs := []*net.SRV{{Target: "reg1.registry.test.", Port: 5000}}
+	lookups := 0
+	now := time.Now()
+	s := newTestSRVDiscovery(rt, nil)
+	s.lookup = func(context.Context, string) ([]*net.SRV, error) {
+		lookups++
+		return recs, nil
+	}
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		resp, err := s.RoundTrip(srvRequest(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if lookups != 1 {
+		t.Fatalf("expected cached targets, got %d lookups", lookups)
+	}
+
+	// Cache expiry triggers re-resolution and picks up new targets.
+	recs = []*net.SRV{{Target: "reg2.registry.test.", Port: 5000}}
+	now = now.Add(srvRefreshInterval + time.Second)
+	resp, err := s.RoundTrip(srvRequest(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if lookups != 2 {
+		t.Fatalf("expected re-resolution, got %d lookups", lookups)
+	}
+	if got := rt.hosts[len(rt.hosts)-1]; got != "reg2.registry.test:5000" {
+		t.Fatalf("expected new target to be used, got %v", got)
+	}
+}
+
+func TestSRVDiscoveryBodyNotReplayed(t *testing.T) {
+	rt := &fakeSRVRoundTripper{
+		failOn: map[string]error{
+			"reg1.registry.test:5000": errors.New("connection reset"),
+		},
+	}
+	s := newTestSRVDiscovery(rt, []*net.SRV{
+		{Target: "reg1.registry.test.", Port: 5000, Priority: 10},
+		{Target: "reg2.registry.test.", Port: 5000, Priority: 20},
+	})
+
+	req, err := http.NewRequest(http.MethodPost, "https://registry.test/v2/", io.NopCloser(strings.NewReader("payload")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// NopCloser leaves GetBody unset, so the request must not be retried
+	// against another target after the body was consumed.
+	req.GetBody = nil
+
+	if _, err := s.RoundTrip(req); err == nil {
+		t.Fatal("expected failure without body replay")
+	}
+	if len(rt.hosts) != 1 {
+		t.Fatalf("expected a single attempt, got %v", rt.hosts)
+	}
+}
+
+func TestOrderTargetsPriority(t *testing.T) {
+	targets := []srvTarget{
+		{addr: "low:5000", priority: 20},
+		{addr: "high-a:5000", priority: 10},
+		{addr: "high-b:5000", priority: 10},
+	}
+	for i := 0; i < 10; i++ {
+		addrs := orderTargets(targets)
+		if len(addrs) != 3 {
+			t.Fatalf("unexpected addrs %v", addrs)
+		}
+		if addrs[2] != "low:5000" {
+			t.Fatalf("expected lower priority target last, got %v", addrs)
+		}
+	}
+}
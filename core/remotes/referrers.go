@@ -0,0 +1,34 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remotes
+
+import (
+	"context"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ReferrersLister is implemented by resolvers which can discover the
+// artifacts attached to a manifest (signatures, SBOMs, attestations, ...)
+// through the OCI referrers API or its fallback tag scheme. The returned
+// index lists one descriptor per attached artifact. An empty artifactType
+// returns all referrers, otherwise only those with a matching artifact
+// type.
+type ReferrersLister interface {
+	Referrers(ctx context.Context, ref string, dgst digest.Digest, artifactType string) (*ocispec.Index, error)
+}
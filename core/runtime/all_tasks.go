@@ -0,0 +1,48 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package runtime
+
+import "github.com/containerd/typeurl/v2"
+
+func init() {
+	typeurl.Register(&ListAllTasksRequest{}, "containerd.runtime.ListAllTasksRequest")
+	typeurl.Register(&ListAllTasksResponse{}, "containerd.runtime.ListAllTasksResponse")
+}
+
+// ListAllTasksRequest asks the tasks service, through the introspection
+// PluginInfo interface, for the tasks of every namespace. The query must
+// be enabled in the tasks service configuration.
+type ListAllTasksRequest struct{}
+
+// TaskSummary describes one task in a ListAllTasksResponse.
+type TaskSummary struct {
+	// Namespace the task runs in.
+	Namespace string `json:"namespace"`
+	// ID of the task.
+	ID string `json:"id"`
+	// Pid is the task's process id on the host.
+	Pid uint32 `json:"pid,omitempty"`
+	// Status of the task, for example "RUNNING".
+	Status string `json:"status"`
+	// ExitStatus of a stopped task.
+	ExitStatus uint32 `json:"exit_status,omitempty"`
+}
+
+// ListAllTasksResponse answers a ListAllTasksRequest.
+type ListAllTasksResponse struct {
+	Tasks []TaskSummary `json:"tasks"`
+}
@@ -19,6 +19,10 @@ package runtime
 import (
 	"github.com/containerd/containerd/api/events"
 	"github.com/containerd/log"
+
+	"github.com/basuotian/containerd/pkg/coredump"
+	"github.com/basuotian/containerd/pkg/sdnotify"
+	"github.com/basuotian/containerd/pkg/startlatency"
 )
 
 const (
@@ -28,8 +32,20 @@ const (
 	TaskStartEventTopic = "/tasks/start"
 	// TaskOOMEventTopic for task oom
 	TaskOOMEventTopic = "/tasks/oom"
+	// TaskOOMVictimEventTopic for details about the processes killed
+	// during a task oom, published separately from TaskOOMEventTopic
+	TaskOOMVictimEventTopic = "/tasks/oom-victim"
 	// TaskExitEventTopic for task exit
 	TaskExitEventTopic = "/tasks/exit"
+	// TaskReadyEventTopic for sd_notify readiness reported by the
+	// workload, published separately from TaskStartEventTopic
+	TaskReadyEventTopic = "/tasks/ready"
+	// TaskStartLatencyEventTopic for the per-stage start latency
+	// breakdown, published separately from TaskStartEventTopic
+	TaskStartLatencyEventTopic = "/tasks/start-latency"
+	// TaskCoreDumpEventTopic for core dumps captured for a task exit,
+	// published separately from TaskExitEventTopic
+	TaskCoreDumpEventTopic = "/tasks/core-dump"
 	// TaskDeleteEventTopic for task delete
 	TaskDeleteEventTopic = "/tasks/delete"
 	// TaskExecAddedEventTopic for task exec create
@@ -58,6 +74,12 @@ func GetTopic(e interface{}) string {
 		return TaskOOMEventTopic
 	case *events.TaskExit:
 		return TaskExitEventTopic
+	case *sdnotify.TaskReady:
+		return TaskReadyEventTopic
+	case *startlatency.TaskStartLatency:
+		return TaskStartLatencyEventTopic
+	case *coredump.TaskCoreDump:
+		return TaskCoreDumpEventTopic
 	case *events.TaskDelete:
 		return TaskDeleteEventTopic
 	case *events.TaskExecAdded:
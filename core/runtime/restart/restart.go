@@ -165,6 +165,23 @@ func WithStatus(status containerd.ProcessStatus) func(context.Context, *containe
 	}
 }
 
+// WithRestartPolicy parses the policy string ("no", "always",
+// "unless-stopped" or "on-failure[:max-retries]"), sets it on the container
+// and marks the desired status as running so the restart monitor keeps the
+// task alive according to the policy.
+func WithRestartPolicy(policy string) func(context.Context, *containerd.Client, *containers.Container) error {
+	return func(_ context.Context, _ *containerd.Client, c *containers.Container) error {
+		rp, err := NewPolicy(policy)
+		if err != nil {
+			return err
+		}
+		ensureLabels(c)
+		c.Labels[PolicyLabel] = rp.String()
+		c.Labels[StatusLabel] = string(containerd.Running)
+		return nil
+	}
+}
+
 // WithPolicy sets the restart policy for a container
 func WithPolicy(policy *Policy) func(context.Context, *containerd.Client, *containers.Container) error {
 	return func(_ context.Context, _ *containerd.Client, c *containers.Container) error {
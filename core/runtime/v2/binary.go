@@ -20,7 +20,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	gruntime "runtime"
@@ -104,7 +103,7 @@ func (b *binary) Start(ctx context.Context, opts *types.Any, onClose func()) (_
 	// copy the shim's logs to containerd's output
 	go func() {
 		defer f.Close()
-		_, err := io.Copy(os.Stderr, f)
+		err := copyShimLog(shimCtx, f, ns, b.bundle.ID, b.runtime)
 		// To prevent flood of error messages, the expected error
 		// should be reset, like os.ErrClosed or os.ErrNotExist, which
 		// depends on platform.
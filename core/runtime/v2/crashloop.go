@@ -0,0 +1,201 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+)
+
+const (
+	// crashLoopThreshold is the number of consecutive shim start failures
+	// for a runtime after which the runtime is quarantined.
+	crashLoopThreshold = 5
+	// crashLoopBackoffBase is the delay applied after the first shim start
+	// failure of a container, doubled on every subsequent failure.
+	crashLoopBackoffBase = 250 * time.Millisecond
+	// crashLoopBackoffMax caps the exponential backoff delay.
+	crashLoopBackoffMax = 30 * time.Second
+
+	// RuntimeQuarantinedEventTopic is published on the event exchange when
+	// a runtime crosses the crash loop threshold and is quarantined.
+	RuntimeQuarantinedEventTopic = "/runtimes/quarantined"
+)
+
+// ErrRuntimeQuarantined is returned by task creation when the requested
+// runtime has been quarantined after repeated shim start failures. The
+// quarantine is lifted through the introspection API with a
+// ResetQuarantineRequest.
+var ErrRuntimeQuarantined = fmt.Errorf("runtime quarantined after repeated shim start failures: %w", errdefs.ErrUnavailable)
+
+func init() {
+	typeurl.Register(&RuntimeQuarantinedEvent{}, "containerd.runtime.v2.RuntimeQuarantinedEvent")
+	typeurl.Register(&ResetQuarantineRequest{}, "containerd.runtime.v2.ResetQuarantineRequest")
+	typeurl.Register(&ResetQuarantineResponse{}, "containerd.runtime.v2.ResetQuarantineResponse")
+}
+
+// RuntimeQuarantinedEvent is the payload published under
+// RuntimeQuarantinedEventTopic.
+type RuntimeQuarantinedEvent struct {
+	// Runtime is the quarantined runtime name
+	Runtime string `json:"runtime"`
+	// ContainerID is the container whose shim start failures tripped the
+	// threshold
+	ContainerID string `json:"container_id"`
+	// Failures is the number of consecutive start failures observed
+	Failures int `json:"failures"`
+}
+
+// ResetQuarantineRequest lifts the quarantine of a runtime and clears its
+// crash history. It is passed to the task manager through the introspection
+// PluginInfo API.
+type ResetQuarantineRequest struct {
+	// Runtime is the runtime name to reset, all quarantined runtimes are
+	// reset when empty
+	Runtime string `json:"runtime"`
+}
+
+// ResetQuarantineResponse is returned for a ResetQuarantineRequest.
+type ResetQuarantineResponse struct {
+	// Reset lists the runtimes whose quarantine was lifted
+	Reset []string `json:"reset"`
+}
+
+// crashRecord tracks consecutive shim start failures of a single
+// runtime+container pair.
+type crashRecord struct {
+	failures    int
+	nextAttempt time.Time
+}
+
+// crashMonitor detects shim crash loops during task create. Failures are
+// tracked per runtime+container with an exponential backoff between start
+// attempts, and a runtime is quarantined once a single container accumulates
+// crashLoopThreshold consecutive failures.
+type crashMonitor struct {
+	mu          sync.Mutex
+	records     map[string]*crashRecord
+	quarantined map[string]bool
+}
+
+func newCrashMonitor() *crashMonitor {
+	return &crashMonitor{
+		records:     map[string]*crashRecord{},
+		quarantined: map[string]bool{},
+	}
+}
+
+func crashKey(runtime, id string) string {
+	return runtime + "/" + id
+}
+
+// check fails fast when the runtime is quarantined or the container is still
+// inside its backoff window.
+func (c *crashMonitor) check(runtime, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.quarantined[runtime] {
+		return fmt.Errorf("runtime %q: %w", runtime, ErrRuntimeQuarantined)
+	}
+	if r, ok := c.records[crashKey(runtime, id)]; ok {
+		if remaining := time.Until(r.nextAttempt); remaining > 0 {
+			return fmt.Errorf("backing off shim start for %q after %d failures, retry in %s: %w",
+				id, r.failures, remaining.Round(time.Millisecond), errdefs.ErrUnavailable)
+		}
+	}
+	return nil
+}
+
+// recordFailure registers a shim start failure and reports whether it
+// quarantined the runtime, together with the consecutive failure count.
+func (c *crashMonitor) recordFailure(runtime, id string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := crashKey(runtime, id)
+	r, ok := c.records[key]
+	if !ok {
+		r = &crashRecord{}
+		c.records[key] = r
+	}
+	r.failures++
+	delay := crashLoopBackoffBase << (r.failures - 1)
+	if delay > crashLoopBackoffMax || delay <= 0 {
+		delay = crashLoopBackoffMax
+	}
+	r.nextAttempt = time.Now().Add(delay)
+	if r.failures >= crashLoopThreshold && !c.quarantined[runtime] {
+		c.quarantined[runtime] = true
+		return r.failures, true
+	}
+	return r.failures, false
+}
+
+// recordSuccess clears the crash history of a container after a successful
+// shim start.
+func (c *crashMonitor) recordSuccess(runtime, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.records, crashKey(runtime, id))
+}
+
+// recordShimStartFailure registers a shim start failure with the crash
+// monitor and publishes a quarantine event when the failure crosses the
+// crash loop threshold.
+func (m *ShimManager) recordShimStartFailure(ctx context.Context, rt, id string) {
+	failures, quarantined := m.crashes.recordFailure(rt, id)
+	if !quarantined {
+		return
+	}
+	log.G(ctx).WithFields(log.Fields{
+		"runtime":  rt,
+		"id":       id,
+		"failures": failures,
+	}).Error("quarantining runtime after repeated shim start failures")
+	if err := m.events.Publish(ctx, RuntimeQuarantinedEventTopic, &RuntimeQuarantinedEvent{
+		Runtime:     rt,
+		ContainerID: id,
+		Failures:    failures,
+	}); err != nil {
+		log.G(ctx).WithError(err).Warn("failed to publish runtime quarantine event")
+	}
+}
+
+// reset lifts the quarantine of the given runtime, or of all runtimes when
+// empty, and returns the runtimes that were reset.
+func (c *crashMonitor) reset(runtime string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var reset []string
+	for rt := range c.quarantined {
+		if runtime == "" || rt == runtime {
+			delete(c.quarantined, rt)
+			reset = append(reset, rt)
+		}
+	}
+	for key := range c.records {
+		if runtime == "" || key == runtime || len(key) > len(runtime) && key[:len(runtime)+1] == runtime+"/" {
+			delete(c.records, key)
+		}
+	}
+	return reset
+}
@@ -0,0 +1,80 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/containerd/errdefs"
+)
+
+func TestCrashMonitorBackoff(t *testing.T) {
+	m := newCrashMonitor()
+
+	if err := m.check("io.containerd.runc.v2", "c1"); err != nil {
+		t.Fatalf("expected no error before any failure, got %v", err)
+	}
+
+	if _, quarantined := m.recordFailure("io.containerd.runc.v2", "c1"); quarantined {
+		t.Fatal("single failure must not quarantine the runtime")
+	}
+	err := m.check("io.containerd.runc.v2", "c1")
+	if !errdefs.IsUnavailable(err) {
+		t.Fatalf("expected unavailable error during backoff, got %v", err)
+	}
+
+	// other containers of the same runtime are unaffected
+	if err := m.check("io.containerd.runc.v2", "c2"); err != nil {
+		t.Fatalf("expected no error for another container, got %v", err)
+	}
+
+	m.recordSuccess("io.containerd.runc.v2", "c1")
+	if err := m.check("io.containerd.runc.v2", "c1"); err != nil {
+		t.Fatalf("expected no error after success, got %v", err)
+	}
+}
+
+func TestCrashMonitorQuarantine(t *testing.T) {
+	m := newCrashMonitor()
+
+	var quarantined bool
+	for i := 0; i < crashLoopThreshold; i++ {
+		_, quarantined = m.recordFailure("io.containerd.runc.v2", "c1")
+	}
+	if !quarantined {
+		t.Fatalf("expected quarantine after %d failures", crashLoopThreshold)
+	}
+
+	// all containers of the runtime now fail fast
+	err := m.check("io.containerd.runc.v2", "c2")
+	if !errors.Is(err, ErrRuntimeQuarantined) {
+		t.Fatalf("expected ErrRuntimeQuarantined, got %v", err)
+	}
+	// other runtimes are unaffected
+	if err := m.check("io.containerd.kata.v2", "c1"); err != nil {
+		t.Fatalf("expected no error for another runtime, got %v", err)
+	}
+
+	reset := m.reset("io.containerd.runc.v2")
+	if len(reset) != 1 || reset[0] != "io.containerd.runc.v2" {
+		t.Fatalf("unexpected reset runtimes %v", reset)
+	}
+	if err := m.check("io.containerd.runc.v2", "c1"); err != nil {
+		t.Fatalf("expected no error after reset, got %v", err)
+	}
+}
@@ -90,7 +90,13 @@ func loadShim(ctx context.Context, bundle *Bundle, onClose func()) (_ ShimInstan
 	// copy the shim's logs to containerd's output
 	go func() {
 		defer f.Close()
-		_, err := io.Copy(os.Stderr, f)
+		// The runtime tag is best effort here, the shim log registry
+		// keeps the one recorded when the shim was started.
+		var runtime string
+		if data, err := os.ReadFile(filepath.Join(bundle.Path, "shim-binary-path")); err == nil {
+			runtime = string(data)
+		}
+		err := copyShimLog(shimCtx, f, bundle.Namespace, bundle.ID, runtime)
 		// To prevent flood of error messages, the expected error
 		// should be reset, like os.ErrClosed or os.ErrNotExist, which
 		// depends on platform.
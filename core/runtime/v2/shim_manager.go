@@ -135,6 +135,7 @@ func NewShimManager(config *ManagerConfig) (*ShimManager, error) {
 		containers:             config.Store,
 		env:                    config.ShimEnv,
 		sandboxStore:           config.SandboxStore,
+		crashes:                newCrashMonitor(),
 	}
 
 	return m, nil
@@ -154,6 +155,8 @@ type ShimManager struct {
 	// runtimePaths is a cache of `runtime names` -> `resolved fs path`
 	runtimePaths sync.Map
 	sandboxStore sandbox.Store
+	// crashes detects shim start crash loops and quarantines runtimes
+	crashes *crashMonitor
 }
 
 // ID of the shim manager
@@ -163,6 +166,10 @@ func (m *ShimManager) ID() string {
 
 // Start launches a new shim instance
 func (m *ShimManager) Start(ctx context.Context, id string, bundle *Bundle, opts runtime.CreateOpts) (_ ShimInstance, retErr error) {
+	if err := m.crashes.check(opts.Runtime, id); err != nil {
+		return nil, err
+	}
+
 	shouldInvokeShimBinary := false
 
 	var params shimbinary.BootstrapParams
@@ -248,8 +255,10 @@ func (m *ShimManager) Start(ctx context.Context, id string, bundle *Bundle, opts
 
 	shim, err := m.startShim(ctx, bundle, id, opts)
 	if err != nil {
+		m.recordShimStartFailure(ctx, opts.Runtime, id)
 		return nil, err
 	}
+	m.crashes.recordSuccess(opts.Runtime, id)
 	defer func() {
 		if retErr != nil {
 			m.cleanupShim(ctx, shim)
@@ -0,0 +1,102 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+
+	task "github.com/containerd/containerd/api/runtime/task/v3"
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+
+	"github.com/basuotian/containerd/pkg/namespaces"
+)
+
+func init() {
+	typeurl.Register(&ShimProcessesRequest{}, "containerd.runtime.v2.ShimProcessesRequest")
+	typeurl.Register(&ShimProcessesResponse{}, "containerd.runtime.v2.ShimProcessesResponse")
+}
+
+// ShimProcessesRequest lists the running shim processes known to the task
+// manager along with their process IDs. It is passed to the task manager
+// through the introspection PluginInfo API.
+type ShimProcessesRequest struct {
+	// Namespace limits the response to shims of one namespace, all
+	// namespaces when empty
+	Namespace string `json:"namespace"`
+	// ID limits the response to a single shim, all shims when empty
+	ID string `json:"id"`
+}
+
+// ShimProcess describes a single running shim process.
+type ShimProcess struct {
+	// Namespace of the shim
+	Namespace string `json:"namespace"`
+	// ID of the shim
+	ID string `json:"id"`
+	// Pid is the process ID of the shim itself, not of the task it runs
+	Pid uint32 `json:"pid"`
+}
+
+// ShimProcessesResponse is returned for a ShimProcessesRequest.
+type ShimProcessesResponse struct {
+	// Shims holds the running shims matching the request
+	Shims []ShimProcess `json:"shims"`
+}
+
+// shimProcesses resolves the shim pid of every running shim matching the
+// request. The pid is reported by the shim itself over its Connect RPC;
+// shims that cannot be reached are skipped rather than failing the whole
+// request.
+func (m *TaskManager) shimProcesses(ctx context.Context, req *ShimProcessesRequest) (*ShimProcessesResponse, error) {
+	shims, err := m.manager.shims.GetAll(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp ShimProcessesResponse
+	for _, s := range shims {
+		if req.Namespace != "" && s.Namespace() != req.Namespace {
+			continue
+		}
+		if req.ID != "" && s.ID() != req.ID {
+			continue
+		}
+
+		st, err := newShimTask(s)
+		if err != nil {
+			log.G(ctx).WithError(err).WithField("id", s.ID()).Debug("failed to create task client for shim")
+			continue
+		}
+		nsCtx := namespaces.WithNamespace(ctx, s.Namespace())
+		connect, err := st.task.Connect(nsCtx, &task.ConnectRequest{
+			ID: s.ID(),
+		})
+		if err != nil {
+			log.G(ctx).WithError(err).WithField("id", s.ID()).Debug("failed to connect to shim")
+			continue
+		}
+
+		resp.Shims = append(resp.Shims, ShimProcess{
+			Namespace: s.Namespace(),
+			ID:        s.ID(),
+			Pid:       connect.GetShimPid(),
+		})
+	}
+
+	return &resp, nil
+}
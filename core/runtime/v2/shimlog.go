@@ -0,0 +1,231 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+)
+
+const (
+	// shimLogRetainBytes is the amount of recent shim log output retained
+	// in memory per shim for postmortems.
+	shimLogRetainBytes = 32 << 10
+	// shimLogMaxLine caps the length of a single shim log line; longer
+	// lines are split.
+	shimLogMaxLine = 16 << 10
+	// shimLogRateLimit is the number of shim log lines per second a single
+	// shim may forward to the daemon logger. Lines beyond the limit are
+	// dropped from the daemon log but still retained in memory.
+	shimLogRateLimit = 100
+	// shimLogMaxShims bounds the number of shims with a retained log tail;
+	// the least recently written tail is evicted beyond it.
+	shimLogMaxShims = 128
+)
+
+func init() {
+	typeurl.Register(&ShimLogsRequest{}, "containerd.runtime.v2.ShimLogsRequest")
+	typeurl.Register(&ShimLogsResponse{}, "containerd.runtime.v2.ShimLogsResponse")
+}
+
+// ShimLogsRequest retrieves the retained tail of shim log output for
+// postmortems. It is passed to the task manager through the introspection
+// PluginInfo API.
+type ShimLogsRequest struct {
+	// Namespace limits the response to shims of one namespace, all
+	// namespaces when empty
+	Namespace string `json:"namespace"`
+	// ID limits the response to a single shim, all shims when empty
+	ID string `json:"id"`
+}
+
+// ShimLogs is the retained log tail of a single shim.
+type ShimLogs struct {
+	// Namespace of the shim
+	Namespace string `json:"namespace"`
+	// ID of the shim
+	ID string `json:"id"`
+	// Runtime is the runtime binary the shim was started from
+	Runtime string `json:"runtime"`
+	// Log holds the most recent shimLogRetainBytes of the shim's output
+	Log string `json:"log"`
+	// Dropped counts lines the rate limit kept out of the daemon log
+	Dropped uint64 `json:"dropped"`
+}
+
+// ShimLogsResponse is returned for a ShimLogsRequest.
+type ShimLogsResponse struct {
+	// Shims holds the retained tails matching the request
+	Shims []ShimLogs `json:"shims"`
+}
+
+// shimLogs retains log tails across shim restarts and after shim exit, so
+// the output of a crashed shim stays available. Both the start and the
+// reload log copiers feed it.
+var shimLogs = &shimLogRegistry{buffers: map[string]*shimLogBuffer{}}
+
+type shimLogRegistry struct {
+	mu      sync.Mutex
+	buffers map[string]*shimLogBuffer
+}
+
+// get returns the buffer of the given shim, creating it if needed. The
+// runtime is recorded when known; reloaded shims keep the recorded one.
+func (r *shimLogRegistry) get(namespace, id, runtime string) *shimLogBuffer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := namespace + "/" + id
+	b, ok := r.buffers[key]
+	if !ok {
+		if len(r.buffers) >= shimLogMaxShims {
+			r.evict()
+		}
+		b = &shimLogBuffer{namespace: namespace, id: id}
+		r.buffers[key] = b
+	}
+	if runtime != "" {
+		b.mu.Lock()
+		b.runtime = runtime
+		b.mu.Unlock()
+	}
+	return b
+}
+
+// evict drops the least recently written buffer. Callers hold r.mu.
+func (r *shimLogRegistry) evict() {
+	var (
+		oldestKey string
+		oldest    time.Time
+	)
+	for key, b := range r.buffers {
+		b.mu.Lock()
+		written := b.lastWrite
+		b.mu.Unlock()
+		if oldestKey == "" || written.Before(oldest) {
+			oldestKey, oldest = key, written
+		}
+	}
+	delete(r.buffers, oldestKey)
+}
+
+func (r *shimLogRegistry) collect(namespace, id string) []ShimLogs {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []ShimLogs
+	for _, b := range r.buffers {
+		b.mu.Lock()
+		if (namespace == "" || b.namespace == namespace) && (id == "" || b.id == id) {
+			out = append(out, ShimLogs{
+				Namespace: b.namespace,
+				ID:        b.id,
+				Runtime:   b.runtime,
+				Log:       string(b.buf),
+				Dropped:   b.dropped,
+			})
+		}
+		b.mu.Unlock()
+	}
+	return out
+}
+
+// shimLogBuffer is the per shim retained tail plus the rate limiter state
+// of the copier feeding it.
+type shimLogBuffer struct {
+	mu        sync.Mutex
+	namespace string
+	id        string
+	runtime   string
+	buf       []byte
+	dropped   uint64
+	lastWrite time.Time
+
+	// rate limiter window, lines counted per second
+	windowStart time.Time
+	windowLines int
+}
+
+// retain appends a line to the tail, trimming the front to the retention
+// size, and reports whether the rate limit allows forwarding it to the
+// daemon logger.
+func (b *shimLogBuffer) retain(line []byte, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, line...)
+	b.buf = append(b.buf, '\n')
+	if excess := len(b.buf) - shimLogRetainBytes; excess > 0 {
+		b.buf = b.buf[excess:]
+	}
+	b.lastWrite = now
+
+	if now.Sub(b.windowStart) >= time.Second {
+		b.windowStart = now
+		b.windowLines = 0
+	}
+	b.windowLines++
+	if b.windowLines > shimLogRateLimit {
+		b.dropped++
+		return false
+	}
+	return true
+}
+
+// copyShimLog forwards the shim's log pipe to the daemon logger line by
+// line, tagged with the shim's namespace, id and runtime, rate limited per
+// shim. The most recent output is retained in memory and can be queried
+// through the introspection PluginInfo API with a ShimLogsRequest.
+func copyShimLog(ctx context.Context, r io.Reader, namespace, id, runtime string) error {
+	var (
+		buffer = shimLogs.get(namespace, id, runtime)
+		entry  = log.G(ctx).WithFields(log.Fields{
+			"namespace": namespace,
+			"id":        id,
+			"runtime":   runtime,
+		})
+		limited bool
+	)
+	br := bufio.NewReaderSize(r, shimLogMaxLine)
+	for {
+		line, err := br.ReadSlice('\n')
+		if len(line) > 0 {
+			line = bytes.TrimRight(line, "\n")
+			if buffer.retain(line, time.Now()) {
+				if limited {
+					limited = false
+					entry.Warn("shim log rate limit lifted, dropped lines are retained in memory")
+				}
+				entry.Info(string(line))
+			} else if !limited {
+				limited = true
+				entry.Warnf("shim logs more than %d lines per second, dropping further lines from the daemon log", shimLogRateLimit)
+			}
+		}
+		switch err {
+		case nil, bufio.ErrBufferFull:
+		case io.EOF:
+			return nil
+		default:
+			return err
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShimLogBufferRetention(t *testing.T) {
+	b := &shimLogBuffer{namespace: "test", id: "c1"}
+	now := time.Now()
+
+	line := bytes.Repeat([]byte("x"), 1024)
+	for i := 0; i < 2*shimLogRetainBytes/len(line); i++ {
+		b.retain(line, now)
+		now = now.Add(time.Second)
+	}
+	if len(b.buf) > shimLogRetainBytes {
+		t.Fatalf("buffer grew to %d bytes, retention is %d", len(b.buf), shimLogRetainBytes)
+	}
+
+	b.retain([]byte("the last line"), now)
+	if !bytes.HasSuffix(b.buf, []byte("the last line\n")) {
+		t.Fatal("buffer should end with the most recent line")
+	}
+}
+
+func TestShimLogBufferRateLimit(t *testing.T) {
+	b := &shimLogBuffer{namespace: "test", id: "c1"}
+	now := time.Now()
+
+	for i := 0; i < shimLogRateLimit; i++ {
+		if !b.retain([]byte("line"), now) {
+			t.Fatalf("line %d should be within the rate limit", i)
+		}
+	}
+	if b.retain([]byte("line"), now) {
+		t.Fatal("line beyond the rate limit should be dropped")
+	}
+	if b.dropped != 1 {
+		t.Fatalf("expected 1 dropped line, got %d", b.dropped)
+	}
+	// A new window lifts the limit.
+	if !b.retain([]byte("line"), now.Add(time.Second)) {
+		t.Fatal("line in the next window should be forwarded again")
+	}
+}
+
+func TestCopyShimLogRetainsTail(t *testing.T) {
+	r := &shimLogRegistry{buffers: map[string]*shimLogBuffer{}}
+	prev := shimLogs
+	shimLogs = r
+	defer func() { shimLogs = prev }()
+
+	input := "first line\nsecond line\n" + strings.Repeat("y", shimLogMaxLine+10) + "\n"
+	if err := copyShimLog(context.Background(), strings.NewReader(input), "ns", "c1", "io.containerd.runc.v2"); err != nil {
+		t.Fatalf("copy failed: %v", err)
+	}
+
+	logs := r.collect("ns", "c1")
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 retained shim, got %d", len(logs))
+	}
+	if logs[0].Runtime != "io.containerd.runc.v2" {
+		t.Fatalf("unexpected runtime %q", logs[0].Runtime)
+	}
+	if !strings.Contains(logs[0].Log, "first line\nsecond line\n") {
+		t.Fatalf("retained log misses lines: %q", logs[0].Log)
+	}
+
+	if logs := r.collect("other", ""); len(logs) != 0 {
+		t.Fatalf("namespace filter returned %d shims", len(logs))
+	}
+}
@@ -0,0 +1,236 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	runhcsoptions "github.com/Microsoft/hcsshim/cmd/containerd-shim-runhcs-v1/options"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/typeurl/v2"
+	"github.com/pelletier/go-toml/v2"
+
+	runcoptions "github.com/containerd/containerd/api/types/runc/options"
+	runtimeoptions "github.com/containerd/containerd/api/types/runtimeoptions/v1"
+
+	"github.com/basuotian/containerd/core/runtime"
+	"github.com/basuotian/containerd/plugins"
+)
+
+func init() {
+	typeurl.Register(&EffectiveTaskOptionsRequest{}, "containerd.runtime.v2.EffectiveTaskOptionsRequest")
+	typeurl.Register(&EffectiveTaskOptionsResponse{}, "containerd.runtime.v2.EffectiveTaskOptionsResponse")
+}
+
+// RuntimeDefaults configures default task options for a single runtime name
+// in the task manager section of the daemon config, for example to enforce
+// SystemdCgroup or pin a BinaryName without touching every client:
+//
+//	[plugins.'io.containerd.runtime.v2.task'.runtime_defaults.'io.containerd.runc.v2']
+//	  enforced = ['SystemdCgroup']
+//	  [plugins.'io.containerd.runtime.v2.task'.runtime_defaults.'io.containerd.runc.v2'.options]
+//	    SystemdCgroup = true
+type RuntimeDefaults struct {
+	// Options holds the default values, decoded into the typed options of
+	// the runtime the same way the cri runtime config handles them. A
+	// default only applies when the client left the field unset.
+	Options map[string]interface{} `toml:"options" json:"options"`
+	// Enforced lists option fields whose configured value always wins,
+	// overriding whatever the client supplied.
+	Enforced []string `toml:"enforced" json:"enforced,omitempty"`
+}
+
+// EffectiveTaskOptionsRequest returns the task options the task manager
+// would hand to a shim of the given runtime, after merging the configured
+// runtime defaults. It is passed to the task manager through the
+// introspection PluginInfo API.
+type EffectiveTaskOptionsRequest struct {
+	// Runtime is the runtime name the defaults are configured for
+	Runtime string `json:"runtime"`
+	// Options are client task options to merge, may be empty to inspect
+	// the bare defaults
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// EffectiveTaskOptionsResponse is returned for an EffectiveTaskOptionsRequest.
+type EffectiveTaskOptionsResponse struct {
+	// TypeURL is the type of the options the runtime receives
+	TypeURL string `json:"type_url"`
+	// Options is the merged result
+	Options map[string]interface{} `json:"options"`
+	// Enforced lists the fields the daemon config overrides
+	Enforced []string `json:"enforced,omitempty"`
+}
+
+// taskOptionsType returns empty typed task options for a runtime name.
+func taskOptionsType(rt string) interface{} {
+	switch rt {
+	case plugins.RuntimeRuncV2:
+		return &runcoptions.Options{}
+	case plugins.RuntimeRunhcsV1:
+		return &runhcsoptions.Options{}
+	default:
+		return &runtimeoptions.Options{}
+	}
+}
+
+// runtimeDefaults holds the decoded defaults of a single runtime.
+type runtimeDefaults struct {
+	config   RuntimeDefaults
+	options  interface{}
+	enforced map[string]bool
+}
+
+// taskDefaults holds the per-runtime default task options of the task
+// manager, decoded and validated once at plugin init.
+type taskDefaults struct {
+	runtimes map[string]*runtimeDefaults
+}
+
+func newTaskDefaults(config map[string]RuntimeDefaults) (*taskDefaults, error) {
+	d := &taskDefaults{
+		runtimes: make(map[string]*runtimeDefaults, len(config)),
+	}
+	for rt, cfg := range config {
+		options := taskOptionsType(rt)
+		if err := decodeOptions(cfg.Options, options); err != nil {
+			return nil, fmt.Errorf("invalid default options for runtime %q: %w", rt, err)
+		}
+		fields := reflect.TypeOf(options).Elem()
+		enforced := make(map[string]bool, len(cfg.Enforced))
+		for _, name := range cfg.Enforced {
+			if f, ok := fields.FieldByName(name); !ok || !f.IsExported() {
+				return nil, fmt.Errorf("enforced option %q for runtime %q does not exist in %T: %w",
+					name, rt, options, errdefs.ErrInvalidArgument)
+			}
+			enforced[name] = true
+		}
+		d.runtimes[rt] = &runtimeDefaults{
+			config:   cfg,
+			options:  options,
+			enforced: enforced,
+		}
+	}
+	return d, nil
+}
+
+// decodeOptions decodes a generic options table from the daemon config into
+// typed task options, going through toml like the cri runtime config does.
+func decodeOptions(raw map[string]interface{}, options interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	b, err := toml.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return toml.Unmarshal(b, options)
+}
+
+// mergeOptions fills unset client option fields from the defaults and
+// overrides the fields marked enforced, mutating client in place.
+func mergeOptions(client, defaults interface{}, enforced map[string]bool) error {
+	cv := reflect.ValueOf(client)
+	dv := reflect.ValueOf(defaults)
+	if cv.Type() != dv.Type() {
+		return fmt.Errorf("cannot merge task options %T with defaults %T: %w",
+			client, defaults, errdefs.ErrInvalidArgument)
+	}
+	cv, dv = cv.Elem(), dv.Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		f := cv.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if enforced[f.Name] || cv.Field(i).IsZero() {
+			cv.Field(i).Set(dv.Field(i))
+		}
+	}
+	return nil
+}
+
+// apply merges the configured defaults of opts.Runtime into the
+// client-supplied task options before the shim is started. The merged
+// result is stored in opts.TaskOptions, which takes precedence over
+// RuntimeOptions everywhere the options are consumed.
+func (d *taskDefaults) apply(opts *runtime.CreateOpts) error {
+	rd, ok := d.runtimes[opts.Runtime]
+	if !ok {
+		return nil
+	}
+
+	topts := opts.TaskOptions
+	if topts == nil || topts.GetValue() == nil {
+		topts = opts.RuntimeOptions
+	}
+	if topts == nil || topts.GetValue() == nil {
+		// No client options, the defaults apply as-is.
+		merged, err := typeurl.MarshalAny(rd.options)
+		if err != nil {
+			return err
+		}
+		opts.TaskOptions = merged
+		return nil
+	}
+
+	client, err := typeurl.UnmarshalAny(topts)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal task options %q: %w", topts.GetTypeUrl(), err)
+	}
+	if err := mergeOptions(client, rd.options, rd.enforced); err != nil {
+		return err
+	}
+	merged, err := typeurl.MarshalAny(client)
+	if err != nil {
+		return err
+	}
+	opts.TaskOptions = merged
+	return nil
+}
+
+// effective answers an EffectiveTaskOptionsRequest, merging the given
+// client options with the configured defaults of the runtime.
+func (d *taskDefaults) effective(req *EffectiveTaskOptionsRequest) (*EffectiveTaskOptionsResponse, error) {
+	options := taskOptionsType(req.Runtime)
+	if err := decodeOptions(req.Options, options); err != nil {
+		return nil, fmt.Errorf("invalid options for runtime %q: %w", req.Runtime, err)
+	}
+	resp := &EffectiveTaskOptionsResponse{}
+	if rd, ok := d.runtimes[req.Runtime]; ok {
+		if err := mergeOptions(options, rd.options, rd.enforced); err != nil {
+			return nil, err
+		}
+		resp.Enforced = rd.config.Enforced
+	}
+	any, err := typeurl.MarshalAny(options)
+	if err != nil {
+		return nil, err
+	}
+	resp.TypeURL = any.GetTypeUrl()
+
+	// Render the merged result as a generic table for the client.
+	b, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &resp.Options); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,146 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package v2
+
+import (
+	"testing"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/typeurl/v2"
+
+	runcoptions "github.com/containerd/containerd/api/types/runc/options"
+
+	"github.com/basuotian/containerd/core/runtime"
+	"github.com/basuotian/containerd/plugins"
+)
+
+func TestNewTaskDefaultsValidation(t *testing.T) {
+	if _, err := newTaskDefaults(map[string]RuntimeDefaults{
+		plugins.RuntimeRuncV2: {
+			Enforced: []string{"NoSuchOption"},
+		},
+	}); !errdefs.IsInvalidArgument(err) {
+		t.Fatalf("expected invalid argument for unknown enforced field, got %v", err)
+	}
+
+	if _, err := newTaskDefaults(map[string]RuntimeDefaults{
+		plugins.RuntimeRuncV2: {
+			Options: map[string]interface{}{"SystemdCgroup": "yes"},
+		},
+	}); err == nil {
+		t.Fatal("expected error decoding string into bool option")
+	}
+}
+
+func TestTaskDefaultsApply(t *testing.T) {
+	d, err := newTaskDefaults(map[string]RuntimeDefaults{
+		plugins.RuntimeRuncV2: {
+			Options: map[string]interface{}{
+				"BinaryName":    "/usr/bin/runc",
+				"SystemdCgroup": true,
+			},
+			Enforced: []string{"SystemdCgroup"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Client options: BinaryName wins, SystemdCgroup is enforced, IoUid kept.
+	client, err := typeurl.MarshalAny(&runcoptions.Options{
+		BinaryName: "/opt/bin/runc",
+		IoUid:      5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := runtime.CreateOpts{
+		Runtime:     plugins.RuntimeRuncV2,
+		TaskOptions: client,
+	}
+	if err := d.apply(&opts); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := typeurl.UnmarshalAny(opts.TaskOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ro := merged.(*runcoptions.Options)
+	if ro.BinaryName != "/opt/bin/runc" {
+		t.Errorf("client BinaryName must win, got %q", ro.BinaryName)
+	}
+	if !ro.SystemdCgroup {
+		t.Error("enforced SystemdCgroup must override the client value")
+	}
+	if ro.IoUid != 5 {
+		t.Errorf("client IoUid must be kept, got %d", ro.IoUid)
+	}
+
+	// No client options: the defaults apply as-is.
+	opts = runtime.CreateOpts{Runtime: plugins.RuntimeRuncV2}
+	if err := d.apply(&opts); err != nil {
+		t.Fatal(err)
+	}
+	merged, err = typeurl.UnmarshalAny(opts.TaskOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ro := merged.(*runcoptions.Options); ro.BinaryName != "/usr/bin/runc" || !ro.SystemdCgroup {
+		t.Errorf("expected bare defaults, got %+v", ro)
+	}
+
+	// Runtime without configured defaults is left alone.
+	opts = runtime.CreateOpts{Runtime: "io.containerd.other.v1", TaskOptions: client}
+	if err := d.apply(&opts); err != nil {
+		t.Fatal(err)
+	}
+	if opts.TaskOptions != client {
+		t.Error("options of runtimes without defaults must not be touched")
+	}
+}
+
+func TestTaskDefaultsEffective(t *testing.T) {
+	d, err := newTaskDefaults(map[string]RuntimeDefaults{
+		plugins.RuntimeRuncV2: {
+			Options:  map[string]interface{}{"SystemdCgroup": true},
+			Enforced: []string{"SystemdCgroup"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := d.effective(&EffectiveTaskOptionsRequest{
+		Runtime: plugins.RuntimeRuncV2,
+		Options: map[string]interface{}{
+			"BinaryName":    "/opt/bin/runc",
+			"SystemdCgroup": false,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Options["binary_name"] != "/opt/bin/runc" {
+		t.Errorf("client BinaryName must win, got %v", resp.Options["binary_name"])
+	}
+	if resp.Options["systemd_cgroup"] != true {
+		t.Errorf("enforced SystemdCgroup must override the client value, got %v", resp.Options["systemd_cgroup"])
+	}
+	if len(resp.Enforced) != 1 || resp.Enforced[0] != "SystemdCgroup" {
+		t.Errorf("unexpected enforced list %v", resp.Enforced)
+	}
+}
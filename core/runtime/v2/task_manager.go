@@ -24,6 +24,7 @@ import (
 	"os"
 	"os/exec"
 	"slices"
+	"time"
 
 	"github.com/containerd/errdefs"
 	"github.com/containerd/log"
@@ -37,7 +38,9 @@ import (
 	apitypes "github.com/containerd/containerd/api/types"
 
 	"github.com/basuotian/containerd/core/runtime"
+	"github.com/basuotian/containerd/pkg/namespaces"
 	"github.com/basuotian/containerd/pkg/protobuf/proto"
+	"github.com/basuotian/containerd/pkg/startlatency"
 	"github.com/basuotian/containerd/pkg/timeout"
 	"github.com/basuotian/containerd/plugins"
 )
@@ -46,6 +49,10 @@ import (
 type TaskConfig struct {
 	// Supported platforms
 	Platforms []string `toml:"platforms"`
+	// RuntimeDefaults are default task options per runtime name, merged
+	// into the client-supplied options before the shim is started. Client
+	// values win for fields not listed as enforced.
+	RuntimeDefaults map[string]RuntimeDefaults `toml:"runtime_defaults"`
 }
 
 func init() {
@@ -78,30 +85,37 @@ func init() {
 					return nil, err
 				}
 			}
-			return NewTaskManager(ic.Context, root, state, shimManager)
+			return NewTaskManager(ic.Context, root, state, shimManager, config.RuntimeDefaults)
 		},
 	})
 }
 
 // TaskManager wraps task service client on top of shim manager.
 type TaskManager struct {
-	root    string
-	state   string
-	manager *ShimManager
+	root     string
+	state    string
+	manager  *ShimManager
+	defaults *taskDefaults
 }
 
 // NewTaskManager creates a new task manager instance.
 // root is the rootDir of TaskManager plugin to store persistent data
 // state is the stateDir of TaskManager plugin to store transient data
 // shims is  ShimManager for TaskManager to create/delete shims
-func NewTaskManager(ctx context.Context, root, state string, shims *ShimManager) (*TaskManager, error) {
+// defaults are default task options per runtime name
+func NewTaskManager(ctx context.Context, root, state string, shims *ShimManager, defaults map[string]RuntimeDefaults) (*TaskManager, error) {
 	if err := shims.LoadExistingShims(ctx, state, root); err != nil {
 		return nil, fmt.Errorf("failed to load existing shims for task manager")
 	}
+	d, err := newTaskDefaults(defaults)
+	if err != nil {
+		return nil, err
+	}
 	m := &TaskManager{
-		root:    root,
-		state:   state,
-		manager: shims,
+		root:     root,
+		state:    state,
+		manager:  shims,
+		defaults: d,
 	}
 	return m, nil
 }
@@ -113,6 +127,10 @@ func (m *TaskManager) ID() string {
 
 // Create launches new shim instance and creates new task
 func (m *TaskManager) Create(ctx context.Context, taskID string, opts runtime.CreateOpts) (_ runtime.Task, retErr error) {
+	if err := m.defaults.apply(&opts); err != nil {
+		return nil, fmt.Errorf("failed to apply default task options: %w", err)
+	}
+
 	bundle, err := NewBundle(ctx, m.root, m.state, taskID, opts.Spec)
 	if err != nil {
 		return nil, err
@@ -123,10 +141,14 @@ func (m *TaskManager) Create(ctx context.Context, taskID string, opts runtime.Cr
 		}
 	}()
 
+	ns, _ := namespaces.Namespace(ctx)
+
+	shimStart := time.Now()
 	shim, err := m.manager.Start(ctx, taskID, bundle, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start shim: %w", err)
 	}
+	startlatency.Record(ns, taskID, startlatency.StageShimStart, time.Since(shimStart))
 
 	// Cast to shim task and call task service to create a new container task instance.
 	// This will not be required once shim service / client implemented.
@@ -141,6 +163,7 @@ func (m *TaskManager) Create(ctx context.Context, taskID string, opts runtime.Cr
 		return nil, fmt.Errorf("failed to validate OCI runtime features: %w", err)
 	}
 
+	createStart := time.Now()
 	t, err := func() (runtime.Task, error) {
 		t, err := shimTask.Create(ctx, opts)
 		if err == nil || !errdefs.IsNotImplemented(err) {
@@ -163,6 +186,8 @@ func (m *TaskManager) Create(ctx context.Context, taskID string, opts runtime.Cr
 		return t, err
 	}()
 	if err != nil {
+		startlatency.Discard(ns, taskID)
+
 		// NOTE: ctx contains required namespace information.
 		m.manager.shims.Delete(ctx, taskID)
 
@@ -183,6 +208,7 @@ func (m *TaskManager) Create(ctx context.Context, taskID string, opts runtime.Cr
 
 		return nil, fmt.Errorf("failed to create shim task: %w", err)
 	}
+	startlatency.Record(ns, taskID, startlatency.StageRuntimeCreate, time.Since(createStart))
 
 	return t, nil
 }
@@ -244,6 +270,22 @@ func (m *TaskManager) Delete(ctx context.Context, taskID string) (*runtime.Exit,
 }
 
 func (m *TaskManager) PluginInfo(ctx context.Context, request interface{}) (interface{}, error) {
+	if req, ok := request.(*ResetQuarantineRequest); ok {
+		return &ResetQuarantineResponse{
+			Reset: m.manager.crashes.reset(req.Runtime),
+		}, nil
+	}
+	if req, ok := request.(*EffectiveTaskOptionsRequest); ok {
+		return m.defaults.effective(req)
+	}
+	if req, ok := request.(*ShimLogsRequest); ok {
+		return &ShimLogsResponse{
+			Shims: shimLogs.collect(req.Namespace, req.ID),
+		}, nil
+	}
+	if req, ok := request.(*ShimProcessesRequest); ok {
+		return m.shimProcesses(ctx, req)
+	}
 	req, ok := request.(*apitypes.RuntimeRequest)
 	if !ok {
 		return nil, fmt.Errorf("unknown request type %T: %w", request, errdefs.ErrNotImplemented)
@@ -25,6 +25,20 @@ import (
 	"github.com/containerd/typeurl/v2"
 )
 
+// StateLabel is a well known sandbox label under which clients record the
+// last observed lifecycle state of a sandbox. The metadata store indexes it
+// so sandboxes can be filtered by state, e.g. `state==running`.
+const StateLabel = "containerd.io/sandbox.state"
+
+// Well known values of the StateLabel label. Controllers remain the source
+// of truth for sandbox liveness, the label reflects the last transition the
+// client drove.
+const (
+	StateCreated = "created"
+	StateRunning = "running"
+	StateStopped = "stopped"
+)
+
 // Sandbox is an object stored in metadata database
 type Sandbox struct {
 	// ID uniquely identifies the sandbox in a namespace
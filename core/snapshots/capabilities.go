@@ -0,0 +1,59 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snapshots
+
+// Capabilities are declared by snapshotter plugins in their plugin
+// metadata and discovered by clients through the introspection service,
+// so feature support can be checked up front instead of through type
+// assertions and trial-and-error. Proxy plugins declare capabilities in
+// their proxy plugin configuration and default to none.
+const (
+	// CapabilityRemapIDs is set when the snapshotter shifts filesystem
+	// ownership from the uid/gid mapping labels at mount time, for
+	// example using idmapped mounts.
+	CapabilityRemapIDs = "remap-ids"
+	// CapabilityOnlyRemapIDs is set when the snapshotter only supports
+	// mount time ownership shifting and a recursive chown must not be
+	// used as a fallback.
+	CapabilityOnlyRemapIDs = "only-remap-ids"
+	// CapabilityVolatile is set when the snapshotter honors the volatile
+	// mount option, trading crash consistency for fewer sync operations.
+	CapabilityVolatile = "volatile"
+	// CapabilityAccurateUsage is set when Usage reports exact disk usage
+	// of a snapshot rather than an estimate.
+	CapabilityAccurateUsage = "accurate-usage"
+	// CapabilityCheapClones is set when committing and preparing from a
+	// parent are constant time operations, such as block or filesystem
+	// level snapshots, rather than file copies.
+	CapabilityCheapClones = "cheap-clones"
+	// CapabilityRemoteLazyLoading is set when the snapshotter can
+	// materialize snapshots from remote content without the layer blobs
+	// being present in the local content store.
+	CapabilityRemoteLazyLoading = "remote-lazy-loading"
+)
+
+// KnownCapability returns true when the capability name is one defined
+// by containerd. Snapshotters are free to report additional names, this
+// is used to flag likely misspellings in proxy plugin configuration.
+func KnownCapability(c string) bool {
+	switch c {
+	case CapabilityRemapIDs, CapabilityOnlyRemapIDs, CapabilityVolatile,
+		CapabilityAccurateUsage, CapabilityCheapClones, CapabilityRemoteLazyLoading:
+		return true
+	}
+	return false
+}
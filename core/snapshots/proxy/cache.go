@@ -0,0 +1,119 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/basuotian/containerd/core/mount"
+	"github.com/basuotian/containerd/core/snapshots"
+)
+
+// cacheEntry holds the cached results of Stat and Mounts calls for a single
+// snapshot key. Either field may be unset if only the other call has been
+// made for the key.
+type cacheEntry struct {
+	info      *snapshots.Info
+	mounts    []mount.Mount
+	expiresAt time.Time
+}
+
+// snapshotCache is a TTL-bounded cache of Stat and Mounts results keyed by
+// snapshot key. Entries are invalidated by any local mutation of the key
+// (Update, Commit, Remove) and expire after the configured TTL to bound
+// staleness caused by out-of-band changes on the remote snapshotter.
+type snapshotCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+}
+
+func newSnapshotCache(ttl time.Duration) *snapshotCache {
+	return &snapshotCache{
+		ttl:     ttl,
+		entries: map[string]*cacheEntry{},
+	}
+}
+
+func (c *snapshotCache) getEntry(key string) *cacheEntry {
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		return nil
+	}
+	return e
+}
+
+func (c *snapshotCache) getInfo(key string) (snapshots.Info, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.getEntry(key); e != nil && e.info != nil {
+		return *e.info, true
+	}
+	return snapshots.Info{}, false
+}
+
+func (c *snapshotCache) putInfo(key string, info snapshots.Info) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.getEntry(key)
+	if e == nil {
+		e = &cacheEntry{}
+		c.entries[key] = e
+	}
+	e.info = &info
+	e.expiresAt = time.Now().Add(c.ttl)
+}
+
+func (c *snapshotCache) getMounts(key string) ([]mount.Mount, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.getEntry(key); e != nil && e.mounts != nil {
+		return e.mounts, true
+	}
+	return nil, false
+}
+
+func (c *snapshotCache) putMounts(key string, mounts []mount.Mount) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.getEntry(key)
+	if e == nil {
+		e = &cacheEntry{}
+		c.entries[key] = e
+	}
+	e.mounts = mounts
+	e.expiresAt = time.Now().Add(c.ttl)
+}
+
+func (c *snapshotCache) invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
+func (c *snapshotCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*cacheEntry{}
+}
@@ -19,6 +19,7 @@ package proxy
 import (
 	"context"
 	"io"
+	"time"
 
 	snapshotsapi "github.com/containerd/containerd/api/services/snapshots/v1"
 	"github.com/containerd/errdefs/pkg/errgrpc"
@@ -28,21 +29,66 @@ import (
 	protobuftypes "github.com/basuotian/containerd/pkg/protobuf/types"
 )
 
+// Opt allows customizing the behavior of the proxy snapshotter.
+type Opt func(*proxySnapshotter)
+
+// WithCache enables client-side caching of Stat and Mounts results keyed by
+// snapshot key. Cached entries are invalidated by local mutations (Update,
+// Commit, Remove) and expire after the provided TTL. Callers subscribed to
+// snapshot events may additionally invalidate entries through the
+// CacheInvalidator interface implemented by the returned snapshotter.
+func WithCache(ttl time.Duration) Opt {
+	return func(p *proxySnapshotter) {
+		p.cache = newSnapshotCache(ttl)
+	}
+}
+
+// CacheInvalidator is implemented by proxy snapshotters constructed with
+// WithCache. InvalidateCache drops any cached state for the given keys, or
+// the entire cache when called without arguments, and is intended to be
+// driven by snapshot events received out of band.
+type CacheInvalidator interface {
+	InvalidateCache(keys ...string)
+}
+
 // NewSnapshotter returns a new Snapshotter which communicates over a GRPC
 // connection using the containerd snapshot GRPC API.
-func NewSnapshotter(client snapshotsapi.SnapshotsClient, snapshotterName string) snapshots.Snapshotter {
-	return &proxySnapshotter{
+func NewSnapshotter(client snapshotsapi.SnapshotsClient, snapshotterName string, opts ...Opt) snapshots.Snapshotter {
+	p := &proxySnapshotter{
 		client:          client,
 		snapshotterName: snapshotterName,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 type proxySnapshotter struct {
 	client          snapshotsapi.SnapshotsClient
 	snapshotterName string
+	cache           *snapshotCache
+}
+
+// InvalidateCache implements CacheInvalidator. It is a no-op when caching is
+// not enabled.
+func (p *proxySnapshotter) InvalidateCache(keys ...string) {
+	if p.cache == nil {
+		return
+	}
+	if len(keys) == 0 {
+		p.cache.invalidateAll()
+		return
+	}
+	p.cache.invalidate(keys...)
 }
 
 func (p *proxySnapshotter) Stat(ctx context.Context, key string) (snapshots.Info, error) {
+	if p.cache != nil {
+		if info, ok := p.cache.getInfo(key); ok {
+			return info, nil
+		}
+	}
 	resp, err := p.client.Stat(ctx,
 		&snapshotsapi.StatSnapshotRequest{
 			Snapshotter: p.snapshotterName,
@@ -51,7 +97,11 @@ func (p *proxySnapshotter) Stat(ctx context.Context, key string) (snapshots.Info
 	if err != nil {
 		return snapshots.Info{}, errgrpc.ToNative(err)
 	}
-	return InfoFromProto(resp.Info), nil
+	info := InfoFromProto(resp.Info)
+	if p.cache != nil {
+		p.cache.putInfo(key, info)
+	}
+	return info, nil
 }
 
 func (p *proxySnapshotter) Update(ctx context.Context, info snapshots.Info, fieldpaths ...string) (snapshots.Info, error) {
@@ -66,6 +116,9 @@ func (p *proxySnapshotter) Update(ctx context.Context, info snapshots.Info, fiel
 	if err != nil {
 		return snapshots.Info{}, errgrpc.ToNative(err)
 	}
+	if p.cache != nil {
+		p.cache.invalidate(info.Name)
+	}
 	return InfoFromProto(resp.Info), nil
 }
 
@@ -81,6 +134,11 @@ func (p *proxySnapshotter) Usage(ctx context.Context, key string) (snapshots.Usa
 }
 
 func (p *proxySnapshotter) Mounts(ctx context.Context, key string) ([]mount.Mount, error) {
+	if p.cache != nil {
+		if mounts, ok := p.cache.getMounts(key); ok {
+			return mounts, nil
+		}
+	}
 	resp, err := p.client.Mounts(ctx, &snapshotsapi.MountsRequest{
 		Snapshotter: p.snapshotterName,
 		Key:         key,
@@ -88,7 +146,11 @@ func (p *proxySnapshotter) Mounts(ctx context.Context, key string) ([]mount.Moun
 	if err != nil {
 		return nil, errgrpc.ToNative(err)
 	}
-	return mount.FromProto(resp.Mounts), nil
+	mounts := mount.FromProto(resp.Mounts)
+	if p.cache != nil {
+		p.cache.putMounts(key, mounts)
+	}
+	return mounts, nil
 }
 
 func (p *proxySnapshotter) Prepare(ctx context.Context, key, parent string, opts ...snapshots.Opt) ([]mount.Mount, error) {
@@ -142,6 +204,9 @@ func (p *proxySnapshotter) Commit(ctx context.Context, name, key string, opts ..
 		Key:         key,
 		Labels:      local.Labels,
 	})
+	if err == nil && p.cache != nil {
+		p.cache.invalidate(name, key)
+	}
 	return errgrpc.ToNative(err)
 }
 
@@ -150,6 +215,9 @@ func (p *proxySnapshotter) Remove(ctx context.Context, key string) error {
 		Snapshotter: p.snapshotterName,
 		Key:         key,
 	})
+	if err == nil && p.cache != nil {
+		p.cache.invalidate(key)
+	}
 	return errgrpc.ToNative(err)
 }
 
@@ -188,5 +256,8 @@ func (p *proxySnapshotter) Cleanup(ctx context.Context) error {
 	_, err := p.client.Cleanup(ctx, &snapshotsapi.CleanupRequest{
 		Snapshotter: p.snapshotterName,
 	})
+	if err == nil && p.cache != nil {
+		p.cache.invalidateAll()
+	}
 	return errgrpc.ToNative(err)
 }
@@ -0,0 +1,233 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+func init() {
+	typeurl.Register(&CompactRequest{}, "containerd.snapshots.storage.CompactRequest")
+	typeurl.Register(&CompactResponse{}, "containerd.snapshots.storage.CompactResponse")
+	typeurl.Register(&DBStatsRequest{}, "containerd.snapshots.storage.DBStatsRequest")
+	typeurl.Register(&DBStatsResponse{}, "containerd.snapshots.storage.DBStatsResponse")
+}
+
+// compactTxMaxSize bounds the size of the copy transactions used during
+// compaction so large databases do not buffer entirely in memory.
+const compactTxMaxSize = 1 << 20
+
+// CompactRequest asks a snapshotter plugin to compact its metastore,
+// sent through the introspection PluginInfo API.
+type CompactRequest struct{}
+
+// CompactResponse reports the result of a metastore compaction.
+type CompactResponse struct {
+	// SizeBefore is the database file size before compaction in bytes.
+	SizeBefore int64 `json:"size_before"`
+	// SizeAfter is the database file size after compaction in bytes.
+	SizeAfter int64 `json:"size_after"`
+	// Duration is how long the database was quiesced, in nanoseconds.
+	Duration time.Duration `json:"duration"`
+}
+
+// DBStatsRequest asks a snapshotter plugin for metastore database
+// statistics, sent through the introspection PluginInfo API.
+type DBStatsRequest struct{}
+
+// DBStatsResponse describes the size and fragmentation of a metastore
+// database file.
+type DBStatsResponse struct {
+	// Path of the database file.
+	Path string `json:"path"`
+	// Size of the database file in bytes.
+	Size int64 `json:"size"`
+	// PageSize of the database in bytes.
+	PageSize int `json:"page_size"`
+	// FreePages is the number of free pages on the freelist.
+	FreePages int `json:"free_pages"`
+	// PendingPages is the number of pending pages on the freelist.
+	PendingPages int `json:"pending_pages"`
+	// Fragmentation is the fraction of the file taken by free and
+	// pending pages, between 0 and 1.
+	Fragmentation float64 `json:"fragmentation"`
+}
+
+// Compact rewrites the database file without its free pages, briefly
+// quiescing the metastore: new transactions block while in-flight ones
+// are waited for, then the compacted copy atomically replaces the file.
+func (ms *MetaStore) Compact(ctx context.Context) (_ *CompactResponse, retErr error) {
+	ms.dbL.Lock()
+	defer ms.dbL.Unlock()
+
+	start := time.Now()
+	if ms.db != nil {
+		// Close waits for in-flight transactions to finish.
+		if err := ms.db.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close database for compaction: %w", err)
+		}
+		ms.db = nil
+	}
+
+	st, err := os.Stat(ms.dbfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("database %s not created yet: %w", ms.dbfile, errdefs.ErrNotFound)
+		}
+		return nil, err
+	}
+	sizeBefore := st.Size()
+
+	srcOpts := ms.opts
+	srcOpts.ReadOnly = true
+	src, err := bolt.Open(ms.dbfile, 0600, &srcOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database for compaction: %w", err)
+	}
+	defer src.Close()
+
+	tmpfile := ms.dbfile + ".compact"
+	if err := os.RemoveAll(tmpfile); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if retErr != nil {
+			os.RemoveAll(tmpfile)
+		}
+	}()
+	dst, err := bolt.Open(tmpfile, 0600, &ms.opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compacted database: %w", err)
+	}
+	if err := bolt.Compact(dst, src, compactTxMaxSize); err != nil {
+		dst.Close()
+		return nil, fmt.Errorf("failed to compact database: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpfile, ms.dbfile); err != nil {
+		return nil, fmt.Errorf("failed to replace database with compacted copy: %w", err)
+	}
+
+	sizeAfter := sizeBefore
+	if st, err := os.Stat(ms.dbfile); err == nil {
+		sizeAfter = st.Size()
+	}
+	updateDBMetrics(ms.dbfile, sizeAfter, 0, 0, 0)
+
+	resp := &CompactResponse{
+		SizeBefore: sizeBefore,
+		SizeAfter:  sizeAfter,
+		Duration:   time.Since(start),
+	}
+	log.G(ctx).WithFields(log.Fields{
+		"db":          ms.dbfile,
+		"size_before": resp.SizeBefore,
+		"size_after":  resp.SizeAfter,
+		"duration":    resp.Duration,
+	}).Info("compacted snapshotter metastore")
+	return resp, nil
+}
+
+// DBStats returns size and fragmentation statistics for the database
+// file and refreshes the corresponding metrics.
+func (ms *MetaStore) DBStats(ctx context.Context) (*DBStatsResponse, error) {
+	st, err := os.Stat(ms.dbfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("database %s not created yet: %w", ms.dbfile, errdefs.ErrNotFound)
+		}
+		return nil, err
+	}
+
+	ms.dbL.Lock()
+	if ms.db == nil {
+		db, err := bolt.Open(ms.dbfile, 0600, &ms.opts)
+		if err != nil {
+			ms.dbL.Unlock()
+			return nil, fmt.Errorf("failed to open database file: %w", err)
+		}
+		ms.db = db
+	}
+	db := ms.db
+	ms.dbL.Unlock()
+
+	stats := db.Stats()
+	pageSize := db.Info().PageSize
+	resp := &DBStatsResponse{
+		Path:         ms.dbfile,
+		Size:         st.Size(),
+		PageSize:     pageSize,
+		FreePages:    stats.FreePageN,
+		PendingPages: stats.PendingPageN,
+	}
+	if resp.Size > 0 {
+		resp.Fragmentation = float64((stats.FreePageN+stats.PendingPageN)*pageSize) / float64(resp.Size)
+	}
+	updateDBMetrics(ms.dbfile, resp.Size, resp.FreePages, resp.PendingPages, resp.Fragmentation)
+	return resp, nil
+}
+
+// ScheduleCompaction compacts the database every interval until the
+// returned stop function is called or ctx is done.
+func (ms *MetaStore) ScheduleCompaction(ctx context.Context, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := ms.Compact(ctx); err != nil && !errdefs.IsNotFound(err) {
+					log.G(ctx).WithError(err).Warnf("scheduled compaction of %s failed", ms.dbfile)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// PluginInfo handles introspection requests for the metastore, allowing
+// snapshotter plugins to expose compaction and database statistics.
+func (ms *MetaStore) PluginInfo(ctx context.Context, request interface{}) (interface{}, error) {
+	switch request.(type) {
+	case *CompactRequest:
+		return ms.Compact(ctx)
+	case *DBStatsRequest:
+		return ms.DBStats(ctx)
+	}
+	return nil, fmt.Errorf("unknown request type %T: %w", request, errdefs.ErrNotImplemented)
+}
@@ -0,0 +1,113 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/basuotian/containerd/core/snapshots"
+	"github.com/containerd/errdefs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompact(t *testing.T) {
+	ctx := context.Background()
+	ms, err := NewMetaStore(filepath.Join(t.TempDir(), "metadata.db"))
+	require.NoError(t, err)
+	defer ms.Close()
+
+	// Create and remove snapshots to leave free pages behind.
+	err = ms.WithTransaction(ctx, true, func(ctx context.Context) error {
+		for i := 0; i < 100; i++ {
+			if _, err := CreateSnapshot(ctx, snapshots.KindActive, fmt.Sprintf("tmp-%d", i), ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	err = ms.WithTransaction(ctx, true, func(ctx context.Context) error {
+		for i := 0; i < 100; i++ {
+			if _, _, err := Remove(ctx, fmt.Sprintf("tmp-%d", i)); err != nil {
+				return err
+			}
+		}
+		_, err := CreateSnapshot(ctx, snapshots.KindActive, "keep", "")
+		return err
+	})
+	require.NoError(t, err)
+
+	stats, err := ms.DBStats(ctx)
+	require.NoError(t, err)
+	freeBefore := stats.FreePages + stats.PendingPages
+	assert.Positive(t, freeBefore)
+
+	resp, err := ms.Compact(ctx)
+	require.NoError(t, err)
+	assert.Positive(t, resp.SizeBefore)
+	assert.LessOrEqual(t, resp.SizeAfter, resp.SizeBefore)
+
+	// The surviving snapshot is intact in the compacted database.
+	err = ms.WithTransaction(ctx, false, func(ctx context.Context) error {
+		_, info, _, err := GetInfo(ctx, "keep")
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, "keep", info.Name)
+		return nil
+	})
+	require.NoError(t, err)
+
+	stats, err = ms.DBStats(ctx)
+	require.NoError(t, err)
+	assert.Less(t, stats.FreePages+stats.PendingPages, freeBefore)
+}
+
+func TestCompactNotCreated(t *testing.T) {
+	ms, err := NewMetaStore(filepath.Join(t.TempDir(), "metadata.db"))
+	require.NoError(t, err)
+	defer ms.Close()
+
+	_, err = ms.Compact(context.Background())
+	assert.True(t, errdefs.IsNotFound(err))
+}
+
+func TestPluginInfoDispatch(t *testing.T) {
+	ctx := context.Background()
+	ms, err := NewMetaStore(filepath.Join(t.TempDir(), "metadata.db"))
+	require.NoError(t, err)
+	defer ms.Close()
+
+	require.NoError(t, ms.WithTransaction(ctx, true, func(ctx context.Context) error {
+		_, err := CreateSnapshot(ctx, snapshots.KindActive, "a", "")
+		return err
+	}))
+
+	info, err := ms.PluginInfo(ctx, &DBStatsRequest{})
+	require.NoError(t, err)
+	stats, ok := info.(*DBStatsResponse)
+	require.True(t, ok)
+	assert.Equal(t, ms.dbfile, stats.Path)
+	assert.Positive(t, stats.Size)
+
+	_, err = ms.PluginInfo(ctx, "bogus")
+	assert.True(t, errdefs.IsNotImplemented(err))
+}
@@ -0,0 +1,41 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package storage
+
+import (
+	metrics "github.com/docker/go-metrics"
+)
+
+var (
+	dbSizeGauge          metrics.LabeledGauge
+	dbFreePagesGauge     metrics.LabeledGauge
+	dbFragmentationGauge metrics.LabeledGauge
+)
+
+func init() {
+	ns := metrics.NewNamespace("containerd", "snapshots_metastore", nil)
+	dbSizeGauge = ns.NewLabeledGauge("db_size", "snapshotter metastore database file size", metrics.Bytes, "db")
+	dbFreePagesGauge = ns.NewLabeledGauge("db_free_pages", "number of free and pending pages in the snapshotter metastore database", metrics.Total, "db")
+	dbFragmentationGauge = ns.NewLabeledGauge("db_fragmentation", "fraction of the snapshotter metastore database file taken by free and pending pages", metrics.Total, "db")
+	metrics.Register(ns)
+}
+
+func updateDBMetrics(db string, size int64, freePages, pendingPages int, fragmentation float64) {
+	dbSizeGauge.WithValues(db).Set(float64(size))
+	dbFreePagesGauge.WithValues(db).Set(float64(freePages + pendingPages))
+	dbFragmentationGauge.WithValues(db).Set(fragmentation)
+}
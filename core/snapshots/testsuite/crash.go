@@ -0,0 +1,278 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package testsuite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/basuotian/containerd/core/snapshots"
+	"github.com/basuotian/containerd/internal/failpoint"
+	"github.com/basuotian/containerd/pkg/namespaces"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log/logtest"
+)
+
+// crashStep is a single snapshotter operation in a crash scenario. The
+// harness injects a simulated crash between steps and verifies that the
+// snapshotter recovers to a consistent state.
+type crashStep struct {
+	name string
+	fn   func(ctx context.Context, h *crashHarness) error
+}
+
+// crashHarness drives a crash scenario. It owns the snapshotter instance
+// and the expected durable state; when its failpoint fires, the snapshotter
+// is shut down and reopened from the same root, after which recoverability
+// invariants are checked before the scenario continues.
+type crashHarness struct {
+	t             *testing.T
+	root          string
+	snapshotterFn SnapshotterFunc
+	fp            *failpoint.Failpoint
+
+	sn      snapshots.Snapshotter
+	cleanup func() error
+
+	// expected durable state, updated as steps complete.
+	committed []string
+	active    []string
+}
+
+func (h *crashHarness) open(ctx context.Context) error {
+	sn, cleanup, err := h.snapshotterFn(ctx, h.root)
+	if err != nil {
+		return err
+	}
+	h.sn, h.cleanup = sn, cleanup
+	return nil
+}
+
+func (h *crashHarness) close() {
+	if h.cleanup != nil {
+		if err := h.cleanup(); err != nil {
+			h.t.Errorf("cleanup failed: %v", err)
+		}
+		h.cleanup = nil
+	}
+}
+
+// maybeCrash evaluates the failpoint before the named step. When it fires,
+// the snapshotter is killed and restarted and the recoverability invariants
+// are asserted against the state expected to be durable at this point.
+func (h *crashHarness) maybeCrash(ctx context.Context, step string) error {
+	if err := h.fp.Evaluate(); err == nil {
+		return nil
+	}
+	h.t.Logf("injecting crash before step %q", step)
+
+	h.close()
+	if err := h.open(ctx); err != nil {
+		return fmt.Errorf("failed to restart snapshotter before step %q: %w", step, err)
+	}
+	return h.checkRecovered(ctx, step)
+}
+
+// checkRecovered asserts the invariants every snapshotter must hold after a
+// restart: Walk succeeds, every previously committed snapshot is still
+// committed, and every active snapshot either survived as active or is
+// cleanly gone.
+func (h *crashHarness) checkRecovered(ctx context.Context, step string) error {
+	seen := map[string]snapshots.Kind{}
+	if err := h.sn.Walk(ctx, func(_ context.Context, info snapshots.Info) error {
+		seen[info.Name] = info.Kind
+		return nil
+	}); err != nil && !errdefs.IsNotFound(err) {
+		// NotFound is how an empty metadata store reports itself.
+		return fmt.Errorf("walk failed after crash before step %q: %w", step, err)
+	}
+
+	for _, key := range h.committed {
+		info, err := h.sn.Stat(ctx, key)
+		if err != nil {
+			return fmt.Errorf("committed snapshot %q lost after crash before step %q: %w", key, step, err)
+		}
+		if info.Kind != snapshots.KindCommitted {
+			return fmt.Errorf("committed snapshot %q recovered as %v after crash before step %q", key, info.Kind, step)
+		}
+	}
+
+	for _, key := range h.active {
+		info, err := h.sn.Stat(ctx, key)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				// An active snapshot may legitimately not survive a
+				// crash, but then it must be fully gone.
+				if kind, ok := seen[key]; ok {
+					return fmt.Errorf("active snapshot %q not statable but walked as %v after crash before step %q", key, kind, step)
+				}
+				continue
+			}
+			return fmt.Errorf("failed to stat active snapshot %q after crash before step %q: %w", key, step, err)
+		}
+		if info.Kind != snapshots.KindActive {
+			return fmt.Errorf("active snapshot %q recovered as %v after crash before step %q", key, info.Kind, step)
+		}
+	}
+
+	// The snapshotter must still accept new work.
+	probe := "crash-probe-" + step
+	if _, err := h.sn.Prepare(ctx, probe, "", opt); err != nil {
+		return fmt.Errorf("failed to prepare new snapshot after crash before step %q: %w", step, err)
+	}
+	if err := h.sn.Remove(ctx, probe); err != nil {
+		return fmt.Errorf("failed to remove probe snapshot after crash before step %q: %w", step, err)
+	}
+	return nil
+}
+
+// lifecycleSteps is the canonical snapshot lifecycle which the crash suite
+// replays with a crash injected between every pair of operations.
+func lifecycleSteps() []crashStep {
+	removeActive := func(key string) func(context.Context, *crashHarness) error {
+		return func(ctx context.Context, h *crashHarness) error {
+			if err := h.sn.Remove(ctx, key); err != nil && !errdefs.IsNotFound(err) {
+				return err
+			}
+			for i, a := range h.active {
+				if a == key {
+					h.active = append(h.active[:i], h.active[i+1:]...)
+					break
+				}
+			}
+			return nil
+		}
+	}
+	return []crashStep{
+		{
+			name: "prepare-base",
+			fn: func(ctx context.Context, h *crashHarness) error {
+				if _, err := h.sn.Prepare(ctx, "prep-base", "", opt); err != nil {
+					return err
+				}
+				h.active = append(h.active, "prep-base")
+				return nil
+			},
+		},
+		{
+			name: "commit-base",
+			fn: func(ctx context.Context, h *crashHarness) error {
+				// The active snapshot may not have survived an earlier
+				// injected crash; recreate it if necessary.
+				if _, err := h.sn.Stat(ctx, "prep-base"); errdefs.IsNotFound(err) {
+					if _, err := h.sn.Prepare(ctx, "prep-base", "", opt); err != nil {
+						return err
+					}
+				}
+				if err := h.sn.Commit(ctx, "base", "prep-base", opt); err != nil {
+					return err
+				}
+				h.committed = append(h.committed, "base")
+				for i, a := range h.active {
+					if a == "prep-base" {
+						h.active = append(h.active[:i], h.active[i+1:]...)
+						break
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name: "prepare-child",
+			fn: func(ctx context.Context, h *crashHarness) error {
+				if _, err := h.sn.Prepare(ctx, "prep-child", "base", opt); err != nil {
+					return err
+				}
+				h.active = append(h.active, "prep-child")
+				return nil
+			},
+		},
+		{
+			name: "remove-child",
+			fn:   removeActive("prep-child"),
+		},
+		{
+			name: "remove-base",
+			fn: func(ctx context.Context, h *crashHarness) error {
+				if err := h.sn.Remove(ctx, "base"); err != nil {
+					return err
+				}
+				h.committed = nil
+				return nil
+			},
+		},
+	}
+}
+
+// checkSnapshotterCrashConsistency replays the snapshot lifecycle once per
+// possible crash position, killing and restarting the snapshotter at that
+// position and asserting that it recovers to a consistent state each time.
+func checkSnapshotterCrashConsistency(name string, snapshotterFn SnapshotterFunc) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+
+		steps := lifecycleSteps()
+		// Crash position 0 fires before the first step, position
+		// len(steps) after the last one.
+		for position := 0; position <= len(steps); position++ {
+			t.Run(fmt.Sprintf("CrashAt%d", position), func(t *testing.T) {
+				ctx := logtest.WithT(context.Background(), t)
+				ctx = namespaces.WithNamespace(ctx, "testsuite")
+
+				tmpDir, err := os.MkdirTemp("", "snapshot-crash-"+name+"-")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.RemoveAll(tmpDir)
+				root := filepath.Join(tmpDir, "root")
+				if err := os.MkdirAll(root, 0777); err != nil {
+					t.Fatal(err)
+				}
+
+				fp, err := failpoint.NewFailpoint(t.Name(), fmt.Sprintf("%d*off->1*error(crash)", position))
+				if err != nil {
+					t.Fatal(err)
+				}
+				h := &crashHarness{
+					t:             t,
+					root:          root,
+					snapshotterFn: snapshotterFn,
+					fp:            fp,
+				}
+				if err := h.open(ctx); err != nil {
+					t.Fatalf("failed to initialize snapshotter: %+v", err)
+				}
+				defer h.close()
+
+				for _, step := range steps {
+					if err := h.maybeCrash(ctx, step.name); err != nil {
+						t.Fatal(err)
+					}
+					if err := step.fn(ctx, h); err != nil {
+						t.Fatalf("step %q failed: %v", step.name, err)
+					}
+				}
+				if err := h.maybeCrash(ctx, "end"); err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	}
+}
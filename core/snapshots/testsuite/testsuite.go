@@ -73,6 +73,8 @@ func SnapshotterSuite(t *testing.T, name string, snapshotterFn SnapshotterFunc)
 	// Different snapshotters behave slightly differently in the tests below.
 	t.Run("Rename", makeTest(name, snapshotterFn, checkRename(name)))
 	t.Run("128LayersMount", makeTest(name, snapshotterFn, check128LayersMount(name)))
+
+	t.Run("CrashConsistency", checkSnapshotterCrashConsistency(name, snapshotterFn))
 }
 
 func makeTest(
@@ -19,6 +19,7 @@ package image
 import (
 	"context"
 	"fmt"
+	"path"
 
 	"github.com/containerd/typeurl/v2"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -52,6 +53,10 @@ type Store struct {
 	// extraReferences are used to store or lookup multiple references
 	extraReferences []Reference
 
+	// immutableTags are image name patterns which may not be re-pointed
+	// to a different target by a store operation
+	immutableTags []string
+
 	unpacks []transfer.UnpackConfiguration
 }
 
@@ -161,6 +166,17 @@ func WithExtraReference(name string) StoreOpt {
 	}
 }
 
+// WithImmutableTags marks image names matching any of the given patterns
+// (path.Match syntax) as immutable. Storing a matching name which already
+// exists with a different target fails with ErrAlreadyExists instead of
+// re-pointing the tag. The option only applies to stores performed with
+// this Store and is not carried across the transfer API.
+func WithImmutableTags(patterns ...string) StoreOpt {
+	return func(s *Store) {
+		s.immutableTags = append(s.immutableTags, patterns...)
+	}
+}
+
 // WithUnpack specifies a platform to unpack for and an optional snapshotter to use
 func WithUnpack(p ocispec.Platform, snapshotter string) StoreOpt {
 	return func(s *Store) {
@@ -301,6 +317,20 @@ func (is *Store) Store(ctx context.Context, desc ocispec.Descriptor, store image
 				return nil, err
 			}
 
+			if is.isImmutable(imgs[i].Name) {
+				existing, err := store.Get(ctx, imgs[i].Name)
+				if err != nil {
+					// if image was removed, try create again
+					if errdefs.IsNotFound(err) {
+						continue
+					}
+					return nil, err
+				}
+				if existing.Target.Digest != imgs[i].Target.Digest {
+					return nil, fmt.Errorf("cannot re-point immutable tag %q: %w", imgs[i].Name, errdefs.ErrAlreadyExists)
+				}
+			}
+
 			updated, err := store.Update(ctx, imgs[i])
 			if err != nil {
 				// if image was removed, try create again
@@ -322,6 +352,15 @@ func (is *Store) Store(ctx context.Context, desc ocispec.Descriptor, store image
 	return imgs, nil
 }
 
+func (is *Store) isImmutable(name string) bool {
+	for _, pattern := range is.immutableTags {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (is *Store) Get(ctx context.Context, store images.Store) (images.Image, error) {
 	return store.Get(ctx, is.imageName)
 }
@@ -0,0 +1,341 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package layout provides a transfer source and destination backed by an
+// OCI image layout directory, allowing import and export without going
+// through a tar stream. The directory must be reachable by the transfer
+// service performing the operation.
+package layout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+	"github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/images"
+	"github.com/basuotian/containerd/core/streaming"
+	"github.com/basuotian/containerd/core/transfer/plugins"
+)
+
+// imageLayoutSpec is the wire representation of an ImageLayout, used to
+// send the type through the transfer API. The directory path only has
+// meaning on the host running the transfer service.
+type imageLayoutSpec struct {
+	Path string `json:"path"`
+}
+
+func init() {
+	typeurl.Register(&imageLayoutSpec{}, "containerd.transfer.ImageLayout")
+	plugins.Register(&imageLayoutSpec{}, &ImageLayout{})
+}
+
+// NewImageLayout returns an OCI image layout directory at the given path
+// which may be used as an import source or export destination. On export
+// the directory is created if it does not exist.
+func NewImageLayout(path string) *ImageLayout {
+	return &ImageLayout{
+		path: path,
+	}
+}
+
+type ImageLayout struct {
+	path string
+}
+
+// Import ingests the blobs referenced by the layout's index into the
+// content store and returns the index descriptor. The caller is expected
+// to hold a lease covering the ingested content.
+func (il *ImageLayout) Import(ctx context.Context, store content.Store) (ocispec.Descriptor, error) {
+	var layout ocispec.ImageLayout
+	lb, err := os.ReadFile(filepath.Join(il.path, ocispec.ImageLayoutFile))
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("unable to read OCI layout file: %w", err)
+	}
+	if err := json.Unmarshal(lb, &layout); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("unable to parse OCI layout file: %w", err)
+	}
+	if layout.Version != ocispec.ImageLayoutVersion {
+		return ocispec.Descriptor{}, fmt.Errorf("unsupported OCI image layout version %q: %w", layout.Version, errdefs.ErrInvalidArgument)
+	}
+
+	ib, err := os.ReadFile(filepath.Join(il.path, ocispec.ImageIndexFile))
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("unable to read OCI layout index: %w", err)
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(ib, &idx); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("unable to parse OCI layout index: %w", err)
+	}
+	index := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(ib),
+		Size:      int64(len(ib)),
+	}
+	if err := content.WriteBlob(ctx, store, "layout-index-"+index.Digest.String(), bytes.NewReader(ib), index); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("unable to store index: %w", err)
+	}
+
+	provider := &layoutProvider{path: il.path}
+	handler := images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		if desc.Digest == index.Digest {
+			// The root index exists as index.json rather than a blob.
+			return idx.Manifests, nil
+		}
+		if err := il.importBlob(ctx, store, desc); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// Sparse layouts may omit blobs such as foreign
+				// layers, leave them to be resolved elsewhere.
+				log.G(ctx).WithField("digest", desc.Digest).Debug("blob not present in layout, skipping")
+				return nil, images.ErrSkipDesc
+			}
+			return nil, err
+		}
+		children, err := images.Children(ctx, provider, desc)
+		if err != nil && errors.Is(err, fs.ErrNotExist) {
+			return nil, images.ErrSkipDesc
+		}
+		return children, err
+	})
+	if err := images.Walk(ctx, handler, index); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	return index, nil
+}
+
+func (il *ImageLayout) importBlob(ctx context.Context, store content.Store, desc ocispec.Descriptor) error {
+	p, err := blobPath(il.path, desc.Digest)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if desc.Size == 0 {
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		desc.Size = fi.Size()
+	}
+	err = content.WriteBlob(ctx, store, "layout-"+desc.Digest.String(), f, desc)
+	if err != nil && !errdefs.IsAlreadyExists(err) {
+		return fmt.Errorf("unable to store blob %v: %w", desc.Digest, err)
+	}
+	return nil
+}
+
+// Export writes the images and their content into the layout directory,
+// merging with any index already present. Content missing from the store,
+// such as layers for platforms which were never pulled, is skipped.
+func (il *ImageLayout) Export(ctx context.Context, cs content.Store, imgs []images.Image) error {
+	if err := os.MkdirAll(il.path, 0755); err != nil {
+		return err
+	}
+
+	index := ocispec.Index{
+		Versioned: ocispecs.Versioned{
+			SchemaVersion: 2,
+		},
+		MediaType: ocispec.MediaTypeImageIndex,
+	}
+	// Merge with an existing index so a layout can accumulate images
+	// from multiple exports.
+	if ib, err := os.ReadFile(filepath.Join(il.path, ocispec.ImageIndexFile)); err == nil {
+		if err := json.Unmarshal(ib, &index); err != nil {
+			return fmt.Errorf("unable to parse OCI layout index: %w", err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	handler := images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		if err := il.exportBlob(ctx, cs, desc); err != nil {
+			if errdefs.IsNotFound(err) {
+				log.G(ctx).WithField("digest", desc.Digest).Debug("blob not present in store, skipping")
+				return nil, images.ErrSkipDesc
+			}
+			return nil, err
+		}
+		children, err := images.Children(ctx, cs, desc)
+		if err != nil && errdefs.IsNotFound(err) {
+			return nil, images.ErrSkipDesc
+		}
+		return children, err
+	})
+
+	for _, img := range imgs {
+		if err := images.Walk(ctx, handler, img.Target); err != nil {
+			return err
+		}
+		desc := img.Target
+		desc.Annotations = make(map[string]string, len(img.Target.Annotations)+1)
+		for k, v := range img.Target.Annotations {
+			desc.Annotations[k] = v
+		}
+		desc.Annotations[ocispec.AnnotationRefName] = img.Name
+		index.Manifests = appendDescriptor(index.Manifests, desc)
+	}
+
+	layout, err := json.Marshal(ocispec.ImageLayout{
+		Version: ocispec.ImageLayoutVersion,
+	})
+	if err != nil {
+		return err
+	}
+	if err := atomicWriteFile(filepath.Join(il.path, ocispec.ImageLayoutFile), layout); err != nil {
+		return err
+	}
+	ib, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return atomicWriteFile(filepath.Join(il.path, ocispec.ImageIndexFile), ib)
+}
+
+func (il *ImageLayout) exportBlob(ctx context.Context, cs content.Store, desc ocispec.Descriptor) error {
+	p, err := blobPath(il.path, desc.Digest)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(p); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	ra, err := cs.ReaderAt(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer ra.Close()
+	return atomicWriteFrom(p, content.NewReader(ra))
+}
+
+func (il *ImageLayout) MarshalAny(ctx context.Context, sm streaming.StreamCreator) (typeurl.Any, error) {
+	return typeurl.MarshalAny(&imageLayoutSpec{
+		Path: il.path,
+	})
+}
+
+func (il *ImageLayout) UnmarshalAny(ctx context.Context, sm streaming.StreamGetter, anyType typeurl.Any) error {
+	var s imageLayoutSpec
+	if err := typeurl.UnmarshalTo(anyType, &s); err != nil {
+		return err
+	}
+	il.path = s.Path
+	return nil
+}
+
+// layoutProvider provides blob content from the layout directory, used
+// to resolve children of descriptors during import.
+type layoutProvider struct {
+	path string
+}
+
+func (p *layoutProvider) ReaderAt(ctx context.Context, desc ocispec.Descriptor) (content.ReaderAt, error) {
+	bp, err := blobPath(p.path, desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(bp)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &fileReaderAt{File: f, size: fi.Size()}, nil
+}
+
+type fileReaderAt struct {
+	*os.File
+	size int64
+}
+
+func (f *fileReaderAt) Size() int64 {
+	return f.size
+}
+
+// blobPath validates the digest before using it as path elements so a
+// crafted layout cannot escape the layout directory.
+func blobPath(root string, dgst digest.Digest) (string, error) {
+	if err := dgst.Validate(); err != nil {
+		return "", fmt.Errorf("invalid blob digest %q: %w", dgst, errdefs.ErrInvalidArgument)
+	}
+	return filepath.Join(root, ocispec.ImageBlobsDir, dgst.Algorithm().String(), dgst.Encoded()), nil
+}
+
+// appendDescriptor replaces an existing index entry carrying the same
+// reference name, or the same digest when unnamed, to keep the layout
+// index free of duplicates across repeated exports.
+func appendDescriptor(manifests []ocispec.Descriptor, desc ocispec.Descriptor) []ocispec.Descriptor {
+	name := desc.Annotations[ocispec.AnnotationRefName]
+	for i, m := range manifests {
+		if name != "" && m.Annotations[ocispec.AnnotationRefName] == name {
+			manifests[i] = desc
+			return manifests
+		}
+		if name == "" && m.Digest == desc.Digest && m.Annotations[ocispec.AnnotationRefName] == "" {
+			manifests[i] = desc
+			return manifests
+		}
+	}
+	return append(manifests, desc)
+}
+
+func atomicWriteFile(path string, b []byte) error {
+	return atomicWriteFrom(path, bytes.NewReader(b))
+}
+
+func atomicWriteFrom(path string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package layout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/images"
+	"github.com/basuotian/containerd/plugins/content/local"
+)
+
+func writeTestBlob(ctx context.Context, t *testing.T, store content.Store, mediaType string, b []byte) ocispec.Descriptor {
+	t.Helper()
+	desc := ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(b),
+		Size:      int64(len(b)),
+	}
+	require.NoError(t, content.WriteBlob(ctx, store, "test-"+desc.Digest.String(), bytes.NewReader(b), desc))
+	return desc
+}
+
+func createTestImage(ctx context.Context, t *testing.T, store content.Store, name string) images.Image {
+	t.Helper()
+	layer := writeTestBlob(ctx, t, store, ocispec.MediaTypeImageLayerGzip, []byte("layer content for "+name))
+
+	config, err := json.Marshal(ocispec.Image{
+		Platform: ocispec.Platform{Architecture: "amd64", OS: "linux"},
+	})
+	require.NoError(t, err)
+	configDesc := writeTestBlob(ctx, t, store, ocispec.MediaTypeImageConfig, config)
+
+	manifest, err := json.Marshal(ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layer},
+	})
+	require.NoError(t, err)
+	manifestDesc := writeTestBlob(ctx, t, store, ocispec.MediaTypeImageManifest, manifest)
+
+	return images.Image{
+		Name:   name,
+		Target: manifestDesc,
+	}
+}
+
+func TestLayoutRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src, err := local.NewStore(t.TempDir())
+	require.NoError(t, err)
+	img := createTestImage(ctx, t, src, "registry.test/image:latest")
+
+	dir := filepath.Join(t.TempDir(), "layout")
+	il := NewImageLayout(dir)
+	require.NoError(t, il.Export(ctx, src, []images.Image{img}))
+
+	// Layout and index files are written.
+	lb, err := os.ReadFile(filepath.Join(dir, ocispec.ImageLayoutFile))
+	require.NoError(t, err)
+	var layout ocispec.ImageLayout
+	require.NoError(t, json.Unmarshal(lb, &layout))
+	assert.Equal(t, ocispec.ImageLayoutVersion, layout.Version)
+
+	ib, err := os.ReadFile(filepath.Join(dir, ocispec.ImageIndexFile))
+	require.NoError(t, err)
+	var index ocispec.Index
+	require.NoError(t, json.Unmarshal(ib, &index))
+	require.Len(t, index.Manifests, 1)
+	assert.Equal(t, img.Target.Digest, index.Manifests[0].Digest)
+	assert.Equal(t, img.Name, index.Manifests[0].Annotations[ocispec.AnnotationRefName])
+
+	dst, err := local.NewStore(t.TempDir())
+	require.NoError(t, err)
+	desc, err := NewImageLayout(dir).Import(ctx, dst)
+	require.NoError(t, err)
+	assert.Equal(t, ocispec.MediaTypeImageIndex, desc.MediaType)
+
+	// All content referenced from the index made it into the store.
+	required := []ocispec.Descriptor{desc, img.Target}
+	children, err := images.Children(ctx, dst, img.Target)
+	require.NoError(t, err)
+	require.NotEmpty(t, children)
+	for _, d := range append(required, children...) {
+		_, err := dst.Info(ctx, d.Digest)
+		assert.NoError(t, err, d.Digest)
+	}
+}
+
+func TestLayoutExportMerge(t *testing.T) {
+	ctx := context.Background()
+	src, err := local.NewStore(t.TempDir())
+	require.NoError(t, err)
+	img1 := createTestImage(ctx, t, src, "registry.test/one:latest")
+	img2 := createTestImage(ctx, t, src, "registry.test/two:latest")
+
+	dir := filepath.Join(t.TempDir(), "layout")
+	require.NoError(t, NewImageLayout(dir).Export(ctx, src, []images.Image{img1}))
+	require.NoError(t, NewImageLayout(dir).Export(ctx, src, []images.Image{img2}))
+	// Re-exporting the same reference must not duplicate index entries.
+	require.NoError(t, NewImageLayout(dir).Export(ctx, src, []images.Image{img1}))
+
+	ib, err := os.ReadFile(filepath.Join(dir, ocispec.ImageIndexFile))
+	require.NoError(t, err)
+	var index ocispec.Index
+	require.NoError(t, json.Unmarshal(ib, &index))
+	require.Len(t, index.Manifests, 2)
+}
+
+func TestLayoutImportInvalid(t *testing.T) {
+	ctx := context.Background()
+	store, err := local.NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	// Missing oci-layout file.
+	_, err = NewImageLayout(t.TempDir()).Import(ctx, store)
+	assert.Error(t, err)
+
+	// Unsupported layout version.
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ocispec.ImageLayoutFile), []byte(`{"imageLayoutVersion":"2.0.0"}`), 0644))
+	_, err = NewImageLayout(dir).Import(ctx, store)
+	assert.Error(t, err)
+}
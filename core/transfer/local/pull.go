@@ -180,7 +180,7 @@ func (ts *localTransferService) pull(ctx context.Context, ir transfer.ImageFetch
 	}
 
 	handler = images.Handlers(append(baseHandlers,
-		fetchHandler(store, fetcher, progressTracker),
+		fetchHandler(ts.scanIngester(store), fetcher, progressTracker),
 		checkNeedsFix,
 		childrenHandler, // List children to track hierarchy
 		appendDistSrcLabelHandler,
@@ -19,6 +19,7 @@ package local
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 	"github.com/basuotian/containerd/core/images"
 	"github.com/basuotian/containerd/core/remotes"
 	"github.com/basuotian/containerd/core/transfer"
+	"github.com/basuotian/containerd/pkg/labels"
 	"github.com/containerd/errdefs"
 	"github.com/containerd/platforms"
 	"github.com/opencontainers/go-digest"
@@ -45,6 +47,22 @@ func (ts *localTransferService) push(ctx context.Context, ig transfer.ImageGette
 		return err
 	}
 
+	// Seed the target descriptor with the distribution source labels of
+	// the image so the pusher can attempt cross-repository mounts even
+	// when the content itself is not labeled with its origin. The push
+	// handlers inherit the annotations down to the layer blobs.
+	for k, v := range img.Labels {
+		if !strings.HasPrefix(k, labels.LabelDistributionSource+".") {
+			continue
+		}
+		if img.Target.Annotations == nil {
+			img.Target.Annotations = make(map[string]string)
+		}
+		if _, has := img.Target.Annotations[k]; !has {
+			img.Target.Annotations[k] = v
+		}
+	}
+
 	if tops.Progress != nil {
 		tops.Progress(transfer.Progress{
 			Event: fmt.Sprintf("Pushing to %s", p),
@@ -0,0 +1,187 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/pkg/blobscanner"
+)
+
+// scanIngester wraps the content store with the configured blob scanners
+// so every fetched blob is streamed through them and scanner rejections
+// surface before the blob is committed. Without scanners the store is
+// returned unchanged.
+func (ts *localTransferService) scanIngester(store content.Ingester) content.Ingester {
+	if len(ts.config.BlobScanners) == 0 {
+		return store
+	}
+	return &scanningIngester{Ingester: store, scanners: ts.config.BlobScanners}
+}
+
+type scanningIngester struct {
+	content.Ingester
+	scanners map[string]blobscanner.BlobScanner
+}
+
+func (s *scanningIngester) Writer(ctx context.Context, opts ...content.WriterOpt) (content.Writer, error) {
+	var wopts content.WriterOpts
+	for _, o := range opts {
+		if err := o(&wopts); err != nil {
+			return nil, err
+		}
+	}
+	w, err := s.Ingester.Writer(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// A resumed ingest would hide the head of the blob from the scanners;
+	// restart it so they see the whole stream.
+	if st, err := w.Status(); err == nil && st.Offset > 0 {
+		if err := w.Truncate(0); err != nil {
+			w.Close()
+			return nil, fmt.Errorf("failed to restart ingest for scanning: %w", err)
+		}
+	}
+	return &scanningWriter{
+		Writer:   w,
+		ctx:      ctx,
+		desc:     wopts.Desc,
+		scanners: s.scanners,
+	}, nil
+}
+
+type scanningWriter struct {
+	content.Writer
+	ctx      context.Context
+	desc     ocispec.Descriptor
+	scanners map[string]blobscanner.BlobScanner
+
+	session *scanSession
+}
+
+func (w *scanningWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if n > 0 {
+		if w.session == nil {
+			w.session = startScanSession(w.ctx, w.desc, w.scanners)
+		}
+		w.session.write(p[:n])
+	}
+	return n, err
+}
+
+func (w *scanningWriter) Truncate(size int64) error {
+	if err := w.Writer.Truncate(size); err != nil {
+		return err
+	}
+	// The stream restarts from the beginning; restart the scanners with
+	// it. Write starts a fresh session on the next write.
+	if w.session != nil {
+		w.session.abort()
+		w.session = nil
+	}
+	return nil
+}
+
+func (w *scanningWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	if w.session == nil {
+		// Scan empty blobs too, so every scanner sees every blob.
+		w.session = startScanSession(w.ctx, w.desc, w.scanners)
+	}
+	session := w.session
+	w.session = nil
+	if err := session.finish(); err != nil {
+		return err
+	}
+	return w.Writer.Commit(ctx, size, expected, opts...)
+}
+
+func (w *scanningWriter) Close() error {
+	if w.session != nil {
+		w.session.abort()
+		w.session = nil
+	}
+	return w.Writer.Close()
+}
+
+var errScanAborted = errors.New("blob scan aborted")
+
+// scanSession streams one blob to every scanner concurrently.
+type scanSession struct {
+	pipes []*io.PipeWriter
+	wg    sync.WaitGroup
+
+	mu   sync.Mutex
+	errs []error
+}
+
+func startScanSession(ctx context.Context, desc ocispec.Descriptor, scanners map[string]blobscanner.BlobScanner) *scanSession {
+	s := &scanSession{}
+	for name, scanner := range scanners {
+		pr, pw := io.Pipe()
+		s.pipes = append(s.pipes, pw)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			err := scanner.ScanBlob(ctx, desc, pr)
+			// Drain whatever the scanner left unread so writes to the
+			// pipe never block on a finished scanner.
+			pr.CloseWithError(errScanAborted)
+			if err != nil && !errors.Is(err, errScanAborted) {
+				s.mu.Lock()
+				s.errs = append(s.errs, fmt.Errorf("blob scanner %s: %w", name, err))
+				s.mu.Unlock()
+			}
+		}()
+	}
+	return s
+}
+
+func (s *scanSession) write(p []byte) {
+	for _, pw := range s.pipes {
+		// A write error means the scanner finished early; its verdict is
+		// collected in finish.
+		pw.Write(p) //nolint:errcheck
+	}
+}
+
+// finish signals end of stream, waits for every scanner and returns their
+// combined verdict.
+func (s *scanSession) finish() error {
+	for _, pw := range s.pipes {
+		pw.Close()
+	}
+	s.wg.Wait()
+	return errors.Join(s.errs...)
+}
+
+// abort tears the session down without collecting a verdict.
+func (s *scanSession) abort() {
+	for _, pw := range s.pipes {
+		pw.CloseWithError(errScanAborted)
+	}
+	s.wg.Wait()
+}
@@ -0,0 +1,153 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/pkg/blobscanner"
+)
+
+// memWriter is a minimal content.Writer collecting written bytes.
+type memWriter struct {
+	buf       bytes.Buffer
+	committed bool
+}
+
+func (w *memWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+func (w *memWriter) Close() error                { return nil }
+func (w *memWriter) Digest() digest.Digest       { return digest.FromBytes(w.buf.Bytes()) }
+func (w *memWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	w.committed = true
+	return nil
+}
+func (w *memWriter) Status() (content.Status, error) {
+	return content.Status{Offset: int64(w.buf.Len())}, nil
+}
+func (w *memWriter) Truncate(size int64) error {
+	if size != 0 {
+		return io.ErrShortWrite
+	}
+	w.buf.Reset()
+	return nil
+}
+
+// wordScanner rejects blobs containing a marker word.
+type wordScanner struct {
+	word string
+	seen []string
+}
+
+func (s *wordScanner) ScanBlob(ctx context.Context, desc ocispec.Descriptor, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.seen = append(s.seen, string(data))
+	if strings.Contains(string(data), s.word) {
+		return &blobscanner.RejectedError{Digest: desc.Digest, Scanner: "word", Reason: "found " + s.word}
+	}
+	return nil
+}
+
+func newScanTestWriter(scanner blobscanner.BlobScanner) (*scanningWriter, *memWriter) {
+	mw := &memWriter{}
+	return &scanningWriter{
+		Writer:   mw,
+		ctx:      context.Background(),
+		desc:     ocispec.Descriptor{MediaType: ocispec.MediaTypeImageLayer},
+		scanners: map[string]blobscanner.BlobScanner{"word": scanner},
+	}, mw
+}
+
+func TestScanningWriterCommitClean(t *testing.T) {
+	scanner := &wordScanner{word: "malware"}
+	w, mw := newScanTestWriter(scanner)
+
+	if _, err := w.Write([]byte("perfectly fine layer")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Commit(context.Background(), 0, ""); err != nil {
+		t.Fatalf("commit of clean blob failed: %v", err)
+	}
+	if !mw.committed {
+		t.Fatal("clean blob was not committed")
+	}
+	if len(scanner.seen) != 1 || scanner.seen[0] != "perfectly fine layer" {
+		t.Fatalf("scanner saw %q", scanner.seen)
+	}
+}
+
+func TestScanningWriterCommitRejected(t *testing.T) {
+	scanner := &wordScanner{word: "malware"}
+	w, mw := newScanTestWriter(scanner)
+
+	if _, err := w.Write([]byte("this layer has malware in it")); err != nil {
+		t.Fatal(err)
+	}
+	err := w.Commit(context.Background(), 0, "")
+	if err == nil {
+		t.Fatal("expected commit to be rejected")
+	}
+	if !blobscanner.IsRejected(err) {
+		t.Fatalf("expected a rejection error, got %v", err)
+	}
+	if mw.committed {
+		t.Fatal("rejected blob must not be committed")
+	}
+}
+
+func TestScanningWriterTruncateRestartsScan(t *testing.T) {
+	scanner := &wordScanner{word: "malware"}
+	w, mw := newScanTestWriter(scanner)
+
+	if _, err := w.Write([]byte("malware here")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("clean restart")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Commit(context.Background(), 0, ""); err != nil {
+		t.Fatalf("commit after restart failed: %v", err)
+	}
+	if !mw.committed {
+		t.Fatal("blob was not committed after restart")
+	}
+	last := scanner.seen[len(scanner.seen)-1]
+	if last != "clean restart" {
+		t.Fatalf("scanner saw %q after restart", last)
+	}
+}
+
+func TestScanIngesterDisabledWithoutScanners(t *testing.T) {
+	ts := &localTransferService{}
+	var store content.Ingester
+	if got := ts.scanIngester(store); got != store {
+		t.Fatal("expected store to be returned unchanged without scanners")
+	}
+}
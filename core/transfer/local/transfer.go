@@ -30,9 +30,12 @@ import (
 	"github.com/basuotian/containerd/core/content"
 	"github.com/basuotian/containerd/core/images"
 	"github.com/basuotian/containerd/core/leases"
+	"github.com/basuotian/containerd/core/remotes"
 	"github.com/basuotian/containerd/core/transfer"
 	"github.com/basuotian/containerd/core/unpack"
+	"github.com/basuotian/containerd/pkg/blobscanner"
 	"github.com/basuotian/containerd/pkg/imageverifier"
+	"github.com/basuotian/containerd/pkg/reference"
 )
 
 type localTransferService struct {
@@ -60,12 +63,40 @@ func NewTransferService(cs content.Store, is images.Store, tc TransferConfig) tr
 	return ts
 }
 
+// checkAirgap refuses a remote source or destination whose registry host
+// is not allowlisted when airgap mode is enabled. Local sources and
+// destinations, such as image stores and archive streams, always pass.
+func (ts *localTransferService) checkAirgap(v interface{}) error {
+	if !ts.config.Airgap {
+		return nil
+	}
+	img, ok := v.(interface{ Image() string })
+	if !ok {
+		return nil
+	}
+	refspec, err := reference.Parse(img.Image())
+	if err != nil {
+		return err
+	}
+	if host := refspec.Hostname(); !remotes.AirgapAllowed(host, ts.config.AirgapAllowedHosts) {
+		return &remotes.AirgapViolationError{Host: host}
+	}
+	return nil
+}
+
 func (ts *localTransferService) Transfer(ctx context.Context, src interface{}, dest interface{}, opts ...transfer.Opt) error {
 	topts := &transfer.Config{}
 	for _, opt := range opts {
 		opt(topts)
 	}
 
+	if err := ts.checkAirgap(src); err != nil {
+		return err
+	}
+	if err := ts.checkAirgap(dest); err != nil {
+		return err
+	}
+
 	// Figure out matrix of whether source destination combination is supported
 	switch s := src.(type) {
 	case transfer.ImageFetcher:
@@ -181,6 +212,15 @@ type TransferConfig struct {
 	// MaxConcurrentUploadedLayers is the max concurrent uploads for push
 	MaxConcurrentUploadedLayers int
 
+	// Airgap refuses any transfer to or from a registry host which is
+	// not on AirgapAllowedHosts, failing with an explicit
+	// remotes.AirgapViolationError instead of timing out.
+	Airgap bool
+
+	// AirgapAllowedHosts are the registry hosts still reachable in
+	// airgap mode.
+	AirgapAllowedHosts []string
+
 	// DuplicationSuppressor is used to make sure that there is only one
 	// in-flight fetch request or unpack handler for a given descriptor's
 	// digest or chain ID.
@@ -197,6 +237,10 @@ type TransferConfig struct {
 	// ImageVerifiers verify the image before saving into the image store.
 	Verifiers map[string]imageverifier.ImageVerifier
 
+	// BlobScanners inspect every fetched blob before it is committed to
+	// the content store and can reject the transfer.
+	BlobScanners map[string]blobscanner.BlobScanner
+
 	// RegistryConfigPath is a path to the root directory containing registry-specific configurations
 	RegistryConfigPath string
 }
@@ -91,8 +91,34 @@ const (
 	// TODO: we should deprecate this annotation as soon as kubelet supports passing RuntimeHandler from PullImageRequest
 	RuntimeHandler = "io.containerd.cri.runtime-handler"
 
+	// ExternalNetNSPath is the sandbox annotation holding the path to a
+	// pre-created network namespace, set up by an external network agent,
+	// that the sandbox should join. When it is set, and the runtime enables
+	// external network namespaces, CNI setup and teardown are skipped and
+	// the namespace is left to the agent to manage.
+	ExternalNetNSPath = "io.containerd.cri.external-netns-path"
+
 	// WindowsHostProcess is used by hcsshim to identify windows pods that are running HostProcesses
 	WindowsHostProcess = "microsoft.com/hostprocess-container"
+
+	// CPUSetCPUs is a cpuset list of cpus to pin the container to, a hint
+	// produced by the kubelet cpu manager. It is used until CRI grows
+	// first class fields for topology hints, and loses to an explicit
+	// cpuset in the container resources.
+	CPUSetCPUs = "io.kubernetes.cri.cpuset-cpus"
+
+	// CPUSetMems is a cpuset list of NUMA memory nodes to bind the
+	// container to, a hint produced by the kubelet memory manager. Like
+	// CPUSetCPUs it loses to an explicit cpuset in the container
+	// resources.
+	CPUSetMems = "io.kubernetes.cri.cpuset-mems"
+
+	// EphemeralStorageLimit is a pod annotation holding the ephemeral
+	// storage limit in bytes to enforce on each container of the pod with
+	// a filesystem project quota. It is used until CRI grows first class
+	// ephemeral storage fields, and requires the runtime option
+	// enforce_ephemeral_storage_quota to be enabled.
+	EphemeralStorageLimit = "io.kubernetes.cri.ephemeral-storage-limit"
 )
 
 // DefaultCRIAnnotations are the default set of CRI annotations to
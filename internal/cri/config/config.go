@@ -31,6 +31,7 @@ import (
 
 	runhcsoptions "github.com/Microsoft/hcsshim/cmd/containerd-shim-runhcs-v1/options"
 	"github.com/basuotian/containerd/internal/cri/annotations"
+	cio "github.com/basuotian/containerd/internal/cri/io"
 	"github.com/basuotian/containerd/internal/cri/opts"
 	streaming "github.com/basuotian/containerd/internal/cri/streamingserver"
 	"github.com/basuotian/containerd/pkg/deprecation"
@@ -78,6 +79,10 @@ const (
 	IOTypeFifo = "fifo"
 	// IOTypeStreaming is container io implemented by connecting the streaming api to sandbox endpoint
 	IOTypeStreaming = "streaming"
+	// LogStallActionDrop drops container log entries while the log writer is stalled
+	LogStallActionDrop = "drop"
+	// LogStallActionKill stops the container when its log writer stalls
+	LogStallActionKill = "kill"
 )
 
 // Runtime struct to contain the type(ID), engine, and root variables for a default runtime
@@ -105,6 +110,19 @@ type Runtime struct {
 	// to the runtime spec when the container when PrivilegedWithoutHostDevices is already enabled. Requires
 	// PrivilegedWithoutHostDevices to be enabled. Defaults to false.
 	PrivilegedWithoutHostDevicesAllDevicesAllowed bool `toml:"privileged_without_host_devices_all_devices_allowed" json:"privileged_without_host_devices_all_devices_allowed"`
+	// PrivilegedWithMaskedPaths keeps the masked and read-only paths of a
+	// container in place when it is privileged instead of unmasking
+	// everything, for environments that must keep paths such as /proc/keys
+	// masked even in privileged pods. Defaults to false.
+	PrivilegedWithMaskedPaths bool `toml:"privileged_with_masked_paths" json:"privileged_with_masked_paths"`
+	// MaskedPaths adjusts the paths masked in containers created with this
+	// handler. Plain entries are added to the paths the client requested
+	// (kubelet requests the OCI defaults); entries prefixed with "-" are
+	// removed from them.
+	MaskedPaths []string `toml:"masked_paths" json:"maskedPaths"`
+	// ReadonlyPaths adjusts the paths made read-only in containers created
+	// with this handler, with the same add/remove semantics as MaskedPaths.
+	ReadonlyPaths []string `toml:"readonly_paths" json:"readonlyPaths"`
 	// CgroupWritable enables writable cgroups in non-privileged containers
 	CgroupWritable bool `toml:"cgroup_writable" json:"cgroupWritable"`
 	// BaseRuntimeSpec is a json file with OCI spec to use as base spec that all container's will be created from.
@@ -130,6 +148,21 @@ type Runtime struct {
 	// we can also set it to "streaming" to create a stream by streaming api,
 	// and use it as a channel to transfer the io stream
 	IOType string `toml:"io_type" json:"io_type"`
+	// SandboxPoolSize is the number of pre-created warm sandboxes kept for
+	// this runtime handler. Incoming RunPodSandbox requests claim a warm
+	// sandbox instead of cold-starting one when the pod configuration
+	// allows it, which cuts pod start latency for microVM runtimes.
+	// Zero (the default) disables the pool for the handler.
+	SandboxPoolSize int `toml:"sandbox_pool_size" json:"sandboxPoolSize"`
+	// ContainerLogFormat selects the format container logs are written
+	// in: "cri" (the default), "json" for docker json-file style entries
+	// or "logfmt". Only the CRI format can be consumed by kubelet's log
+	// handling, the other formats are for clusters with their own log
+	// collectors.
+	ContainerLogFormat string `toml:"container_log_format" json:"containerLogFormat"`
+	// CompressRotatedLogs gzips rotated container log files when the log
+	// of a container is reopened after rotation.
+	CompressRotatedLogs bool `toml:"compress_rotated_logs" json:"compressRotatedLogs"`
 }
 
 // ContainerdConfig contains toml config related to containerd
@@ -252,6 +285,11 @@ type Registry struct {
 type RegistryConfig struct {
 	// Auth contains information to authenticate to the registry.
 	Auth *AuthConfig `toml:"auth" json:"auth"`
+	// ImagePullProgressTimeout overrides the global
+	// image_pull_progress_timeout for pulls from this registry, e.g. to
+	// give a slow mirror a longer activity deadline. The string is in the
+	// golang duration format, an empty value uses the global setting.
+	ImagePullProgressTimeout string `toml:"image_pull_progress_timeout" json:"imagePullProgressTimeout"`
 }
 
 // ImageDecryption contains configuration to handling decryption of encrypted container images.
@@ -304,6 +342,13 @@ type ImageConfig struct {
 	// (PluginConfig).SandboxImage string `toml:"sandbox_image" json:"sandboxImage"`
 	PinnedImages map[string]string `toml:"pinned_images" json:"pinned_images"`
 
+	// PrewarmManifestPath is the path to an optional JSON or YAML manifest
+	// listing image references, e.g. {"images": ["registry.k8s.io/pause:3.10"]},
+	// which the CRI plugin keeps pulled and pinned on the node. The file is
+	// watched and re-applied on change, failed pulls are retried with
+	// backoff. An empty path disables pre-warming.
+	PrewarmManifestPath string `toml:"prewarm_manifest_path" json:"prewarmManifestPath"`
+
 	// RuntimePlatforms is map between the runtime and the image platform to
 	// use for that runtime. When resolving an image for a runtime, this
 	// mapping will be used to select the image for the platform and the
@@ -356,6 +401,12 @@ type RuntimeConfig struct {
 	CniConfig `toml:"cni" json:"cni"`
 	// EnableSelinux indicates to enable the selinux support.
 	EnableSelinux bool `toml:"enable_selinux" json:"enableSelinux"`
+	// EnforceEphemeralStorageQuota enables enforcement of the pod ephemeral
+	// storage limit annotation by placing filesystem project quotas on the
+	// container writable layers and log directories. It requires Linux with
+	// an ext4 or xfs filesystem mounted with project quota accounting;
+	// without that, limits are logged and ignored as before.
+	EnforceEphemeralStorageQuota bool `toml:"enforce_ephemeral_storage_quota" json:"enforceEphemeralStorageQuota"`
 	// SelinuxCategoryRange allows the upper bound on the category range to be set.
 	// If not specified or set to 0, defaults to 1024 from the selinux package.
 	SelinuxCategoryRange int `toml:"selinux_category_range" json:"selinuxCategoryRange"`
@@ -363,6 +414,21 @@ type RuntimeConfig struct {
 	// Log line longer than the limit will be split into multiple lines. Non-positive
 	// value means no limit.
 	MaxContainerLogLineSize int `toml:"max_container_log_line_size" json:"maxContainerLogLineSize"`
+	// ContainerLogWriteTimeout is the maximum duration a write to a container
+	// log may block before the log pipe is considered stalled and the write is
+	// dropped. This protects container stdio from a log consumer which stopped
+	// reading, e.g. after a kubelet restart or a full disk.
+	//
+	// The string is in the golang duration format, see:
+	//   https://golang.org/pkg/time/#ParseDuration
+	//
+	// An empty string or a zero duration disables stall detection.
+	ContainerLogWriteTimeout string `toml:"container_log_write_timeout" json:"containerLogWriteTimeout"`
+	// ContainerLogStallAction controls what happens to a container whose log
+	// pipe stalled. "drop" keeps the container running and drops log entries
+	// while the writer remains stalled, "kill" additionally stops the
+	// container. Defaults to "drop".
+	ContainerLogStallAction string `toml:"container_log_stall_action" json:"containerLogStallAction"`
 	// DisableApparmor indicates to disable the apparmor support.
 	// This is useful when the containerd does not have permission to access Apparmor.
 	DisableApparmor bool `toml:"disable_apparmor" json:"disableApparmor"`
@@ -395,6 +461,12 @@ type RuntimeConfig struct {
 	// of being placed under the hardcoded directory /var/run/netns. Changing this setting requires
 	// that all containers are deleted.
 	NetNSMountsUnderStateDir bool `toml:"netns_mounts_under_state_dir" json:"netnsMountsUnderStateDir"`
+	// EnableExternalNetNS allows sandboxes to join a pre-created network
+	// namespace supplied by an external network agent through the
+	// "io.containerd.cri.external-netns-path" pod annotation instead of
+	// having CNI set one up. The external namespace is left intact when the
+	// sandbox is torn down.
+	EnableExternalNetNS bool `toml:"enable_external_netns" json:"enableExternalNetNS"`
 	// EnableUnprivilegedPorts configures net.ipv4.ip_unprivileged_port_start=0
 	// for all containers which are not using host network
 	// and if it is not overwritten by PodSandboxConfig
@@ -425,6 +497,42 @@ type RuntimeConfig struct {
 	// IgnoreDeprecationWarnings is the list of the deprecation IDs (such as "io.containerd.deprecation/pull-schema-1-image")
 	// that should be ignored for checking "ContainerdHasNoDeprecationWarnings" condition.
 	IgnoreDeprecationWarnings []string `toml:"ignore_deprecation_warnings" json:"ignoreDeprecationWarnings"`
+
+	// SandboxGuardrails holds per-pod file descriptor and process
+	// accounting guardrails enforced by the CRI layer.
+	SandboxGuardrails SandboxGuardrails `toml:"sandbox_guardrails" json:"sandboxGuardrails"`
+
+	// ContainerEventsBufferSize is the number of container events kept
+	// in the persisted replay buffer used to backfill GetContainerEvents
+	// subscribers after a missed event or a containerd restart. Zero
+	// uses the default of 1024, a negative value disables the buffer.
+	ContainerEventsBufferSize int `toml:"container_events_buffer_size" json:"containerEventsBufferSize"`
+}
+
+// SandboxGuardrails configures per-pod resource guardrails. All guardrails
+// are disabled by default.
+type SandboxGuardrails struct {
+	// DefaultRlimitNofileSoft is the soft RLIMIT_NOFILE applied to
+	// container processes in place of the OCI default. Zero leaves the
+	// default untouched. Requires DefaultRlimitNofileHard to be set.
+	DefaultRlimitNofileSoft uint64 `toml:"default_rlimit_nofile_soft" json:"defaultRlimitNofileSoft"`
+	// DefaultRlimitNofileHard is the hard RLIMIT_NOFILE applied to
+	// container processes in place of the OCI default. Zero leaves the
+	// default untouched.
+	DefaultRlimitNofileHard uint64 `toml:"default_rlimit_nofile_hard" json:"defaultRlimitNofileHard"`
+	// DefaultPidsLimit is the pids cgroup limit applied to containers
+	// that do not configure one. Zero disables the default.
+	DefaultPidsLimit int64 `toml:"default_pids_limit" json:"defaultPidsLimit"`
+	// ShimFDThreshold is the number of open file descriptors in a
+	// sandbox's shim process above which the sandbox is flagged and an
+	// event is emitted. Zero disables shim fd monitoring.
+	ShimFDThreshold uint64 `toml:"shim_fd_threshold" json:"shimFDThreshold"`
+	// ShimFDCheckPeriod is the interval between shim fd checks in the
+	// golang duration format. Defaults to 1m when empty.
+	ShimFDCheckPeriod string `toml:"shim_fd_check_period" json:"shimFDCheckPeriod"`
+	// RestartShimOnFDThreshold kills the shim of a flagged sandbox so the
+	// sandbox is reported as not ready and recreated by the kubelet.
+	RestartShimOnFDThreshold bool `toml:"restart_shim_on_fd_threshold" json:"restartShimOnFDThreshold"`
 }
 
 // X509KeyPairStreaming contains the x509 configuration for streaming
@@ -463,6 +571,18 @@ type ServerConfig struct {
 	// The string is in the golang duration format, see:
 	//   https://golang.org/pkg/time/#ParseDuration
 	StreamIdleTimeout string `toml:"stream_idle_timeout" json:"streamIdleTimeout"`
+	// StreamSessionIdleTimeout is the maximum time an exec/attach session
+	// can be idle before the session is terminated. Zero value or empty
+	// string disables idle session termination.
+	// The string is in the golang duration format, see:
+	//   https://golang.org/pkg/time/#ParseDuration
+	StreamSessionIdleTimeout string `toml:"stream_session_idle_timeout" json:"streamSessionIdleTimeout"`
+	// MaxStreamingSessions is the maximum number of concurrent exec/attach
+	// sessions on the node. Zero means no limit.
+	MaxStreamingSessions int `toml:"max_streaming_sessions" json:"maxStreamingSessions"`
+	// MaxStreamingSessionsPerPod is the maximum number of concurrent
+	// exec/attach sessions per pod sandbox. Zero means no limit.
+	MaxStreamingSessionsPerPod int `toml:"max_streaming_sessions_per_pod" json:"maxStreamingSessionsPerPod"`
 	// EnableTLSStreaming indicates to enable the TLS streaming support.
 	EnableTLSStreaming bool `toml:"enable_tls_streaming" json:"enableTLSStreaming"`
 	// X509KeyPairStreaming is a x509 key pair used for TLS streaming
@@ -525,6 +645,14 @@ func ValidateImageConfig(ctx context.Context, c *ImageConfig) ([]deprecation.War
 			return warnings, fmt.Errorf("invalid image pull progress timeout: %w", err)
 		}
 	}
+	for host, config := range c.Registry.Configs {
+		if config.ImagePullProgressTimeout == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(config.ImagePullProgressTimeout); err != nil {
+			return warnings, fmt.Errorf("invalid image pull progress timeout for registry %q: %w", host, err)
+		}
+	}
 
 	return warnings, nil
 }
@@ -659,6 +787,14 @@ func ValidateRuntimeConfig(ctx context.Context, c *RuntimeConfig) ([]deprecation
 		if r.IOType != IOTypeStreaming && r.IOType != IOTypeFifo {
 			return warnings, errors.New("`io_type` can only be `streaming` or `named_pipe`")
 		}
+
+		if r.SandboxPoolSize < 0 {
+			return warnings, fmt.Errorf("runtime %s: `sandbox_pool_size` must not be negative", k)
+		}
+
+		if !cio.ValidLogFormat(cio.LogFormat(r.ContainerLogFormat)) {
+			return warnings, fmt.Errorf("runtime %s: `container_log_format` can only be `cri`, `json` or `logfmt`", k)
+		}
 	}
 
 	// Validation for drain_exec_sync_io_timeout
@@ -667,6 +803,32 @@ func ValidateRuntimeConfig(ctx context.Context, c *RuntimeConfig) ([]deprecation
 			return warnings, fmt.Errorf("invalid `drain_exec_sync_io_timeout`: %w", err)
 		}
 	}
+
+	// Validation for container_log_write_timeout
+	if c.ContainerLogWriteTimeout != "" {
+		if _, err := time.ParseDuration(c.ContainerLogWriteTimeout); err != nil {
+			return warnings, fmt.Errorf("invalid `container_log_write_timeout`: %w", err)
+		}
+	}
+	switch c.ContainerLogStallAction {
+	case "", LogStallActionDrop, LogStallActionKill:
+	default:
+		return warnings, errors.New("`container_log_stall_action` can only be `drop` or `kill`")
+	}
+
+	// Validation for sandbox_guardrails
+	gr := c.SandboxGuardrails
+	if gr.DefaultRlimitNofileSoft > gr.DefaultRlimitNofileHard {
+		return warnings, errors.New("`default_rlimit_nofile_soft` must not exceed `default_rlimit_nofile_hard`")
+	}
+	if gr.DefaultPidsLimit < 0 {
+		return warnings, errors.New("`default_pids_limit` must not be negative")
+	}
+	if gr.ShimFDCheckPeriod != "" {
+		if _, err := time.ParseDuration(gr.ShimFDCheckPeriod); err != nil {
+			return warnings, fmt.Errorf("invalid `shim_fd_check_period`: %w", err)
+		}
+	}
 	if err := ValidateEnableUnprivileged(ctx, c); err != nil {
 		return warnings, err
 	}
@@ -682,6 +844,18 @@ func ValidateServerConfig(ctx context.Context, c *ServerConfig) ([]deprecation.W
 			return warnings, fmt.Errorf("invalid stream idle timeout: %w", err)
 		}
 	}
+	// Validation for stream_session_idle_timeout
+	if c.StreamSessionIdleTimeout != "" {
+		if _, err := time.ParseDuration(c.StreamSessionIdleTimeout); err != nil {
+			return warnings, fmt.Errorf("invalid stream session idle timeout: %w", err)
+		}
+	}
+	if c.MaxStreamingSessions < 0 {
+		return warnings, errors.New("`max_streaming_sessions` must not be negative")
+	}
+	if c.MaxStreamingSessionsPerPod < 0 {
+		return warnings, errors.New("`max_streaming_sessions_per_pod` must not be negative")
+	}
 	return warnings, nil
 }
 
@@ -297,6 +297,40 @@ func TestValidateConfig(t *testing.T) {
 			},
 			runtimeExpectedErr: "invalid `drain_exec_sync_io_timeout`",
 		},
+		"sandbox_guardrails soft rlimit over hard rlimit": {
+			runtimeConfig: &RuntimeConfig{
+				ContainerdConfig: ContainerdConfig{
+					DefaultRuntimeName: RuntimeDefault,
+					Runtimes: map[string]Runtime{
+						RuntimeDefault: {
+							Type: "default",
+						},
+					},
+				},
+				SandboxGuardrails: SandboxGuardrails{
+					DefaultRlimitNofileSoft: 2048,
+					DefaultRlimitNofileHard: 1024,
+				},
+			},
+			runtimeExpectedErr: "`default_rlimit_nofile_soft` must not exceed `default_rlimit_nofile_hard`",
+		},
+		"sandbox_guardrails invalid shim_fd_check_period": {
+			runtimeConfig: &RuntimeConfig{
+				ContainerdConfig: ContainerdConfig{
+					DefaultRuntimeName: RuntimeDefault,
+					Runtimes: map[string]Runtime{
+						RuntimeDefault: {
+							Type: "default",
+						},
+					},
+				},
+				SandboxGuardrails: SandboxGuardrails{
+					ShimFDThreshold:   4096,
+					ShimFDCheckPeriod: "60",
+				},
+			},
+			runtimeExpectedErr: "invalid `shim_fd_check_period`",
+		},
 	} {
 		t.Run(desc, func(t *testing.T) {
 			var warnings []deprecation.Warning
@@ -81,6 +81,15 @@ func (c *ServerConfig) StreamingConfig() (streaming.Config, error) {
 			return streaming.Config{}, fmt.Errorf("invalid stream idle timeout: %w", err)
 		}
 	}
+	if c.StreamSessionIdleTimeout != "" {
+		var err error
+		config.SessionIdleTimeout, err = time.ParseDuration(c.StreamSessionIdleTimeout)
+		if err != nil {
+			return streaming.Config{}, fmt.Errorf("invalid stream session idle timeout: %w", err)
+		}
+	}
+	config.MaxSessions = c.MaxStreamingSessions
+	config.MaxSessionsPerPod = c.MaxStreamingSessionsPerPod
 	config.Addr = net.JoinHostPort(addr, port)
 
 	tlsMode, err := getStreamListenerMode(c)
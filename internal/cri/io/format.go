@@ -0,0 +1,195 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package io
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// LogFormat selects the format container log lines are written in.
+type LogFormat string
+
+const (
+	// LogFormatCRI is the kubelet compatible CRI logging format and the
+	// default. It is the only format kubelet's log handling understands.
+	LogFormatCRI LogFormat = "cri"
+	// LogFormatJSON writes docker json-file style entries, one JSON
+	// object per line.
+	LogFormatJSON LogFormat = "json"
+	// LogFormatLogfmt writes logfmt style key=value entries.
+	LogFormatLogfmt LogFormat = "logfmt"
+)
+
+// ValidLogFormat returns whether format selects a known log format. The
+// empty string is valid and means the default CRI format.
+func ValidLogFormat(format LogFormat) bool {
+	switch format {
+	case "", LogFormatCRI, LogFormatJSON, LogFormatLogfmt:
+		return true
+	}
+	return false
+}
+
+// logEncoder encodes a single log entry, a full or partial line of one
+// stream, into the write buffer.
+type logEncoder interface {
+	Encode(buf *bytes.Buffer, stream StreamType, partial bool, line [][]byte)
+}
+
+// newLogEncoder returns the encoder for format, the CRI encoder when
+// format is empty.
+func newLogEncoder(format LogFormat) (logEncoder, error) {
+	switch format {
+	case "", LogFormatCRI:
+		return &criEncoder{timeBuffer: make([]byte, len(timestampFormat))}, nil
+	case LogFormatJSON:
+		return &jsonEncoder{}, nil
+	case LogFormatLogfmt:
+		return &logfmtEncoder{}, nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// criEncoder writes the CRI logging format:
+// "<timestamp> <stream> <tag> <line>".
+type criEncoder struct {
+	timeBuffer []byte
+}
+
+func (e *criEncoder) Encode(buf *bytes.Buffer, stream StreamType, partial bool, line [][]byte) {
+	tag := runtime.LogTagFull
+	if partial {
+		tag = runtime.LogTagPartial
+	}
+	e.timeBuffer = time.Now().AppendFormat(e.timeBuffer[:0], timestampFormat)
+	for _, h := range [][]byte{e.timeBuffer, []byte(stream), []byte(tag)} {
+		buf.Write(h)
+		buf.WriteByte(delimiter)
+	}
+	for _, l := range line {
+		buf.Write(l)
+	}
+	buf.WriteByte(eol)
+}
+
+// jsonLogEntry is the docker json-file style schema of LogFormatJSON.
+type jsonLogEntry struct {
+	Log     string `json:"log"`
+	Stream  string `json:"stream"`
+	Time    string `json:"time"`
+	Partial bool   `json:"partial,omitempty"`
+}
+
+type jsonEncoder struct{}
+
+func (e *jsonEncoder) Encode(buf *bytes.Buffer, stream StreamType, partial bool, line [][]byte) {
+	entry := jsonLogEntry{
+		Stream:  string(stream),
+		Time:    time.Now().Format(timestampFormat),
+		Partial: partial,
+	}
+	var sb strings.Builder
+	for _, l := range line {
+		sb.Write(l)
+	}
+	if !partial {
+		sb.WriteByte(eol)
+	}
+	entry.Log = sb.String()
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		// A string only struct cannot fail to marshal.
+		panic(err)
+	}
+	buf.Write(data)
+	buf.WriteByte(eol)
+}
+
+type logfmtEncoder struct{}
+
+func (e *logfmtEncoder) Encode(buf *bytes.Buffer, stream StreamType, partial bool, line [][]byte) {
+	var sb strings.Builder
+	for _, l := range line {
+		sb.Write(l)
+	}
+	fmt.Fprintf(buf, "time=%s stream=%s partial=%t log=%s", time.Now().Format(timestampFormat), stream, partial, strconv.Quote(sb.String()))
+	buf.WriteByte(eol)
+}
+
+// CompressRotatedLogFiles gzips rotated siblings of the container log at
+// logPath, i.e. files named "<logPath>.<suffix>" which are not compressed
+// yet. It is meant to run after the log was reopened post rotation.
+func CompressRotatedLogFiles(logPath string) error {
+	entries, err := os.ReadDir(filepath.Dir(logPath))
+	if err != nil {
+		return err
+	}
+	base := filepath.Base(logPath)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, base+".") || strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		if err := compressLogFile(filepath.Join(filepath.Dir(logPath), name)); err != nil {
+			return fmt.Errorf("failed to compress rotated log %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func compressLogFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	// Compress next to the original and rename so a crash cannot leave a
+	// half written .gz behind.
+	tmp, err := os.OpenFile(path+".gz.tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	gw := gzip.NewWriter(tmp)
+	if _, err := io.Copy(gw, in); err == nil {
+		err = gw.Close()
+	} else {
+		gw.Close()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path+".gz"); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,98 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package io
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidLogFormat(t *testing.T) {
+	for _, format := range []LogFormat{"", LogFormatCRI, LogFormatJSON, LogFormatLogfmt} {
+		assert.True(t, ValidLogFormat(format), "format %q", format)
+	}
+	assert.False(t, ValidLogFormat("json-file"))
+}
+
+func TestJSONEncoder(t *testing.T) {
+	enc, err := newLogEncoder(LogFormatJSON)
+	require.NoError(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	enc.Encode(buf, Stdout, false, [][]byte{[]byte("hello "), []byte("world")})
+
+	var entry jsonLogEntry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "hello world\n", entry.Log)
+	assert.Equal(t, string(Stdout), entry.Stream)
+	assert.False(t, entry.Partial)
+	_, err = time.Parse(timestampFormat, entry.Time)
+	assert.NoError(t, err)
+
+	buf.Reset()
+	enc.Encode(buf, Stderr, true, [][]byte{[]byte("part")})
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "part", entry.Log)
+	assert.True(t, entry.Partial)
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	enc, err := newLogEncoder(LogFormatLogfmt)
+	require.NoError(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	enc.Encode(buf, Stdout, false, [][]byte{[]byte(`say "hi"`)})
+	line := buf.String()
+	assert.True(t, strings.HasSuffix(line, " stream=stdout partial=false log=\"say \\\"hi\\\"\"\n"), "got %q", line)
+}
+
+func TestCompressRotatedLogFiles(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "0.log")
+	rotated := logPath + ".20260828-101112"
+	require.NoError(t, os.WriteFile(logPath, []byte("current\n"), 0640))
+	require.NoError(t, os.WriteFile(rotated, []byte("rotated content\n"), 0640))
+	require.NoError(t, os.WriteFile(rotated+".gz", []byte("already compressed"), 0640))
+
+	require.NoError(t, CompressRotatedLogFiles(logPath))
+
+	// The active log stays untouched, the rotated file is replaced by
+	// its gzipped version.
+	_, err := os.Stat(logPath)
+	assert.NoError(t, err)
+	_, err = os.Stat(rotated)
+	assert.True(t, os.IsNotExist(err))
+
+	f, err := os.Open(rotated + ".gz")
+	require.NoError(t, err)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	assert.Equal(t, "rotated content\n", string(data))
+}
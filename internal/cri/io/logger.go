@@ -23,8 +23,6 @@ import (
 	"io"
 	"time"
 
-	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
-
 	cioutil "github.com/basuotian/containerd/pkg/ioutil"
 	"github.com/containerd/log"
 )
@@ -51,14 +49,29 @@ func NewDiscardLogger() io.WriteCloser {
 // maxLen is the max length limit of a line. A line longer than the
 // limit will be cut into multiple lines.
 func NewCRILogger(path string, w io.Writer, stream StreamType, maxLen int) (io.WriteCloser, <-chan struct{}) {
+	wc, stop, err := NewFormattedLogger(path, w, stream, maxLen, LogFormatCRI)
+	if err != nil {
+		// The CRI format always has an encoder.
+		panic(err)
+	}
+	return wc, stop
+}
+
+// NewFormattedLogger is NewCRILogger with a selectable log format. The
+// empty format means the default CRI format.
+func NewFormattedLogger(path string, w io.Writer, stream StreamType, maxLen int, format LogFormat) (io.WriteCloser, <-chan struct{}, error) {
+	enc, err := newLogEncoder(format)
+	if err != nil {
+		return nil, nil, err
+	}
 	log.L.Debugf("Start writing stream %q to log file %q", stream, path)
 	prc, pwc := io.Pipe()
 	stop := make(chan struct{})
 	go func() {
-		redirectLogs(path, prc, w, stream, maxLen)
+		redirectLogs(path, prc, w, stream, maxLen, enc)
 		close(stop)
 	}()
-	return pwc, stop
+	return pwc, stop, nil
 }
 
 // bufio.ReadLine in golang eats both read errors and tailing newlines
@@ -111,18 +124,13 @@ func readLine(b *bufio.Reader) (line []byte, isPrefix bool, err error) {
 	return
 }
 
-func redirectLogs(path string, rc io.ReadCloser, w io.Writer, s StreamType, maxLen int) {
+func redirectLogs(path string, rc io.ReadCloser, w io.Writer, s StreamType, maxLen int, enc logEncoder) {
 	defer rc.Close()
 	var (
-		stream    = []byte(s)
-		delimiter = []byte{delimiter}
-		partial   = []byte(runtime.LogTagPartial)
-		full      = []byte(runtime.LogTagFull)
-		buf       [][]byte
-		length    int
-		bufSize   = defaultBufSize
+		buf     [][]byte
+		length  int
+		bufSize = defaultBufSize
 
-		timeBuffer = make([]byte, len(timestampFormat))
 		lineBuffer = bytes.Buffer{}
 	)
 	// Make sure bufSize <= maxLen
@@ -130,19 +138,9 @@ func redirectLogs(path string, rc io.ReadCloser, w io.Writer, s StreamType, maxL
 		bufSize = maxLen
 	}
 	r := bufio.NewReaderSize(rc, bufSize)
-	writeLineBuffer := func(tag []byte, lineBytes [][]byte) {
-		timeBuffer = time.Now().AppendFormat(timeBuffer[:0], timestampFormat)
-		headers := [][]byte{timeBuffer, stream, tag}
-
+	writeLineBuffer := func(partial bool, lineBytes [][]byte) {
 		lineBuffer.Reset()
-		for _, h := range headers {
-			lineBuffer.Write(h)
-			lineBuffer.Write(delimiter)
-		}
-		for _, l := range lineBytes {
-			lineBuffer.Write(l)
-		}
-		lineBuffer.WriteByte(eol)
+		enc.Encode(&lineBuffer, s, partial, lineBytes)
 		if n, err := lineBuffer.WriteTo(w); err == nil {
 			outputEntries.Inc()
 			outputBytes.Inc(float64(n))
@@ -187,7 +185,7 @@ func redirectLogs(path string, rc io.ReadCloser, w io.Writer, s StreamType, maxL
 				panic("exceed length should <= last buffer size")
 			}
 			buf[len(buf)-1] = last[:len(last)-exceedLen]
-			writeLineBuffer(partial, buf)
+			writeLineBuffer(true, buf)
 			splitEntries.Inc()
 			buf = [][]byte{last[len(last)-exceedLen:]}
 			length = exceedLen
@@ -199,9 +197,9 @@ func redirectLogs(path string, rc io.ReadCloser, w io.Writer, s StreamType, maxL
 			// readLine only returns error when the message doesn't
 			// end with a newline, in that case it should be treated
 			// as a partial line.
-			writeLineBuffer(partial, buf)
+			writeLineBuffer(true, buf)
 		} else {
-			writeLineBuffer(full, buf)
+			writeLineBuffer(false, buf)
 		}
 		buf = nil
 		length = 0
@@ -240,7 +240,9 @@ func TestRedirectLogs(t *testing.T) {
 			rc := io.NopCloser(strings.NewReader(test.input))
 			buf := bytes.NewBuffer(nil)
 			wc := cioutil.NewNopWriteCloser(buf)
-			redirectLogs("test-path", rc, wc, test.stream, test.maxLen)
+			enc, err := newLogEncoder(LogFormatCRI)
+			require.NoError(t, err)
+			redirectLogs("test-path", rc, wc, test.stream, test.maxLen, enc)
 			output := buf.String()
 			lines := strings.Split(output, "\n")
 			lines = lines[:len(lines)-1] // Discard empty string after last \n
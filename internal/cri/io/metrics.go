@@ -19,11 +19,13 @@ package io
 import "github.com/docker/go-metrics"
 
 var (
-	inputEntries  metrics.Counter
-	outputEntries metrics.Counter
-	inputBytes    metrics.Counter
-	outputBytes   metrics.Counter
-	splitEntries  metrics.Counter
+	inputEntries   metrics.Counter
+	outputEntries  metrics.Counter
+	inputBytes     metrics.Counter
+	outputBytes    metrics.Counter
+	splitEntries   metrics.Counter
+	droppedEntries metrics.Counter
+	droppedBytes   metrics.Counter
 )
 
 func init() {
@@ -37,6 +39,10 @@ func init() {
 	splitEntries = ns.NewCounter("split_entries", "Number of extra log entries created by splitting the "+
 		"original log entry. This happens when the original log entry exceeds length limit. "+
 		"This metric does not count the original log entry.")
+	droppedEntries = ns.NewCounter("dropped_entries", "Number of log entries dropped because the log writer "+
+		"was stalled longer than the configured container log write timeout")
+	droppedBytes = ns.NewCounter("dropped_bytes", "Size of logs dropped because the log writer was stalled "+
+		"longer than the configured container log write timeout")
 
 	metrics.Register(ns)
 }
@@ -0,0 +1,100 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package io
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/containerd/log"
+)
+
+// stallWriterBufferSize is the number of pending writes buffered before
+// a blocked underlying writer is considered stalled.
+const stallWriterBufferSize = 32
+
+// stallWriter decouples log writes from the underlying writer through a
+// buffered channel drained by a dedicated goroutine. When the underlying
+// writer blocks, e.g. because the log consumer stopped reading a pipe or
+// the disk is full, writes time out and the content is dropped instead of
+// blocking the container's stdio forever. Drops are counted in metrics
+// and the stall callback is invoked once so the caller can react.
+type stallWriter struct {
+	w       io.Writer
+	timeout time.Duration
+
+	ch        chan []byte
+	closeOnce sync.Once
+	stallOnce sync.Once
+	onStall   func()
+}
+
+// NewStallWriter creates a write closer which applies timeout to writes
+// to w. Writes blocked longer than the timeout are dropped and counted in
+// the dropped entries/bytes metrics; onStall is called on the first drop.
+// Closing the stall writer does not close w.
+func NewStallWriter(w io.Writer, timeout time.Duration, onStall func()) io.WriteCloser {
+	s := &stallWriter{
+		w:       w,
+		timeout: timeout,
+		ch:      make(chan []byte, stallWriterBufferSize),
+		onStall: onStall,
+	}
+	go s.run()
+	return s
+}
+
+func (s *stallWriter) run() {
+	for b := range s.ch {
+		if _, err := s.w.Write(b); err != nil {
+			log.L.WithError(err).Error("Fail to write log content from stall writer")
+			// Continue to drain pending writes.
+		}
+	}
+}
+
+// Write queues p for writing. It never blocks longer than the configured
+// timeout and never returns an error, because a stalled log consumer must
+// not stop the container's output from being drained.
+func (s *stallWriter) Write(p []byte) (int, error) {
+	b := make([]byte, len(p))
+	copy(b, p)
+	t := time.NewTimer(s.timeout)
+	defer t.Stop()
+	select {
+	case s.ch <- b:
+	case <-t.C:
+		droppedEntries.Inc()
+		droppedBytes.Inc(float64(len(p)))
+		s.stallOnce.Do(func() {
+			if s.onStall != nil {
+				s.onStall()
+			}
+		})
+	}
+	return len(p), nil
+}
+
+// Close stops the drain goroutine after pending writes are flushed. The
+// underlying writer is left open, its lifecycle belongs to the caller.
+func (s *stallWriter) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.ch)
+	})
+	return nil
+}
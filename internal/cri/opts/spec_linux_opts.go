@@ -454,6 +454,48 @@ func WithPodOOMScoreAdj(adj int, restrict bool) oci.SpecOpts {
 	}
 }
 
+// WithDefaultRlimitNofile replaces the RLIMIT_NOFILE entry of the spec with
+// the configured soft and hard limits. CRI has no API to configure rlimits,
+// so the entry being replaced is always the OCI generator default.
+func WithDefaultRlimitNofile(soft, hard uint64) oci.SpecOpts {
+	return func(ctx context.Context, client oci.Client, c *containers.Container, s *runtimespec.Spec) error {
+		if s.Process == nil {
+			s.Process = &runtimespec.Process{}
+		}
+		rlimit := runtimespec.POSIXRlimit{
+			Type: "RLIMIT_NOFILE",
+			Soft: soft,
+			Hard: hard,
+		}
+		for i, r := range s.Process.Rlimits {
+			if r.Type == rlimit.Type {
+				s.Process.Rlimits[i] = rlimit
+				return nil
+			}
+		}
+		s.Process.Rlimits = append(s.Process.Rlimits, rlimit)
+		return nil
+	}
+}
+
+// WithDefaultPidsLimit sets a pids cgroup limit on the container unless the
+// spec already carries one.
+func WithDefaultPidsLimit(limit int64) oci.SpecOpts {
+	return func(ctx context.Context, client oci.Client, c *containers.Container, s *runtimespec.Spec) error {
+		if s.Linux == nil {
+			s.Linux = &runtimespec.Linux{}
+		}
+		if s.Linux.Resources == nil {
+			s.Linux.Resources = &runtimespec.LinuxResources{}
+		}
+		if s.Linux.Resources.Pids != nil && s.Linux.Resources.Pids.Limit > 0 {
+			return nil
+		}
+		s.Linux.Resources.Pids = &runtimespec.LinuxPids{Limit: limit}
+		return nil
+	}
+}
+
 func getCurrentOOMScoreAdj() (int, error) {
 	b, err := os.ReadFile("/proc/self/oom_score_adj")
 	if err != nil {
@@ -477,3 +519,73 @@ func restrictOOMScoreAdj(preferredOOMScoreAdj int) (int, error) {
 	}
 	return preferredOOMScoreAdj, nil
 }
+
+// WithPrivilegedKeepMaskedPaths is oci.WithPrivileged except that the
+// masked and read-only paths already set on the spec are preserved. It is
+// used for runtime handlers configured with privileged_with_masked_paths.
+func WithPrivilegedKeepMaskedPaths(ctx context.Context, client oci.Client, c *containers.Container, s *runtimespec.Spec) error {
+	return oci.Compose(
+		oci.WithAllCurrentCapabilities,
+		oci.WithWriteableSysfs,
+		oci.WithWriteableCgroupfs,
+		oci.WithSelinuxLabel(""),
+		oci.WithApparmorProfile(""),
+		oci.WithSeccompUnconfined,
+	)(ctx, client, c, s)
+}
+
+// adjustPaths applies add/remove adjustments to a path list: entries
+// prefixed with "-" are removed from it, other entries are appended
+// unless already present.
+func adjustPaths(paths, adjustments []string) []string {
+	removed := make(map[string]struct{})
+	present := make(map[string]struct{}, len(paths))
+	for _, a := range adjustments {
+		if rest, ok := strings.CutPrefix(a, "-"); ok {
+			removed[rest] = struct{}{}
+		}
+	}
+	adjusted := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if _, ok := removed[p]; ok {
+			continue
+		}
+		adjusted = append(adjusted, p)
+		present[p] = struct{}{}
+	}
+	for _, a := range adjustments {
+		if strings.HasPrefix(a, "-") {
+			continue
+		}
+		if _, ok := present[a]; ok {
+			continue
+		}
+		adjusted = append(adjusted, a)
+		present[a] = struct{}{}
+	}
+	return adjusted
+}
+
+// WithAdjustedMaskedPaths adds or removes entries from the masked paths
+// already set on the spec. Adjustments prefixed with "-" are removed.
+func WithAdjustedMaskedPaths(adjustments []string) oci.SpecOpts {
+	return func(_ context.Context, _ oci.Client, _ *containers.Container, s *runtimespec.Spec) error {
+		if s.Linux == nil {
+			s.Linux = &runtimespec.Linux{}
+		}
+		s.Linux.MaskedPaths = adjustPaths(s.Linux.MaskedPaths, adjustments)
+		return nil
+	}
+}
+
+// WithAdjustedReadonlyPaths adds or removes entries from the read-only
+// paths already set on the spec. Adjustments prefixed with "-" are removed.
+func WithAdjustedReadonlyPaths(adjustments []string) oci.SpecOpts {
+	return func(_ context.Context, _ oci.Client, _ *containers.Container, s *runtimespec.Spec) error {
+		if s.Linux == nil {
+			s.Linux = &runtimespec.Linux{}
+		}
+		s.Linux.ReadonlyPaths = adjustPaths(s.Linux.ReadonlyPaths, adjustments)
+		return nil
+	}
+}
@@ -17,8 +17,10 @@
 package opts
 
 import (
+	"context"
 	"testing"
 
+	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -45,3 +47,98 @@ func TestRestrictOOMScoreAdj(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, got, current+1)
 }
+
+func TestWithDefaultRlimitNofile(t *testing.T) {
+	ctx := context.Background()
+
+	// The generator default entry is replaced.
+	spec := &runtimespec.Spec{
+		Process: &runtimespec.Process{
+			Rlimits: []runtimespec.POSIXRlimit{
+				{Type: "RLIMIT_NOFILE", Soft: 1024, Hard: 1024},
+				{Type: "RLIMIT_NPROC", Soft: 10, Hard: 10},
+			},
+		},
+	}
+	require.NoError(t, WithDefaultRlimitNofile(2048, 4096)(ctx, nil, nil, spec))
+	assert.Equal(t, []runtimespec.POSIXRlimit{
+		{Type: "RLIMIT_NOFILE", Soft: 2048, Hard: 4096},
+		{Type: "RLIMIT_NPROC", Soft: 10, Hard: 10},
+	}, spec.Process.Rlimits)
+
+	// A missing entry is appended.
+	spec = &runtimespec.Spec{}
+	require.NoError(t, WithDefaultRlimitNofile(2048, 4096)(ctx, nil, nil, spec))
+	assert.Equal(t, []runtimespec.POSIXRlimit{
+		{Type: "RLIMIT_NOFILE", Soft: 2048, Hard: 4096},
+	}, spec.Process.Rlimits)
+}
+
+func TestWithDefaultPidsLimit(t *testing.T) {
+	ctx := context.Background()
+
+	// A missing pids limit is filled in.
+	spec := &runtimespec.Spec{}
+	require.NoError(t, WithDefaultPidsLimit(512)(ctx, nil, nil, spec))
+	require.NotNil(t, spec.Linux.Resources.Pids)
+	assert.Equal(t, int64(512), spec.Linux.Resources.Pids.Limit)
+
+	// An existing limit is kept.
+	spec = &runtimespec.Spec{
+		Linux: &runtimespec.Linux{
+			Resources: &runtimespec.LinuxResources{
+				Pids: &runtimespec.LinuxPids{Limit: 100},
+			},
+		},
+	}
+	require.NoError(t, WithDefaultPidsLimit(512)(ctx, nil, nil, spec))
+	assert.Equal(t, int64(100), spec.Linux.Resources.Pids.Limit)
+}
+
+func TestAdjustPaths(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		paths       []string
+		adjustments []string
+		expected    []string
+	}{
+		{
+			desc:     "no adjustments leaves paths alone",
+			paths:    []string{"/proc/keys"},
+			expected: []string{"/proc/keys"},
+		},
+		{
+			desc:        "plain entries are appended",
+			paths:       []string{"/proc/keys"},
+			adjustments: []string{"/proc/timer_list"},
+			expected:    []string{"/proc/keys", "/proc/timer_list"},
+		},
+		{
+			desc:        "duplicates are not appended twice",
+			paths:       []string{"/proc/keys"},
+			adjustments: []string{"/proc/keys", "/proc/keys"},
+			expected:    []string{"/proc/keys"},
+		},
+		{
+			desc:        "prefixed entries are removed",
+			paths:       []string{"/proc/keys", "/proc/timer_list"},
+			adjustments: []string{"-/proc/timer_list"},
+			expected:    []string{"/proc/keys"},
+		},
+		{
+			desc:        "add and remove combine",
+			paths:       []string{"/proc/keys"},
+			adjustments: []string{"-/proc/keys", "/sys/firmware"},
+			expected:    []string{"/sys/firmware"},
+		},
+		{
+			desc:        "adjusting empty paths only adds",
+			adjustments: []string{"-/proc/keys", "/proc/kcore"},
+			expected:    []string{"/proc/kcore"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.expected, adjustPaths(test.paths, test.adjustments))
+		})
+	}
+}
@@ -274,7 +274,7 @@ func (c *criService) createContainer(r *createContainerRequest) (_ string, retEr
 	}
 
 	// mutate the extra CRI volume mounts from the runtime spec to properly specify the OCI image volume mount requests as bind mounts for this container
-	err = c.mutateMounts(r.ctx, r.containerConfig.GetMounts(), c.RuntimeSnapshotter(r.ctx, ociRuntime), r.sandboxID, platform)
+	err = c.mutateMounts(r.ctx, r.containerConfig.GetMounts(), c.RuntimeSnapshotter(r.ctx, ociRuntime), r.sandboxID, platform, r.podSandboxConfig, r.sandboxRuntimeHandler)
 	if err != nil {
 		return "", fmt.Errorf("failed to mount image volume: %w", err)
 	}
@@ -440,6 +440,10 @@ func (c *criService) createContainer(r *createContainerRequest) (_ string, retEr
 		}
 	}()
 
+	if err := c.applyEphemeralStorageQuota(r.ctx, r.containerID, c.RuntimeSnapshotter(r.ctx, ociRuntime), r.podSandboxConfig, r.meta.LogPath); err != nil {
+		return "", err
+	}
+
 	status := containerstore.Status{CreatedAt: time.Now().UnixNano(), Restore: r.restore}
 	status = copyResourcesToStatus(spec, status)
 	container, err := containerstore.NewContainer(*r.meta,
@@ -814,6 +818,16 @@ func (c *criService) buildLinuxSpec(
 		}
 	}
 
+	// Apply per-handler path adjustments on top of whatever the client
+	// requested, or on top of the defaults when proc mount handling is
+	// disabled.
+	if len(ociRuntime.MaskedPaths) > 0 {
+		specOpts = append(specOpts, customopts.WithAdjustedMaskedPaths(ociRuntime.MaskedPaths))
+	}
+	if len(ociRuntime.ReadonlyPaths) > 0 {
+		specOpts = append(specOpts, customopts.WithAdjustedReadonlyPaths(ociRuntime.ReadonlyPaths))
+	}
+
 	specOpts = append(specOpts, customopts.WithDevices(c.os, config, c.config.DeviceOwnershipFromSecurityContext),
 		customopts.WithCapabilities(securityContext, c.allCaps))
 
@@ -821,7 +835,11 @@ func (c *criService) buildLinuxSpec(
 		if !sandboxConfig.GetLinux().GetSecurityContext().GetPrivileged() {
 			return nil, errors.New("no privileged container allowed in sandbox")
 		}
-		specOpts = append(specOpts, oci.WithPrivileged)
+		if ociRuntime.PrivilegedWithMaskedPaths {
+			specOpts = append(specOpts, customopts.WithPrivilegedKeepMaskedPaths)
+		} else {
+			specOpts = append(specOpts, oci.WithPrivileged)
+		}
 		if !ociRuntime.PrivilegedWithoutHostDevices {
 			specOpts = append(specOpts, oci.WithHostDevices, oci.WithAllDevicesAllowed)
 		} else if ociRuntime.PrivilegedWithoutHostDevicesAllDevicesAllowed {
@@ -878,6 +896,28 @@ func (c *criService) buildLinuxSpec(
 		specOpts = append(specOpts, oci.WithRdt(rdtClass, "", ""))
 	}
 
+	// Translate kubelet cpu/memory manager placement hints into a precise
+	// cpuset and NUMA memory binding, validated against the node topology.
+	cpusetCPUs, cpusetMems, err := c.cpusetHints(config, sandboxConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve cpuset hints: %w", err)
+	}
+	if cpusetCPUs != "" {
+		specOpts = append(specOpts, oci.WithCPUs(cpusetCPUs))
+	}
+	if cpusetMems != "" {
+		specOpts = append(specOpts, oci.WithCPUsMems(cpusetMems))
+	}
+
+	// Per-pod guardrails: default RLIMIT_NOFILE and pids limit for
+	// containers that do not bring their own.
+	if guardrails := c.config.SandboxGuardrails; guardrails.DefaultRlimitNofileHard > 0 {
+		specOpts = append(specOpts, customopts.WithDefaultRlimitNofile(guardrails.DefaultRlimitNofileSoft, guardrails.DefaultRlimitNofileHard))
+	}
+	if guardrails := c.config.SandboxGuardrails; guardrails.DefaultPidsLimit > 0 {
+		specOpts = append(specOpts, customopts.WithDefaultPidsLimit(guardrails.DefaultPidsLimit))
+	}
+
 	for pKey, pValue := range util.GetPassthroughAnnotations(sandboxConfig.Annotations,
 		ociRuntime.PodAnnotations) {
 		specOpts = append(specOpts, customopts.WithAnnotation(pKey, pValue))
@@ -888,6 +928,26 @@ func (c *criService) buildLinuxSpec(
 		specOpts = append(specOpts, customopts.WithAnnotation(pKey, pValue))
 	}
 
+	// The kernel keyring sharing policy is configured on the pod; forward
+	// its keyring annotations so the runc shim applies the same policy to
+	// every container in the sandbox.
+	for pKey, pValue := range sandboxConfig.Annotations {
+		if strings.HasPrefix(pKey, oci.KeyringAnnotationPrefix) {
+			specOpts = append(specOpts, customopts.WithAnnotation(pKey, pValue))
+		}
+	}
+
+	// Core scheduling may be requested for the whole pod or, for stricter
+	// SMT isolation, on the individual container; the container annotation
+	// wins.
+	schedCoreScope := config.GetAnnotations()[oci.SchedCoreAnnotation]
+	if schedCoreScope == "" {
+		schedCoreScope = sandboxConfig.GetAnnotations()[oci.SchedCoreAnnotation]
+	}
+	if schedCoreScope != "" {
+		specOpts = append(specOpts, oci.WithSchedCore(schedCoreScope))
+	}
+
 	// Default target PID namespace is the sandbox PID.
 	targetPid := sandboxPid
 	// If the container targets another container's PID namespace,
@@ -857,20 +857,22 @@ func TestMaskedAndReadonlyPaths(t *testing.T) {
 	testContainerName := "container-name"
 	testPid := uint32(1234)
 	containerConfig, sandboxConfig, imageConfig, specCheck := getCreateContainerTestData()
-	ociRuntime := config.Runtime{}
 	c := newTestCRIService()
 
 	defaultSpec, err := oci.GenerateSpec(ctrdutil.NamespacedContext(), nil, &containers.Container{ID: testID})
 	require.NoError(t, err)
 
 	for _, test := range []struct {
-		desc             string
-		disableProcMount bool
-		masked           []string
-		readonly         []string
-		expectedMasked   []string
-		expectedReadonly []string
-		privileged       bool
+		desc                 string
+		disableProcMount     bool
+		masked               []string
+		readonly             []string
+		runtimeMasked        []string
+		runtimeReadonly      []string
+		privilegedKeepMasked bool
+		expectedMasked       []string
+		expectedReadonly     []string
+		privileged           bool
 	}{
 		{
 			desc:             "should apply default if not specified when disable_proc_mount = true",
@@ -906,6 +908,24 @@ func TestMaskedAndReadonlyPaths(t *testing.T) {
 			expectedReadonly: []string{"/sys"},
 			privileged:       false,
 		},
+		{
+			desc:             "should apply runtime handler adjustments to CRI specified paths",
+			masked:           []string{"/proc", "/proc/timer_list"},
+			readonly:         []string{"/sys"},
+			runtimeMasked:    []string{"-/proc/timer_list", "/proc/keys"},
+			runtimeReadonly:  []string{"-/sys", "/proc/bus"},
+			expectedMasked:   []string{"/proc", "/proc/keys"},
+			expectedReadonly: []string{"/proc/bus"},
+			privileged:       false,
+		},
+		{
+			desc:             "should apply runtime handler adjustments to defaults when disable_proc_mount = true",
+			disableProcMount: true,
+			runtimeMasked:    []string{"/dev/extra"},
+			expectedMasked:   append(append([]string{}, defaultSpec.Linux.MaskedPaths...), "/dev/extra"),
+			expectedReadonly: defaultSpec.Linux.ReadonlyPaths,
+			privileged:       false,
+		},
 		{
 			desc:             "default should be nil for privileged",
 			expectedMasked:   nil,
@@ -928,8 +948,30 @@ func TestMaskedAndReadonlyPaths(t *testing.T) {
 			expectedReadonly: nil,
 			privileged:       true,
 		},
+		{
+			desc:                 "should keep paths for privileged when privileged_with_masked_paths is set",
+			masked:               []string{"/proc/keys"},
+			readonly:             []string{"/proc/sys"},
+			privilegedKeepMasked: true,
+			expectedMasked:       []string{"/proc/keys"},
+			expectedReadonly:     []string{"/proc/sys"},
+			privileged:           true,
+		},
+		{
+			desc:                 "should keep runtime handler additions for privileged when privileged_with_masked_paths is set",
+			runtimeMasked:        []string{"/proc/keys"},
+			privilegedKeepMasked: true,
+			expectedMasked:       []string{"/proc/keys"},
+			expectedReadonly:     []string{},
+			privileged:           true,
+		},
 	} {
 		t.Run(test.desc, func(t *testing.T) {
+			ociRuntime := config.Runtime{
+				MaskedPaths:               test.runtimeMasked,
+				ReadonlyPaths:             test.runtimeReadonly,
+				PrivilegedWithMaskedPaths: test.privilegedKeepMasked,
+			}
 			c.config.DisableProcMount = test.disableProcMount
 			containerConfig.Linux.SecurityContext.MaskedPaths = test.masked
 			containerConfig.Linux.SecurityContext.ReadonlyPaths = test.readonly
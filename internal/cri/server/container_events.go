@@ -17,15 +17,192 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/containerd/log"
+	"google.golang.org/grpc/metadata"
 	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
 )
 
+const (
+	// defaultContainerEventsBufferSize is the replay buffer size used
+	// when container_events_buffer_size is left at zero.
+	defaultContainerEventsBufferSize = 1024
+	// containerEventSequenceAnnotation carries the event sequence number
+	// in the sandbox status annotations of a container event, since the
+	// CRI ContainerEventResponse message has no dedicated field for it.
+	// Consumers use consecutive sequence numbers to detect missed events.
+	containerEventSequenceAnnotation = "io.containerd.cri.event-sequence"
+	// containerEventsSinceSequenceMD is the gRPC metadata key a
+	// GetContainerEvents subscriber sets to the last sequence number it
+	// has seen; buffered events after it are replayed before live ones.
+	containerEventsSinceSequenceMD = "containerd-container-events-since-seq"
+	// containerEventsFile persists the replay buffer in the CRI root
+	// directory so sequences survive a containerd restart.
+	containerEventsFile = "container_events.json"
+)
+
+// sequencedEvent pairs a container event with its monotonically
+// increasing sequence number while it travels through the event queue
+// and the replay buffer.
+type sequencedEvent struct {
+	Sequence uint64                         `json:"sequence"`
+	Event    runtime.ContainerEventResponse `json:"event"`
+}
+
+type persistedContainerEvents struct {
+	NextSequence uint64           `json:"next_sequence"`
+	Events       []sequencedEvent `json:"events"`
+}
+
+// containerEventBuffer assigns sequence numbers to container events and
+// keeps the most recent ones for backfilling subscribers.
+type containerEventBuffer struct {
+	mu      sync.Mutex
+	path    string
+	size    int
+	nextSeq uint64
+	events  []sequencedEvent
+}
+
+// newContainerEventBuffer creates the buffer, reloading any state
+// persisted at path. A size of zero or less disables buffering, an
+// empty path disables persistence.
+func newContainerEventBuffer(path string, size int) *containerEventBuffer {
+	b := &containerEventBuffer{
+		path:    path,
+		size:    size,
+		nextSeq: 1,
+	}
+	if size <= 0 || path == "" {
+		return b
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.L.WithError(err).Warnf("Failed to read persisted container events from %q", path)
+		}
+		return b
+	}
+	var persisted persistedContainerEvents
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		log.L.WithError(err).Warnf("Failed to parse persisted container events from %q", path)
+		return b
+	}
+	if persisted.NextSequence > 0 {
+		b.nextSeq = persisted.NextSequence
+	}
+	b.events = persisted.Events
+	if len(b.events) > size {
+		b.events = b.events[len(b.events)-size:]
+	}
+	return b
+}
+
+// append assigns the next sequence number to the event, stamps it into
+// the sandbox status annotations when present, buffers the event and
+// persists the buffer.
+func (b *containerEventBuffer) append(event *runtime.ContainerEventResponse) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	seq := b.nextSeq
+	b.nextSeq++
+	if event.PodSandboxStatus != nil {
+		if event.PodSandboxStatus.Annotations == nil {
+			event.PodSandboxStatus.Annotations = make(map[string]string)
+		}
+		event.PodSandboxStatus.Annotations[containerEventSequenceAnnotation] = strconv.FormatUint(seq, 10)
+	}
+	if b.size <= 0 {
+		return seq
+	}
+	b.events = append(b.events, sequencedEvent{Sequence: seq, Event: *event})
+	if len(b.events) > b.size {
+		b.events = b.events[len(b.events)-b.size:]
+	}
+	if err := b.persist(); err != nil {
+		log.L.WithError(err).Warnf("Failed to persist container events to %q", b.path)
+	}
+	return seq
+}
+
+// since returns the buffered events with a sequence number greater than
+// seq, oldest first.
+func (b *containerEventBuffer) since(seq uint64) []sequencedEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var events []sequencedEvent
+	for _, e := range b.events {
+		if e.Sequence > seq {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func (b *containerEventBuffer) persist() error {
+	if b.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(persistedContainerEvents{
+		NextSequence: b.nextSeq,
+		Events:       b.events,
+	})
+	if err != nil {
+		return err
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path)
+}
+
+// sinceSequenceFromContext extracts the backfill request from the gRPC
+// metadata of a GetContainerEvents stream.
+func sinceSequenceFromContext(ctx context.Context) (uint64, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	values := md.Get(containerEventsSinceSequenceMD)
+	if len(values) == 0 {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(values[0], 10, 64)
+	if err != nil {
+		log.L.WithError(err).Warnf("Ignoring invalid %s metadata %q", containerEventsSinceSequenceMD, values[0])
+		return 0, false
+	}
+	return seq, true
+}
+
 func (c *criService) GetContainerEvents(r *runtime.GetEventsRequest, s runtime.RuntimeService_GetContainerEventsServer) error {
+	// Subscribe before replaying so events arriving during the replay
+	// are not missed; replayed sequences are deduplicated below.
 	eventC, closer := c.containerEventsQ.Subscribe()
 	defer closer.Close()
 
+	var replayed uint64
+	if since, ok := sinceSequenceFromContext(s.Context()); ok {
+		for _, e := range c.containerEvents.since(since) {
+			if err := s.Send(&e.Event); err != nil {
+				return fmt.Errorf("failed to backfill container event %d: %w", e.Sequence, err)
+			}
+			replayed = e.Sequence
+		}
+	}
+
 	for event := range eventC {
-		if err := s.Send(&event); err != nil {
+		if event.Sequence <= replayed {
+			continue
+		}
+		if err := s.Send(&event.Event); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,86 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+func newTestEvent(id string) *runtime.ContainerEventResponse {
+	return &runtime.ContainerEventResponse{
+		ContainerId:        id,
+		ContainerEventType: runtime.ContainerEventType_CONTAINER_STARTED_EVENT,
+		PodSandboxStatus:   &runtime.PodSandboxStatus{Id: "sb-" + id},
+	}
+}
+
+func TestContainerEventBufferSequences(t *testing.T) {
+	b := newContainerEventBuffer("", 4)
+
+	e1 := newTestEvent("c1")
+	assert.Equal(t, uint64(1), b.append(e1))
+	// The sequence is surfaced in the sandbox status annotations.
+	assert.Equal(t, "1", e1.PodSandboxStatus.Annotations[containerEventSequenceAnnotation])
+
+	e2 := newTestEvent("c2")
+	assert.Equal(t, uint64(2), b.append(e2))
+
+	events := b.since(1)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(2), events[0].Sequence)
+	assert.Equal(t, "c2", events[0].Event.ContainerId)
+
+	assert.Empty(t, b.since(2))
+}
+
+func TestContainerEventBufferBounded(t *testing.T) {
+	b := newContainerEventBuffer("", 2)
+	for i := 0; i < 5; i++ {
+		b.append(newTestEvent("c"))
+	}
+	events := b.since(0)
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(4), events[0].Sequence)
+	assert.Equal(t, uint64(5), events[1].Sequence)
+}
+
+func TestContainerEventBufferPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), containerEventsFile)
+
+	b := newContainerEventBuffer(path, 4)
+	b.append(newTestEvent("c1"))
+	b.append(newTestEvent("c2"))
+
+	// A new buffer picks up both the events and the sequence counter.
+	reloaded := newContainerEventBuffer(path, 4)
+	events := reloaded.since(0)
+	require.Len(t, events, 2)
+	assert.Equal(t, "c1", events[0].Event.ContainerId)
+	assert.Equal(t, uint64(3), reloaded.append(newTestEvent("c3")))
+}
+
+func TestContainerEventBufferDisabled(t *testing.T) {
+	b := newContainerEventBuffer("", -1)
+	// Sequences are still assigned when buffering is disabled.
+	assert.Equal(t, uint64(1), b.append(newTestEvent("c1")))
+	assert.Empty(t, b.since(0))
+}
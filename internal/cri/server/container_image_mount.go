@@ -40,6 +40,8 @@ func (c *criService) mutateMounts(
 	snapshotter string,
 	sandboxID string,
 	platform imagespec.Platform,
+	sandboxConfig *runtime.PodSandboxConfig,
+	runtimeHandler string,
 ) error {
 	if err := c.ensureLeaseExist(ctx, sandboxID); err != nil {
 		return fmt.Errorf("failed to ensure lease %v for sandbox: %w", sandboxID, err)
@@ -47,7 +49,7 @@ func (c *criService) mutateMounts(
 
 	ctx = leases.WithLease(ctx, sandboxID)
 	for _, m := range extraMounts {
-		err := c.mutateImageMount(ctx, m, snapshotter, sandboxID, platform)
+		err := c.mutateImageMount(ctx, m, snapshotter, sandboxID, platform, sandboxConfig, runtimeHandler)
 		if err != nil {
 			return fmt.Errorf("%w: %w", crierrors.ErrImageVolumeMountFailed, err)
 		}
@@ -72,6 +74,8 @@ func (c *criService) mutateImageMount(
 	snapshotter string,
 	sandboxID string,
 	platform imagespec.Platform,
+	sandboxConfig *runtime.PodSandboxConfig,
+	runtimeHandler string,
 ) (retErr error) {
 	imageSpec := extraMount.GetImage()
 	if imageSpec == nil {
@@ -90,7 +94,19 @@ func (c *criService) mutateImageMount(
 	}
 	image, err := c.LocalResolve(ref)
 	if err != nil {
-		return fmt.Errorf("failed to resolve image %q: %w", ref, err)
+		if !errdefs.IsNotFound(err) {
+			return fmt.Errorf("failed to resolve image %q: %w", ref, err)
+		}
+		// Kubelets without the ImageVolume feature gate don't pull image
+		// volume sources before creating the container, so pull on demand.
+		imageID, err := c.PullImage(ctx, ref, nil, sandboxConfig, runtimeHandler)
+		if err != nil {
+			return fmt.Errorf("failed to pull image volume %q: %w", ref, err)
+		}
+		image, err = c.LocalResolve(imageID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve pulled image volume %q: %w", imageID, err)
+		}
 	}
 	containerdImage, err := c.toContainerdImage(ctx, image)
 	if err != nil {
@@ -125,14 +141,14 @@ func (c *criService) mutateImageMount(
 		chainID := identity.ChainID(diffIDs).String()
 
 		s := c.client.SnapshotService(snapshotter)
-		mounts, err := s.Prepare(ctx, target, chainID)
+		mounts, err := s.View(ctx, target, chainID)
 		if err != nil {
 			if errdefs.IsAlreadyExists(err) {
 				mounts, err = s.Mounts(ctx, target)
 			}
 		}
 		if err != nil {
-			return fmt.Errorf("failed to prepare for image volume %q: %w", ref, err)
+			return fmt.Errorf("failed to create view for image volume %q: %w", ref, err)
 		}
 		defer func() {
 			if retErr != nil {
@@ -21,6 +21,7 @@ package server
 import (
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/basuotian/containerd/core/mount"
@@ -52,6 +53,19 @@ func addVolatileOptionOnImageVolumeMount(mounts []mount.Mount) []mount.Mount {
 			continue
 		}
 
+		// volatile requires an upperdir; read-only view snapshots are
+		// mounted with lowerdirs only.
+		hasUpper := false
+		for _, opt := range m.Options {
+			if strings.HasPrefix(opt, "upperdir=") {
+				hasUpper = true
+				break
+			}
+		}
+		if !hasUpper {
+			continue
+		}
+
 		need := true
 		for _, opt := range m.Options {
 			if opt == "volatile" {
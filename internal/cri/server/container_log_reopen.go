@@ -21,7 +21,10 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/containerd/log"
 	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	cio "github.com/basuotian/containerd/internal/cri/io"
 )
 
 // ReopenContainerLog asks the cri plugin to reopen the stdout/stderr log file for the container.
@@ -37,7 +40,7 @@ func (c *criService) ReopenContainerLog(ctx context.Context, r *runtime.ReopenCo
 	}
 
 	// Create new container logger and replace the existing ones.
-	stdoutWC, stderrWC, err := c.createContainerLoggers(container.LogPath, container.Config.GetTty())
+	stdoutWC, stderrWC, err := c.createContainerLoggers(container.ID, container.LogPath, container.Config.GetTty(), container.SandboxID)
 	if err != nil {
 		return nil, err
 	}
@@ -48,5 +51,15 @@ func (c *criService) ReopenContainerLog(ctx context.Context, r *runtime.ReopenCo
 	if oldStderrWC != nil {
 		oldStderrWC.Close()
 	}
+
+	// The kubelet renames the log file before asking for a reopen, gzip
+	// the rotated files left next to the new log when configured.
+	if _, compress := c.containerLogSettings(container.SandboxID); compress && container.LogPath != "" {
+		go func() {
+			if err := cio.CompressRotatedLogFiles(container.LogPath); err != nil {
+				log.G(ctx).WithError(err).Warnf("Failed to compress rotated logs of container %q", container.ID)
+			}
+		}()
+	}
 	return &runtime.ReopenContainerLogResponse{}, nil
 }
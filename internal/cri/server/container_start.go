@@ -31,6 +31,7 @@ import (
 	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
 
 	containerd "github.com/basuotian/containerd/client"
+	criconfig "github.com/basuotian/containerd/internal/cri/config"
 	cio "github.com/basuotian/containerd/internal/cri/io"
 	containerstore "github.com/basuotian/containerd/internal/cri/store/container"
 	sandboxstore "github.com/basuotian/containerd/internal/cri/store/sandbox"
@@ -96,7 +97,7 @@ func (c *criService) StartContainer(ctx context.Context, r *runtime.StartContain
 	span.SetAttributes(tracing.Attribute("sandbox.id", sandboxID))
 
 	ioCreation := func(id string) (_ containerdio.IO, err error) {
-		stdoutWC, stderrWC, err := c.createContainerLoggers(meta.LogPath, config.GetTty())
+		stdoutWC, stderrWC, err := c.createContainerLoggers(id, meta.LogPath, config.GetTty(), meta.SandboxID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create container loggers: %w", err)
 		}
@@ -316,8 +317,23 @@ func resetContainerStarting(container containerstore.Container) error {
 	})
 }
 
+// containerLogSettings returns the log format settings of the runtime
+// handler the container's sandbox runs under, falling back to the default
+// CRI format when the sandbox or its runtime cannot be resolved.
+func (c *criService) containerLogSettings(sandboxID string) (cio.LogFormat, bool) {
+	sandbox, err := c.sandboxStore.Get(sandboxID)
+	if err != nil {
+		return cio.LogFormatCRI, false
+	}
+	ociRuntime, err := c.config.GetSandboxRuntime(sandbox.Config, sandbox.Metadata.RuntimeHandler)
+	if err != nil {
+		return cio.LogFormatCRI, false
+	}
+	return cio.LogFormat(ociRuntime.ContainerLogFormat), ociRuntime.CompressRotatedLogs
+}
+
 // createContainerLoggers creates container loggers and return write closer for stdout and stderr.
-func (c *criService) createContainerLoggers(logPath string, tty bool) (stdout io.WriteCloser, stderr io.WriteCloser, err error) {
+func (c *criService) createContainerLoggers(id, logPath string, tty bool, sandboxID string) (stdout io.WriteCloser, stderr io.WriteCloser, err error) {
 	if logPath != "" {
 		// Only generate container log when log path is specified.
 		f, err := openLogFile(logPath)
@@ -329,12 +345,28 @@ func (c *criService) createContainerLoggers(logPath string, tty bool) (stdout io
 				f.Close()
 			}
 		}()
-		var stdoutCh, stderrCh <-chan struct{}
-		wc := cioutil.NewSerialWriteCloser(f)
-		stdout, stdoutCh = cio.NewCRILogger(logPath, wc, cio.Stdout, c.config.MaxContainerLogLineSize)
+		var (
+			stdoutCh, stderrCh <-chan struct{}
+			w                  io.Writer = cioutil.NewSerialWriteCloser(f)
+			stallWriter        io.WriteCloser
+		)
+		if timeout := c.containerLogWriteTimeout(); timeout > 0 {
+			stallWriter = cio.NewStallWriter(w, timeout, func() {
+				c.handleContainerLogStall(id, logPath)
+			})
+			w = stallWriter
+		}
+		format, _ := c.containerLogSettings(sandboxID)
+		stdout, stdoutCh, err = cio.NewFormattedLogger(logPath, w, cio.Stdout, c.config.MaxContainerLogLineSize, format)
+		if err != nil {
+			return nil, nil, err
+		}
 		// Only redirect stderr when there is no tty.
 		if !tty {
-			stderr, stderrCh = cio.NewCRILogger(logPath, wc, cio.Stderr, c.config.MaxContainerLogLineSize)
+			stderr, stderrCh, err = cio.NewFormattedLogger(logPath, w, cio.Stderr, c.config.MaxContainerLogLineSize, format)
+			if err != nil {
+				return nil, nil, err
+			}
 		}
 		go func() {
 			if stdoutCh != nil {
@@ -344,6 +376,9 @@ func (c *criService) createContainerLoggers(logPath string, tty bool) (stdout io
 				<-stderrCh
 			}
 			log.L.Debugf("Finish redirecting log file %q, closing it", logPath)
+			if stallWriter != nil {
+				stallWriter.Close()
+			}
 			f.Close()
 		}()
 	} else {
@@ -352,3 +387,38 @@ func (c *criService) createContainerLoggers(logPath string, tty bool) (stdout io
 	}
 	return
 }
+
+// containerLogWriteTimeout returns the configured container log write
+// timeout, or zero when stall detection is disabled.
+func (c *criService) containerLogWriteTimeout() time.Duration {
+	if c.config.ContainerLogWriteTimeout == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(c.config.ContainerLogWriteTimeout)
+	if err != nil {
+		// The timeout is validated when the config is loaded.
+		log.L.WithError(err).Errorf("Invalid container log write timeout %q", c.config.ContainerLogWriteTimeout)
+		return 0
+	}
+	return timeout
+}
+
+// handleContainerLogStall applies the configured stall action after a
+// container log write blocked longer than the configured timeout.
+func (c *criService) handleContainerLogStall(id, logPath string) {
+	log.L.Errorf("Log writer for container %q stalled, dropping log entries written to %q", id, logPath)
+	if c.config.ContainerLogStallAction != criconfig.LogStallActionKill {
+		return
+	}
+	go func() {
+		ctx := ctrdutil.NamespacedContext()
+		container, err := c.containerStore.Get(id)
+		if err != nil {
+			log.L.WithError(err).Errorf("Failed to find container %q to stop after log stall", id)
+			return
+		}
+		if err := c.stopContainer(ctx, container, 0); err != nil {
+			log.L.WithError(err).Errorf("Failed to stop container %q after log stall", id)
+		}
+	}()
+}
@@ -367,6 +367,12 @@ func (c *criService) linuxContainerMetrics(
 		usedBytes = sn.Size
 		inodesUsed = sn.Inodes
 	}
+	// an enforced ephemeral storage quota reports usage itself, cheaper
+	// and more current than the periodically synced snapshot usage
+	if u, err := c.ephemeralStorageUsage(context.Background(), meta.ID, snapshotter); err == nil {
+		usedBytes = u.Bytes
+		inodesUsed = u.Inodes
+	}
 	cs.WritableLayer = &runtime.FilesystemUsage{
 		Timestamp: sn.Timestamp,
 		FsId: &runtime.FilesystemIdentifier{
@@ -74,7 +74,20 @@ func (c *criService) ContainerStatus(ctx context.Context, r *runtime.ContainerSt
 		status.CreatedAt = info.CreatedAt.UnixNano()
 	}
 
-	info, err := toCRIContainerInfo(ctx, container, r.GetVerbose())
+	var stats *runtime.ContainerStats
+	if r.GetVerbose() {
+		// collect on-demand stats, including the filesystem usage of the
+		// writable layer, on a best effort basis: the container may have
+		// no running task to report metrics
+		resp, err := c.ContainerStats(ctx, &runtime.ContainerStatsRequest{ContainerId: r.GetContainerId()})
+		if err != nil {
+			log.G(ctx).WithError(err).Debugf("failed to get stats for container %q", r.GetContainerId())
+		} else {
+			stats = resp.GetStats()
+		}
+	}
+
+	info, err := toCRIContainerInfo(ctx, container, stats, r.GetVerbose())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get verbose container info: %w", err)
 	}
@@ -147,10 +160,13 @@ type ContainerInfo struct {
 	RuntimeOptions interface{}              `json:"runtimeOptions"`
 	Config         *runtime.ContainerConfig `json:"config"`
 	RuntimeSpec    *runtimespec.Spec        `json:"runtimeSpec"`
+	// Stats hold on-demand container stats, including the filesystem
+	// usage of the writable layer, collected when the status was queried
+	Stats *runtime.ContainerStats `json:"stats,omitempty"`
 }
 
 // toCRIContainerInfo converts internal container object information to CRI container status response info map.
-func toCRIContainerInfo(ctx context.Context, container containerstore.Container, verbose bool) (map[string]string, error) {
+func toCRIContainerInfo(ctx context.Context, container containerstore.Container, stats *runtime.ContainerStats, verbose bool) (map[string]string, error) {
 	if !verbose {
 		return nil, nil
 	}
@@ -164,6 +180,7 @@ func toCRIContainerInfo(ctx context.Context, container containerstore.Container,
 		Pid:       status.Pid,
 		Removing:  status.Removing,
 		Config:    meta.Config,
+		Stats:     stats,
 	}
 
 	var err error
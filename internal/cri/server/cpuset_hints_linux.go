@@ -0,0 +1,76 @@
+//go:build linux
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/basuotian/containerd/internal/cri/annotations"
+)
+
+// cpusetHints resolves the cpu pinning hints of a container from its
+// annotations and the pod annotations, translating kubelet cpu and memory
+// manager placement decisions into a precise cpuset and NUMA memory
+// binding. The container annotation wins over the pod annotation, and an
+// explicit cpuset in the container resources wins over both since it is
+// already applied through WithResources. The hints are validated against
+// the node topology discovered at startup.
+func (c *criService) cpusetHints(config *runtime.ContainerConfig, sandboxConfig *runtime.PodSandboxConfig) (cpus, mems string, err error) {
+	cpus = config.GetAnnotations()[annotations.CPUSetCPUs]
+	if cpus == "" {
+		cpus = sandboxConfig.GetAnnotations()[annotations.CPUSetCPUs]
+	}
+	mems = config.GetAnnotations()[annotations.CPUSetMems]
+	if mems == "" {
+		mems = sandboxConfig.GetAnnotations()[annotations.CPUSetMems]
+	}
+
+	resources := config.GetLinux().GetResources()
+	if resources.GetCpusetCpus() != "" {
+		cpus = resources.GetCpusetCpus()
+	}
+	if resources.GetCpusetMems() != "" {
+		mems = resources.GetCpusetMems()
+	}
+
+	if c.topology != nil {
+		if cpus != "" {
+			if err := c.topology.ValidateCPUs(cpus); err != nil {
+				return "", "", fmt.Errorf("cpuset cpus hint: %w", err)
+			}
+		}
+		if mems != "" {
+			if err := c.topology.ValidateMems(mems); err != nil {
+				return "", "", fmt.Errorf("cpuset mems hint: %w", err)
+			}
+		}
+	}
+
+	// The resource fields are already applied, only the annotation hints
+	// need to go into the spec.
+	if resources.GetCpusetCpus() != "" {
+		cpus = ""
+	}
+	if resources.GetCpusetMems() != "" {
+		mems = ""
+	}
+	return cpus, mems, nil
+}
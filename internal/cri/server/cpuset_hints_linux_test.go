@@ -0,0 +1,96 @@
+//go:build linux
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/basuotian/containerd/internal/cri/annotations"
+	"github.com/basuotian/containerd/internal/cri/topology"
+)
+
+func TestCpusetHints(t *testing.T) {
+	c := newTestCRIService()
+	c.topology = &topology.NodeTopology{
+		CPUs: map[int]struct{}{0: {}, 1: {}, 2: {}, 3: {}},
+		Mems: map[int]struct{}{0: {}, 1: {}},
+	}
+
+	sandboxConfig := &runtime.PodSandboxConfig{
+		Annotations: map[string]string{
+			annotations.CPUSetCPUs: "0-1",
+			annotations.CPUSetMems: "0",
+		},
+	}
+
+	// Pod annotations apply to containers without their own hints.
+	cpus, mems, err := c.cpusetHints(&runtime.ContainerConfig{}, sandboxConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "0-1", cpus)
+	assert.Equal(t, "0", mems)
+
+	// Container annotations win over the pod annotations.
+	cpus, mems, err = c.cpusetHints(&runtime.ContainerConfig{
+		Annotations: map[string]string{
+			annotations.CPUSetCPUs: "2-3",
+			annotations.CPUSetMems: "1",
+		},
+	}, sandboxConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "2-3", cpus)
+	assert.Equal(t, "1", mems)
+
+	// An explicit cpuset in the resources wins and is still validated,
+	// but is not returned since WithResources already applies it.
+	cpus, mems, err = c.cpusetHints(&runtime.ContainerConfig{
+		Linux: &runtime.LinuxContainerConfig{
+			Resources: &runtime.LinuxContainerResources{
+				CpusetCpus: "3",
+				CpusetMems: "1",
+			},
+		},
+	}, sandboxConfig)
+	require.NoError(t, err)
+	assert.Empty(t, cpus)
+	assert.Empty(t, mems)
+
+	// Hints naming offline cpus or NUMA nodes are rejected.
+	_, _, err = c.cpusetHints(&runtime.ContainerConfig{
+		Annotations: map[string]string{annotations.CPUSetCPUs: "0-4"},
+	}, sandboxConfig)
+	assert.Error(t, err)
+	_, _, err = c.cpusetHints(&runtime.ContainerConfig{
+		Linux: &runtime.LinuxContainerConfig{
+			Resources: &runtime.LinuxContainerResources{CpusetMems: "2"},
+		},
+	}, sandboxConfig)
+	assert.Error(t, err)
+
+	// Without a discovered topology hints pass through unvalidated.
+	c.topology = nil
+	cpus, _, err = c.cpusetHints(&runtime.ContainerConfig{
+		Annotations: map[string]string{annotations.CPUSetCPUs: "0-63"},
+	}, sandboxConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "0-63", cpus)
+}
@@ -0,0 +1,113 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/basuotian/containerd/core/mount"
+	"github.com/basuotian/containerd/internal/cri/annotations"
+	"github.com/basuotian/containerd/pkg/fsquota"
+)
+
+// applyEphemeralStorageQuota enforces the pod ephemeral storage limit
+// annotation by placing project quotas on the container writable layer and
+// its log directory. Filesystems without project quota support only get a
+// warning, so pods keep working with kubelet eviction as before.
+func (c *criService) applyEphemeralStorageQuota(ctx context.Context, id, snapshotter string, sandboxConfig *runtime.PodSandboxConfig, logPath string) error {
+	if !c.config.EnforceEphemeralStorageQuota {
+		return nil
+	}
+	value := sandboxConfig.GetAnnotations()[annotations.EphemeralStorageLimit]
+	if value == "" {
+		return nil
+	}
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid ephemeral storage limit %q: %w", value, err)
+	}
+
+	mounts, err := c.client.SnapshotService(snapshotter).Mounts(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get mounts of container %q: %w", id, err)
+	}
+	dirs := make([]string, 0, 2)
+	if dir, err := writableLayerPath(mounts); err != nil {
+		log.G(ctx).WithError(err).Warnf("cannot enforce ephemeral storage limit on writable layer of container %q", id)
+	} else {
+		dirs = append(dirs, dir)
+	}
+	if logPath != "" {
+		dirs = append(dirs, filepath.Dir(logPath))
+	}
+
+	for _, dir := range dirs {
+		if err := fsquota.SetProjectQuota(dir, limit); err != nil {
+			if errdefs.IsNotImplemented(err) {
+				log.G(ctx).WithError(err).Warnf("ephemeral storage limit on %q not enforced", dir)
+				continue
+			}
+			return fmt.Errorf("failed to enforce ephemeral storage limit on %q: %w", dir, err)
+		}
+		log.G(ctx).Debugf("Enforcing ephemeral storage limit of %d bytes on %q", limit, dir)
+	}
+	return nil
+}
+
+// ephemeralStorageUsage returns the project quota usage of the container
+// writable layer when one is enforced. It is much cheaper than walking the
+// snapshot and reflects writes immediately.
+func (c *criService) ephemeralStorageUsage(ctx context.Context, id, snapshotter string) (*fsquota.Usage, error) {
+	if !c.config.EnforceEphemeralStorageQuota {
+		return nil, errdefs.ErrNotFound
+	}
+	mounts, err := c.client.SnapshotService(snapshotter).Mounts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := writableLayerPath(mounts)
+	if err != nil {
+		return nil, err
+	}
+	return fsquota.GetUsage(dir)
+}
+
+// writableLayerPath returns the directory writes of a container land in for
+// the given rootfs mounts.
+func writableLayerPath(mounts []mount.Mount) (string, error) {
+	for _, m := range mounts {
+		switch m.Type {
+		case "overlay":
+			for _, o := range m.Options {
+				if upper, ok := strings.CutPrefix(o, "upperdir="); ok {
+					return upper, nil
+				}
+			}
+		case "bind", "rbind":
+			return m.Source, nil
+		}
+	}
+	return "", fmt.Errorf("cannot determine writable layer from mounts: %w", errdefs.ErrNotImplemented)
+}
@@ -0,0 +1,84 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/basuotian/containerd/core/mount"
+)
+
+func TestWritableLayerPath(t *testing.T) {
+	for _, test := range []struct {
+		desc      string
+		mounts    []mount.Mount
+		expected  string
+		expectErr bool
+	}{
+		{
+			desc: "overlay upperdir",
+			mounts: []mount.Mount{
+				{
+					Type: "overlay",
+					Options: []string{
+						"index=off",
+						"lowerdir=/var/lib/snapshots/1/fs",
+						"upperdir=/var/lib/snapshots/2/fs",
+						"workdir=/var/lib/snapshots/2/work",
+					},
+				},
+			},
+			expected: "/var/lib/snapshots/2/fs",
+		},
+		{
+			desc: "bind mount",
+			mounts: []mount.Mount{
+				{
+					Type:   "bind",
+					Source: "/var/lib/snapshots/2/fs",
+				},
+			},
+			expected: "/var/lib/snapshots/2/fs",
+		},
+		{
+			desc: "overlay without upperdir",
+			mounts: []mount.Mount{
+				{
+					Type:    "overlay",
+					Options: []string{"lowerdir=/var/lib/snapshots/1/fs"},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			desc:      "no mounts",
+			expectErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			dir, err := writableLayerPath(test.mounts)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, dir)
+		})
+	}
+}
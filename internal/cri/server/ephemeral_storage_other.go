@@ -0,0 +1,38 @@
+//go:build !linux
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+
+	"github.com/containerd/errdefs"
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/basuotian/containerd/pkg/fsquota"
+)
+
+// applyEphemeralStorageQuota is only supported on Linux.
+func (c *criService) applyEphemeralStorageQuota(ctx context.Context, id, snapshotter string, sandboxConfig *runtime.PodSandboxConfig, logPath string) error {
+	return nil
+}
+
+// ephemeralStorageUsage is only supported on Linux.
+func (c *criService) ephemeralStorageUsage(ctx context.Context, id, snapshotter string) (*fsquota.Usage, error) {
+	return nil, errdefs.ErrNotFound
+}
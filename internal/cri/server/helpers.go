@@ -366,7 +366,8 @@ func (c *criService) generateAndSendContainerEvent(ctx context.Context, containe
 		ContainersStatuses: containerStatuses,
 	}
 
-	c.containerEventsQ.Send(event)
+	seq := c.containerEvents.append(&event)
+	c.containerEventsQ.Send(sequencedEvent{Sequence: seq, Event: event})
 }
 
 func (c *criService) getPodSandboxRuntime(sandboxID string) (runtime criconfig.Runtime, err error) {
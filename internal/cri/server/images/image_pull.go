@@ -32,8 +32,6 @@ import (
 	"time"
 
 	"github.com/containerd/errdefs"
-	"github.com/containerd/imgcrypt/v2"
-	"github.com/containerd/imgcrypt/v2/images/encryption"
 	"github.com/containerd/log"
 	"github.com/containerd/platforms"
 	distribution "github.com/distribution/reference"
@@ -43,6 +41,7 @@ import (
 	containerd "github.com/basuotian/containerd/client"
 	"github.com/basuotian/containerd/core/diff"
 	containerdimages "github.com/basuotian/containerd/core/images"
+	"github.com/basuotian/containerd/core/images/encryption"
 	"github.com/basuotian/containerd/core/remotes/docker"
 	"github.com/basuotian/containerd/core/remotes/docker/config"
 	"github.com/basuotian/containerd/core/transfer"
@@ -165,6 +164,16 @@ func (c *CRIImageService) PullImage(ctx context.Context, name string, credential
 		return "", fmt.Errorf("failed to parse image_pull_progress_timeout %q: %w", c.config.ImagePullProgressTimeout, err)
 	}
 
+	// A registry may override the global activity deadline, e.g. a slow
+	// mirror that delivers steadily but needs a longer stall window.
+	if hostConfig, ok := c.config.Registry.Configs[distribution.Domain(namedRef)]; ok && hostConfig.ImagePullProgressTimeout != "" {
+		imagePullProgressTimeout, err = time.ParseDuration(hostConfig.ImagePullProgressTimeout)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse image_pull_progress_timeout %q for registry %q: %w",
+				hostConfig.ImagePullProgressTimeout, distribution.Domain(namedRef), err)
+		}
+	}
+
 	snapshotter, err := c.snapshotterFromPodSandboxConfig(ctx, ref, sandboxConfig)
 	if err != nil {
 		return "", err
@@ -419,6 +428,9 @@ func (c *CRIImageService) getLabels(ctx context.Context, name string) map[string
 			labels[crilabels.PinnedImageLabelKey] = crilabels.PinnedImageLabelValue
 		}
 	}
+	if c.prewarm != nil && c.prewarm.isWanted(name) {
+		labels[crilabels.PinnedImageLabelKey] = crilabels.PinnedImageLabelValue
+	}
 	return labels
 }
 
@@ -636,10 +648,9 @@ func (c *CRIImageService) registryEndpoints(host string) ([]string, error) {
 
 // encryptedImagesPullOpts returns the necessary list of pull options required
 // for decryption of encrypted images based on the cri decryption configuration.
-// Temporarily removed for v2 upgrade
 func (c *CRIImageService) encryptedImagesPullOpts() []containerd.RemoteOpt {
 	if c.config.ImageDecryption.KeyModel == criconfig.KeyModelNode {
-		ltdd := imgcrypt.Payload{}
+		ltdd := encryption.Payload{}
 		decUnpackOpt := encryption.WithUnpackConfigApplyOpts(encryption.WithDecryptedUnpack(&ltdd))
 		opt := containerd.WithUnpackOpts([]containerd.UnpackOpt{decUnpackOpt})
 		return []containerd.RemoteOpt{opt}
@@ -653,6 +664,16 @@ const (
 	defaultPullProgressReportInterval = 10 * time.Second
 )
 
+// registryHostFromRef returns the registry host of an image ref, used as
+// the metric label for progress timeout cancellations.
+func registryHostFromRef(ref string) string {
+	named, err := distribution.ParseDockerRef(ref)
+	if err != nil {
+		return "unknown"
+	}
+	return distribution.Domain(named)
+}
+
 // pullProgressReporter is used to check single PullImage progress.
 type pullProgressReporter struct {
 	ref         string
@@ -721,6 +742,7 @@ func (reporter *pullProgressReporter) start(ctx context.Context) {
 
 				if time.Since(lastSeenTimestamp) > reporter.timeout {
 					log.G(ctx).Errorf("cancel pulling image %s because of no progress in %v", reporter.ref, reporter.timeout)
+					imagePullProgressTimeouts.WithValues(registryHostFromRef(reporter.ref)).Inc()
 					reporter.cancel()
 					return
 				}
@@ -1021,6 +1043,7 @@ func (reporter *transferProgressReporter) checkProgress(ctx context.Context, rep
 
 	if time.Since(lastSeenTimestamp) > reporter.timeout {
 		log.G(ctx).Errorf("cancel pulling image %s because of no progress in %v", reporter.ref, reporter.timeout)
+		imagePullProgressTimeouts.WithValues(registryHostFromRef(reporter.ref)).Inc()
 		reporter.cancel()
 	}
 }
@@ -737,3 +737,14 @@ func TestTransferProgressReporter(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistryHostFromRef(t *testing.T) {
+	for ref, host := range map[string]string{
+		"docker.io/library/busybox:latest": "docker.io",
+		"registry.example.com:5000/app:v1": "registry.example.com:5000",
+		"busybox":                          "docker.io",
+		"invalid ref":                      "unknown",
+	} {
+		assert.Equal(t, host, registryHostFromRef(ref), ref)
+	}
+}
@@ -26,6 +26,8 @@ var (
 	inProgressImagePulls metrics.Gauge
 	// image size in MB / image pull duration in seconds
 	imagePullThroughput prom.Histogram
+	// pulls cancelled because no data was received within the progress deadline
+	imagePullProgressTimeouts metrics.LabeledCounter
 )
 
 func init() {
@@ -38,6 +40,7 @@ func init() {
 	ns := metrics.NewNamespace(namespace, subsystem, nil)
 
 	imagePulls = ns.NewLabeledCounter("image_pulls", "succeeded and failed counters", "status")
+	imagePullProgressTimeouts = ns.NewLabeledCounter("image_pull_progress_timeouts", "pulls cancelled for making no progress within the deadline", "registry")
 	inProgressImagePulls = ns.NewGauge("in_progress_image_pulls", "in progress pulls", metrics.Total)
 	imagePullThroughput = prom.NewHistogram(
 		prom.HistogramOpts{
@@ -0,0 +1,328 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	docker "github.com/distribution/reference"
+	"github.com/fsnotify/fsnotify"
+	"sigs.k8s.io/yaml"
+
+	"github.com/basuotian/containerd/internal/cri/constants"
+	crilabels "github.com/basuotian/containerd/internal/cri/labels"
+	"github.com/basuotian/containerd/pkg/namespaces"
+)
+
+const (
+	// prewarmRetryInitial is the first retry delay after a failed pull.
+	prewarmRetryInitial = time.Second
+	// prewarmRetryMax caps the exponential retry backoff per image.
+	prewarmRetryMax = 5 * time.Minute
+	// prewarmResyncPeriod is how often the wanted set is re-checked even
+	// without manifest changes, re-pulling images removed behind our back.
+	prewarmResyncPeriod = time.Minute
+)
+
+// prewarmManifest is the on-disk format of the pre-warm manifest. The file
+// may be JSON or YAML.
+type prewarmManifest struct {
+	Images []string `json:"images"`
+}
+
+// parsePrewarmManifest parses manifest data and returns the normalized image
+// references it lists. Invalid references fail the whole manifest so a typo
+// does not silently drop an image from the wanted set.
+func parsePrewarmManifest(data []byte) ([]string, error) {
+	var manifest prewarmManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse pre-warm manifest: %w", err)
+	}
+	refs := make([]string, 0, len(manifest.Images))
+	for _, ref := range manifest.Images {
+		named, err := docker.ParseDockerRef(ref)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image reference %q in pre-warm manifest: %w", ref, err)
+		}
+		refs = append(refs, named.String())
+	}
+	return refs, nil
+}
+
+// prewarmController keeps the images listed in a node-local manifest file
+// pulled and pinned, so node images like pause or logging sidecars are warm
+// before any pod references them. The manifest is watched for changes and
+// failed pulls are retried with exponential backoff.
+type prewarmController struct {
+	c    *CRIImageService
+	path string
+
+	// kick wakes the ensure loop after the wanted set changed.
+	kick chan struct{}
+
+	mu      sync.RWMutex
+	wanted  map[string]struct{}
+	retryAt map[string]time.Time
+	backoff map[string]time.Duration
+}
+
+func newPrewarmController(c *CRIImageService, path string) *prewarmController {
+	return &prewarmController{
+		c:       c,
+		path:    path,
+		kick:    make(chan struct{}, 1),
+		wanted:  make(map[string]struct{}),
+		retryAt: make(map[string]time.Time),
+		backoff: make(map[string]time.Duration),
+	}
+}
+
+// start loads the manifest and launches the watch and ensure loops.
+func (p *prewarmController) start() {
+	ctx := namespaces.WithNamespace(context.Background(), constants.K8sContainerdNamespace)
+	p.reload(ctx)
+	go p.watchLoop(ctx)
+	go p.ensureLoop(ctx)
+}
+
+// isWanted reports whether the named image is in the manifest, so getLabels
+// pins it the same way configured pinned images are pinned.
+func (p *prewarmController) isWanted(name string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.wanted[name]
+	return ok
+}
+
+// watchLoop reloads the manifest on fs change events, following the cni conf
+// syncer approach of watching the parent directory so atomic renames onto
+// the manifest path are seen.
+func (p *prewarmController) watchLoop(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.G(ctx).WithError(err).Error("failed to create pre-warm manifest watcher")
+		return
+	}
+	defer watcher.Close()
+
+	dir := filepath.Dir(p.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to create pre-warm manifest dir %q", dir)
+		return
+	}
+	if err := watcher.Add(dir); err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to watch pre-warm manifest dir %q", dir)
+		return
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Name != p.path || event.Has(fsnotify.Chmod) {
+				continue
+			}
+			log.G(ctx).Debugf("pre-warm manifest change event: %s", event)
+			p.reload(ctx)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.G(ctx).WithError(err).Error("pre-warm manifest watcher error")
+		}
+	}
+}
+
+// reload reads the manifest and replaces the wanted set, unpinning images
+// which are no longer listed. A missing manifest is treated as empty so
+// deleting the file releases every pre-warmed image; a malformed manifest
+// keeps the last good set.
+func (p *prewarmController) reload(ctx context.Context) {
+	var refs []string
+	data, err := os.ReadFile(p.path)
+	switch {
+	case err == nil:
+		refs, err = parsePrewarmManifest(data)
+		if err != nil {
+			log.G(ctx).WithError(err).Errorf("keeping previous pre-warm set, manifest %q is invalid", p.path)
+			return
+		}
+	case os.IsNotExist(err):
+		log.G(ctx).Debugf("pre-warm manifest %q does not exist, nothing to pre-warm", p.path)
+	default:
+		log.G(ctx).WithError(err).Errorf("keeping previous pre-warm set, failed to read manifest %q", p.path)
+		return
+	}
+
+	wanted := make(map[string]struct{}, len(refs))
+	for _, ref := range refs {
+		wanted[ref] = struct{}{}
+	}
+
+	var removed []string
+	p.mu.Lock()
+	for ref := range p.wanted {
+		if _, ok := wanted[ref]; !ok {
+			removed = append(removed, ref)
+			delete(p.retryAt, ref)
+			delete(p.backoff, ref)
+		}
+	}
+	p.wanted = wanted
+	p.mu.Unlock()
+
+	for _, ref := range removed {
+		p.unpin(ctx, ref)
+	}
+
+	select {
+	case p.kick <- struct{}{}:
+	default:
+	}
+}
+
+// ensureLoop drives ensure whenever the wanted set changes, a retry comes
+// due or the resync period elapses.
+func (p *prewarmController) ensureLoop(ctx context.Context) {
+	for {
+		wait := p.ensure(ctx)
+		if wait <= 0 || wait > prewarmResyncPeriod {
+			wait = prewarmResyncPeriod
+		}
+		select {
+		case <-p.kick:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// ensure pulls and pins every wanted image which is missing, returning how
+// long until the earliest pending retry.
+func (p *prewarmController) ensure(ctx context.Context) time.Duration {
+	p.mu.RLock()
+	refs := make([]string, 0, len(p.wanted))
+	for ref := range p.wanted {
+		refs = append(refs, ref)
+	}
+	p.mu.RUnlock()
+	sort.Strings(refs)
+
+	var next time.Duration
+	for _, ref := range refs {
+		p.mu.RLock()
+		at := p.retryAt[ref]
+		p.mu.RUnlock()
+		if wait := time.Until(at); wait > 0 {
+			if next == 0 || wait < next {
+				next = wait
+			}
+			continue
+		}
+
+		if img, err := p.c.LocalResolve(ref); err == nil {
+			if img.Pinned {
+				p.clearBackoff(ref)
+				continue
+			}
+			// Present but pulled outside the manifest, re-apply the
+			// CRI labels so it is pinned.
+			if err := p.c.UpdateImage(ctx, ref); err != nil {
+				log.G(ctx).WithError(err).Errorf("failed to pin pre-warmed image %q, retrying in %v", ref, p.nextBackoff(ref))
+			} else {
+				log.G(ctx).Infof("pinned already present pre-warm image %q", ref)
+				p.clearBackoff(ref)
+			}
+			continue
+		}
+
+		log.G(ctx).Infof("pre-warm pull of image %q starting", ref)
+		start := time.Now()
+		if _, err := p.c.PullImage(ctx, ref, nil, nil, ""); err != nil {
+			retry := p.nextBackoff(ref)
+			log.G(ctx).WithError(err).Errorf("pre-warm pull of image %q failed, retrying in %v", ref, retry)
+			if next == 0 || retry < next {
+				next = retry
+			}
+			continue
+		}
+		log.G(ctx).Infof("pre-warm pull of image %q done in %v", ref, time.Since(start))
+		p.clearBackoff(ref)
+	}
+	return next
+}
+
+// nextBackoff doubles the retry delay for the image up to prewarmRetryMax
+// and records when the next attempt is due.
+func (p *prewarmController) nextBackoff(ref string) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b := p.backoff[ref] * 2
+	if b == 0 {
+		b = prewarmRetryInitial
+	} else if b > prewarmRetryMax {
+		b = prewarmRetryMax
+	}
+	p.backoff[ref] = b
+	p.retryAt[ref] = time.Now().Add(b)
+	return b
+}
+
+func (p *prewarmController) clearBackoff(ref string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.backoff, ref)
+	delete(p.retryAt, ref)
+}
+
+// unpin drops the pinned label from an image which left the manifest, unless
+// it is also pinned through the image config.
+func (p *prewarmController) unpin(ctx context.Context, ref string) {
+	for _, pinned := range p.c.config.PinnedImages {
+		if pinned == ref {
+			return
+		}
+	}
+	img, err := p.c.images.Get(ctx, ref)
+	if err != nil {
+		if !errdefs.IsNotFound(err) {
+			log.G(ctx).WithError(err).Errorf("failed to get image %q for unpinning", ref)
+		}
+		return
+	}
+	if img.Labels[crilabels.PinnedImageLabelKey] != crilabels.PinnedImageLabelValue {
+		return
+	}
+	img.Labels[crilabels.PinnedImageLabelKey] = ""
+	if _, err := p.c.images.Update(ctx, img, "labels."+crilabels.PinnedImageLabelKey); err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to unpin image %q", ref)
+		return
+	}
+	if err := p.c.UpdateImage(ctx, ref); err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to refresh unpinned image %q", ref)
+	}
+	log.G(ctx).Infof("unpinned image %q removed from pre-warm manifest", ref)
+}
@@ -0,0 +1,71 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package images
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrewarmManifest(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		data     string
+		expected []string
+		err      bool
+	}{
+		{
+			desc:     "JSON manifest with normalization",
+			data:     `{"images": ["busybox", "registry.k8s.io/pause:3.10"]}`,
+			expected: []string{"docker.io/library/busybox:latest", "registry.k8s.io/pause:3.10"},
+		},
+		{
+			desc: "YAML manifest",
+			data: "images:\n- docker.io/library/ubuntu:22.04\n",
+			expected: []string{
+				"docker.io/library/ubuntu:22.04",
+			},
+		},
+		{
+			desc:     "empty manifest",
+			data:     `{}`,
+			expected: []string{},
+		},
+		{
+			desc: "invalid image reference",
+			data: `{"images": ["UPPERCASE:latest"]}`,
+			err:  true,
+		},
+		{
+			desc: "malformed document",
+			data: `{"images": "not-a-list"}`,
+			err:  true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			refs, err := parsePrewarmManifest([]byte(test.data))
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, refs)
+		})
+	}
+}
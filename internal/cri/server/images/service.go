@@ -76,6 +76,10 @@ type CRIImageService struct {
 
 	// downloadLimiter is used to limit the number of concurrent downloads.
 	downloadLimiter *semaphore.Weighted
+
+	// prewarm keeps the images listed in the configured manifest pulled
+	// and pinned, nil when pre-warming is disabled.
+	prewarm *prewarmController
 }
 
 type GRPCCRIImageService struct {
@@ -134,6 +138,12 @@ func NewService(config criconfig.ImageConfig, options *CRIImageServiceOptions) (
 	)
 	snapshotsSyncer.start()
 
+	if config.PrewarmManifestPath != "" {
+		log.L.Infof("Start image pre-warm controller for manifest %q", config.PrewarmManifestPath)
+		svc.prewarm = newPrewarmController(&svc, config.PrewarmManifestPath)
+		svc.prewarm.start()
+	}
+
 	return &svc, nil
 }
 
@@ -40,6 +40,12 @@ var (
 	networkPluginOperations        metrics.LabeledCounter
 	networkPluginOperationsErrors  metrics.LabeledCounter
 	networkPluginOperationsLatency metrics.LabeledTimer
+
+	networkBandwidthOperations       metrics.LabeledCounter
+	networkBandwidthOperationsErrors metrics.LabeledCounter
+
+	reconcileTimer       metrics.Timer
+	reconcileCorrections metrics.LabeledCounter
 )
 
 func init() {
@@ -66,6 +72,12 @@ func init() {
 	networkPluginOperationsErrors = ns.NewLabeledCounter("network_plugin_operations_errors_total", "cumulative number of network plugin operations by operation type", "operation_type")
 	networkPluginOperationsLatency = ns.NewLabeledTimer("network_plugin_operations_duration_seconds", "latency in seconds of network plugin operations. Broken down by operation type", "operation_type")
 
+	networkBandwidthOperations = ns.NewLabeledCounter("network_bandwidth_operations_total", "cumulative number of fallback tc bandwidth shaping operations by operation type", "operation_type")
+	networkBandwidthOperationsErrors = ns.NewLabeledCounter("network_bandwidth_operations_errors_total", "cumulative number of failed fallback tc bandwidth shaping operations by operation type", "operation_type")
+
+	reconcileTimer = ns.NewTimer("reconcile", "time to reconcile CRI state with containerd")
+	reconcileCorrections = ns.NewLabeledCounter("reconcile_corrections_total", "cumulative number of state divergences repaired by the reconciler, by kind of correction", "correction")
+
 	metrics.Register(ns)
 }
 
@@ -76,3 +88,8 @@ const (
 	networkSetUpOp    = "set_up_pod"
 	networkTearDownOp = "tear_down_pod"
 )
+
+const (
+	networkShapePodOp   = "shape_pod"
+	networkUnshapePodOp = "unshape_pod"
+)
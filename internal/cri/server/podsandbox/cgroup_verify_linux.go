@@ -0,0 +1,260 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package podsandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containerd/cgroups/v3"
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+	systemddbus "github.com/coreos/go-systemd/v22/dbus"
+	"github.com/fsnotify/fsnotify"
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/basuotian/containerd/internal/cri/systemd"
+	ctrdutil "github.com/basuotian/containerd/internal/cri/util"
+)
+
+// podCgroupDriftTopic is the event topic published when systemd changes a
+// pod cgroup limit behind containerd's back.
+const podCgroupDriftTopic = "/cri/pod-cgroup-drift"
+
+// podCgroupVerifyTimeout bounds how long pod start waits for systemd to
+// realize the pod cgroup on disk.
+const podCgroupVerifyTimeout = 10 * time.Second
+
+func init() {
+	typeurl.Register(&PodCgroupDrift{},
+		"github.com/basuotian/containerd/internal/cri/server/podsandbox", "PodCgroupDrift")
+}
+
+// PodCgroupDrift is published on podCgroupDriftTopic when a limit file in a
+// pod cgroup changes after the sandbox started, i.e. something other than
+// containerd reconfigured the pod's resources.
+type PodCgroupDrift struct {
+	// SandboxID is the pod sandbox whose cgroup changed.
+	SandboxID string `json:"sandbox_id"`
+	// File is the name of the changed limit file, e.g. "memory.max".
+	File string `json:"file"`
+	// Previous is the value observed when the sandbox started.
+	Previous string `json:"previous"`
+	// Current is the value observed after the change.
+	Current string `json:"current"`
+}
+
+// podLimitFiles are the cgroup v2 limit files watched for drift.
+var podLimitFiles = []string{"cpu.max", "memory.max", "pids.max"}
+
+// verifyPodCgroup actively checks that systemd realized the pod slice and
+// scope requested through the systemd cgroup driver. It waits for the
+// cgroup directory to appear using inotify, cross-checks the scope unit
+// placement over D-Bus, and starts a watcher which reports limit drift as
+// events. It is a no-op when the pod does not use a systemd cgroup parent.
+func (c *Controller) verifyPodCgroup(ctx context.Context, id string, config *runtime.PodSandboxConfig) error {
+	parent := config.GetLinux().GetCgroupParent()
+	slice := path.Base(parent)
+	if parent == "" || !strings.HasSuffix(slice, ".slice") || !systemd.IsRunningSystemd() {
+		return nil
+	}
+
+	slicePath, err := expandSlice(slice)
+	if err != nil {
+		return err
+	}
+	root := "/sys/fs/cgroup"
+	if cgroups.Mode() != cgroups.Unified {
+		root = filepath.Join(root, "systemd")
+	}
+	scope := "cri-containerd-" + id + ".scope"
+	cgroupDir := filepath.Join(root, slicePath, scope)
+
+	if err := waitForCgroup(ctx, cgroupDir, podCgroupVerifyTimeout); err != nil {
+		return fmt.Errorf("systemd did not create pod cgroup %q: %w; verify that the runtime uses the systemd cgroup driver and that slice %q is not masked", cgroupDir, err, slice)
+	}
+	if err := verifyScopeUnit(ctx, scope, slice); err != nil {
+		return err
+	}
+
+	go c.monitorPodCgroupDrift(id, cgroupDir)
+	return nil
+}
+
+// expandSlice converts a systemd slice name into its path below the cgroup
+// root, e.g. "kubepods-besteffort.slice" becomes
+// "kubepods.slice/kubepods-besteffort.slice".
+func expandSlice(slice string) (string, error) {
+	const suffix = ".slice"
+	name := strings.TrimSuffix(slice, suffix)
+	if name == slice || name == "" || strings.ContainsAny(name, "/.") {
+		return "", fmt.Errorf("invalid systemd slice name %q", slice)
+	}
+	var (
+		slicePath string
+		prefix    string
+	)
+	for _, component := range strings.Split(name, "-") {
+		if component == "" {
+			return "", fmt.Errorf("invalid systemd slice name %q", slice)
+		}
+		slicePath = filepath.Join(slicePath, prefix+component+suffix)
+		prefix += component + "-"
+	}
+	return slicePath, nil
+}
+
+// waitForCgroup waits for dir to appear in the cgroup filesystem. It
+// watches the nearest existing ancestor with inotify and walks down as
+// systemd creates the hierarchy.
+func waitForCgroup(ctx context.Context, dir string, timeout time.Duration) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create cgroup watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	deadline := time.After(timeout)
+	for {
+		watchDir := dir
+		for {
+			if _, err := os.Stat(watchDir); err == nil {
+				break
+			}
+			watchDir = filepath.Dir(watchDir)
+		}
+		if err := watcher.Add(watchDir); err != nil {
+			return fmt.Errorf("failed to watch %q: %w", watchDir, err)
+		}
+		// Re-check after the watch is in place so a creation racing
+		// with the Add is not missed.
+		if _, err := os.Stat(dir); err == nil {
+			return nil
+		}
+		select {
+		case <-watcher.Events:
+			// Part of the hierarchy changed, re-evaluate from the
+			// deepest existing directory.
+			_ = watcher.Remove(watchDir)
+		case err := <-watcher.Errors:
+			return err
+		case <-deadline:
+			return fmt.Errorf("timed out after %v", timeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// verifyScopeUnit cross-checks over D-Bus that systemd knows the scope unit
+// and placed it in the requested slice. The check is skipped when the
+// system bus is not reachable, e.g. in rootless setups.
+func verifyScopeUnit(ctx context.Context, scope, slice string) error {
+	conn, err := systemddbus.NewWithContext(ctx)
+	if err != nil {
+		log.G(ctx).WithError(err).Warnf("cannot reach systemd over D-Bus, skipping verification of scope %q", scope)
+		return nil
+	}
+	defer conn.Close()
+
+	props, err := conn.GetUnitTypePropertiesContext(ctx, scope, "Scope")
+	if err != nil {
+		return fmt.Errorf("systemd has no scope unit %q for the pod: %w; the runtime likely did not delegate the pod cgroup to systemd", scope, err)
+	}
+	if got, ok := props["Slice"].(string); ok && got != slice {
+		return fmt.Errorf("pod scope %q was placed in slice %q instead of requested %q", scope, got, slice)
+	}
+	return nil
+}
+
+// monitorPodCgroupDrift watches the pod cgroup limit files and publishes a
+// PodCgroupDrift event whenever one changes after sandbox start. It returns
+// when the cgroup is removed.
+func (c *Controller) monitorPodCgroupDrift(id, cgroupDir string) {
+	if cgroups.Mode() != cgroups.Unified {
+		// The named systemd hierarchy carries no limit files.
+		return
+	}
+	ctx := ctrdutil.NamespacedContext()
+	logger := log.G(ctx).WithField("podsandboxid", id)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.WithError(err).Warn("failed to create pod cgroup drift watcher")
+		return
+	}
+	defer watcher.Close()
+	if err := watcher.Add(cgroupDir); err != nil {
+		logger.WithError(err).Warnf("failed to watch pod cgroup %q", cgroupDir)
+		return
+	}
+
+	baseline := make(map[string]string, len(podLimitFiles))
+	for _, f := range podLimitFiles {
+		baseline[f] = readLimitFile(cgroupDir, f)
+	}
+
+	for {
+		select {
+		case ev := <-watcher.Events:
+			if ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+				return
+			}
+			name := filepath.Base(ev.Name)
+			previous, watched := baseline[name]
+			if !watched || !ev.Has(fsnotify.Write) {
+				continue
+			}
+			current := readLimitFile(cgroupDir, name)
+			if current == previous {
+				continue
+			}
+			baseline[name] = current
+			logger.Warnf("pod cgroup limit %s changed behind containerd's back: %q -> %q", name, previous, current)
+			drift := &PodCgroupDrift{
+				SandboxID: id,
+				File:      name,
+				Previous:  previous,
+				Current:   current,
+			}
+			if err := c.client.EventService().Publish(ctx, podCgroupDriftTopic, drift); err != nil {
+				logger.WithError(err).Warn("failed to publish pod cgroup drift event")
+			}
+		case err := <-watcher.Errors:
+			if err != nil {
+				logger.WithError(err).Warn("pod cgroup drift watcher error")
+			}
+			return
+		}
+	}
+}
+
+func readLimitFile(dir, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
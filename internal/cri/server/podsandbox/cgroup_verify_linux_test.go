@@ -0,0 +1,103 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package podsandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandSlice(t *testing.T) {
+	for _, test := range []struct {
+		slice    string
+		expected string
+		err      bool
+	}{
+		{
+			slice:    "kubepods.slice",
+			expected: "kubepods.slice",
+		},
+		{
+			slice:    "kubepods-besteffort.slice",
+			expected: "kubepods.slice/kubepods-besteffort.slice",
+		},
+		{
+			slice:    "kubepods-burstable-pod123.slice",
+			expected: "kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod123.slice",
+		},
+		{
+			slice: "kubepods",
+			err:   true,
+		},
+		{
+			slice: ".slice",
+			err:   true,
+		},
+		{
+			slice: "kubepods--besteffort.slice",
+			err:   true,
+		},
+		{
+			slice: "kube/pods.slice",
+			err:   true,
+		},
+	} {
+		t.Run(test.slice, func(t *testing.T) {
+			got, err := expandSlice(test.slice)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestWaitForCgroup(t *testing.T) {
+	root := t.TempDir()
+	target := filepath.Join(root, "kubepods.slice", "cri-containerd-abc.scope")
+
+	// Already existing directory returns immediately.
+	require.NoError(t, os.MkdirAll(target, 0755))
+	assert.NoError(t, waitForCgroup(context.Background(), target, time.Second))
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "kubepods.slice")))
+
+	// Directory created while waiting is picked up via inotify.
+	done := make(chan error, 1)
+	go func() {
+		done <- waitForCgroup(context.Background(), target, 10*time.Second)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, os.MkdirAll(target, 0755))
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("waitForCgroup did not observe the created directory")
+	}
+
+	// A directory which never appears times out.
+	err := waitForCgroup(context.Background(), filepath.Join(root, "missing.slice"), 100*time.Millisecond)
+	assert.Error(t, err)
+}
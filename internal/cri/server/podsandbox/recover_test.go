@@ -35,6 +35,7 @@ import (
 	"github.com/basuotian/containerd/internal/cri/store/sandbox"
 	"github.com/basuotian/containerd/pkg/cio"
 	"github.com/basuotian/containerd/pkg/oci"
+	"github.com/basuotian/containerd/pkg/processtree"
 	"github.com/containerd/containerd/api/types"
 )
 
@@ -127,6 +128,10 @@ func (f *fakeTask) Pids(ctx context.Context) ([]containerd.ProcessInfo, error) {
 	return []containerd.ProcessInfo{}, errdefs.ErrNotImplemented
 }
 
+func (f *fakeTask) ProcessTree(ctx context.Context) ([]*processtree.Node, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
 func (f *fakeTask) Checkpoint(ctx context.Context, opts ...containerd.CheckpointTaskOpts) (containerd.Image, error) {
 	return nil, errdefs.ErrNotImplemented
 }
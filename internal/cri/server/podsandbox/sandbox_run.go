@@ -278,6 +278,13 @@ func (c *Controller) Start(ctx context.Context, id string) (cin sandbox.Controll
 	if err := task.Start(ctx); err != nil {
 		return cin, fmt.Errorf("failed to start sandbox container task %q: %w", id, err)
 	}
+
+	// When the pod runs under a systemd cgroup parent, verify that systemd
+	// actually realized the requested slice and scope.
+	if err := c.verifyPodCgroup(ctx, id, config); err != nil {
+		return cin, fmt.Errorf("failed to verify pod cgroup for sandbox %q: %w", id, err)
+	}
+
 	pid := task.Pid()
 	if err := podSandbox.Status.Update(func(status sandboxstore.Status) (sandboxstore.Status, error) {
 		status.Pid = pid
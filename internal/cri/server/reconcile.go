@@ -0,0 +1,263 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	eventtypes "github.com/containerd/containerd/api/events"
+
+	containerd "github.com/basuotian/containerd/client"
+	crilabels "github.com/basuotian/containerd/internal/cri/labels"
+	containerstore "github.com/basuotian/containerd/internal/cri/store/container"
+	sandboxstore "github.com/basuotian/containerd/internal/cri/store/sandbox"
+	"github.com/basuotian/containerd/pkg/protobuf"
+)
+
+// reconcileInterval is the time between two reconciliation rounds.
+const reconcileInterval = 5 * time.Minute
+
+// Correction kinds reported by the reconcileCorrections metric.
+const (
+	correctionContainerExited = "container_exited"
+	correctionSandboxExited   = "sandbox_exited"
+	correctionMonitorReattach = "monitor_reattached"
+	correctionOrphanContainer = "orphan_container_removed"
+	correctionOrphanSandbox   = "orphan_sandbox_removed"
+)
+
+// reconcileLoop periodically compares the CRI sandbox and container stores
+// with the actual containerd state and repairs divergence, e.g. exit events
+// lost because an exit monitor died. Every correction is counted, so state
+// drift shows up in metrics even when the reconciler papers over it.
+func (c *criService) reconcileLoop(ctx context.Context) {
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			c.reconcileContainers(ctx)
+			c.reconcileSandboxes(ctx)
+			c.reconcileOrphans(ctx)
+			reconcileTimer.UpdateSince(start)
+		}
+	}
+}
+
+// reconcileContainers marks containers as exited whose task is gone or
+// stopped while the store still reports them running, and re-attaches an
+// exit monitor to containers recovered in unknown state whose task turned
+// out to be alive.
+func (c *criService) reconcileContainers(ctx context.Context) {
+	for _, cntr := range c.containerStore.List() {
+		status := cntr.Status.Get()
+		state := status.State()
+		if state != runtime.ContainerState_CONTAINER_RUNNING && state != runtime.ContainerState_CONTAINER_UNKNOWN {
+			continue
+		}
+		task, err := cntr.Container.Task(ctx, nil)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				log.G(ctx).Warnf("reconciler: container %q is %s but its task is gone, marking exited", cntr.ID, state)
+				c.correctContainerExit(ctx, cntr, unknownExitCode, time.Now())
+			} else {
+				log.G(ctx).WithError(err).Warnf("reconciler: failed to load task for container %q", cntr.ID)
+			}
+			continue
+		}
+		taskStatus, err := task.Status(ctx)
+		if err != nil {
+			log.G(ctx).WithError(err).Warnf("reconciler: failed to get task status for container %q", cntr.ID)
+			continue
+		}
+		switch taskStatus.Status {
+		case containerd.Stopped:
+			log.G(ctx).Warnf("reconciler: container %q is %s but its task has stopped, marking exited", cntr.ID, state)
+			c.correctContainerExit(ctx, cntr, taskStatus.ExitStatus, taskStatus.ExitTime)
+		case containerd.Running:
+			if state != runtime.ContainerState_CONTAINER_UNKNOWN {
+				continue
+			}
+			// The task is alive although recovery could not determine its
+			// state; watch it again and let the store reflect that.
+			exitCh, err := task.Wait(ctx)
+			if err != nil {
+				log.G(ctx).WithError(err).Warnf("reconciler: failed to wait task for container %q", cntr.ID)
+				continue
+			}
+			c.startContainerExitMonitor(context.Background(), cntr.ID, task.Pid(), exitCh)
+			err = cntr.Status.UpdateSync(func(status containerstore.Status) (containerstore.Status, error) {
+				status.Unknown = false
+				return status, nil
+			})
+			if err != nil {
+				log.G(ctx).WithError(err).Warnf("reconciler: failed to update status of container %q", cntr.ID)
+				continue
+			}
+			log.G(ctx).Warnf("reconciler: re-attached exit monitor to container %q", cntr.ID)
+			reconcileCorrections.WithValues(correctionMonitorReattach).Inc()
+		}
+	}
+}
+
+// correctContainerExit routes a divergence through the regular exit
+// handling so IO, task records and CRI events are cleaned up the same way
+// a delivered exit event would have.
+func (c *criService) correctContainerExit(ctx context.Context, cntr containerstore.Container, exitStatus uint32, exitedAt time.Time) {
+	if exitedAt.IsZero() {
+		exitedAt = time.Now()
+	}
+	e := &eventtypes.TaskExit{
+		ContainerID: cntr.ID,
+		ID:          cntr.ID,
+		Pid:         cntr.Status.Get().Pid,
+		ExitStatus:  exitStatus,
+		ExitedAt:    protobuf.ToTimestamp(exitedAt),
+	}
+	if err := c.handleContainerExit(ctx, e, cntr, cntr.SandboxID); err != nil {
+		log.G(ctx).WithError(err).Warnf("reconciler: failed to mark container %q exited", cntr.ID)
+		return
+	}
+	reconcileCorrections.WithValues(correctionContainerExited).Inc()
+}
+
+// reconcileSandboxes marks sandboxes as not ready whose controller reports
+// them gone or stopped while the store still reports them ready.
+func (c *criService) reconcileSandboxes(ctx context.Context) {
+	for _, sb := range c.sandboxStore.List() {
+		if sb.Status.Get().State != sandboxstore.StateReady {
+			continue
+		}
+		ctrl, err := c.sandboxService.SandboxStatus(ctx, sb.Sandboxer, sb.ID, false)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				log.G(ctx).Warnf("reconciler: sandbox %q is ready but unknown to its controller, marking not ready", sb.ID)
+				c.correctSandboxExit(ctx, sb, time.Now())
+			} else {
+				// Possibly a transient shim failure; only the next round
+				// can tell.
+				log.G(ctx).WithError(err).Warnf("reconciler: failed to get status of sandbox %q", sb.ID)
+			}
+			continue
+		}
+		if code, ok := runtime.PodSandboxState_value[ctrl.State]; ok && code == int32(runtime.PodSandboxState_SANDBOX_NOTREADY) {
+			log.G(ctx).Warnf("reconciler: sandbox %q is ready but its controller reports it stopped, marking not ready", sb.ID)
+			c.correctSandboxExit(ctx, sb, ctrl.ExitedAt)
+		}
+	}
+}
+
+func (c *criService) correctSandboxExit(ctx context.Context, sb sandboxstore.Sandbox, exitedAt time.Time) {
+	if exitedAt.IsZero() {
+		exitedAt = time.Now()
+	}
+	if err := c.handleSandboxExit(ctx, sb, unknownExitCode, exitedAt); err != nil {
+		log.G(ctx).WithError(err).Warnf("reconciler: failed to mark sandbox %q not ready", sb.ID)
+		return
+	}
+	reconcileCorrections.WithValues(correctionSandboxExited).Inc()
+}
+
+// reconcileOrphans removes containerd containers and sandbox records which
+// are unknown to the CRI stores. An orphan is only cleaned up when it is
+// seen in two consecutive rounds, so resources of requests still in flight
+// are left alone.
+func (c *criService) reconcileOrphans(ctx context.Context) {
+	suspects := make(map[string]struct{})
+	defer func() {
+		c.reconcileSuspects = suspects
+	}()
+
+	for _, kind := range []string{crilabels.ContainerKindSandbox, crilabels.ContainerKindContainer} {
+		cntrs, err := c.client.Containers(ctx, filterLabel(crilabels.ContainerKindLabel, kind))
+		if err != nil {
+			log.G(ctx).WithError(err).Warn("reconciler: failed to list containers")
+			return
+		}
+		for _, cntr := range cntrs {
+			id := cntr.ID()
+			if kind == crilabels.ContainerKindSandbox {
+				if _, err := c.sandboxStore.Get(id); err == nil {
+					continue
+				}
+				// Warm pool sandboxes live outside the CRI stores on
+				// purpose.
+				if sbx, err := c.client.SandboxStore().Get(ctx, id); err == nil {
+					if _, ok := sbx.Labels[warmPoolLabel]; ok {
+						continue
+					}
+				}
+			} else if _, err := c.containerStore.Get(id); err == nil {
+				continue
+			}
+			if _, ok := c.reconcileSuspects[id]; !ok {
+				suspects[id] = struct{}{}
+				continue
+			}
+			log.G(ctx).Warnf("reconciler: removing orphaned %s container %q", kind, id)
+			if task, err := cntr.Task(ctx, nil); err == nil {
+				if _, err := task.Delete(ctx, containerd.WithProcessKill); err != nil && !errdefs.IsNotFound(err) {
+					log.G(ctx).WithError(err).Warnf("reconciler: failed to delete task of orphan %q", id)
+					continue
+				}
+			} else if !errdefs.IsNotFound(err) {
+				log.G(ctx).WithError(err).Warnf("reconciler: failed to load task of orphan %q", id)
+				continue
+			}
+			if err := cntr.Delete(ctx, containerd.WithSnapshotCleanup); err != nil && !errdefs.IsNotFound(err) {
+				log.G(ctx).WithError(err).Warnf("reconciler: failed to delete orphan %q", id)
+				continue
+			}
+			if kind == crilabels.ContainerKindSandbox {
+				reconcileCorrections.WithValues(correctionOrphanSandbox).Inc()
+			} else {
+				reconcileCorrections.WithValues(correctionOrphanContainer).Inc()
+			}
+		}
+	}
+
+	// Sandboxes in the sandbox store have no backing container; scan the
+	// store records as well.
+	storedSandboxes, err := c.client.SandboxStore().List(ctx)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("reconciler: failed to list stored sandboxes")
+		return
+	}
+	for _, sbx := range storedSandboxes {
+		if _, err := c.sandboxStore.Get(sbx.ID); err == nil {
+			continue
+		}
+		if _, ok := sbx.Labels[warmPoolLabel]; ok {
+			continue
+		}
+		if _, ok := c.reconcileSuspects[sbx.ID]; !ok {
+			suspects[sbx.ID] = struct{}{}
+			continue
+		}
+		log.G(ctx).Warnf("reconciler: removing orphaned sandbox record %q", sbx.ID)
+		c.cleanupStaleSandbox(ctx, sbx)
+		reconcileCorrections.WithValues(correctionOrphanSandbox).Inc()
+	}
+}
@@ -103,6 +103,14 @@ func (c *criService) recover(ctx context.Context) error {
 			continue
 		}
 
+		// Warm pool sandboxes are not re-pooled across restarts; remove
+		// any left behind by the previous run.
+		if _, ok := sbx.Labels[warmPoolLabel]; ok {
+			log.G(ctx).WithField("podsandboxid", sbx.ID).Info("removing stale warm pool sandbox")
+			c.cleanupStaleSandbox(ctx, sbx)
+			continue
+		}
+
 		metadata := sandboxstore.Metadata{}
 		err := sbx.GetExtension(podsandbox.MetadataKey, &metadata)
 		if err != nil {
@@ -285,7 +293,7 @@ func (c *criService) loadContainer(ctx context.Context, cntr containerd.Containe
 	err = func() error {
 		// Load up-to-date status from containerd.
 		t, err := cntr.Task(ctx, func(fifos *containerdio.FIFOSet) (_ containerdio.IO, err error) {
-			stdoutWC, stderrWC, err := c.createContainerLoggers(meta.LogPath, meta.Config.GetTty())
+			stdoutWC, stderrWC, err := c.createContainerLoggers(id, meta.LogPath, meta.Config.GetTty(), meta.SandboxID)
 			if err != nil {
 				return nil, err
 			}
@@ -0,0 +1,184 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/containerd/errdefs"
+	cni "github.com/containerd/go-cni"
+	"github.com/containerd/log"
+
+	"github.com/basuotian/containerd/internal/cri/bandwidth"
+	sandboxstore "github.com/basuotian/containerd/internal/cri/store/sandbox"
+)
+
+const (
+	// bandwidthPluginType is the CNI plugin type which consumes the
+	// bandwidth capability args built from the pod annotations.
+	bandwidthPluginType = "bandwidth"
+	bridgePluginType    = "bridge"
+	// defaultBridgeName is the device the bridge plugin creates when its
+	// configuration does not name one.
+	defaultBridgeName = "cni0"
+)
+
+// hasBandwidthPlugin reports whether any network in the loaded CNI
+// configuration includes the bandwidth plugin. When it does, the
+// capability args passed on setup are enforced by CNI and no fallback
+// shaping is needed.
+func hasBandwidthPlugin(conf *cni.ConfigResult) bool {
+	if conf == nil {
+		return false
+	}
+	for _, network := range conf.Networks {
+		for _, plugin := range network.Config.Plugins {
+			if plugin.Network != nil && plugin.Network.Type == bandwidthPluginType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bridgeDevice returns the host bridge device of the first bridge
+// network in the loaded CNI configuration, which is where fallback
+// traffic shaping is applied.
+func bridgeDevice(conf *cni.ConfigResult) (string, bool) {
+	if conf == nil {
+		return "", false
+	}
+	for _, network := range conf.Networks {
+		for _, plugin := range network.Config.Plugins {
+			if plugin.Network == nil || plugin.Network.Type != bridgePluginType {
+				continue
+			}
+			var bridgeConf struct {
+				Bridge string `json:"bridge"`
+			}
+			if err := json.Unmarshal([]byte(plugin.Source), &bridgeConf); err != nil {
+				continue
+			}
+			if bridgeConf.Bridge == "" {
+				bridgeConf.Bridge = defaultBridgeName
+			}
+			return bridgeConf.Bridge, true
+		}
+	}
+	return "", false
+}
+
+// podBandwidthCIDR returns the pod IP as a host CIDR for tc filter
+// matching. Only IPv4 is supported, the u32 matching used by the shaper
+// does not handle IPv6.
+func podBandwidthCIDR(podIP string) (string, bool) {
+	ip := net.ParseIP(podIP)
+	if ip == nil || ip.To4() == nil {
+		return "", false
+	}
+	return podIP + "/32", true
+}
+
+// setupPodBandwidth applies tc based traffic shaping on the host bridge
+// for the pod's bandwidth annotations when the loaded CNI configuration
+// has no bandwidth plugin to enforce them. It is a no-op when the pod
+// carries no bandwidth annotations.
+func (c *criService) setupPodBandwidth(ctx context.Context, netPlugin cni.CNI, sandbox *sandboxstore.Sandbox) error {
+	ingress, egress, err := bandwidth.ExtractPodBandwidthResources(sandbox.Config.GetAnnotations())
+	if err != nil {
+		return fmt.Errorf("reading pod bandwidth annotations: %w", err)
+	}
+	if ingress == nil && egress == nil {
+		return nil
+	}
+	conf := netPlugin.GetConfig()
+	if hasBandwidthPlugin(conf) {
+		// Enforced by the bandwidth plugin through the capability args.
+		return nil
+	}
+	device, ok := bridgeDevice(conf)
+	if !ok {
+		log.G(ctx).WithField("podsandboxid", sandbox.ID).Warn("pod bandwidth annotations not enforced: CNI config has no bandwidth plugin and no bridge network to shape")
+		return nil
+	}
+	cidr, ok := podBandwidthCIDR(sandbox.IP)
+	if !ok {
+		log.G(ctx).WithField("podsandboxid", sandbox.ID).Warnf("pod bandwidth annotations not enforced: pod IP %q is not shapeable", sandbox.IP)
+		return nil
+	}
+	shaper := bandwidth.NewTCShaper(device)
+	networkBandwidthOperations.WithValues(networkShapePodOp).Inc()
+	if err := shaper.ReconcileInterface(); err != nil {
+		if errdefs.IsNotImplemented(err) {
+			log.G(ctx).WithField("podsandboxid", sandbox.ID).Warn("pod bandwidth annotations not enforced: traffic shaping is not supported on this platform")
+			return nil
+		}
+		networkBandwidthOperationsErrors.WithValues(networkShapePodOp).Inc()
+		return fmt.Errorf("failed to initialize traffic shaping on %q: %w", device, err)
+	}
+	if err := shaper.ReconcileCIDR(cidr, egress, ingress); err != nil {
+		networkBandwidthOperationsErrors.WithValues(networkShapePodOp).Inc()
+		return fmt.Errorf("failed to shape traffic for %q on %q: %w", cidr, device, err)
+	}
+	log.G(ctx).WithField("podsandboxid", sandbox.ID).Infof("applied traffic shaping for %q on %q", cidr, device)
+	return nil
+}
+
+// teardownPodBandwidth removes traffic shaping applied by
+// setupPodBandwidth. It is best effort, the tc state does not survive a
+// reboot and failures must not block sandbox teardown.
+func (c *criService) teardownPodBandwidth(ctx context.Context, netPlugin cni.CNI, sandbox sandboxstore.Sandbox) {
+	ingress, egress, err := bandwidth.ExtractPodBandwidthResources(sandbox.Config.GetAnnotations())
+	if err != nil || (ingress == nil && egress == nil) {
+		return
+	}
+	conf := netPlugin.GetConfig()
+	if hasBandwidthPlugin(conf) {
+		return
+	}
+	device, ok := bridgeDevice(conf)
+	if !ok {
+		return
+	}
+	cidr, ok := podBandwidthCIDR(sandbox.IP)
+	if !ok {
+		return
+	}
+	networkBandwidthOperations.WithValues(networkUnshapePodOp).Inc()
+	if err := shaperReset(bandwidth.NewTCShaper(device), cidr); err != nil {
+		networkBandwidthOperationsErrors.WithValues(networkUnshapePodOp).Inc()
+		log.G(ctx).WithError(err).WithField("podsandboxid", sandbox.ID).Warnf("failed to remove traffic shaping for %q on %q", cidr, device)
+	}
+}
+
+// shaperReset resets the cidr, treating a filter which is already gone
+// as success.
+func shaperReset(shaper bandwidth.Shaper, cidr string) error {
+	cidrs, err := shaper.GetCIDRs()
+	if err != nil {
+		return err
+	}
+	for _, c := range cidrs {
+		if c == cidr {
+			return shaper.Reset(cidr)
+		}
+	}
+	return nil
+}
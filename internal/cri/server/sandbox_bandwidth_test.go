@@ -0,0 +1,153 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+
+	cni "github.com/containerd/go-cni"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func confWithPlugins(plugins ...*cni.NetworkConf) *cni.ConfigResult {
+	return &cni.ConfigResult{
+		Networks: []*cni.ConfNetwork{
+			{
+				Config: &cni.NetworkConfList{
+					Name:    "test-net",
+					Plugins: plugins,
+				},
+			},
+		},
+	}
+}
+
+func TestHasBandwidthPlugin(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		conf     *cni.ConfigResult
+		expected bool
+	}{
+		{
+			desc:     "nil config",
+			conf:     nil,
+			expected: false,
+		},
+		{
+			desc: "bridge only",
+			conf: confWithPlugins(
+				&cni.NetworkConf{Network: &types.NetConf{Type: "bridge"}},
+			),
+			expected: false,
+		},
+		{
+			desc: "bridge with bandwidth",
+			conf: confWithPlugins(
+				&cni.NetworkConf{Network: &types.NetConf{Type: "bridge"}},
+				&cni.NetworkConf{Network: &types.NetConf{Type: "bandwidth"}},
+			),
+			expected: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.expected, hasBandwidthPlugin(test.conf))
+		})
+	}
+}
+
+func TestBridgeDevice(t *testing.T) {
+	for _, test := range []struct {
+		desc           string
+		conf           *cni.ConfigResult
+		expectedDevice string
+		expectedOK     bool
+	}{
+		{
+			desc:       "nil config",
+			conf:       nil,
+			expectedOK: false,
+		},
+		{
+			desc: "no bridge network",
+			conf: confWithPlugins(
+				&cni.NetworkConf{Network: &types.NetConf{Type: "ptp"}},
+			),
+			expectedOK: false,
+		},
+		{
+			desc: "named bridge device",
+			conf: confWithPlugins(
+				&cni.NetworkConf{
+					Network: &types.NetConf{Type: "bridge"},
+					Source:  `{"type":"bridge","bridge":"br0"}`,
+				},
+			),
+			expectedDevice: "br0",
+			expectedOK:     true,
+		},
+		{
+			desc: "default bridge device",
+			conf: confWithPlugins(
+				&cni.NetworkConf{
+					Network: &types.NetConf{Type: "bridge"},
+					Source:  `{"type":"bridge"}`,
+				},
+			),
+			expectedDevice: "cni0",
+			expectedOK:     true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			device, ok := bridgeDevice(test.conf)
+			assert.Equal(t, test.expectedOK, ok)
+			assert.Equal(t, test.expectedDevice, device)
+		})
+	}
+}
+
+func TestPodBandwidthCIDR(t *testing.T) {
+	for _, test := range []struct {
+		desc         string
+		ip           string
+		expectedCIDR string
+		expectedOK   bool
+	}{
+		{
+			desc:         "ipv4",
+			ip:           "10.0.0.5",
+			expectedCIDR: "10.0.0.5/32",
+			expectedOK:   true,
+		},
+		{
+			desc:       "ipv6",
+			ip:         "2001:db8::1",
+			expectedOK: false,
+		},
+		{
+			desc:       "invalid",
+			ip:         "not-an-ip",
+			expectedOK: false,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			cidr, ok := podBandwidthCIDR(test.ip)
+			assert.Equal(t, test.expectedOK, ok)
+			assert.Equal(t, test.expectedCIDR, cidr)
+		})
+	}
+}
@@ -0,0 +1,348 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/basuotian/containerd/core/leases"
+	sb "github.com/basuotian/containerd/core/sandbox"
+	"github.com/basuotian/containerd/internal/cri/annotations"
+	criconfig "github.com/basuotian/containerd/internal/cri/config"
+	"github.com/basuotian/containerd/internal/cri/server/podsandbox"
+	sandboxstore "github.com/basuotian/containerd/internal/cri/store/sandbox"
+	"github.com/basuotian/containerd/internal/cri/util"
+	"github.com/basuotian/containerd/pkg/netns"
+)
+
+// warmPoolLabel marks sandbox records which belong to the warm sandbox
+// pool and have not yet been claimed by a RunPodSandbox request. The
+// value is the runtime handler the sandbox was created for.
+const warmPoolLabel = "io.cri-containerd.warm-pool"
+
+// warmSandbox is a pre-created sandbox waiting to be claimed. The runtime
+// side of the sandbox is already started, so claiming one only requires
+// re-labelling the record and setting up the pod network in the already
+// created network namespace.
+type warmSandbox struct {
+	id        string
+	netNS     *netns.NetNS
+	info      sb.Sandbox
+	ctrl      sb.ControllerInstance
+	sandboxer string
+}
+
+// sandboxPool keeps a configurable number of pre-created sandboxes per
+// runtime handler to cut pod cold-start latency for runtimes with an
+// expensive start path, such as microVMs. Pool sizes are configured with
+// `sandbox_pool_size` on the runtime handler.
+type sandboxPool struct {
+	c *criService
+
+	mu    sync.Mutex
+	warm  map[string][]*warmSandbox
+	sizes map[string]int
+
+	refill chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newSandboxPool returns a pool for the handlers with a non-zero pool
+// size, or nil when no handler enables pooling.
+func newSandboxPool(c *criService) *sandboxPool {
+	sizes := make(map[string]int)
+	for handler, r := range c.config.Runtimes {
+		if r.SandboxPoolSize > 0 {
+			sizes[handler] = r.SandboxPoolSize
+		}
+	}
+	if len(sizes) == 0 {
+		return nil
+	}
+	return &sandboxPool{
+		c:      c,
+		warm:   make(map[string][]*warmSandbox),
+		sizes:  sizes,
+		refill: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// run fills the pool and keeps it filled until the pool is closed.
+// Failed creations are retried on the next refill or tick.
+func (p *sandboxPool) run(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	defer close(p.done)
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	p.fill(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.refill:
+		case <-ticker.C:
+		}
+		p.fill(ctx)
+	}
+}
+
+// close stops the refill loop and tears down all unclaimed sandboxes.
+func (p *sandboxPool) close() {
+	if p.cancel != nil {
+		p.cancel()
+		<-p.done
+	}
+
+	p.mu.Lock()
+	warm := p.warm
+	p.warm = make(map[string][]*warmSandbox)
+	p.mu.Unlock()
+
+	ctx := util.NamespacedContext()
+	for _, entries := range warm {
+		for _, w := range entries {
+			p.destroy(ctx, w)
+		}
+	}
+}
+
+func (p *sandboxPool) fill(ctx context.Context) {
+	for handler, size := range p.sizes {
+		for p.count(handler) < size && ctx.Err() == nil {
+			w, err := p.create(ctx, handler)
+			if err != nil {
+				log.G(ctx).WithError(err).WithField("runtime", handler).Error("failed to create warm sandbox")
+				break
+			}
+			p.mu.Lock()
+			p.warm[handler] = append(p.warm[handler], w)
+			p.mu.Unlock()
+			log.G(ctx).WithField("podsandboxid", w.id).WithField("runtime", handler).Debug("created warm sandbox")
+		}
+	}
+}
+
+func (p *sandboxPool) count(handler string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.warm[handler])
+}
+
+// claim pops a warm sandbox for the handler when the pod configuration
+// can be served by one, triggering a refill in the background. Pooled
+// sandboxes run in their own network namespace with the template sandbox
+// spec, so pods which need host networking or a pod user namespace are
+// always cold-started.
+func (p *sandboxPool) claim(handler string, config *runtime.PodSandboxConfig) *warmSandbox {
+	if !poolableSandboxConfig(config) {
+		return nil
+	}
+
+	p.mu.Lock()
+	entries := p.warm[handler]
+	if len(entries) == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	w := entries[0]
+	p.warm[handler] = entries[1:]
+	p.mu.Unlock()
+
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+	return w
+}
+
+func poolableSandboxConfig(config *runtime.PodSandboxConfig) bool {
+	if hostNetwork(config) {
+		return false
+	}
+	// Pods joining a pre-created external network namespace cannot reuse a
+	// pooled namespace.
+	if config.GetAnnotations()[annotations.ExternalNetNSPath] != "" {
+		return false
+	}
+	usernsOpts := config.GetLinux().GetSecurityContext().GetNamespaceOptions().GetUsernsOptions()
+	return usernsOpts == nil || usernsOpts.GetMode() != runtime.NamespaceMode_POD
+}
+
+// create starts one warm sandbox for the handler, mirroring the resource
+// creation order of RunPodSandbox with a template pod configuration.
+func (p *sandboxPool) create(ctx context.Context, handler string) (_ *warmSandbox, retErr error) {
+	c := p.c
+	ociRuntime, ok := c.config.Runtimes[handler]
+	if !ok {
+		return nil, fmt.Errorf("no runtime for handler %q", handler)
+	}
+
+	id := util.GenerateID()
+
+	leaseSvc := c.client.LeasesService()
+	ls, err := leaseSvc.Create(ctx, leases.WithID(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lease for warm sandbox: %w", err)
+	}
+	defer func() {
+		if retErr != nil {
+			deferCtx, deferCancel := util.DeferContext()
+			defer deferCancel()
+			if err := leaseSvc.Delete(deferCtx, ls); err != nil {
+				log.G(deferCtx).WithError(err).Error("failed to delete warm sandbox lease during cleanup")
+			}
+		}
+	}()
+
+	sandboxInfo := sb.Sandbox{ID: id}
+	sandboxInfo.Runtime.Name = ociRuntime.Type
+	sandboxInfo.Sandboxer = ociRuntime.Sandboxer
+
+	runtimeOpts, err := criconfig.GenerateRuntimeOptions(ociRuntime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate warm sandbox runtime options: %w", err)
+	}
+	if runtimeOpts != nil {
+		sandboxInfo.Runtime.Options, err = typeurl.MarshalAny(runtimeOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal warm sandbox runtime options: %w", err)
+		}
+	}
+	sandboxInfo.AddLabel(warmPoolLabel, handler)
+
+	netnsMountDir := "/var/run/netns"
+	if c.config.NetNSMountsUnderStateDir {
+		netnsMountDir = filepath.Join(c.config.StateDir, "netns")
+	}
+	netNS, err := netns.NewNetNS(netnsMountDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create network namespace for warm sandbox: %w", err)
+	}
+	defer func() {
+		if retErr != nil {
+			if err := netNS.Remove(); err != nil {
+				log.G(ctx).WithError(err).Error("failed to remove warm sandbox network namespace during cleanup")
+			}
+		}
+	}()
+
+	config := warmSandboxConfig(id)
+	metadata := sandboxstore.Metadata{
+		ID:             id,
+		Name:           "warm-" + id,
+		Config:         config,
+		NetNSPath:      netNS.GetPath(),
+		RuntimeHandler: handler,
+	}
+	if err := sandboxInfo.AddExtension(podsandbox.MetadataKey, &metadata); err != nil {
+		return nil, fmt.Errorf("unable to set extensions for warm sandbox: %w", err)
+	}
+
+	if _, err := c.client.SandboxStore().Create(ctx, sandboxInfo); err != nil {
+		return nil, fmt.Errorf("failed to save warm sandbox metadata: %w", err)
+	}
+	defer func() {
+		if retErr != nil {
+			if err := c.client.SandboxStore().Delete(ctx, id); err != nil {
+				log.G(ctx).WithError(err).Error("failed to delete warm sandbox metadata during cleanup")
+			}
+		}
+	}()
+
+	if err := c.sandboxService.CreateSandbox(ctx, sandboxInfo, sb.WithOptions(config), sb.WithNetNSPath(netNS.GetPath())); err != nil {
+		return nil, fmt.Errorf("failed to create warm sandbox: %w", err)
+	}
+
+	ctrl, err := c.sandboxService.StartSandbox(ctx, ociRuntime.Sandboxer, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start warm sandbox: %w", err)
+	}
+
+	return &warmSandbox{
+		id:        id,
+		netNS:     netNS,
+		info:      sandboxInfo,
+		ctrl:      ctrl,
+		sandboxer: ociRuntime.Sandboxer,
+	}, nil
+}
+
+// destroy tears down an unclaimed warm sandbox.
+func (p *sandboxPool) destroy(ctx context.Context, w *warmSandbox) {
+	c := p.c
+	if err := c.sandboxService.StopSandbox(ctx, w.sandboxer, w.id); err != nil {
+		log.G(ctx).WithError(err).WithField("podsandboxid", w.id).Error("failed to stop warm sandbox")
+	}
+	if err := c.sandboxService.ShutdownSandbox(ctx, w.sandboxer, w.id); err != nil {
+		log.G(ctx).WithError(err).WithField("podsandboxid", w.id).Error("failed to shutdown warm sandbox")
+	}
+	if err := w.netNS.Remove(); err != nil {
+		log.G(ctx).WithError(err).WithField("podsandboxid", w.id).Error("failed to remove warm sandbox network namespace")
+	}
+	if err := c.client.SandboxStore().Delete(ctx, w.id); err != nil {
+		log.G(ctx).WithError(err).WithField("podsandboxid", w.id).Error("failed to delete warm sandbox metadata")
+	}
+	if err := c.client.LeasesService().Delete(ctx, leases.Lease{ID: w.id}); err != nil {
+		log.G(ctx).WithError(err).WithField("podsandboxid", w.id).Error("failed to delete warm sandbox lease")
+	}
+}
+
+// warmSandboxConfig is the template pod configuration warm sandboxes are
+// created with before they are re-labelled for the claiming pod.
+func warmSandboxConfig(id string) *runtime.PodSandboxConfig {
+	return &runtime.PodSandboxConfig{
+		Metadata: &runtime.PodSandboxMetadata{
+			Name:      "warm-" + id,
+			Namespace: "warm-pool.cri-containerd.io",
+			Uid:       id,
+		},
+		Linux: &runtime.LinuxPodSandboxConfig{},
+	}
+}
+
+// cleanupStaleSandbox shuts down and deletes a sandbox record which is no
+// longer tracked, together with its network namespace and lease.
+func (c *criService) cleanupStaleSandbox(ctx context.Context, sbx sb.Sandbox) {
+	if err := c.sandboxService.ShutdownSandbox(ctx, sbx.Sandboxer, sbx.ID); err != nil && !errdefs.IsNotFound(err) {
+		log.G(ctx).WithError(err).WithField("podsandboxid", sbx.ID).Error("failed to shutdown stale sandbox")
+	}
+	metadata := sandboxstore.Metadata{}
+	if err := sbx.GetExtension(podsandbox.MetadataKey, &metadata); err == nil && metadata.NetNSPath != "" {
+		if err := netns.LoadNetNS(metadata.NetNSPath).Remove(); err != nil {
+			log.G(ctx).WithError(err).WithField("podsandboxid", sbx.ID).Error("failed to remove stale sandbox network namespace")
+		}
+	}
+	if err := c.client.SandboxStore().Delete(ctx, sbx.ID); err != nil && !errdefs.IsNotFound(err) {
+		log.G(ctx).WithError(err).WithField("podsandboxid", sbx.ID).Error("failed to delete stale sandbox metadata")
+	}
+	if err := c.client.LeasesService().Delete(ctx, leases.Lease{ID: sbx.ID}); err != nil && !errdefs.IsNotFound(err) {
+		log.G(ctx).WithError(err).WithField("podsandboxid", sbx.ID).Error("failed to delete stale sandbox lease")
+	}
+}
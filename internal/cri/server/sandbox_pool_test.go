@@ -0,0 +1,81 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/basuotian/containerd/internal/cri/annotations"
+)
+
+func TestPoolableSandboxConfig(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		config   *runtime.PodSandboxConfig
+		poolable bool
+	}{
+		{
+			desc:     "plain pod network sandbox should be poolable",
+			config:   &runtime.PodSandboxConfig{},
+			poolable: true,
+		},
+		{
+			desc: "host network sandbox should not be poolable",
+			config: &runtime.PodSandboxConfig{
+				Linux: &runtime.LinuxPodSandboxConfig{
+					SecurityContext: &runtime.LinuxSandboxSecurityContext{
+						NamespaceOptions: &runtime.NamespaceOption{
+							Network: runtime.NamespaceMode_NODE,
+						},
+					},
+				},
+			},
+			poolable: false,
+		},
+		{
+			desc: "external network namespace sandbox should not be poolable",
+			config: &runtime.PodSandboxConfig{
+				Annotations: map[string]string{
+					annotations.ExternalNetNSPath: "/var/run/netns/agent-0",
+				},
+			},
+			poolable: false,
+		},
+		{
+			desc: "pod user namespace sandbox should not be poolable",
+			config: &runtime.PodSandboxConfig{
+				Linux: &runtime.LinuxPodSandboxConfig{
+					SecurityContext: &runtime.LinuxSandboxSecurityContext{
+						NamespaceOptions: &runtime.NamespaceOption{
+							UsernsOptions: &runtime.UserNamespace{
+								Mode: runtime.NamespaceMode_POD,
+							},
+						},
+					},
+				},
+			},
+			poolable: false,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.poolable, poolableSandboxConfig(test.config))
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/basuotian/containerd/core/leases"
+	sandboxstore "github.com/basuotian/containerd/internal/cri/store/sandbox"
 	"github.com/basuotian/containerd/pkg/tracing"
 	"github.com/containerd/errdefs"
 	"github.com/containerd/log"
@@ -56,24 +57,59 @@ func (c *criService) RemovePodSandbox(ctx context.Context, r *runtime.RemovePodS
 	// Even if it's in a NotReady state, this will close its network namespace, if open.
 	// This can happen if the task process associated with the Pod died or it was killed.
 	log.G(ctx).Infof("Forcibly stopping sandbox %q", id)
-	if err := c.stopPodSandbox(ctx, sandbox); err != nil {
+	if err := c.teardownPodSandbox(ctx, sandbox, false); err != nil {
 		return nil, fmt.Errorf("failed to forcibly stop sandbox %q: %w", id, err)
 	}
 
-	if err := c.client.LeasesService().Delete(ctx, leases.Lease{ID: id}); err != nil {
-		if !errdefs.IsNotFound(err) {
-			return nil, fmt.Errorf("failed to delete lease for sandbox %q: %w", id, err)
+	if err := c.removePodSandbox(ctx, sandbox, false); err != nil {
+		return nil, err
+	}
+	c.teardowns.finish(id)
+
+	sandboxRemoveTimer.WithValues(sandbox.RuntimeHandler).UpdateSince(start)
+
+	span.AddEvent("pod sandbox removed",
+		tracing.Attribute("sandbox.remove.duration", time.Since(start).String()),
+	)
+
+	return &runtime.RemovePodSandboxResponse{}, nil
+}
+
+// removePodSandbox runs the remove half of pod teardown as tracked stages:
+// the lease goes first so the snapshot is collectable, containers are
+// removed before the sandbox is shut down, and the metadata stores are
+// only cleaned once the controller released the shim and snapshot.
+// Completed stages are skipped on retry; with force set, failing stages
+// are skipped and recorded as leaked.
+func (c *criService) removePodSandbox(ctx context.Context, sandbox sandboxstore.Sandbox, force bool) error {
+	id := sandbox.ID
+	c.teardowns.begin(id)
+
+	err := c.runTeardownStage(ctx, id, teardownStageLease, force, func() error {
+		if err := c.client.LeasesService().Delete(ctx, leases.Lease{ID: id}); err != nil && !errdefs.IsNotFound(err) {
+			return fmt.Errorf("failed to delete lease for sandbox %q: %w", id, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Return error if sandbox network namespace is not closed yet.
-	if sandbox.NetNS != nil {
+	err = c.runTeardownStage(ctx, id, teardownStageNetNSClosed, force, func() error {
+		if sandbox.NetNS == nil {
+			return nil
+		}
 		nsPath := sandbox.NetNS.GetPath()
 		if closed, err := sandbox.NetNS.Closed(); err != nil {
-			return nil, fmt.Errorf("failed to check sandbox network namespace %q closed: %w", nsPath, err)
+			return fmt.Errorf("failed to check sandbox network namespace %q closed: %w", nsPath, err)
 		} else if !closed {
-			return nil, fmt.Errorf("sandbox network namespace %q is not fully closed", nsPath)
+			return fmt.Errorf("sandbox network namespace %q is not fully closed", nsPath)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Remove all containers inside the sandbox.
@@ -81,26 +117,36 @@ func (c *criService) RemovePodSandbox(ctx context.Context, r *runtime.RemovePodS
 	// not rely on this behavior.
 	// TODO(random-liu): Introduce an intermediate state to avoid container creation after
 	// this point.
-	cntrs := c.containerStore.List()
-	for _, cntr := range cntrs {
-		if cntr.SandboxID != id {
-			continue
-		}
-		_, err = c.RemoveContainer(ctx, &runtime.RemoveContainerRequest{ContainerId: cntr.ID})
-		if err != nil {
-			return nil, fmt.Errorf("failed to remove container %q: %w", cntr.ID, err)
+	err = c.runTeardownStage(ctx, id, teardownStageRemoveContainers, force, func() error {
+		cntrs := c.containerStore.List()
+		for _, cntr := range cntrs {
+			if cntr.SandboxID != id {
+				continue
+			}
+			if _, err := c.RemoveContainer(ctx, &runtime.RemoveContainerRequest{ContainerId: cntr.ID}); err != nil {
+				return fmt.Errorf("failed to remove container %q: %w", cntr.ID, err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	if err := c.sandboxService.ShutdownSandbox(ctx, sandbox.Sandboxer, id); err != nil && !errdefs.IsNotFound(err) {
-		return nil, fmt.Errorf("failed to delete sandbox %q: %w", id, err)
+	err = c.runTeardownStage(ctx, id, teardownStageShutdown, force, func() error {
+		if err := c.sandboxService.ShutdownSandbox(ctx, sandbox.Sandboxer, id); err != nil && !errdefs.IsNotFound(err) {
+			return fmt.Errorf("failed to delete sandbox %q: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Send CONTAINER_DELETED event with ContainerId equal to SandboxId.
 	c.generateAndSendContainerEvent(ctx, id, id, runtime.ContainerEventType_CONTAINER_DELETED_EVENT)
 
-	err = c.nri.RemovePodSandbox(ctx, &sandbox)
-	if err != nil {
+	if err := c.nri.RemovePodSandbox(ctx, &sandbox); err != nil {
 		log.G(ctx).WithError(err).Errorf("NRI pod removal notification failed")
 	}
 
@@ -113,7 +159,7 @@ func (c *criService) RemovePodSandbox(ctx context.Context, r *runtime.RemovePodS
 
 	if err := c.client.SandboxStore().Delete(ctx, id); err != nil {
 		if !errdefs.IsNotFound(err) {
-			return nil, fmt.Errorf("failed to remove sandbox metadata from store: %w", err)
+			return fmt.Errorf("failed to remove sandbox metadata from store: %w", err)
 		}
 		log.G(ctx).WithError(err).Warnf("failed to delete sandbox metadata from store: %q maybe recovered from v1.x release", id)
 	}
@@ -121,11 +167,5 @@ func (c *criService) RemovePodSandbox(ctx context.Context, r *runtime.RemovePodS
 	// Release the sandbox name reserved for the sandbox.
 	c.sandboxNameIndex.ReleaseByKey(id)
 
-	sandboxRemoveTimer.WithValues(sandbox.RuntimeHandler).UpdateSince(start)
-
-	span.AddEvent("pod sandbox removed",
-		tracing.Attribute("sandbox.remove.duration", time.Since(start).String()),
-	)
-
-	return &runtime.RemovePodSandboxResponse{}, nil
+	return nil
 }
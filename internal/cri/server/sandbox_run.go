@@ -36,10 +36,12 @@ import (
 	"github.com/basuotian/containerd/internal/cri/annotations"
 	"github.com/basuotian/containerd/internal/cri/bandwidth"
 	criconfig "github.com/basuotian/containerd/internal/cri/config"
+	"github.com/basuotian/containerd/internal/cri/constants"
 	"github.com/basuotian/containerd/internal/cri/server/podsandbox"
 	sandboxstore "github.com/basuotian/containerd/internal/cri/store/sandbox"
 	"github.com/basuotian/containerd/internal/cri/util"
 	"github.com/basuotian/containerd/pkg/netns"
+	"github.com/basuotian/containerd/pkg/startlatency"
 	"github.com/basuotian/containerd/pkg/tracing"
 )
 
@@ -63,6 +65,16 @@ func (c *criService) RunPodSandbox(ctx context.Context, r *runtime.RunPodSandbox
 	}
 	name := makeSandboxName(metadata)
 
+	// Claim a pre-created sandbox from the warm pool when one is available
+	// for the requested runtime handler and the pod configuration allows it.
+	var warm *warmSandbox
+	if c.sandboxPool != nil {
+		if warm = c.sandboxPool.claim(r.GetRuntimeHandler(), config); warm != nil {
+			id = warm.id
+			log.G(ctx).WithField("podsandboxid", id).Debugf("claimed warm sandbox for %q", name)
+		}
+	}
+
 	span.SetAttributes(
 		tracing.Attribute("sandbox.id", id),
 		tracing.Attribute("sandbox.name", name),
@@ -89,9 +101,14 @@ func (c *criService) RunPodSandbox(ctx context.Context, r *runtime.RunPodSandbox
 	}()
 
 	leaseSvc := c.client.LeasesService()
-	ls, lerr := leaseSvc.Create(ctx, leases.WithID(id))
-	if lerr != nil {
-		return nil, fmt.Errorf("failed to create lease for sandbox name %q: %w", name, lerr)
+	// Warm sandboxes already hold a lease with their id.
+	ls := leases.Lease{ID: id}
+	if warm == nil {
+		var lerr error
+		ls, lerr = leaseSvc.Create(ctx, leases.WithID(id))
+		if lerr != nil {
+			return nil, fmt.Errorf("failed to create lease for sandbox name %q: %w", name, lerr)
+		}
 	}
 	defer func() {
 		if retErr != nil {
@@ -114,6 +131,20 @@ func (c *criService) RunPodSandbox(ctx context.Context, r *runtime.RunPodSandbox
 		return nil, fmt.Errorf("unable to get OCI runtime for sandbox %q: %w", id, err)
 	}
 
+	if warm != nil {
+		// The runtime side of a claimed warm sandbox is already running;
+		// shut it down if the rest of the start path fails.
+		defer func() {
+			if retErr != nil {
+				deferCtx, deferCancel := util.DeferContext()
+				defer deferCancel()
+				if err := c.sandboxService.ShutdownSandbox(deferCtx, ociRuntime.Sandboxer, id); err != nil {
+					log.G(deferCtx).WithError(err).Errorf("failed to shutdown warm sandbox %q during cleanup", id)
+				}
+			}
+		}()
+	}
+
 	sandboxInfo.Runtime.Name = ociRuntime.Type
 	sandboxInfo.Sandboxer = ociRuntime.Sandboxer
 
@@ -153,8 +184,16 @@ func (c *criService) RunPodSandbox(ctx context.Context, r *runtime.RunPodSandbox
 		return nil, fmt.Errorf("unable to update extensions for sandbox %q: %w", id, err)
 	}
 
-	if _, err := c.client.SandboxStore().Create(ctx, sandboxInfo); err != nil {
-		return nil, fmt.Errorf("failed to save sandbox metadata: %w", err)
+	if warm == nil {
+		if _, err := c.client.SandboxStore().Create(ctx, sandboxInfo); err != nil {
+			return nil, fmt.Errorf("failed to save sandbox metadata: %w", err)
+		}
+	} else {
+		// Re-label the pooled record for the claiming pod, which also
+		// drops the warm pool label.
+		if sandboxInfo, err = c.client.SandboxStore().Update(ctx, sandboxInfo, "labels", "extensions"); err != nil {
+			return nil, fmt.Errorf("failed to re-label warm sandbox %q: %w", id, err)
+		}
 	}
 	defer func() {
 		if retErr != nil && cleanupErr == nil {
@@ -200,7 +239,22 @@ func (c *criService) RunPodSandbox(ctx context.Context, r *runtime.RunPodSandbox
 			netnsMountDir = filepath.Join(c.config.StateDir, "netns")
 		}
 
-		if !userNsEnabled {
+		externalNetNSPath := config.GetAnnotations()[annotations.ExternalNetNSPath]
+		if externalNetNSPath != "" {
+			// The network namespace was created, and its network configured,
+			// by an external network agent; join it instead of running CNI.
+			if !c.config.EnableExternalNetNS {
+				return nil, fmt.Errorf("sandbox %q requests external network namespace %q, but enable_external_netns is not set", id, externalNetNSPath)
+			}
+			if userNsEnabled {
+				return nil, fmt.Errorf("external network namespace %q cannot be combined with a pod user namespace", externalNetNSPath)
+			}
+			sandbox.NetNS, err = c.joinExternalNetNS(netnsMountDir, externalNetNSPath)
+		} else if warm != nil {
+			// Warm sandboxes were started in a pre-created network
+			// namespace; the pod network is configured in it below.
+			sandbox.NetNS = warm.netNS
+		} else if !userNsEnabled {
 			sandbox.NetNS, err = netns.NewNetNS(netnsMountDir)
 		} else {
 			usernsOpts := config.GetLinux().GetSecurityContext().GetNamespaceOptions().GetUsernsOptions()
@@ -229,39 +283,51 @@ func (c *criService) RunPodSandbox(ctx context.Context, r *runtime.RunPodSandbox
 		if sandboxInfo, err = c.client.SandboxStore().Update(ctx, sandboxInfo, "extensions"); err != nil {
 			return nil, fmt.Errorf("unable to save sandbox %q to sandbox store: %w", id, err)
 		}
-		// Define this defer to teardownPodNetwork prior to the setupPodNetwork function call.
-		// This is because in setupPodNetwork the resource is allocated even if it returns error, unlike other resource
-		// creation functions.
-		defer func() {
-			// Remove the network namespace only if all the resource cleanup is done.
-			if retErr != nil && cleanupErr == nil {
-				deferCtx, deferCancel := util.DeferContext()
-				defer deferCancel()
-				// Teardown network if an error is returned.
-				if cleanupErr = c.teardownPodNetwork(deferCtx, sandbox); cleanupErr != nil {
-					log.G(ctx).WithError(cleanupErr).Errorf("Failed to destroy network for sandbox %q", id)
-
-					// ignoring failed to destroy networks when we failed to setup networks
-					if sandbox.CNIResult == nil {
-						cleanupErr = nil
+		if externalNetNSPath != "" {
+			// The external agent configured the network before handing over
+			// the namespace, so CNI setup is skipped. With no CNIResult the
+			// network teardown on stop is skipped as well, and removing
+			// sandbox.NetNS only detaches the sandbox-local bind mount,
+			// leaving the external namespace intact.
+			log.G(ctx).Infof("Sandbox %q joined pre-created network namespace %q", id, externalNetNSPath)
+		} else {
+			// Define this defer to teardownPodNetwork prior to the setupPodNetwork function call.
+			// This is because in setupPodNetwork the resource is allocated even if it returns error, unlike other resource
+			// creation functions.
+			defer func() {
+				// Remove the network namespace only if all the resource cleanup is done.
+				if retErr != nil && cleanupErr == nil {
+					deferCtx, deferCancel := util.DeferContext()
+					defer deferCancel()
+					// Teardown network if an error is returned.
+					if cleanupErr = c.teardownPodNetwork(deferCtx, sandbox); cleanupErr != nil {
+						log.G(ctx).WithError(cleanupErr).Errorf("Failed to destroy network for sandbox %q", id)
+
+						// ignoring failed to destroy networks when we failed to setup networks
+						if sandbox.CNIResult == nil {
+							cleanupErr = nil
+						}
 					}
-				}
 
+				}
+			}()
+
+			// Setup network for sandbox.
+			// Certain VM based solutions like clear containers (Issue containerd/cri-containerd#524)
+			// rely on the assumption that CRI shim will not be querying the network namespace to check the
+			// network states such as IP.
+			// In future runtime implementation should avoid relying on CRI shim implementation details.
+			// In this case however caching the IP will add a subtle performance enhancement by avoiding
+			// calls to network namespace of the pod to query the IP of the veth interface on every
+			// SandboxStatus request.
+			if err := c.setupPodNetwork(ctx, &sandbox); err != nil {
+				return nil, fmt.Errorf("failed to setup network for sandbox %q: %w", id, err)
 			}
-		}()
-
-		// Setup network for sandbox.
-		// Certain VM based solutions like clear containers (Issue containerd/cri-containerd#524)
-		// rely on the assumption that CRI shim will not be querying the network namespace to check the
-		// network states such as IP.
-		// In future runtime implementation should avoid relying on CRI shim implementation details.
-		// In this case however caching the IP will add a subtle performance enhancement by avoiding
-		// calls to network namespace of the pod to query the IP of the veth interface on every
-		// SandboxStatus request.
-		if err := c.setupPodNetwork(ctx, &sandbox); err != nil {
-			return nil, fmt.Errorf("failed to setup network for sandbox %q: %w", id, err)
+			sandboxCreateNetworkTimer.UpdateSince(netStart)
+			// Recorded before the sandbox task starts so it is folded
+			// into the start latency breakdown published on task start.
+			startlatency.Record(constants.K8sContainerdNamespace, id, startlatency.StageNetworkReady, time.Since(netStart))
 		}
-		sandboxCreateNetworkTimer.UpdateSince(netStart)
 
 		if err := sandboxInfo.AddExtension(podsandbox.MetadataKey, &sandbox.Metadata); err != nil {
 			return nil, fmt.Errorf("unable to update extensions for sandbox %q: %w", id, err)
@@ -273,24 +339,29 @@ func (c *criService) RunPodSandbox(ctx context.Context, r *runtime.RunPodSandbox
 		}
 	}
 
-	if err := c.sandboxService.CreateSandbox(ctx, sandboxInfo, sb.WithOptions(config), sb.WithNetNSPath(sandbox.NetNSPath)); err != nil {
-		return nil, fmt.Errorf("failed to create sandbox %q: %w", id, err)
-	}
+	var ctrl sb.ControllerInstance
+	if warm != nil {
+		ctrl = warm.ctrl
+	} else {
+		if err := c.sandboxService.CreateSandbox(ctx, sandboxInfo, sb.WithOptions(config), sb.WithNetNSPath(sandbox.NetNSPath)); err != nil {
+			return nil, fmt.Errorf("failed to create sandbox %q: %w", id, err)
+		}
 
-	ctrl, err := c.sandboxService.StartSandbox(ctx, sandbox.Sandboxer, id)
-	if err != nil {
-		var cerr podsandbox.CleanupErr
-		if errors.As(err, &cerr) {
-			cleanupErr = fmt.Errorf("failed to cleanup sandbox: %w", cerr)
-
-			// Strip last error as cleanup error to handle separately
-			if merr, ok := err.(interface{ Unwrap() []error }); ok {
-				if errs := merr.Unwrap(); len(errs) > 0 {
-					err = errs[0]
+		ctrl, err = c.sandboxService.StartSandbox(ctx, sandbox.Sandboxer, id)
+		if err != nil {
+			var cerr podsandbox.CleanupErr
+			if errors.As(err, &cerr) {
+				cleanupErr = fmt.Errorf("failed to cleanup sandbox: %w", cerr)
+
+				// Strip last error as cleanup error to handle separately
+				if merr, ok := err.(interface{ Unwrap() []error }); ok {
+					if errs := merr.Unwrap(); len(errs) > 0 {
+						err = errs[0]
+					}
 				}
 			}
+			return nil, fmt.Errorf("failed to start sandbox %q: %w", id, err)
 		}
-		return nil, fmt.Errorf("failed to start sandbox %q: %w", id, err)
 	}
 
 	if ctrl.Address != "" {
@@ -431,6 +502,9 @@ func (c *criService) setupPodNetwork(ctx context.Context, sandbox *sandboxstore.
 	if configs, ok := result.Interfaces[defaultIfName]; ok && len(configs.IPConfigs) > 0 {
 		sandbox.IP, sandbox.AdditionalIPs = selectPodIPs(ctx, configs.IPConfigs, c.config.IPPreference)
 		sandbox.CNIResult = result
+		if err := c.setupPodBandwidth(ctx, netPlugin, sandbox); err != nil {
+			return fmt.Errorf("failed to set up pod bandwidth limits: %w", err)
+		}
 		return nil
 	}
 	return fmt.Errorf("failed to find network info for sandbox %q", id)
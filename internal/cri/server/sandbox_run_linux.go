@@ -18,6 +18,7 @@ package server
 
 import (
 	"fmt"
+	"os"
 	"syscall"
 
 	"github.com/basuotian/containerd/pkg/netns"
@@ -41,6 +42,24 @@ func (c *criService) bringUpLoopback(netns string) error {
 	return nil
 }
 
+// joinExternalNetNS validates the pre-created network namespace supplied by
+// an external network agent and bind mounts it under netnsMountDir. The
+// namespace must be a mounted netns owned by root or by the user running
+// containerd, so an unprivileged user cannot hand the sandbox an arbitrary
+// file.
+func (c *criService) joinExternalNetNS(netnsMountDir, nsPath string) (*netns.NetNS, error) {
+	st, err := os.Stat(nsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat external network namespace %q: %w", nsPath, err)
+	}
+	if stat, ok := st.Sys().(*syscall.Stat_t); ok {
+		if uid := int(stat.Uid); uid != 0 && uid != os.Geteuid() {
+			return nil, fmt.Errorf("external network namespace %q is owned by uid %d, expected root or uid %d", nsPath, uid, os.Geteuid())
+		}
+	}
+	return netns.NewNetNSFromPath(netnsMountDir, nsPath)
+}
+
 func (c *criService) setupNetnsWithinUserns(netnsMountDir string, opt *runtime.UserNamespace) (*netns.NetNS, error) {
 	if opt.GetMode() != runtime.NamespaceMode_POD {
 		return nil, fmt.Errorf("required pod-level user namespace setting")
@@ -32,3 +32,7 @@ func (c *criService) bringUpLoopback(string) error {
 func (c *criService) setupNetnsWithinUserns(basedir string, cfg *runtime.UserNamespace) (*netns.NetNS, error) {
 	return nil, fmt.Errorf("unsupported to setup netns within userns on unix platform")
 }
+
+func (c *criService) joinExternalNetNS(netnsMountDir, nsPath string) (*netns.NetNS, error) {
+	return nil, fmt.Errorf("unsupported to join external netns on unix platform")
+}
@@ -58,38 +58,60 @@ func (c *criService) StopPodSandbox(ctx context.Context, r *runtime.StopPodSandb
 }
 
 func (c *criService) stopPodSandbox(ctx context.Context, sandbox sandboxstore.Sandbox) error {
+	return c.teardownPodSandbox(ctx, sandbox, false)
+}
+
+// teardownPodSandbox runs the stop half of pod teardown as tracked stages:
+// containers are stopped before the sandbox, the sandbox before CNI, and
+// CNI before image mount cleanup. Completed stages are persisted and
+// skipped on retry; with force set, failing stages are skipped and
+// recorded as leaked instead of aborting the teardown.
+func (c *criService) teardownPodSandbox(ctx context.Context, sandbox sandboxstore.Sandbox, force bool) error {
 	span := tracing.SpanFromContext(ctx)
 	// Use the full sandbox id.
 	id := sandbox.ID
+	c.teardowns.begin(id)
 
 	// Stop all containers inside the sandbox. This terminates the container forcibly,
 	// and container may still be created, so production should not rely on this behavior.
 	// TODO(random-liu): Introduce a state in sandbox to avoid future container creation.
 	span.AddEvent("stopping containers in the sandbox")
 	stop := time.Now()
-	containers := c.containerStore.List()
-	for _, container := range containers {
-		if container.SandboxID != id {
-			continue
-		}
-		// Forcibly stop the container. Do not use `StopContainer`, because it introduces a race
-		// if a container is removed after list.
-		if err := c.stopContainer(ctx, container, 0); err != nil {
-			return fmt.Errorf("failed to stop container %q: %w", container.ID, err)
+	err := c.runTeardownStage(ctx, id, teardownStageContainers, force, func() error {
+		containers := c.containerStore.List()
+		for _, container := range containers {
+			if container.SandboxID != id {
+				continue
+			}
+			// Forcibly stop the container. Do not use `StopContainer`, because it introduces a race
+			// if a container is removed after list.
+			if err := c.stopContainer(ctx, container, 0); err != nil {
+				return fmt.Errorf("failed to stop container %q: %w", container.ID, err)
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Only stop sandbox container when it's running or unknown.
-	state := sandbox.Status.Get().State
-	if state == sandboxstore.StateReady || state == sandboxstore.StateUnknown {
-		if err := c.sandboxService.StopSandbox(ctx, sandbox.Sandboxer, id); err != nil {
-			// Log and ignore the error if controller already removed the sandbox
-			if errdefs.IsNotFound(err) {
-				log.G(ctx).Warnf("sandbox %q is not found when stopping it", id)
-			} else {
-				return fmt.Errorf("failed to stop sandbox %q: %w", id, err)
+	err = c.runTeardownStage(ctx, id, teardownStageSandbox, force, func() error {
+		// Only stop sandbox container when it's running or unknown.
+		state := sandbox.Status.Get().State
+		if state == sandboxstore.StateReady || state == sandboxstore.StateUnknown {
+			if err := c.sandboxService.StopSandbox(ctx, sandbox.Sandboxer, id); err != nil {
+				// Log and ignore the error if controller already removed the sandbox
+				if errdefs.IsNotFound(err) {
+					log.G(ctx).Warnf("sandbox %q is not found when stopping it", id)
+				} else {
+					return fmt.Errorf("failed to stop sandbox %q: %w", id, err)
+				}
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	sandboxRuntimeStopTimer.WithValues(sandbox.RuntimeHandler).UpdateSince(stop)
@@ -98,13 +120,15 @@ func (c *criService) stopPodSandbox(ctx context.Context, sandbox sandboxstore.Sa
 		tracing.Attribute("sandbox.stop.duration", time.Since(stop).String()),
 	)
 
-	err := c.nri.StopPodSandbox(ctx, &sandbox)
-	if err != nil {
+	if err := c.nri.StopPodSandbox(ctx, &sandbox); err != nil {
 		log.G(ctx).WithError(err).Errorf("NRI sandbox stop notification failed")
 	}
 
 	// Teardown network for sandbox.
-	if sandbox.NetNS != nil {
+	err = c.runTeardownStage(ctx, id, teardownStageNetwork, force, func() error {
+		if sandbox.NetNS == nil {
+			return nil
+		}
 		span.AddEvent("start pod network teardown")
 		netStop := time.Now()
 		// Use empty netns path if netns is not available. This is defined in:
@@ -127,15 +151,20 @@ func (c *criService) stopPodSandbox(ctx context.Context, sandbox sandboxstore.Sa
 		span.AddEvent("finished pod network teardown",
 			tracing.Attribute("network.teardown.duration", time.Since(netStop).String()),
 		)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	log.G(ctx).Infof("TearDown network for sandbox %q successfully", id)
 
-	err = c.cleanupImageMounts(ctx, id)
-	if err != nil {
-		return fmt.Errorf("failed to cleanup image mounts for sandbox %q: %w", id, err)
-	}
-	return nil
+	return c.runTeardownStage(ctx, id, teardownStageImageMounts, force, func() error {
+		if err := c.cleanupImageMounts(ctx, id); err != nil {
+			return fmt.Errorf("failed to cleanup image mounts for sandbox %q: %w", id, err)
+		}
+		return nil
+	})
 }
 
 // waitSandboxStop waits for sandbox to be stopped until context is cancelled or
@@ -166,6 +195,8 @@ func (c *criService) teardownPodNetwork(ctx context.Context, sandbox sandboxstor
 		return fmt.Errorf("get cni namespace options: %w", err)
 	}
 
+	c.teardownPodBandwidth(ctx, netPlugin, sandbox)
+
 	netStart := time.Now()
 	err = netPlugin.Remove(ctx, id, path, opts...)
 	networkPluginOperations.WithValues(networkTearDownOp).Inc()
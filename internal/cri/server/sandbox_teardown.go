@@ -0,0 +1,318 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+)
+
+// Pod teardown runs through a fixed sequence of stages; progress is
+// persisted per sandbox so a retried StopPodSandbox or RemovePodSandbox
+// does not redo stages that already succeeded (e.g. tear down CNI twice
+// after the shim refused to shut down).
+const (
+	// teardownStageContainers stops all containers in the sandbox.
+	teardownStageContainers = "stop-containers"
+	// teardownStageSandbox stops the sandbox container itself.
+	teardownStageSandbox = "stop-sandbox"
+	// teardownStageNetwork tears down CNI and removes the network namespace.
+	teardownStageNetwork = "teardown-network"
+	// teardownStageImageMounts unmounts image volumes used by the sandbox.
+	teardownStageImageMounts = "cleanup-image-mounts"
+	// teardownStageLease deletes the sandbox resource lease.
+	teardownStageLease = "delete-lease"
+	// teardownStageNetNSClosed verifies the network namespace is gone.
+	teardownStageNetNSClosed = "verify-netns-closed"
+	// teardownStageRemoveContainers removes all containers in the sandbox.
+	teardownStageRemoveContainers = "remove-containers"
+	// teardownStageShutdown shuts down the sandbox through its controller,
+	// releasing the shim and the rootfs snapshot.
+	teardownStageShutdown = "shutdown-sandbox"
+)
+
+// teardownStateDir under the CRI root dir holds one JSON file per sandbox
+// with in-progress teardown state.
+const teardownStateDir = "teardown"
+
+func init() {
+	typeurl.Register(&ForcePodCleanupRequest{}, "containerd.cri.ForcePodCleanupRequest")
+	typeurl.Register(&ForcePodCleanupResponse{}, "containerd.cri.ForcePodCleanupResponse")
+}
+
+// ForcePodCleanupRequest asks the CRI plugin, through the introspection
+// API, to tear down a pod sandbox skipping stages that keep failing. Any
+// skipped stage is recorded as leaked in the response.
+type ForcePodCleanupRequest struct {
+	SandboxID string `json:"sandbox_id"`
+}
+
+// ForcePodCleanupResponse reports what a forced pod cleanup did.
+type ForcePodCleanupResponse struct {
+	SandboxID string `json:"sandbox_id"`
+	// Completed lists the teardown stages that ran successfully, in order.
+	Completed []string `json:"completed,omitempty"`
+	// Leaked maps skipped stages to the error that made them
+	// irrecoverable; the named resources were left behind on the host.
+	Leaked map[string]string `json:"leaked,omitempty"`
+}
+
+// teardownState is the persisted progress of one pod teardown.
+type teardownState struct {
+	SandboxID string    `json:"sandbox_id"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Completed lists stages that succeeded, in completion order.
+	Completed []string `json:"completed,omitempty"`
+	// Attempts counts failures per stage.
+	Attempts map[string]int `json:"attempts,omitempty"`
+	// Leaked maps force-skipped stages to their last error.
+	Leaked map[string]string `json:"leaked,omitempty"`
+}
+
+func (s *teardownState) completed(stage string) bool {
+	for _, c := range s.Completed {
+		if c == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// teardownTracker keeps per-sandbox teardown state in memory and mirrors it
+// to disk so progress survives a containerd restart. An empty directory
+// disables persistence.
+type teardownTracker struct {
+	mu     sync.Mutex
+	dir    string
+	states map[string]*teardownState
+}
+
+func newTeardownTracker(dir string) *teardownTracker {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			log.L.WithError(err).Warnf("failed to create teardown state dir %q, teardown progress will not be persisted", dir)
+			dir = ""
+		}
+	}
+	return &teardownTracker{
+		dir:    dir,
+		states: make(map[string]*teardownState),
+	}
+}
+
+// begin returns the teardown state for the sandbox, loading persisted
+// progress from a previous attempt if there is any.
+func (t *teardownTracker) begin(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.states[id]; ok {
+		return
+	}
+	st := &teardownState{
+		SandboxID: id,
+		StartedAt: time.Now(),
+		Attempts:  make(map[string]int),
+		Leaked:    make(map[string]string),
+	}
+	if t.dir != "" {
+		if data, err := os.ReadFile(t.statePath(id)); err == nil {
+			if err := json.Unmarshal(data, st); err != nil {
+				log.L.WithError(err).Warnf("corrupt teardown state for sandbox %q, starting over", id)
+			}
+			if st.Attempts == nil {
+				st.Attempts = make(map[string]int)
+			}
+			if st.Leaked == nil {
+				st.Leaked = make(map[string]string)
+			}
+		}
+	}
+	t.states[id] = st
+}
+
+func (t *teardownTracker) statePath(id string) string {
+	return filepath.Join(t.dir, id+".json")
+}
+
+func (t *teardownTracker) isCompleted(id, stage string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.states[id]
+	return ok && st.completed(stage)
+}
+
+// done marks a stage as completed.
+func (t *teardownTracker) done(id, stage string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.states[id]
+	if !ok || st.completed(stage) {
+		return
+	}
+	st.Completed = append(st.Completed, stage)
+	t.persist(st)
+}
+
+// failed counts a stage failure and returns the total number of failed
+// attempts for that stage.
+func (t *teardownTracker) failed(id, stage string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.states[id]
+	if !ok {
+		return 0
+	}
+	st.Attempts[stage]++
+	t.persist(st)
+	return st.Attempts[stage]
+}
+
+// leak records that a stage was skipped and its resources leaked.
+func (t *teardownTracker) leak(id, stage string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.states[id]
+	if !ok {
+		return
+	}
+	st.Leaked[stage] = err.Error()
+	st.Completed = append(st.Completed, stage)
+	t.persist(st)
+}
+
+// state returns a snapshot of the teardown state for the sandbox.
+func (t *teardownTracker) state(id string) *teardownState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.states[id]
+	if !ok {
+		return nil
+	}
+	copied := *st
+	copied.Completed = append([]string(nil), st.Completed...)
+	copied.Leaked = make(map[string]string, len(st.Leaked))
+	for k, v := range st.Leaked {
+		copied.Leaked[k] = v
+	}
+	return &copied
+}
+
+// finish drops the teardown state once the sandbox is fully removed.
+func (t *teardownTracker) finish(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.states, id)
+	if t.dir != "" {
+		if err := os.Remove(t.statePath(id)); err != nil && !os.IsNotExist(err) {
+			log.L.WithError(err).Warnf("failed to remove teardown state for sandbox %q", id)
+		}
+	}
+}
+
+func (t *teardownTracker) persist(st *teardownState) {
+	if t.dir == "" {
+		return
+	}
+	st.UpdatedAt = time.Now()
+	data, err := json.Marshal(st)
+	if err != nil {
+		log.L.WithError(err).Warnf("failed to marshal teardown state for sandbox %q", st.SandboxID)
+		return
+	}
+	path := t.statePath(st.SandboxID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		log.L.WithError(err).Warnf("failed to persist teardown state for sandbox %q", st.SandboxID)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.L.WithError(err).Warnf("failed to persist teardown state for sandbox %q", st.SandboxID)
+	}
+}
+
+// runTeardownStage runs one teardown stage for the sandbox, skipping it if
+// a previous attempt already completed it. On failure the stage fails the
+// teardown so the kubelet keeps retrying, unless force is set - then it is
+// skipped with its resources recorded as leaked. Only the explicit
+// ForcePodCleanup API sets force; the regular StopPodSandbox and
+// RemovePodSandbox paths must never report success while containers or the
+// network namespace may still be live.
+func (c *criService) runTeardownStage(ctx context.Context, id, stage string, force bool, fn func() error) error {
+	if c.teardowns.isCompleted(id, stage) {
+		log.G(ctx).Debugf("Skipping completed teardown stage %q for sandbox %q", stage, id)
+		return nil
+	}
+	err := fn()
+	if err == nil {
+		c.teardowns.done(id, stage)
+		return nil
+	}
+	attempts := c.teardowns.failed(id, stage)
+	if force {
+		log.G(ctx).WithError(err).Warnf("Skipping teardown stage %q for sandbox %q after %d attempts, resources leaked", stage, id, attempts)
+		c.teardowns.leak(id, stage, err)
+		return nil
+	}
+	return fmt.Errorf("teardown stage %q for sandbox %q: %w", stage, id, err)
+}
+
+// ForcePodCleanup tears down and removes the sandbox, skipping stages that
+// fail and recording what was leaked instead of returning an error. It is
+// the last resort for pods whose regular teardown is wedged.
+func (c *criService) ForcePodCleanup(ctx context.Context, id string) (*ForcePodCleanupResponse, error) {
+	sandbox, err := c.sandboxStore.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sandbox %q: %w", id, err)
+	}
+	id = sandbox.ID
+
+	log.G(ctx).Warnf("Forcing cleanup of pod sandbox %q", id)
+	if err := c.teardownPodSandbox(ctx, sandbox, true); err != nil {
+		return nil, err
+	}
+	if err := c.removePodSandbox(ctx, sandbox, true); err != nil {
+		return nil, err
+	}
+
+	resp := &ForcePodCleanupResponse{SandboxID: id}
+	if st := c.teardowns.state(id); st != nil {
+		resp.Completed = st.Completed
+		resp.Leaked = st.Leaked
+	}
+	c.teardowns.finish(id)
+	return resp, nil
+}
+
+// PluginInfo exposes pod cleanup operations through the introspection API.
+func (c *criService) PluginInfo(ctx context.Context, request interface{}) (interface{}, error) {
+	switch r := request.(type) {
+	case *ForcePodCleanupRequest:
+		return c.ForcePodCleanup(ctx, r.SandboxID)
+	default:
+		return nil, fmt.Errorf("unsupported request type %T: %w", request, errdefs.ErrNotImplemented)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeardownStageSkipsCompleted(t *testing.T) {
+	c := newTestCRIService()
+	ctx := context.Background()
+	const id = "sandbox-1"
+	c.teardowns.begin(id)
+
+	calls := 0
+	fn := func() error {
+		calls++
+		return nil
+	}
+	require.NoError(t, c.runTeardownStage(ctx, id, teardownStageNetwork, false, fn))
+	require.NoError(t, c.runTeardownStage(ctx, id, teardownStageNetwork, false, fn))
+	assert.Equal(t, 1, calls)
+}
+
+func TestTeardownStageForceRecordsLeak(t *testing.T) {
+	c := newTestCRIService()
+	ctx := context.Background()
+	const id = "sandbox-2"
+	c.teardowns.begin(id)
+
+	stageErr := errors.New("cni delete failed")
+	err := c.runTeardownStage(ctx, id, teardownStageNetwork, true, func() error { return stageErr })
+	require.NoError(t, err)
+
+	st := c.teardowns.state(id)
+	require.NotNil(t, st)
+	assert.Equal(t, stageErr.Error(), st.Leaked[teardownStageNetwork])
+	// A leaked stage counts as handled and is not retried.
+	assert.True(t, st.completed(teardownStageNetwork))
+}
+
+func TestTeardownStageNeverLeaksWithoutForce(t *testing.T) {
+	c := newTestCRIService()
+	ctx := context.Background()
+	const id = "sandbox-3"
+	c.teardowns.begin(id)
+
+	stageErr := errors.New("shim shutdown failed")
+	fn := func() error { return stageErr }
+	// Without force the stage keeps failing no matter how often it is
+	// retried; only ForcePodCleanup may skip and leak.
+	for i := 0; i < 5; i++ {
+		err := c.runTeardownStage(ctx, id, teardownStageShutdown, false, fn)
+		require.Error(t, err)
+	}
+	st := c.teardowns.state(id)
+	require.NotNil(t, st)
+	assert.Equal(t, 5, st.Attempts[teardownStageShutdown])
+	assert.NotContains(t, st.Leaked, teardownStageShutdown)
+	assert.False(t, st.completed(teardownStageShutdown))
+}
+
+func TestTeardownTrackerPersistence(t *testing.T) {
+	dir := t.TempDir()
+	const id = "sandbox-4"
+
+	tracker := newTeardownTracker(dir)
+	tracker.begin(id)
+	tracker.done(id, teardownStageContainers)
+	tracker.failed(id, teardownStageNetwork)
+	tracker.leak(id, teardownStageNetwork, errors.New("leaked netns"))
+
+	// A new tracker, as after a containerd restart, loads the progress.
+	reloaded := newTeardownTracker(dir)
+	reloaded.begin(id)
+	assert.True(t, reloaded.isCompleted(id, teardownStageContainers))
+	assert.True(t, reloaded.isCompleted(id, teardownStageNetwork))
+	st := reloaded.state(id)
+	require.NotNil(t, st)
+	assert.Equal(t, 1, st.Attempts[teardownStageNetwork])
+	assert.Equal(t, "leaked netns", st.Leaked[teardownStageNetwork])
+
+	// finish drops both the in-memory and the on-disk state.
+	reloaded.finish(id)
+	fresh := newTeardownTracker(dir)
+	fresh.begin(id)
+	assert.False(t, fresh.isCompleted(id, teardownStageContainers))
+}
+
+func TestPluginInfoUnsupportedRequest(t *testing.T) {
+	c := newTestCRIService()
+	_, err := c.PluginInfo(context.Background(), "bogus")
+	assert.Error(t, err)
+}
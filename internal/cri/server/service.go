@@ -21,16 +21,19 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"path/filepath"
 	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	criuutils "github.com/checkpoint-restore/go-criu/v7/utils"
 	"github.com/containerd/go-cni"
 	"github.com/containerd/log"
 	"github.com/containerd/typeurl/v2"
 	imagespec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/opencontainers/runtime-spec/specs-go/features"
+	"github.com/opencontainers/selinux/go-selinux"
 	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
 
 	apitypes "github.com/containerd/containerd/api/types"
@@ -39,6 +42,7 @@ import (
 	"github.com/basuotian/containerd/core/introspection"
 	_ "github.com/basuotian/containerd/core/runtime" // for typeurl init
 	"github.com/basuotian/containerd/core/sandbox"
+	"github.com/basuotian/containerd/core/snapshots"
 	"github.com/basuotian/containerd/internal/cri/config"
 	criconfig "github.com/basuotian/containerd/internal/cri/config"
 	"github.com/basuotian/containerd/internal/cri/nri"
@@ -49,6 +53,7 @@ import (
 	sandboxstore "github.com/basuotian/containerd/internal/cri/store/sandbox"
 	snapshotstore "github.com/basuotian/containerd/internal/cri/store/snapshot"
 	streaming "github.com/basuotian/containerd/internal/cri/streamingserver"
+	"github.com/basuotian/containerd/internal/cri/topology"
 	ctrdutil "github.com/basuotian/containerd/internal/cri/util"
 	"github.com/basuotian/containerd/internal/eventq"
 	nriservice "github.com/basuotian/containerd/internal/nri"
@@ -149,15 +154,38 @@ type criService struct {
 	allCaps []string //nolint:nolintlint,unused // Ignore on non-Linux
 	// containerEventsQ is used to capture container events and send them
 	// to the callers of GetContainerEvents.
-	containerEventsQ eventq.EventQueue[runtime.ContainerEventResponse]
+	containerEventsQ eventq.EventQueue[sequencedEvent]
+	// containerEvents assigns sequence numbers to container events and
+	// keeps a bounded persisted buffer for backfilling subscribers.
+	containerEvents *containerEventBuffer
+	// teardowns tracks per-sandbox pod teardown progress so retried and
+	// forced teardowns skip stages that already completed.
+	teardowns *teardownTracker
 	// nri is used to hook NRI into CRI request processing.
 	nri *nri.API
 	// sandboxService is the sandbox related service for CRI
 	sandboxService sandboxService
+	// sandboxPool keeps pre-created warm sandboxes per runtime handler,
+	// nil when no handler configures a pool.
+	sandboxPool *sandboxPool
+	// reconcileCancel stops the state reconciliation loop.
+	reconcileCancel context.CancelFunc
+	// shimFDCancel stops the shim fd guardrail monitor.
+	shimFDCancel context.CancelFunc
+	// reconcileSuspects are the orphan candidates seen by the previous
+	// reconciliation round, only touched by the reconcile loop.
+	reconcileSuspects map[string]struct{}
 	// runtimeHandlers contains runtime handler info
 	runtimeHandlers map[string]*runtime.RuntimeHandler
+	// runtimeHandlerFeatures contains feature flags probed per runtime
+	// handler at startup, surfaced through verbose RuntimeStatus info.
+	runtimeHandlerFeatures map[string]runtimeHandlerFeatures
 	// runtimeFeatures container runtime features info
 	runtimeFeatures *runtime.RuntimeFeatures
+	// topology is the cpu and NUMA node topology of the node, discovered
+	// at startup and used to validate cpuset pinning hints. Nil when
+	// discovery failed or is not supported on this platform.
+	topology *topology.NodeTopology
 }
 
 type CRIServiceOptions struct {
@@ -199,15 +227,33 @@ func NewCRIService(options *CRIServiceOptions) (CRIService, runtime.RuntimeServi
 		netPlugin:          make(map[string]cni.CNI),
 		sandboxService:     newCriSandboxService(&config, options.SandboxControllers),
 		runtimeHandlers:    make(map[string]*runtime.RuntimeHandler),
+
+		runtimeHandlerFeatures: make(map[string]runtimeHandlerFeatures),
+	}
+
+	eventsBufferSize := config.ContainerEventsBufferSize
+	if eventsBufferSize == 0 {
+		eventsBufferSize = defaultContainerEventsBufferSize
+	}
+	var eventsPath string
+	if config.RootDir != "" {
+		eventsPath = filepath.Join(config.RootDir, containerEventsFile)
+	}
+	c.containerEvents = newContainerEventBuffer(eventsPath, eventsBufferSize)
+
+	var teardownPath string
+	if config.RootDir != "" {
+		teardownPath = filepath.Join(config.RootDir, teardownStateDir)
 	}
+	c.teardowns = newTeardownTracker(teardownPath)
 
 	// TODO: Make discard time configurable
-	c.containerEventsQ = eventq.New[runtime.ContainerEventResponse](5*time.Minute, func(event runtime.ContainerEventResponse) {
+	c.containerEventsQ = eventq.New[sequencedEvent](5*time.Minute, func(event sequencedEvent) {
 		containerEventsDroppedCount.Inc()
 		log.L.WithFields(
 			log.Fields{
-				"container": event.ContainerId,
-				"type":      event.ContainerEventType,
+				"container": event.Event.ContainerId,
+				"type":      event.Event.ContainerEventType,
 			}).Info("container event discarded")
 	})
 
@@ -216,7 +262,7 @@ func NewCRIService(options *CRIServiceOptions) (CRIService, runtime.RuntimeServi
 	}
 
 	// prepare streaming server
-	c.streamServer, err = streaming.NewServer(options.StreamingConfig, newStreamRuntime(c))
+	c.streamServer, err = streaming.NewServer(options.StreamingConfig, newStreamRuntime(c, options.StreamingConfig))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create stream server: %w", err)
 	}
@@ -268,6 +314,26 @@ func (c *criService) Run(ready func()) error {
 		return fmt.Errorf("failed to recover state: %w", err)
 	}
 
+	// Start the warm sandbox pool if any runtime handler configures one.
+	if c.sandboxPool = newSandboxPool(c); c.sandboxPool != nil {
+		log.L.Info("Start warm sandbox pool")
+		go c.sandboxPool.run(ctrdutil.NamespacedContext())
+	}
+
+	// Start the periodic state reconciler.
+	log.L.Info("Start state reconciler")
+	reconcileCtx, reconcileCancel := context.WithCancel(ctrdutil.NamespacedContext())
+	c.reconcileCancel = reconcileCancel
+	go c.reconcileLoop(reconcileCtx)
+
+	// Start the shim fd guardrail monitor when configured.
+	if c.config.SandboxGuardrails.ShimFDThreshold > 0 {
+		log.L.Info("Start shim fd monitor")
+		shimFDCtx, shimFDCancel := context.WithCancel(ctrdutil.NamespacedContext())
+		c.shimFDCancel = shimFDCancel
+		go c.shimFDMonitorLoop(shimFDCtx)
+	}
+
 	// Start event handler.
 	log.L.Info("Start event monitor")
 	eventMonitorErrCh := c.eventMonitor.Start()
@@ -351,6 +417,15 @@ func (c *criService) Run(ready func()) error {
 // TODO(random-liu): Make close synchronous.
 func (c *criService) Close() error {
 	log.L.Info("Stop CRI service")
+	if c.reconcileCancel != nil {
+		c.reconcileCancel()
+	}
+	if c.shimFDCancel != nil {
+		c.shimFDCancel()
+	}
+	if c.sandboxPool != nil {
+		c.sandboxPool.close()
+	}
 	for name, h := range c.cniNetConfMonitor {
 		if err := h.stop(); err != nil {
 			log.L.WithError(err).Errorf("failed to stop cni network conf monitor for %s", name)
@@ -372,6 +447,9 @@ func (c *criService) introspectRuntimeHandler(ctx context.Context, intro introsp
 	h := &runtime.RuntimeHandler{
 		Name: name,
 	}
+	hf := runtimeHandlerFeatures{
+		Checkpoint: criuutils.CheckForCriu(criuutils.PodCriuVersion) == nil,
+	}
 	rawFeatures, err := introspectRuntimeFeatures(ctx, intro, r)
 	if err != nil {
 		log.G(ctx).WithError(err).Debugf("failed to introspect features of runtime %q", name)
@@ -388,18 +466,56 @@ func (c *criService) introspectRuntimeHandler(ctx context.Context, intro introsp
 		userns := supportsCRIUserns(rawFeatures)
 		h.Features.UserNamespaces = userns
 		log.G(ctx).Debugf("runtime %q supports CRI userns: %v", name, userns)
+
+		hf.UserNamespaces = userns
+		hf.RecursiveReadOnlyMounts = h.Features.RecursiveReadOnlyMounts
+		if l := rawFeatures.Linux; l != nil {
+			if m := l.MountExtensions; m != nil && m.IDMap != nil && m.IDMap.Enabled != nil {
+				hf.IdmappedMounts = *m.IDMap.Enabled
+			}
+			if hf.IdmappedMounts {
+				// The runtime alone is not enough, the snapshotter for this
+				// handler must remap ownership at mount time as well.
+				snapshotter := c.RuntimeSnapshotter(ctx, r)
+				if capabs, err := c.client.GetSnapshotterCapabilities(ctx, snapshotter); err != nil {
+					log.G(ctx).WithError(err).Debugf("failed to get capabilities of snapshotter %q", snapshotter)
+				} else if !slices.Contains(capabs, snapshots.CapabilityRemapIDs) {
+					log.G(ctx).Debugf("snapshotter %q does not remap ids, disabling idmapped mounts for runtime %q", snapshotter, name)
+					hf.IdmappedMounts = false
+				}
+			}
+			if s := l.Selinux; s != nil && s.Enabled != nil && *s.Enabled {
+				// the runtime supports SELinux; report it only when the
+				// host has it enabled as well
+				hf.SELinux = selinux.GetEnabled()
+			}
+		}
 	}
 
 	c.runtimeHandlers[name] = h
+	c.runtimeHandlerFeatures[name] = hf
 	if name == c.config.DefaultRuntimeName {
 		defH := *h
 		defH.Name = "" // denotes default
 		c.runtimeHandlers[""] = &defH
+		c.runtimeHandlerFeatures[""] = hf
 	}
 
 	return nil
 }
 
+// runtimeHandlerFeatures are the feature flags probed for a runtime
+// handler at startup, beyond what the CRI RuntimeHandler message can
+// carry. They are reported in verbose RuntimeStatus info so scheduler
+// level tooling can make placement decisions.
+type runtimeHandlerFeatures struct {
+	UserNamespaces          bool `json:"userNamespaces"`
+	RecursiveReadOnlyMounts bool `json:"recursiveReadOnlyMounts"`
+	IdmappedMounts          bool `json:"idmappedMounts"`
+	SELinux                 bool `json:"selinux"`
+	Checkpoint              bool `json:"checkpoint"`
+}
+
 func introspectRuntimeFeatures(ctx context.Context, intro introspection.Service, r config.Runtime) (*features.Features, error) {
 	if r.Type != plugins.RuntimeRuncV2 {
 		return nil, fmt.Errorf("introspecting OCI runtime features needs the runtime type to be %q, got %q",
@@ -23,6 +23,7 @@ import (
 	"github.com/opencontainers/selinux/go-selinux"
 	"tags.cncf.io/container-device-interface/pkg/cdi"
 
+	"github.com/basuotian/containerd/internal/cri/topology"
 	"github.com/basuotian/containerd/pkg/cap"
 	"github.com/basuotian/containerd/pkg/kernelversion"
 	"github.com/containerd/go-cni"
@@ -108,6 +109,13 @@ func (c *criService) initPlatform() (err error) {
 		}
 	}
 
+	// Discover the node topology used to validate cpuset pinning hints.
+	// Nodes without the expected sysfs layout only lose the validation.
+	if c.topology, err = topology.Discover(); err != nil {
+		log.L.WithError(err).Warn("failed to discover node topology, cpuset hints will not be validated")
+		c.topology, err = nil, nil
+	}
+
 	return nil
 }
 
@@ -151,6 +151,7 @@ func newTestCRIService(opts ...testOpt) *criService {
 			defaultNetworkPlugin: servertesting.NewFakeCNIPlugin(),
 		},
 		sandboxService: &fakeSandboxService{},
+		teardowns:      newTeardownTracker(""),
 	}
 	for _, opt := range opts {
 		opt(service)
@@ -0,0 +1,172 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+	"golang.org/x/sys/unix"
+
+	shimcore "github.com/basuotian/containerd/core/runtime/v2"
+	"github.com/basuotian/containerd/internal/cri/constants"
+	sandboxstore "github.com/basuotian/containerd/internal/cri/store/sandbox"
+	"github.com/basuotian/containerd/plugins"
+)
+
+// ShimFDPressureTopic is the containerd event topic published when a
+// sandbox's shim crosses the configured open file descriptor threshold.
+const ShimFDPressureTopic = "/cri/sandboxes/shim-fd-pressure"
+
+// defaultShimFDCheckPeriod is the interval between shim fd checks when
+// `shim_fd_check_period` is not configured.
+const defaultShimFDCheckPeriod = time.Minute
+
+func init() {
+	typeurl.Register(&ShimFDPressureEvent{}, "containerd.cri.ShimFDPressureEvent")
+}
+
+// ShimFDPressureEvent is the event payload published on
+// ShimFDPressureTopic.
+type ShimFDPressureEvent struct {
+	// SandboxID of the flagged sandbox
+	SandboxID string `json:"sandbox_id"`
+	// Pid of the sandbox's shim process
+	Pid uint32 `json:"pid"`
+	// OpenFDs is the observed open file descriptor count of the shim
+	OpenFDs uint64 `json:"open_fds"`
+	// Threshold is the configured `shim_fd_threshold`
+	Threshold uint64 `json:"threshold"`
+	// Restarted reports whether the shim was killed so the kubelet
+	// recreates the sandbox
+	Restarted bool `json:"restarted"`
+}
+
+// shimFDMonitorLoop periodically compares the open fd count of every ready
+// sandbox's shim against `shim_fd_threshold`. A sandbox crossing the
+// threshold is flagged once, an event is published, and, when
+// `restart_shim_on_fd_threshold` is set, its shim is killed so the sandbox
+// is reported as not ready and recreated by the kubelet.
+func (c *criService) shimFDMonitorLoop(ctx context.Context) {
+	period := defaultShimFDCheckPeriod
+	if p := c.config.SandboxGuardrails.ShimFDCheckPeriod; p != "" {
+		// Validated at config load time.
+		if d, err := time.ParseDuration(p); err == nil {
+			period = d
+		}
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	// flagged tracks sandboxes already over the threshold so each crossing
+	// emits a single event; entries are dropped once the count recovers or
+	// the sandbox goes away.
+	flagged := make(map[string]struct{})
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkShimFDs(ctx, flagged)
+		}
+	}
+}
+
+func (c *criService) checkShimFDs(ctx context.Context, flagged map[string]struct{}) {
+	resp, err := c.client.IntrospectionService().PluginInfo(ctx, string(plugins.RuntimePluginV2), "task", &shimcore.ShimProcessesRequest{
+		Namespace: constants.K8sContainerdNamespace,
+	})
+	if err != nil {
+		log.G(ctx).WithError(err).Debug("failed to list shim processes")
+		return
+	}
+	var procs shimcore.ShimProcessesResponse
+	if err := typeurl.UnmarshalTo(resp.Extra, &procs); err != nil {
+		log.G(ctx).WithError(err).Warn("failed to unmarshal shim processes")
+		return
+	}
+
+	threshold := c.config.SandboxGuardrails.ShimFDThreshold
+	seen := make(map[string]struct{}, len(procs.Shims))
+	for _, p := range procs.Shims {
+		sandbox, err := c.sandboxStore.Get(p.ID)
+		if err != nil {
+			// Container shims of a pod are grouped under the sandbox shim;
+			// anything else is not a sandbox and not our concern.
+			continue
+		}
+		if sandbox.Status.Get().State != sandboxstore.StateReady {
+			continue
+		}
+		seen[p.ID] = struct{}{}
+
+		fds, err := countOpenFDs(p.Pid)
+		if err != nil {
+			log.G(ctx).WithError(err).WithField("podsandboxid", p.ID).Debug("failed to count shim fds")
+			continue
+		}
+		if fds < threshold {
+			delete(flagged, p.ID)
+			continue
+		}
+		if _, ok := flagged[p.ID]; ok {
+			continue
+		}
+		flagged[p.ID] = struct{}{}
+
+		restart := c.config.SandboxGuardrails.RestartShimOnFDThreshold
+		log.G(ctx).WithField("podsandboxid", p.ID).
+			Warnf("sandbox shim (pid %d) has %d open fds, over the threshold of %d (restart=%t)",
+				p.Pid, fds, threshold, restart)
+
+		event := &ShimFDPressureEvent{
+			SandboxID: p.ID,
+			Pid:       p.Pid,
+			OpenFDs:   fds,
+			Threshold: threshold,
+			Restarted: restart,
+		}
+		if err := c.client.EventService().Publish(ctx, ShimFDPressureTopic, event); err != nil {
+			log.G(ctx).WithError(err).WithField("podsandboxid", p.ID).Warn("failed to publish shim fd pressure event")
+		}
+
+		if restart {
+			if err := unix.Kill(int(p.Pid), unix.SIGKILL); err != nil {
+				log.G(ctx).WithError(err).WithField("podsandboxid", p.ID).Warn("failed to kill shim over fd threshold")
+			}
+		}
+	}
+
+	for id := range flagged {
+		if _, ok := seen[id]; !ok {
+			delete(flagged, id)
+		}
+	}
+}
+
+func countOpenFDs(pid uint32) (uint64, error) {
+	entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(entries)), nil
+}
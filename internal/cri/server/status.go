@@ -75,6 +75,12 @@ func (c *criService) Status(ctx context.Context, r *runtime.StatusRequest) (*run
 		}
 		resp.Info["golang"] = string(versionByt)
 
+		handlerFeaturesByt, err := json.Marshal(c.runtimeHandlerFeatures)
+		if err != nil {
+			return nil, err
+		}
+		resp.Info["runtimeHandlerFeatures"] = string(handlerFeaturesByt)
+
 		if netPlugin != nil {
 			cniConfig, err := json.Marshal(netPlugin.GetConfig())
 			if err != nil {
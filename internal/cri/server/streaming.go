@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/remotecommand"
@@ -31,17 +32,42 @@ import (
 )
 
 type streamRuntime struct {
-	c *criService
+	c        *criService
+	sessions *sessionTracker
 }
 
-func newStreamRuntime(c *criService) streaming.Runtime {
-	return &streamRuntime{c: c}
+func newStreamRuntime(c *criService, config streaming.Config) streaming.Runtime {
+	return &streamRuntime{
+		c:        c,
+		sessions: newSessionTracker(config.MaxSessions, config.MaxSessionsPerPod, config.SessionIdleTimeout),
+	}
 }
 
 // Exec executes a command inside the container. executil.ExitError is returned if the command
 // returns non-zero exit code.
 func (s *streamRuntime) Exec(ctx context.Context, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.WriteCloser,
 	tty bool, resize <-chan remotecommand.TerminalSize) error {
+	cntr, err := s.c.containerStore.Get(containerID)
+	if err != nil {
+		return fmt.Errorf("failed to find container %q: %w", containerID, err)
+	}
+	release, err := s.sessions.acquire(cntr.SandboxID)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stdin, stdout, stderr = s.sessions.watchStreams(ctx, cancel, stdin, stdout, stderr)
+
+	audit := &StreamingAuditEvent{
+		Kind:        "exec",
+		ContainerID: cntr.ID,
+		SandboxID:   cntr.SandboxID,
+		Cmd:         cmd,
+		StartedAt:   time.Now(),
+	}
 	exitCode, err := s.c.execInContainer(ctrdutil.WithNamespace(ctx), containerID, execOptions{
 		cmd:    cmd,
 		stdin:  stdin,
@@ -50,6 +76,15 @@ func (s *streamRuntime) Exec(ctx context.Context, containerID string, cmd []stri
 		tty:    tty,
 		resize: resize,
 	})
+	audit.FinishedAt = time.Now()
+	if exitCode != nil {
+		code := int32(*exitCode)
+		audit.ExitCode = &code
+	}
+	if err != nil {
+		audit.Error = err.Error()
+	}
+	s.publishAudit(audit)
 	if err != nil {
 		return fmt.Errorf("failed to exec in container: %w", err)
 	}
@@ -62,9 +97,35 @@ func (s *streamRuntime) Exec(ctx context.Context, containerID string, cmd []stri
 	}
 }
 
-func (s *streamRuntime) Attach(ctx context.Context, containerID string, in io.Reader, out, err io.WriteCloser, tty bool,
+func (s *streamRuntime) Attach(ctx context.Context, containerID string, in io.Reader, out, errw io.WriteCloser, tty bool,
 	resize <-chan remotecommand.TerminalSize) error {
-	return s.c.attachContainer(ctrdutil.WithNamespace(ctx), containerID, in, out, err, tty, resize)
+	cntr, err := s.c.containerStore.Get(containerID)
+	if err != nil {
+		return fmt.Errorf("failed to find container %q: %w", containerID, err)
+	}
+	release, err := s.sessions.acquire(cntr.SandboxID)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	in, out, errw = s.sessions.watchStreams(ctx, cancel, in, out, errw)
+
+	audit := &StreamingAuditEvent{
+		Kind:        "attach",
+		ContainerID: cntr.ID,
+		SandboxID:   cntr.SandboxID,
+		StartedAt:   time.Now(),
+	}
+	err = s.c.attachContainer(ctrdutil.WithNamespace(ctx), containerID, in, out, errw, tty, resize)
+	audit.FinishedAt = time.Now()
+	if err != nil {
+		audit.Error = err.Error()
+	}
+	s.publishAudit(audit)
+	return err
 }
 
 func (s *streamRuntime) PortForward(ctx context.Context, podSandboxID string, port int32, stream io.ReadWriteCloser) error {
@@ -0,0 +1,193 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+
+	ctrdutil "github.com/basuotian/containerd/internal/cri/util"
+)
+
+// streamingAuditTopic is the topic of StreamingAuditEvent events
+// published to the containerd event service.
+const streamingAuditTopic = "/cri/streaming-session"
+
+func init() {
+	typeurl.Register(&StreamingAuditEvent{},
+		"github.com/basuotian/containerd/internal/cri/server", "StreamingAuditEvent")
+}
+
+// StreamingAuditEvent records an exec or attach session for auditing.
+// One event is published per session once the session finishes.
+type StreamingAuditEvent struct {
+	// Kind is "exec" or "attach".
+	Kind string `json:"kind"`
+	// ContainerID of the session target.
+	ContainerID string `json:"containerID"`
+	// SandboxID of the pod the target container belongs to.
+	SandboxID string `json:"sandboxID"`
+	// Cmd is the command of an exec session.
+	Cmd []string `json:"cmd,omitempty"`
+	// StartedAt is when the session started streaming.
+	StartedAt time.Time `json:"startedAt"`
+	// FinishedAt is when the session finished.
+	FinishedAt time.Time `json:"finishedAt"`
+	// ExitCode of an exec session command, unset when the session
+	// failed before the command finished.
+	ExitCode *int32 `json:"exitCode,omitempty"`
+	// Error of the session, empty on success.
+	Error string `json:"error,omitempty"`
+}
+
+// sessionTracker enforces the configured concurrent session caps and
+// session idle timeout for exec and attach sessions.
+type sessionTracker struct {
+	maxSessions       int
+	maxSessionsPerPod int
+	idleTimeout       time.Duration
+
+	mu     sync.Mutex
+	total  int
+	perPod map[string]int
+}
+
+func newSessionTracker(maxSessions, maxSessionsPerPod int, idleTimeout time.Duration) *sessionTracker {
+	return &sessionTracker{
+		maxSessions:       maxSessions,
+		maxSessionsPerPod: maxSessionsPerPod,
+		idleTimeout:       idleTimeout,
+		perPod:            make(map[string]int),
+	}
+}
+
+// acquire reserves a session slot for the pod and returns a release
+// function, or an error when a configured cap is reached.
+func (t *sessionTracker) acquire(podID string) (func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.maxSessions > 0 && t.total >= t.maxSessions {
+		return nil, fmt.Errorf("too many streaming sessions (max %d): %w", t.maxSessions, errdefs.ErrUnavailable)
+	}
+	if t.maxSessionsPerPod > 0 && t.perPod[podID] >= t.maxSessionsPerPod {
+		return nil, fmt.Errorf("too many streaming sessions for sandbox %q (max %d): %w", podID, t.maxSessionsPerPod, errdefs.ErrUnavailable)
+	}
+	t.total++
+	t.perPod[podID]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			t.total--
+			if t.perPod[podID] <= 1 {
+				delete(t.perPod, podID)
+			} else {
+				t.perPod[podID]--
+			}
+			t.mu.Unlock()
+		})
+	}, nil
+}
+
+// watchStreams wraps the session streams to record activity and cancels
+// the session, closing its output streams, once no activity is observed
+// for the configured idle timeout.
+func (t *sessionTracker) watchStreams(ctx context.Context, cancel context.CancelFunc, stdin io.Reader, stdout, stderr io.WriteCloser) (io.Reader, io.WriteCloser, io.WriteCloser) {
+	if t.idleTimeout <= 0 {
+		return stdin, stdout, stderr
+	}
+	var last atomic.Int64
+	last.Store(time.Now().UnixNano())
+	touch := func() {
+		last.Store(time.Now().UnixNano())
+	}
+	go func() {
+		timer := time.NewTimer(t.idleTimeout)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				idle := time.Since(time.Unix(0, last.Load()))
+				if idle >= t.idleTimeout {
+					log.G(ctx).Warnf("Terminating streaming session idle for %v", idle)
+					cancel()
+					if stdout != nil {
+						stdout.Close()
+					}
+					if stderr != nil {
+						stderr.Close()
+					}
+					return
+				}
+				timer.Reset(t.idleTimeout - idle)
+			}
+		}
+	}()
+	if stdin != nil {
+		stdin = &activityReader{r: stdin, touch: touch}
+	}
+	if stdout != nil {
+		stdout = &activityWriter{w: stdout, touch: touch}
+	}
+	if stderr != nil {
+		stderr = &activityWriter{w: stderr, touch: touch}
+	}
+	return stdin, stdout, stderr
+}
+
+// publishAudit publishes the audit event for a finished session.
+func (s *streamRuntime) publishAudit(audit *StreamingAuditEvent) {
+	ctx, cancel := context.WithTimeout(ctrdutil.NamespacedContext(), time.Minute)
+	defer cancel()
+	if err := s.c.client.EventService().Publish(ctx, streamingAuditTopic, audit); err != nil {
+		log.G(ctx).WithError(err).WithField("containerID", audit.ContainerID).Warn("Failed to publish streaming audit event")
+	}
+}
+
+type activityReader struct {
+	r     io.Reader
+	touch func()
+}
+
+func (a *activityReader) Read(p []byte) (int, error) {
+	a.touch()
+	return a.r.Read(p)
+}
+
+type activityWriter struct {
+	w     io.WriteCloser
+	touch func()
+}
+
+func (a *activityWriter) Write(p []byte) (int, error) {
+	a.touch()
+	return a.w.Write(p)
+}
+
+func (a *activityWriter) Close() error {
+	return a.w.Close()
+}
@@ -97,6 +97,14 @@ type Config struct {
 	// How long to wait for clients to create streams. Only used for SPDY streaming.
 	StreamCreationTimeout time.Duration
 
+	// The maximum number of concurrent exec/attach sessions on the node. Zero means no limit.
+	MaxSessions int
+	// The maximum number of concurrent exec/attach sessions per pod sandbox. Zero means no limit.
+	MaxSessionsPerPod int
+	// How long an exec/attach session may be idle before it is terminated. Zero disables
+	// idle session termination.
+	SessionIdleTimeout time.Duration
+
 	// The streaming protocols the server supports (understands and permits).  See
 	// k8s.io/kubernetes/pkg/kubelet/server/remotecommand/constants.go for available protocols.
 	// Only used for SPDY streaming.
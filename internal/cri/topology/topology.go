@@ -0,0 +1,118 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package topology discovers the cpu and NUMA memory node topology of the
+// node, used to validate cpuset pinning hints before they are written into
+// an OCI spec.
+package topology
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/errdefs"
+)
+
+const (
+	onlineCPUsPath = "/sys/devices/system/cpu/online"
+	onlineMemsPath = "/sys/devices/system/node/online"
+)
+
+// NodeTopology holds the cpus and NUMA memory nodes online on this node.
+type NodeTopology struct {
+	// CPUs is the set of online cpu ids
+	CPUs map[int]struct{}
+	// Mems is the set of online NUMA memory node ids
+	Mems map[int]struct{}
+}
+
+// Discover reads the online cpu and NUMA node sets from sysfs.
+func Discover() (*NodeTopology, error) {
+	return discover(onlineCPUsPath, onlineMemsPath)
+}
+
+func discover(cpusPath, memsPath string) (*NodeTopology, error) {
+	cpus, err := parseListFile(cpusPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover online cpus: %w", err)
+	}
+	mems, err := parseListFile(memsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover online NUMA nodes: %w", err)
+	}
+	return &NodeTopology{CPUs: cpus, Mems: mems}, nil
+}
+
+func parseListFile(path string) (map[int]struct{}, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseList(strings.TrimSpace(string(b)))
+}
+
+// ParseList parses a cpuset list format string such as "0-3,7" into the set
+// of ids it describes.
+func ParseList(list string) (map[int]struct{}, error) {
+	set := make(map[int]struct{})
+	if list == "" {
+		return set, nil
+	}
+	for _, r := range strings.Split(list, ",") {
+		lo, hi, ok := strings.Cut(r, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(lo))
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid cpuset list %q: %w", list, errdefs.ErrInvalidArgument)
+		}
+		last := first
+		if ok {
+			if last, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil || last < first {
+				return nil, fmt.Errorf("invalid cpuset list %q: %w", list, errdefs.ErrInvalidArgument)
+			}
+		}
+		for id := first; id <= last; id++ {
+			set[id] = struct{}{}
+		}
+	}
+	return set, nil
+}
+
+// ValidateCPUs returns an error when the cpuset list names a cpu that is
+// not online on this node.
+func (t *NodeTopology) ValidateCPUs(list string) error {
+	return validate(list, t.CPUs, "cpu")
+}
+
+// ValidateMems returns an error when the cpuset list names a NUMA memory
+// node that is not online on this node.
+func (t *NodeTopology) ValidateMems(list string) error {
+	return validate(list, t.Mems, "NUMA node")
+}
+
+func validate(list string, online map[int]struct{}, kind string) error {
+	set, err := ParseList(list)
+	if err != nil {
+		return err
+	}
+	for id := range set {
+		if _, ok := online[id]; !ok {
+			return fmt.Errorf("%s %d in %q is not online on this node: %w", kind, id, list, errdefs.ErrInvalidArgument)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package topology
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/errdefs"
+)
+
+func TestParseList(t *testing.T) {
+	for _, tc := range []struct {
+		list    string
+		want    []int
+		wantErr bool
+	}{
+		{list: "", want: nil},
+		{list: "0", want: []int{0}},
+		{list: "0-3", want: []int{0, 1, 2, 3}},
+		{list: "0-1,4,6-7", want: []int{0, 1, 4, 6, 7}},
+		{list: "3-1", wantErr: true},
+		{list: "-1", wantErr: true},
+		{list: "a", wantErr: true},
+		{list: "0-", wantErr: true},
+	} {
+		set, err := ParseList(tc.list)
+		if tc.wantErr {
+			if !errdefs.IsInvalidArgument(err) {
+				t.Errorf("ParseList(%q): expected invalid argument, got %v", tc.list, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseList(%q): %v", tc.list, err)
+			continue
+		}
+		if len(set) != len(tc.want) {
+			t.Errorf("ParseList(%q) = %v, expected %v", tc.list, set, tc.want)
+			continue
+		}
+		for _, id := range tc.want {
+			if _, ok := set[id]; !ok {
+				t.Errorf("ParseList(%q) is missing %d", tc.list, id)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	topo := &NodeTopology{
+		CPUs: map[int]struct{}{0: {}, 1: {}, 2: {}, 3: {}},
+		Mems: map[int]struct{}{0: {}},
+	}
+
+	if err := topo.ValidateCPUs("0-3"); err != nil {
+		t.Errorf("ValidateCPUs(0-3): %v", err)
+	}
+	if err := topo.ValidateCPUs("2-4"); !errdefs.IsInvalidArgument(err) {
+		t.Errorf("ValidateCPUs(2-4): expected invalid argument, got %v", err)
+	}
+	if err := topo.ValidateMems("0"); err != nil {
+		t.Errorf("ValidateMems(0): %v", err)
+	}
+	if err := topo.ValidateMems("1"); !errdefs.IsInvalidArgument(err) {
+		t.Errorf("ValidateMems(1): expected invalid argument, got %v", err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	cpusPath := filepath.Join(dir, "cpu_online")
+	memsPath := filepath.Join(dir, "node_online")
+	if err := os.WriteFile(cpusPath, []byte("0-7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(memsPath, []byte("0-1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	topo, err := discover(cpusPath, memsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topo.CPUs) != 8 || len(topo.Mems) != 2 {
+		t.Errorf("unexpected topology %+v", topo)
+	}
+
+	if _, err := discover(filepath.Join(dir, "missing"), memsPath); err == nil {
+		t.Error("expected error for missing sysfs file")
+	}
+}
@@ -0,0 +1,168 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// parallelChunkSize is the amount of input compressed into one gzip member
+// by the parallel gzip writer. The chunking depends only on byte offsets, so
+// the produced stream is deterministic for a given input.
+const parallelChunkSize = 1 << 20
+
+// CompressStreamParallel is like CompressStream but spreads the compression
+// work over up to concurrency goroutines. The output is deterministic for a
+// given input. For gzip the stream is assembled in order from independently
+// compressed members, which any RFC 1952 decompressor (including
+// DecompressStream) reads back transparently; zstd uses the encoder's own
+// concurrent block compression.
+func CompressStreamParallel(dest io.Writer, compression Compression, concurrency int) (io.WriteCloser, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	switch compression {
+	case Uncompressed:
+		return &writeCloserWrapper{dest, nil}, nil
+	case Gzip:
+		if concurrency == 1 {
+			return gzip.NewWriter(dest), nil
+		}
+		return newParallelGzipWriter(dest, concurrency), nil
+	case Zstd:
+		return zstd.NewWriter(dest, zstd.WithEncoderConcurrency(concurrency))
+	default:
+		return nil, fmt.Errorf("unsupported compression format %s", (&compression).Extension())
+	}
+}
+
+// parallelGzipWriter compresses fixed size chunks of its input concurrently
+// and writes the resulting gzip members to dest in input order.
+type parallelGzipWriter struct {
+	dest  io.Writer
+	queue chan *gzipChunk
+	sem   chan struct{}
+	buf   []byte
+	n     int
+	wrote bool
+	done  chan struct{}
+	// err is only written by the assembler goroutine and only read after
+	// done is closed
+	err    error
+	closed bool
+}
+
+type gzipChunk struct {
+	res chan gzipResult
+}
+
+type gzipResult struct {
+	data []byte
+	err  error
+}
+
+func newParallelGzipWriter(dest io.Writer, concurrency int) *parallelGzipWriter {
+	w := &parallelGzipWriter{
+		dest:  dest,
+		queue: make(chan *gzipChunk, concurrency),
+		sem:   make(chan struct{}, concurrency),
+		buf:   make([]byte, parallelChunkSize),
+		done:  make(chan struct{}),
+	}
+	go w.assemble()
+	return w
+}
+
+// assemble drains the chunk queue in submission order, keeping the output
+// stream ordered regardless of which compressor finishes first.
+func (w *parallelGzipWriter) assemble() {
+	defer close(w.done)
+	for c := range w.queue {
+		r := <-c.res
+		if w.err != nil {
+			continue
+		}
+		if r.err != nil {
+			w.err = r.err
+			continue
+		}
+		if _, err := w.dest.Write(r.data); err != nil {
+			w.err = err
+		}
+	}
+}
+
+func (w *parallelGzipWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("parallel gzip: write after close")
+	}
+	total := 0
+	for len(p) > 0 {
+		n := copy(w.buf[w.n:], p)
+		w.n += n
+		p = p[n:]
+		total += n
+		if w.n == len(w.buf) {
+			w.flushChunk()
+		}
+	}
+	return total, nil
+}
+
+// flushChunk hands the buffered input to a compressor goroutine. The chunk
+// is queued before compression starts, fixing its position in the output.
+func (w *parallelGzipWriter) flushChunk() {
+	in := w.buf[:w.n]
+	w.buf = make([]byte, parallelChunkSize)
+	w.n = 0
+	w.wrote = true
+
+	c := &gzipChunk{res: make(chan gzipResult, 1)}
+	w.queue <- c
+	w.sem <- struct{}{}
+	go func() {
+		defer func() { <-w.sem }()
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(in)
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+		c.res <- gzipResult{data: buf.Bytes(), err: err}
+	}()
+}
+
+func (w *parallelGzipWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	// flush the trailing partial chunk, or produce an empty member so the
+	// output is a well formed gzip stream even without input
+	if w.n > 0 || !w.wrote {
+		w.flushChunk()
+	}
+	close(w.queue)
+	<-w.done
+	return w.err
+}
@@ -0,0 +1,113 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func testCompressParallel(t testing.TB, orig []byte, compression Compression, concurrency int) []byte {
+	var b bytes.Buffer
+	compressor, err := CompressStreamParallel(&b, compression, concurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// write in odd sized pieces so chunk boundaries do not line up with
+	// the write sizes
+	for in := orig; len(in) > 0; {
+		n := 99991
+		if n > len(in) {
+			n = len(in)
+		}
+		if _, err := compressor.Write(in[:n]); err != nil {
+			t.Fatal(err)
+		}
+		in = in[n:]
+	}
+	if err := compressor.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestCompressParallelRoundTrip(t *testing.T) {
+	for _, compression := range []Compression{Gzip, Zstd} {
+		for _, size := range []int{0, 1024, parallelChunkSize, 4*parallelChunkSize + 123} {
+			t.Run(fmt.Sprintf("%s/size=%d", compression.Extension(), size), func(t *testing.T) {
+				orig := generateData(t, size)
+				compressed := testCompressParallel(t, orig, compression, 4)
+				decompressed, decompressor := testDecompress(t, compressed)
+				defer decompressor.Close()
+				if !bytes.Equal(orig, decompressed) {
+					t.Fatal("strange decompressed data")
+				}
+			})
+		}
+	}
+}
+
+func TestCompressParallelDeterministic(t *testing.T) {
+	orig := generateData(t, 4*parallelChunkSize+123)
+	first := testCompressParallel(t, orig, Gzip, 4)
+	for _, concurrency := range []int{2, 4, 8} {
+		if !bytes.Equal(first, testCompressParallel(t, orig, Gzip, concurrency)) {
+			t.Fatalf("output with concurrency %d differs", concurrency)
+		}
+	}
+}
+
+func TestCompressParallelEmptyStream(t *testing.T) {
+	var b bytes.Buffer
+	compressor, err := CompressStreamParallel(&b, Gzip, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := compressor.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// even without input the output must be a well formed gzip stream
+	decompressed, decompressor := testDecompress(t, b.Bytes())
+	defer decompressor.Close()
+	if len(decompressed) != 0 {
+		t.Fatalf("expected empty stream, got %d bytes", len(decompressed))
+	}
+}
+
+func BenchmarkCompressionParallel(b *testing.B) {
+	const size = 64 * 1024 * 1024
+	data := generateData(b, size)
+
+	for _, compression := range []Compression{Gzip, Zstd} {
+		b.Run(compression.Extension(), func(b *testing.B) {
+			b.Run("serial", func(b *testing.B) {
+				b.SetBytes(size)
+				for i := 0; i < b.N; i++ {
+					testCompress(b, data, compression)
+				}
+			})
+			b.Run(fmt.Sprintf("parallel=%d", runtime.GOMAXPROCS(0)), func(b *testing.B) {
+				b.SetBytes(size)
+				for i := 0; i < b.N; i++ {
+					testCompressParallel(b, data, compression, runtime.GOMAXPROCS(0))
+				}
+			})
+		})
+	}
+}
@@ -136,6 +136,29 @@ const (
 	userXattrPrefix = "user."
 )
 
+// overlayOpaqueXattrs are the xattrs overlayfs uses to mark a directory as
+// opaque, depending on whether the writer ran in a user namespace.
+var overlayOpaqueXattrs = []string{"trusted.overlay.opaque", "user.overlay.opaque"}
+
+// isOverlayWhiteout reports whether the header describes an overlay style
+// whiteout, which overlayfs represents as a 0:0 character device.
+func isOverlayWhiteout(hdr *tar.Header) bool {
+	return hdr.Typeflag == tar.TypeChar && hdr.Devmajor == 0 && hdr.Devminor == 0
+}
+
+// stripOverlayOpaqueXattrs removes the overlay opaque markers from the
+// header, reporting whether any were present.
+func stripOverlayOpaqueXattrs(hdr *tar.Header) bool {
+	var opaque bool
+	for _, key := range overlayOpaqueXattrs {
+		if hdr.PAXRecords[paxSchilyXattr+key] == "y" {
+			delete(hdr.PAXRecords, paxSchilyXattr+key)
+			opaque = true
+		}
+	}
+	return opaque
+}
+
 // Apply applies a tar stream of an OCI style diff tar.
 // See https://github.com/opencontainers/image-spec/blob/main/layer.md#applying-changesets
 func Apply(ctx context.Context, root string, r io.Reader, opts ...ApplyOpt) (int64, error) {
@@ -275,6 +298,20 @@ func applyNaive(ctx context.Context, root string, r io.Reader, options ApplyOpti
 			}
 		}
 
+		// Translate overlay style whiteout markers into the aufs style
+		// markers convertWhiteout understands.
+		var opaqueDir bool
+		if options.SourceOverlayWhiteouts {
+			if isOverlayWhiteout(hdr) {
+				hdr.Name = filepath.Join(filepath.Dir(hdr.Name), whiteoutPrefix+filepath.Base(hdr.Name))
+				path = filepath.Join(filepath.Dir(path), whiteoutPrefix+filepath.Base(path))
+			} else if hdr.Typeflag == tar.TypeDir {
+				// The opaque marker is handled below, once the directory
+				// itself has been created.
+				opaqueDir = stripOverlayOpaqueXattrs(hdr)
+			}
+		}
+
 		// Naive whiteout convert function which handles whiteout files by
 		// removing the target files.
 		if err := validateWhiteout(path); err != nil {
@@ -312,6 +349,12 @@ func applyNaive(ctx context.Context, root string, r io.Reader, options ApplyOpti
 			dirs = append(dirs, hdr)
 		}
 		unpackedPaths[path] = struct{}{}
+
+		if opaqueDir {
+			if _, err := convertWhiteout(hdr, filepath.Join(path, whiteoutOpaqueDir)); err != nil {
+				return 0, fmt.Errorf("failed to convert opaque directory %q: %w", hdr.Name, err)
+			}
+		}
 	}
 
 	for _, hdr := range dirs {
@@ -22,9 +22,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"golang.org/x/sys/unix"
+
 	"github.com/basuotian/containerd/core/mount"
 	"github.com/basuotian/containerd/pkg/testutil"
 	"github.com/basuotian/containerd/plugins/snapshots/overlay/overlayutils"
@@ -48,6 +51,39 @@ func TestOverlayApply(t *testing.T) {
 	})
 }
 
+func TestOverlayWhiteoutSourceConversion(t *testing.T) {
+	testutil.RequiresRoot(t)
+
+	// An overlay capable target keeps the markers in their native format,
+	// so applying an overlay sourced layer into an upper directory with
+	// OverlayConvertWhiteout round trips them.
+	td := t.TempDir()
+	base := fstest.Apply(
+		fstest.CreateDir("/etc", 0755),
+		fstest.CreateDir("/opt", 0755),
+	)
+	if err := base.Apply(td); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Apply(context.Background(), td, overlayWhiteoutTar(t),
+		WithSourceOverlayWhiteouts(), WithConvertWhiteout(OverlayConvertWhiteout)); err != nil {
+		t.Fatalf("failed to apply: %v", err)
+	}
+
+	var st unix.Stat_t
+	if err := unix.Lstat(filepath.Join(td, "etc", "hosts"), &st); err != nil {
+		t.Fatalf("failed to stat whiteout: %v", err)
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFCHR || st.Rdev != 0 {
+		t.Errorf("expected 0:0 character device whiteout, got mode %o rdev %d", st.Mode, st.Rdev)
+	}
+	opaque := make([]byte, 1)
+	if n, err := unix.Getxattr(filepath.Join(td, "opt"), "trusted.overlay.opaque", opaque); err != nil || n != 1 || opaque[0] != 'y' {
+		t.Errorf("expected trusted.overlay.opaque=y on opt, got %q, %v", opaque[:n], err)
+	}
+}
+
 func TestOverlayApplyNoParents(t *testing.T) {
 	testutil.RequiresRoot(t)
 
@@ -30,6 +30,11 @@ type ApplyOptions struct {
 	Parents         []string        // Parent directories to handle inherited attributes without CoW
 	NoSameOwner     bool            // NoSameOwner will not attempt to preserve the owner specified in the tar archive.
 
+	// SourceOverlayWhiteouts translates overlay style whiteout markers in the
+	// archive (0:0 character devices and overlay opaque xattrs) into the aufs
+	// style markers handled by ConvertWhiteout.
+	SourceOverlayWhiteouts bool
+
 	applyFunc func(context.Context, string, io.Reader, ApplyOptions) (int64, error)
 }
 
@@ -63,6 +68,20 @@ func WithConvertWhiteout(c ConvertWhiteout) ApplyOpt {
 	}
 }
 
+// WithSourceOverlayWhiteouts indicates the archive was produced from an
+// overlayfs upper directory and may mark deletions with 0:0 character
+// devices and overlay opaque xattrs rather than the aufs style markers of
+// an OCI layer. The markers are rewritten into their aufs equivalents and
+// routed through the configured whiteout handling, which by default removes
+// the target files. Without the conversion such archives silently misbehave
+// on filesystems lacking trusted xattr or device node support.
+func WithSourceOverlayWhiteouts() ApplyOpt {
+	return func(options *ApplyOptions) error {
+		options.SourceOverlayWhiteouts = true
+		return nil
+	}
+}
+
 // WithNoSameOwner is same as '--no-same-owner` in 'tar' command.
 // It'll skip attempt to preserve the owner specified in the tar archive.
 func WithNoSameOwner() ApplyOpt {
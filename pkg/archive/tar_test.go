@@ -875,6 +875,62 @@ func TestApplyTar(t *testing.T) {
 	}
 }
 
+func TestApplySourceOverlayWhiteouts(t *testing.T) {
+	td := t.TempDir()
+	base := fstest.Apply(
+		fstest.CreateDir("/etc", 0755),
+		fstest.CreateFile("/etc/hosts", []byte("localhost"), 0644),
+		fstest.CreateDir("/opt", 0755),
+		fstest.CreateFile("/opt/stale", []byte("stale"), 0644),
+	)
+	require.NoError(t, base.Apply(td))
+
+	// Explicitly deleted markers as produced by an overlayfs upper
+	// directory rather than the aufs style markers of an OCI layer.
+	_, err := Apply(context.Background(), td, overlayWhiteoutTar(t), WithSourceOverlayWhiteouts())
+	require.NoError(t, err)
+
+	if _, err := os.Lstat(filepath.Join(td, "etc", "hosts")); !os.IsNotExist(err) {
+		t.Errorf("expected overlay whiteout to delete etc/hosts, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(td, "opt", "stale")); !os.IsNotExist(err) {
+		t.Errorf("expected opaque opt to drop stale entries, got %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(td, "opt", "fresh"))
+	require.NoError(t, err)
+	require.Equal(t, "fresh", string(b))
+}
+
+// overlayWhiteoutTar returns a layer which deletes /etc/hosts with a 0:0
+// character device, makes /opt opaque with the overlay xattr and adds
+// /opt/fresh.
+func overlayWhiteoutTar(t *testing.T) io.Reader {
+	buf := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeChar,
+		Name:     "etc/hosts",
+		Mode:     0600,
+	}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Typeflag:   tar.TypeDir,
+		Name:       "opt/",
+		Mode:       0755,
+		PAXRecords: map[string]string{"SCHILY.xattr.trusted.overlay.opaque": "y"},
+	}))
+	content := []byte("fresh")
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     "opt/fresh",
+		Mode:     0644,
+		Size:     int64(len(content)),
+	}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	return buf
+}
+
 func testApply(t *testing.T, a fstest.Applier) error {
 	td := t.TempDir()
 	dest := t.TempDir()
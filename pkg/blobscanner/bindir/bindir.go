@@ -0,0 +1,193 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package bindir scans blobs with the executables of a directory. Every
+// binary is run with the blob streamed to its stdin and the descriptor
+// passed as flags; a non-zero exit rejects the blob with the binary's
+// stderr as the reason.
+package bindir
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/containerd/log"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/internal/tomlext"
+	"github.com/basuotian/containerd/pkg/blobscanner"
+)
+
+const outputLimitBytes = 1 << 15 // 32 KiB
+
+type Config struct {
+	BinDir         string           `toml:"bin_dir"`
+	MaxScanners    int              `toml:"max_scanners"`
+	PerBlobTimeout tomlext.Duration `toml:"per_blob_timeout"`
+}
+
+type BlobScanner struct {
+	config *Config
+}
+
+var _ blobscanner.BlobScanner = (*BlobScanner)(nil)
+
+func NewBlobScanner(c *Config) *BlobScanner {
+	return &BlobScanner{
+		config: c,
+	}
+}
+
+func (s *BlobScanner) ScanBlob(ctx context.Context, desc ocispec.Descriptor, r io.Reader) error {
+	// os.ReadDir sorts entries by name.
+	entries, err := os.ReadDir(s.config.BinDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to list directory contents: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if s.config.MaxScanners >= 0 && len(entries) > s.config.MaxScanners {
+		log.G(ctx).Warnf("blob scanners are being skipped since directory %v has %v entries, more than configured max of %v scanners", s.config.BinDir, len(entries), s.config.MaxScanners)
+		entries = entries[:s.config.MaxScanners]
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tomlext.ToStdTime(s.config.PerBlobTimeout))
+	defer cancel()
+
+	// All scanners consume the same single pass over the stream. Real
+	// pipes are used for stdin so a scanner exiting early surfaces as a
+	// write error instead of blocking the stream.
+	type scanProc struct {
+		bin    string
+		cmd    *exec.Cmd
+		stdin  *os.File
+		stderr *bytes.Buffer
+		broken bool
+	}
+	var procs []*scanProc
+	defer func() {
+		for _, p := range procs {
+			p.stdin.Close()
+			if p.cmd.Process != nil {
+				p.cmd.Wait()
+			}
+		}
+	}()
+	for _, entry := range entries {
+		bin := entry.Name()
+		stdinRead, stdinWrite, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		cmd := exec.CommandContext(ctx, filepath.Join(s.config.BinDir, bin),
+			"-digest", desc.Digest.String(),
+			"-media-type", desc.MediaType,
+			"-size", fmt.Sprintf("%d", desc.Size),
+		)
+		cmd.Stdin = stdinRead
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = &limitedWriter{w: stderr, n: outputLimitBytes}
+		if d, ok := ctx.Deadline(); ok {
+			stdinWrite.SetDeadline(d)
+		}
+		err = cmd.Start()
+		stdinRead.Close()
+		if err != nil {
+			stdinWrite.Close()
+			return fmt.Errorf("failed to start blob scanner %v: %w", bin, err)
+		}
+		procs = append(procs, &scanProc{bin: bin, cmd: cmd, stdin: stdinWrite, stderr: stderr})
+	}
+
+	// Stream the blob to every scanner that is still reading. A scanner
+	// closing its stdin early is fine, its verdict comes from its exit
+	// code.
+	buf := make([]byte, 32*1024)
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			for _, p := range procs {
+				if p.broken {
+					continue
+				}
+				if _, werr := p.stdin.Write(buf[:n]); werr != nil {
+					p.broken = true
+				}
+			}
+		}
+		if rerr != nil {
+			if rerr != io.EOF {
+				return fmt.Errorf("failed to read blob %v: %w", desc.Digest, rerr)
+			}
+			break
+		}
+	}
+
+	var rejections []error
+	for _, p := range procs {
+		p.stdin.Close()
+		err := p.cmd.Wait()
+		if err == nil {
+			continue
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return fmt.Errorf("failed to run blob scanner %v: %w", p.bin, err)
+		}
+		reason := strings.TrimSpace(p.stderr.String())
+		if reason == "" {
+			reason = fmt.Sprintf("exit code %d", exitErr.ExitCode())
+		}
+		rejections = append(rejections, &blobscanner.RejectedError{
+			Digest:  desc.Digest,
+			Scanner: p.bin,
+			Reason:  reason,
+		})
+	}
+	return errors.Join(rejections...)
+}
+
+// limitedWriter keeps the first n bytes written and discards the rest, so
+// a noisy scanner cannot grow the rejection reason without bound.
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if l.n > 0 {
+		if len(p) > l.n {
+			p = p[:l.n]
+		}
+		if _, err := l.w.Write(p); err != nil {
+			return 0, err
+		}
+		l.n -= len(p)
+	}
+	return n, nil
+}
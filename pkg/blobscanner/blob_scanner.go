@@ -0,0 +1,59 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package blobscanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// BlobScanner inspects blobs fetched by the transfer service before they
+// are committed to the content store, for example to scan layers for
+// malware.
+type BlobScanner interface {
+	// ScanBlob reads the blob from r as it is being downloaded. Returning
+	// a *RejectedError rejects the blob and fails the transfer with that
+	// rejection; any other error fails the transfer as a scanner failure;
+	// nil lets the blob be committed.
+	ScanBlob(ctx context.Context, desc ocispec.Descriptor, r io.Reader) error
+}
+
+// RejectedError is the typed error a scanner returns when it finds a blob
+// unacceptable.
+type RejectedError struct {
+	// Digest of the rejected blob.
+	Digest digest.Digest
+	// Scanner that rejected the blob.
+	Scanner string
+	// Reason the scanner gave for the rejection.
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("blob %s rejected by scanner %s: %s", e.Digest, e.Scanner, e.Reason)
+}
+
+// IsRejected reports whether err is or wraps a blob rejection.
+func IsRejected(err error) bool {
+	var re *RejectedError
+	return errors.As(err, &re)
+}
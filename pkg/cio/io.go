@@ -26,6 +26,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/basuotian/containerd/core/streaming"
 	"github.com/basuotian/containerd/defaults"
 )
 
@@ -99,6 +100,10 @@ type Streams struct {
 	Stderr   io.Writer
 	Terminal bool
 	FIFODir  string
+	// StreamCreator, when set, carries the IO over streams of the
+	// streaming service of the daemon instead of fifos, for clients
+	// which do not share a filesystem with the daemon.
+	StreamCreator streaming.StreamCreator
 }
 
 // Opt customize options for creating a Creator or Attach
@@ -131,6 +136,15 @@ func WithFIFODir(dir string) Opt {
 	}
 }
 
+// WithStreamCreator carries the IO over streams of the streaming service
+// of the daemon instead of fifos, for clients which do not share a
+// filesystem with the daemon.
+func WithStreamCreator(creator streaming.StreamCreator) Opt {
+	return func(opt *Streams) {
+		opt.StreamCreator = creator
+	}
+}
+
 // NewCreator returns an IO creator from the options
 func NewCreator(opts ...Opt) Creator {
 	streams := &Streams{}
@@ -140,6 +154,9 @@ func NewCreator(opts ...Opt) Creator {
 	if streams.FIFODir == "" {
 		streams.FIFODir = defaults.DefaultFIFODir
 	}
+	if streams.StreamCreator != nil {
+		return streamingIOCreator(streams)
+	}
 	return func(id string) (IO, error) {
 		fifos, err := NewFIFOSetInDir(streams.FIFODir, id, streams.Terminal)
 		if err != nil {
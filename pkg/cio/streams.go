@@ -0,0 +1,100 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	transferstreaming "github.com/basuotian/containerd/core/transfer/streaming"
+)
+
+// StreamingURIScheme is used in the IO configuration to reference streams
+// of the streaming service of the daemon instead of fifo paths. The
+// daemon bridges such streams to local fifos before handing the IO to
+// the shim.
+const StreamingURIScheme = "streaming"
+
+// streamingIOCreator multiplexes the task IO over streams of the
+// streaming service, so clients on a different host than the daemon,
+// where fifo paths are meaningless, still get interactive IO.
+func streamingIOCreator(streams *Streams) Creator {
+	return func(id string) (_ IO, retErr error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &cio{
+			config: Config{Terminal: streams.Terminal},
+			wg:     &sync.WaitGroup{},
+			cancel: cancel,
+		}
+		defer func() {
+			if retErr != nil {
+				cancel()
+				s.Close()
+			}
+		}()
+
+		if streams.Stdin != nil {
+			name := id + "-stdin"
+			stream, err := streams.StreamCreator.Create(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create stdin stream: %w", err)
+			}
+			s.closers = append(s.closers, stream)
+			transferstreaming.SendStream(ctx, streams.Stdin, stream)
+			s.config.Stdin = StreamingURIScheme + "://" + name
+		}
+		if streams.Stdout != nil {
+			name := id + "-stdout"
+			stream, err := streams.StreamCreator.Create(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create stdout stream: %w", err)
+			}
+			s.closers = append(s.closers, stream)
+			r := transferstreaming.ReceiveStream(ctx, stream)
+			s.wg.Add(1)
+			go func() {
+				p := bufPool.Get().(*[]byte)
+				defer bufPool.Put(p)
+
+				io.CopyBuffer(streams.Stdout, r, *p)
+				s.wg.Done()
+			}()
+			s.config.Stdout = StreamingURIScheme + "://" + name
+		}
+		if !streams.Terminal && streams.Stderr != nil {
+			name := id + "-stderr"
+			stream, err := streams.StreamCreator.Create(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create stderr stream: %w", err)
+			}
+			s.closers = append(s.closers, stream)
+			r := transferstreaming.ReceiveStream(ctx, stream)
+			s.wg.Add(1)
+			go func() {
+				p := bufPool.Get().(*[]byte)
+				defer bufPool.Put(p)
+
+				io.CopyBuffer(streams.Stderr, r, *p)
+				s.wg.Done()
+			}()
+			s.config.Stderr = StreamingURIScheme + "://" + name
+		}
+		return s, nil
+	}
+}
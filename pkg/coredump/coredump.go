@@ -0,0 +1,96 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package coredump holds the event and index record types for core dumps
+// captured by a shim when a container process crashes with a core
+// producing signal.
+package coredump
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/containerd/typeurl/v2"
+)
+
+func init() {
+	typeurl.Register(&TaskCoreDump{}, "containerd.event.TaskCoreDump")
+}
+
+// IndexName is the name of the index file kept next to captured core
+// dumps, holding one JSON encoded TaskCoreDump record per line.
+const IndexName = "cores.json"
+
+// TaskCoreDump supplements the TaskExit API event with the core dump
+// captured for the exit. It is published on a separate topic so that
+// subscribers which do not care about core dumps keep seeing the
+// unchanged TaskExit event.
+type TaskCoreDump struct {
+	ContainerID string    `json:"container_id"`
+	ID          string    `json:"id"`
+	Pid         uint32    `json:"pid"`
+	Signal      uint32    `json:"signal"`
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	Truncated   bool      `json:"truncated,omitempty"`
+	ExitedAt    time.Time `json:"exited_at"`
+}
+
+// Append records a captured core dump in the index file in dir.
+func Append(dir string, e *TaskCoreDump) error {
+	f, err := os.OpenFile(filepath.Join(dir, IndexName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	p, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(p, '\n'))
+	return err
+}
+
+// List returns the core dumps recorded in the index file in dir. A
+// missing index is treated as no dumps having been captured.
+func List(dir string) ([]TaskCoreDump, error) {
+	f, err := os.Open(filepath.Join(dir, IndexName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var dumps []TaskCoreDump
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var e TaskCoreDump
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, err
+		}
+		dumps = append(dumps, e)
+	}
+	return dumps, scanner.Err()
+}
@@ -0,0 +1,58 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package criu holds checkpoint types shared between the containerd client
+// and the runc shim which are not yet expressible through the stable runtime
+// options protobuf API, such as CRIU incremental (pre-dump) checkpoints.
+package criu
+
+import typeurl "github.com/containerd/typeurl/v2"
+
+func init() {
+	typeurl.Register(&CheckpointOptions{}, "containerd.runc.v1.CriuCheckpointOptions")
+}
+
+// CheckpointOptions mirrors the runc runtime CheckpointOptions message with
+// additional fields supporting CRIU incremental checkpoint chains. It is
+// registered with typeurl and transmitted as a JSON-encoded Any so that it
+// can be used without revving the runtime options protobuf API.
+type CheckpointOptions struct {
+	// Exit stops the container after the checkpoint
+	Exit bool `json:"exit,omitempty"`
+	// OpenTCP allows open tcp connections to be checkpointed
+	OpenTCP bool `json:"open_tcp,omitempty"`
+	// ExternalUnixSockets allows external unix sockets to be checkpointed
+	ExternalUnixSockets bool `json:"external_unix_sockets,omitempty"`
+	// Terminal allows terminals (ptys) to be checkpointed
+	Terminal bool `json:"terminal,omitempty"`
+	// FileLocks allows file locks to be checkpointed
+	FileLocks bool `json:"file_locks,omitempty"`
+	// EmptyNamespaces restores the provided namespaces as empty namespaces
+	EmptyNamespaces []string `json:"empty_namespaces,omitempty"`
+	// CgroupsMode sets the cgroups mode, soft, full, strict
+	CgroupsMode string `json:"cgroups_mode,omitempty"`
+	// ImagePath is the path to write criu image files to
+	ImagePath string `json:"image_path,omitempty"`
+	// WorkPath is the path for criu work files and logs
+	WorkPath string `json:"work_path,omitempty"`
+	// PreDump makes the checkpoint an incremental pre-dump: only memory
+	// pages are dumped and the task keeps running afterwards. A pre-dump
+	// requires ImagePath to be set.
+	PreDump bool `json:"pre_dump,omitempty"`
+	// ParentPath points at the image directory of the previous dump in an
+	// incremental checkpoint chain, relative to ImagePath.
+	ParentPath string `json:"parent_path,omitempty"`
+}
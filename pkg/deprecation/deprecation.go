@@ -33,6 +33,9 @@ const (
 	TracingOTLPConfig Warning = Prefix + "tracing-processor-config"
 	// TracingServiceConfig is a warning for the use of the `tracing` property
 	TracingServiceConfig Warning = Prefix + "tracing-service-config"
+	// PullSchema1Image is a warning for pulling a legacy schema 1 image
+	// through the conversion fallback
+	PullSchema1Image Warning = Prefix + "pull-schema-1-image"
 )
 
 const (
@@ -53,6 +56,8 @@ var messages = map[Warning]string{
 		"Use OTLP environment variables instead: https://opentelemetry.io/docs/specs/otel/protocol/exporter/",
 	TracingServiceConfig: "The `tracing` property of `[plugins.\"io.containerd.internal.v1\".tracing]` is deprecated since containerd v1.6 and will be removed in containerd v2.2." +
 		"Use OTEL environment variables instead: https://opentelemetry.io/docs/specs/otel/configuration/sdk-environment-variables/",
+	PullSchema1Image: "Docker schema 1 images are deprecated since containerd v1.7, the image was converted to an OCI manifest on pull. " +
+		"Rebuild the image as a schema 2 or OCI image.",
 }
 
 // Valid checks whether a given Warning is valid
@@ -0,0 +1,31 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package fsquota manages filesystem project quotas on directories. A
+// project quota enforces a hard limit on the space a directory tree may use
+// and makes its usage cheap to query. Project quotas are only available on
+// Linux with ext4 or xfs filesystems mounted with project quota accounting
+// enabled; on anything else the calls fail with ErrNotImplemented.
+package fsquota
+
+// Usage is the space a directory tree with a project quota uses.
+type Usage struct {
+	// Bytes is the number of bytes used.
+	Bytes uint64
+
+	// Inodes is the number of inodes used.
+	Inodes uint64
+}
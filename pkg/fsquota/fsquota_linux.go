@@ -0,0 +1,255 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package fsquota
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unsafe"
+
+	"github.com/containerd/errdefs"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ioctls to read and write the project ID of a directory
+	fsIOCFSGetXattr = 0x801c581f
+	fsIOCFSSetXattr = 0x401c5820
+
+	// fsXFlagProjInherit makes new files below a directory inherit its
+	// project ID
+	fsXFlagProjInherit = 0x00000200
+
+	// prjQuotaType selects project quotas in a quotactl command
+	prjQuotaType = 2
+
+	// generic quotactl subcommands
+	qGetQuota = 0x800007
+	qSetQuota = 0x800008
+
+	// qifBLimits marks the block limit fields of a dqblk as valid
+	qifBLimits = 1
+
+	// quotaBlockSize is the unit of the dqblk block limit fields
+	quotaBlockSize = 1024
+
+	// projectIDBase is the first project ID used for quotas, leaving the
+	// lower IDs to the administrator
+	projectIDBase = 1 << 20
+
+	// projectIDRange is how many project IDs are probed for a free one
+	projectIDRange = 1 << 20
+)
+
+// fsxattr mirrors struct fsxattr from linux/fs.h.
+type fsxattr struct {
+	xflags     uint32
+	extsize    uint32
+	nextents   uint32
+	projid     uint32
+	cowextsize uint32
+	pad        [8]byte
+}
+
+// dqblk mirrors struct if_dqblk from linux/quota.h. Block limits are in
+// units of quotaBlockSize, current space is in bytes.
+type dqblk struct {
+	bHardlimit uint64
+	bSoftlimit uint64
+	curSpace   uint64
+	iHardlimit uint64
+	iSoftlimit uint64
+	curInodes  uint64
+	bTime      uint64
+	iTime      uint64
+	valid      uint32
+	_          uint32
+}
+
+// SetProjectQuota puts dir under a project quota with the given hard limit
+// in bytes. A project ID is assigned to the directory on first use and
+// inherited by everything created below it; calling again adjusts the limit.
+func SetProjectQuota(dir string, limitBytes uint64) error {
+	dev, err := backingDevice(dir)
+	if err != nil {
+		return err
+	}
+	id, err := getProjectID(dir)
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		if id, err = findFreeProjectID(dev); err != nil {
+			return err
+		}
+		if err := setProjectID(dir, id); err != nil {
+			return err
+		}
+	}
+
+	blocks := (limitBytes + quotaBlockSize - 1) / quotaBlockSize
+	d := dqblk{
+		bHardlimit: blocks,
+		bSoftlimit: blocks,
+		valid:      qifBLimits,
+	}
+	if err := quotactl(qSetQuota, dev, id, &d); err != nil {
+		return fmt.Errorf("failed to set project quota on %s: %w", dir, err)
+	}
+	return nil
+}
+
+// GetUsage returns the usage of the project quota dir is under.
+func GetUsage(dir string) (*Usage, error) {
+	id, err := getProjectID(dir)
+	if err != nil {
+		return nil, err
+	}
+	if id == 0 {
+		return nil, fmt.Errorf("no project quota on %s: %w", dir, errdefs.ErrNotFound)
+	}
+	dev, err := backingDevice(dir)
+	if err != nil {
+		return nil, err
+	}
+	var d dqblk
+	if err := quotactl(qGetQuota, dev, id, &d); err != nil {
+		return nil, fmt.Errorf("failed to query project quota on %s: %w", dir, err)
+	}
+	return &Usage{
+		Bytes:  d.curSpace,
+		Inodes: d.curInodes,
+	}, nil
+}
+
+func quotactl(subcmd int, dev string, id uint32, d *dqblk) error {
+	devp, err := unix.BytePtrFromString(dev)
+	if err != nil {
+		return err
+	}
+	cmd := subcmd<<8 | prjQuotaType
+	if _, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, uintptr(cmd), uintptr(unsafe.Pointer(devp)),
+		uintptr(id), uintptr(unsafe.Pointer(d)), 0, 0); errno != 0 {
+		return wrapUnsupported(errno)
+	}
+	return nil
+}
+
+func getProjectID(dir string) (uint32, error) {
+	attr, f, err := getFsxattr(dir)
+	if err != nil {
+		return 0, err
+	}
+	f.Close()
+	return attr.projid, nil
+}
+
+func setProjectID(dir string, id uint32) error {
+	attr, f, err := getFsxattr(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	attr.projid = id
+	attr.xflags |= fsXFlagProjInherit
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), fsIOCFSSetXattr, uintptr(unsafe.Pointer(attr))); errno != 0 {
+		return fmt.Errorf("failed to set project ID on %s: %w", dir, wrapUnsupported(errno))
+	}
+	return nil
+}
+
+func getFsxattr(dir string) (*fsxattr, *os.File, error) {
+	f, err := os.OpenFile(dir, os.O_RDONLY|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	var attr fsxattr
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), fsIOCFSGetXattr, uintptr(unsafe.Pointer(&attr))); errno != 0 {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to get project ID of %s: %w", dir, wrapUnsupported(errno))
+	}
+	return &attr, f, nil
+}
+
+// findFreeProjectID probes the quota state of dev for a project ID without
+// usage or limits, starting above the administrator range.
+func findFreeProjectID(dev string) (uint32, error) {
+	for id := uint32(projectIDBase); id < projectIDBase+projectIDRange; id++ {
+		var d dqblk
+		if err := quotactl(qGetQuota, dev, id, &d); err != nil {
+			return 0, err
+		}
+		if d == (dqblk{}) {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("no free project ID on %s", dev)
+}
+
+// backingDevice returns the source device of the mount dir lives on.
+func backingDevice(dir string) (string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var (
+		device string
+		best   int
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// format: id parent major:minor root mountpoint options ... - fstype source superopts
+		pre, post, ok := strings.Cut(scanner.Text(), " - ")
+		if !ok {
+			continue
+		}
+		preFields := strings.Fields(pre)
+		postFields := strings.Fields(post)
+		if len(preFields) < 5 || len(postFields) < 2 {
+			continue
+		}
+		mountPoint := preFields[4]
+		if mountPoint != "/" && !strings.HasPrefix(dir+"/", mountPoint+"/") {
+			continue
+		}
+		if len(mountPoint) >= best {
+			best = len(mountPoint)
+			device = postFields[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if device == "" {
+		return "", fmt.Errorf("no mount found for %s", dir)
+	}
+	return device, nil
+}
+
+// wrapUnsupported maps errnos indicating a filesystem without (enabled)
+// project quota support to ErrNotImplemented so callers can fall back.
+func wrapUnsupported(errno unix.Errno) error {
+	switch errno {
+	case unix.ENOTTY, unix.EOPNOTSUPP, unix.ENOSYS, unix.ESRCH, unix.EINVAL:
+		return fmt.Errorf("project quotas not supported: %w (%w)", errno, errdefs.ErrNotImplemented)
+	}
+	return errno
+}
@@ -0,0 +1,35 @@
+//go:build !linux
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package fsquota
+
+import (
+	"fmt"
+
+	"github.com/containerd/errdefs"
+)
+
+// SetProjectQuota is only supported on Linux.
+func SetProjectQuota(dir string, limitBytes uint64) error {
+	return fmt.Errorf("project quotas are only supported on Linux: %w", errdefs.ErrNotImplemented)
+}
+
+// GetUsage is only supported on Linux.
+func GetUsage(dir string) (*Usage, error) {
+	return nil, fmt.Errorf("project quotas are only supported on Linux: %w", errdefs.ErrNotImplemented)
+}
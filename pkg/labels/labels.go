@@ -24,6 +24,22 @@ const LabelUncompressed = "containerd.io/uncompressed"
 // contents to be shared.
 const LabelSharedNamespace = "containerd.io/namespace.shareable"
 
+// LabelSharingPolicy is added to a namespace to override the store wide
+// content sharing policy for that namespace. Supported values are
+// "shared", "isolated" and "shared-pull-only".
+const LabelSharingPolicy = "containerd.io/namespace.sharing-policy"
+
 // LabelDistributionSource is added to content to indicate its origin.
 // e.g., "containerd.io/distribution.source.docker.io=library/redis"
 const LabelDistributionSource = "containerd.io/distribution.source"
+
+// LabelBlobAccessedAt is surfaced on content info of blobs tracked by the
+// metadata content store. The value is the RFC 3339 timestamp of the last
+// read of the blob. The label is maintained by the store and cannot be set
+// through updates.
+const LabelBlobAccessedAt = "containerd.io/blob.accessed-at"
+
+// LabelBlobAccessCount is surfaced on content info of blobs tracked by the
+// metadata content store. The value is the number of reads of the blob.
+// The label is maintained by the store and cannot be set through updates.
+const LabelBlobAccessCount = "containerd.io/blob.access-count"
@@ -50,9 +50,9 @@ import (
 
 // newNS creates a new persistent (bind-mounted) network namespace and returns the
 // path to the network namespace.
-// If pid is not 0, returns the netns from that pid persistently mounted. Otherwise,
-// a new netns is created.
-func newNS(baseDir string, pid uint32) (nsPath string, err error) {
+// If src is not empty, returns the netns it references persistently mounted.
+// Otherwise, a new netns is created.
+func newNS(baseDir string, src string) (nsPath string, err error) {
 	b := make([]byte, 16)
 
 	_, err = rand.Read(b)
@@ -83,12 +83,11 @@ func newNS(baseDir string, pid uint32) (nsPath string, err error) {
 		}
 	}()
 
-	if pid != 0 {
-		procNsPath := getNetNSPathFromPID(pid)
+	if src != "" {
 		// bind mount the netns onto the mount point. This causes the namespace
 		// to persist, even when there are no threads in the ns.
-		if err = unix.Mount(procNsPath, nsPath, "none", unix.MS_BIND, ""); err != nil {
-			return "", fmt.Errorf("failed to bind mount ns src: %v at %s: %w", procNsPath, nsPath, err)
+		if err = unix.Mount(src, nsPath, "none", unix.MS_BIND, ""); err != nil {
+			return "", fmt.Errorf("failed to bind mount ns src: %v at %s: %w", src, nsPath, err)
 		}
 		return nsPath, nil
 	}
@@ -189,7 +188,37 @@ func NewNetNS(baseDir string) (*NetNS, error) {
 // The returned netns is created under baseDir, with its path
 // following the pattern "baseDir/<generated-name>".
 func NewNetNSFromPID(baseDir string, pid uint32) (*NetNS, error) {
-	path, err := newNS(baseDir, pid)
+	var src string
+	if pid != 0 {
+		src = getNetNSPathFromPID(pid)
+	}
+	path, err := newNS(baseDir, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup netns: %w", err)
+	}
+	return &NetNS{path: path}, nil
+}
+
+// NewNetNSFromPath bind mounts the existing network namespace at nsPath
+// under baseDir and returns it. The path must reference a mounted network
+// namespace. The returned netns owns only the new bind mount, so removing
+// it detaches the sandbox from the namespace while the original mount, and
+// the namespace itself, stay intact.
+func NewNetNSFromPath(baseDir string, nsPath string) (*NetNS, error) {
+	ns, err := cnins.GetNS(nsPath)
+	if err != nil {
+		switch err.(type) {
+		case cnins.NSPathNotExistErr:
+			return nil, fmt.Errorf("netns %q does not exist: %w", nsPath, err)
+		case cnins.NSPathNotNSErr:
+			return nil, fmt.Errorf("%q is not a network namespace: %w", nsPath, err)
+		default:
+			return nil, fmt.Errorf("failed to open netns %q: %w", nsPath, err)
+		}
+	}
+	ns.Close()
+
+	path, err := newNS(baseDir, nsPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to setup netns: %w", err)
 	}
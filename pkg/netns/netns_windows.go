@@ -45,6 +45,11 @@ func NewNetNSFromPID(baseDir string, pid uint32) (*NetNS, error) {
 	return nil, errNotImplementedOnWindows
 }
 
+// NewNetNSFromPath returns the existing netns referenced by nsPath.
+func NewNetNSFromPath(baseDir string, nsPath string) (*NetNS, error) {
+	return nil, errNotImplementedOnWindows
+}
+
 // LoadNetNS loads existing network namespace.
 func LoadNetNS(path string) *NetNS {
 	return &NetNS{path: path}
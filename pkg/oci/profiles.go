@@ -0,0 +1,108 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"fmt"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/basuotian/containerd/core/containers"
+)
+
+// Spec preset profile names, modeled after the Kubernetes Pod Security
+// Standards of the same names.
+const (
+	// ProfileRestricted is a heavily restricted preset following current
+	// pod hardening best practices.
+	ProfileRestricted = "restricted"
+	// ProfileBaseline is a minimally restrictive preset that prevents
+	// known privilege escalations while staying close to the defaults.
+	ProfileBaseline = "baseline"
+	// ProfilePrivileged is an unrestricted preset allowing known
+	// privilege escalations.
+	ProfilePrivileged = "privileged"
+)
+
+// WithProfile applies the spec preset with the given name, one of
+// ProfileRestricted, ProfileBaseline or ProfilePrivileged.
+func WithProfile(name string) SpecOpts {
+	switch name {
+	case ProfileRestricted:
+		return WithRestrictedProfile
+	case ProfileBaseline:
+		return WithBaselineProfile
+	case ProfilePrivileged:
+		return WithPrivileged
+	default:
+		return func(_ context.Context, _ Client, _ *containers.Container, _ *Spec) error {
+			return fmt.Errorf("unknown spec profile %q", name)
+		}
+	}
+}
+
+// WithRestrictedProfile applies the "restricted" preset: all capabilities
+// are dropped, privilege escalation is disabled and host namespaces are
+// replaced with private ones. Capabilities may be selectively re-added
+// afterwards with WithAddedCapabilities, e.g. CAP_NET_BIND_SERVICE. The
+// seccomp profile is left untouched; compose with
+// contrib/seccomp.WithDefaultProfile to match the RuntimeDefault
+// requirement of the restricted standard.
+func WithRestrictedProfile(ctx context.Context, client Client, c *containers.Container, s *Spec) error {
+	return Compose(
+		WithCapabilities(nil),
+		WithNoNewPrivileges,
+		withIsolatedNamespaces,
+	)(ctx, client, c, s)
+}
+
+// WithBaselineProfile applies the "baseline" preset: the default capability
+// set is restored and host namespaces are replaced with private ones, while
+// everything else is left at its defaults.
+func WithBaselineProfile(ctx context.Context, client Client, c *containers.Container, s *Spec) error {
+	return Compose(
+		WithCapabilities(defaultUnixCaps()),
+		withIsolatedNamespaces,
+	)(ctx, client, c, s)
+}
+
+// withIsolatedNamespaces makes sure the network, pid, ipc and uts namespaces
+// are private to the container, undoing any previously configured host
+// namespace sharing.
+func withIsolatedNamespaces(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+	setLinux(s)
+	for _, ns := range []specs.LinuxNamespaceType{
+		specs.NetworkNamespace,
+		specs.PIDNamespace,
+		specs.IPCNamespace,
+		specs.UTSNamespace,
+	} {
+		found := false
+		for i, n := range s.Linux.Namespaces {
+			if n.Type == ns {
+				s.Linux.Namespaces[i].Path = ""
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.Linux.Namespaces = append(s.Linux.Namespaces, specs.LinuxNamespace{Type: ns})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"testing"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/basuotian/containerd/core/containers"
+	"github.com/basuotian/containerd/pkg/namespaces"
+)
+
+func TestWithRestrictedProfile(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx = namespaces.WithNamespace(context.Background(), "test")
+		c   = containers.Container{ID: t.Name()}
+	)
+
+	spec, err := GenerateSpec(ctx, nil, &c,
+		WithHostNamespace(specs.NetworkNamespace),
+		WithProfile(ProfileRestricted),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spec.Process.Capabilities.Bounding) != 0 {
+		t.Errorf("expected all capabilities dropped, got %v", spec.Process.Capabilities.Bounding)
+	}
+	if !spec.Process.NoNewPrivileges {
+		t.Error("expected NoNewPrivileges to be set")
+	}
+	for _, ns := range spec.Linux.Namespaces {
+		if ns.Type == specs.NetworkNamespace && ns.Path != "" {
+			t.Errorf("expected private network namespace, got path %q", ns.Path)
+		}
+	}
+}
+
+func TestWithBaselineProfile(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx = namespaces.WithNamespace(context.Background(), "test")
+		c   = containers.Container{ID: t.Name()}
+	)
+
+	spec, err := GenerateSpec(ctx, nil, &c,
+		WithCapabilities([]string{"CAP_SYS_ADMIN"}),
+		WithProfile(ProfileBaseline),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cap := range spec.Process.Capabilities.Bounding {
+		if cap == "CAP_SYS_ADMIN" {
+			t.Error("expected CAP_SYS_ADMIN to be reset to the default capability set")
+		}
+	}
+}
+
+func TestWithUnknownProfile(t *testing.T) {
+	t.Parallel()
+	var (
+		ctx = namespaces.WithNamespace(context.Background(), "test")
+		c   = containers.Container{ID: t.Name()}
+	)
+
+	if _, err := GenerateSpec(ctx, nil, &c, WithProfile("unconfined")); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+}
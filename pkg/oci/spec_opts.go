@@ -115,6 +115,16 @@ func setCPUWindows(s *Spec) {
 	}
 }
 
+//nolint:nolintlint,unused // not used on all platforms
+func setStorageWindows(s *Spec) {
+	setResourcesWindows(s)
+	if s.Windows != nil {
+		if s.Windows.Resources.Storage == nil {
+			s.Windows.Resources.Storage = &specs.WindowsStorageResources{}
+		}
+	}
+}
+
 // setCapabilities sets Linux Capabilities to empty if unset
 func setCapabilities(s *Spec) {
 	setProcess(s)
@@ -318,6 +328,74 @@ func WithoutMounts(dests ...string) SpecOpts {
 	}
 }
 
+// sharesPropagation maps each valid mount propagation mode to whether it
+// propagates mount events to its peer group.
+var sharesPropagation = map[string]bool{
+	"shared":      true,
+	"rshared":     true,
+	"slave":       false,
+	"rslave":      false,
+	"private":     false,
+	"rprivate":    false,
+	"unbindable":  false,
+	"runbindable": false,
+}
+
+// ValidPropagationMode reports whether mode is a valid mount propagation
+// mode.
+func ValidPropagationMode(mode string) bool {
+	_, ok := sharesPropagation[mode]
+	return ok
+}
+
+// WithRootFSPropagation sets the rootfs mount propagation mode
+func WithRootFSPropagation(propagation string) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		if !ValidPropagationMode(propagation) {
+			return fmt.Errorf("invalid rootfs propagation mode %q", propagation)
+		}
+		setLinux(s)
+		s.Linux.RootfsPropagation = propagation
+		return nil
+	}
+}
+
+// WithMountPropagation sets the propagation mode for the mount at
+// destination, replacing any propagation option already present. A shared
+// mount under a rootfs which does not itself propagate mount events would
+// silently degrade to private at runtime, so that combination is rejected.
+func WithMountPropagation(destination, propagation string) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		if !ValidPropagationMode(propagation) {
+			return fmt.Errorf("invalid mount propagation mode %q", propagation)
+		}
+		if sharesPropagation[propagation] {
+			var rootfs string
+			if s.Linux != nil {
+				rootfs = s.Linux.RootfsPropagation
+			}
+			if !sharesPropagation[rootfs] {
+				return fmt.Errorf("%s mount at %q requires a shared rootfs, but rootfs propagation is %q", propagation, destination, rootfs)
+			}
+		}
+		dest := filepath.Clean(destination)
+		for i, m := range s.Mounts {
+			if filepath.Clean(m.Destination) != dest {
+				continue
+			}
+			options := make([]string, 0, len(m.Options)+1)
+			for _, o := range m.Options {
+				if !ValidPropagationMode(o) {
+					options = append(options, o)
+				}
+			}
+			s.Mounts[i].Options = append(options, propagation)
+			return nil
+		}
+		return fmt.Errorf("no mount with destination %q", destination)
+	}
+}
+
 // WithHostNamespace allows a task to run inside the host's linux namespace
 func WithHostNamespace(ns specs.LinuxNamespaceType) SpecOpts {
 	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
@@ -1659,6 +1737,80 @@ func WithWindowsCPUMaximum(max uint16) SpecOpts {
 	}
 }
 
+// WithWindowsCPUGroups sets the `Windows.Resources.CPU.Affinity` section to
+// the group affinities specified. Each affinity names a processor group and a
+// CPU mask relative to that group; an empty mask is rejected since it would
+// leave the container with no processors to schedule on. It is a no-op for
+// non-Windows specs.
+func WithWindowsCPUGroups(affinities ...specs.WindowsCPUGroupAffinity) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		for _, a := range affinities {
+			if a.Mask == 0 {
+				return fmt.Errorf("cpu group %d affinity has an empty mask", a.Group)
+			}
+		}
+		if s.Windows == nil {
+			return nil
+		}
+		setCPUWindows(s)
+		s.Windows.Resources.CPU.Affinity = append(s.Windows.Resources.CPU.Affinity, affinities...)
+		return nil
+	}
+}
+
+// WithWindowsMemoryLimit sets the `Windows.Resources.Memory.Limit` section to
+// the `limit` specified, enforced by the runtime as a job object memory
+// limit. Unlike WithMemoryLimit it leaves the Linux resource section alone,
+// so it can be combined with LCOW opts without clobbering the guest limit.
+// It is a no-op for non-Windows specs.
+func WithWindowsMemoryLimit(limit uint64) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		if s.Windows == nil {
+			return nil
+		}
+		setResourcesWindows(s)
+		if s.Windows.Resources.Memory == nil {
+			s.Windows.Resources.Memory = &specs.WindowsMemoryResources{}
+		}
+		s.Windows.Resources.Memory.Limit = &limit
+		return nil
+	}
+}
+
+// WithWindowsStorageIops sets the `Windows.Resources.Storage.Iops` section to
+// the `iops` specified, capping IO operations per second on the container's
+// system drive. It is a no-op for non-Windows specs.
+func WithWindowsStorageIops(iops uint64) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		if iops == 0 {
+			return errors.New("storage iops limit must be greater than 0")
+		}
+		if s.Windows == nil {
+			return nil
+		}
+		setStorageWindows(s)
+		s.Windows.Resources.Storage.Iops = &iops
+		return nil
+	}
+}
+
+// WithWindowsStorageBps sets the `Windows.Resources.Storage.Bps` section to
+// the `bps` specified, capping IO bandwidth in bytes per second on the
+// container's system drive. It is a no-op for non-Windows specs.
+func WithWindowsStorageBps(bps uint64) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		if bps == 0 {
+			return errors.New("storage bandwidth limit must be greater than 0")
+		}
+		if s.Windows == nil {
+			return nil
+		}
+		setStorageWindows(s)
+		s.Windows.Resources.Storage.Bps = &bps
+		return nil
+	}
+}
+
 // WithWindowsIgnoreFlushesDuringBoot sets `Windows.IgnoreFlushesDuringBoot`.
 func WithWindowsIgnoreFlushesDuringBoot() SpecOpts {
 	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
@@ -1698,3 +1850,147 @@ func WithWindowsNetworkNamespace(ns string) SpecOpts {
 		return nil
 	}
 }
+
+// Keyring annotations understood by the runc shim. They configure how the
+// session keyring of a container is set up, for workloads using kernel
+// keys such as Kerberos credential caches or ecryptfs.
+const (
+	// KeyringAnnotationPrefix is shared by all keyring annotations.
+	KeyringAnnotationPrefix = "io.containerd.runc.v2.keyring"
+	// KeyringPolicyAnnotation selects the keyring sharing policy, one of
+	// KeyringPolicyPrivate, KeyringPolicyHost or KeyringPolicyPod.
+	KeyringPolicyAnnotation = "io.containerd.runc.v2.keyring"
+	// KeyringNameAnnotation overrides the name of the session keyring
+	// joined under KeyringPolicyPod. It defaults to the sandbox ID.
+	KeyringNameAnnotation = "io.containerd.runc.v2.keyring-name"
+	// KeyringLinksAnnotation is a comma separated list of keyring names
+	// linked into the session keyring of the container. The names are
+	// looked up in the user keyring of the shim.
+	KeyringLinksAnnotation = "io.containerd.runc.v2.keyring-links"
+	// KeyringMaxKeysAnnotation is the number of keys the workload needs.
+	// The kernel enforces key quotas per UID, not per container, so the
+	// shim can only verify the kernel limit covers the request.
+	KeyringMaxKeysAnnotation = "io.containerd.runc.v2.keyring-maxkeys"
+	// KeyringMaxBytesAnnotation is the number of bytes of key payload the
+	// workload needs, verified like KeyringMaxKeysAnnotation.
+	KeyringMaxBytesAnnotation = "io.containerd.runc.v2.keyring-maxbytes"
+)
+
+// Keyring sharing policies accepted by KeyringPolicyAnnotation.
+const (
+	// KeyringPolicyPrivate gives the container a session keyring of its
+	// own, the runc default.
+	KeyringPolicyPrivate = "private"
+	// KeyringPolicyHost leaves the container on the session keyring of the
+	// shim, equivalent to WithNoNewKeyring.
+	KeyringPolicyHost = "host"
+	// KeyringPolicyPod places the container on a named session keyring
+	// shared by all containers of its pod.
+	KeyringPolicyPod = "pod"
+)
+
+// ValidKeyringPolicy reports whether policy is a known keyring sharing
+// policy.
+func ValidKeyringPolicy(policy string) bool {
+	switch policy {
+	case KeyringPolicyPrivate, KeyringPolicyHost, KeyringPolicyPod:
+		return true
+	}
+	return false
+}
+
+// WithKeyringPolicy sets the kernel keyring sharing policy for the
+// container. The policy is applied by the runc shim when the container is
+// created.
+func WithKeyringPolicy(policy string) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		if !ValidKeyringPolicy(policy) {
+			return fmt.Errorf("invalid keyring policy: %q", policy)
+		}
+		return WithAnnotations(map[string]string{KeyringPolicyAnnotation: policy})(nil, nil, nil, s)
+	}
+}
+
+// WithKeyringName sets the name of the session keyring joined under the
+// pod keyring policy, overriding the default derived from the sandbox ID.
+func WithKeyringName(name string) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		if name == "" {
+			return errors.New("keyring name must not be empty")
+		}
+		return WithAnnotations(map[string]string{KeyringNameAnnotation: name})(nil, nil, nil, s)
+	}
+}
+
+// WithKeyringLinks links the named keyrings into the session keyring of
+// the container. The names are resolved against the user keyring when the
+// container is created.
+func WithKeyringLinks(names ...string) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		for _, name := range names {
+			if name == "" || strings.Contains(name, ",") {
+				return fmt.Errorf("invalid keyring name: %q", name)
+			}
+		}
+		return WithAnnotations(map[string]string{KeyringLinksAnnotation: strings.Join(names, ",")})(nil, nil, nil, s)
+	}
+}
+
+// WithKeyringQuota records the key quota the workload needs. Keyring
+// quotas are enforced by the kernel per UID, so the quota cannot be
+// limited to the container; the runc shim verifies at create time that
+// the kernel limits cover the request and fails the container otherwise.
+// Zero leaves the respective quota unchecked.
+func WithKeyringQuota(maxKeys, maxBytes uint64) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		annotations := map[string]string{}
+		if maxKeys > 0 {
+			annotations[KeyringMaxKeysAnnotation] = strconv.FormatUint(maxKeys, 10)
+		}
+		if maxBytes > 0 {
+			annotations[KeyringMaxBytesAnnotation] = strconv.FormatUint(maxBytes, 10)
+		}
+		return WithAnnotations(annotations)(nil, nil, nil, s)
+	}
+}
+
+// SchedCoreAnnotation is understood by the runc shim. It assigns a core
+// scheduling (SCHED_CORE) cookie to the container, so its tasks never run
+// on SMT siblings of cores occupied by tasks outside the cookie. The value
+// is one of SchedCoreScopePod or SchedCoreScopeContainer.
+const SchedCoreAnnotation = "io.containerd.runc.v2.sched-core"
+
+// Core scheduling scopes accepted by SchedCoreAnnotation.
+const (
+	// SchedCoreScopePod places the container on a cookie shared by all
+	// containers of its pod, keeping the pod off SMT siblings of other
+	// workloads while its own containers may still share cores.
+	SchedCoreScopePod = "pod"
+	// SchedCoreScopeContainer gives the container a cookie of its own,
+	// so not even other containers of the same pod share its SMT
+	// siblings.
+	SchedCoreScopeContainer = "container"
+)
+
+// ValidSchedCoreScope reports whether scope is a known core scheduling
+// scope.
+func ValidSchedCoreScope(scope string) bool {
+	switch scope {
+	case SchedCoreScopePod, SchedCoreScopeContainer:
+		return true
+	}
+	return false
+}
+
+// WithSchedCore assigns a core scheduling cookie to the container in the
+// given scope. The cookie is created by the runc shim once the init
+// process exists and is inherited by every process of the container,
+// including processes exec'd into it later.
+func WithSchedCore(scope string) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		if !ValidSchedCoreScope(scope) {
+			return fmt.Errorf("invalid core scheduling scope: %q", scope)
+		}
+		return WithAnnotations(map[string]string{SchedCoreAnnotation: scope})(nil, nil, nil, s)
+	}
+}
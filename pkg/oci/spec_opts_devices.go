@@ -0,0 +1,202 @@
+//go:build !windows
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package oci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/basuotian/containerd/core/containers"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// WithDeviceCgroupRules appends device cgroup allow rules to the spec without
+// adding device nodes. Each rule uses the runc/docker format
+// "<type> <major>:<minor> <access>", for example "c 1:3 rwm"; the type may be
+// "a", "b" or "c" and the major and minor numbers may be "*" to match any
+// device.
+func WithDeviceCgroupRules(rules ...string) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		setLinux(s)
+		setResources(s)
+		for _, rule := range rules {
+			dc, err := parseDeviceCgroupRule(rule)
+			if err != nil {
+				return err
+			}
+			s.Linux.Resources.Devices = append(s.Linux.Resources.Devices, dc)
+		}
+		return nil
+	}
+}
+
+// WithDeviceAllowlist reads a device allowlist file and adds the listed
+// devices and their cgroup allow rules to the spec. The file contains one
+// entry per line; blank lines and lines starting with "#" are ignored. An
+// entry is either a cgroup rule in the "<type> <major>:<minor> <access>"
+// format accepted by [WithDeviceCgroupRules], or a host device path glob
+// optionally followed by an access string, for example "/dev/sd* rw".
+// Path entries are resolved against the host: each match must be a device
+// node (or a directory of device nodes) and contributes both the device
+// entry and its cgroup rule. A glob matching nothing is an error.
+func WithDeviceAllowlist(path string) SpecOpts {
+	return func(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open device allowlist: %w", err)
+		}
+		defer f.Close()
+
+		setLinux(s)
+		setResources(s)
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			entry := strings.TrimSpace(scanner.Text())
+			if entry == "" || strings.HasPrefix(entry, "#") {
+				continue
+			}
+			if strings.HasPrefix(entry, "/") {
+				if err := allowDeviceGlob(s, entry); err != nil {
+					return fmt.Errorf("device allowlist %s:%d: %w", path, line, err)
+				}
+				continue
+			}
+			dc, err := parseDeviceCgroupRule(entry)
+			if err != nil {
+				return fmt.Errorf("device allowlist %s:%d: %w", path, line, err)
+			}
+			s.Linux.Resources.Devices = append(s.Linux.Resources.Devices, dc)
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read device allowlist: %w", err)
+		}
+		return nil
+	}
+}
+
+// allowDeviceGlob resolves a "<glob> [<access>]" allowlist entry against the
+// host and appends the matching devices and their cgroup rules to the spec.
+func allowDeviceGlob(s *Spec, entry string) error {
+	pattern, access, _ := strings.Cut(entry, " ")
+	access = strings.TrimSpace(access)
+	if access == "" {
+		access = "rwm"
+	} else if err := validateDeviceAccess(access); err != nil {
+		return err
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid device glob %q: %w", pattern, err)
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("device glob %q matched no host devices", pattern)
+	}
+	for _, match := range matches {
+		devs, err := getDevices(match, "")
+		if err != nil {
+			return err
+		}
+		for i := range devs {
+			s.Linux.Devices = append(s.Linux.Devices, devs[i])
+			s.Linux.Resources.Devices = append(s.Linux.Resources.Devices, specs.LinuxDeviceCgroup{
+				Allow:  true,
+				Type:   devs[i].Type,
+				Major:  &devs[i].Major,
+				Minor:  &devs[i].Minor,
+				Access: access,
+			})
+		}
+	}
+	return nil
+}
+
+// parseDeviceCgroupRule parses a "<type> <major>:<minor> <access>" rule into
+// a device cgroup entry.
+func parseDeviceCgroupRule(rule string) (specs.LinuxDeviceCgroup, error) {
+	fields := strings.Fields(rule)
+	if len(fields) != 3 {
+		return specs.LinuxDeviceCgroup{}, fmt.Errorf("invalid device cgroup rule %q: expected \"<type> <major>:<minor> <access>\"", rule)
+	}
+
+	devType := fields[0]
+	switch devType {
+	case wildcardDevice, blockDevice, charDevice:
+	default:
+		return specs.LinuxDeviceCgroup{}, fmt.Errorf("invalid device cgroup rule %q: unknown device type %q", rule, devType)
+	}
+
+	majorStr, minorStr, ok := strings.Cut(fields[1], ":")
+	if !ok {
+		return specs.LinuxDeviceCgroup{}, fmt.Errorf("invalid device cgroup rule %q: expected <major>:<minor>", rule)
+	}
+	major, err := parseDeviceNumber(majorStr)
+	if err != nil {
+		return specs.LinuxDeviceCgroup{}, fmt.Errorf("invalid device cgroup rule %q: %w", rule, err)
+	}
+	minor, err := parseDeviceNumber(minorStr)
+	if err != nil {
+		return specs.LinuxDeviceCgroup{}, fmt.Errorf("invalid device cgroup rule %q: %w", rule, err)
+	}
+
+	access := fields[2]
+	if err := validateDeviceAccess(access); err != nil {
+		return specs.LinuxDeviceCgroup{}, fmt.Errorf("invalid device cgroup rule %q: %w", rule, err)
+	}
+
+	return specs.LinuxDeviceCgroup{
+		Allow:  true,
+		Type:   devType,
+		Major:  major,
+		Minor:  minor,
+		Access: access,
+	}, nil
+}
+
+// parseDeviceNumber parses a major or minor number, returning nil for the
+// "*" wildcard.
+func parseDeviceNumber(s string) (*int64, error) {
+	if s == "*" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid device number %q", s)
+	}
+	return &n, nil
+}
+
+func validateDeviceAccess(access string) error {
+	if access == "" {
+		return fmt.Errorf("empty device access")
+	}
+	for _, c := range access {
+		switch c {
+		case 'r', 'w', 'm':
+		default:
+			return fmt.Errorf("invalid device access %q", access)
+		}
+	}
+	return nil
+}
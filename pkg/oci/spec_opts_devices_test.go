@@ -0,0 +1,129 @@
+//go:build !windows
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package oci
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDeviceCgroupRule(t *testing.T) {
+	t.Parallel()
+
+	one, three := int64(1), int64(3)
+	for _, tc := range []struct {
+		rule   string
+		typ    string
+		major  *int64
+		minor  *int64
+		access string
+		err    bool
+	}{
+		{rule: "c 1:3 rwm", typ: "c", major: &one, minor: &three, access: "rwm"},
+		{rule: "b 1:* rw", typ: "b", major: &one, access: "rw"},
+		{rule: "a *:* m", typ: "a", access: "m"},
+		{rule: "c 1:3", err: true},
+		{rule: "x 1:3 rwm", err: true},
+		{rule: "c 1 rwm", err: true},
+		{rule: "c -1:3 rwm", err: true},
+		{rule: "c 1:3 rwx", err: true},
+		{rule: "c 1:3 ", err: true},
+	} {
+		dc, err := parseDeviceCgroupRule(tc.rule)
+		if tc.err {
+			assert.Error(t, err, tc.rule)
+			continue
+		}
+		require.NoError(t, err, tc.rule)
+		assert.True(t, dc.Allow, tc.rule)
+		assert.Equal(t, tc.typ, dc.Type, tc.rule)
+		assert.Equal(t, tc.major, dc.Major, tc.rule)
+		assert.Equal(t, tc.minor, dc.Minor, tc.rule)
+		assert.Equal(t, tc.access, dc.Access, tc.rule)
+	}
+}
+
+func TestWithDeviceCgroupRules(t *testing.T) {
+	t.Parallel()
+
+	var s Spec
+	err := WithDeviceCgroupRules("c 1:3 rwm", "b *:* r")(nil, nil, nil, &s)
+	require.NoError(t, err)
+	require.Len(t, s.Linux.Resources.Devices, 2)
+	assert.Equal(t, "c", s.Linux.Resources.Devices[0].Type)
+	assert.Equal(t, "b", s.Linux.Resources.Devices[1].Type)
+	assert.Nil(t, s.Linux.Resources.Devices[1].Major)
+	assert.Empty(t, s.Linux.Devices)
+
+	err = WithDeviceCgroupRules("bogus")(nil, nil, nil, &s)
+	assert.Error(t, err)
+}
+
+func TestWithDeviceAllowlist(t *testing.T) {
+	t.Parallel()
+
+	allowlist := filepath.Join(t.TempDir(), "devices.allow")
+	content := `# tty and null
+c 5:* rwm
+
+/dev/null rw
+`
+	require.NoError(t, os.WriteFile(allowlist, []byte(content), 0600))
+
+	var s Spec
+	err := WithDeviceAllowlist(allowlist)(nil, nil, nil, &s)
+	require.NoError(t, err)
+
+	require.Len(t, s.Linux.Devices, 1)
+	assert.Equal(t, "/dev/null", s.Linux.Devices[0].Path)
+
+	require.Len(t, s.Linux.Resources.Devices, 2)
+	assert.Equal(t, "c", s.Linux.Resources.Devices[0].Type)
+	assert.Nil(t, s.Linux.Resources.Devices[0].Minor)
+	assert.Equal(t, s.Linux.Devices[0].Major, *s.Linux.Resources.Devices[1].Major)
+	assert.Equal(t, "rw", s.Linux.Resources.Devices[1].Access)
+}
+
+func TestWithDeviceAllowlistErrors(t *testing.T) {
+	t.Parallel()
+
+	td := t.TempDir()
+	for name, content := range map[string]string{
+		"bad-rule":   "c 1:3 rwx\n",
+		"no-matches": "/dev/this-device-does-not-exist-* r\n",
+		"bad-access": "/dev/null xyz\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			allowlist := filepath.Join(td, name)
+			require.NoError(t, os.WriteFile(allowlist, []byte(content), 0600))
+
+			var s Spec
+			err := WithDeviceAllowlist(allowlist)(nil, nil, nil, &s)
+			assert.Error(t, err)
+		})
+	}
+
+	var s Spec
+	err := WithDeviceAllowlist(filepath.Join(td, "missing"))(nil, nil, nil, &s)
+	assert.Error(t, err)
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/basuotian/containerd/core/containers"
 	"github.com/basuotian/containerd/pkg/cap"
+	"github.com/basuotian/containerd/pkg/sdnotify"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 )
 
@@ -60,6 +61,25 @@ func WithDevices(devicePath, containerPath, permissions string) SpecOpts {
 	}
 }
 
+// WithSdNotify wires the container up for sd_notify readiness
+// notifications. The shim listens on a notification socket kept in the
+// bundle, the socket directory is bind mounted into the container and
+// NOTIFY_SOCKET points the workload at it. Shims supporting the
+// protocol translate READY=1 into a task ready event.
+func WithSdNotify(_ context.Context, _ Client, _ *containers.Container, s *Spec) error {
+	setProcess(s)
+	s.Process.Env = append(s.Process.Env, "NOTIFY_SOCKET="+sdnotify.ContainerSocket)
+	s.Mounts = append(s.Mounts, specs.Mount{
+		Destination: sdnotify.ContainerDir,
+		Type:        "bind",
+		// A relative source resolves against the bundle directory,
+		// where the shim creates the socket.
+		Source:  sdnotify.BundleDir,
+		Options: []string{"bind", "rw"},
+	})
+	return nil
+}
+
 // WithAllCurrentCapabilities propagates the effective capabilities of the caller process to the container process.
 // The capability set may differ from [WithAllKnownCapabilities] when running in a container.
 func WithAllCurrentCapabilities(ctx context.Context, client Client, c *containers.Container, s *Spec) error {
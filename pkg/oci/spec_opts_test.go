@@ -262,6 +262,42 @@ func TestWithMounts(t *testing.T) {
 	assert.Equal(t, "new-dest", s.Mounts[1].Destination, "invalid mount")
 }
 
+func TestWithRootFSPropagation(t *testing.T) {
+	t.Parallel()
+
+	var s Spec
+	assert.NoError(t, WithRootFSPropagation("rslave")(nil, nil, nil, &s))
+	assert.Equal(t, "rslave", s.Linux.RootfsPropagation)
+
+	assert.Error(t, WithRootFSPropagation("bogus")(nil, nil, nil, &s))
+}
+
+func TestWithMountPropagation(t *testing.T) {
+	t.Parallel()
+
+	s := Spec{
+		Mounts: []specs.Mount{
+			{
+				Destination: "/mnt",
+				Options:     []string{"rbind", "private"},
+			},
+		},
+	}
+
+	err := WithMountPropagation("/mnt", "rslave")(nil, nil, nil, &s)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rbind", "rslave"}, s.Mounts[0].Options, "propagation option not replaced")
+
+	// A shared mount requires a shared rootfs.
+	assert.Error(t, WithMountPropagation("/mnt", "rshared")(nil, nil, nil, &s))
+	assert.NoError(t, WithRootFSPropagation("rshared")(nil, nil, nil, &s))
+	assert.NoError(t, WithMountPropagation("/mnt", "rshared")(nil, nil, nil, &s))
+	assert.Equal(t, []string{"rbind", "rshared"}, s.Mounts[0].Options)
+
+	assert.Error(t, WithMountPropagation("/mnt", "bogus")(nil, nil, nil, &s))
+	assert.Error(t, WithMountPropagation("/missing", "rslave")(nil, nil, nil, &s))
+}
+
 func TestWithDefaultSpec(t *testing.T) {
 	t.Parallel()
 	var (
@@ -895,3 +931,108 @@ func TestWithWindowsCPUMaximum(t *testing.T) {
 		})
 	}
 }
+
+func TestWithWindowsCPUGroups(t *testing.T) {
+	for name, spec := range emptySpecs {
+		t.Run(name, func(t *testing.T) {
+			expected := specs.WindowsCPUGroupAffinity{Group: 1, Mask: 0xff}
+			err := WithWindowsCPUGroups(expected)(nil, nil, nil, &spec)
+			assert.NoError(t, err)
+			if name == "windows" {
+				assert.Equal(t, []specs.WindowsCPUGroupAffinity{expected}, spec.Windows.Resources.CPU.Affinity)
+			} else {
+				assert.Empty(t, spec.Windows, "should not have modified spec")
+			}
+
+			err = WithWindowsCPUGroups(specs.WindowsCPUGroupAffinity{Group: 2})(nil, nil, nil, &spec)
+			assert.Error(t, err, "empty mask should be rejected")
+		})
+	}
+}
+
+func TestWithWindowsMemoryLimit(t *testing.T) {
+	for name, spec := range emptySpecs {
+		t.Run(name, func(t *testing.T) {
+			expected := uint64(512 * 1024 * 1024)
+			err := WithWindowsMemoryLimit(expected)(nil, nil, nil, &spec)
+			assert.NoError(t, err)
+			if name == "windows" {
+				assert.Equal(t, expected, *spec.Windows.Resources.Memory.Limit)
+				assert.Empty(t, spec.Linux, "should not have modified the Linux section")
+			} else {
+				assert.Empty(t, spec.Windows, "should not have modified spec")
+			}
+		})
+	}
+}
+
+func TestWithWindowsStorageIops(t *testing.T) {
+	for name, spec := range emptySpecs {
+		t.Run(name, func(t *testing.T) {
+			expected := uint64(1000)
+			err := WithWindowsStorageIops(expected)(nil, nil, nil, &spec)
+			assert.NoError(t, err)
+			if name == "windows" {
+				assert.Equal(t, expected, *spec.Windows.Resources.Storage.Iops)
+			} else {
+				assert.Empty(t, spec.Windows, "should not have modified spec")
+			}
+
+			err = WithWindowsStorageIops(0)(nil, nil, nil, &spec)
+			assert.Error(t, err, "zero iops limit should be rejected")
+		})
+	}
+}
+
+func TestWithWindowsStorageBps(t *testing.T) {
+	for name, spec := range emptySpecs {
+		t.Run(name, func(t *testing.T) {
+			expected := uint64(64 * 1024 * 1024)
+			err := WithWindowsStorageBps(expected)(nil, nil, nil, &spec)
+			assert.NoError(t, err)
+			if name == "windows" {
+				assert.Equal(t, expected, *spec.Windows.Resources.Storage.Bps)
+			} else {
+				assert.Empty(t, spec.Windows, "should not have modified spec")
+			}
+
+			err = WithWindowsStorageBps(0)(nil, nil, nil, &spec)
+			assert.Error(t, err, "zero bandwidth limit should be rejected")
+		})
+	}
+}
+
+func TestWithKeyringAnnotations(t *testing.T) {
+	t.Parallel()
+	var s Spec
+
+	require.Error(t, WithKeyringPolicy("shared")(nil, nil, nil, &s))
+	require.Error(t, WithKeyringName("")(nil, nil, nil, &s))
+	require.Error(t, WithKeyringLinks("a,b")(nil, nil, nil, &s))
+
+	require.NoError(t, WithKeyringPolicy(KeyringPolicyPod)(nil, nil, nil, &s))
+	require.NoError(t, WithKeyringName("kerberos")(nil, nil, nil, &s))
+	require.NoError(t, WithKeyringLinks("host-certs", "ccache")(nil, nil, nil, &s))
+	require.NoError(t, WithKeyringQuota(100, 0)(nil, nil, nil, &s))
+
+	assert.Equal(t, map[string]string{
+		KeyringPolicyAnnotation:  KeyringPolicyPod,
+		KeyringNameAnnotation:    "kerberos",
+		KeyringLinksAnnotation:   "host-certs,ccache",
+		KeyringMaxKeysAnnotation: "100",
+	}, s.Annotations)
+}
+
+func TestWithSchedCore(t *testing.T) {
+	t.Parallel()
+	var s Spec
+
+	require.Error(t, WithSchedCore("process")(nil, nil, nil, &s))
+	require.Error(t, WithSchedCore("")(nil, nil, nil, &s))
+
+	require.NoError(t, WithSchedCore(SchedCoreScopeContainer)(nil, nil, nil, &s))
+
+	assert.Equal(t, map[string]string{
+		SchedCoreAnnotation: SchedCoreScopeContainer,
+	}, s.Annotations)
+}
@@ -44,6 +44,7 @@ func New(publisher events.Publisher) (oom.Watcher, error) {
 		fd:        fd,
 		publisher: publisher,
 		set:       make(map[uintptr]*item),
+		victims:   oom.NewVictimReader(),
 	}, nil
 }
 
@@ -54,6 +55,7 @@ type epoller struct {
 	fd        int
 	publisher events.Publisher
 	set       map[uintptr]*item
+	victims   *oom.VictimReader
 }
 
 type item struct {
@@ -141,6 +143,14 @@ func (e *epoller) process(ctx context.Context, fd uintptr) {
 	}); err != nil {
 		log.G(ctx).WithError(err).Error("publish OOM event")
 	}
+	if victims := e.victims.Collect(ctx, i.id); len(victims) > 0 {
+		if err := e.publisher.Publish(ctx, runtime.TaskOOMVictimEventTopic, &oom.TaskOOMVictim{
+			ContainerID: i.id,
+			Victims:     victims,
+		}); err != nil {
+			log.G(ctx).WithError(err).Error("publish OOM victim event")
+		}
+	}
 }
 
 func flush(fd uintptr) error {
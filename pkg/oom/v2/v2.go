@@ -36,6 +36,7 @@ func New(publisher events.Publisher) (oom.Watcher, error) {
 	return &watcher{
 		itemCh:    make(chan item),
 		publisher: publisher,
+		victims:   oom.NewVictimReader(),
 	}, nil
 }
 
@@ -43,6 +44,7 @@ func New(publisher events.Publisher) (oom.Watcher, error) {
 type watcher struct {
 	itemCh    chan item
 	publisher events.Publisher
+	victims   *oom.VictimReader
 }
 
 type item struct {
@@ -76,6 +78,14 @@ func (w *watcher) Run(ctx context.Context) {
 				}); err != nil {
 					log.G(ctx).WithError(err).Error("publish OOM event")
 				}
+				if victims := w.victims.Collect(ctx, i.id); len(victims) > 0 {
+					if err := w.publisher.Publish(ctx, runtime.TaskOOMVictimEventTopic, &oom.TaskOOMVictim{
+						ContainerID: i.id,
+						Victims:     victims,
+					}); err != nil {
+						log.G(ctx).WithError(err).Error("publish OOM victim event")
+					}
+				}
 			}
 			if i.ev.OOMKill > 0 {
 				lastOOMMap[i.id] = i.ev.OOMKill
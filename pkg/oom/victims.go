@@ -0,0 +1,195 @@
+//go:build linux
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package oom
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	typeurl.Register(&TaskOOMVictim{}, "containerd.event.TaskOOMVictim")
+}
+
+// TaskOOMVictim supplements the TaskOOM API event with details about the
+// processes picked by the kernel OOM killer. It is published on a separate
+// topic so that subscribers which only care about the fact of an OOM kill
+// keep seeing the unchanged TaskOOM event.
+type TaskOOMVictim struct {
+	ContainerID string   `json:"container_id"`
+	Victims     []Victim `json:"victims"`
+}
+
+// Victim describes a single process killed by the kernel OOM killer. The
+// memory figures mirror the fields the kernel reports in its oom kill log
+// record and may be zero on kernels which do not log them.
+type Victim struct {
+	Pid        int    `json:"pid"`
+	Comm       string `json:"comm"`
+	UID        int    `json:"uid"`
+	CgroupPath string `json:"cgroup_path,omitempty"`
+	TotalVMKB  uint64 `json:"total_vm_kb,omitempty"`
+	AnonRSSKB  uint64 `json:"anon_rss_kb,omitempty"`
+	FileRSSKB  uint64 `json:"file_rss_kb,omitempty"`
+	ShmemRSSKB uint64 `json:"shmem_rss_kb,omitempty"`
+}
+
+var (
+	// oom-kill:constraint=CONSTRAINT_MEMCG,...,task_memcg=/path,task=comm,pid=123,uid=0
+	oomKillRegexp = regexp.MustCompile(`oom-kill:.*task_memcg=([^,]*),task=(.*),pid=(\d+),uid=(\d+)`)
+	// Killed process 123 (comm) total-vm:123kB, anon-rss:123kB, file-rss:123kB, shmem-rss:123kB
+	killedProcessRegexp = regexp.MustCompile(`Killed process (\d+) \((.*)\) total-vm:(\d+)kB, anon-rss:(\d+)kB, file-rss:(\d+)kB, shmem-rss:(\d+)kB`)
+)
+
+// VictimReader collects OOM victim details from the kernel log. The cgroup
+// memory event counters only say that a kill happened; the identity of the
+// killed process is only available from the records the kernel writes to its
+// log buffer, so collection is strictly best effort.
+type VictimReader struct {
+	mu      sync.Mutex
+	lastSeq uint64
+}
+
+// NewVictimReader returns a reader primed past the records currently in the
+// kernel log buffer so that kills which predate the watcher are not
+// attributed to its containers.
+func NewVictimReader() *VictimReader {
+	r := &VictimReader{}
+	for _, rec := range r.readRecords() {
+		if rec.seq > r.lastSeq {
+			r.lastSeq = rec.seq
+		}
+	}
+	return r
+}
+
+type kmsgRecord struct {
+	seq     uint64
+	message string
+}
+
+// readRecords drains the currently buffered kernel log records without
+// blocking. Errors, including lack of permission to read the kernel log, are
+// treated as no records being available.
+func (r *VictimReader) readRecords() []kmsgRecord {
+	fd, err := unix.Open("/dev/kmsg", unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil
+	}
+	defer unix.Close(fd)
+
+	var (
+		records []kmsgRecord
+		buf     [8192]byte
+	)
+	for {
+		n, err := unix.Read(fd, buf[:])
+		if err != nil {
+			if err == unix.EPIPE {
+				// Records were overwritten while reading, skip to
+				// the next available one.
+				continue
+			}
+			// EAGAIN means the buffer has been drained.
+			return records
+		}
+		prefix, message, ok := strings.Cut(strings.TrimRight(string(buf[:n]), "\n"), ";")
+		if !ok {
+			continue
+		}
+		// The prefix is "priority,sequence,timestamp,flags".
+		fields := strings.Split(prefix, ",")
+		if len(fields) < 3 {
+			continue
+		}
+		seq, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		records = append(records, kmsgRecord{seq: seq, message: message})
+	}
+}
+
+// Collect returns the victims of OOM kills reported by the kernel since the
+// previous call which can be attributed to the container with the given id.
+// Attribution matches the id against the cgroup path the kernel logged for
+// the killed task.
+func (r *VictimReader) Collect(ctx context.Context, id string) []Victim {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		victims []Victim
+		byPid   = make(map[int]int) // pid -> index into victims
+	)
+	for _, rec := range r.readRecords() {
+		if rec.seq <= r.lastSeq {
+			continue
+		}
+		r.lastSeq = rec.seq
+
+		if m := oomKillRegexp.FindStringSubmatch(rec.message); m != nil {
+			cgroupPath := m[1]
+			if cgroupPath != "" && !strings.Contains(cgroupPath, id) {
+				continue
+			}
+			pid, err := strconv.Atoi(m[3])
+			if err != nil {
+				continue
+			}
+			uid, _ := strconv.Atoi(m[4])
+			victims = append(victims, Victim{
+				Pid:        pid,
+				Comm:       m[2],
+				UID:        uid,
+				CgroupPath: cgroupPath,
+			})
+			byPid[pid] = len(victims) - 1
+			continue
+		}
+		if m := killedProcessRegexp.FindStringSubmatch(rec.message); m != nil {
+			pid, err := strconv.Atoi(m[1])
+			if err != nil {
+				continue
+			}
+			idx, ok := byPid[pid]
+			if !ok {
+				// The kill record belongs to another cgroup, or its
+				// oom-kill line was lost.
+				continue
+			}
+			v := &victims[idx]
+			v.TotalVMKB, _ = strconv.ParseUint(m[3], 10, 64)
+			v.AnonRSSKB, _ = strconv.ParseUint(m[4], 10, 64)
+			v.FileRSSKB, _ = strconv.ParseUint(m[5], 10, 64)
+			v.ShmemRSSKB, _ = strconv.ParseUint(m[6], 10, 64)
+		}
+	}
+	if len(victims) > 0 {
+		log.G(ctx).WithField("id", id).Debugf("collected %d OOM victim(s) from the kernel log", len(victims))
+	}
+	return victims
+}
@@ -0,0 +1,95 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package processtree
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Collect gathers the details of pid from /proc. The process may exit
+// while it is inspected; callers should treat errors as the process
+// being gone rather than fatal.
+func Collect(pid uint32) (Process, error) {
+	p := Process{Pid: pid}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return p, err
+	}
+	p.Comm, p.Ppid, p.StartTime, err = parseStat(string(stat))
+	if err != nil {
+		return p, err
+	}
+	// Zombies and kernel threads have no command line.
+	if cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil {
+		p.Cmdline = string(bytes.TrimRight(bytes.ReplaceAll(cmdline, []byte{0}, []byte{' '}), " "))
+	}
+	if cgroup, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid)); err == nil {
+		p.Cgroup = parseCgroup(string(cgroup))
+	}
+	return p, nil
+}
+
+// parseStat extracts the command name, parent pid and start time from a
+// /proc/<pid>/stat line. The comm field is enclosed in parentheses and
+// may itself contain spaces and parentheses, so fields are located
+// relative to the last closing parenthesis.
+func parseStat(stat string) (comm string, ppid uint32, startTime uint64, err error) {
+	open := strings.IndexByte(stat, '(')
+	close := strings.LastIndexByte(stat, ')')
+	if open < 0 || close < open {
+		return "", 0, 0, fmt.Errorf("malformed stat %q", stat)
+	}
+	comm = stat[open+1 : close]
+	// Fields after comm, so field n of stat is fields[n-3].
+	fields := strings.Fields(stat[close+1:])
+	if len(fields) < 20 {
+		return "", 0, 0, fmt.Errorf("malformed stat %q", stat)
+	}
+	// ppid is stat field 4, starttime field 22.
+	parsedPpid, err := strconv.ParseUint(fields[1], 10, 32)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("malformed ppid in stat %q: %w", stat, err)
+	}
+	startTime, err = strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("malformed starttime in stat %q: %w", stat, err)
+	}
+	return comm, uint32(parsedPpid), startTime, nil
+}
+
+// parseCgroup returns the unified hierarchy path of a /proc/<pid>/cgroup
+// listing, falling back to the first entry on cgroup v1.
+func parseCgroup(data string) string {
+	var first string
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			return parts[2]
+		}
+		if first == "" {
+			first = parts[2]
+		}
+	}
+	return first
+}
@@ -0,0 +1,58 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package processtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStat(t *testing.T) {
+	// Comm may contain spaces and parentheses.
+	stat := "42 (a (we)ird) name) S 7 42 42 0 -1 4194560 1110 0 0 0 2 1 0 0 20 0 1 0 4327 2293760 183 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 1 0 0 0 0 0 0 0 0 0 0 0 0 0"
+	comm, ppid, startTime, err := parseStat(stat)
+	require.NoError(t, err)
+	assert.Equal(t, "a (we)ird) name", comm)
+	assert.Equal(t, uint32(7), ppid)
+	assert.Equal(t, uint64(4327), startTime)
+
+	_, _, _, err = parseStat("42 (short) S 7")
+	assert.Error(t, err)
+}
+
+func TestParseCgroup(t *testing.T) {
+	v2 := "0::/kubepods/pod1/container2\n"
+	assert.Equal(t, "/kubepods/pod1/container2", parseCgroup(v2))
+
+	v1 := "12:pids:/docker/abc\n11:memory:/docker/abc\n"
+	assert.Equal(t, "/docker/abc", parseCgroup(v1))
+
+	// Hybrid listings prefer the unified entry.
+	hybrid := "12:pids:/docker/abc\n0::/unified/abc\n"
+	assert.Equal(t, "/unified/abc", parseCgroup(hybrid))
+}
+
+func TestCollectSelf(t *testing.T) {
+	p, err := Collect(1)
+	if err != nil {
+		t.Skipf("cannot read pid 1: %v", err)
+	}
+	assert.Equal(t, uint32(1), p.Pid)
+	assert.NotEmpty(t, p.Comm)
+}
@@ -0,0 +1,82 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package processtree defines the enriched process details a shim can
+// attach to task Pids responses, so clients can reconstruct the process
+// tree of a container when debugging zombie or orphan processes.
+package processtree
+
+import (
+	"sort"
+
+	"github.com/containerd/typeurl/v2"
+)
+
+func init() {
+	typeurl.Register(&Process{}, "containerd.processtree.Process")
+}
+
+// Process describes a single process inside a task, gathered by the shim
+// from /proc at the time of the Pids request.
+type Process struct {
+	// Pid of the process in the host pid namespace
+	Pid uint32 `json:"pid"`
+	// Ppid is the parent pid in the host pid namespace, zero when unknown
+	Ppid uint32 `json:"ppid,omitempty"`
+	// Comm is the process command name
+	Comm string `json:"comm,omitempty"`
+	// Cmdline is the full command line, empty for kernel threads and
+	// zombies
+	Cmdline string `json:"cmdline,omitempty"`
+	// StartTime is the process start time in clock ticks since boot
+	StartTime uint64 `json:"start_time,omitempty"`
+	// Cgroup is the cgroup path the process is attributed to
+	Cgroup string `json:"cgroup,omitempty"`
+	// ExecID is the task exec id when the process was started through
+	// the task API
+	ExecID string `json:"exec_id,omitempty"`
+}
+
+// Node is a process with its children, forming the process tree.
+type Node struct {
+	Process
+	Children []*Node
+}
+
+// Tree arranges processes into trees by their parent relationship.
+// Processes whose parent is not part of the listing become roots, which
+// covers both the init process and orphans reparented outside the
+// container. Roots and children are sorted by pid.
+func Tree(procs []Process) []*Node {
+	nodes := make(map[uint32]*Node, len(procs))
+	for _, p := range procs {
+		nodes[p.Pid] = &Node{Process: p}
+	}
+	var roots []*Node
+	for _, p := range procs {
+		n := nodes[p.Pid]
+		if parent, ok := nodes[p.Ppid]; ok && p.Ppid != p.Pid {
+			parent.Children = append(parent.Children, n)
+		} else {
+			roots = append(roots, n)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Pid < roots[j].Pid })
+	for _, n := range nodes {
+		sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Pid < n.Children[j].Pid })
+	}
+	return roots
+}
@@ -0,0 +1,51 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package processtree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTree(t *testing.T) {
+	procs := []Process{
+		{Pid: 30, Ppid: 10, Comm: "sleep"},
+		{Pid: 10, Ppid: 1, Comm: "init"},
+		{Pid: 20, Ppid: 10, Comm: "sh"},
+		{Pid: 40, Ppid: 20, Comm: "cat"},
+		// Orphan reparented outside the listing.
+		{Pid: 50, Ppid: 2, Comm: "zombie"},
+	}
+	roots := Tree(procs)
+	require.Len(t, roots, 2)
+	assert.Equal(t, uint32(10), roots[0].Pid)
+	assert.Equal(t, uint32(50), roots[1].Pid)
+	require.Len(t, roots[0].Children, 2)
+	assert.Equal(t, uint32(20), roots[0].Children[0].Pid)
+	assert.Equal(t, uint32(30), roots[0].Children[1].Pid)
+	require.Len(t, roots[0].Children[0].Children, 1)
+	assert.Equal(t, uint32(40), roots[0].Children[0].Children[0].Pid)
+}
+
+func TestTreeSelfParent(t *testing.T) {
+	// pid 0 kernel idiom: a process must not become its own child.
+	roots := Tree([]Process{{Pid: 1, Ppid: 1}})
+	require.Len(t, roots, 1)
+	assert.Empty(t, roots[0].Children)
+}
@@ -43,7 +43,18 @@ func Create(t PidType) error {
 	return unix.Prctl(unix.PR_SCHED_CORE, unix.PR_SCHED_CORE_CREATE, 0, uintptr(t), 0)
 }
 
+// CreateFor creates a new sched core domain for the provided pid
+func CreateFor(pid uint64, t PidType) error {
+	return unix.Prctl(unix.PR_SCHED_CORE, unix.PR_SCHED_CORE_CREATE, uintptr(pid), uintptr(t), 0)
+}
+
 // ShareFrom shares the sched core domain from the provided pid
 func ShareFrom(pid uint64, t PidType) error {
 	return unix.Prctl(unix.PR_SCHED_CORE, unix.PR_SCHED_CORE_SHARE_FROM, uintptr(pid), uintptr(t), 0)
 }
+
+// ShareTo shares the sched core domain of the calling thread with the
+// provided pid
+func ShareTo(pid uint64, t PidType) error {
+	return unix.Prctl(unix.PR_SCHED_CORE, unix.PR_SCHED_CORE_SHARE_TO, uintptr(pid), uintptr(t), 0)
+}
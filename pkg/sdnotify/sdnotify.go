@@ -0,0 +1,146 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package sdnotify implements the receiving side of the sd_notify(3)
+// readiness protocol. A shim listens on a notification socket kept in
+// the container bundle and bind mounted into the container, and
+// translates READY=1 messages from the workload into a task ready
+// event, so orchestrators can wait for application readiness rather
+// than process start.
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/containerd/typeurl/v2"
+)
+
+func init() {
+	typeurl.Register(&TaskReady{}, "containerd.event.TaskReady")
+}
+
+const (
+	// BundleDir is the bundle relative directory holding the
+	// notification socket.
+	BundleDir = "sdnotify"
+	// SocketName is the name of the notification socket.
+	SocketName = "notify.sock"
+	// ContainerDir is the path the socket directory is mounted at
+	// inside the container.
+	ContainerDir = "/run/containerd/sdnotify"
+	// ContainerSocket is the path of the notification socket inside the
+	// container, the value NOTIFY_SOCKET is set to.
+	ContainerSocket = ContainerDir + "/" + SocketName
+)
+
+// TaskReady is published on the task ready topic when the workload
+// reports READY=1 on its notification socket.
+type TaskReady struct {
+	ContainerID string `json:"container_id"`
+}
+
+// Listener owns a notification socket and reports when READY=1 has been
+// received on it.
+type Listener struct {
+	conn  *net.UnixConn
+	ready chan struct{}
+	done  chan struct{}
+
+	readyOnce sync.Once
+	closeOnce sync.Once
+}
+
+// Listen creates the notification socket in dir and starts receiving
+// notification messages. The socket is made writable for any container
+// user, readiness is not a privileged operation.
+func Listen(dir string) (*Listener, error) {
+	if err := os.MkdirAll(dir, 0711); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, SocketName)
+	// Remove a socket left behind by a previous run of the container.
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0666); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	l := &Listener{
+		conn:  conn,
+		ready: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go l.run()
+	return l, nil
+}
+
+func (l *Listener) run() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := l.conn.Read(buf)
+		if err != nil {
+			return
+		}
+		// A datagram holds newline separated KEY=VALUE assignments,
+		// everything but readiness is ignored.
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			if strings.TrimSpace(line) == "READY=1" {
+				l.readyOnce.Do(func() {
+					close(l.ready)
+				})
+			}
+		}
+	}
+}
+
+// Ready returns a channel which is closed once READY=1 has been received
+func (l *Listener) Ready() <-chan struct{} {
+	return l.ready
+}
+
+// IsReady reports whether READY=1 has been received
+func (l *Listener) IsReady() bool {
+	select {
+	case <-l.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// Done returns a channel which is closed when the listener is closed
+func (l *Listener) Done() <-chan struct{} {
+	return l.done
+}
+
+// Close stops receiving notification messages
+func (l *Listener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.done)
+		err = l.conn.Close()
+	})
+	return err
+}
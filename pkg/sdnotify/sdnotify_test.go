@@ -0,0 +1,80 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sdnotify
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func notify(t *testing.T, path, msg string) {
+	t.Helper()
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(msg))
+	require.NoError(t, err)
+}
+
+func TestListenerReady(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), BundleDir)
+	l, err := Listen(dir)
+	require.NoError(t, err)
+	defer l.Close()
+
+	path := filepath.Join(dir, SocketName)
+
+	notify(t, path, "STATUS=starting")
+	require.False(t, l.IsReady())
+
+	notify(t, path, "STATUS=up\nREADY=1")
+	select {
+	case <-l.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for readiness")
+	}
+	require.True(t, l.IsReady())
+}
+
+func TestListenerClose(t *testing.T) {
+	l, err := Listen(filepath.Join(t.TempDir(), BundleDir))
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+	select {
+	case <-l.Done():
+	default:
+		t.Fatal("done channel not closed")
+	}
+	// Closing twice must not panic or error.
+	require.NoError(t, l.Close())
+}
+
+func TestListenerRelisten(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), BundleDir)
+	l, err := Listen(dir)
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	// A stale socket from a previous run must not prevent listening.
+	l, err = Listen(dir)
+	require.NoError(t, err)
+	defer l.Close()
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/basuotian/containerd/core/events"
 	"github.com/basuotian/containerd/pkg/namespaces"
 	"github.com/basuotian/containerd/pkg/protobuf"
+	"github.com/basuotian/containerd/pkg/tracing"
 	"github.com/basuotian/containerd/pkg/ttrpcutil"
 	v1 "github.com/containerd/containerd/api/services/ttrpc/events/v1"
 	"github.com/containerd/containerd/api/types"
@@ -102,7 +103,17 @@ func (l *RemoteEventsPublisher) processQueue() {
 			continue
 		}
 
-		if err := l.forwardRequest(i.ctx, &v1.ForwardRequest{Envelope: i.ev}); err != nil {
+		// The publish call that queued the event has already returned, so the
+		// retry runs in a fresh trace linked back to the originating context.
+		ctx, span := tracing.StartSpan(namespaces.WithNamespace(context.Background(), i.ev.Namespace),
+			"shim.publisher.forward",
+			tracing.WithAttribute("event.topic", i.ev.Topic),
+			tracing.WithLinkFromContext(i.ctx),
+		)
+		err := l.forwardRequest(ctx, &v1.ForwardRequest{Envelope: i.ev})
+		span.SetStatus(err)
+		span.End()
+		if err != nil {
 			log.L.WithError(err).Error("forward event")
 			l.queue(i)
 		}
@@ -0,0 +1,90 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package shim
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/basuotian/containerd/pkg/namespaces"
+)
+
+func TestSocketAddressAbstract(t *testing.T) {
+	ctx := namespaces.WithNamespace(context.Background(), t.Name())
+
+	address, err := SocketAddress(ctx, "/run/containerd/containerd.sock", "abstract-id", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !socket(address).isAbstract() {
+		t.Fatalf("expected abstract address, got %q", address)
+	}
+	if debug, err := SocketAddress(ctx, "/run/containerd/containerd.sock", "abstract-id", true); err != nil {
+		t.Fatal(err)
+	} else if debug == address {
+		t.Fatalf("debug address %q must differ from shim address", debug)
+	}
+}
+
+func TestSocketAddressLegacyFallback(t *testing.T) {
+	ctx := namespaces.WithNamespace(context.Background(), t.Name())
+
+	d := sha256.Sum256([]byte(filepath.Join("/run/containerd/containerd.sock", t.Name(), "legacy-id")))
+	legacy := fmt.Sprintf("unix://%s/%x", filepath.Join(socketRoot, "s"), d)
+	l, err := NewSocket(legacy)
+	if err != nil {
+		t.Skipf("cannot bind under %s: %v", socketRoot, err)
+	}
+	defer func() {
+		l.Close()
+		RemoveSocket(legacy)
+	}()
+
+	address, err := SocketAddress(ctx, "/run/containerd/containerd.sock", "legacy-id", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != legacy {
+		t.Fatalf("expected live legacy address %q, got %q", legacy, address)
+	}
+}
+
+func TestRebindSocket(t *testing.T) {
+	address := "unix://" + filepath.Join(t.TempDir(), "shim.sock")
+
+	l, err := NewSocket(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave a stale socket file behind, as a shim killed without
+	// cleanup would.
+	l.SetUnlinkOnClose(false)
+	l.Close()
+
+	l, err = RebindSocket(address)
+	if err != nil {
+		t.Fatalf("rebind over stale socket: %v", err)
+	}
+	defer l.Close()
+
+	if _, err := RebindSocket(address); err == nil {
+		t.Fatal("expected rebind of a served socket to fail")
+	}
+}
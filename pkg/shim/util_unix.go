@@ -35,6 +35,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/containerd/errdefs"
 	"github.com/containerd/log"
 	"github.com/mdlayher/vsock"
 
@@ -75,7 +76,13 @@ func AdjustOOMScore(pid int) error {
 
 const socketRoot = defaults.DefaultStateDir
 
-// SocketAddress returns a socket address
+// SocketAddress returns a socket address for the id. On Linux the address
+// is in the abstract socket namespace, which is not subject to the unix
+// socket path length limit; elsewhere it is a hashed path under a short
+// socket directory so that deep state directories stay below the limit.
+// A shim which is still serving on the hashed path of an older release is
+// preferred over a fresh abstract address so that dialing and shim
+// grouping keep working across an upgrade.
 func SocketAddress(ctx context.Context, socketPath, id string, debug bool) (string, error) {
 	ns, err := namespaces.NamespaceRequired(ctx)
 	if err != nil {
@@ -86,7 +93,14 @@ func SocketAddress(ctx context.Context, socketPath, id string, debug bool) (stri
 		path = filepath.Join(path, "debug")
 	}
 	d := sha256.Sum256([]byte(path))
-	return fmt.Sprintf("unix://%s/%x", filepath.Join(socketRoot, "s"), d), nil
+	legacy := fmt.Sprintf("unix://%s/%x", filepath.Join(socketRoot, "s"), d)
+	if !abstractSocketsPreferred {
+		return legacy, nil
+	}
+	if CanConnect(legacy) {
+		return legacy, nil
+	}
+	return fmt.Sprintf("containerd-shim-%x", d), nil
 }
 
 // AnonDialer returns a dialer for a socket
@@ -165,6 +179,25 @@ func (s socket) path() string {
 	return path
 }
 
+// RebindSocket binds a listener at an address a shim previously served
+// on, such as one read back from a bundle when restoring after a daemon
+// restart. Both abstract and path based addresses are accepted; a stale
+// socket file left behind on the filesystem is removed first if nothing
+// is accepting connections on it.
+func RebindSocket(address string) (*net.UnixListener, error) {
+	l, err := NewSocket(address)
+	if err == nil || !SocketEaddrinuse(err) {
+		return l, err
+	}
+	if CanConnect(address) {
+		return nil, fmt.Errorf("socket %s still accepting connections: %w", address, errdefs.ErrAlreadyExists)
+	}
+	if err := RemoveSocket(address); err != nil {
+		return nil, fmt.Errorf("remove stale socket: %w", err)
+	}
+	return NewSocket(address)
+}
+
 // RemoveSocket removes the socket at the specified address if
 // it exists on the filesystem
 func RemoveSocket(address string) error {
@@ -0,0 +1,139 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package startlatency accumulates per-stage timings for a container
+// start and folds them into a single event published when the task
+// starts, so that latency regressions can be tracked per stage across
+// a fleet instead of as one opaque end-to-end number.
+package startlatency
+
+import (
+	"sync"
+	"time"
+
+	"github.com/containerd/typeurl/v2"
+)
+
+func init() {
+	typeurl.Register(&TaskStartLatency{}, "containerd.event.TaskStartLatency")
+}
+
+// Well known stage names. Components may record additional stages, but
+// these cover the common container start path.
+const (
+	// StageSnapshotMounts is the time spent collecting the rootfs
+	// mounts from the snapshotter.
+	StageSnapshotMounts = "snapshot-mounts"
+	// StageShimStart is the time spent launching the shim process and
+	// waiting for its task service to come up.
+	StageShimStart = "shim-start"
+	// StageRuntimeCreate is the time spent in the shim Create call,
+	// which covers mounting the rootfs and the OCI runtime create.
+	StageRuntimeCreate = "runtime-create"
+	// StageRuntimeStart is the time spent in the shim Start call, which
+	// covers the OCI runtime start.
+	StageRuntimeStart = "runtime-start"
+	// StageNetworkReady is the time spent setting up the pod network,
+	// recorded by the CRI implementation for sandboxes.
+	StageNetworkReady = "network-ready"
+)
+
+// Stage is one timed step of a container start.
+type Stage struct {
+	Name string `json:"name"`
+	// Duration of the stage in nanoseconds.
+	Duration time.Duration `json:"duration"`
+}
+
+// TaskStartLatency is the per-start breakdown, published once on
+// runtime.TaskStartLatencyEventTopic after the task has started. The
+// stages appear in the order they were recorded.
+type TaskStartLatency struct {
+	ContainerID string  `json:"container_id"`
+	Stages      []Stage `json:"stages"`
+	// Total is the sum of the recorded stage durations, not the wall
+	// clock time between the first and last stage.
+	Total time.Duration `json:"total"`
+}
+
+// staleAfter bounds how long stages for a task which never reaches
+// start are kept, so that failed or abandoned creates do not accumulate.
+const staleAfter = 10 * time.Minute
+
+type record struct {
+	updated time.Time
+	stages  []Stage
+}
+
+var (
+	mu      sync.Mutex
+	records = map[string]*record{}
+)
+
+func key(namespace, id string) string {
+	return namespace + "/" + id
+}
+
+// Record adds a stage duration for the given task. Stages recorded
+// between create and start are folded into the single breakdown
+// returned by Finish.
+func Record(namespace, id, stage string, d time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	for k, r := range records {
+		if now.Sub(r.updated) > staleAfter {
+			delete(records, k)
+		}
+	}
+	k := key(namespace, id)
+	r := records[k]
+	if r == nil {
+		r = &record{}
+		records[k] = r
+	}
+	r.updated = now
+	r.stages = append(r.stages, Stage{Name: stage, Duration: d})
+}
+
+// Finish returns the accumulated breakdown for the task and stops
+// tracking it. It returns nil when no stages were recorded.
+func Finish(namespace, id string) *TaskStartLatency {
+	mu.Lock()
+	defer mu.Unlock()
+	k := key(namespace, id)
+	r := records[k]
+	if r == nil {
+		return nil
+	}
+	delete(records, k)
+	e := &TaskStartLatency{
+		ContainerID: id,
+		Stages:      r.stages,
+	}
+	for _, s := range r.stages {
+		e.Total += s.Duration
+	}
+	return e
+}
+
+// Discard drops any stages recorded for the task. It is called when a
+// create fails or the task is deleted without having started.
+func Discard(namespace, id string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(records, key(namespace, id))
+}
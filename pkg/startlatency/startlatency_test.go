@@ -0,0 +1,61 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package startlatency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordFinish(t *testing.T) {
+	Record("default", "c1", StageShimStart, 100*time.Millisecond)
+	Record("default", "c1", StageRuntimeCreate, 50*time.Millisecond)
+	Record("default", "c1", StageRuntimeStart, 25*time.Millisecond)
+
+	e := Finish("default", "c1")
+	if assert.NotNil(t, e) {
+		assert.Equal(t, "c1", e.ContainerID)
+		assert.Equal(t, []Stage{
+			{Name: StageShimStart, Duration: 100 * time.Millisecond},
+			{Name: StageRuntimeCreate, Duration: 50 * time.Millisecond},
+			{Name: StageRuntimeStart, Duration: 25 * time.Millisecond},
+		}, e.Stages)
+		assert.Equal(t, 175*time.Millisecond, e.Total)
+	}
+
+	// A second finish has nothing left to report.
+	assert.Nil(t, Finish("default", "c1"))
+}
+
+func TestNamespaceIsolation(t *testing.T) {
+	Record("ns1", "c1", StageShimStart, time.Millisecond)
+	Record("ns2", "c1", StageShimStart, 2*time.Millisecond)
+	defer Discard("ns2", "c1")
+
+	e := Finish("ns1", "c1")
+	if assert.NotNil(t, e) {
+		assert.Equal(t, time.Millisecond, e.Total)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	Record("default", "c2", StageShimStart, time.Millisecond)
+	Discard("default", "c2")
+	assert.Nil(t, Finish("default", "c2"))
+}
@@ -0,0 +1,69 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithBaggage attaches the given attributes to the context as OpenTelemetry
+// baggage. Baggage crosses process boundaries together with the span context,
+// so attributes set on the client side become available to spans started on
+// the daemon side. Attributes that cannot be represented as baggage members
+// are silently dropped.
+func WithBaggage(ctx context.Context, kvs ...attribute.KeyValue) context.Context {
+	bag := baggage.FromContext(ctx)
+	for _, kv := range kvs {
+		member, err := baggage.NewMemberRaw(string(kv.Key), kv.Value.Emit())
+		if err != nil {
+			continue
+		}
+		if bag, err = bag.SetMember(member); err != nil {
+			continue
+		}
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// AttributesFromBaggage returns the baggage carried by the context as span
+// attributes.
+func AttributesFromBaggage(ctx context.Context) []attribute.KeyValue {
+	members := baggage.FromContext(ctx).Members()
+	if len(members) == 0 {
+		return nil
+	}
+	kvs := make([]attribute.KeyValue, 0, len(members))
+	for _, member := range members {
+		kvs = append(kvs, attribute.String(member.Key(), member.Value()))
+	}
+	return kvs
+}
+
+// WithBaggageAttributes copies the baggage carried by the context onto a new
+// span, so operations deep in the daemon keep the identifying attributes of
+// the client call that caused them.
+func WithBaggageAttributes(ctx context.Context) SpanOpt {
+	return func(config *StartConfig) {
+		if kvs := AttributesFromBaggage(ctx); len(kvs) > 0 {
+			config.spanOpts = append(config.spanOpts, trace.WithAttributes(kvs...))
+		}
+	}
+}
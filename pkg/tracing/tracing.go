@@ -44,6 +44,18 @@ func WithAttribute(k string, v interface{}) SpanOpt {
 	}
 }
 
+// WithLinkFromContext links a new span to the span recorded in the given
+// context without parenting it. This keeps causality visible for work that
+// outlives the request that triggered it, such as queued retries.
+func WithLinkFromContext(ctx context.Context) SpanOpt {
+	return func(config *StartConfig) {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			config.spanOpts = append(config.spanOpts,
+				trace.WithLinks(trace.Link{SpanContext: sc}))
+		}
+	}
+}
+
 // UpdateHTTPClient updates the http client with the necessary otel transport
 func UpdateHTTPClient(client *http.Client, name string) {
 	client.Transport = otelhttp.NewTransport(
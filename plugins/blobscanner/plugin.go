@@ -0,0 +1,48 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package blobscanner
+
+import (
+	"time"
+
+	"github.com/basuotian/containerd/internal/tomlext"
+	"github.com/basuotian/containerd/pkg/blobscanner/bindir"
+	"github.com/basuotian/containerd/plugins"
+	"github.com/containerd/plugin"
+	"github.com/containerd/plugin/registry"
+)
+
+// Register default blob scanner service plugin
+func init() {
+	registry.Register(&plugin.Registration{
+		Type:   plugins.BlobScannerPlugin,
+		ID:     "bindir",
+		Config: defaultConfig(),
+		InitFn: func(ic *plugin.InitContext) (interface{}, error) {
+			cfg := ic.Config.(*bindir.Config)
+			return bindir.NewBlobScanner(cfg), nil
+		},
+	})
+}
+
+func defaultConfig() *bindir.Config {
+	return &bindir.Config{
+		BinDir:         defaultPath,
+		MaxScanners:    10,
+		PerBlobTimeout: tomlext.FromStdTime(5 * time.Minute),
+	}
+}
@@ -0,0 +1,129 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package local
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// NewFSLabelStore returns a LabelStore persisting labels as JSON files
+// under root, laid out like the blob directories: root/<alg>/<encoded>.
+// It is for stores that need labels without the metadata database, e.g.
+// a standalone labeled store carrying encryption metadata.
+func NewFSLabelStore(root string) (LabelStore, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	return &fsLabelStore{root: root}, nil
+}
+
+type fsLabelStore struct {
+	mu   sync.Mutex
+	root string
+}
+
+func (s *fsLabelStore) path(d digest.Digest) string {
+	return filepath.Join(s.root, d.Algorithm().String(), d.Encoded())
+}
+
+func (s *fsLabelStore) Get(d digest.Digest) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(d)
+}
+
+func (s *fsLabelStore) read(d digest.Digest) (map[string]string, error) {
+	data, err := os.ReadFile(s.path(d))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("corrupt label file for %v: %w", d, err)
+	}
+	return labels, nil
+}
+
+func (s *fsLabelStore) Set(d digest.Digest, labels map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(d, labels)
+}
+
+func (s *fsLabelStore) write(d digest.Digest, labels map[string]string) error {
+	if len(labels) == 0 {
+		if err := os.Remove(s.path(d)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path(d)), 0700); err != nil {
+		return err
+	}
+	// Write via a temporary file so a crash cannot leave a half written
+	// label file behind.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path(d)), ".tmp-"+d.Encoded())
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(d))
+}
+
+func (s *fsLabelStore) Update(d digest.Digest, update map[string]string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	labels, err := s.read(d)
+	if err != nil {
+		return nil, err
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range update {
+		if v == "" {
+			delete(labels, k)
+		} else {
+			labels[k] = v
+		}
+	}
+	if err := s.write(d, labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
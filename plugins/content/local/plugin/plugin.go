@@ -17,21 +17,58 @@
 package plugin
 
 import (
+	"fmt"
+	"path/filepath"
+
 	"github.com/containerd/plugin"
 	"github.com/containerd/plugin/registry"
 
+	"github.com/basuotian/containerd/core/content/encrypted"
 	"github.com/basuotian/containerd/plugins"
 	"github.com/basuotian/containerd/plugins/content/local"
 )
 
+// Config is the configuration of the content store plugin.
+type Config struct {
+	// EncryptBlobs encrypts blob data at rest. Blobs written while
+	// encryption was disabled remain readable in the clear.
+	EncryptBlobs bool `toml:"encrypt_blobs"`
+	// EncryptionKeyPath is the file holding the 32 byte AES-256 master
+	// key, raw or hex encoded, which wraps the per blob data keys. The
+	// file must only be accessible by the daemon user.
+	EncryptionKeyPath string `toml:"encryption_key_path"`
+}
+
 func init() {
 	registry.Register(&plugin.Registration{
-		Type: plugins.ContentPlugin,
-		ID:   "content",
+		Type:   plugins.ContentPlugin,
+		ID:     "content",
+		Config: &Config{},
 		InitFn: func(ic *plugin.InitContext) (interface{}, error) {
 			root := ic.Properties[plugins.PropertyRootDir]
 			ic.Meta.Exports["root"] = root
-			return local.NewStore(root)
+			cfg := ic.Config.(*Config)
+			if !cfg.EncryptBlobs {
+				return local.NewStore(root)
+			}
+			if cfg.EncryptionKeyPath == "" {
+				return nil, fmt.Errorf("encrypt_blobs requires encryption_key_path to be set")
+			}
+			provider, err := encrypted.NewFileKeyProvider(cfg.EncryptionKeyPath)
+			if err != nil {
+				return nil, err
+			}
+			// The encryption metadata lives in labels, which need a label
+			// store since the local store alone has none.
+			ls, err := local.NewFSLabelStore(filepath.Join(root, "labels"))
+			if err != nil {
+				return nil, err
+			}
+			store, err := local.NewLabeledStore(root, ls)
+			if err != nil {
+				return nil, err
+			}
+			return encrypted.NewStore(store, provider), nil
 		},
 	})
 }
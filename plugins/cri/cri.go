@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/containerd/errdefs"
 	"github.com/containerd/log"
 	"github.com/containerd/plugin"
 	"github.com/containerd/plugin/registry"
@@ -167,6 +168,17 @@ type criGRPCServer struct {
 	initializer
 }
 
+// PluginInfo forwards introspection requests, such as
+// server.ForcePodCleanupRequest, to the CRI service.
+func (c *criGRPCServer) PluginInfo(ctx context.Context, request interface{}) (interface{}, error) {
+	if p, ok := c.RuntimeServiceServer.(interface {
+		PluginInfo(context.Context, interface{}) (interface{}, error)
+	}); ok {
+		return p.PluginInfo(ctx, request)
+	}
+	return nil, fmt.Errorf("unsupported request type %T: %w", request, errdefs.ErrNotImplemented)
+}
+
 func (c *criGRPCServer) register(s *grpc.Server) error {
 	instrumented := instrument.NewService(c)
 	runtime.RegisterRuntimeServiceServer(s, instrumented)
@@ -0,0 +1,208 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package decrypt provides a diff implementation which decrypts
+// ocicrypt encrypted layers (org.opencontainers.image.enc media types)
+// in process while applying them. Layer keys are taken from the
+// decryption payload passed by the client through the apply
+// configuration and from key files configured in the daemon. Plain
+// layers are not handled and fall through to the next differ.
+package decrypt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+	encconfig "github.com/containers/ocicrypt/config"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/diff"
+	"github.com/basuotian/containerd/core/images/encryption"
+	"github.com/basuotian/containerd/core/mount"
+	"github.com/basuotian/containerd/pkg/archive"
+	"github.com/basuotian/containerd/pkg/archive/compression"
+)
+
+type differ interface {
+	diff.Comparer
+	diff.Applier
+}
+
+type decryptDiff struct {
+	store    content.Store
+	keyPaths []string
+	keyDir   string
+}
+
+var emptyDesc = ocispec.Descriptor{}
+
+// NewDecryptDiff returns a differ which decrypts encrypted layers using
+// the given key files and any key file found in keyDir before applying
+// them. It only implements Apply; Compare is left to other differs.
+func NewDecryptDiff(store content.Store, keyPaths []string, keyDir string) differ {
+	return &decryptDiff{
+		store:    store,
+		keyPaths: keyPaths,
+		keyDir:   keyDir,
+	}
+}
+
+// Compare is not implemented, encrypted layers are produced by image
+// conversion rather than by diffing snapshots.
+func (s *decryptDiff) Compare(ctx context.Context, lower, upper []mount.Mount, opts ...diff.Opt) (ocispec.Descriptor, error) {
+	return emptyDesc, fmt.Errorf("decryptDiff does not implement Compare method: %w", errdefs.ErrNotImplemented)
+}
+
+// Apply decrypts the content associated with the provided descriptor and
+// applies it onto the provided mounts. Non-encrypted media types are
+// rejected with ErrNotImplemented so another differ can handle them.
+func (s *decryptDiff) Apply(ctx context.Context, desc ocispec.Descriptor, mounts []mount.Mount, opts ...diff.ApplyOpt) (d ocispec.Descriptor, err error) {
+	t1 := time.Now()
+	defer func() {
+		if err == nil {
+			log.G(ctx).WithFields(log.Fields{
+				"d":      time.Since(t1),
+				"digest": desc.Digest,
+				"size":   desc.Size,
+				"media":  desc.MediaType,
+			}).Debugf("diff applied")
+		}
+	}()
+
+	if !encryption.IsEncryptedDiff(ctx, desc.MediaType) {
+		return emptyDesc, fmt.Errorf("unsupported diff media type: %v: %w", desc.MediaType, errdefs.ErrNotImplemented)
+	}
+
+	var config diff.ApplyConfig
+	for _, o := range opts {
+		if err := o(ctx, desc, &config); err != nil {
+			return emptyDesc, fmt.Errorf("failed to apply config opt: %w", err)
+		}
+	}
+
+	dc, err := s.decryptConfig(&config)
+	if err != nil {
+		return emptyDesc, err
+	}
+
+	ra, err := s.store.ReaderAt(ctx, desc)
+	if err != nil {
+		return emptyDesc, fmt.Errorf("failed to get reader from content store: %w", err)
+	}
+	defer ra.Close()
+
+	_, decrypted, _, err := encryption.DecryptLayer(dc, content.NewReader(ra), desc, false)
+	if err != nil {
+		return emptyDesc, fmt.Errorf("failed to decrypt layer %s: %w", desc.Digest, err)
+	}
+
+	decompressed, err := compression.DecompressStream(decrypted)
+	if err != nil {
+		return emptyDesc, fmt.Errorf("failed to decompress layer %s: %w", desc.Digest, err)
+	}
+	defer decompressed.Close()
+
+	digester := digest.Canonical.Digester()
+	rc := &readCounter{
+		r: io.TeeReader(decompressed, digester.Hash()),
+	}
+
+	if err := mount.WithTempMount(ctx, mounts, func(root string) error {
+		_, err := archive.Apply(ctx, root, rc)
+		return err
+	}); err != nil {
+		return emptyDesc, err
+	}
+
+	// Read any trailing data
+	if _, err := io.Copy(io.Discard, rc); err != nil {
+		return emptyDesc, err
+	}
+
+	return ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Size:      rc.c,
+		Digest:    digester.Digest(),
+	}, nil
+}
+
+// decryptConfig assembles the decryption parameters for a layer from the
+// keys configured in the daemon and the payload the client attached to
+// the apply configuration.
+func (s *decryptDiff) decryptConfig(config *diff.ApplyConfig) (*encconfig.DecryptConfig, error) {
+	var ccs []encconfig.CryptoConfig
+
+	keys := append([]string{}, s.keyPaths...)
+	if s.keyDir != "" {
+		entries, err := os.ReadDir(s.keyDir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read key directory %q: %w", s.keyDir, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				keys = append(keys, filepath.Join(s.keyDir, entry.Name()))
+			}
+		}
+	}
+	if len(keys) > 0 {
+		cc, err := encryption.DecryptConfigFromKeyFiles(keys...)
+		if err != nil {
+			return nil, err
+		}
+		ccs = append(ccs, cc)
+	}
+
+	// the client attaches the same payload to all tool ids
+	for _, id := range encryption.PayloadToolIDs {
+		anything, ok := config.ProcessorPayloads[id]
+		if !ok {
+			continue
+		}
+		v, err := typeurl.UnmarshalAny(anything)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal processor payload: %w", err)
+		}
+		payload, ok := v.(*encryption.Payload)
+		if !ok {
+			return nil, fmt.Errorf("unknown processor payload type %T", v)
+		}
+		ccs = append(ccs, encconfig.InitDecryption(payload.DecryptConfig.Parameters))
+		break
+	}
+
+	cc := encconfig.CombineCryptoConfigs(ccs)
+	return cc.DecryptConfig, nil
+}
+
+type readCounter struct {
+	r io.Reader
+	c int64
+}
+
+func (rc *readCounter) Read(p []byte) (n int, err error) {
+	n, err = rc.r.Read(p)
+	rc.c += int64(n)
+	return
+}
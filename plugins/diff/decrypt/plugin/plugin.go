@@ -0,0 +1,63 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package plugin
+
+import (
+	"path/filepath"
+
+	"github.com/basuotian/containerd/core/metadata"
+	"github.com/basuotian/containerd/defaults"
+	"github.com/basuotian/containerd/plugins"
+	"github.com/basuotian/containerd/plugins/diff/decrypt"
+	"github.com/containerd/platforms"
+	"github.com/containerd/plugin"
+	"github.com/containerd/plugin/registry"
+)
+
+// Config represents configuration for the decrypt plugin.
+type Config struct {
+	// KeyPaths are additional private key files used to decrypt layers
+	KeyPaths []string `toml:"keys"`
+	// KeyDir is a directory whose key files are used to decrypt layers
+	KeyDir string `toml:"key_dir"`
+}
+
+func init() {
+	registry.Register(&plugin.Registration{
+		Type: plugins.DiffPlugin,
+		ID:   "decrypt",
+		Requires: []plugin.Type{
+			plugins.MetadataPlugin,
+		},
+		Config: &Config{
+			// same default key location as the external ocicrypt decoder
+			KeyDir: filepath.Join(defaults.DefaultConfigDir, "ocicrypt", "keys"),
+		},
+		InitFn: func(ic *plugin.InitContext) (interface{}, error) {
+			md, err := ic.GetSingle(plugins.MetadataPlugin)
+			if err != nil {
+				return nil, err
+			}
+
+			ic.Meta.Platforms = append(ic.Meta.Platforms, platforms.DefaultSpec())
+			cs := md.(*metadata.DB).ContentStore()
+			config := ic.Config.(*Config)
+
+			return decrypt.NewDecryptDiff(cs, config.KeyPaths, config.KeyDir), nil
+		},
+	})
+}
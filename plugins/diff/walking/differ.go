@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"runtime"
 	"time"
 
 	"github.com/containerd/errdefs"
@@ -143,13 +144,28 @@ func (s *walkingDiff) Compare(ctx context.Context, lower, upper []mount.Mount, o
 						return fmt.Errorf("failed to get compressed stream: %w", errOpen)
 					}
 				} else {
-					compressed, errOpen = compression.CompressStream(cw, compressionType)
+					compressed, errOpen = compression.CompressStreamParallel(cw, compressionType, runtime.GOMAXPROCS(0))
 					if errOpen != nil {
 						return fmt.Errorf("failed to get compressed stream: %w", errOpen)
 					}
 				}
-				errOpen = archive.WriteDiff(ctx, io.MultiWriter(compressed, dgstr.Hash()), lowerRoot, upperRoot, writeDiffOpts...)
-				compressed.Close()
+				// hash the uncompressed stream in its own goroutine so
+				// digest computation overlaps tar packing and compression
+				hashRead, hashWrite := io.Pipe()
+				hashDone := make(chan error, 1)
+				go func() {
+					_, err := io.Copy(dgstr.Hash(), hashRead)
+					hashRead.CloseWithError(err)
+					hashDone <- err
+				}()
+				errOpen = archive.WriteDiff(ctx, io.MultiWriter(compressed, hashWrite), lowerRoot, upperRoot, writeDiffOpts...)
+				if cerr := compressed.Close(); errOpen == nil {
+					errOpen = cerr
+				}
+				hashWrite.CloseWithError(errOpen)
+				if herr := <-hashDone; errOpen == nil {
+					errOpen = herr
+				}
 				if errOpen != nil {
 					return fmt.Errorf("failed to write compressed diff: %w", errOpen)
 				}
@@ -17,6 +17,8 @@
 package plugin
 
 import (
+	"errors"
+
 	"github.com/basuotian/containerd/core/diff"
 	"github.com/basuotian/containerd/core/diff/apply"
 	"github.com/basuotian/containerd/core/metadata"
@@ -27,6 +29,16 @@ import (
 	"github.com/containerd/plugin/registry"
 )
 
+// Config represents configuration for the walking diff plugin.
+type Config struct {
+	// ConvertOverlayWhiteouts translates overlay style whiteout markers
+	// (0:0 character devices and overlay opaque xattrs) in applied layers
+	// into explicit deletions. Set it when the configured snapshotter
+	// cannot represent the markers, for example because its backing
+	// filesystem lacks trusted xattr support.
+	ConvertOverlayWhiteouts bool `toml:"convert_overlay_whiteouts"`
+}
+
 func init() {
 	registry.Register(&plugin.Registration{
 		Type: plugins.DiffPlugin,
@@ -34,18 +46,29 @@ func init() {
 		Requires: []plugin.Type{
 			plugins.MetadataPlugin,
 		},
+		Config: &Config{},
 		InitFn: func(ic *plugin.InitContext) (interface{}, error) {
 			md, err := ic.GetSingle(plugins.MetadataPlugin)
 			if err != nil {
 				return nil, err
 			}
 
+			config, ok := ic.Config.(*Config)
+			if !ok {
+				return nil, errors.New("invalid walking diff configuration")
+			}
+
 			ic.Meta.Platforms = append(ic.Meta.Platforms, platforms.DefaultSpec())
 			cs := md.(*metadata.DB).ContentStore()
 
+			var aOpts []apply.ApplierOpt
+			if config.ConvertOverlayWhiteouts {
+				aOpts = append(aOpts, apply.WithOverlayWhiteoutConversion())
+			}
+
 			return diffPlugin{
 				Comparer: walking.NewWalkingDiff(cs),
-				Applier:  apply.NewFileSystemApplier(cs),
+				Applier:  apply.NewFileSystemApplier(cs, aOpts...),
 			}, nil
 		},
 	})
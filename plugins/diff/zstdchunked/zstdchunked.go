@@ -0,0 +1,350 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package zstdchunked provides a diff implementation which compresses
+// layers in the zstd:chunked format. The produced blobs are valid
+// tar+zstd layers: every file's content is compressed into its own zstd
+// frame and a table of contents describing the frames is appended as
+// zstd skippable frames, so that consumers aware of the format can
+// lazily fetch individual files while everything else treats the blob
+// as a regular zstd compressed layer.
+package zstdchunked
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/diff"
+	"github.com/basuotian/containerd/core/mount"
+	"github.com/basuotian/containerd/plugins/diff/walking"
+)
+
+const (
+	// ManifestChecksumAnnotation is the annotation holding the digest of
+	// the compressed table of contents of a zstd:chunked layer.
+	ManifestChecksumAnnotation = "io.github.containers.zstd-chunked.manifest-checksum"
+	// ManifestPositionAnnotation is the annotation holding the position
+	// of the table of contents inside the layer blob, in the format
+	// "offset:compressed length:uncompressed length:type".
+	ManifestPositionAnnotation = "io.github.containers.zstd-chunked.manifest-position"
+
+	// manifestTypeCRFS is the type of the table of contents manifest.
+	manifestTypeCRFS = 1
+
+	// zstdSkippableFrameMagic is the magic number of a zstd skippable frame.
+	zstdSkippableFrameMagic = 0x184d2a50
+)
+
+// zstdChunkedFrameMagic identifies the zstd:chunked footer inside the
+// final skippable frame of the blob.
+var zstdChunkedFrameMagic = []byte{0x47, 0x6e, 0x55, 0x6c, 0x49, 0x6e, 0x55, 0x78}
+
+// toc is the table of contents describing the frames of a zstd:chunked blob.
+type toc struct {
+	Version int        `json:"version"`
+	Entries []tocEntry `json:"entries"`
+}
+
+// tocEntry describes a single tar entry and, for regular files, the
+// compressed frame holding its content.
+type tocEntry struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	LinkName  string `json:"linkName,omitempty"`
+	Mode      int64  `json:"mode,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	UID       int    `json:"uid,omitempty"`
+	GID       int    `json:"gid,omitempty"`
+	ModTime   string `json:"modtime,omitempty"`
+	DevMajor  int64  `json:"devMajor,omitempty"`
+	DevMinor  int64  `json:"devMinor,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Offset    int64  `json:"offset,omitempty"`
+	EndOffset int64  `json:"endOffset,omitempty"`
+}
+
+var emptyDesc = ocispec.Descriptor{}
+
+// NewZstdChunkedDiff returns a diff.Comparer which emits zstd:chunked
+// compressed layers and annotates the returned descriptors with the
+// table of contents position, enabling later lazy pulls of blobs
+// produced by this daemon.
+func NewZstdChunkedDiff(store content.Store) diff.Comparer {
+	return &zstdChunkedDiff{
+		inner: walking.NewWalkingDiff(store),
+	}
+}
+
+type zstdChunkedDiff struct {
+	inner diff.Comparer
+}
+
+// Compare creates a zstd:chunked diff between the given mounts and
+// uploads the result to the content store.
+func (s *zstdChunkedDiff) Compare(ctx context.Context, lower, upper []mount.Mount, opts ...diff.Opt) (ocispec.Descriptor, error) {
+	c := &chunkedCompressor{}
+	// The compressor and media type are appended after the caller's
+	// options so a conflicting compressor cannot be injected.
+	opts = append(opts,
+		diff.WithMediaType(ocispec.MediaTypeImageLayerZstd),
+		diff.WithCompressor(c.compress),
+	)
+	desc, err := s.inner.Compare(ctx, lower, upper, opts...)
+	if err != nil {
+		return emptyDesc, err
+	}
+	if c.err != nil {
+		return emptyDesc, fmt.Errorf("failed to write zstd:chunked layer: %w", c.err)
+	}
+	if desc.Annotations == nil {
+		desc.Annotations = map[string]string{}
+	}
+	desc.Annotations[ManifestChecksumAnnotation] = c.manifestDigest.String()
+	desc.Annotations[ManifestPositionAnnotation] = fmt.Sprintf("%d:%d:%d:%d",
+		c.manifestOffset, c.manifestCompressedSize, c.manifestUncompressedSize, manifestTypeCRFS)
+	return desc, nil
+}
+
+// chunkedCompressor holds the per-layer results of the compression so
+// the comparer can annotate the committed descriptor.
+type chunkedCompressor struct {
+	manifestDigest           digest.Digest
+	manifestOffset           uint64
+	manifestCompressedSize   uint64
+	manifestUncompressedSize uint64
+	err                      error
+}
+
+// compress implements the diff.Config compressor contract. The returned
+// write closer accepts the uncompressed tar stream produced by the
+// differ and writes the zstd:chunked blob to dest.
+func (c *chunkedCompressor) compress(dest io.Writer, mediaType string) (io.WriteCloser, error) {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := c.run(dest, pr)
+		// Unblock the writing side on failure.
+		pr.CloseWithError(err)
+		c.err = err
+	}()
+	return &waitCloser{PipeWriter: pw, done: done, c: c}, nil
+}
+
+// waitCloser makes Close wait for the compression goroutine, so that
+// the blob, including its table of contents, is complete before the
+// differ commits the content.
+type waitCloser struct {
+	*io.PipeWriter
+	done chan struct{}
+	c    *chunkedCompressor
+}
+
+func (w *waitCloser) Close() error {
+	w.PipeWriter.Close()
+	<-w.done
+	return w.c.err
+}
+
+// run parses the tar stream from r while compressing it to dest,
+// closing the current zstd frame around every regular file's content
+// and recording the frame offsets in the table of contents.
+func (c *chunkedCompressor) run(dest io.Writer, r io.Reader) error {
+	cw := &countWriter{w: dest}
+	enc, err := zstd.NewWriter(cw)
+	if err != nil {
+		return err
+	}
+	defer enc.Close()
+
+	// Everything read from the tar reader passes through the tee into
+	// the current zstd frame, so the blob decompresses to the original
+	// tar byte for byte.
+	tr := tar.NewReader(io.TeeReader(r, writerFunc(func(p []byte) (int, error) {
+		return enc.Write(p)
+	})))
+
+	endFrame := func() error {
+		return enc.Close()
+	}
+	startFrame := func() {
+		enc.Reset(cw)
+	}
+
+	layerTOC := toc{Version: 1}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		entry, ok := newTOCEntry(hdr)
+		if !ok {
+			continue
+		}
+		if hdr.Typeflag == tar.TypeReg && hdr.Size > 0 {
+			// Close the frame holding the tar header and compress the
+			// file content into its own frame so it can be fetched and
+			// decompressed independently.
+			if err := endFrame(); err != nil {
+				return err
+			}
+			entry.Offset = cw.n
+			startFrame()
+			dgstr := digest.SHA256.Digester()
+			if _, err := io.Copy(dgstr.Hash(), tr); err != nil {
+				return err
+			}
+			entry.Digest = dgstr.Digest().String()
+			if err := endFrame(); err != nil {
+				return err
+			}
+			entry.EndOffset = cw.n
+			startFrame()
+		}
+		layerTOC.Entries = append(layerTOC.Entries, entry)
+	}
+	// Compress the tar trailer left behind by the tar reader.
+	if _, err := io.Copy(io.Discard, io.TeeReader(r, writerFunc(func(p []byte) (int, error) {
+		return enc.Write(p)
+	}))); err != nil {
+		return err
+	}
+	if err := endFrame(); err != nil {
+		return err
+	}
+
+	return c.writeTOC(cw, &layerTOC)
+}
+
+// writeTOC appends the compressed table of contents and the
+// zstd:chunked footer as skippable frames.
+func (c *chunkedCompressor) writeTOC(cw *countWriter, layerTOC *toc) error {
+	manifest, err := json.Marshal(layerTOC)
+	if err != nil {
+		return err
+	}
+	var compressed bytes.Buffer
+	enc, err := zstd.NewWriter(&compressed)
+	if err != nil {
+		return err
+	}
+	if _, err := enc.Write(manifest); err != nil {
+		enc.Close()
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	// The manifest offset points at the compressed manifest inside the
+	// skippable frame, past the 8 byte frame header.
+	c.manifestOffset = uint64(cw.n) + 8
+	c.manifestCompressedSize = uint64(compressed.Len())
+	c.manifestUncompressedSize = uint64(len(manifest))
+	c.manifestDigest = digest.SHA256.FromBytes(compressed.Bytes())
+
+	if err := writeSkippableFrame(cw, compressed.Bytes()); err != nil {
+		return err
+	}
+
+	footer := make([]byte, 0, 48)
+	footer = binary.LittleEndian.AppendUint64(footer, c.manifestOffset)
+	footer = binary.LittleEndian.AppendUint64(footer, c.manifestCompressedSize)
+	footer = binary.LittleEndian.AppendUint64(footer, c.manifestUncompressedSize)
+	footer = binary.LittleEndian.AppendUint64(footer, manifestTypeCRFS)
+	footer = append(footer, zstdChunkedFrameMagic...)
+	return writeSkippableFrame(cw, footer)
+}
+
+func writeSkippableFrame(w io.Writer, data []byte) error {
+	header := make([]byte, 0, 8)
+	header = binary.LittleEndian.AppendUint32(header, zstdSkippableFrameMagic)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// newTOCEntry converts a tar header into a table of contents entry.
+func newTOCEntry(hdr *tar.Header) (tocEntry, bool) {
+	var typ string
+	switch hdr.Typeflag {
+	case tar.TypeReg:
+		typ = "reg"
+	case tar.TypeDir:
+		typ = "dir"
+	case tar.TypeSymlink:
+		typ = "symlink"
+	case tar.TypeLink:
+		typ = "hardlink"
+	case tar.TypeChar:
+		typ = "char"
+	case tar.TypeBlock:
+		typ = "block"
+	case tar.TypeFifo:
+		typ = "fifo"
+	default:
+		return tocEntry{}, false
+	}
+	entry := tocEntry{
+		Type:     typ,
+		Name:     hdr.Name,
+		LinkName: hdr.Linkname,
+		Mode:     hdr.Mode,
+		UID:      hdr.Uid,
+		GID:      hdr.Gid,
+		ModTime:  hdr.ModTime.UTC().Format(time.RFC3339),
+		DevMajor: hdr.Devmajor,
+		DevMinor: hdr.Devminor,
+	}
+	if hdr.Typeflag == tar.TypeReg {
+		entry.Size = hdr.Size
+	}
+	return entry, true
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,151 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package zstdchunked
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/pkg/archive/compression"
+)
+
+func testTar(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	content := bytes.Repeat([]byte("zstd chunked content "), 1000)
+	for _, f := range []struct {
+		hdr  tar.Header
+		data []byte
+	}{
+		{hdr: tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0o755}},
+		{hdr: tar.Header{Name: "dir/file.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}, data: content},
+		{hdr: tar.Header{Name: "dir/empty", Typeflag: tar.TypeReg, Mode: 0o644}},
+		{hdr: tar.Header{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "file.txt"}},
+	} {
+		if err := tw.WriteHeader(&f.hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), content
+}
+
+func compressTar(t *testing.T, uncompressed []byte) (*chunkedCompressor, []byte) {
+	t.Helper()
+	var out bytes.Buffer
+	c := &chunkedCompressor{}
+	wc, err := c.compress(&out, ocispec.MediaTypeImageLayerZstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(wc, bytes.NewReader(uncompressed)); err != nil {
+		t.Fatal(err)
+	}
+	if err := wc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c.err != nil {
+		t.Fatal(c.err)
+	}
+	return c, out.Bytes()
+}
+
+func TestZstdChunkedRoundTrip(t *testing.T) {
+	uncompressed, _ := testTar(t)
+	_, blob := compressTar(t, uncompressed)
+
+	// The blob must stay a valid tar+zstd layer for consumers unaware
+	// of the chunked format.
+	dec, err := compression.DecompressStream(bytes.NewReader(blob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dec.Close()
+	decompressed, err := io.ReadAll(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed, uncompressed) {
+		t.Fatalf("decompressed blob does not match the original tar: %d bytes != %d bytes", len(decompressed), len(uncompressed))
+	}
+}
+
+func TestZstdChunkedTOC(t *testing.T) {
+	uncompressed, content := testTar(t)
+	c, blob := compressTar(t, uncompressed)
+
+	if c.manifestOffset == 0 || c.manifestCompressedSize == 0 {
+		t.Fatalf("manifest position not recorded: %+v", c)
+	}
+
+	// Extract the manifest from its recorded position and verify it
+	// describes the tar entries.
+	compressed := blob[c.manifestOffset : c.manifestOffset+c.manifestCompressedSize]
+	dec, err := zstd.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dec.Close()
+	manifest, err := io.ReadAll(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(len(manifest)) != c.manifestUncompressedSize {
+		t.Fatalf("unexpected manifest size %d, expected %d", len(manifest), c.manifestUncompressedSize)
+	}
+
+	var layerTOC toc
+	if err := json.Unmarshal(manifest, &layerTOC); err != nil {
+		t.Fatal(err)
+	}
+	if len(layerTOC.Entries) != 4 {
+		t.Fatalf("expected 4 TOC entries, got %d", len(layerTOC.Entries))
+	}
+	fileEntry := layerTOC.Entries[1]
+	if fileEntry.Name != "dir/file.txt" || fileEntry.Type != "reg" {
+		t.Fatalf("unexpected TOC entry %+v", fileEntry)
+	}
+	if fileEntry.Offset == 0 || fileEntry.EndOffset <= fileEntry.Offset {
+		t.Fatalf("file frame position not recorded: %+v", fileEntry)
+	}
+
+	// The file's frame must decompress on its own.
+	frame, err := zstd.NewReader(bytes.NewReader(blob[fileEntry.Offset:fileEntry.EndOffset]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer frame.Close()
+	data, err := io.ReadAll(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Fatalf("file frame does not decompress to the file content: %d bytes != %d bytes", len(data), len(content))
+	}
+}
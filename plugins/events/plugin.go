@@ -17,18 +17,53 @@
 package events
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/basuotian/containerd/core/events/exchange"
+	"github.com/basuotian/containerd/internal/tomlext"
 	"github.com/basuotian/containerd/plugins"
 	"github.com/containerd/plugin"
 	"github.com/containerd/plugin/registry"
 )
 
+// Config for the event exchange
+type Config struct {
+	// SubscriberBufferLimit bounds the number of events buffered for each
+	// subscriber. Zero leaves subscriber queues unbounded.
+	SubscriberBufferLimit int `toml:"subscriber_buffer_limit"`
+	// SlowSubscriberPolicy selects what happens to a subscriber which
+	// reaches its buffer limit: "drop-oldest", "disconnect" or
+	// "block-with-timeout".
+	SlowSubscriberPolicy string `toml:"slow_subscriber_policy"`
+	// BlockTimeout is how long publishing blocks on a full subscriber
+	// under the "block-with-timeout" policy before disconnecting it.
+	BlockTimeout tomlext.Duration `toml:"block_timeout"`
+}
+
 func init() {
 	registry.Register(&plugin.Registration{
 		Type: plugins.EventPlugin,
 		ID:   "exchange",
+		Config: &Config{
+			SlowSubscriberPolicy: string(exchange.PolicyDropOldest),
+			BlockTimeout:         tomlext.FromStdTime(time.Second),
+		},
 		InitFn: func(ic *plugin.InitContext) (interface{}, error) {
-			return exchange.NewExchange(), nil
+			config, ok := ic.Config.(*Config)
+			if !ok {
+				return nil, errors.New("invalid events exchange configuration")
+			}
+			policy := exchange.SlowSubscriberPolicy(config.SlowSubscriberPolicy)
+			if !policy.Valid() {
+				return nil, fmt.Errorf("invalid slow subscriber policy %q", config.SlowSubscriberPolicy)
+			}
+			return exchange.NewExchange(
+				exchange.WithSubscriberBufferLimit(config.SubscriberBufferLimit),
+				exchange.WithSlowSubscriberPolicy(policy),
+				exchange.WithBlockTimeout(tomlext.ToStdTime(config.BlockTimeout)),
+			), nil
 		},
 	})
 }
@@ -24,11 +24,19 @@ var (
 
 	// gcTimeHist histogram metrics for duration of gc scheduler collections.
 	gcTimeHist metrics.Timer
+
+	// sweepCycleTimeHist histogram metrics for duration of incremental gc sweep cycles.
+	sweepCycleTimeHist metrics.Timer
+
+	// reclaimedCounter metrics for counter of metadata nodes reclaimed by collections.
+	reclaimedCounter metrics.Counter
 )
 
 func init() {
 	ns := metrics.NewNamespace("containerd", "gc", nil)
 	collectionCounter = ns.NewLabeledCounter("collections", "counter of gc scheduler collections", "status")
 	gcTimeHist = ns.NewTimer("gc", "duration of gc scheduler collections")
+	sweepCycleTimeHist = ns.NewTimer("sweep_cycle", "duration of incremental gc sweep cycles")
+	reclaimedCounter = ns.NewCounter("reclaimed", "counter of metadata nodes reclaimed by gc collections")
 	metrics.Register(ns)
 }
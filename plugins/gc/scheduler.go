@@ -23,6 +23,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/basuotian/containerd/core/metadata"
 	"github.com/basuotian/containerd/internal/tomlext"
 	"github.com/basuotian/containerd/pkg/gc"
 	"github.com/basuotian/containerd/plugins"
@@ -81,6 +82,16 @@ type config struct {
 	//
 	// Default is "100ms"
 	StartupDelay tomlext.Duration `toml:"startup_delay"`
+
+	// PauseBudget bounds how long a garbage collection may block
+	// metadata writers at a time. When set, marking is done without
+	// blocking writers and the sweep is split across multiple short
+	// transactions, each holding the metadata lock for at most this
+	// duration. A zero value collects in a single transaction. Use
+	// suffix "ms" for millisecond and "s" for second.
+	//
+	// Default is "0ms"
+	PauseBudget tomlext.Duration `toml:"pause_budget"`
 }
 
 func init() {
@@ -96,6 +107,7 @@ func init() {
 			MutationThreshold: 100,
 			ScheduleDelay:     tomlext.FromStdTime(0),
 			StartupDelay:      tomlext.FromStdTime(100 * time.Millisecond),
+			PauseBudget:       tomlext.FromStdTime(0),
 		},
 		InitFn: func(ic *plugin.InitContext) (interface{}, error) {
 			md, err := ic.GetSingle(plugins.MetadataPlugin)
@@ -115,6 +127,7 @@ func init() {
 				"DeletionThreshold": fmt.Sprint(m.deletionThreshold),
 				"MutationThreshold": fmt.Sprint(m.mutationThreshold),
 				"ScheduleDelay":     fmt.Sprint(m.scheduleDelay),
+				"PauseBudget":       fmt.Sprint(m.pauseBudget),
 			}
 
 			go m.run(ic.Context)
@@ -135,6 +148,12 @@ type collector interface {
 	GarbageCollect(context.Context) (gc.Stats, error)
 }
 
+// incrementalCollector is implemented by collectors which can bound the
+// time writers are blocked during a collection.
+type incrementalCollector interface {
+	GarbageCollectIncremental(context.Context, time.Duration) (gc.Stats, error)
+}
+
 type gcScheduler struct {
 	c collector
 
@@ -148,6 +167,7 @@ type gcScheduler struct {
 	mutationThreshold int
 	scheduleDelay     time.Duration
 	startupDelay      time.Duration
+	pauseBudget       time.Duration
 }
 
 func newScheduler(c collector, cfg *config) *gcScheduler {
@@ -161,6 +181,7 @@ func newScheduler(c collector, cfg *config) *gcScheduler {
 		mutationThreshold: cfg.MutationThreshold,
 		scheduleDelay:     time.Duration(cfg.ScheduleDelay),
 		startupDelay:      time.Duration(cfg.StartupDelay),
+		pauseBudget:       time.Duration(cfg.PauseBudget),
 	}
 
 	if s.pauseThreshold < 0.0 {
@@ -178,6 +199,9 @@ func newScheduler(c collector, cfg *config) *gcScheduler {
 	if s.startupDelay < 0 {
 		s.startupDelay = 0
 	}
+	if s.pauseBudget < 0 {
+		s.pauseBudget = 0
+	}
 
 	c.RegisterMutationCallback(s.mutationCallback)
 
@@ -295,7 +319,15 @@ func (s *gcScheduler) run(ctx context.Context) {
 
 		s.waiterL.Lock()
 
-		stats, err := s.c.GarbageCollect(ctx)
+		var (
+			stats gc.Stats
+			err   error
+		)
+		if ic, ok := s.c.(incrementalCollector); ok && s.pauseBudget > 0 {
+			stats, err = ic.GarbageCollectIncremental(ctx, s.pauseBudget)
+		} else {
+			stats, err = s.c.GarbageCollect(ctx)
+		}
 		last := time.Now()
 		if err != nil {
 			log.G(ctx).WithError(err).Error("garbage collection failed")
@@ -321,6 +353,12 @@ func (s *gcScheduler) run(ctx context.Context) {
 		gcTimeSum += gcTime
 		collections++
 		collectionCounter.WithValues("success").Inc()
+		if mstats, ok := stats.(metadata.GCStats); ok {
+			reclaimedCounter.Inc(float64(mstats.Removed))
+			for _, d := range mstats.CycleD {
+				sweepCycleTimeHist.Update(d)
+			}
+		}
 		triggered = false
 		deletions = 0
 		mutations = 0
@@ -0,0 +1,230 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package mirror provides an optional pull-through cache service which
+// serves the local content store over the read-only subset of the OCI
+// distribution API. Other nodes configure the service as a hosts.toml
+// mirror so that a cluster of containerd nodes can peer-share layers;
+// tag resolution is not offered, so clients fall back to the upstream
+// registry for anything the node does not hold by digest.
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+	"github.com/containerd/plugin"
+	"github.com/containerd/plugin/registry"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/basuotian/containerd/core/content"
+	"github.com/basuotian/containerd/core/metadata"
+	"github.com/basuotian/containerd/pkg/namespaces"
+	"github.com/basuotian/containerd/plugins"
+)
+
+// Config for the registry mirror service.
+type Config struct {
+	// Address is the TCP address the distribution endpoint listens on.
+	// The service is disabled when the address is empty.
+	Address string `toml:"address"`
+	// Namespace is the containerd namespace content is served from.
+	// Defaults to the default namespace.
+	Namespace string `toml:"namespace"`
+}
+
+func init() {
+	registry.Register(&plugin.Registration{
+		Type: plugins.InternalPlugin,
+		ID:   "registry-mirror",
+		Requires: []plugin.Type{
+			plugins.MetadataPlugin,
+		},
+		Config: &Config{},
+		InitFn: func(ic *plugin.InitContext) (interface{}, error) {
+			config := ic.Config.(*Config)
+			if config.Address == "" {
+				return nil, fmt.Errorf("registry mirror not configured: %w", plugin.ErrSkipPlugin)
+			}
+			if config.Namespace == "" {
+				config.Namespace = namespaces.Default
+			}
+
+			md, err := ic.GetSingle(plugins.MetadataPlugin)
+			if err != nil {
+				return nil, err
+			}
+
+			l, err := net.Listen("tcp", config.Address)
+			if err != nil {
+				return nil, fmt.Errorf("failed to listen on %q: %w", config.Address, err)
+			}
+
+			s := &server{
+				store:     md.(*metadata.DB).ContentStore(),
+				namespace: config.Namespace,
+				listener:  l,
+			}
+			s.http = &http.Server{
+				Handler:           s,
+				ReadHeaderTimeout: 5 * time.Minute,
+			}
+			go func() {
+				if err := s.http.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.G(ic.Context).WithError(err).Error("registry mirror server failure")
+				}
+			}()
+			log.G(ic.Context).WithField("address", config.Address).Info("serving registry mirror")
+			return s, nil
+		},
+	})
+}
+
+type server struct {
+	store     content.Store
+	namespace string
+	listener  net.Listener
+	http      *http.Server
+}
+
+// Close shuts down the distribution endpoint.
+func (s *server) Close() error {
+	return s.http.Close()
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The served content store is read-only; mutating distribution
+	// requests are rejected before they are routed.
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "registry mirror is read-only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := namespaces.WithNamespace(r.Context(), s.namespace)
+
+	p := strings.TrimPrefix(r.URL.Path, "/v2/")
+	if p == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+	if p == "" {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "{}")
+		return
+	}
+
+	// Paths have the form <name>/manifests/<reference> or
+	// <name>/blobs/<digest>. The repository name is not significant for
+	// lookups since the content store is addressed by digest.
+	var (
+		i         = strings.LastIndex(p, "/manifests/")
+		manifests = true
+	)
+	if i < 0 {
+		i = strings.LastIndex(p, "/blobs/")
+		manifests = false
+	}
+	if i <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+	reference := p[i+len("/manifests/"):]
+	if !manifests {
+		reference = p[i+len("/blobs/"):]
+	}
+
+	dgst, err := digest.Parse(reference)
+	if err != nil {
+		// Tags are not resolvable from the content store; clients
+		// fall back to the upstream host for them.
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := s.serveBlob(ctx, w, r, dgst, manifests); err != nil {
+		if errdefs.IsNotFound(err) {
+			http.NotFound(w, r)
+			return
+		}
+		log.G(ctx).WithError(err).WithField("digest", dgst).Error("failed to serve content")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func (s *server) serveBlob(ctx context.Context, w http.ResponseWriter, r *http.Request, dgst digest.Digest, manifest bool) error {
+	info, err := s.store.Info(ctx, dgst)
+	if err != nil {
+		return err
+	}
+
+	mediaType := "application/octet-stream"
+	if manifest {
+		mediaType, err = s.manifestMediaType(ctx, dgst, info.Size)
+		if err != nil {
+			return err
+		}
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Docker-Content-Digest", dgst.String())
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size))
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	ra, err := s.store.ReaderAt(ctx, ocispec.Descriptor{Digest: dgst, Size: info.Size})
+	if err != nil {
+		return err
+	}
+	defer ra.Close()
+
+	http.ServeContent(w, r, "", info.UpdatedAt, io.NewSectionReader(ra, 0, ra.Size()))
+	return nil
+}
+
+// manifestMediaType determines the media type to return for a manifest
+// from its payload, as the content store does not record it.
+func (s *server) manifestMediaType(ctx context.Context, dgst digest.Digest, size int64) (string, error) {
+	const maxManifestSize = 8 << 20
+	if size > maxManifestSize {
+		return "", fmt.Errorf("manifest %v too large: %w", dgst, errdefs.ErrInvalidArgument)
+	}
+	p, err := content.ReadBlob(ctx, s.store, ocispec.Descriptor{Digest: dgst, Size: size})
+	if err != nil {
+		return "", err
+	}
+	var manifest struct {
+		MediaType string `json:"mediaType"`
+	}
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		return "", fmt.Errorf("invalid manifest %v: %w", dgst, err)
+	}
+	if manifest.MediaType == "" {
+		return ocispec.MediaTypeImageManifest, nil
+	}
+	return manifest.MediaType, nil
+}
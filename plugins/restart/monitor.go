@@ -38,6 +38,12 @@ import (
 type Config struct {
 	// Interval for how long to wait to check for state changes
 	Interval tomlext.Duration `toml:"interval"`
+	// BackoffBase is the delay before the first restart of a failed task.
+	// The delay doubles with every subsequent restart of the same container
+	// until it reaches BackoffMax.
+	BackoffBase tomlext.Duration `toml:"backoff_base"`
+	// BackoffMax caps the exponential restart backoff.
+	BackoffMax tomlext.Duration `toml:"backoff_max"`
 }
 
 func init() {
@@ -49,7 +55,9 @@ func init() {
 		},
 		ID: "restart",
 		Config: &Config{
-			Interval: tomlext.FromStdTime(10 * time.Second),
+			Interval:    tomlext.FromStdTime(10 * time.Second),
+			BackoffBase: tomlext.FromStdTime(time.Second),
+			BackoffMax:  tomlext.FromStdTime(5 * time.Minute),
 		},
 		InitFn: func(ic *plugin.InitContext) (interface{}, error) {
 			ic.Meta.Capabilities = []string{"no", "always", "on-failure", "unless-stopped"}
@@ -57,10 +65,13 @@ func init() {
 			if err != nil {
 				return nil, err
 			}
+			config := ic.Config.(*Config)
 			m := &monitor{
-				client: client,
+				client:      client,
+				backoffBase: tomlext.ToStdTime(config.BackoffBase),
+				backoffMax:  tomlext.ToStdTime(config.BackoffMax),
 			}
-			go m.run(tomlext.ToStdTime(ic.Config.(*Config).Interval))
+			go m.run(tomlext.ToStdTime(config.Interval))
 			return m, nil
 		},
 		ConfigMigration: func(ctx context.Context, configVersion int, pluginConfigs map[string]interface{}) error {
@@ -84,7 +95,32 @@ type change interface {
 }
 
 type monitor struct {
-	client *containerd.Client
+	client      *containerd.Client
+	backoffBase time.Duration
+	backoffMax  time.Duration
+}
+
+// backoffDelay returns how long to wait after the last exit before the
+// given restart attempt, doubling from backoffBase up to backoffMax.
+func (m *monitor) backoffDelay(restartCount int) time.Duration {
+	if m.backoffBase <= 0 {
+		return 0
+	}
+	max := m.backoffMax
+	if max <= 0 {
+		max = 5 * time.Minute
+	}
+	delay := m.backoffBase
+	for i := 1; i < restartCount; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
 }
 
 func (m *monitor) run(interval time.Duration) {
@@ -180,6 +216,15 @@ func (m *monitor) monitor(ctx context.Context) ([]change, error) {
 			}
 
 			restartCount, _ := strconv.Atoi(labels[restart.CountLabel])
+			if restartCount > 0 && !status.ExitTime.IsZero() {
+				if delay := m.backoffDelay(restartCount + 1); time.Since(status.ExitTime) < delay {
+					log.G(ctx).WithFields(log.Fields{
+						"container": c.ID(),
+						"delay":     delay,
+					}).Debug("delaying restart for backoff")
+					continue
+				}
+			}
 			if labels["containerd.io/restart.logpath"] != "" {
 				log.G(ctx).Warn(`Label "containerd.io/restart.logpath" is no longer supported since containerd v2.0. Use "containerd.io/restart.loguri" instead.`)
 			}
@@ -19,6 +19,6 @@
 package diff
 
 var defaultDifferConfig = &config{
-	Order:  []string{"walking"},
+	Order:  []string{"decrypt", "walking"},
 	SyncFs: false,
 }
@@ -18,6 +18,7 @@ package images
 
 import (
 	"context"
+	"errors"
 
 	"github.com/containerd/errdefs/pkg/errgrpc"
 	"github.com/containerd/log"
@@ -42,10 +43,18 @@ import (
 
 var empty = &ptypes.Empty{}
 
+// Config represents configuration for the images service plugin.
+type Config struct {
+	// ImmutableTags are image name patterns (path.Match syntax) which may
+	// not be re-pointed to a different target once created.
+	ImmutableTags []string `toml:"immutable_tags"`
+}
+
 func init() {
 	registry.Register(&plugin.Registration{
-		Type: plugins.ServicePlugin,
-		ID:   services.ImagesService,
+		Type:   plugins.ServicePlugin,
+		ID:     services.ImagesService,
+		Config: &Config{},
 		Requires: []plugin.Type{
 			plugins.MetadataPlugin,
 			plugins.GCPlugin,
@@ -65,8 +74,17 @@ func init() {
 				return nil, err
 			}
 
+			config, ok := ic.Config.(*Config)
+			if !ok {
+				return nil, errors.New("invalid images service configuration")
+			}
+			var opts []metadata.ImageStoreOpt
+			if len(config.ImmutableTags) > 0 {
+				opts = append(opts, metadata.WithImmutableTags(config.ImmutableTags...))
+			}
+
 			return &local{
-				store:    metadata.NewImageStore(m.(*metadata.DB)),
+				store:    metadata.NewImageStore(m.(*metadata.DB), opts...),
 				gc:       g.(gcScheduler),
 				warnings: w.(warning.Service),
 			}, nil
@@ -31,6 +31,7 @@ import (
 	"github.com/basuotian/containerd/core/snapshots"
 	"github.com/basuotian/containerd/core/snapshots/proxy"
 	ptypes "github.com/basuotian/containerd/pkg/protobuf/types"
+	"github.com/basuotian/containerd/pkg/tracing"
 	"github.com/basuotian/containerd/plugins"
 	"github.com/basuotian/containerd/plugins/services"
 )
@@ -46,6 +47,8 @@ func init() {
 	})
 }
 
+const spanSnapshotsPrefix = "snapshots.service"
+
 var empty = &ptypes.Empty{}
 
 type service struct {
@@ -79,6 +82,14 @@ func (s *service) Register(gs *grpc.Server) error {
 }
 
 func (s *service) Prepare(ctx context.Context, pr *snapshotsapi.PrepareSnapshotRequest) (*snapshotsapi.PrepareSnapshotResponse, error) {
+	ctx, span := tracing.StartSpan(ctx,
+		tracing.Name(spanSnapshotsPrefix, "Prepare"),
+		tracing.WithAttribute("snapshot.key", pr.Key),
+		tracing.WithAttribute("snapshot.parent", pr.Parent),
+		tracing.WithAttribute("snapshot.snapshotter", pr.Snapshotter),
+		tracing.WithBaggageAttributes(ctx),
+	)
+	defer span.End()
 	log.G(ctx).WithFields(log.Fields{"parent": pr.Parent, "key": pr.Key, "snapshotter": pr.Snapshotter}).Debugf("prepare snapshot")
 	sn, err := s.getSnapshotter(pr.Snapshotter)
 	if err != nil {
@@ -0,0 +1,68 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/containerd/errdefs"
+	"github.com/containerd/log"
+
+	"github.com/basuotian/containerd/core/runtime"
+	"github.com/basuotian/containerd/pkg/namespaces"
+)
+
+// PluginInfo answers introspection queries against the tasks service. The
+// only supported request is runtime.ListAllTasksRequest, which returns the
+// tasks of every namespace with their namespace populated so admin tooling
+// does not have to iterate namespaces client-side. The query has to be
+// enabled with allow_cross_namespace_list in the service configuration.
+func (l *local) PluginInfo(ctx context.Context, request interface{}) (interface{}, error) {
+	switch request.(type) {
+	case *runtime.ListAllTasksRequest:
+		if !l.allowCrossNamespaceList {
+			return nil, fmt.Errorf("cross namespace task listing is not enabled: %w", errdefs.ErrFailedPrecondition)
+		}
+		tasks, err := l.v2Runtime.Tasks(ctx, true)
+		if err != nil {
+			return nil, err
+		}
+		resp := &runtime.ListAllTasksResponse{}
+		for _, t := range tasks {
+			// State queries go through the shim and need the task's own
+			// namespace on the context.
+			nsctx := namespaces.WithNamespace(ctx, t.Namespace())
+			p, err := getProcessState(nsctx, t)
+			if err != nil {
+				if !errdefs.IsNotFound(err) { // handle race with deletion
+					log.G(ctx).WithError(err).WithField("id", t.ID()).Error("converting task to protobuf")
+				}
+				continue
+			}
+			resp.Tasks = append(resp.Tasks, runtime.TaskSummary{
+				Namespace:  t.Namespace(),
+				ID:         p.ID,
+				Pid:        p.Pid,
+				Status:     p.Status.String(),
+				ExitStatus: p.ExitStatus,
+			})
+		}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("request type %T: %w", request, errdefs.ErrNotImplemented)
+}
@@ -49,14 +49,19 @@ import (
 	"github.com/basuotian/containerd/core/metadata"
 	"github.com/basuotian/containerd/core/mount"
 	"github.com/basuotian/containerd/core/runtime"
+	"github.com/basuotian/containerd/core/streaming"
 	"github.com/basuotian/containerd/pkg/archive"
 	"github.com/basuotian/containerd/pkg/blockio"
+	"github.com/basuotian/containerd/pkg/criu"
 	"github.com/basuotian/containerd/pkg/filters"
+	"github.com/basuotian/containerd/pkg/namespaces"
 	"github.com/basuotian/containerd/pkg/protobuf"
 	"github.com/basuotian/containerd/pkg/protobuf/proto"
 	ptypes "github.com/basuotian/containerd/pkg/protobuf/types"
 	"github.com/basuotian/containerd/pkg/rdt"
+	"github.com/basuotian/containerd/pkg/startlatency"
 	"github.com/basuotian/containerd/pkg/timeout"
+	"github.com/basuotian/containerd/pkg/tracing"
 	"github.com/basuotian/containerd/plugins"
 	"github.com/basuotian/containerd/plugins/services"
 )
@@ -69,11 +74,14 @@ var (
 		plugins.RuntimePluginV2,
 		plugins.MetadataPlugin,
 		plugins.TaskMonitorPlugin,
+		plugins.StreamingPlugin,
 	}
 )
 
 const (
 	stateTimeout = "io.containerd.timeout.task.state"
+
+	spanTasksPrefix = "tasks.local"
 )
 
 // Config for the tasks service plugin
@@ -82,6 +90,10 @@ type Config struct {
 	BlockIOConfigFile string `toml:"blockio_config_file" json:"blockioConfigFile"`
 	// RdtConfigFile specifies the path to RDT configuration file
 	RdtConfigFile string `toml:"rdt_config_file" json:"rdtConfigFile"`
+	// AllowCrossNamespaceList enables the introspection query that lists
+	// the tasks of every namespace in one call, for node-level tooling.
+	// Disabled by default.
+	AllowCrossNamespaceList bool `toml:"allow_cross_namespace_list" json:"allowCrossNamespaceList"`
 }
 
 func init() {
@@ -122,6 +134,11 @@ func initFunc(ic *plugin.InitContext) (interface{}, error) {
 		monitor = runtime.NewNoopMonitor()
 	}
 
+	sp, err := ic.GetByID(plugins.StreamingPlugin, "manager")
+	if err != nil {
+		return nil, err
+	}
+
 	db := m.(*metadata.DB)
 	l := &local{
 		containers: metadata.NewContainerStore(db),
@@ -129,6 +146,9 @@ func initFunc(ic *plugin.InitContext) (interface{}, error) {
 		publisher:  ep.(events.Publisher),
 		monitor:    monitor.(runtime.TaskMonitor),
 		v2Runtime:  v2r.(runtime.PlatformRuntime),
+		streams:    sp.(streaming.StreamManager),
+
+		allowCrossNamespaceList: config.AllowCrossNamespaceList,
 	}
 
 	v2Tasks, err := l.v2Runtime.Tasks(ic.Context, true)
@@ -156,9 +176,18 @@ type local struct {
 
 	monitor   runtime.TaskMonitor
 	v2Runtime runtime.PlatformRuntime
+	streams   streaming.StreamManager
+
+	allowCrossNamespaceList bool
 }
 
 func (l *local) Create(ctx context.Context, r *api.CreateTaskRequest, _ ...grpc.CallOption) (*api.CreateTaskResponse, error) {
+	ctx, span := tracing.StartSpan(ctx,
+		tracing.Name(spanTasksPrefix, "Create"),
+		tracing.WithAttribute("task.container.id", r.ContainerID),
+		tracing.WithBaggageAttributes(ctx),
+	)
+	defer span.End()
 	container, err := l.getContainer(ctx, r.ContainerID)
 	if err != nil {
 		return nil, errgrpc.ToGRPC(err)
@@ -215,14 +244,22 @@ func (l *local) Create(ctx context.Context, r *api.CreateTaskRequest, _ ...grpc.
 		}
 	}
 
+	taskIO := runtime.IO{
+		Stdin:    r.Stdin,
+		Stdout:   r.Stdout,
+		Stderr:   r.Stderr,
+		Terminal: r.Terminal,
+	}
+	if isStreamingIO(taskIO) {
+		taskIO, err = l.forwardStreamingIO(ctx, r.ContainerID, taskIO)
+		if err != nil {
+			return nil, errgrpc.ToGRPC(err)
+		}
+	}
+
 	opts := runtime.CreateOpts{
-		Spec: container.Spec,
-		IO: runtime.IO{
-			Stdin:    r.Stdin,
-			Stdout:   r.Stdout,
-			Stderr:   r.Stderr,
-			Terminal: r.Terminal,
-		},
+		Spec:            container.Spec,
+		IO:              taskIO,
 		Checkpoint:      checkpointPath,
 		RestoreFromPath: restoreFromPath,
 		Runtime:         container.Runtime.Name,
@@ -272,6 +309,12 @@ func (l *local) Create(ctx context.Context, r *api.CreateTaskRequest, _ ...grpc.
 }
 
 func (l *local) Start(ctx context.Context, r *api.StartRequest, _ ...grpc.CallOption) (*api.StartResponse, error) {
+	ctx, span := tracing.StartSpan(ctx,
+		tracing.Name(spanTasksPrefix, "Start"),
+		tracing.WithAttribute("task.container.id", r.ContainerID),
+		tracing.WithBaggageAttributes(ctx),
+	)
+	defer span.End()
 	t, err := l.getTask(ctx, r.ContainerID)
 	if err != nil {
 		return nil, err
@@ -282,9 +325,13 @@ func (l *local) Start(ctx context.Context, r *api.StartRequest, _ ...grpc.CallOp
 			return nil, errgrpc.ToGRPC(err)
 		}
 	}
+	startTime := time.Now()
 	if err := p.Start(ctx); err != nil {
 		return nil, errgrpc.ToGRPC(err)
 	}
+	if r.ExecID == "" {
+		l.publishStartLatency(ctx, r.ContainerID, time.Since(startTime))
+	}
 	state, err := p.State(ctx)
 	if err != nil {
 		return nil, errgrpc.ToGRPC(err)
@@ -294,6 +341,24 @@ func (l *local) Start(ctx context.Context, r *api.StartRequest, _ ...grpc.CallOp
 	}, nil
 }
 
+// publishStartLatency folds the runtime start duration into the stage
+// breakdown collected since task create and publishes the combined
+// event. Failing to publish does not fail the start.
+func (l *local) publishStartLatency(ctx context.Context, id string, startDuration time.Duration) {
+	ns, err := namespaces.NamespaceRequired(ctx)
+	if err != nil {
+		return
+	}
+	startlatency.Record(ns, id, startlatency.StageRuntimeStart, startDuration)
+	e := startlatency.Finish(ns, id)
+	if e == nil {
+		return
+	}
+	if err := l.publisher.Publish(ctx, runtime.TaskStartLatencyEventTopic, e); err != nil {
+		log.G(ctx).WithError(err).WithField("id", id).Warn("failed to publish task start latency event")
+	}
+}
+
 func (l *local) Delete(ctx context.Context, r *api.DeleteTaskRequest, _ ...grpc.CallOption) (*api.DeleteResponse, error) {
 	container, err := l.getContainer(ctx, r.ContainerID)
 	if err != nil {
@@ -315,6 +380,11 @@ func (l *local) Delete(ctx context.Context, r *api.DeleteTaskRequest, _ ...grpc.
 		return nil, errgrpc.ToGRPC(err)
 	}
 
+	// Drop any start stages recorded for a task deleted without starting.
+	if ns, err := namespaces.NamespaceRequired(ctx); err == nil {
+		startlatency.Discard(ns, r.ContainerID)
+	}
+
 	return &api.DeleteResponse{
 		ExitStatus: exit.Status,
 		ExitedAt:   protobuf.ToTimestamp(exit.Timestamp),
@@ -502,14 +572,21 @@ func (l *local) Exec(ctx context.Context, r *api.ExecProcessRequest, _ ...grpc.C
 	if err != nil {
 		return nil, err
 	}
+	execIO := runtime.IO{
+		Stdin:    r.Stdin,
+		Stdout:   r.Stdout,
+		Stderr:   r.Stderr,
+		Terminal: r.Terminal,
+	}
+	if isStreamingIO(execIO) {
+		execIO, err = l.forwardStreamingIO(ctx, r.ContainerID+"-"+r.ExecID, execIO)
+		if err != nil {
+			return nil, errgrpc.ToGRPC(err)
+		}
+	}
 	if _, err := t.Exec(ctx, r.ExecID, runtime.ExecOpts{
 		Spec: r.Spec,
-		IO: runtime.IO{
-			Stdin:    r.Stdin,
-			Stdout:   r.Stdout,
-			Stderr:   r.Stderr,
-			Terminal: r.Terminal,
-		},
+		IO:   execIO,
 	}); err != nil {
 		return nil, errgrpc.ToGRPC(err)
 	}
@@ -744,18 +821,18 @@ func getCheckpointPath(runtime string, option *ptypes.Any) (string, error) {
 		return "", nil
 	}
 
-	var checkpointPath string
 	v, err := typeurl.UnmarshalAny(option)
 	if err != nil {
 		return "", err
 	}
-	opts, ok := v.(*options.CheckpointOptions)
-	if !ok {
+	switch opts := v.(type) {
+	case *options.CheckpointOptions:
+		return opts.ImagePath, nil
+	case *criu.CheckpointOptions:
+		return opts.ImagePath, nil
+	default:
 		return "", fmt.Errorf("invalid task checkpoint option for %s", runtime)
 	}
-	checkpointPath = opts.ImagePath
-
-	return checkpointPath, nil
 }
 
 func formatOptions(runtime string, option *ptypes.Any) (*options.Options, error) {
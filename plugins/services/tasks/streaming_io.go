@@ -0,0 +1,151 @@
+//go:build !windows
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/containerd/fifo"
+	"github.com/containerd/log"
+
+	"github.com/basuotian/containerd/core/runtime"
+	transferstreaming "github.com/basuotian/containerd/core/transfer/streaming"
+	"github.com/basuotian/containerd/pkg/cio"
+	"github.com/basuotian/containerd/pkg/namespaces"
+)
+
+const streamingIOPrefix = cio.StreamingURIScheme + "://"
+
+// isStreamingIO reports whether any of the IO paths references a stream
+// of the streaming service instead of a fifo.
+func isStreamingIO(rio runtime.IO) bool {
+	return strings.HasPrefix(rio.Stdin, streamingIOPrefix) ||
+		strings.HasPrefix(rio.Stdout, streamingIOPrefix) ||
+		strings.HasPrefix(rio.Stderr, streamingIOPrefix)
+}
+
+// forwardStreamingIO bridges streams the client registered with the
+// streaming service to local fifos the shim can open, and returns the
+// rewritten IO configuration. Remote clients cannot reach fifos on the
+// daemon host, so the daemon pumps the data between both worlds itself.
+// The fifos are removed once the task closes its ends and the copies
+// drain.
+func (l *local) forwardStreamingIO(ctx context.Context, id string, rio runtime.IO) (runtime.IO, error) {
+	if l.streams == nil {
+		return runtime.IO{}, fmt.Errorf("streaming service is not available")
+	}
+	ns, err := namespaces.NamespaceRequired(ctx)
+	if err != nil {
+		return runtime.IO{}, err
+	}
+	// The pumps outlive the request, only namespace and logger carry over.
+	bctx, cancel := context.WithCancel(namespaces.WithNamespace(context.Background(), ns))
+	bctx = log.WithLogger(bctx, log.G(ctx))
+
+	fifos, err := cio.NewFIFOSetInDir("", id, rio.Terminal)
+	if err != nil {
+		cancel()
+		return runtime.IO{}, err
+	}
+
+	out := runtime.IO{Terminal: rio.Terminal}
+	var wg sync.WaitGroup
+	ok := false
+	defer func() {
+		if !ok {
+			cancel()
+			fifos.Close()
+		}
+	}()
+
+	if strings.HasPrefix(rio.Stdin, streamingIOPrefix) {
+		stream, err := l.streams.Get(ctx, strings.TrimPrefix(rio.Stdin, streamingIOPrefix))
+		if err != nil {
+			return runtime.IO{}, fmt.Errorf("failed to get stdin stream: %w", err)
+		}
+		w, err := fifo.OpenFifo(bctx, fifos.Stdin, syscall.O_WRONLY|syscall.O_CREAT|syscall.O_NONBLOCK, 0700)
+		if err != nil {
+			stream.Close()
+			return runtime.IO{}, fmt.Errorf("failed to open stdin fifo: %w", err)
+		}
+		r := transferstreaming.ReceiveStream(bctx, stream)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			io.Copy(w, r)
+			// Propagate the stdin EOF of the client to the task.
+			w.Close()
+		}()
+		out.Stdin = fifos.Stdin
+	}
+	if strings.HasPrefix(rio.Stdout, streamingIOPrefix) {
+		stream, err := l.streams.Get(ctx, strings.TrimPrefix(rio.Stdout, streamingIOPrefix))
+		if err != nil {
+			return runtime.IO{}, fmt.Errorf("failed to get stdout stream: %w", err)
+		}
+		r, err := fifo.OpenFifo(bctx, fifos.Stdout, syscall.O_RDONLY|syscall.O_CREAT|syscall.O_NONBLOCK, 0700)
+		if err != nil {
+			stream.Close()
+			return runtime.IO{}, fmt.Errorf("failed to open stdout fifo: %w", err)
+		}
+		w := transferstreaming.WriteByteStream(bctx, stream)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			io.Copy(w, r)
+			r.Close()
+			// Closing the stream signals EOF to the client.
+			w.Close()
+		}()
+		out.Stdout = fifos.Stdout
+	}
+	if !rio.Terminal && strings.HasPrefix(rio.Stderr, streamingIOPrefix) {
+		stream, err := l.streams.Get(ctx, strings.TrimPrefix(rio.Stderr, streamingIOPrefix))
+		if err != nil {
+			return runtime.IO{}, fmt.Errorf("failed to get stderr stream: %w", err)
+		}
+		r, err := fifo.OpenFifo(bctx, fifos.Stderr, syscall.O_RDONLY|syscall.O_CREAT|syscall.O_NONBLOCK, 0700)
+		if err != nil {
+			stream.Close()
+			return runtime.IO{}, fmt.Errorf("failed to open stderr fifo: %w", err)
+		}
+		w := transferstreaming.WriteByteStream(bctx, stream)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			io.Copy(w, r)
+			r.Close()
+			w.Close()
+		}()
+		out.Stderr = fifos.Stderr
+	}
+
+	ok = true
+	go func() {
+		wg.Wait()
+		cancel()
+		fifos.Close()
+	}()
+	return out, nil
+}
@@ -0,0 +1,42 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/containerd/errdefs"
+
+	"github.com/basuotian/containerd/core/runtime"
+	"github.com/basuotian/containerd/pkg/cio"
+)
+
+const streamingIOPrefix = cio.StreamingURIScheme + "://"
+
+// isStreamingIO reports whether any of the IO paths references a stream
+// of the streaming service instead of a pipe.
+func isStreamingIO(rio runtime.IO) bool {
+	return strings.HasPrefix(rio.Stdin, streamingIOPrefix) ||
+		strings.HasPrefix(rio.Stdout, streamingIOPrefix) ||
+		strings.HasPrefix(rio.Stderr, streamingIOPrefix)
+}
+
+func (l *local) forwardStreamingIO(ctx context.Context, id string, rio runtime.IO) (runtime.IO, error) {
+	return runtime.IO{}, fmt.Errorf("streaming task IO is not supported on windows: %w", errdefs.ErrNotImplemented)
+}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/containerd/btrfs/v2"
@@ -35,17 +36,53 @@ import (
 	"github.com/containerd/plugin"
 )
 
+// LimitSizeLabel is a label on a snapshot limiting how many bytes the
+// snapshot may reference. The limit is enforced with a btrfs qgroup
+// limit and requires qgroup management to be enabled on the
+// snapshotter.
+const LimitSizeLabel = "containerd.io/snapshot/btrfs/limit.sizebytes"
+
+// SnapshotterConfig holds the configurable properties of the snapshotter.
+type SnapshotterConfig struct {
+	enableQgroups bool
+}
+
+// Opt is an option to configure the btrfs snapshotter
+type Opt func(config *SnapshotterConfig)
+
+// WithQgroups enables btrfs qgroup management. Snapshot usage is then
+// taken from qgroup accounting and snapshots may be size limited with
+// the LimitSizeLabel label.
+func WithQgroups() Opt {
+	return func(config *SnapshotterConfig) {
+		config.enableQgroups = true
+	}
+}
+
+// WithSizeLimit returns a snapshot option limiting the snapshot to size
+// bytes via the LimitSizeLabel label.
+func WithSizeLimit(size uint64) snapshots.Opt {
+	return snapshots.WithLabels(map[string]string{
+		LimitSizeLabel: strconv.FormatUint(size, 10),
+	})
+}
+
 type snapshotter struct {
-	device string // device of the root
-	root   string // root provides paths for internal storage.
-	ms     *storage.MetaStore
+	device  string // device of the root
+	root    string // root provides paths for internal storage.
+	ms      *storage.MetaStore
+	qgroups bool // use qgroups for usage accounting and size limits
 }
 
 // NewSnapshotter returns a Snapshotter using btrfs. Uses the provided
 // root directory for snapshots and stores the metadata in
 // a file in the provided root.
 // root needs to be a mount point of btrfs.
-func NewSnapshotter(root string) (snapshots.Snapshotter, error) {
+func NewSnapshotter(root string, opts ...Opt) (snapshots.Snapshotter, error) {
+	var config SnapshotterConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
 	// If directory does not exist, create it
 	if st, err := os.Stat(root); err != nil {
 		if !os.IsNotExist(err) {
@@ -82,15 +119,22 @@ func NewSnapshotter(root string) (snapshots.Snapshotter, error) {
 			return nil, err
 		}
 	}
+	if config.enableQgroups {
+		if err := enableQuota(root); err != nil {
+			return nil, err
+		}
+	}
+
 	ms, err := storage.NewMetaStore(filepath.Join(root, "metadata.db"))
 	if err != nil {
 		return nil, err
 	}
 
 	return &snapshotter{
-		device: mnt.Source,
-		root:   root,
-		ms:     ms,
+		device:  mnt.Source,
+		root:    root,
+		ms:      ms,
+		qgroups: config.enableQgroups,
 	}, nil
 }
 
@@ -149,6 +193,20 @@ func (b *snapshotter) usage(ctx context.Context, key string) (usage snapshots.Us
 		return snapshots.Usage{}, err
 	}
 
+	if b.qgroups {
+		p := filepath.Join(b.root, strings.ToLower(info.Kind.String()), id)
+		if info.Kind == snapshots.KindCommitted {
+			p = filepath.Join(b.root, "snapshots", id)
+		}
+		qu, err := qgroupSnapshotUsage(p)
+		if err == nil {
+			return qu, nil
+		}
+		// Fall back to walking the filesystem, e.g. while a qgroup
+		// rescan is still pending for pre-existing snapshots.
+		log.G(ctx).WithError(err).WithField("key", key).Debug("qgroup usage unavailable")
+	}
+
 	if info.Kind == snapshots.KindActive {
 		var du fs.Usage
 		p := filepath.Join(b.root, "active", id)
@@ -189,6 +247,23 @@ func (b *snapshotter) makeSnapshot(ctx context.Context, kind snapshots.Kind, key
 		s      storage.Snapshot
 	)
 
+	var snapshotInfo snapshots.Info
+	for _, o := range opts {
+		if err := o(&snapshotInfo); err != nil {
+			return nil, err
+		}
+	}
+	var sizeLimit uint64
+	if v, ok := snapshotInfo.Labels[LimitSizeLabel]; ok {
+		if !b.qgroups {
+			return nil, fmt.Errorf("label %q requires qgroups to be enabled on the btrfs snapshotter", LimitSizeLabel)
+		}
+		sizeLimit, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse label %q=%q: %w", LimitSizeLabel, v, err)
+		}
+	}
+
 	err = b.ms.WithTransaction(ctx, true, func(ctx context.Context) error {
 		s, err = storage.CreateSnapshot(ctx, kind, key, parent, opts...)
 		if err != nil {
@@ -200,13 +275,23 @@ func (b *snapshotter) makeSnapshot(ctx context.Context, kind snapshots.Kind, key
 		if len(s.ParentIDs) == 0 {
 			// create new subvolume
 			// btrfs subvolume create /dir
-			return btrfs.SubvolCreate(target)
+			if err := btrfs.SubvolCreate(target); err != nil {
+				return err
+			}
+		} else {
+			parentp := filepath.Join(b.root, "snapshots", s.ParentIDs[0])
+
+			// btrfs subvolume snapshot /parent /subvol
+			readOnly := kind == snapshots.KindView
+			if err := btrfs.SubvolSnapshot(target, parentp, readOnly); err != nil {
+				return err
+			}
 		}
-		parentp := filepath.Join(b.root, "snapshots", s.ParentIDs[0])
 
-		// btrfs subvolume snapshot /parent /subvol
-		readOnly := kind == snapshots.KindView
-		return btrfs.SubvolSnapshot(target, parentp, readOnly)
+		if sizeLimit > 0 {
+			return setQgroupLimit(target, sizeLimit)
+		}
+		return nil
 	})
 
 	if err != nil {
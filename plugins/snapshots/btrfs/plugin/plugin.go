@@ -23,6 +23,7 @@ import (
 
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
+	"github.com/basuotian/containerd/core/snapshots"
 	"github.com/basuotian/containerd/plugins"
 	"github.com/basuotian/containerd/plugins/snapshots/btrfs"
 	"github.com/containerd/platforms"
@@ -34,6 +35,10 @@ import (
 type Config struct {
 	// Root directory for the plugin
 	RootPath string `toml:"root_path"`
+	// EnableQgroups enables btrfs qgroup management. Snapshot usage is
+	// then reported from qgroup accounting and snapshots may be size
+	// limited via the btrfs.LimitSizeLabel label.
+	EnableQgroups bool `toml:"enable_qgroups"`
 }
 
 func init() {
@@ -54,8 +59,17 @@ func init() {
 				root = config.RootPath
 			}
 
+			var opts []btrfs.Opt
+			// Subvolume snapshots are constant time regardless of size.
+			ic.Meta.Capabilities = append(ic.Meta.Capabilities, snapshots.CapabilityCheapClones)
+			if config.EnableQgroups {
+				opts = append(opts, btrfs.WithQgroups())
+				// Qgroup accounting reports exact per-snapshot usage.
+				ic.Meta.Capabilities = append(ic.Meta.Capabilities, snapshots.CapabilityAccurateUsage)
+			}
+
 			ic.Meta.Exports[plugins.SnapshotterRootDir] = root
-			return btrfs.NewSnapshotter(root)
+			return btrfs.NewSnapshotter(root, opts...)
 		},
 	})
 }
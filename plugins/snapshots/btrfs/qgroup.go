@@ -0,0 +1,145 @@
+//go:build linux && !no_btrfs && cgo
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package btrfs
+
+/*
+#include <linux/btrfs.h>
+#include <linux/btrfs_tree.h>
+*/
+import "C"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/containerd/btrfs/v2"
+	"github.com/containerd/errdefs"
+	"golang.org/x/sys/unix"
+
+	"github.com/basuotian/containerd/core/snapshots"
+)
+
+// qgroupUsage is the accounting the btrfs quota machinery keeps for a
+// level-0 qgroup, i.e. a single subvolume.
+type qgroupUsage struct {
+	// referenced is the total number of bytes reachable from the
+	// subvolume, including extents shared with other snapshots.
+	referenced uint64
+	// exclusive is the number of bytes only reachable from the
+	// subvolume, which is what deleting it would free.
+	exclusive uint64
+}
+
+// enableQuota enables btrfs quota (qgroup) accounting on the filesystem
+// containing path. Enabling is idempotent; the kernel rescans existing
+// subvolumes in the background, so accounting for pre-existing snapshots
+// becomes accurate once the rescan finishes.
+func enableQuota(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var args C.struct_btrfs_ioctl_quota_ctl_args
+	args.cmd = C.BTRFS_QUOTA_CTL_ENABLE
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), C.BTRFS_IOC_QUOTA_CTL, uintptr(unsafe.Pointer(&args))); errno != 0 && errno != unix.EEXIST {
+		return fmt.Errorf("failed to enable btrfs qgroups on %v: %w", path, errno)
+	}
+	return nil
+}
+
+// setQgroupLimit limits the referenced bytes of the subvolume at path,
+// making writes beyond size fail with ENOSPC/EDQUOT. A size of zero
+// removes the limit.
+func setQgroupLimit(path string, size uint64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var args C.struct_btrfs_ioctl_qgroup_limit_args
+	// qgroupid zero applies the limit to the subvolume the fd refers to.
+	args.lim.max_rfer = C.__u64(size)
+	args.lim.flags = C.BTRFS_QGROUP_LIMIT_MAX_RFER
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), C.BTRFS_IOC_QGROUP_LIMIT, uintptr(unsafe.Pointer(&args))); errno != 0 {
+		return fmt.Errorf("failed to limit qgroup of %v to %d bytes: %w", path, size, errno)
+	}
+	return nil
+}
+
+// subvolQgroupUsage looks up the qgroup accounting of the subvolume at
+// path by searching the quota tree for its level-0 qgroup info item.
+func subvolQgroupUsage(path string) (qgroupUsage, error) {
+	id, err := btrfs.SubvolID(path)
+	if err != nil {
+		return qgroupUsage{}, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return qgroupUsage{}, err
+	}
+	defer f.Close()
+
+	var args C.struct_btrfs_ioctl_search_args
+	args.key.tree_id = C.BTRFS_QUOTA_TREE_OBJECTID
+	args.key.min_type = C.BTRFS_QGROUP_INFO_KEY
+	args.key.max_type = C.BTRFS_QGROUP_INFO_KEY
+	// Level-0 qgroup info items are keyed (0, BTRFS_QGROUP_INFO_KEY, subvolid).
+	args.key.min_offset = C.__u64(id)
+	args.key.max_offset = C.__u64(id)
+	args.key.max_transid = ^C.__u64(0)
+	args.key.nr_items = 1
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), C.BTRFS_IOC_TREE_SEARCH, uintptr(unsafe.Pointer(&args))); errno != 0 {
+		return qgroupUsage{}, fmt.Errorf("failed to search qgroup info for %v: %w", path, errno)
+	}
+	if args.key.nr_items == 0 {
+		return qgroupUsage{}, fmt.Errorf("no qgroup info for subvolume %v, qgroups not enabled or rescan pending: %w", path, errdefs.ErrNotFound)
+	}
+
+	// The result buffer holds a search header followed by a
+	// btrfs_qgroup_info_item: generation, rfer, rfer_cmpr, excl and
+	// excl_cmpr, all little-endian u64.
+	const (
+		headerSize = C.sizeof_struct_btrfs_ioctl_search_header
+		itemSize   = 5 * 8
+	)
+	buf := C.GoBytes(unsafe.Pointer(&args.buf[0]), headerSize+itemSize)
+	item := buf[headerSize:]
+	return qgroupUsage{
+		referenced: binary.LittleEndian.Uint64(item[8:16]),
+		exclusive:  binary.LittleEndian.Uint64(item[24:32]),
+	}, nil
+}
+
+// qgroupSnapshotUsage converts the qgroup accounting of the subvolume at
+// path to the snapshot usage reported to callers. Size is the exclusive
+// byte count, matching what removing the snapshot would reclaim; inodes
+// are not tracked by qgroups.
+func qgroupSnapshotUsage(path string) (snapshots.Usage, error) {
+	qu, err := subvolQgroupUsage(path)
+	if err != nil {
+		return snapshots.Usage{}, err
+	}
+	return snapshots.Usage{Size: int64(qu.exclusive)}, nil
+}
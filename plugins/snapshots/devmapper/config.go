@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/docker/go-units"
 	"github.com/pelletier/go-toml/v2"
@@ -51,6 +52,26 @@ type Config struct {
 
 	// Defines optional file system options passed through config file
 	FsOptions string `toml:"fs_options"`
+
+	// MaintenanceInterval defines how often the background maintenance
+	// loop runs to discard removed thin devices in batches and check
+	// thin-pool usage. The string is in the golang duration format.
+	// Empty or zero disables the maintenance loop.
+	MaintenanceInterval         string        `toml:"maintenance_interval"`
+	MaintenanceIntervalDuration time.Duration `toml:"-"`
+
+	// DiscardBatchSize limits how many removed thin devices are discarded
+	// per maintenance run so that discards don't monopolize the pool.
+	// Defaults to 8 when the maintenance loop is enabled.
+	DiscardBatchSize int `toml:"discard_batch_size"`
+
+	// DataWatermarkPercent is the thin-pool data usage percentage above
+	// which a pool usage alert is emitted. Zero disables the alert.
+	DataWatermarkPercent int `toml:"data_watermark_percent"`
+
+	// MetadataWatermarkPercent is the thin-pool metadata usage percentage
+	// above which a pool usage alert is emitted. Zero disables the alert.
+	MetadataWatermarkPercent int `toml:"metadata_watermark_percent"`
 }
 
 // LoadConfig reads devmapper configuration file from disk in TOML format
@@ -92,6 +113,18 @@ func (c *Config) parse() error {
 		c.FileSystemType = fsTypeExt4
 	}
 
+	if c.MaintenanceInterval != "" {
+		interval, err := time.ParseDuration(c.MaintenanceInterval)
+		if err != nil {
+			return fmt.Errorf("failed to parse maintenance interval: '%s': %w", c.MaintenanceInterval, err)
+		}
+		c.MaintenanceIntervalDuration = interval
+	}
+
+	if c.DiscardBatchSize == 0 {
+		c.DiscardBatchSize = defaultDiscardBatchSize
+	}
+
 	c.BaseImageSizeBytes = uint64(baseImageSize)
 	return nil
 }
@@ -122,5 +155,25 @@ func (c *Config) Validate() error {
 		result = append(result, fmt.Errorf("filesystem type cannot be empty"))
 	}
 
+	if c.MaintenanceIntervalDuration < 0 {
+		result = append(result, fmt.Errorf("maintenance_interval must not be negative"))
+	}
+
+	if c.DiscardBatchSize < 0 {
+		result = append(result, fmt.Errorf("discard_batch_size must not be negative"))
+	}
+
+	for _, watermark := range []struct {
+		name  string
+		value int
+	}{
+		{"data_watermark_percent", c.DataWatermarkPercent},
+		{"metadata_watermark_percent", c.MetadataWatermarkPercent},
+	} {
+		if watermark.value < 0 || watermark.value > 100 {
+			result = append(result, fmt.Errorf("%s must be a percentage between 0 and 100", watermark.name))
+		}
+	}
+
 	return errors.Join(result...)
 }
@@ -0,0 +1,160 @@
+//go:build linux
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package devmapper
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/containerd/log"
+	"github.com/containerd/typeurl/v2"
+	metrics "github.com/docker/go-metrics"
+)
+
+// defaultDiscardBatchSize limits how many removed devices are discarded
+// per maintenance run when discard_batch_size is not configured.
+const defaultDiscardBatchSize = 8
+
+// PoolUsageEventTopic is the topic of events published when thin-pool
+// usage crosses a configured watermark.
+const PoolUsageEventTopic = "/snapshot/devmapper/pool-usage"
+
+func init() {
+	typeurl.Register(&PoolUsageAlert{}, "containerd.event.devmapper.PoolUsageAlert")
+}
+
+// PoolUsageAlert is published on PoolUsageEventTopic when the thin-pool
+// data or metadata usage crosses one of the configured watermarks, so
+// operators are warned before the pool exhausts.
+type PoolUsageAlert struct {
+	PoolName                 string  `json:"pool_name"`
+	DataPercent              float64 `json:"data_percent"`
+	MetadataPercent          float64 `json:"metadata_percent"`
+	DataWatermarkPercent     int     `json:"data_watermark_percent,omitempty"`
+	MetadataWatermarkPercent int     `json:"metadata_watermark_percent,omitempty"`
+}
+
+var (
+	dataUsagePercent     metrics.Gauge
+	metadataUsagePercent metrics.Gauge
+	discardedDevices     metrics.Counter
+)
+
+func init() {
+	ns := metrics.NewNamespace("containerd", "devmapper", nil)
+
+	dataUsagePercent = ns.NewGauge("pool_data_usage", "Percentage of used thin-pool data blocks", metrics.Total)
+	metadataUsagePercent = ns.NewGauge("pool_metadata_usage", "Percentage of used thin-pool metadata blocks", metrics.Total)
+	discardedDevices = ns.NewCounter("discarded_devices", "Number of removed thin devices discarded by the maintenance loop")
+
+	metrics.Register(ns)
+}
+
+// runMaintenance periodically discards removed thin devices in batches
+// and checks thin-pool usage against the configured watermarks until the
+// context is cancelled.
+func (s *Snapshotter) runMaintenance(ctx context.Context) {
+	interval := s.config.MaintenanceIntervalDuration
+	log.G(ctx).WithField("interval", interval).Debug("starting devmapper maintenance loop")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.G(ctx).Debug("devmapper maintenance loop stopped")
+			return
+		case <-ticker.C:
+			if s.config.AsyncRemove {
+				if err := s.discardRemoved(ctx, s.config.DiscardBatchSize); err != nil {
+					log.G(ctx).WithError(err).Error("devmapper maintenance discard failed")
+				}
+			}
+			s.checkPoolUsage(ctx)
+		}
+	}
+}
+
+// discardRemoved removes up to max thin devices which are marked as
+// removed, issuing discards for their blocks when configured.
+func (s *Snapshotter) discardRemoved(ctx context.Context, max int) error {
+	var removedDevices []*DeviceInfo
+	if err := s.pool.WalkDevices(ctx, func(info *DeviceInfo) error {
+		if info.State == Removed && len(removedDevices) < max {
+			removedDevices = append(removedDevices, info)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var result []error
+	for _, dev := range removedDevices {
+		log.G(ctx).WithField("device", dev.Name).Debug("discarding removed device")
+		if err := s.pool.RemoveDevice(ctx, dev.Name); err != nil {
+			result = append(result, err)
+			continue
+		}
+		discardedDevices.Inc()
+	}
+
+	return errors.Join(result...)
+}
+
+// checkPoolUsage refreshes the pool usage metrics and emits an alert
+// when the usage crosses a configured watermark. The alert fires on the
+// crossing, not on every run above the watermark.
+func (s *Snapshotter) checkPoolUsage(ctx context.Context) {
+	usage, err := s.pool.PoolUsage()
+	if err != nil {
+		log.G(ctx).WithError(err).Error("failed to query thin-pool usage")
+		return
+	}
+
+	dataPercent := usage.DataPercent()
+	metadataPercent := usage.MetadataPercent()
+	dataUsagePercent.Set(dataPercent)
+	metadataUsagePercent.Set(metadataPercent)
+
+	above := s.config.DataWatermarkPercent > 0 && dataPercent >= float64(s.config.DataWatermarkPercent) ||
+		s.config.MetadataWatermarkPercent > 0 && metadataPercent >= float64(s.config.MetadataWatermarkPercent)
+	defer func() {
+		s.aboveWatermark = above
+	}()
+	if !above || s.aboveWatermark {
+		return
+	}
+
+	log.G(ctx).Warnf("thin-pool %q usage crossed a watermark: data %.1f%% (watermark %d%%), metadata %.1f%% (watermark %d%%)",
+		s.config.PoolName, dataPercent, s.config.DataWatermarkPercent, metadataPercent, s.config.MetadataWatermarkPercent)
+
+	if s.events == nil {
+		return
+	}
+	if err := s.events.Publish(ctx, PoolUsageEventTopic, &PoolUsageAlert{
+		PoolName:                 s.config.PoolName,
+		DataPercent:              dataPercent,
+		MetadataPercent:          metadataPercent,
+		DataWatermarkPercent:     s.config.DataWatermarkPercent,
+		MetadataWatermarkPercent: s.config.MetadataWatermarkPercent,
+	}); err != nil {
+		log.G(ctx).WithError(err).Error("failed to publish pool usage alert")
+	}
+}
@@ -22,6 +22,8 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/basuotian/containerd/core/events"
+	"github.com/basuotian/containerd/core/snapshots"
 	"github.com/basuotian/containerd/plugins"
 	"github.com/basuotian/containerd/plugins/snapshots/devmapper"
 	"github.com/containerd/platforms"
@@ -34,8 +36,13 @@ func init() {
 		Type:   plugins.SnapshotPlugin,
 		ID:     "devmapper",
 		Config: &devmapper.Config{},
+		Requires: []plugin.Type{
+			plugins.EventPlugin,
+		},
 		InitFn: func(ic *plugin.InitContext) (interface{}, error) {
 			ic.Meta.Platforms = append(ic.Meta.Platforms, platforms.DefaultSpec())
+			// Thin device snapshots are constant time regardless of size.
+			ic.Meta.Capabilities = append(ic.Meta.Capabilities, snapshots.CapabilityCheapClones)
 
 			config, ok := ic.Config.(*devmapper.Config)
 			if !ok {
@@ -50,8 +57,14 @@ func init() {
 				config.RootPath = ic.Properties[plugins.PropertyRootDir]
 			}
 
+			ep, err := ic.GetSingle(plugins.EventPlugin)
+			if err != nil {
+				return nil, err
+			}
+
 			ic.Meta.Exports[plugins.SnapshotterRootDir] = config.RootPath
-			return devmapper.NewSnapshotter(ic.Context, config)
+			return devmapper.NewSnapshotter(ic.Context, config,
+				devmapper.WithEventPublisher(ep.(events.Publisher)))
 		},
 	})
 }
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/sys/unix"
@@ -496,6 +497,76 @@ func (p *PoolDevice) GetUsage(deviceName string) (int64, error) {
 	return count * dmsetup.SectorSize, nil
 }
 
+// PoolUsage describes how many blocks of the thin-pool data and metadata
+// devices are in use, as reported by 'dmsetup status' for the pool:
+//
+//	0 2097152 thin-pool 0 280/32768 561/327680 - rw discard_passdown ...
+//
+// Where 280/32768 are the used/total metadata blocks and 561/327680 the
+// used/total data blocks.
+type PoolUsage struct {
+	DataUsed      uint64
+	DataTotal     uint64
+	MetadataUsed  uint64
+	MetadataTotal uint64
+}
+
+// DataPercent returns the thin-pool data usage as a percentage.
+func (u PoolUsage) DataPercent() float64 {
+	return percent(u.DataUsed, u.DataTotal)
+}
+
+// MetadataPercent returns the thin-pool metadata usage as a percentage.
+func (u PoolUsage) MetadataPercent() float64 {
+	return percent(u.MetadataUsed, u.MetadataTotal)
+}
+
+func percent(used, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100
+}
+
+// PoolUsage reports the thin-pool data and metadata usage.
+func (p *PoolDevice) PoolUsage() (PoolUsage, error) {
+	status, err := dmsetup.Status(p.poolName)
+	if err != nil {
+		return PoolUsage{}, fmt.Errorf("can't get status for pool %q: %w", p.poolName, err)
+	}
+
+	if len(status.Params) < 3 {
+		return PoolUsage{}, fmt.Errorf("unexpected output from dmsetup status for pool %q: %v", p.poolName, status.Params)
+	}
+
+	var usage PoolUsage
+	if usage.MetadataUsed, usage.MetadataTotal, err = parseBlocksPair(status.Params[1]); err != nil {
+		return PoolUsage{}, fmt.Errorf("failed to parse metadata blocks of pool %q: %w", p.poolName, err)
+	}
+	if usage.DataUsed, usage.DataTotal, err = parseBlocksPair(status.Params[2]); err != nil {
+		return PoolUsage{}, fmt.Errorf("failed to parse data blocks of pool %q: %w", p.poolName, err)
+	}
+
+	return usage, nil
+}
+
+// parseBlocksPair parses a "used/total" blocks pair from a pool status line.
+func parseBlocksPair(s string) (uint64, uint64, error) {
+	usedStr, totalStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("not a used/total pair: %q", s)
+	}
+	used, err := strconv.ParseUint(usedStr, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	total, err := strconv.ParseUint(totalStr, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return used, total, nil
+}
+
 // RemoveDevice completely wipes out thin device from thin-pool and frees it's device ID
 func (p *PoolDevice) RemoveDevice(ctx context.Context, deviceName string) error {
 	info, err := p.metadata.GetDevice(ctx, deviceName)
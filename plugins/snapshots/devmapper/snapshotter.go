@@ -28,6 +28,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/basuotian/containerd/core/events"
 	"github.com/basuotian/containerd/core/mount"
 	"github.com/basuotian/containerd/core/snapshots"
 	"github.com/basuotian/containerd/core/snapshots/storage"
@@ -59,12 +60,31 @@ type Snapshotter struct {
 	config    *Config
 	cleanupFn []closeFunc
 	closeOnce sync.Once
+
+	// events receives the pool usage alerts emitted by the maintenance
+	// loop, optional.
+	events events.Publisher
+	// aboveWatermark remembers whether pool usage was above a watermark
+	// during the previous maintenance run, so alerts only fire on the
+	// crossing.
+	aboveWatermark bool
+}
+
+// Opt is an option for the devmapper snapshotter
+type Opt func(*Snapshotter)
+
+// WithEventPublisher sets the publisher used for pool usage alerts
+// emitted by the maintenance loop.
+func WithEventPublisher(publisher events.Publisher) Opt {
+	return func(s *Snapshotter) {
+		s.events = publisher
+	}
 }
 
 // NewSnapshotter creates new device mapper snapshotter.
 // Internally it creates thin-pool device (or reloads if it's already exists) and
 // initializes a database file for metadata.
-func NewSnapshotter(ctx context.Context, config *Config) (*Snapshotter, error) {
+func NewSnapshotter(ctx context.Context, config *Config, opts ...Opt) (*Snapshotter, error) {
 	// Make sure snapshotter configuration valid before running
 	if err := config.parse(); err != nil {
 		return nil, err
@@ -94,12 +114,27 @@ func NewSnapshotter(ctx context.Context, config *Config) (*Snapshotter, error) {
 
 	cleanupFn = append(cleanupFn, poolDevice.Close)
 
-	return &Snapshotter{
+	snapshotter := &Snapshotter{
 		store:     store,
 		config:    config,
 		pool:      poolDevice,
 		cleanupFn: cleanupFn,
-	}, nil
+	}
+
+	for _, opt := range opts {
+		opt(snapshotter)
+	}
+
+	if config.MaintenanceIntervalDuration > 0 {
+		maintenanceCtx, cancel := context.WithCancel(context.Background())
+		snapshotter.cleanupFn = append(snapshotter.cleanupFn, func() error {
+			cancel()
+			return nil
+		})
+		go snapshotter.runMaintenance(maintenanceCtx)
+	}
+
+	return snapshotter, nil
 }
 
 // Stat returns the info for an active or committed snapshot from store
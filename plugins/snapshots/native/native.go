@@ -33,6 +33,11 @@ import (
 type snapshotter struct {
 	root string
 	ms   *storage.MetaStore
+
+	// reflink indicates the filesystem backing the snapshots directory
+	// supports FICLONE, letting Prepare clone parent file contents
+	// instead of copying them.
+	reflink bool
 }
 
 // NewSnapshotter returns a Snapshotter which copies layers on the underlying
@@ -50,9 +55,15 @@ func NewSnapshotter(root string) (snapshots.Snapshotter, error) {
 		return nil, err
 	}
 
+	reflink := reflinkSupported(filepath.Join(root, "snapshots"))
+	if reflink {
+		log.L.WithField("root", root).Debug("native snapshotter using reflink copies")
+	}
+
 	return &snapshotter{
-		root: root,
-		ms:   ms,
+		root:    root,
+		ms:      ms,
+		reflink: reflink,
 	}, nil
 }
 
@@ -255,10 +266,15 @@ func (o *snapshotter) createSnapshot(ctx context.Context, kind snapshots.Kind, k
 					log.G(ctx).WithError(copyErr).Debugf("failed to copy xattr %q", xattrKey)
 					return nil
 				}
-				copyDirOpts := []fs.CopyDirOpt{
-					fs.WithXAttrErrorHandler(xattrErrorHandler),
+				if o.reflink {
+					err = copyDirReflink(td, parent, xattrErrorHandler)
+				} else {
+					copyDirOpts := []fs.CopyDirOpt{
+						fs.WithXAttrErrorHandler(xattrErrorHandler),
+					}
+					err = fs.CopyDir(td, parent, copyDirOpts...)
 				}
-				if err = fs.CopyDir(td, parent, copyDirOpts...); err != nil {
+				if err != nil {
 					return fmt.Errorf("copying of parent failed: %w", err)
 				}
 			}
@@ -314,3 +330,9 @@ func (o *snapshotter) mounts(s storage.Snapshot) []mount.Mount {
 func (o *snapshotter) Close() error {
 	return o.ms.Close()
 }
+
+// PluginInfo forwards introspection requests, such as
+// storage.CompactRequest and storage.DBStatsRequest, to the metastore.
+func (o *snapshotter) PluginInfo(ctx context.Context, request interface{}) (interface{}, error) {
+	return o.ms.PluginInfo(ctx, request)
+}
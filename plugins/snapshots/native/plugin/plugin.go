@@ -19,6 +19,7 @@ package plugin
 import (
 	"errors"
 
+	"github.com/basuotian/containerd/core/snapshots"
 	"github.com/basuotian/containerd/plugins"
 	"github.com/basuotian/containerd/plugins/snapshots/native"
 	"github.com/containerd/platforms"
@@ -50,6 +51,10 @@ func init() {
 				root = config.RootPath
 			}
 
+			// Snapshots are plain directory copies, so disk usage
+			// reflects the full cost of each snapshot.
+			ic.Meta.Capabilities = append(ic.Meta.Capabilities, snapshots.CapabilityAccurateUsage)
+
 			ic.Meta.Exports[plugins.SnapshotterRootDir] = root
 			return native.NewSnapshotter(root)
 		},
@@ -0,0 +1,284 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package native
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/containerd/continuity/fs"
+	"github.com/containerd/continuity/sysx"
+	"golang.org/x/sys/unix"
+)
+
+// reflinkSupported probes whether the filesystem backing dir supports
+// FICLONE by cloning one temporary file into another. Snapshot copies
+// always stay within the snapshots directory, so probing there is
+// representative.
+func reflinkSupported(dir string) bool {
+	src, err := os.CreateTemp(dir, ".reflink-probe-")
+	if err != nil {
+		return false
+	}
+	defer func() {
+		src.Close()
+		os.Remove(src.Name())
+	}()
+	if _, err := src.Write([]byte("probe")); err != nil {
+		return false
+	}
+
+	dst, err := os.CreateTemp(dir, ".reflink-probe-")
+	if err != nil {
+		return false
+	}
+	defer func() {
+		dst.Close()
+		os.Remove(dst.Name())
+	}()
+
+	return unix.IoctlFileClone(int(dst.Fd()), int(src.Fd())) == nil
+}
+
+// copyDirReflink copies the directory tree at src to dst, cloning
+// regular file contents with FICLONE where the filesystem allows it and
+// falling back to a plain copy per file otherwise. Metadata handling
+// mirrors fs.CopyDir: ownership, mode, timestamps, hard links and
+// xattrs are preserved, with xattr errors routed through xeh.
+func copyDirReflink(dst, src string, xeh fs.XAttrErrorHandler) error {
+	inodes := map[uint64]string{}
+	return reflinkCopyDirectory(dst, src, inodes, xeh)
+}
+
+func reflinkCopyDirectory(dst, src string, inodes map[uint64]string, xeh fs.XAttrErrorHandler) error {
+	stat, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("source %s is not a directory", src)
+	}
+
+	if _, err := os.Stat(dst); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Mkdir(dst, stat.Mode()); err != nil {
+			return fmt.Errorf("failed to mkdir %s: %w", dst, err)
+		}
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+
+	for _, entry := range entries {
+		source := filepath.Join(src, entry.Name())
+		target := filepath.Join(dst, entry.Name())
+
+		fileInfo, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to get file info for %s: %w", entry.Name(), err)
+		}
+
+		switch {
+		case entry.IsDir():
+			if err := reflinkCopyDirectory(target, source, inodes, xeh); err != nil {
+				return err
+			}
+			continue
+		case (fileInfo.Mode() & os.ModeType) == 0:
+			link, err := getLinkSource(target, fileInfo, inodes)
+			if err != nil {
+				return fmt.Errorf("failed to get hardlink: %w", err)
+			}
+			if link != "" {
+				if err := os.Link(link, target); err != nil {
+					return fmt.Errorf("failed to create hard link: %w", err)
+				}
+			} else if err := reflinkCopyFile(target, source, fileInfo); err != nil {
+				return fmt.Errorf("failed to copy files: %w", err)
+			}
+		case (fileInfo.Mode() & os.ModeSymlink) == os.ModeSymlink:
+			link, err := os.Readlink(source)
+			if err != nil {
+				return fmt.Errorf("failed to read link: %s: %w", source, err)
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return fmt.Errorf("failed to create symlink: %s: %w", target, err)
+			}
+		case (fileInfo.Mode() & os.ModeDevice) == os.ModeDevice,
+			(fileInfo.Mode() & os.ModeNamedPipe) == os.ModeNamedPipe,
+			(fileInfo.Mode() & os.ModeSocket) == os.ModeSocket:
+			if err := copyIrregular(target, fileInfo); err != nil {
+				return fmt.Errorf("failed to create irregular file: %w", err)
+			}
+		default:
+			continue
+		}
+
+		if err := copyFileInfo(fileInfo, target); err != nil {
+			return fmt.Errorf("failed to copy file info: %w", err)
+		}
+
+		if err := copyXAttrs(target, source, xeh); err != nil {
+			return fmt.Errorf("failed to copy xattrs: %w", err)
+		}
+	}
+
+	if err := copyFileInfo(stat, dst); err != nil {
+		return fmt.Errorf("failed to copy file info: %w", err)
+	}
+
+	return copyXAttrs(dst, src, xeh)
+}
+
+// reflinkCopyFile clones source into target with FICLONE, falling back
+// to a regular copy when the filesystem rejects the clone, e.g. when
+// the file crosses filesystems or the backing filesystem lacks support.
+func reflinkCopyFile(target, source string, fi os.FileInfo) error {
+	src, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open source %s: %w", source, err)
+	}
+	defer src.Close()
+
+	tgt, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode())
+	if err != nil {
+		return fmt.Errorf("failed to open target %s: %w", target, err)
+	}
+	defer tgt.Close()
+
+	if err := unix.IoctlFileClone(int(tgt.Fd()), int(src.Fd())); err != nil {
+		if !errors.Is(err, unix.EOPNOTSUPP) && !errors.Is(err, unix.EINVAL) && !errors.Is(err, unix.EXDEV) {
+			return fmt.Errorf("failed to clone %s: %w", source, err)
+		}
+		if _, err := io.Copy(tgt, src); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", source, err)
+		}
+	}
+
+	return nil
+}
+
+func getLinkSource(target string, fi os.FileInfo, inodes map[uint64]string) (string, error) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", fmt.Errorf("unsupported stat type: %v", fi.Sys())
+	}
+	if st.Nlink < 2 {
+		return "", nil
+	}
+
+	existing, ok := inodes[st.Ino]
+	if !ok {
+		inodes[st.Ino] = target
+	}
+	return existing, nil
+}
+
+func copyFileInfo(fi os.FileInfo, name string) error {
+	st := fi.Sys().(*syscall.Stat_t)
+	if err := os.Lchown(name, int(st.Uid), int(st.Gid)); err != nil {
+		if os.IsPermission(err) {
+			// Normally if uid/gid are the same this would be a no-op, but some
+			// filesystems may still return EPERM... for instance NFS does this.
+			// In such a case, this is not an error.
+			if dstStat, err2 := os.Lstat(name); err2 == nil {
+				st2 := dstStat.Sys().(*syscall.Stat_t)
+				if st.Uid == st2.Uid && st.Gid == st2.Gid {
+					err = nil
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to chown %s: %w", name, err)
+		}
+	}
+
+	if (fi.Mode() & os.ModeSymlink) != os.ModeSymlink {
+		if err := os.Chmod(name, fi.Mode()); err != nil {
+			return fmt.Errorf("failed to chmod %s: %w", name, err)
+		}
+	}
+
+	timespec := []unix.Timespec{
+		unix.NsecToTimespec(syscall.TimespecToNsec(fs.StatAtime(st))),
+		unix.NsecToTimespec(syscall.TimespecToNsec(fs.StatMtime(st))),
+	}
+	if err := unix.UtimesNanoAt(unix.AT_FDCWD, name, timespec, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return fmt.Errorf("failed to utime %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// copyIrregular covers devices, pipes, and sockets
+func copyIrregular(dst string, fi os.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("unsupported stat type: %s: %v", dst, fi.Mode())
+	}
+	var rDev int
+	if fi.Mode()&os.ModeDevice == os.ModeDevice {
+		rDev = int(st.Rdev)
+	}
+	//nolint:unconvert
+	return syscall.Mknod(dst, uint32(st.Mode), rDev)
+}
+
+func copyXAttrs(dst, src string, xeh fs.XAttrErrorHandler) error {
+	xattrKeys, err := sysx.LListxattr(src)
+	if err != nil {
+		if errors.Is(err, unix.ENOTSUP) {
+			return nil
+		}
+		e := fmt.Errorf("failed to list xattrs on %s: %w", src, err)
+		if xeh != nil {
+			e = xeh(dst, src, "", e)
+		}
+		return e
+	}
+	for _, xattr := range xattrKeys {
+		data, err := sysx.LGetxattr(src, xattr)
+		if err != nil {
+			e := fmt.Errorf("failed to get xattr %q on %s: %w", xattr, src, err)
+			if xeh != nil {
+				if e = xeh(dst, src, xattr, e); e == nil {
+					continue
+				}
+			}
+			return e
+		}
+		if err := sysx.LSetxattr(dst, xattr, data, 0); err != nil {
+			e := fmt.Errorf("failed to set xattr %q on %s: %w", xattr, dst, err)
+			if xeh != nil {
+				if e = xeh(dst, src, xattr, e); e == nil {
+					continue
+				}
+			}
+			return e
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package native
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/containerd/continuity/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCopyDirReflink verifies copyDirReflink reproduces the source tree
+// whether or not the backing filesystem supports FICLONE; on filesystems
+// without support every file takes the per-file fallback path.
+func TestCopyDirReflink(t *testing.T) {
+	var (
+		root = t.TempDir()
+		src  = filepath.Join(root, "src")
+		dst  = filepath.Join(root, "dst")
+	)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "dir"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "file"), []byte("content"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "dir", "secret"), []byte("hidden"), 0600))
+	require.NoError(t, os.Symlink("file", filepath.Join(src, "link")))
+	require.NoError(t, os.Link(filepath.Join(src, "file"), filepath.Join(src, "hardlink")))
+
+	require.NoError(t, copyDirReflink(dst, src, nil))
+
+	b, err := os.ReadFile(filepath.Join(dst, "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(b))
+
+	b, err = os.ReadFile(filepath.Join(dst, "dir", "secret"))
+	require.NoError(t, err)
+	assert.Equal(t, "hidden", string(b))
+
+	fi, err := os.Stat(filepath.Join(dst, "dir", "secret"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	target, err := os.Readlink(filepath.Join(dst, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "file", target)
+
+	st1, err := os.Stat(filepath.Join(dst, "file"))
+	require.NoError(t, err)
+	st2, err := os.Stat(filepath.Join(dst, "hardlink"))
+	require.NoError(t, err)
+	assert.Equal(t, st1.Sys().(*syscall.Stat_t).Ino, st2.Sys().(*syscall.Stat_t).Ino, "hard link not preserved")
+}
+
+// BenchmarkParentCopy compares the reflink copy used by Prepare against
+// the plain fs.CopyDir fallback. The reflink variant is skipped when the
+// filesystem backing the test directory does not support FICLONE.
+func BenchmarkParentCopy(b *testing.B) {
+	root := b.TempDir()
+	src := filepath.Join(root, "src")
+	if err := os.Mkdir(src, 0755); err != nil {
+		b.Fatal(err)
+	}
+
+	data := make([]byte, 1<<20)
+	for i := 0; i < 32; i++ {
+		if err := os.WriteFile(filepath.Join(src, fmt.Sprintf("layer-%d", i)), data, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("copydir", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			dst := filepath.Join(root, fmt.Sprintf("copydir-%d", i))
+			if err := fs.CopyDir(dst, src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("reflink", func(b *testing.B) {
+		if !reflinkSupported(root) {
+			b.Skip("filesystem does not support reflink")
+		}
+		for i := 0; i < b.N; i++ {
+			dst := filepath.Join(root, fmt.Sprintf("reflink-%d", i))
+			if err := copyDirReflink(dst, src, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,36 @@
+//go:build !linux
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package native
+
+import (
+	"fmt"
+
+	"github.com/containerd/continuity/fs"
+	"github.com/containerd/errdefs"
+)
+
+// reflinkSupported reports whether reflink copies can be used for
+// snapshot directories under dir. Only implemented on Linux.
+func reflinkSupported(dir string) bool {
+	return false
+}
+
+func copyDirReflink(dst, src string, xeh fs.XAttrErrorHandler) error {
+	return fmt.Errorf("reflink copies are only supported on Linux: %w", errdefs.ErrNotImplemented)
+}
@@ -0,0 +1,248 @@
+//go:build linux
+
+/*
+   Copyright The containerd Authors.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package overlay
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/basuotian/containerd/core/mount"
+	"github.com/basuotian/containerd/core/snapshots"
+	"github.com/basuotian/containerd/core/snapshots/storage"
+	"github.com/containerd/continuity/fs"
+	"github.com/containerd/log"
+	metrics "github.com/docker/go-metrics"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// flattenTempGrace is how old an abandoned flatten temp directory must be
+// before Cleanup removes it, so a flatten that is still copying is not
+// swept from underneath.
+const flattenTempGrace = time.Hour
+
+var (
+	flattenOperations metrics.Counter
+	flattenReuses     metrics.Counter
+	flattenFailures   metrics.Counter
+)
+
+func init() {
+	ns := metrics.NewNamespace("containerd", "snapshotter_overlay", nil)
+	flattenOperations = ns.NewCounter("lowerdir_flattens", "Total number of lower directory chains flattened")
+	flattenReuses = ns.NewCounter("lowerdir_flatten_reuses", "Total number of times a cached flattened lower directory was reused")
+	flattenFailures = ns.NewCounter("lowerdir_flatten_failures", "Total number of failed lower directory chain flattens")
+	metrics.Register(ns)
+}
+
+// flattenLowerdirs replaces the lower directory chain by a single
+// flattened directory when the resulting mount options would no longer
+// fit into the mount argument buffer, so deep images do not fail at
+// mount time. The remaining options of the final mount are passed in so
+// their size can be taken into account. Flattening failures are logged
+// and the original chain returned, leaving the error to the mount.
+func (o *snapshotter) flattenLowerdirs(ctx context.Context, lowers []string, options []string) []string {
+	if o.flattenThreshold < 0 || len(lowers) <= 1 {
+		return lowers
+	}
+	lowerdir := fmt.Sprintf("lowerdir=%s", strings.Join(lowers, ":"))
+	if o.flattenThreshold > 0 {
+		if len(lowerdir) <= o.flattenThreshold {
+			return lowers
+		}
+	} else if !mount.OverlayOptionsTooLarge(append(options, lowerdir)) {
+		return lowers
+	}
+	flat, err := o.flattened(ctx, lowers)
+	if err != nil {
+		flattenFailures.Inc()
+		log.G(ctx).WithError(err).Warnf("failed to flatten %d lower directories", len(lowers))
+		return lowers
+	}
+	return []string{flat}
+}
+
+// flattened returns a directory holding the merged content of the given
+// lower directories, top first. The directory is cached under the
+// snapshotter root keyed by the chain, so sibling snapshots of the same
+// deep image reuse a single flattened copy.
+func (o *snapshotter) flattened(ctx context.Context, lowers []string) (string, error) {
+	flattenRoot := filepath.Join(o.root, "flatten")
+	dir := filepath.Join(flattenRoot, digest.SHA256.FromString(strings.Join(lowers, ":")).Encoded())
+	if _, err := os.Stat(dir); err == nil {
+		flattenReuses.Inc()
+		return dir, nil
+	}
+	if err := os.MkdirAll(flattenRoot, 0700); err != nil {
+		return "", err
+	}
+
+	t1 := time.Now()
+
+	// Merge bottom up in chunks whose lowerdir option is guaranteed to fit
+	// into the mount argument buffer, copying out the merged view each
+	// time. The result of the previous chunks is mounted as the bottom
+	// most lower directory of the next one.
+	var (
+		acc    string
+		budget = os.Getpagesize() / 2
+	)
+	defer func() {
+		if acc != "" {
+			os.RemoveAll(acc)
+		}
+	}()
+	for i := len(lowers); i > 0; {
+		j, size := i, 0
+		for j > 0 && (i-j < 2 || size+len(lowers[j-1])+1 < budget) {
+			j--
+			size += len(lowers[j]) + 1
+		}
+		mountLowers := lowers[j:i]
+		if acc != "" {
+			mountLowers = append(append([]string{}, mountLowers...), acc)
+		}
+		next, err := os.MkdirTemp(flattenRoot, "new-")
+		if err != nil {
+			return "", err
+		}
+		m := mount.Mount{
+			Type:    "overlay",
+			Source:  "overlay",
+			Options: []string{"ro", fmt.Sprintf("lowerdir=%s", strings.Join(mountLowers, ":"))},
+		}
+		if err := mount.WithTempMount(ctx, []mount.Mount{m}, func(root string) error {
+			return fs.CopyDir(next, root)
+		}); err != nil {
+			os.RemoveAll(next)
+			return "", fmt.Errorf("failed to merge lower directories: %w", err)
+		}
+		if acc != "" {
+			os.RemoveAll(acc)
+		}
+		acc = next
+		i = j
+	}
+
+	if err := os.Rename(acc, dir); err != nil {
+		if _, serr := os.Stat(dir); serr == nil {
+			// lost the race against a concurrent flatten of the same chain
+			flattenReuses.Inc()
+			return dir, nil
+		}
+		return "", err
+	}
+	acc = ""
+	flattenOperations.Inc()
+	log.G(ctx).WithField("d", time.Since(t1)).Debugf("flattened %d lower directories", len(lowers))
+	return dir, nil
+}
+
+// cleanupFlatten removes cached flattened lower directories whose chain no
+// longer matches the parent chain of any snapshot, so the cache does not
+// outlive the images it was built from, along with temp directories left
+// behind by flattens interrupted by a crash.
+func (o *snapshotter) cleanupFlatten(ctx context.Context) error {
+	stale, err := o.staleFlattenDirectories(ctx)
+	if err != nil {
+		return err
+	}
+	for _, dir := range stale {
+		if err := os.RemoveAll(dir); err != nil {
+			log.G(ctx).WithError(err).WithField("path", dir).Warn("failed to remove flattened lower directory")
+		}
+	}
+	return nil
+}
+
+// staleFlattenDirectories returns the flatten cache entries to remove. An
+// entry is live while some snapshot's chain of parent upper directories
+// still hashes to its key; everything else only wastes space.
+func (o *snapshotter) staleFlattenDirectories(ctx context.Context) ([]string, error) {
+	flattenRoot := filepath.Join(o.root, "flatten")
+	fd, err := os.Open(flattenRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer fd.Close()
+
+	dirs, err := fd.Readdirnames(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+
+	live := map[string]struct{}{}
+	if err := o.ms.WithTransaction(ctx, true, func(ctx context.Context) error {
+		var (
+			ids     = make(map[string]string)
+			parents = make(map[string]string)
+		)
+		if err := storage.WalkInfo(ctx, func(ctx context.Context, info snapshots.Info) error {
+			id, _, _, err := storage.GetInfo(ctx, info.Name)
+			if err != nil {
+				return err
+			}
+			ids[info.Name] = id
+			parents[info.Name] = info.Parent
+			return nil
+		}); err != nil {
+			return err
+		}
+		for name := range ids {
+			var chain []string
+			for parent := parents[name]; parent != ""; parent = parents[parent] {
+				chain = append(chain, o.upperPath(ids[parent]))
+			}
+			if len(chain) <= 1 {
+				// chains of a single lower directory are never flattened
+				continue
+			}
+			live[digest.SHA256.FromString(strings.Join(chain, ":")).Encoded()] = struct{}{}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for _, d := range dirs {
+		if strings.HasPrefix(d, "new-") {
+			// Leave young temp directories alone, a concurrent flatten may
+			// still be copying into them.
+			if fi, err := os.Stat(filepath.Join(flattenRoot, d)); err == nil && time.Since(fi.ModTime()) < flattenTempGrace {
+				continue
+			}
+			stale = append(stale, filepath.Join(flattenRoot, d))
+			continue
+		}
+		if _, ok := live[d]; !ok {
+			stale = append(stale, filepath.Join(flattenRoot, d))
+		}
+	}
+	return stale, nil
+}
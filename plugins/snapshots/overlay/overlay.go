@@ -25,6 +25,7 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/basuotian/containerd/core/mount"
 	"github.com/basuotian/containerd/core/snapshots"
@@ -32,6 +33,7 @@ import (
 	"github.com/basuotian/containerd/internal/userns"
 	"github.com/basuotian/containerd/plugins/snapshots/overlay/overlayutils"
 	"github.com/containerd/continuity/fs"
+	"github.com/containerd/errdefs"
 	"github.com/containerd/log"
 )
 
@@ -42,12 +44,14 @@ const upperdirKey = "containerd.io/snapshot/overlay.upperdir"
 
 // SnapshotterConfig is used to configure the overlay snapshotter instance
 type SnapshotterConfig struct {
-	asyncRemove   bool
-	upperdirLabel bool
-	ms            MetaStore
-	mountOptions  []string
-	remapIDs      bool
-	slowChown     bool
+	asyncRemove      bool
+	upperdirLabel    bool
+	ms               MetaStore
+	mountOptions     []string
+	remapIDs         bool
+	slowChown        bool
+	flattenThreshold int
+	compactInterval  time.Duration
 }
 
 // Opt is an option to configure the overlay snapshotter
@@ -105,14 +109,39 @@ func WithSlowChown(config *SnapshotterConfig) error {
 	return nil
 }
 
+// WithFlattenThreshold configures when a lower directory chain is
+// flattened into a single cached directory before mounting. A positive
+// value flattens once the lowerdir option string grows beyond that many
+// bytes, zero flattens only when the mount options would no longer fit
+// into the mount argument buffer and a negative value disables
+// flattening altogether.
+func WithFlattenThreshold(threshold int) Opt {
+	return func(config *SnapshotterConfig) error {
+		config.flattenThreshold = threshold
+		return nil
+	}
+}
+
+// WithCompactInterval compacts the metastore database on the given
+// interval to reclaim free pages. Zero disables scheduled compaction;
+// a compaction can still be triggered through the introspection API.
+func WithCompactInterval(interval time.Duration) Opt {
+	return func(config *SnapshotterConfig) error {
+		config.compactInterval = interval
+		return nil
+	}
+}
+
 type snapshotter struct {
-	root          string
-	ms            MetaStore
-	asyncRemove   bool
-	upperdirLabel bool
-	options       []string
-	remapIDs      bool
-	slowChown     bool
+	root             string
+	ms               MetaStore
+	asyncRemove      bool
+	upperdirLabel    bool
+	options          []string
+	remapIDs         bool
+	slowChown        bool
+	flattenThreshold int
+	compactStop      func()
 }
 
 // NewSnapshotter returns a Snapshotter which uses overlayfs. The overlayfs
@@ -162,15 +191,22 @@ func NewSnapshotter(root string, opts ...Opt) (snapshots.Snapshotter, error) {
 		config.mountOptions = append(config.mountOptions, "index=off")
 	}
 
-	return &snapshotter{
-		root:          root,
-		ms:            config.ms,
-		asyncRemove:   config.asyncRemove,
-		upperdirLabel: config.upperdirLabel,
-		options:       config.mountOptions,
-		remapIDs:      config.remapIDs,
-		slowChown:     config.slowChown,
-	}, nil
+	o := &snapshotter{
+		root:             root,
+		ms:               config.ms,
+		asyncRemove:      config.asyncRemove,
+		upperdirLabel:    config.upperdirLabel,
+		options:          config.mountOptions,
+		remapIDs:         config.remapIDs,
+		slowChown:        config.slowChown,
+		flattenThreshold: config.flattenThreshold,
+	}
+	if config.compactInterval > 0 {
+		if c, ok := o.ms.(metaStoreCompactor); ok {
+			o.compactStop = c.ScheduleCompaction(context.Background(), config.compactInterval)
+		}
+	}
+	return o, nil
 }
 
 func hasOption(options []string, key string, hasValue bool) bool {
@@ -291,7 +327,7 @@ func (o *snapshotter) Mounts(ctx context.Context, key string) (_ []mount.Mount,
 	}); err != nil {
 		return nil, err
 	}
-	return o.mounts(s, info), nil
+	return o.mounts(ctx, s, info), nil
 }
 
 func (o *snapshotter) Commit(ctx context.Context, name, key string, opts ...snapshots.Opt) error {
@@ -380,7 +416,7 @@ func (o *snapshotter) Cleanup(ctx context.Context) error {
 		}
 	}
 
-	return nil
+	return o.cleanupFlatten(ctx)
 }
 
 func (o *snapshotter) cleanupDirectories(ctx context.Context) (_ []string, err error) {
@@ -527,7 +563,7 @@ func (o *snapshotter) createSnapshot(ctx context.Context, kind snapshots.Kind, k
 	}); err != nil {
 		return nil, err
 	}
-	return o.mounts(s, info), nil
+	return o.mounts(ctx, s, info), nil
 }
 
 func (o *snapshotter) prepareDirectory(ctx context.Context, snapshotDir string, kind snapshots.Kind) (string, error) {
@@ -549,7 +585,7 @@ func (o *snapshotter) prepareDirectory(ctx context.Context, snapshotDir string,
 	return td, nil
 }
 
-func (o *snapshotter) mounts(s storage.Snapshot, info snapshots.Info) []mount.Mount {
+func (o *snapshotter) mounts(ctx context.Context, s storage.Snapshot, info snapshots.Info) []mount.Mount {
 	var options []string
 
 	if o.remapIDs {
@@ -602,6 +638,10 @@ func (o *snapshotter) mounts(s storage.Snapshot, info snapshots.Info) []mount.Mo
 	for i := range s.ParentIDs {
 		parentPaths[i] = o.upperPath(s.ParentIDs[i])
 	}
+	probe := make([]string, 0, len(options)+len(o.options))
+	probe = append(probe, options...)
+	probe = append(probe, o.options...)
+	parentPaths = o.flattenLowerdirs(ctx, parentPaths, probe)
 	options = append(options, fmt.Sprintf("lowerdir=%s", strings.Join(parentPaths, ":")))
 	options = append(options, o.options...)
 
@@ -624,9 +664,29 @@ func (o *snapshotter) workPath(id string) string {
 
 // Close closes the snapshotter
 func (o *snapshotter) Close() error {
+	if o.compactStop != nil {
+		o.compactStop()
+	}
 	return o.ms.Close()
 }
 
+// metaStoreCompactor is implemented by metastores which can reclaim
+// free database pages, such as *storage.MetaStore.
+type metaStoreCompactor interface {
+	ScheduleCompaction(ctx context.Context, interval time.Duration) func()
+}
+
+// PluginInfo forwards introspection requests, such as
+// storage.CompactRequest and storage.DBStatsRequest, to the metastore.
+func (o *snapshotter) PluginInfo(ctx context.Context, request interface{}) (interface{}, error) {
+	if p, ok := o.ms.(interface {
+		PluginInfo(ctx context.Context, request interface{}) (interface{}, error)
+	}); ok {
+		return p.PluginInfo(ctx, request)
+	}
+	return nil, fmt.Errorf("unknown request type %T: %w", request, errdefs.ErrNotImplemented)
+}
+
 // supportsIndex checks whether the "index=off" option is supported by the kernel.
 func supportsIndex() bool {
 	if _, err := os.Stat("/sys/module/overlay/parameters/index"); err == nil {
@@ -23,8 +23,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
+	"time"
 
 	containerd "github.com/basuotian/containerd/client"
 	"github.com/basuotian/containerd/core/mount"
@@ -34,6 +36,7 @@ import (
 	"github.com/basuotian/containerd/internal/userns"
 	"github.com/basuotian/containerd/pkg/testutil"
 	"github.com/basuotian/containerd/plugins/snapshots/overlay/overlayutils"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/opencontainers/runtime-spec/specs-go"
 )
 
@@ -747,3 +750,66 @@ func testOverlayView(t *testing.T, newSnapshotter testsuite.SnapshotterFunc) {
 		t.Error("expected option first option to be provided option \"volatile\"")
 	}
 }
+
+func TestOverlayFlattenCleanup(t *testing.T) {
+	ctx := context.TODO()
+	root := t.TempDir()
+	o, _, err := newSnapshotterWithOpts()(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "/tmp/base"
+	if _, err := o.Prepare(ctx, key, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Commit(ctx, "base", key); err != nil {
+		t.Fatal(err)
+	}
+	key = "/tmp/mid"
+	if _, err := o.Prepare(ctx, key, "base"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Commit(ctx, "mid", key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Prepare(ctx, "/tmp/top", "mid"); err != nil {
+		t.Fatal(err)
+	}
+
+	sn := o.(*snapshotter)
+	flattenRoot := filepath.Join(root, "flatten")
+	// The chain of "/tmp/top" is its parents' upper directories, top first.
+	chain := strings.Join([]string{sn.upperPath("2"), sn.upperPath("1")}, ":")
+	var (
+		liveDir   = filepath.Join(flattenRoot, digest.SHA256.FromString(chain).Encoded())
+		staleDir  = filepath.Join(flattenRoot, digest.SHA256.FromString("removed chain").Encoded())
+		oldTemp   = filepath.Join(flattenRoot, "new-123")
+		freshTemp = filepath.Join(flattenRoot, "new-456")
+	)
+	for _, d := range []string{liveDir, staleDir, oldTemp, freshTemp} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Age the abandoned temp directory past the grace period.
+	old := time.Now().Add(-2 * flattenTempGrace)
+	if err := os.Chtimes(oldTemp, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sn.Cleanup(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range []string{liveDir, freshTemp} {
+		if _, err := os.Stat(d); err != nil {
+			t.Errorf("expected %q to survive cleanup: %v", d, err)
+		}
+	}
+	for _, d := range []string{staleDir, oldTemp} {
+		if _, err := os.Stat(d); !os.IsNotExist(err) {
+			t.Errorf("expected %q to be removed by cleanup", d)
+		}
+	}
+}
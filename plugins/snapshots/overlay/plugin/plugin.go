@@ -20,7 +20,10 @@ package overlay
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/basuotian/containerd/core/snapshots"
 	"github.com/basuotian/containerd/plugins"
 	"github.com/basuotian/containerd/plugins/snapshots/overlay"
 	"github.com/basuotian/containerd/plugins/snapshots/overlay/overlayutils"
@@ -29,11 +32,6 @@ import (
 	"github.com/containerd/plugin/registry"
 )
 
-const (
-	capaRemapIDs     = "remap-ids"
-	capaOnlyRemapIDs = "only-remap-ids"
-)
-
 // Config represents configuration for the overlay plugin.
 type Config struct {
 	// Root directory for the plugin
@@ -48,6 +46,20 @@ type Config struct {
 
 	// MountOptions are options used for the overlay mount (not used on bind mounts)
 	MountOptions []string `toml:"mount_options"`
+
+	// FlattenThreshold controls when a lower directory chain is flattened
+	// into a single cached directory before mounting. A positive value
+	// flattens once the lowerdir option string exceeds that many bytes,
+	// zero (the default) flattens only when the mount options would not
+	// fit into the mount argument buffer and a negative value disables
+	// flattening.
+	FlattenThreshold int `toml:"flatten_threshold"`
+
+	// CompactInterval compacts the metadata database on the given
+	// interval to reclaim free pages, in the golang duration format.
+	// Empty disables scheduled compaction; a compaction can still be
+	// triggered through the introspection API.
+	CompactInterval string `toml:"compact_interval"`
 }
 
 func init() {
@@ -79,19 +91,32 @@ func init() {
 			if len(config.MountOptions) > 0 {
 				oOpts = append(oOpts, overlay.WithMountOptions(config.MountOptions))
 			}
+			if config.FlattenThreshold != 0 {
+				oOpts = append(oOpts, overlay.WithFlattenThreshold(config.FlattenThreshold))
+			}
+			if config.CompactInterval != "" {
+				interval, err := time.ParseDuration(config.CompactInterval)
+				if err != nil {
+					return nil, fmt.Errorf("invalid compact_interval %q: %w", config.CompactInterval, err)
+				}
+				oOpts = append(oOpts, overlay.WithCompactInterval(interval))
+			}
 			if ok, err := overlayutils.SupportsIDMappedMounts(); err == nil && ok {
 				oOpts = append(oOpts, overlay.WithRemapIDs)
-				ic.Meta.Capabilities = append(ic.Meta.Capabilities, capaRemapIDs)
+				ic.Meta.Capabilities = append(ic.Meta.Capabilities, snapshots.CapabilityRemapIDs)
 			}
 
 			if config.SlowChown {
 				oOpts = append(oOpts, overlay.WithSlowChown)
 			} else {
-				// If slowChown is false, we use capaOnlyRemapIDs to signal we only
+				// If slowChown is false, we use snapshots.CapabilityOnlyRemapIDs to signal we only
 				// allow idmap mounts.
-				ic.Meta.Capabilities = append(ic.Meta.Capabilities, capaOnlyRemapIDs)
+				ic.Meta.Capabilities = append(ic.Meta.Capabilities, snapshots.CapabilityOnlyRemapIDs)
 			}
 
+			// overlayfs honors the volatile mount option since Linux 5.10.
+			ic.Meta.Capabilities = append(ic.Meta.Capabilities, snapshots.CapabilityVolatile)
+
 			ic.Meta.Exports[plugins.SnapshotterRootDir] = root
 			return overlay.NewSnapshotter(root, oOpts...)
 		},
@@ -32,12 +32,14 @@ import (
 	"github.com/basuotian/containerd/core/unpack"
 	"github.com/basuotian/containerd/defaults"
 	"github.com/basuotian/containerd/internal/kmutex"
+	"github.com/basuotian/containerd/pkg/blobscanner"
 	"github.com/basuotian/containerd/pkg/imageverifier"
 	"github.com/basuotian/containerd/plugins"
 
 	// Load packages with type registrations
 	_ "github.com/basuotian/containerd/core/transfer/archive"
 	_ "github.com/basuotian/containerd/core/transfer/image"
+	_ "github.com/basuotian/containerd/core/transfer/layout"
 	_ "github.com/basuotian/containerd/core/transfer/registry"
 )
 
@@ -51,6 +53,7 @@ func init() {
 			plugins.MetadataPlugin,
 			plugins.DiffPlugin,
 			plugins.ImageVerifierPlugin,
+			plugins.BlobScannerPlugin,
 			plugins.SnapshotPlugin,
 		},
 		Config: defaultConfig(),
@@ -81,6 +84,17 @@ func init() {
 				}
 			}
 
+			bsps, err := ic.GetByType(plugins.BlobScannerPlugin)
+			if err != nil {
+				return nil, err
+			}
+			if len(bsps) > 0 {
+				lc.BlobScanners = make(map[string]blobscanner.BlobScanner)
+				for name, bsp := range bsps {
+					lc.BlobScanners[name] = bsp.(blobscanner.BlobScanner)
+				}
+			}
+
 			// Set configuration based on default or user input
 			lc.MaxConcurrentDownloads = config.MaxConcurrentDownloads
 			lc.ConcurrentLayerFetchBuffer = config.ConcurrentLayerFetchBuffer
@@ -173,6 +187,8 @@ func init() {
 				lc.UnpackPlatforms = append(lc.UnpackPlatforms, up)
 			}
 			lc.RegistryConfigPath = config.RegistryConfigPath
+			lc.Airgap = config.Airgap
+			lc.AirgapAllowedHosts = config.AirgapAllowedHosts
 			lc.DuplicationSuppressor = kmutex.New()
 
 			return local.NewTransferService(ms.ContentStore(), metadata.NewImageStore(ms), lc), nil
@@ -202,6 +218,14 @@ type transferConfig struct {
 
 	// RegistryConfigPath is a path to the root directory containing registry-specific configurations
 	RegistryConfigPath string `toml:"config_path"`
+
+	// Airgap refuses any transfer to or from a registry host not listed
+	// in AirgapAllowedHosts, failing explicitly instead of timing out.
+	Airgap bool `toml:"airgap"`
+
+	// AirgapAllowedHosts are the registry hosts still reachable when
+	// airgap is enabled.
+	AirgapAllowedHosts []string `toml:"airgap_allowed_hosts"`
 }
 
 type unpackConfiguration struct {
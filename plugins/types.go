@@ -69,6 +69,8 @@ const (
 	SandboxControllerPlugin plugin.Type = "io.containerd.sandbox.controller.v1"
 	// ImageVerifierPlugin implements an image verifier service
 	ImageVerifierPlugin plugin.Type = "io.containerd.image-verifier.v1"
+	// BlobScannerPlugin implements a blob scanner for the transfer service
+	BlobScannerPlugin plugin.Type = "io.containerd.blob-scanner.v1"
 	// WarningPlugin implements a warning service
 	WarningPlugin plugin.Type = "io.containerd.warning.v1"
 	// CRIServicePlugin implements a cri service